@@ -0,0 +1,146 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+)
+
+/** Length of the rho value in a Dilithium round 2 (8,7) public key */
+const DILITHIUM_R2_87_RHO_LENGTH = 32
+
+/** Length of the packed t1 vector in a Dilithium round 2 (8,7) public key */
+const DILITHIUM_R2_87_T1_LENGTH = 2304
+
+/** Length of a Dilithium round 2 (8,7) signature */
+const DILITHIUM_R2_87_SIGNATURE_LENGTH = 4668
+
+/** Object identifier dilithium-r2-8-7, the same as in ep11cmds */
+var oidDilithiumR2_87 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 2, 267, 1, 8, 7}
+
+/*----------------------------------------------------------------------------*/
+/* A Dilithium round 2 public key with matrix dimensions (8,7), the form used */
+/* for the Dilithium OA signature keys of CEX8 crypto modules.                */
+/*                                                                            */
+/* The Go standard library has no Dilithium support, so the TKE SDK does not  */
+/* generate Dilithium keys or signatures.  Signers for Dilithium keys pass    */
+/* the signing operation to a key store that supports Dilithium, such as a    */
+/* PKCS #11 token using the CKM_IBM_DILITHIUM mechanism.                      */
+/*----------------------------------------------------------------------------*/
+type DilithiumPublicKey struct {
+	Rho []byte // 32-byte seed for the public matrix A
+	T1  []byte // packed t1 vector, 2304 bytes
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a Dilithium round 2 (8,7) public key, checking the lengths of rho  */
+/* and t1.                                                                    */
+/*----------------------------------------------------------------------------*/
+func NewDilithiumPublicKey(rho []byte, t1 []byte) (*DilithiumPublicKey, error) {
+	if len(rho) != DILITHIUM_R2_87_RHO_LENGTH ||
+		len(t1) != DILITHIUM_R2_87_T1_LENGTH {
+		return nil, errors.New("Invalid Dilithium round 2 (8,7) public key, " +
+			"rho length = " + strconv.Itoa(len(rho)) + ", t1 length = " +
+			strconv.Itoa(len(t1)))
+	}
+	return &DilithiumPublicKey{
+		Rho: append([]byte(nil), rho...),
+		T1:  append([]byte(nil), t1...),
+	}, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the subjectPublicKey value for the key: an ASN.1 sequence of two   */
+/* BIT STRINGs holding rho and t1.  This is the layout of the Dilithium       */
+/* public key in the OA certificates of CEX8 crypto modules.                  */
+/*----------------------------------------------------------------------------*/
+func (k *DilithiumPublicKey) KeyValue() []byte {
+	elements := make([][]byte, 2)
+	elements[0] = Asn1FormBitString(k.Rho)
+	elements[1] = Asn1FormBitString(k.T1)
+	return Asn1FormSequence(elements)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the DER encoded SubjectPublicKeyInfo for the key.  The algorithm   */
+/* is dilithium-r2-8-7 with NULL parameters.                                  */
+/*----------------------------------------------------------------------------*/
+func (k *DilithiumPublicKey) SPKI() []byte {
+	oid, _ := asn1.Marshal(oidDilithiumR2_87)
+	algIdFields := make([][]byte, 2)
+	algIdFields[0] = oid
+	algIdFields[1] = asn1.NullBytes
+	elements := make([][]byte, 2)
+	elements[0] = Asn1FormSequence(algIdFields)
+	elements[1] = Asn1FormBitString(k.KeyValue())
+	return Asn1FormSequence(elements)
+}
+
+/** Used to decode a Dilithium SubjectPublicKeyInfo */
+type dilithiumSPKI struct {
+	Algorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue `asn1:"optional"`
+	}
+	PublicKey asn1.BitString
+}
+
+/** Used to decode the subjectPublicKey value of a Dilithium key */
+type dilithiumKeyValue struct {
+	Rho asn1.BitString
+	T1  asn1.BitString
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes a DER encoded SubjectPublicKeyInfo holding a Dilithium round 2     */
+/* (8,7) public key, in the form returned by DilithiumPublicKey.SPKI.         */
+/*----------------------------------------------------------------------------*/
+func ParseDilithiumSPKI(der []byte) (*DilithiumPublicKey, error) {
+	var spki dilithiumSPKI
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err == nil && len(rest) != 0 {
+		err = errors.New("extra data after the SubjectPublicKeyInfo")
+	}
+	if err == nil && !spki.Algorithm.Algorithm.Equal(oidDilithiumR2_87) {
+		err = errors.New("the algorithm is not dilithium-r2-8-7")
+	}
+	var value dilithiumKeyValue
+	if err == nil {
+		rest, err = asn1.Unmarshal(spki.PublicKey.RightAlign(), &value)
+		if err == nil && len(rest) != 0 {
+			err = errors.New("extra data after the public key value")
+		}
+	}
+	if err != nil {
+		return nil, errors.New("Invalid Dilithium public key.\nMessage: " +
+			err.Error())
+	}
+	return NewDilithiumPublicKey(value.Rho.RightAlign(), value.T1.RightAlign())
+}
+
+/*----------------------------------------------------------------------------*/
+/* Calculates the Subject Key Identifier of a Dilithium key.  As for EC and   */
+/* RSA keys, this is the SHA-256 hash of the subjectPublicKey value in the    */
+/* SubjectPublicKeyInfo, see DilithiumPublicKey.KeyValue.                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* DilithiumPublicKey pubKey -- the Dilithium public key                      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the calculated subject key identifier                            */
+/*----------------------------------------------------------------------------*/
+func CalculateDilithiumKeyHash(pubKey DilithiumPublicKey) []byte {
+	hash := sha256.Sum256(pubKey.KeyValue())
+	return hash[:]
+}
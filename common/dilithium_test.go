@@ -0,0 +1,135 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Returns a Dilithium public key with recognizable rho and t1 values */
+func dilithiumTestKey(t *testing.T) *common.DilithiumPublicKey {
+	rho := bytes.Repeat([]byte{0x5A}, common.DILITHIUM_R2_87_RHO_LENGTH)
+	t1 := make([]byte, common.DILITHIUM_R2_87_T1_LENGTH)
+	for i := range t1 {
+		t1[i] = byte(i)
+	}
+	key, err := common.NewDilithiumPublicKey(rho, t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+/** Only rho and t1 values of the round 2 (8,7) lengths are accepted */
+func TestNewDilithiumPublicKey(t *testing.T) {
+	rho := make([]byte, common.DILITHIUM_R2_87_RHO_LENGTH)
+	t1 := make([]byte, common.DILITHIUM_R2_87_T1_LENGTH)
+	key, err := common.NewDilithiumPublicKey(rho, t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key holds copies
+	rho[0] = 1
+	if key.Rho[0] != 0 {
+		t.Error("The key shares rho with the caller")
+	}
+
+	if _, err := common.NewDilithiumPublicKey(rho[1:], t1); err == nil {
+		t.Error("A 31-byte rho was accepted")
+	}
+	if _, err := common.NewDilithiumPublicKey(rho, t1[1:]); err == nil {
+		t.Error("A 2303-byte t1 was accepted")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* The SubjectPublicKeyInfo has the dilithium-r2-8-7 algorithm with NULL      */
+/* parameters and a sequence of two bit strings, and the SKI is the SHA-256   */
+/* hash of that sequence                                                      */
+/*----------------------------------------------------------------------------*/
+func TestDilithiumSPKI(t *testing.T) {
+	key := dilithiumTestKey(t)
+	spki := key.SPKI()
+
+	var decoded struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.RawValue
+		}
+		PublicKey asn1.BitString
+	}
+	rest, err := asn1.Unmarshal(spki, &decoded)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("The SPKI could not be decoded: %v", err)
+	}
+	expectedOID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 2, 267, 1, 8, 7}
+	if !decoded.Algorithm.Algorithm.Equal(expectedOID) ||
+		!bytes.Equal(decoded.Algorithm.Parameters.FullBytes, asn1.NullBytes) {
+		t.Errorf("Unexpected algorithm %v", decoded.Algorithm)
+	}
+	if !bytes.Equal(decoded.PublicKey.RightAlign(), key.KeyValue()) {
+		t.Error("The subjectPublicKey is not the key value")
+	}
+
+	var value struct {
+		Rho asn1.BitString
+		T1  asn1.BitString
+	}
+	rest, err = asn1.Unmarshal(key.KeyValue(), &value)
+	if err != nil || len(rest) != 0 || !bytes.Equal(value.Rho.Bytes, key.Rho) ||
+		!bytes.Equal(value.T1.Bytes, key.T1) {
+		t.Errorf("Unexpected key value: %v", err)
+	}
+
+	hash := sha256.Sum256(key.KeyValue())
+	if !bytes.Equal(common.CalculateDilithiumKeyHash(*key), hash[:]) {
+		t.Error("Unexpected Dilithium SKI")
+	}
+
+	parsed, err := common.ParseDilithiumSPKI(spki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Rho, key.Rho) || !bytes.Equal(parsed.T1, key.T1) {
+		t.Error("ParseDilithiumSPKI returned a different key")
+	}
+}
+
+/** Other algorithms, extra data, and short keys are rejected */
+func TestParseDilithiumSPKIErrors(t *testing.T) {
+	key := dilithiumTestKey(t)
+	spki := key.SPKI()
+
+	ecSPKI, err := x509.MarshalPKIXPublicKey(&p521TestKey(t).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortKey := &common.DilithiumPublicKey{Rho: key.Rho, T1: key.T1[1:]}
+
+	badInputs := map[string][]byte{
+		"EC key":     ecSPKI,
+		"extra data": append(append([]byte(nil), spki...), 0x00),
+		"truncated":  spki[:len(spki)-1],
+		"short t1":   shortKey.SPKI(),
+		"empty":      nil,
+	}
+	for name, input := range badInputs {
+		if _, err := common.ParseDilithiumSPKI(input); err == nil {
+			t.Errorf("ParseDilithiumSPKI accepted input: %s", name)
+		}
+	}
+}
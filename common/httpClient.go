@@ -0,0 +1,167 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/rest"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Settings for an HTTP client created by NewHTTPClient.                      */
+/*                                                                            */
+/* Zero values select the defaults: the system certificate pool, the proxy    */
+/* from the HTTPS_PROXY and NO_PROXY environment variables, no overall        */
+/* request timeout, and a 10 second TLS handshake timeout.                    */
+/*----------------------------------------------------------------------------*/
+type HTTPClientConfig struct {
+	RootCAs             *x509.CertPool    // trusted CAs, replaces the system pool
+	CACertFile          string            // PEM file of additional trusted CAs
+	ProxyURL            string            // proxy for all requests
+	ClientCertificates  []tls.Certificate // for mutual TLS
+	Timeout             time.Duration     // limit on each request and response
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration // how long idle connections are kept
+	MaxIdleConnsPerHost int
+}
+
+/** Defaults used for fields left zero in an HTTPClientConfig */
+const (
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConnsPerHost = 4
+)
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTTP client for requests to the TKE REST API and to signing     */
+/* services.                                                                  */
+/*                                                                            */
+/* The client keeps connections open for reuse, so create one client and use  */
+/* it for all requests rather than creating a client for each request.        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* config -- settings for the client                                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *http.Client -- the new client                                             */
+/* error -- reports an unreadable CA file or an invalid proxy URL             */
+/*----------------------------------------------------------------------------*/
+func NewHTTPClient(config HTTPClientConfig) (*http.Client, error) {
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      config.RootCAs,
+		Certificates: config.ClientCertificates,
+	}
+
+	if config.CACertFile != "" {
+		pemBytes, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs, err = x509.SystemCertPool()
+			if err != nil || tlsConfig.RootCAs == nil {
+				tlsConfig.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pemBytes) {
+			return nil, errors.New("No certificates found in CA file " +
+				config.CACertFile)
+		}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, errors.New("Invalid proxy URL " + config.ProxyURL +
+				"\nMessage: " + err.Error())
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: config.TLSHandshakeTimeout,
+		IdleConnTimeout:     config.IdleConnTimeout,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:   true,
+	}
+	if transport.TLSHandshakeTimeout == 0 {
+		transport.TLSHandshakeTimeout = defaultTLSHandshakeTimeout
+	}
+	if transport.IdleConnTimeout == 0 {
+		transport.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+
+	return &http.Client{Transport: transport, Timeout: config.Timeout}, nil
+}
+
+/** Client used for requests that are not given one.  Created when first
+    needed so it is shared by all requests. */
+var defaultHTTPClient *http.Client
+var defaultHTTPClientMutex sync.Mutex
+
+/*----------------------------------------------------------------------------*/
+/* Sets the HTTP client used by requests that are not given a client.  This   */
+/* includes all requests to signing services and the TKE REST API requests of */
+/* functions such as SubmitHTPRequest.  Pass nil to restore the default.      */
+/*----------------------------------------------------------------------------*/
+func SetDefaultHTTPClient(client *http.Client) {
+	defaultHTTPClientMutex.Lock()
+	defer defaultHTTPClientMutex.Unlock()
+	defaultHTTPClient = client
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the HTTP client used by requests that are not given a client       */
+/*----------------------------------------------------------------------------*/
+func DefaultHTTPClient() *http.Client {
+	defaultHTTPClientMutex.Lock()
+	defer defaultHTTPClientMutex.Unlock()
+	if defaultHTTPClient == nil {
+		// Cannot fail with an empty configuration
+		defaultHTTPClient, _ = NewHTTPClient(HTTPClientConfig{})
+	}
+	return defaultHTTPClient
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a REST client that sends requests using an HTTP client, or the     */
+/* default HTTP client if httpClient is nil                                   */
+/*----------------------------------------------------------------------------*/
+func newRESTClient(httpClient *http.Client) *rest.Client {
+	client := rest.NewClient()
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	} else {
+		client.HTTPClient = DefaultHTTPClient()
+	}
+	return client
+}
@@ -0,0 +1,223 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Writes the certificate of a TLS test server to a PEM file */
+func writeServerCA(t *testing.T, server *httptest.Server,
+	dir string) string {
+
+	path := filepath.Join(dir, "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+		Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(path, pemBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+/** A client trusting a CA file reaches a server the default client rejects */
+func TestNewHTTPClientCACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "httpclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client, err := common.NewHTTPClient(common.HTTPClientConfig{
+		CACertFile: writeServerCA(t, server, dir),
+		Timeout:    10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+
+	if _, err = common.DefaultHTTPClient().Get(server.URL); err == nil {
+		t.Error("Default client trusted the test server")
+	}
+}
+
+/** Settings that cannot be used are reported */
+func TestNewHTTPClientErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	notPEM := filepath.Join(dir, "notpem.txt")
+	if err = ioutil.WriteFile(notPEM, []byte("not a certificate"),
+		0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := []common.HTTPClientConfig{
+		{CACertFile: filepath.Join(dir, "missing.pem")},
+		{CACertFile: notPEM},
+		{ProxyURL: "http://[::1"},
+	}
+	for _, config := range configs {
+		if _, err = common.NewHTTPClient(config); err == nil {
+			t.Errorf("Configuration %+v was accepted", config)
+		}
+	}
+
+	client, err := common.NewHTTPClient(common.HTTPClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.TLSHandshakeTimeout == 0 || transport.IdleConnTimeout == 0 ||
+		transport.MaxIdleConnsPerHost == 0 {
+		t.Error("Defaults were not applied")
+	}
+	if transport.TLSClientConfig.MinVersion < tls.VersionTLS12 {
+		t.Error("TLS versions before 1.2 are allowed")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a TLS stand-in for a version 2 signing service holding one P521 EC */
+/* key named key1.                                                            */
+/*----------------------------------------------------------------------------*/
+func newTLSSigningService(t *testing.T) *httptest.Server {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var response interface{}
+			switch r.URL.Path {
+			case "/version":
+				response = map[string]interface{}{"versions": []int{1, 2}}
+			case "/v2/keys/key1":
+				response = map[string]string{
+					"publickey": base64.StdEncoding.EncodeToString(der)}
+			case "/v2/sign/key1":
+				var request map[string]string
+				json.NewDecoder(r.Body).Decode(&request)
+				digest, _ := base64.StdEncoding.DecodeString(
+					request["digest"])
+				r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+				signature, _ := asn1.Marshal(common.ECSignature{R: r, S: s})
+				response = map[string]string{
+					"signature": base64.StdEncoding.EncodeToString(signature)}
+			default:
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+		}))
+}
+
+/** Signing service signers send their requests using the given client */
+func TestSigningServiceSignerWithClient(t *testing.T) {
+	server := newTLSSigningService(t)
+	defer server.Close()
+
+	_, err := common.NewSigningServiceSigner(server.URL, "key1", "token")
+	if err == nil {
+		t.Fatal("Signer was created using the default client")
+	}
+
+	signers := make([]common.Signer, 0)
+	signer, err := common.NewSigningServiceSignerWithClient(server.Client(),
+		server.URL, "key1", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers = append(signers, signer)
+	keyURI := "signsvc://" + strings.TrimPrefix(server.URL, "https://") +
+		"/key1"
+	uriSigner, err := common.NewSignerWithClient(server.Client(), keyURI,
+		"token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers = append(signers, uriSigner)
+
+	data := []byte("data to sign")
+	hash := sha512.Sum512(data)
+	for _, signer := range signers {
+		signature, err := signer.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sequence common.ECSignature
+		if _, err = asn1.Unmarshal(signature, &sequence); err != nil {
+			t.Fatal(err)
+		}
+		publicKey := signer.PublicKey().(*ecdsa.PublicKey)
+		if !ecdsa.Verify(publicKey, hash[:], sequence.R, sequence.S) {
+			t.Error("Signature is not valid")
+		}
+	}
+}
+
+/** IAM token requests use the fallback client when HTTPClient is nil */
+func TestIAMTokenProviderFallbackClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"abc","token_type":"Bearer",` +
+				`"expires_in":3600}`))
+		}))
+	defer server.Close()
+
+	provider := common.NewIAMAPIKeyTokenProvider("apikey")
+	provider.IAMURL = server.URL
+	if _, err := provider.Token(context.Background()); err == nil {
+		t.Fatal("Token was obtained using the default client")
+	}
+	provider.SetFallbackHTTPClient(server.Client())
+	token, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "Bearer abc" {
+		t.Errorf("Token is %q", token)
+	}
+}
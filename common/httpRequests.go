@@ -8,19 +8,43 @@
 // Date          Initials        Description
 // 07/04/2021    CLH             Adapt for TKE SDK
 // 07/23/2021    CLH             Fix URL for private endpoints
+// 10/18/2026    CLH             Add endpoint templates and CheckBaseURL
+// 10/18/2026    CLH             Add signing service version 2 requests
 
 package common
 
 import (
 	"errors"
+	"net/url"
+	"strings"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/rest"
 )
 
+/** Placeholder for the region in an API endpoint template */
+const REGION_PLACEHOLDER = "{region}"
+
 /*----------------------------------------------------------------------------*/
 /* Determines the base URL to use for HTTP requests to the IBM Cloud          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* apiEndPoint -- one of cloud.ibm.com, test.cloud.ibm.com,                   */
+/*    private.cloud.ibm.com, and private.test.cloud.ibm.com, or a URL         */
+/*    template containing {region}, such as                                   */
+/*    https://tke.{region}.hs-crypto.example.com                              */
+/* region -- the region of the service instance, such as us-south             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the base URL, without a trailing slash                           */
+/* error -- reports an unknown API endpoint or an invalid template            */
 /*----------------------------------------------------------------------------*/
 func GetBaseURL(apiEndPoint string, region string) (string, error) {
 
+	if strings.Contains(apiEndPoint, REGION_PLACEHOLDER) {
+		return CheckBaseURL(strings.Replace(apiEndPoint, REGION_PLACEHOLDER,
+			region, -1))
+	}
+
 	if apiEndPoint == "cloud.ibm.com" ||
 		apiEndPoint == "https://cloud.ibm.com" {
 		return "https://tke." + region + ".hs-crypto.cloud.ibm.com", nil
@@ -38,6 +62,38 @@ func GetBaseURL(apiEndPoint string, region string) (string, error) {
 	}
 }
 
+/*----------------------------------------------------------------------------*/
+/* Checks a base URL supplied by the caller, for the TKE REST API or for a    */
+/* signing service.                                                           */
+/*                                                                            */
+/* The URL must be an absolute http or https URL with no query or fragment.   */
+/* It may include a path, for services reached through a gateway.             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* baseURL -- the URL to check                                                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the URL without a trailing slash                                 */
+/* error -- reports an invalid URL                                            */
+/*----------------------------------------------------------------------------*/
+func CheckBaseURL(baseURL string) (string, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", errors.New("Invalid base URL " + baseURL +
+			"\nMessage: " + err.Error())
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") ||
+		parsed.Host == "" {
+		return "", errors.New("Invalid base URL " + baseURL +
+			"\nThe URL must start with https:// or http:// and include a host")
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", errors.New("Invalid base URL " + baseURL +
+			"\nThe URL cannot include a query or fragment")
+	}
+	return strings.TrimRight(baseURL, "/"), nil
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTTP request for querying the domains for a crypto instance.   */
 /*----------------------------------------------------------------------------*/
@@ -94,3 +150,54 @@ func CreateSignDataRequest(sigkeyToken string, urlStart string,
 	req.Body(`{"hash_algorithm":"sha2-512","input":"` + dataToSign + `"}`)
 	return req
 }
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTTP request to a signing service specified by the user to      */
+/* return the versions of the signing service protocol it supports.  Version  */
+/* 1 signing services do not support this request.                            */
+/*----------------------------------------------------------------------------*/
+func CreateGetVersionRequest(sigkeyToken string, urlStart string) *rest.Request {
+
+	url := urlStart + "/version"
+	req := rest.GetRequest(url)
+	req.Set("Content-type", "application/json")
+	req.Set("Authorization", sigkeyToken)
+	return req
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTTP request to a version 2 signing service to return the       */
+/* public part of a signature key                                             */
+/*----------------------------------------------------------------------------*/
+func CreateGetPublicKeyV2Request(sigkeyToken string, urlStart string,
+		sigkey string) *rest.Request {
+
+	reqURL := urlStart + "/v2/keys/" + url.PathEscape(sigkey)
+	req := rest.GetRequest(reqURL)
+	req.Set("Content-type", "application/json")
+	req.Set("Authorization", sigkeyToken)
+	return req
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTTP request to a version 2 signing service to sign a hash.     */
+/* Only the hash is sent, not the data it was calculated from.                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* sigkeyToken -- authentication token for the signature key                  */
+/* urlStart -- base URL for the signing service                               */
+/* sigkey -- identifies the signature key to use                              */
+/* hashAlgorithm -- "sha2-512" for P521 EC keys or "sha2-256" for RSA keys    */
+/* digest -- the base64 encoded hash                                          */
+/*----------------------------------------------------------------------------*/
+func CreateSignDigestRequest(sigkeyToken string, urlStart string,
+		sigkey string, hashAlgorithm string, digest string) *rest.Request {
+
+	reqURL := urlStart + "/v2/sign/" + url.PathEscape(sigkey)
+	req := rest.PostRequest(reqURL)
+	req.Set("Content-type", "application/json")
+	req.Set("Authorization", sigkeyToken)
+	req.Body(`{"hash_algorithm":"` + hashAlgorithm + `","digest":"` +
+		digest + `"}`)
+	return req
+}
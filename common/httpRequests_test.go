@@ -0,0 +1,109 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"os"
+	"testing"
+)
+
+/** Base URLs are built for the IBM Cloud endpoints and for templates */
+func TestGetBaseURL(t *testing.T) {
+	tests := []struct {
+		apiEndpoint string
+		expected    string
+	}{
+		{"cloud.ibm.com", "https://tke.us-south.hs-crypto.cloud.ibm.com"},
+		{"https://cloud.ibm.com",
+			"https://tke.us-south.hs-crypto.cloud.ibm.com"},
+		{"test.cloud.ibm.com",
+			"https://tke.us-south.hs-crypto.test.cloud.ibm.com"},
+		{"private.cloud.ibm.com",
+			"https://tke.private.us-south.hs-crypto.cloud.ibm.com"},
+		{"https://private.test.cloud.ibm.com",
+			"https://tke.private.us-south.hs-crypto.test.cloud.ibm.com"},
+		{"https://tke.{region}.example.com/",
+			"https://tke.us-south.example.com"},
+		{"http://localhost:8080/{region}/tke",
+			"http://localhost:8080/us-south/tke"},
+	}
+	for _, test := range tests {
+		baseURL, err := GetBaseURL(test.apiEndpoint, "us-south")
+		if err != nil || baseURL != test.expected {
+			t.Errorf("%s gave %q %v, expected %q", test.apiEndpoint,
+				baseURL, err, test.expected)
+		}
+	}
+
+	for _, apiEndpoint := range []string{"", "cloud.example.com",
+		"ftp://{region}.example.com", "tke.{region}.example.com"} {
+		if _, err := GetBaseURL(apiEndpoint, "us-south"); err == nil {
+			t.Errorf("Endpoint %q was accepted", apiEndpoint)
+		}
+	}
+}
+
+/** Base URLs must be absolute http or https URLs without a query */
+func TestCheckBaseURL(t *testing.T) {
+	valid := map[string]string{
+		"https://tke.example.com":         "https://tke.example.com",
+		"https://tke.example.com/":        "https://tke.example.com",
+		"http://127.0.0.1:9000/gateway//": "http://127.0.0.1:9000/gateway",
+	}
+	for baseURL, expected := range valid {
+		checked, err := CheckBaseURL(baseURL)
+		if err != nil || checked != expected {
+			t.Errorf("%s gave %q %v, expected %q", baseURL, checked, err,
+				expected)
+		}
+	}
+	for _, baseURL := range []string{"tke.example.com", "https://",
+		"ftp://tke.example.com", "https://tke.example.com/?a=b",
+		"https://tke.example.com/#top", "https://%zz"} {
+		if _, err := CheckBaseURL(baseURL); err == nil {
+			t.Errorf("Base URL %q was accepted", baseURL)
+		}
+	}
+}
+
+/** SetSigningServiceURL overrides the TKE_SIGNSERV_URL variable */
+func TestSigningServiceURL(t *testing.T) {
+	saved, wasSet := os.LookupEnv("TKE_SIGNSERV_URL")
+	defer func() {
+		SetSigningServiceURL("")
+		if wasSet {
+			os.Setenv("TKE_SIGNSERV_URL", saved)
+		} else {
+			os.Unsetenv("TKE_SIGNSERV_URL")
+		}
+	}()
+
+	os.Setenv("TKE_SIGNSERV_URL", "https://env.example.com")
+	if GetSigningServiceURL() != "https://env.example.com" {
+		t.Errorf("URL is %q without an override", GetSigningServiceURL())
+	}
+	if err := SetSigningServiceURL("https://set.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if GetSigningServiceURL() != "https://set.example.com" {
+		t.Errorf("URL is %q with an override", GetSigningServiceURL())
+	}
+	if err := SetSigningServiceURL("set.example.com"); err == nil {
+		t.Error("Invalid signing service URL was accepted")
+	}
+	if GetSigningServiceURL() != "https://set.example.com" {
+		t.Error("Invalid URL replaced the override")
+	}
+	SetSigningServiceURL("")
+	if GetSigningServiceURL() != "https://env.example.com" {
+		t.Error("Clearing the override did not restore the variable")
+	}
+}
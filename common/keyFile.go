@@ -0,0 +1,368 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add version 2 signature key files
+// 10/18/2026    CLH             Pass the key file version explicitly
+
+package common
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Signature key file versions.                                               */
+/*                                                                            */
+/* Version 1 files are written by the TKE CLI plug-in.  The encryption key is */
+/* derived from the password using PBKDF2-SHA256 with 4096 iterations, and    */
+/* the files have no version field.                                           */
+/*                                                                            */
+/* Version 2 files have a version field of "2".  The encryption key is        */
+/* derived using scrypt, with the cost parameters saved in the file, and the  */
+/* other fields are authenticated as AES-GCM additional data.  The TKE CLI    */
+/* plug-in cannot read version 2 files.                                       */
+/*----------------------------------------------------------------------------*/
+const SIGNATURE_KEY_FILE_VERSION_1 = 1
+const SIGNATURE_KEY_FILE_VERSION_2 = 2
+
+/** scrypt cost parameters used for new version 2 signature key files */
+const SCRYPT_N = 1 << 15
+const SCRYPT_R = 8
+const SCRYPT_P = 1
+
+/** Upper limit on the scrypt memory use accepted when reading a file */
+const maxScryptMemory = 1 << 30
+
+/** Fields of a version 2 file authenticated as additional data, in order */
+var keyFileV2AuthenticatedFields = []string{"version", "keyType", "ski",
+	"kdf", "scryptN", "scryptR", "scryptP", "seaSalt"}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the version of a signature key file from its fields.  Files with   */
+/* no version field are version 1.                                            */
+/*----------------------------------------------------------------------------*/
+func SignatureKeyFileVersion(skfields map[string]string) (int, error) {
+	switch skfields["version"] {
+	case "":
+		return SIGNATURE_KEY_FILE_VERSION_1, nil
+	case "2":
+		return SIGNATURE_KEY_FILE_VERSION_2, nil
+	}
+	return 0, errors.New("Unsupported signature key file version: " +
+		skfields["version"])
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the fields of a signature key file.  The fields are:                 */
+/*                                                                            */
+/* keyType -- KEY_TYPE_P521EC, or absent or KEY_TYPE_RSA2048 for RSA keys     */
+/* enckey -- the encrypted signature key, as a hexadecimal string             */
+/* seaSalt -- the salt used to derive the encryption key from the password    */
+/* ski -- the Subject Key Identifier, as a hexadecimal string                 */
+/*                                                                            */
+/* Version 2 files also have version, kdf, scryptN, scryptR, and scryptP      */
+/* fields.  Other fields written by the TKE CLI plug-in are returned          */
+/* unchanged.                                                                 */
+/*----------------------------------------------------------------------------*/
+func ReadSignatureKeyFile(sigkey string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(sigkey)
+	if err != nil {
+		return nil, err
+	}
+	var skfields map[string]string
+	err = json.Unmarshal(data, &skfields)
+	if err != nil {
+		return nil, errors.New("Invalid signature key file " + sigkey +
+			"\nMessage: " + err.Error())
+	}
+	return skfields, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decrypts the signature key from the fields of a signature key file.        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* skfields -- the fields of the signature key file                           */
+/* sigkeyToken -- the file password                                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* crypto.PrivateKey -- an *ecdsa.PrivateKey or an *rsa.PrivateKey            */
+/* error -- reports an invalid password or fields that cannot be decoded      */
+/*----------------------------------------------------------------------------*/
+func DecryptSignatureKey(skfields map[string]string,
+	sigkeyToken string) (crypto.PrivateKey, error) {
+
+	version, err := SignatureKeyFileVersion(skfields)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(skfields["seaSalt"])
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("Invalid signature key file.\nThe seaSalt " +
+			"field is missing or is not a hexadecimal string.")
+	}
+	enckey, err := hex.DecodeString(skfields["enckey"])
+	if err != nil {
+		return nil, errors.New("Invalid signature key file." +
+			"\nMessage: " + err.Error())
+	}
+
+	// Derive the encryption key from the password and decrypt the
+	// signature key
+	var pemBytes []byte
+	if version == SIGNATURE_KEY_FILE_VERSION_1 {
+		aeskey, _ := Derive_aes_key(sigkeyToken, skfields["seaSalt"])
+		pemBytes, err = Decrypt(enckey, aeskey)
+	} else {
+		var aeskey []byte
+		aeskey, err = deriveScryptKey(sigkeyToken, salt, skfields)
+		if err != nil {
+			return nil, err
+		}
+		pemBytes, err = openKeyFile(enckey, aeskey,
+			keyFileAdditionalData(skfields))
+		if err != nil {
+			return nil, errors.New("Invalid password, or the signature key " +
+				"file has been modified.")
+		}
+	}
+	if err != nil {
+		return nil, errors.New("Invalid password.")
+	}
+
+	// Recover the private key
+	pemBlock, _ := pem.Decode(pemBytes)
+	if pemBlock == nil || pemBlock.Type != "PRIVATE KEY" {
+		return nil, errors.New("PEM decode of signature key failed.")
+	}
+	if skfields["keyType"] == KEY_TYPE_P521EC {
+		return x509.ParseECPrivateKey(pemBlock.Bytes)
+	}
+	// Files without the P521 EC key type hold 2048-bit RSA keys
+	return x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encrypts a signature key for a signature key file.  The key is encoded as  */
+/* SEC 1 (EC) or PKCS #1 (RSA) DER in a PEM PRIVATE KEY block, and encrypted  */
+/* using AES-GCM with a key derived from the password and a new random salt.  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- a P521 EC or 2048-bit RSA private key                               */
+/* sigkeyToken -- the file password                                           */
+/* version -- SIGNATURE_KEY_FILE_VERSION_1 or SIGNATURE_KEY_FILE_VERSION_2    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* map[string]string -- the fields of the signature key file                  */
+/* error -- reports an unsupported key or version, or an empty password       */
+/*----------------------------------------------------------------------------*/
+func EncryptSignatureKey(key crypto.PrivateKey, sigkeyToken string,
+	version int) (map[string]string, error) {
+
+	if sigkeyToken == "" {
+		return nil, errors.New("A password is required for a signature " +
+			"key file.")
+	}
+	if version != SIGNATURE_KEY_FILE_VERSION_1 &&
+		version != SIGNATURE_KEY_FILE_VERSION_2 {
+		return nil, errors.New("Unsupported signature key file version: " +
+			strconv.Itoa(version))
+	}
+	signer, err := NewPrivateKeySigner(key)
+	if err != nil {
+		return nil, err
+	}
+	var der []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		der, err = x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+	case *rsa.PrivateKey:
+		der = x509.MarshalPKCS1PrivateKey(k)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if version == SIGNATURE_KEY_FILE_VERSION_1 {
+		aeskey, salt := Derive_aes_key(sigkeyToken, "")
+		enckey, err := Encrypt(pemBytes, aeskey)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"keyType": signer.KeyType(),
+			"enckey":  hex.EncodeToString(enckey),
+			"seaSalt": hex.EncodeToString(salt),
+			"ski":     hex.EncodeToString(signer.SKI()),
+		}, nil
+	}
+
+	salt := make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	skfields := map[string]string{
+		"version": strconv.Itoa(SIGNATURE_KEY_FILE_VERSION_2),
+		"keyType": signer.KeyType(),
+		"ski":     hex.EncodeToString(signer.SKI()),
+		"kdf":     "scrypt",
+		"scryptN": strconv.Itoa(SCRYPT_N),
+		"scryptR": strconv.Itoa(SCRYPT_R),
+		"scryptP": strconv.Itoa(SCRYPT_P),
+		"seaSalt": hex.EncodeToString(salt),
+	}
+	aeskey, err := deriveScryptKey(sigkeyToken, salt, skfields)
+	if err != nil {
+		return nil, err
+	}
+	enckey, err := sealKeyFile(pemBytes, aeskey,
+		keyFileAdditionalData(skfields))
+	if err != nil {
+		return nil, err
+	}
+	skfields["enckey"] = hex.EncodeToString(enckey)
+	return skfields, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Derives the AES key for a version 2 signature key file using scrypt and    */
+/* the cost parameters in the file.  Parameters that would use more than 1    */
+/* GiB of memory are rejected.                                                */
+/*----------------------------------------------------------------------------*/
+func deriveScryptKey(sigkeyToken string, salt []byte,
+	skfields map[string]string) ([]byte, error) {
+
+	if skfields["kdf"] != "scrypt" {
+		return nil, errors.New("Invalid signature key file.\nUnsupported " +
+			"key derivation function: " + skfields["kdf"])
+	}
+	n, errN := strconv.Atoi(skfields["scryptN"])
+	r, errR := strconv.Atoi(skfields["scryptR"])
+	p, errP := strconv.Atoi(skfields["scryptP"])
+	if errN != nil || errR != nil || errP != nil || n < 2 || n&(n-1) != 0 ||
+		r < 1 || p < 1 || r > maxScryptMemory/128/n || p > 16 {
+		return nil, errors.New("Invalid signature key file.\nThe scrypt " +
+			"cost parameters are not valid.")
+	}
+	return scrypt.Key([]byte(sigkeyToken), salt, n, r, p, 32)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the additional data authenticated by AES-GCM in a version 2        */
+/* signature key file.  Each field is represented as name=value followed by a */
+/* newline.                                                                   */
+/*----------------------------------------------------------------------------*/
+func keyFileAdditionalData(skfields map[string]string) []byte {
+	aad := make([]byte, 0)
+	for _, name := range keyFileV2AuthenticatedFields {
+		aad = append(aad, []byte(name+"="+skfields[name]+"\n")...)
+	}
+	return aad
+}
+
+/** Encrypts using AES-GCM, returning a nonce followed by the ciphertext */
+func sealKeyFile(plaintext []byte, aeskey []byte,
+	additionalData []byte) ([]byte, error) {
+
+	blockCipher, err := aes.NewCipher(aeskey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(blockCipher)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+/** Decrypts a nonce followed by ciphertext using AES-GCM */
+func openKeyFile(data []byte, aeskey []byte,
+	additionalData []byte) ([]byte, error) {
+
+	blockCipher, err := aes.NewCipher(aeskey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(blockCipher)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("decrypt: input data shorter than nonce size")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes the fields of a signature key file.  The file is readable only by   */
+/* the owner.  A new file is never written over an existing file.  When       */
+/* replace is true, an existing file is replaced by renaming a temporary file */
+/* over it, so the original is kept if the write fails.                       */
+/*----------------------------------------------------------------------------*/
+func WriteSignatureKeyFile(sigkey string, skfields map[string]string,
+	replace bool) error {
+
+	data, err := json.Marshal(skfields)
+	if err != nil {
+		return err
+	}
+	if !replace {
+		f, err := os.OpenFile(sigkey, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(sigkey)
+		}
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(sigkey), ".sigkey")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), sigkey)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
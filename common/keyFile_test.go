@@ -0,0 +1,239 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Always change the last byte of enckey
+
+package common_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Encrypts using AES-GCM with a zero nonce, as the key files are laid out */
+func sealForTest(t *testing.T, key []byte, plaintext []byte,
+	additionalData []byte) []byte {
+
+	blockCipher, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, _ := cipher.NewGCM(blockCipher)
+	nonce := make([]byte, gcm.NonceSize())
+	return gcm.Seal(nonce, nonce, plaintext, additionalData)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Files built by hand with published key derivation test vectors can be      */
+/* read, which fixes the layout of both versions.  The PBKDF2-SHA256 key for  */
+/* "password" and "salt" with 4096 iterations is the widely published vector; */
+/* the scrypt key is the first 32 bytes of the RFC 7914 vector for            */
+/* "password", "NaCl", N=1024, r=8, p=16.                                     */
+/*----------------------------------------------------------------------------*/
+func TestSignatureKeyFileKnownKeys(t *testing.T) {
+	key := p521TestKey(t)
+	der, _ := x509.MarshalECPrivateKey(key)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	ski := hex.EncodeToString(common.CalculateECKeyHash(key.PublicKey))
+
+	pbkdf2Key, _ := hex.DecodeString("c5e478d59288c841aa530db6845c4c8d" +
+		"962893a001ce4e11a4963873aa98134a")
+	v1 := map[string]string{
+		"keyType": common.KEY_TYPE_P521EC,
+		"seaSalt": hex.EncodeToString([]byte("salt")),
+		"ski":     ski,
+		"enckey": hex.EncodeToString(sealForTest(t, pbkdf2Key, pemBytes,
+			nil)),
+	}
+
+	scryptKey, _ := hex.DecodeString("fdbabe1c9d3472007856e7190d01e9fe" +
+		"7c6ad7cbc8237830e77376634b373162")
+	v2 := map[string]string{
+		"version": "2",
+		"keyType": common.KEY_TYPE_P521EC,
+		"ski":     ski,
+		"kdf":     "scrypt",
+		"scryptN": "1024",
+		"scryptR": "8",
+		"scryptP": "16",
+		"seaSalt": hex.EncodeToString([]byte("NaCl")),
+	}
+	aad := "version=2\nkeyType=" + common.KEY_TYPE_P521EC + "\nski=" + ski +
+		"\nkdf=scrypt\nscryptN=1024\nscryptR=8\nscryptP=16\nseaSalt=" +
+		v2["seaSalt"] + "\n"
+	v2["enckey"] = hex.EncodeToString(sealForTest(t, scryptKey, pemBytes,
+		[]byte(aad)))
+
+	for _, skfields := range []map[string]string{v1, v2} {
+		decrypted, err := common.DecryptSignatureKey(skfields, "password")
+		if err != nil {
+			t.Fatalf("Version %q: %v", skfields["version"], err)
+		}
+		signer, _ := common.NewPrivateKeySigner(decrypted)
+		if signer == nil || hex.EncodeToString(signer.SKI()) != ski {
+			t.Errorf("Version %q decrypted the wrong key", skfields["version"])
+		}
+	}
+}
+
+/** Both versions can be written and read, for EC and RSA keys */
+func TestSignatureKeyFileVersions(t *testing.T) {
+	keys := []interface{}{p521TestKey(t), rsaTestKey(t)}
+	for _, version := range []int{common.SIGNATURE_KEY_FILE_VERSION_1,
+		common.SIGNATURE_KEY_FILE_VERSION_2} {
+
+		for _, key := range keys {
+			skfields, err := common.EncryptSignatureKey(key, "password1",
+				version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileVersion, err := common.SignatureKeyFileVersion(skfields)
+			if err != nil || fileVersion != version {
+				t.Errorf("Wrote version %d, expected %d", fileVersion, version)
+			}
+			if version == common.SIGNATURE_KEY_FILE_VERSION_1 {
+				if _, ok := skfields["version"]; ok {
+					t.Error("Version 1 fields have a version field")
+				}
+			} else if skfields["kdf"] != "scrypt" ||
+				skfields["scryptN"] != "32768" || skfields["scryptR"] != "8" ||
+				skfields["scryptP"] != "1" {
+				t.Errorf("Unexpected version 2 fields %v", skfields)
+			}
+
+			decrypted, err := common.DecryptSignatureKey(skfields, "password1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := common.NewPrivateKeySigner(key)
+			signer, _ := common.NewPrivateKeySigner(decrypted)
+			if signer == nil || !bytes.Equal(signer.SKI(), expected.SKI()) {
+				t.Errorf("Version %d returned the wrong %T", version, key)
+			}
+			if _, err := common.DecryptSignatureKey(skfields,
+				"password2"); err == nil {
+				t.Errorf("Version %d accepted the wrong password", version)
+			}
+		}
+	}
+
+	if _, err := common.EncryptSignatureKey(keys[0], "password1",
+		3); err == nil {
+		t.Error("EncryptSignatureKey accepted version 3")
+	}
+	if _, err := common.EncryptSignatureKey(keys[0], "",
+		common.SIGNATURE_KEY_FILE_VERSION_1); err == nil {
+		t.Error("EncryptSignatureKey accepted an empty password")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* The metadata of version 2 files is authenticated, and cost parameters that */
+/* are not valid are rejected before any key is derived                       */
+/*----------------------------------------------------------------------------*/
+func TestSignatureKeyFileV2Checks(t *testing.T) {
+	key := p521TestKey(t)
+	skfields, err := common.EncryptSignatureKey(key, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSKI := hex.EncodeToString(
+		common.CalculateECKeyHash(p521TestKey(t).PublicKey))
+	enckey, _ := hex.DecodeString(skfields["enckey"])
+	enckey[len(enckey)-1] ^= 0x01
+
+	changes := []struct {
+		name  string
+		value string
+	}{
+		// Authenticated as additional data
+		{"ski", otherSKI},
+		{"keyType", common.KEY_TYPE_RSA2048},
+		{"scryptN", "16384"},
+		{"seaSalt", hex.EncodeToString(make([]byte, 32))},
+		{"enckey", hex.EncodeToString(enckey)},
+		// Not valid
+		{"version", "3"},
+		{"kdf", "argon2id"},
+		{"scryptN", "1000"},
+		{"scryptN", "2097152"}, // 2 GiB with r=8
+		{"scryptR", "0"},
+		{"scryptP", "17"},
+		{"seaSalt", ""},
+		{"enckey", "zz"},
+	}
+	for _, change := range changes {
+		changed := make(map[string]string)
+		for name, value := range skfields {
+			changed[name] = value
+		}
+		changed[change.name] = change.value
+		_, err := common.DecryptSignatureKey(changed, "password1")
+		if err == nil {
+			t.Errorf("A file with %s=%s was accepted", change.name,
+				change.value)
+		}
+	}
+}
+
+/** Files are created only once, or replaced as a whole */
+func TestWriteSignatureKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "admin1.sigkey")
+
+	err = common.WriteSignatureKeyFile(path, map[string]string{"ski": "01"},
+		false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = common.WriteSignatureKeyFile(path, map[string]string{"ski": "02"},
+		false)
+	if err == nil {
+		t.Error("An existing file was written over")
+	}
+	err = common.WriteSignatureKeyFile(path, map[string]string{"ski": "03"},
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skfields, err := common.ReadSignatureKeyFile(path)
+	if err != nil || skfields["ski"] != "03" {
+		t.Errorf("ReadSignatureKeyFile returned %v, %v", skfields, err)
+	}
+	stat, _ := os.Stat(path)
+	if stat.Mode().Perm() != 0600 {
+		t.Errorf("The file has mode %v", stat.Mode().Perm())
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files were left in the directory", len(entries))
+	}
+
+	ioutil.WriteFile(path, []byte("not JSON"), 0600)
+	if _, err := common.ReadSignatureKeyFile(path); err == nil {
+		t.Error("ReadSignatureKeyFile accepted a file that is not JSON")
+	}
+}
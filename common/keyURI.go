@@ -0,0 +1,362 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add vault and vault+http schemes
+// 10/18/2026    CLH             Pass an HTTP client to the built-in schemes
+// 10/18/2026    CLH             Reject Vault key URIs ending with a slash
+// 10/18/2026    CLH             Create a new PKCS #11 signer for each key URI
+
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a signature key identified by a key URI.              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* keyURI -- the full key URI, including the scheme                           */
+/* sigkeyToken -- authentication token for the signature key                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* Signer -- signs using the signature key                                    */
+/* error -- reports an invalid URI or any error accessing the signature key   */
+/*----------------------------------------------------------------------------*/
+type SignerFactory func(keyURI string, sigkeyToken string) (Signer, error)
+
+/** Signer factories for each key URI scheme, see RegisterSignerScheme */
+var signerSchemes = map[string]SignerFactory{
+	"file":         newFileURISigner,
+	"signsvc":      newSigningServiceURISigner,
+	"signsvc+http": newSigningServiceURISigner,
+	"pkcs11":       newPKCS11URISigner,
+	"vault":        newVaultURISigner,
+	"vault+http":   newVaultURISigner,
+}
+var signerSchemesMutex sync.Mutex
+
+/*----------------------------------------------------------------------------*/
+/* Built-in schemes whose signers send HTTP requests, with factories that     */
+/* take the HTTP client to use.  A scheme is removed from this map when       */
+/* RegisterSignerScheme replaces its factory.                                 */
+/*----------------------------------------------------------------------------*/
+var httpSignerSchemes = map[string]func(httpClient *http.Client,
+	keyURI string, sigkeyToken string) (Signer, error){
+	"signsvc":      newSigningServiceURISignerWithClient,
+	"signsvc+http": newSigningServiceURISignerWithClient,
+	"vault":        newVaultURISignerWithClient,
+	"vault+http":   newVaultURISignerWithClient,
+}
+
+/** Matches the scheme of a URI, as defined in RFC 3986 */
+var keyURISchemePattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):`)
+
+/*----------------------------------------------------------------------------*/
+/* Registers a signer factory for a key URI scheme, so that AdminInfo.Key     */
+/* values starting with the scheme are resolved by the factory.  Registering  */
+/* a scheme again replaces the factory, and a nil factory removes the scheme. */
+/*                                                                            */
+/* The schemes file, signsvc, signsvc+http, pkcs11, vault, and vault+http     */
+/* are registered by the TKE SDK.  Schemes are not case sensitive, and must   */
+/* be at least two characters long so that Windows drive letters are not      */
+/* taken as schemes.                                                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* scheme -- the URI scheme, without the colon                                */
+/* factory -- creates signers for key URIs with the scheme                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports an invalid scheme                                         */
+/*----------------------------------------------------------------------------*/
+func RegisterSignerScheme(scheme string, factory SignerFactory) error {
+	if len(scheme) < 2 || !keyURISchemePattern.MatchString(scheme+":") {
+		return errors.New("Invalid key URI scheme: " + scheme)
+	}
+	signerSchemesMutex.Lock()
+	defer signerSchemesMutex.Unlock()
+	delete(httpSignerSchemes, strings.ToLower(scheme))
+	if factory == nil {
+		delete(signerSchemes, strings.ToLower(scheme))
+	} else {
+		signerSchemes[strings.ToLower(scheme)] = factory
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the scheme of a signature key identified by a registered key URI   */
+/* scheme, in lower case, or "" if the key does not start with a registered   */
+/* scheme.                                                                    */
+/*----------------------------------------------------------------------------*/
+func KeyURIScheme(sigkey string) string {
+	match := keyURISchemePattern.FindStringSubmatch(sigkey)
+	if match == nil || len(match[1]) < 2 {
+		return ""
+	}
+	scheme := strings.ToLower(match[1])
+	signerSchemesMutex.Lock()
+	defer signerSchemesMutex.Unlock()
+	if _, ok := signerSchemes[scheme]; !ok {
+		return ""
+	}
+	return scheme
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a key URI, or returns false if the key does not start */
+/* with a registered scheme.  Keys of the form scheme://... with a scheme     */
+/* that is not registered are reported as errors rather than being taken as   */
+/* file names or signing service key names.  Signers for the built-in signsvc */
+/* and vault schemes send their requests using httpClient, or                 */
+/* DefaultHTTPClient if httpClient is nil.                                    */
+/*----------------------------------------------------------------------------*/
+func newKeyURISigner(httpClient *http.Client, sigkey string,
+	sigkeyToken string) (Signer, bool, error) {
+
+	match := keyURISchemePattern.FindStringSubmatch(sigkey)
+	if match == nil || len(match[1]) < 2 {
+		return nil, false, nil
+	}
+	scheme := strings.ToLower(match[1])
+	signerSchemesMutex.Lock()
+	factory := signerSchemes[scheme]
+	httpFactory := httpSignerSchemes[scheme]
+	signerSchemesMutex.Unlock()
+	if httpFactory != nil {
+		signer, err := httpFactory(httpClient, sigkey, sigkeyToken)
+		return signer, true, err
+	}
+	if factory == nil {
+		if strings.HasPrefix(sigkey[len(match[0]):], "//") {
+			return nil, true, errors.New("No signer is registered for key " +
+				"URI scheme " + scheme + ".")
+		}
+		return nil, false, nil
+	}
+	signer, err := factory(sigkey, sigkeyToken)
+	return signer, true, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the path of the signature key file identified by a file URI, such  */
+/* as file:///keys/admin1.sigkey.  The host must be empty or localhost.       */
+/*----------------------------------------------------------------------------*/
+func ParseFileKeyURI(keyURI string) (string, error) {
+	parsed, err := url.Parse(keyURI)
+	if err == nil && !strings.EqualFold(parsed.Scheme, "file") {
+		err = errors.New("the scheme is not file")
+	}
+	if err == nil && parsed.Host != "" && parsed.Host != "localhost" {
+		err = errors.New("only local files can be used")
+	}
+	if err == nil && parsed.Path == "" {
+		err = errors.New("no file is specified")
+	}
+	if err == nil && (parsed.RawQuery != "" || parsed.Fragment != "") {
+		err = errors.New("the URI cannot include a query or fragment")
+	}
+	if err != nil {
+		return "", errors.New("Invalid signature key URI " + keyURI +
+			"\nMessage: " + err.Error())
+	}
+	return parsed.Path, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signing service URL and key name for a signature key held by a */
+/* signing service.  The key name is the last segment of the path, and the    */
+/* rest of the URI is the signing service URL:                                */
+/*                                                                            */
+/*   signsvc://signer.example.com:8443/base/admin1                            */
+/*     -- https://signer.example.com:8443/base and key admin1                 */
+/*   signsvc+http://localhost:8080/admin1                                     */
+/*     -- http://localhost:8080 and key admin1                                */
+/*----------------------------------------------------------------------------*/
+func ParseSigningServiceKeyURI(keyURI string) (string, string, error) {
+	parsed, err := url.Parse(keyURI)
+	var ssURL, sigkey string
+	if err == nil {
+		switch strings.ToLower(parsed.Scheme) {
+		case "signsvc":
+			parsed.Scheme = "https"
+		case "signsvc+http":
+			parsed.Scheme = "http"
+		default:
+			err = errors.New("the scheme is not signsvc or signsvc+http")
+		}
+	}
+	if err == nil {
+		i := strings.LastIndex(parsed.Path, "/")
+		if i < 0 || i == len(parsed.Path)-1 {
+			err = errors.New("no key name is specified")
+		} else {
+			sigkey = parsed.Path[i+1:]
+			parsed.Path = parsed.Path[0:i]
+			parsed.RawPath = ""
+			ssURL, err = CheckBaseURL(parsed.String())
+		}
+	}
+	if err != nil {
+		return "", "", errors.New("Invalid signature key URI " + keyURI +
+			"\nMessage: " + err.Error())
+	}
+	return ssURL, sigkey, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Vault configuration for a signature key held by the transit    */
+/* secrets engine of a Vault server.  The key name is the last segment of the */
+/* path and the rest of the path is the transit mount path:                   */
+/*                                                                            */
+/*   vault://vault.example.com:8200/transit/admin1                            */
+/*     -- key admin1 of the transit engine mounted at transit, using https    */
+/*   vault+http://localhost:8200/admin1?role_id=1234                          */
+/*     -- key admin1 of the default transit mount, using http and AppRole     */
+/*                                                                            */
+/* The query may set namespace, version (the key version), role_id (to use    */
+/* AppRole authentication), and approle_mount.  The token for the key is the  */
+/* Vault token, or the AppRole secret ID if role_id is set.  The Token and    */
+/* SecretID fields are not set by this function.                              */
+/*----------------------------------------------------------------------------*/
+func ParseVaultKeyURI(keyURI string) (VaultConfig, error) {
+	var config VaultConfig
+	parsed, err := url.Parse(keyURI)
+	if err == nil {
+		switch strings.ToLower(parsed.Scheme) {
+		case "vault":
+			parsed.Scheme = "https"
+		case "vault+http":
+			parsed.Scheme = "http"
+		default:
+			err = errors.New("the scheme is not vault or vault+http")
+		}
+	}
+	if err == nil && parsed.Fragment != "" {
+		err = errors.New("the URI cannot include a fragment")
+	}
+	var query url.Values
+	if err == nil {
+		query, err = url.ParseQuery(parsed.RawQuery)
+	}
+	if err == nil {
+		for name := range query {
+			switch name {
+			case "namespace", "version", "role_id", "approle_mount":
+			default:
+				err = errors.New("unknown query parameter " + name)
+			}
+		}
+	}
+	if err == nil && query.Get("version") != "" {
+		config.KeyVersion, err = strconv.Atoi(query.Get("version"))
+		if err == nil && config.KeyVersion < 1 {
+			err = errors.New("the key version must be a positive number")
+		}
+	}
+	if err == nil {
+		path := strings.TrimPrefix(parsed.Path, "/")
+		i := strings.LastIndex(path, "/")
+		config.KeyName = path[i+1:]
+		if i >= 0 {
+			config.Mount = path[0:i]
+		}
+		if config.KeyName == "" {
+			err = errors.New("no key name is specified")
+		}
+	}
+	if err == nil {
+		config.Address, err = CheckBaseURL(parsed.Scheme + "://" + parsed.Host)
+	}
+	if err != nil {
+		return config, errors.New("Invalid signature key URI " + keyURI +
+			"\nMessage: " + err.Error())
+	}
+	config.Namespace = query.Get("namespace")
+	config.RoleID = query.Get("role_id")
+	config.AppRoleMount = query.Get("approle_mount")
+	return config, nil
+}
+
+/** Creates a signer for a file URI */
+func newFileURISigner(keyURI string, sigkeyToken string) (Signer, error) {
+	path, err := ParseFileKeyURI(keyURI)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewKeyFileSigner(path, sigkeyToken)
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+/** Creates a signer for a signsvc or signsvc+http URI */
+func newSigningServiceURISigner(keyURI string,
+	sigkeyToken string) (Signer, error) {
+
+	return newSigningServiceURISignerWithClient(nil, keyURI, sigkeyToken)
+}
+
+/** Creates a signer for a signsvc or signsvc+http URI using an HTTP client */
+func newSigningServiceURISignerWithClient(httpClient *http.Client,
+	keyURI string, sigkeyToken string) (Signer, error) {
+
+	ssURL, sigkey, err := ParseSigningServiceKeyURI(keyURI)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewSigningServiceSignerWithClient(httpClient, ssURL, sigkey,
+		sigkeyToken)
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+/** Creates a signer for a PKCS #11 URI */
+func newPKCS11URISigner(keyURI string, sigkeyToken string) (Signer, error) {
+	signer, err := NewPKCS11Signer(keyURI, sigkeyToken)
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+/** Creates a signer for a vault or vault+http URI */
+func newVaultURISigner(keyURI string, sigkeyToken string) (Signer, error) {
+	return newVaultURISignerWithClient(nil, keyURI, sigkeyToken)
+}
+
+/** Creates a signer for a vault or vault+http URI using an HTTP client */
+func newVaultURISignerWithClient(httpClient *http.Client, keyURI string,
+	sigkeyToken string) (Signer, error) {
+
+	config, err := ParseVaultKeyURI(keyURI)
+	if err != nil {
+		return nil, err
+	}
+	config.HTTPClient = httpClient
+	if config.RoleID != "" {
+		config.SecretID = sigkeyToken
+	} else {
+		config.Token = sigkeyToken
+	}
+	signer, err := NewVaultSigner(config)
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
@@ -0,0 +1,255 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Only registered schemes of two or more characters are key URI schemes */
+func TestKeyURIScheme(t *testing.T) {
+	tests := []struct {
+		sigkey string
+		scheme string
+	}{
+		{"file:///keys/admin1.sigkey", "file"},
+		{"FILE:///keys/admin1.sigkey", "file"},
+		{"signsvc://signer.example.com/admin1", "signsvc"},
+		{"signsvc+http://localhost:8080/admin1", "signsvc+http"},
+		{"pkcs11:token=tke;object=admin1", "pkcs11"},
+		{"vault://vault.example.com:8200/transit/admin1", "vault"},
+		{"vault+http://localhost:8200/admin1", "vault+http"},
+		{`C:\keys\admin1.sigkey`, ""},
+		{"/keys/admin1.sigkey", ""},
+		{"admin1", ""},
+		{"unknown://host/admin1", ""},
+	}
+	for _, test := range tests {
+		if scheme := common.KeyURIScheme(test.sigkey); scheme != test.scheme {
+			t.Errorf("KeyURIScheme(%s) returned %q, expected %q", test.sigkey,
+				scheme, test.scheme)
+		}
+	}
+}
+
+/** File URIs name local files */
+func TestParseFileKeyURI(t *testing.T) {
+	for keyURI, expected := range map[string]string{
+		"file:///keys/admin1.sigkey":          "/keys/admin1.sigkey",
+		"file://localhost/keys/admin1.sigkey": "/keys/admin1.sigkey",
+		"file:///keys/admin%201.sigkey":       "/keys/admin 1.sigkey",
+	} {
+		path, err := common.ParseFileKeyURI(keyURI)
+		if err != nil || path != expected {
+			t.Errorf("ParseFileKeyURI(%s) returned %q, %v", keyURI, path, err)
+		}
+	}
+	for _, keyURI := range []string{
+		"file://server/keys/admin1.sigkey",
+		"file://",
+		"file:///keys/admin1.sigkey?x=1",
+		"file:///keys/admin1.sigkey#x",
+		"signsvc://localhost/admin1",
+	} {
+		if _, err := common.ParseFileKeyURI(keyURI); err == nil {
+			t.Errorf("ParseFileKeyURI accepted %s", keyURI)
+		}
+	}
+}
+
+/** The last segment of a signing service key URI is the key name */
+func TestParseSigningServiceKeyURI(t *testing.T) {
+	tests := []struct {
+		keyURI string
+		ssURL  string
+		sigkey string
+	}{
+		{"signsvc://signer.example.com:8443/base/admin1",
+			"https://signer.example.com:8443/base", "admin1"},
+		{"signsvc+http://localhost:8080/admin1", "http://localhost:8080",
+			"admin1"},
+		{"SIGNSVC://signer.example.com/a/b/admin1",
+			"https://signer.example.com/a/b", "admin1"},
+	}
+	for _, test := range tests {
+		ssURL, sigkey, err := common.ParseSigningServiceKeyURI(test.keyURI)
+		if err != nil || ssURL != test.ssURL || sigkey != test.sigkey {
+			t.Errorf("ParseSigningServiceKeyURI(%s) returned %q, %q, %v",
+				test.keyURI, ssURL, sigkey, err)
+		}
+	}
+	for _, keyURI := range []string{
+		"signsvc://signer.example.com",
+		"signsvc://signer.example.com/base/",
+		"signsvc:///admin1",
+		"signsvc://signer.example.com/admin1?x=1",
+		"file:///admin1",
+	} {
+		if _, _, err := common.ParseSigningServiceKeyURI(keyURI); err == nil {
+			t.Errorf("ParseSigningServiceKeyURI accepted %s", keyURI)
+		}
+	}
+}
+
+/** Vault key URIs give the mount, key name, and authentication settings */
+func TestParseVaultKeyURI(t *testing.T) {
+	config, err := common.ParseVaultKeyURI("vault://vault.example.com:8200/" +
+		"team/transit/admin1?namespace=ns1&version=3&role_id=1234&" +
+		"approle_mount=tke-approle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := common.VaultConfig{Address: "https://vault.example.com:8200",
+		Namespace: "ns1", Mount: "team/transit", KeyName: "admin1",
+		KeyVersion: 3, RoleID: "1234", AppRoleMount: "tke-approle"}
+	if config != expected {
+		t.Errorf("ParseVaultKeyURI returned %+v, expected %+v", config,
+			expected)
+	}
+
+	config, err = common.ParseVaultKeyURI("vault+http://localhost:8200/admin1")
+	if err != nil || config.Address != "http://localhost:8200" ||
+		config.Mount != "" || config.KeyName != "admin1" ||
+		config.KeyVersion != 0 || config.RoleID != "" {
+		t.Errorf("ParseVaultKeyURI returned %+v, %v", config, err)
+	}
+
+	for _, keyURI := range []string{
+		"vault://vault.example.com:8200",
+		"vault://vault.example.com:8200/transit/",
+		"vault:///admin1",
+		"vault://vault.example.com/admin1?version=0",
+		"vault://vault.example.com/admin1?version=x",
+		"vault://vault.example.com/admin1?token=s.1234",
+		"vault://vault.example.com/admin1#x",
+		"signsvc://vault.example.com/admin1",
+	} {
+		if _, err := common.ParseVaultKeyURI(keyURI); err == nil {
+			t.Errorf("ParseVaultKeyURI accepted %s", keyURI)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Key URIs choose the backend for each key, so a key file and a signing      */
+/* service key can be used together whether or not TKE_SIGNSERV_URL is set    */
+/*----------------------------------------------------------------------------*/
+func TestNewSignerMixedBackends(t *testing.T) {
+	defer withoutSigningService(t)()
+	dir, err := ioutil.TempDir("", "keyuri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileKey := rsaTestKey(t)
+	path := filepath.Join(dir, "admin1.sigkey")
+	skfields, err := common.EncryptSignatureKey(fileKey, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = common.WriteSignatureKeyFile(path, skfields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss := &testSigningService{key: p521TestKey(t), token: "Bearer token1"}
+	server := httptest.NewServer(ss)
+	defer server.Close()
+	ssKeyURI := "signsvc+http://" + server.Listener.Addr().String() + "/key1"
+
+	for _, ssURL := range []string{"", server.URL} {
+		err = common.SetSigningServiceURL(ssURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileSigner, err := common.NewSigner("file://"+path, "password1")
+		if err != nil {
+			t.Fatalf("Signing service URL %q: %v", ssURL, err)
+		}
+		if fileSigner.KeyType() != common.KEY_TYPE_RSA2048 {
+			t.Errorf("The file URI returned a %s signer", fileSigner.KeyType())
+		}
+		ssSigner, err := common.NewSigner(ssKeyURI, "Bearer token1")
+		if err != nil {
+			t.Fatalf("Signing service URL %q: %v", ssURL, err)
+		}
+		if _, ok := ssSigner.(*common.SigningServiceSigner); !ok ||
+			ssSigner.KeyType() != common.KEY_TYPE_P521EC {
+			t.Errorf("The signsvc URI returned a %T", ssSigner)
+		}
+		signature, err := common.SignWithSignatureKey([]byte("data"), ssKeyURI,
+			"Bearer token1")
+		if err != nil || !common.VerifySignature(ssSigner.PublicKey(),
+			[]byte("data"), signature) {
+			t.Errorf("SignWithSignatureKey using the signsvc URI: %v", err)
+		}
+	}
+	common.SetSigningServiceURL("")
+}
+
+/** Applications can add schemes of their own, and remove them */
+func TestRegisterSignerScheme(t *testing.T) {
+	key := p521TestKey(t)
+	var received []string
+	factory := func(keyURI string, sigkeyToken string) (common.Signer, error) {
+		received = append(received, keyURI, sigkeyToken)
+		if keyURI == "tkeapp:missing" {
+			return nil, errors.New("No such key")
+		}
+		return common.NewPrivateKeySigner(key)
+	}
+	err := common.RegisterSignerScheme("TKEApp", factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.RegisterSignerScheme("tkeapp", nil)
+
+	signer, err := common.NewSigner("tkeapp:admin1", "token1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := common.CalculateECKeyHash(key.PublicKey)
+	if !bytes.Equal(signer.SKI(), expected) {
+		t.Errorf("The registered factory was not used: SKI %X", signer.SKI())
+	}
+	if len(received) != 2 || received[0] != "tkeapp:admin1" ||
+		received[1] != "token1" {
+		t.Errorf("The factory received %v", received)
+	}
+	if _, err := common.NewSigner("tkeapp:missing", "token1"); err == nil {
+		t.Error("An error from the factory was not returned")
+	}
+
+	// Unregistered schemes with an authority are not taken as file names
+	common.RegisterSignerScheme("tkeapp", nil)
+	if common.KeyURIScheme("tkeapp:admin1") != "" {
+		t.Error("The scheme was not removed")
+	}
+	if _, err := common.NewSigner("tkeapp://host/admin1", "token1"); err == nil {
+		t.Error("A key URI with an unregistered scheme was accepted")
+	}
+
+	for _, scheme := range []string{"", "c", "1abc", "bad scheme", "a/b"} {
+		if common.RegisterSignerScheme(scheme, factory) == nil {
+			t.Errorf("RegisterSignerScheme accepted scheme %q", scheme)
+		}
+	}
+}
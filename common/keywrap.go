@@ -0,0 +1,114 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             AES key wrap for encrypted key parts
+
+package common
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+/** Default initial value for AES key wrap, from RFC 3394 */
+var AES_KEY_WRAP_IV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+/*----------------------------------------------------------------------------*/
+/* Wraps a key using the AES key wrap algorithm defined in RFC 3394.          */
+/*                                                                            */
+/* This is the aes256-wrap algorithm used to encrypt key parts in the         */
+/* RecipientInfo structures processed by Export WK and Import WK.             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte kek -- the AES key encrypting key                                   */
+/* []byte key -- the key to be wrapped, a multiple of 8 bytes in length       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the wrapped key, 8 bytes longer than the input key               */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func AESKeyWrap(kek []byte, key []byte) ([]byte, error) {
+
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, errors.New("Invalid length of key to be wrapped")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	a := make([]byte, 8)
+	copy(a, AES_KEY_WRAP_IV)
+	r := make([]byte, len(key))
+	copy(r, key)
+
+	buffer := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buffer[0:8], a)
+			copy(buffer[8:16], r[i*8:i*8+8])
+			block.Encrypt(buffer, buffer)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buffer[0:8])^t)
+			copy(r[i*8:i*8+8], buffer[8:16])
+		}
+	}
+
+	wrapped := make([]byte, 0, len(key)+8)
+	wrapped = append(wrapped, a...)
+	wrapped = append(wrapped, r...)
+	return wrapped, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Unwraps a key using the AES key wrap algorithm defined in RFC 3394.        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte kek -- the AES key encrypting key                                   */
+/* []byte wrapped -- the wrapped key                                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the unwrapped key, 8 bytes shorter than the wrapped key          */
+/* error -- reports any errors, including an integrity check failure          */
+/*----------------------------------------------------------------------------*/
+func AESKeyUnwrap(kek []byte, wrapped []byte) ([]byte, error) {
+
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errors.New("Invalid length of wrapped key")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[0:8])
+	r := make([]byte, len(wrapped)-8)
+	copy(r, wrapped[8:])
+
+	buffer := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(buffer[0:8], binary.BigEndian.Uint64(a)^t)
+			copy(buffer[8:16], r[i*8:i*8+8])
+			block.Decrypt(buffer, buffer)
+			copy(a, buffer[0:8])
+			copy(r[i*8:i*8+8], buffer[8:16])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, AES_KEY_WRAP_IV) != 1 {
+		return nil, errors.New("Integrity check failed when unwrapping key")
+	}
+	return r, nil
+}
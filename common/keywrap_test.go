@@ -0,0 +1,99 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+/** Test vectors from section 4 of RFC 3394 */
+var keyWrapVectors = []struct {
+	name    string
+	kek     string
+	key     string
+	wrapped string
+}{
+	{"4.1 128-bit data with a 128-bit KEK",
+		"000102030405060708090A0B0C0D0E0F",
+		"00112233445566778899AABBCCDDEEFF",
+		"1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+	{"4.3 128-bit data with a 256-bit KEK",
+		"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		"00112233445566778899AABBCCDDEEFF",
+		"64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+	{"4.6 256-bit data with a 256-bit KEK",
+		"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+		"00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+		"28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43B" +
+			"FB988B9B7A02DD21"},
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+/** AESKeyWrap and AESKeyUnwrap match the RFC 3394 known answers */
+func TestAESKeyWrapVectors(t *testing.T) {
+	for _, v := range keyWrapVectors {
+		kek := mustDecodeHex(t, v.kek)
+		key := mustDecodeHex(t, v.key)
+		expected := mustDecodeHex(t, v.wrapped)
+
+		wrapped, err := AESKeyWrap(kek, key)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if !bytes.Equal(wrapped, expected) {
+			t.Errorf("%s: wrapped key is %X, expected %X", v.name, wrapped,
+				expected)
+		}
+		unwrapped, err := AESKeyUnwrap(kek, expected)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Errorf("%s: unwrapped key is %X, expected %X", v.name,
+				unwrapped, key)
+		}
+	}
+}
+
+/** Changed wrapped keys and invalid lengths are rejected */
+func TestAESKeyUnwrapRejects(t *testing.T) {
+	v := keyWrapVectors[2]
+	kek := mustDecodeHex(t, v.kek)
+	wrapped := mustDecodeHex(t, v.wrapped)
+	for i := range wrapped {
+		changed := append([]byte{}, wrapped...)
+		changed[i] ^= 0x01
+		if _, err := AESKeyUnwrap(kek, changed); err == nil {
+			t.Fatalf("Unwrap succeeded with byte %d changed", i)
+		}
+	}
+	if _, err := AESKeyUnwrap(kek, wrapped[:16]); err == nil {
+		t.Error("Unwrap accepted a 16-byte input")
+	}
+	if _, err := AESKeyUnwrap(kek, wrapped[:len(wrapped)-1]); err == nil {
+		t.Error("Unwrap accepted an input that is not a multiple of 8 bytes")
+	}
+	if _, err := AESKeyWrap(kek, make([]byte, 8)); err == nil {
+		t.Error("Wrap accepted an 8-byte key")
+	}
+	if _, err := AESKeyWrap(kek[:7], make([]byte, 16)); err == nil {
+		t.Error("Wrap accepted an invalid key encrypting key")
+	}
+}
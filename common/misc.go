@@ -8,6 +8,8 @@
 // Date          Initials        Description
 // 04/30/2021    CLH             Modify for TKE SDK
 // 01/09/2025    CLH             Set last four bytes of VP to zero
+// 10/18/2026    CLH             Accept more public key formats from signing services
+// 10/18/2026    CLH             Read signing service public keys with a given client
 
 package common
 
@@ -19,13 +21,13 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/asn1"
-	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"golang.org/x/crypto/pbkdf2"
 	"io"
 	"math/big"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -377,7 +379,8 @@ func IsBitSet(data []byte, bitnum int) bool {
 }
 
 /*----------------------------------------------------------------------------*/
-/* Gets the public key from a signing service                                 */
+/* Gets the public key from a signing service using version 1 of the          */
+/* signing service protocol                                                   */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* string -- base URL for the signing service                                 */
@@ -391,54 +394,47 @@ func IsBitSet(data []byte, bitnum int) bool {
 /* error -- reports any error encountered during processing                   */
 /*----------------------------------------------------------------------------*/
 func GetPublicKeyFromSigningService(ssURL string, sigkey string, sigkeyToken string) ([]byte, error) {
+	return getPublicKeyFromSigningService(nil, ssURL, sigkey, sigkeyToken)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as GetPublicKeyFromSigningService, but sends the request using        */
+/* httpClient, or the default HTTP client if httpClient is nil                */
+/*----------------------------------------------------------------------------*/
+func getPublicKeyFromSigningService(httpClient *http.Client, ssURL string,
+	sigkey string, sigkeyToken string) ([]byte, error) {
 
 	// Create dummy public key for error return
 	rtnkey := make([]byte, 0)
 
 	// Get the public key from the signing service
 	req := CreateGetPublicKeyRequest(sigkeyToken, ssURL, sigkey)
-	pubkey, err := SubmitQueryPublicKeyRequest(req)
+	pubkey, err := submitQueryPublicKeyRequest(httpClient, req)
 	if err != nil {
 		return rtnkey, err
 	}
 
-	// Decode the public key
-	decodedKey, err := base64.StdEncoding.DecodeString(pubkey)
+	// Decode the public key.  The signing service may return it in any of
+	// the forms accepted by ParsePublicKey.
+	key, err := ParsePublicKey(pubkey)
 	if err != nil {
 		return rtnkey, err
 	}
 
-	// When a signing service is used, only P521 EC signature keys are
-	// supported.
-
-	// Check that the decoded key is an ASN.1 sequence with expected fields.
-	// Reformat if needed so X and Y coordinates are each 66 bytes long.
-	var ecpubkey ECPublicKey
-	_, err = asn1.Unmarshal(decodedKey, &ecpubkey)
-	if err != nil {
-		return rtnkey, err // invalid ASN.1 sequence
+	// When a version 1 signing service is used, only P521 EC signature keys
+	// are supported.
+	ecpubkey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return rtnkey, errors.New("Invalid public key returned by signing service.  Only P521 EC keys are supported.")
 	}
 
+	// Format the key so X and Y coordinates are each 66 bytes long.
 	var publicKey [133]byte
 	publicKey[0] = 0x04
-	bytes := ecpubkey.X.Bytes()
-	length := len(bytes)
-	if length > 66 {
-		return rtnkey, errors.New("Invalid public key returned by signing service.  Only P521 EC keys are supported.")
-	}
-	// copy the X coordinate
-	for i:=0; i<length; i++ {
-		publicKey[1 + (66 - length) + i] = bytes[i]
-	}
-	bytes = ecpubkey.Y.Bytes()
-	length = len(bytes)
-	if length > 66 {
-		return rtnkey, errors.New("Invalid public key returned by signing service.  Only P521 EC keys are supported.")
-	}
-	// copy the Y coordinate
-	for i:=0; i<length; i++ {
-		publicKey[1 + 66 + (66 - length) + i] = bytes[i]
-	}
+	x := ecpubkey.X.Bytes()
+	copy(publicKey[1+(66-len(x)):67], x)
+	y := ecpubkey.Y.Bytes()
+	copy(publicKey[67+(66-len(y)):133], y)
 
 	return publicKey[:], nil
 }
@@ -0,0 +1,75 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+/*----------------------------------------------------------------------------*/
+/* OA certificate chains normally end with a certificate signed by one of the */
+/* IBM root keys hard-coded in the ep11cmds functions that verify them.  A    */
+/* crypto module emulator signs its OA certificates with a root key it        */
+/* generates itself.  Attaching that root key to the transport used to reach  */
+/* the emulator lets its OA certificate chains be verified.  The root key is  */
+/* trusted only for requests sent using that transport.                       */
+/*----------------------------------------------------------------------------*/
+
+/** A P521 EC key accepted at the top of an OA certificate chain */
+type OARootKey struct {
+	SKI []byte // as in the signer SKI field of the topmost OA certificate
+	X   []byte // x coordinate of the public key, 66 bytes
+	Y   []byte // y coordinate of the public key, 66 bytes
+}
+
+/*----------------------------------------------------------------------------*/
+/* Implemented by transports that trust OA root keys besides the IBM root     */
+/* keys                                                                       */
+/*----------------------------------------------------------------------------*/
+type OARootKeyHolder interface {
+	GetOARootKeys() []OARootKey
+}
+
+/** Transport with OA root keys attached, see WithOARootKeys */
+type oaRootKeyTransport struct {
+	Transport
+	keys []OARootKey
+}
+
+func (t oaRootKeyTransport) GetOARootKeys() []OARootKey {
+	return t.keys
+}
+
+/** Keeps the retry policy of the wrapped transport visible */
+func (t oaRootKeyTransport) GetRetryPolicy() RetryPolicy {
+	return GetRetryPolicy(t.Transport)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Attaches OA root keys to a transport.                                      */
+/*                                                                            */
+/* The returned transport sends each request exactly as tr does.  OA          */
+/* certificate chains of the crypto modules reached using it may end with a   */
+/* certificate signed by one of the keys, in addition to the IBM root keys    */
+/* and any root keys already attached to tr.  Only attach root keys from      */
+/* sources you trust, such as Emulator.OARootKey.                             */
+/*----------------------------------------------------------------------------*/
+func WithOARootKeys(tr Transport, keys ...OARootKey) Transport {
+	all := append([]OARootKey(nil), GetOARootKeys(tr)...)
+	return oaRootKeyTransport{Transport: tr, keys: append(all, keys...)}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the OA root keys attached to a transport, or nil if it has none.   */
+/*----------------------------------------------------------------------------*/
+func GetOARootKeys(tr Transport) []OARootKey {
+	holder, ok := tr.(OARootKeyHolder)
+	if ok {
+		return holder.GetOARootKeys()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/*----------------------------------------------------------------------------*/
+/* The OA certificate chains of an emulator reached over HTTP are only        */
+/* accepted by a transport with its root key attached, and attaching the root */
+/* key of one emulator does not make another emulator trusted.                */
+/*----------------------------------------------------------------------------*/
+func TestWithOARootKeys(t *testing.T) {
+	em := newTestEmulator(t)
+	defer em.Close()
+	other := newTestEmulator(t)
+	defer other.Close()
+	server := httptest.NewServer(em)
+	defer server.Close()
+	tr := common.NewHTTPTransport("Bearer token", server.URL)
+
+	_, err := tkesdk.Query(tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: tr})
+	if err == nil || !strings.Contains(err.Error(), "root key") {
+		t.Errorf("Query without the root key returned %v", err)
+	}
+	_, err = tkesdk.Query(tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: common.WithOARootKeys(tr, other.OARootKey())})
+	if err == nil || !strings.Contains(err.Error(), "root key") {
+		t.Errorf("Query with the root key of another emulator returned %v",
+			err)
+	}
+
+	// The root keys stay attached when other options are added
+	wrapped := common.WithRetryPolicy(common.WithRateLimiter(
+		common.WithOARootKeys(tr, em.OARootKey()),
+		common.NewRateLimiter(0, 0)), common.DefaultRetryPolicy())
+	keys := common.GetOARootKeys(wrapped)
+	if len(keys) != 1 || !common.ByteSlicesAreEqual(keys[0].SKI,
+		em.OARootKey().SKI) {
+		t.Errorf("GetOARootKeys returned %v", keys)
+	}
+	wrapped = common.WithOARootKeys(wrapped, other.OARootKey())
+	if len(common.GetOARootKeys(wrapped)) != 2 {
+		t.Error("WithOARootKeys dropped the root keys already attached")
+	}
+	if common.GetRetryPolicy(wrapped).MaxAttempts !=
+		common.DefaultRetryPolicy().MaxAttempts {
+		t.Error("WithOARootKeys hid the retry policy")
+	}
+	hsminfo, err := tkesdk.Query(tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: wrapped})
+	if err != nil || len(hsminfo) != 2 {
+		t.Errorf("Query with the root key returned %v %v", hsminfo, err)
+	}
+	if common.GetOARootKeys(tr) != nil {
+		t.Error("The HTTP transport has root keys of its own")
+	}
+}
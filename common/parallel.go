@@ -0,0 +1,237 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Treat a rate of 0 or less as no limit
+// 10/18/2026    CLH             Keep the OA root keys of wrapped transports
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Runs a set of tasks, with at most parallelism tasks running at once.       */
+/*                                                                            */
+/* Tasks are started in index order.  Once a task fails no more tasks are     */
+/* started, but tasks already running are allowed to finish.  Since every     */
+/* task with a lower index has already been started, the error returned is    */
+/* always the error of the failing task with the lowest index: the same error */
+/* the tasks would report if they were run one after another.                 */
+/*                                                                            */
+/* Tasks must not depend on each other, and must store their results by       */
+/* index so the order of the results does not depend on timing.              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- no more tasks are started once ctx is cancelled                     */
+/* parallelism -- maximum number of tasks to run at once.  Values below 2     */
+/*    run the tasks one after another in the calling goroutine.               */
+/* count -- the number of tasks                                               */
+/* task -- runs the task with the given index, 0 to count - 1                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- the error of the failing task with the lowest index, or ctx.Err() */
+/*    if ctx was cancelled before all tasks were started                      */
+/*----------------------------------------------------------------------------*/
+func RunParallel(ctx context.Context, parallelism int, count int,
+	task func(index int) error) error {
+
+	if parallelism < 2 || count < 2 {
+		for i := 0; i < count; i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err := task(i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, count)
+	var failed bool
+	var failedMutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+
+	var startErr error
+	for i := 0; i < count; i++ {
+		// Wait for a free slot
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			startErr = ctx.Err()
+		}
+		if startErr != nil {
+			break
+		}
+		failedMutex.Lock()
+		stop := failed
+		failedMutex.Unlock()
+		if stop {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			err := task(index)
+			if err != nil {
+				errs[index] = err
+				failedMutex.Lock()
+				failed = true
+				failedMutex.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return startErr
+}
+
+/*----------------------------------------------------------------------------*/
+/* Limits the rate at which requests are sent.                                */
+/*                                                                            */
+/* Requests are allowed at an average of rate per second, with bursts of up   */
+/* to burst requests.  One limiter can be shared by several transports and    */
+/* goroutines to limit their combined rate.                                   */
+/*----------------------------------------------------------------------------*/
+type RateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration // time to earn one request, 0 for no limit
+	burst    int
+	tokens   float64
+	last     time.Time
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a rate limiter.                                                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* rate -- average number of requests allowed per second.  A rate of 0 or     */
+/*    less places no limit on requests.                                       */
+/* burst -- number of requests that can be sent at once after a quiet period, */
+/*    values below 1 are treated as 1                                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *RateLimiter -- the new rate limiter                                       */
+/*----------------------------------------------------------------------------*/
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	// An interval of 0 means requests are never delayed
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+	return &RateLimiter{
+		interval: interval,
+		burst:    burst,
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Waits until a request is allowed.  Returns ctx.Err() if ctx is cancelled   */
+/* or its deadline passes first.                                              */
+/*----------------------------------------------------------------------------*/
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	// Reserve a request, waiting for it to be earned if necessary
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens * float64(l.interval))
+	}
+	l.mutex.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the reservation
+		l.mutex.Lock()
+		l.tokens++
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+/** Transport that waits for a rate limiter before each request */
+type rateLimitedTransport struct {
+	Transport
+	limiter *RateLimiter
+}
+
+func (t rateLimitedTransport) QueryDomains(ctx context.Context,
+	cryptoInstance string) ([]string, []string, []string, []string, error) {
+
+	err := t.limiter.Wait(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return t.Transport.QueryDomains(ctx, cryptoInstance)
+}
+
+func (t rateLimitedTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	err := t.limiter.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	return t.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+		htpRequest)
+}
+
+/** Keeps the retry policy of the wrapped transport visible */
+func (t rateLimitedTransport) GetRetryPolicy() RetryPolicy {
+	return GetRetryPolicy(t.Transport)
+}
+
+/** Keeps the OA root keys of the wrapped transport trusted */
+func (t rateLimitedTransport) GetOARootKeys() []OARootKey {
+	return GetOARootKeys(t.Transport)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a transport that waits for a rate limiter before sending each      */
+/* request using tr.  A retry policy and OA root keys attached to tr remain   */
+/* in effect.                                                                 */
+/*----------------------------------------------------------------------------*/
+func WithRateLimiter(tr Transport, limiter *RateLimiter) Transport {
+	return rateLimitedTransport{Transport: tr, limiter: limiter}
+}
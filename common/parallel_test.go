@@ -0,0 +1,191 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Every task runs once and no more than parallelism run at once */
+func TestRunParallel(t *testing.T) {
+	for _, parallelism := range []int{0, 1, 3, 20} {
+		var mutex sync.Mutex
+		running, maxRunning := 0, 0
+		results := make([]int, 10)
+		err := common.RunParallel(context.Background(), parallelism, 10,
+			func(index int) error {
+				mutex.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mutex.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				results[index] = index + 1
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return nil
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, result := range results {
+			if result != i+1 {
+				t.Errorf("Task %d did not run with parallelism %d", i,
+					parallelism)
+			}
+		}
+		limit := parallelism
+		if limit < 1 {
+			limit = 1
+		}
+		if maxRunning > limit {
+			t.Errorf("%d tasks ran at once with parallelism %d", maxRunning,
+				parallelism)
+		}
+	}
+}
+
+/** The error returned is the one a sequential run would return */
+func TestRunParallelError(t *testing.T) {
+	for _, parallelism := range []int{1, 4} {
+		err := common.RunParallel(context.Background(), parallelism, 8,
+			func(index int) error {
+				// Later failures finish first
+				time.Sleep(time.Duration(8-index) * time.Millisecond)
+				if index >= 2 {
+					return errors.New("task " + strconv.Itoa(index))
+				}
+				return nil
+			})
+		if err == nil || err.Error() != "task 2" {
+			t.Errorf("RunParallel returned %v with parallelism %d, "+
+				"expected task 2", err, parallelism)
+		}
+	}
+}
+
+/** No tasks are started once the context is cancelled */
+func TestRunParallelCancelled(t *testing.T) {
+	for _, parallelism := range []int{1, 2} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var mutex sync.Mutex
+		started := 0
+		err := common.RunParallel(ctx, parallelism, 10,
+			func(index int) error {
+				mutex.Lock()
+				started++
+				mutex.Unlock()
+				if index == 1 {
+					cancel()
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+		if err != context.Canceled {
+			t.Errorf("RunParallel returned %v with parallelism %d, "+
+				"expected context.Canceled", err, parallelism)
+		}
+		if started == 10 {
+			t.Errorf("All tasks started after cancellation with "+
+				"parallelism %d", parallelism)
+		}
+		cancel()
+	}
+}
+
+/** A burst is allowed at once and later requests are paced */
+func TestRateLimiter(t *testing.T) {
+	limiter := common.NewRateLimiter(100, 3)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("Burst of 3 requests took %v", elapsed)
+	}
+	for i := 0; i < 5; i++ {
+		err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Five more requests at 100 per second need about 50ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("8 requests took %v, expected about 50ms", elapsed)
+	}
+}
+
+/** A cancelled wait returns the context error */
+func TestRateLimiterCancelled(t *testing.T) {
+	limiter := common.NewRateLimiter(0.1, 1)
+	limiter.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Wait returned %v, expected context.DeadlineExceeded", err)
+	}
+}
+
+/** A rate of 0 or less places no limit on requests */
+func TestRateLimiterNoLimit(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		limiter := common.NewRateLimiter(rate, 0)
+		start := time.Now()
+		for i := 0; i < 1000; i++ {
+			err := limiter.Wait(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("1000 requests took %v with rate %v", elapsed, rate)
+		}
+	}
+}
+
+/** WithRateLimiter paces requests and keeps the retry policy */
+func TestWithRateLimiter(t *testing.T) {
+	em := newTestEmulator(t)
+	defer em.Close()
+
+	policy := common.RetryPolicy{MaxAttempts: 2}
+	tr := common.WithRateLimiter(common.WithRetryPolicy(em, policy),
+		common.NewRateLimiter(100, 1))
+	if common.GetRetryPolicy(tr).MaxAttempts != 2 {
+		t.Error("WithRateLimiter hid the retry policy")
+	}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, _, _, _, err := tr.QueryDomains(context.Background(), "instance1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("4 queries took %v, expected about 30ms", elapsed)
+	}
+}
@@ -0,0 +1,499 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add Dilithium signature keys
+// 10/18/2026    CLH             Call the token through pkcs11Functions
+
+// +build cgo
+
+package common
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+/** PKCS #11 libraries that have been loaded and initialized */
+var pkcs11Modules = make(map[string]*pkcs11.Ctx)
+var pkcs11ModulesMutex sync.Mutex
+
+/** DER encoding of the object identifier for the P521 curve (secp521r1) */
+var p521CurveParams, _ = asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 35})
+
+/** IBM vendor defined values for Dilithium keys, as used by openCryptoki */
+const (
+	CKK_IBM_PQC_DILITHIUM     = pkcs11.CKK_VENDOR_DEFINED + 0x10023
+	CKM_IBM_DILITHIUM         = pkcs11.CKM_VENDOR_DEFINED + 0x10023
+	CKA_IBM_DILITHIUM_KEYFORM = pkcs11.CKA_VENDOR_DEFINED + 0xd0001
+	CKA_IBM_DILITHIUM_RHO     = pkcs11.CKA_VENDOR_DEFINED + 0xd0002
+	CKA_IBM_DILITHIUM_T1      = pkcs11.CKA_VENDOR_DEFINED + 0xd0008
+
+	CK_IBM_DILITHIUM_KEYFORM_ROUND2_87 = 2
+)
+
+/*----------------------------------------------------------------------------*/
+/* The PKCS #11 functions a PKCS11Signer calls once its session is open.      */
+/* Implemented by *pkcs11.Ctx.  Lets the tests stand in for tokens with       */
+/* mechanisms SoftHSM does not support, such as CKM_IBM_DILITHIUM.            */
+/*----------------------------------------------------------------------------*/
+type pkcs11Functions interface {
+	Login(sh pkcs11.SessionHandle, userType uint, pin string) error
+	GetAttributeValue(sh pkcs11.SessionHandle, o pkcs11.ObjectHandle,
+		a []*pkcs11.Attribute) ([]*pkcs11.Attribute, error)
+	FindObjectsInit(sh pkcs11.SessionHandle, temp []*pkcs11.Attribute) error
+	FindObjects(sh pkcs11.SessionHandle,
+		max int) ([]pkcs11.ObjectHandle, bool, error)
+	FindObjectsFinal(sh pkcs11.SessionHandle) error
+	SignInit(sh pkcs11.SessionHandle, m []*pkcs11.Mechanism,
+		o pkcs11.ObjectHandle) error
+	Sign(sh pkcs11.SessionHandle, message []byte) ([]byte, error)
+	CloseSession(sh pkcs11.SessionHandle) error
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signer for a P521 EC, 2048-bit RSA, or Dilithium round 2 (8,7) private     */
+/* key held in a PKCS #11 token.  The private key never leaves the token.     */
+/*                                                                            */
+/* A PKCS11Signer keeps a session open with the token.  Sign may be called    */
+/* from several goroutines; calls are serialized.                             */
+/*----------------------------------------------------------------------------*/
+type PKCS11Signer struct {
+	ctx       pkcs11Functions
+	session   pkcs11.SessionHandle
+	key       pkcs11.ObjectHandle
+	keyType   string
+	publicKey crypto.PublicKey
+	ski       []byte
+	mutex     sync.Mutex
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a private key in a PKCS #11 token.  The PKCS #11      */
+/* library is loaded, a session is opened with the token, and the user is     */
+/* logged in when the signer is created.                                      */
+/*                                                                            */
+/* For a P521 EC key or a Dilithium key, the token must also hold the         */
+/* matching public key object with the same CKA_ID, or the same CKA_LABEL if  */
+/* the private key has no CKA_ID, since the public key is read from it.       */
+/* Dilithium keys use the IBM vendor defined key type CKK_IBM_PQC_DILITHIUM   */
+/* with the round 2 (8,7) key form, as supported by the EP11 token of         */
+/* openCryptoki.                                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* uri -- PKCS #11 URI identifying the token and the private key.  See        */
+/*    ParsePKCS11URI.                                                         */
+/* pin -- the user PIN for the token.  If "", the pin-value attribute of the  */
+/*    URI is used, and if there is none the user is not logged in.            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *PKCS11Signer -- signs using the private key in the token                  */
+/* error -- reports any error finding or accessing the key                    */
+/*----------------------------------------------------------------------------*/
+func NewPKCS11Signer(uri string, pin string) (*PKCS11Signer, error) {
+	p, err := ParsePKCS11URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if pin == "" {
+		pin = p.PinValue
+	}
+	ctx, err := loadPKCS11Module(p.ModulePath)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := findPKCS11Token(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, errors.New("Error opening a session with the PKCS #11 " +
+			"token.\nMessage: " + err.Error())
+	}
+	s := &PKCS11Signer{ctx: ctx, session: session}
+	err = s.open(p, pin)
+	if err != nil {
+		ctx.CloseSession(session)
+		return nil, err
+	}
+	return s, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Logs in to the token, finds the private key, and reads the public key.     */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) open(p *PKCS11URI, pin string) error {
+	if pin != "" {
+		err := s.ctx.Login(s.session, pkcs11.CKU_USER, pin)
+		// The login state is shared by all sessions with the token
+		if err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+			return errors.New("Error logging in to the PKCS #11 token.\n" +
+				"Message: " + err.Error())
+		}
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+	}
+	if p.Object != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, p.Object))
+	}
+	if len(p.ID) > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, p.ID))
+	}
+	key, err := s.findObject(template, "private key")
+	if err != nil {
+		return err
+	}
+	s.key = key
+
+	attrs, err := s.ctx.GetAttributeValue(s.session, key, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+	})
+	if err != nil {
+		return errors.New("Error reading the attributes of the PKCS #11 " +
+			"private key.\nMessage: " + err.Error())
+	}
+	// CK_ULONG values are in native byte order, so compare encoded values
+	keyType := attrs[0].Value
+	switch {
+	case bytes.Equal(keyType, pkcs11KeyType(pkcs11.CKK_EC)):
+		return s.readECPublicKey(attrs[1].Value, attrs[2].Value)
+	case bytes.Equal(keyType, pkcs11KeyType(pkcs11.CKK_RSA)):
+		return s.readRSAPublicKey()
+	case bytes.Equal(keyType, pkcs11KeyType(CKK_IBM_PQC_DILITHIUM)):
+		return s.readDilithiumPublicKey(attrs[1].Value, attrs[2].Value)
+	default:
+		return errors.New("Only P521 EC keys, 2048-bit RSA keys, and " +
+			"Dilithium keys can be used as signature keys.  The PKCS #11 " +
+			"private key is not an EC, RSA, or Dilithium key.")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the public key object matching a private key, found by CKA_ID or,  */
+/* if the private key has no CKA_ID, by CKA_LABEL.                            */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) findPublicKey(id []byte,
+	label []byte) (pkcs11.ObjectHandle, error) {
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+	}
+	if len(id) > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, id))
+	} else {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, label))
+	}
+	return s.findObject(template, "public key")
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the public key for a P521 EC private key from the matching public    */
+/* key object.                                                                */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) readECPublicKey(id []byte, label []byte) error {
+	pubkey, err := s.findPublicKey(id, label)
+	if err != nil {
+		return err
+	}
+	attrs, err := s.ctx.GetAttributeValue(s.session, pubkey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return errors.New("Error reading the attributes of the PKCS #11 " +
+			"public key.\nMessage: " + err.Error())
+	}
+	if !bytes.Equal(attrs[0].Value, p521CurveParams) {
+		return errors.New("The PKCS #11 EC key is not on the P521 curve.  " +
+			"Only P521 EC keys and 2048-bit RSA keys can be used as " +
+			"signature keys.")
+	}
+
+	// CKA_EC_POINT is a DER OCTET STRING holding the uncompressed point,
+	// but some libraries return the point without the OCTET STRING
+	point := attrs[1].Value
+	var inner []byte
+	rest, err := asn1.Unmarshal(point, &inner)
+	if err == nil && len(rest) == 0 {
+		point = inner
+	}
+	x, y := elliptic.Unmarshal(elliptic.P521(), point)
+	if x == nil {
+		return errors.New("Invalid CKA_EC_POINT in the PKCS #11 public key.")
+	}
+
+	ecKey := ecdsa.PublicKey{Curve: elliptic.P521(), X: x, Y: y}
+	s.keyType = KEY_TYPE_P521EC
+	s.publicKey = &ecKey
+	s.ski = CalculateECKeyHash(ecKey)
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the public key for a 2048-bit RSA private key                        */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) readRSAPublicKey() error {
+	attrs, err := s.ctx.GetAttributeValue(s.session, s.key, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil {
+		return errors.New("Error reading the attributes of the PKCS #11 " +
+			"private key.\nMessage: " + err.Error())
+	}
+	rsaKey := rsa.PublicKey{
+		N: new(big.Int).SetBytes(attrs[0].Value),
+		E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+	}
+	// Administrator certificates assume a public exponent of 65537
+	if rsaKey.N.BitLen() != 2048 || rsaKey.E != 65537 {
+		return errors.New("Only P521 EC keys and 2048-bit RSA keys " +
+			"with a public exponent of 65537 can be used as signature keys.")
+	}
+	ski, err := CalculateRSAKeyHash(rsaKey)
+	if err != nil {
+		return err
+	}
+	s.keyType = KEY_TYPE_RSA2048
+	s.publicKey = &rsaKey
+	s.ski = ski
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the public key for a Dilithium private key from the matching public  */
+/* key object.  Only the round 2 (8,7) key form is supported.                 */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) readDilithiumPublicKey(id []byte, label []byte) error {
+	pubkey, err := s.findPublicKey(id, label)
+	if err != nil {
+		return err
+	}
+	attrs, err := s.ctx.GetAttributeValue(s.session, pubkey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(CKA_IBM_DILITHIUM_KEYFORM, nil),
+		pkcs11.NewAttribute(CKA_IBM_DILITHIUM_RHO, nil),
+		pkcs11.NewAttribute(CKA_IBM_DILITHIUM_T1, nil),
+	})
+	if err != nil {
+		return errors.New("Error reading the attributes of the PKCS #11 " +
+			"public key.\nMessage: " + err.Error())
+	}
+	keyForm := pkcs11.NewAttribute(CKA_IBM_DILITHIUM_KEYFORM,
+		CK_IBM_DILITHIUM_KEYFORM_ROUND2_87).Value
+	if !bytes.Equal(attrs[0].Value, keyForm) {
+		return errors.New("The PKCS #11 Dilithium key is not a round 2 " +
+			"(8,7) key.  Only round 2 (8,7) Dilithium keys can be used as " +
+			"signature keys.")
+	}
+	dilithiumKey, err := NewDilithiumPublicKey(attrs[1].Value, attrs[2].Value)
+	if err != nil {
+		return err
+	}
+	s.keyType = KEY_TYPE_DILITHIUM_R2_87
+	s.publicKey = dilithiumKey
+	s.ski = CalculateDilithiumKeyHash(*dilithiumKey)
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Finds the one object matching a template.  Reports an error if there is no */
+/* matching object or more than one.                                          */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) findObject(template []*pkcs11.Attribute,
+	description string) (pkcs11.ObjectHandle, error) {
+
+	err := s.ctx.FindObjectsInit(s.session, template)
+	if err != nil {
+		return 0, errors.New("Error searching the PKCS #11 token.\n" +
+			"Message: " + err.Error())
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 2)
+	s.ctx.FindObjectsFinal(s.session)
+	if err != nil {
+		return 0, errors.New("Error searching the PKCS #11 token.\n" +
+			"Message: " + err.Error())
+	}
+	if len(objects) == 0 {
+		return 0, errors.New("The " + description + " was not found in the " +
+			"PKCS #11 token.")
+	}
+	if len(objects) > 1 {
+		return 0, errors.New("More than one " + description + " in the " +
+			"PKCS #11 token matches the URI.")
+	}
+	return objects[0], nil
+}
+
+/** Returns the Subject Key Identifier of the private key */
+func (s *PKCS11Signer) SKI() []byte {
+	return s.ski
+}
+
+/** Returns KEY_TYPE_P521EC, KEY_TYPE_RSA2048, or KEY_TYPE_DILITHIUM_R2_87 */
+func (s *PKCS11Signer) KeyType() string {
+	return s.keyType
+}
+
+/** Returns the public key read from the token */
+func (s *PKCS11Signer) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using the private key in the token.  The hash is calculated     */
+/* locally.  EC keys sign it using CKM_ECDSA.  For RSA keys, the ANSI X9.31   */
+/* formatted hash is signed using CKM_RSA_X_509.  Dilithium keys sign the     */
+/* data itself using CKM_IBM_DILITHIUM.  See Signer.Sign for the format of    */
+/* the signature.                                                             */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) Sign(data []byte) ([]byte, error) {
+	var mechanism uint
+	var input []byte
+	if s.keyType == KEY_TYPE_DILITHIUM_R2_87 {
+		mechanism = CKM_IBM_DILITHIUM
+		input = data
+	} else if s.keyType == KEY_TYPE_P521EC {
+		hash := sha512.Sum512(data)
+		mechanism = pkcs11.CKM_ECDSA
+		input = hash[:]
+	} else {
+		hash := sha256.Sum256(data)
+		mechanism = pkcs11.CKM_RSA_X_509
+		input = PadANSIX931(hash[:], 0, len(hash), 2048)
+	}
+
+	s.mutex.Lock()
+	err := s.ctx.SignInit(s.session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, s.key)
+	var signature []byte
+	if err == nil {
+		signature, err = s.ctx.Sign(s.session, input)
+	}
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, errors.New("Error signing with the PKCS #11 token.\n" +
+			"Message: " + err.Error())
+	}
+
+	if s.keyType == KEY_TYPE_DILITHIUM_R2_87 {
+		if len(signature) != DILITHIUM_R2_87_SIGNATURE_LENGTH {
+			return nil, errors.New("Invalid Dilithium signature returned by " +
+				"the PKCS #11 token.")
+		}
+		return signature, nil
+	}
+	if s.keyType == KEY_TYPE_P521EC {
+		// CKM_ECDSA returns R followed by S, each the size of the curve order
+		if len(signature) == 0 || len(signature)%2 != 0 {
+			return nil, errors.New("Invalid EC signature returned by the " +
+				"PKCS #11 token.")
+		}
+		half := len(signature) / 2
+		return asn1.Marshal(ECSignature{
+			R: new(big.Int).SetBytes(signature[0:half]),
+			S: new(big.Int).SetBytes(signature[half:]),
+		})
+	}
+	if len(signature) < 256 {
+		signature = append(make([]byte, 256-len(signature)), signature...)
+	}
+	return signature, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Closes the session with the token.  The signer cannot be used afterwards.  */
+/* The PKCS #11 library remains loaded for use by other signers.              */
+/*----------------------------------------------------------------------------*/
+func (s *PKCS11Signer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.ctx.CloseSession(s.session)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Loads and initializes a PKCS #11 library, or returns the library if it has */
+/* already been loaded.  Libraries are not finalized, since signers for keys  */
+/* in different tokens may share them.                                        */
+/*----------------------------------------------------------------------------*/
+func loadPKCS11Module(modulePath string) (*pkcs11.Ctx, error) {
+	pkcs11ModulesMutex.Lock()
+	defer pkcs11ModulesMutex.Unlock()
+	if ctx, ok := pkcs11Modules[modulePath]; ok {
+		return ctx, nil
+	}
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, errors.New("Unable to load the PKCS #11 library " +
+			modulePath)
+	}
+	err := ctx.Initialize()
+	if err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, errors.New("Error initializing the PKCS #11 library " +
+			modulePath + "\nMessage: " + err.Error())
+	}
+	pkcs11Modules[modulePath] = ctx
+	return ctx, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the slot of the one token matching the URI                         */
+/*----------------------------------------------------------------------------*/
+func findPKCS11Token(ctx *pkcs11.Ctx, p *PKCS11URI) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, errors.New("Error listing PKCS #11 tokens.\nMessage: " +
+			err.Error())
+	}
+	matches := make([]uint, 0)
+	for _, slot := range slots {
+		if p.SlotID >= 0 && slot != uint(p.SlotID) {
+			continue
+		}
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			continue
+		}
+		if (p.Token == "" || p.Token == info.Label) &&
+			(p.Manufacturer == "" || p.Manufacturer == info.ManufacturerID) &&
+			(p.Serial == "" || p.Serial == info.SerialNumber) &&
+			(p.Model == "" || p.Model == info.Model) {
+			matches = append(matches, slot)
+		}
+	}
+	if len(matches) == 0 {
+		return 0, errors.New("No PKCS #11 token matches the URI.")
+	}
+	if len(matches) > 1 {
+		return 0, errors.New("More than one PKCS #11 token matches the URI.  " +
+			"Add a token or serial attribute to the URI.")
+	}
+	return matches[0], nil
+}
+
+/** Returns the encoded CKA_KEY_TYPE attribute value for a key type */
+func pkcs11KeyType(keyType uint) []byte {
+	return pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, keyType).Value
+}
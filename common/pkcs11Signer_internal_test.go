@@ -0,0 +1,181 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+// +build cgo
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Token holding a Dilithium key pair, standing in for a token that supports  */
+/* CKM_IBM_DILITHIUM.  Object 1 is the private key and object 2 the public    */
+/* key.  Records the mechanism and input of the last signature.               */
+/*----------------------------------------------------------------------------*/
+type dilithiumToken struct {
+	keyForm   uint
+	rho       []byte
+	t1        []byte
+	signature []byte
+	template  []*pkcs11.Attribute
+	mechanism uint
+	signed    []byte
+}
+
+func (d *dilithiumToken) Login(sh pkcs11.SessionHandle, userType uint,
+	pin string) error {
+
+	return nil
+}
+
+func (d *dilithiumToken) GetAttributeValue(sh pkcs11.SessionHandle,
+	o pkcs11.ObjectHandle,
+	a []*pkcs11.Attribute) ([]*pkcs11.Attribute, error) {
+
+	values := map[uint]interface{}{
+		pkcs11.CKA_ID:             []byte{0x01},
+		pkcs11.CKA_LABEL:          []byte("dilithium1"),
+		pkcs11.CKA_KEY_TYPE:       CKK_IBM_PQC_DILITHIUM,
+		CKA_IBM_DILITHIUM_KEYFORM: d.keyForm,
+		CKA_IBM_DILITHIUM_RHO:     d.rho,
+		CKA_IBM_DILITHIUM_T1:      d.t1,
+	}
+	attrs := make([]*pkcs11.Attribute, len(a))
+	for i, attr := range a {
+		value, ok := values[attr.Type]
+		if !ok {
+			return nil, pkcs11.Error(pkcs11.CKR_ATTRIBUTE_TYPE_INVALID)
+		}
+		attrs[i] = pkcs11.NewAttribute(attr.Type, value)
+	}
+	return attrs, nil
+}
+
+func (d *dilithiumToken) FindObjectsInit(sh pkcs11.SessionHandle,
+	temp []*pkcs11.Attribute) error {
+
+	d.template = temp
+	return nil
+}
+
+func (d *dilithiumToken) FindObjects(sh pkcs11.SessionHandle,
+	max int) ([]pkcs11.ObjectHandle, bool, error) {
+
+	class := d.template[0].Value
+	if bytes.Equal(class, pkcs11.NewAttribute(pkcs11.CKA_CLASS,
+		pkcs11.CKO_PRIVATE_KEY).Value) {
+		return []pkcs11.ObjectHandle{1}, false, nil
+	}
+	return []pkcs11.ObjectHandle{2}, false, nil
+}
+
+func (d *dilithiumToken) FindObjectsFinal(sh pkcs11.SessionHandle) error {
+	return nil
+}
+
+func (d *dilithiumToken) SignInit(sh pkcs11.SessionHandle,
+	m []*pkcs11.Mechanism, o pkcs11.ObjectHandle) error {
+
+	if o != 1 {
+		return errors.New("not the private key")
+	}
+	d.mechanism = m[0].Mechanism
+	return nil
+}
+
+func (d *dilithiumToken) Sign(sh pkcs11.SessionHandle,
+	message []byte) ([]byte, error) {
+
+	d.signed = message
+	return d.signature, nil
+}
+
+func (d *dilithiumToken) CloseSession(sh pkcs11.SessionHandle) error {
+	return nil
+}
+
+/** Returns a token holding a valid round 2 (8,7) Dilithium key pair */
+func newDilithiumToken() *dilithiumToken {
+	return &dilithiumToken{
+		keyForm:   CK_IBM_DILITHIUM_KEYFORM_ROUND2_87,
+		rho:       bytes.Repeat([]byte{0x5A}, DILITHIUM_R2_87_RHO_LENGTH),
+		t1:        bytes.Repeat([]byte{0xA5}, DILITHIUM_R2_87_T1_LENGTH),
+		signature: bytes.Repeat([]byte{0x3C}, DILITHIUM_R2_87_SIGNATURE_LENGTH),
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A Dilithium key in a PKCS #11 token signs the data itself with             */
+/* CKM_IBM_DILITHIUM, and its SKI is the hash of the public key               */
+/*----------------------------------------------------------------------------*/
+func TestPKCS11SignerDilithium(t *testing.T) {
+	token := newDilithiumToken()
+	s := &PKCS11Signer{ctx: token, session: 1}
+	if err := s.open(&PKCS11URI{Object: "dilithium1"}, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyType() != KEY_TYPE_DILITHIUM_R2_87 {
+		t.Errorf("Key type is %s", s.KeyType())
+	}
+	publicKey, err := NewDilithiumPublicKey(token.rho, token.t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.SKI(), CalculateDilithiumKeyHash(*publicKey)) {
+		t.Error("The SKI is not the hash of the Dilithium public key")
+	}
+
+	data := []byte("administrative command")
+	signature, err := s.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.mechanism != CKM_IBM_DILITHIUM {
+		t.Errorf("Signed with mechanism %#x", token.mechanism)
+	}
+	if !bytes.Equal(token.signed, data) {
+		t.Error("The token did not sign the data itself")
+	}
+	if !bytes.Equal(signature, token.signature) {
+		t.Error("The signature returned by the token was changed")
+	}
+	if !CheckDilithiumSignatureLength(signature) {
+		t.Error("The signature does not have the Dilithium length")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Dilithium keys other than round 2 (8,7) and signatures with the wrong      */
+/* length are rejected                                                        */
+/*----------------------------------------------------------------------------*/
+func TestPKCS11SignerDilithiumErrors(t *testing.T) {
+	token := newDilithiumToken()
+	token.keyForm = 1
+	s := &PKCS11Signer{ctx: token, session: 1}
+	if err := s.open(&PKCS11URI{Object: "dilithium1"}, ""); err == nil {
+		t.Error("A Dilithium key that is not round 2 (8,7) was accepted")
+	}
+
+	token = newDilithiumToken()
+	token.signature = token.signature[1:]
+	s = &PKCS11Signer{ctx: token, session: 1}
+	if err := s.open(&PKCS11URI{Object: "dilithium1"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Sign([]byte("data")); err == nil {
+		t.Error("A signature with the wrong length was accepted")
+	}
+}
@@ -0,0 +1,62 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+// +build !cgo
+
+package common
+
+import (
+	"crypto"
+	"errors"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Signer for a private key held in a PKCS #11 token.  PKCS #11 libraries are */
+/* loaded using cgo, so programs built with CGO_ENABLED=0 cannot create one.  */
+/*----------------------------------------------------------------------------*/
+type PKCS11Signer struct {
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports that PKCS #11 tokens are not supported without cgo                 */
+/*----------------------------------------------------------------------------*/
+func NewPKCS11Signer(uri string, pin string) (*PKCS11Signer, error) {
+	_, err := ParsePKCS11URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return nil, errors.New("PKCS #11 signature keys are not supported.  " +
+		"The program was built without cgo.")
+}
+
+/** Not used, since a PKCS11Signer cannot be created */
+func (s *PKCS11Signer) SKI() []byte {
+	return nil
+}
+
+/** Not used, since a PKCS11Signer cannot be created */
+func (s *PKCS11Signer) KeyType() string {
+	return ""
+}
+
+/** Not used, since a PKCS11Signer cannot be created */
+func (s *PKCS11Signer) PublicKey() crypto.PublicKey {
+	return nil
+}
+
+/** Not used, since a PKCS11Signer cannot be created */
+func (s *PKCS11Signer) Sign(data []byte) ([]byte, error) {
+	return nil, errors.New("PKCS #11 signature keys are not supported.")
+}
+
+/** Not used, since a PKCS11Signer cannot be created */
+func (s *PKCS11Signer) Close() error {
+	return nil
+}
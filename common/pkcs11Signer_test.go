@@ -0,0 +1,272 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Signers are no longer shared
+
+// +build cgo
+
+package common_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/miekg/pkcs11"
+)
+
+/** Places SoftHSM is commonly installed */
+var softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+/** DER encoding of the OID of the P521 curve, for CKA_EC_PARAMS */
+var p521ECParams = []byte{0x06, 0x05, 0x2B, 0x81, 0x04, 0x00, 0x23}
+
+const softHSMUserPIN = "5678"
+
+/*----------------------------------------------------------------------------*/
+/* Creates a SoftHSM token labelled admins in a temporary directory, holding  */
+/* a P521 EC key pair labelled ec1 and a 2048-bit RSA key pair labelled rsa1. */
+/* Skips the test if SoftHSM is not installed.  Set SOFTHSM2_MODULE to use a  */
+/* library that is not in one of the usual places.  Call it once per test     */
+/* process, since SoftHSM reads its configuration only once.                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the SoftHSM library                                              */
+/* func() -- removes the temporary directory                                  */
+/*----------------------------------------------------------------------------*/
+func newSoftHSMToken(t *testing.T) (string, func()) {
+	module := os.Getenv("SOFTHSM2_MODULE")
+	if module == "" {
+		for _, path := range softHSMModules {
+			if _, err := os.Stat(path); err == nil {
+				module = path
+				break
+			}
+		}
+	}
+	if module == "" {
+		t.Skip("SoftHSM is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "softhsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	tokenDir := filepath.Join(dir, "tokens")
+	os.Mkdir(tokenDir, 0700)
+	conf := filepath.Join(dir, "softhsm2.conf")
+	ioutil.WriteFile(conf, []byte("directories.tokendir = "+tokenDir+
+		"\nobjectstore.backend = file\n"), 0600)
+	os.Setenv("SOFTHSM2_CONF", conf)
+
+	// The library is left initialized, since the signers share it
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		cleanup()
+		t.Skip("Unable to load " + module)
+	}
+	err = ctx.Initialize()
+	if err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		cleanup()
+		t.Fatal(err)
+	}
+	fail := func(err error) {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	slots, err := ctx.GetSlotList(false)
+	if err == nil && len(slots) == 0 {
+		err = errors.New("SoftHSM has no slots")
+	}
+	if err != nil {
+		fail(err)
+	}
+	err = ctx.InitToken(slots[len(slots)-1], "1234", "admins")
+	if err != nil {
+		fail(err)
+	}
+
+	// SoftHSM moves an initialized token to a new slot
+	slots, err = ctx.GetSlotList(true)
+	if err != nil {
+		fail(err)
+	}
+	var slot uint
+	found := false
+	for _, s := range slots {
+		info, err := ctx.GetTokenInfo(s)
+		if err == nil && info.Label == "admins" {
+			slot, found = s, true
+		}
+	}
+	if !found {
+		fail(errors.New("The initialized SoftHSM token was not found"))
+	}
+	session, err := ctx.OpenSession(slot,
+		pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		fail(err)
+	}
+	defer ctx.CloseSession(session)
+	err = ctx.Login(session, pkcs11.CKU_SO, "1234")
+	if err == nil {
+		err = ctx.InitPIN(session, softHSMUserPIN)
+	}
+	if err == nil {
+		err = ctx.Logout(session)
+	}
+	if err == nil {
+		err = ctx.Login(session, pkcs11.CKU_USER, softHSMUserPIN)
+	}
+	if err != nil {
+		fail(err)
+	}
+	defer ctx.Logout(session)
+
+	privateTemplate := func(label string) []*pkcs11.Attribute {
+		return []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(label)),
+		}
+	}
+	_, _, err = ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, p521ECParams),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, "ec1"),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, []byte("ec1")),
+		}, privateTemplate("ec1"))
+	if err != nil {
+		fail(err)
+	}
+	_, _, err = ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{
+			pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, 2048),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, "rsa1"),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, []byte("rsa1")),
+		}, privateTemplate("rsa1"))
+	if err != nil {
+		fail(err)
+	}
+	return module, cleanup
+}
+
+/** Keys in a PKCS #11 token sign as keys held in memory do */
+func TestPKCS11Signer(t *testing.T) {
+	module, cleanup := newSoftHSMToken(t)
+	defer cleanup()
+
+	// The login state is shared by all sessions with the token, so a wrong
+	// PIN is checked before any signer logs in
+	signer, err := common.NewPKCS11Signer(
+		"pkcs11:token=admins;object=ec1?module-path="+module, "0000")
+	if err == nil {
+		signer.Close()
+		t.Error("NewPKCS11Signer accepted the wrong PIN")
+	}
+
+	tests := []struct {
+		uri     string
+		keyType string
+	}{
+		{"pkcs11:token=admins;object=ec1?module-path=" + module,
+			common.KEY_TYPE_P521EC},
+		{"pkcs11:token=admins;id=rsa1?module-path=" + module,
+			common.KEY_TYPE_RSA2048},
+	}
+	data := []byte("administrative command")
+	for _, test := range tests {
+		signer, err := common.NewPKCS11Signer(test.uri, softHSMUserPIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.KeyType() != test.keyType {
+			t.Errorf("%s has key type %s, expected %s", test.uri,
+				signer.KeyType(), test.keyType)
+		}
+		signature, err := signer.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !common.VerifySignature(signer.PublicKey(), data, signature) {
+			t.Errorf("%s signature did not verify", test.keyType)
+		}
+		if common.VerifySignature(signer.PublicKey(), []byte("other"),
+			signature) {
+			t.Errorf("%s signature verified for other data", test.keyType)
+		}
+		signer.Close()
+	}
+
+	// NewSigner opens a session for each signer, and takes the PIN from the
+	// pin-value attribute.  Closing one signer leaves the others usable.
+	uri := "pkcs11:token=admins;object=ec1?module-path=" + module +
+		"&pin-value=" + softHSMUserPIN
+	first, err := common.NewSigner(uri, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := common.NewSigner(uri, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("NewSigner returned the same signer twice")
+	}
+	err = common.CloseSigners([]common.Signer{first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = second.Sign(data)
+	if err != nil {
+		t.Errorf("Signing failed after another signer was closed: %v", err)
+	}
+	common.CloseSigners([]common.Signer{second})
+
+	// Missing keys, tokens, and libraries are reported
+	errorTests := []struct {
+		uri string
+		pin string
+	}{
+		{"pkcs11:token=admins;object=missing?module-path=" + module,
+			softHSMUserPIN},
+		{"pkcs11:token=others;object=ec1?module-path=" + module,
+			softHSMUserPIN},
+		{"pkcs11:token=admins;object=ec1?module-path=/no/such/library.so",
+			softHSMUserPIN},
+	}
+	for _, test := range errorTests {
+		signer, err := common.NewPKCS11Signer(test.uri, test.pin)
+		if err == nil {
+			signer.Close()
+			t.Errorf("NewPKCS11Signer(%s) succeeded", test.uri)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/** Prefix identifying a signature key held in a PKCS #11 token */
+const PKCS11_URI_PREFIX = "pkcs11:"
+
+/*----------------------------------------------------------------------------*/
+/* Identifies a private key in a PKCS #11 token.  Parsed from a PKCS #11 URI  */
+/* as defined in RFC 7512, for example                                        */
+/*   pkcs11:token=admins;object=admin1?module-path=/usr/lib/libsofthsm2.so    */
+/*                                                                            */
+/* Empty fields match any token or key.                                       */
+/*----------------------------------------------------------------------------*/
+type PKCS11URI struct {
+	Token        string // token label
+	Manufacturer string // token manufacturer ID
+	Serial       string // token serial number
+	Model        string // token model
+	SlotID       int    // slot identifier, or -1 to match any slot
+	Object       string // key label (CKA_LABEL)
+	ID           []byte // key identifier (CKA_ID)
+	ModulePath   string // PKCS #11 library to load
+	PinValue     string // token PIN, used if no PIN is supplied separately
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns true if a signature key is identified by a PKCS #11 URI            */
+/*----------------------------------------------------------------------------*/
+func IsPKCS11URI(sigkey string) bool {
+	return strings.HasPrefix(sigkey, PKCS11_URI_PREFIX)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Parses a PKCS #11 URI identifying a private key.                           */
+/*                                                                            */
+/* The path attributes token, manufacturer, serial, model, slot-id, object,   */
+/* id, and type, and the query attributes module-path and pin-value, are      */
+/* recognized.  Vendor attributes starting with "x-" are ignored.  If the URI */
+/* has no module-path, the TKE_PKCS11_MODULE environment variable names the   */
+/* PKCS #11 library.                                                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* uri -- the PKCS #11 URI                                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *PKCS11URI -- the parsed URI                                               */
+/* error -- reports a URI that is not valid or does not identify a private    */
+/*    key                                                                     */
+/*----------------------------------------------------------------------------*/
+func ParsePKCS11URI(uri string) (*PKCS11URI, error) {
+	if !IsPKCS11URI(uri) {
+		return nil, errors.New("Invalid PKCS #11 URI.  The URI must start " +
+			"with " + PKCS11_URI_PREFIX)
+	}
+	p := &PKCS11URI{SlotID: -1}
+	path := uri[len(PKCS11_URI_PREFIX):]
+	query := ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		query = path[i+1:]
+		path = path[0:i]
+	}
+	// The query may hold the PIN, so only the path is used in messages
+	uri = PKCS11_URI_PREFIX + path
+
+	err := parsePKCS11Attributes(path, ";", func(name string, value string) error {
+		switch name {
+		case "token":
+			p.Token = value
+		case "manufacturer":
+			p.Manufacturer = value
+		case "serial":
+			p.Serial = value
+		case "model":
+			p.Model = value
+		case "slot-id":
+			slotID, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return errors.New("slot-id must be a number")
+			}
+			p.SlotID = int(slotID)
+		case "object":
+			p.Object = value
+		case "id":
+			p.ID = []byte(value)
+		case "type":
+			if value != "private" {
+				return errors.New("type must be private")
+			}
+		default:
+			if !strings.HasPrefix(name, "x-") {
+				return errors.New("unsupported attribute " + name)
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = parsePKCS11Attributes(query, "&", func(name string, value string) error {
+			switch name {
+			case "module-path":
+				p.ModulePath = value
+			case "pin-value":
+				p.PinValue = value
+			default:
+				if !strings.HasPrefix(name, "x-") {
+					return errors.New("unsupported query attribute " + name)
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, errors.New("Invalid PKCS #11 URI: " + uri +
+			"\nMessage: " + err.Error())
+	}
+
+	if p.Object == "" && len(p.ID) == 0 {
+		return nil, errors.New("Invalid PKCS #11 URI: " + uri +
+			"\nThe URI must include an object or id attribute to identify " +
+			"the signature key.")
+	}
+	if p.ModulePath == "" {
+		p.ModulePath = os.Getenv("TKE_PKCS11_MODULE")
+	}
+	if p.ModulePath == "" {
+		return nil, errors.New("Invalid PKCS #11 URI: " + uri +
+			"\nNo PKCS #11 library is specified.  Include a module-path " +
+			"attribute or set the TKE_PKCS11_MODULE environment variable.")
+	}
+	return p, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Splits a list of name=value attributes and passes each one, with the value */
+/* percent-decoded, to a function.                                            */
+/*----------------------------------------------------------------------------*/
+func parsePKCS11Attributes(s string, separator string,
+	attribute func(name string, value string) error) error {
+
+	if s == "" {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(s, separator) {
+		i := strings.Index(field, "=")
+		if i <= 0 {
+			return errors.New("attribute " + strconv.Quote(field) +
+				" is not of the form name=value")
+		}
+		name := field[0:i]
+		if seen[name] {
+			return errors.New("attribute " + name + " is repeated")
+		}
+		seen[name] = true
+		value, err := url.PathUnescape(field[i+1:])
+		if err != nil {
+			return errors.New("attribute " + name + " is not correctly " +
+				"percent-encoded")
+		}
+		err = attribute(name, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
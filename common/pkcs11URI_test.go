@@ -0,0 +1,134 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Sets TKE_PKCS11_MODULE until the returned function is called */
+func withPKCS11ModuleEnv(value string) func() {
+	saved, wasSet := os.LookupEnv("TKE_PKCS11_MODULE")
+	if value == "" {
+		os.Unsetenv("TKE_PKCS11_MODULE")
+	} else {
+		os.Setenv("TKE_PKCS11_MODULE", value)
+	}
+	return func() {
+		if wasSet {
+			os.Setenv("TKE_PKCS11_MODULE", saved)
+		} else {
+			os.Unsetenv("TKE_PKCS11_MODULE")
+		}
+	}
+}
+
+/** Path and query attributes are parsed and percent-decoded */
+func TestParsePKCS11URI(t *testing.T) {
+	defer withPKCS11ModuleEnv("")()
+	tests := []struct {
+		uri      string
+		expected common.PKCS11URI
+	}{
+		{"pkcs11:token=admins;object=admin1" +
+			"?module-path=/usr/lib/softhsm/libsofthsm2.so",
+			common.PKCS11URI{Token: "admins", Object: "admin1", SlotID: -1,
+				ModulePath: "/usr/lib/softhsm/libsofthsm2.so"}},
+		{"pkcs11:manufacturer=IBM;serial=93AABC1234;model=EP11;slot-id=4;" +
+			"id=%01%02%FF;type=private;x-vendor=1" +
+			"?module-path=/lib/p11.so&pin-value=12%2634&x-extra=yes",
+			common.PKCS11URI{Manufacturer: "IBM", Serial: "93AABC1234",
+				Model: "EP11", SlotID: 4, ID: []byte{1, 2, 0xFF},
+				ModulePath: "/lib/p11.so", PinValue: "12&34"}},
+		{"pkcs11:token=my%20token;object=admin%3B1?module-path=%2Flib%2Fp11.so",
+			common.PKCS11URI{Token: "my token", Object: "admin;1", SlotID: -1,
+				ModulePath: "/lib/p11.so"}},
+	}
+	for _, test := range tests {
+		if !common.IsPKCS11URI(test.uri) {
+			t.Errorf("IsPKCS11URI(%s) returned false", test.uri)
+		}
+		p, err := common.ParsePKCS11URI(test.uri)
+		if err != nil {
+			t.Errorf("ParsePKCS11URI(%s) returned %v", test.uri, err)
+			continue
+		}
+		if !reflect.DeepEqual(*p, test.expected) {
+			t.Errorf("ParsePKCS11URI(%s) returned\n%+v\nexpected\n%+v",
+				test.uri, *p, test.expected)
+		}
+	}
+	if common.IsPKCS11URI("/keys/admin1.sigkey") {
+		t.Error("IsPKCS11URI returned true for a file name")
+	}
+}
+
+/** TKE_PKCS11_MODULE is used when the URI has no module-path */
+func TestParsePKCS11URIModuleEnv(t *testing.T) {
+	defer withPKCS11ModuleEnv("/opt/p11/libp11.so")()
+	p, err := common.ParsePKCS11URI("pkcs11:object=admin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ModulePath != "/opt/p11/libp11.so" {
+		t.Errorf("Module path %s, expected the environment variable",
+			p.ModulePath)
+	}
+	p, err = common.ParsePKCS11URI("pkcs11:object=admin1?module-path=/a.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ModulePath != "/a.so" {
+		t.Errorf("Module path %s, expected the module-path attribute",
+			p.ModulePath)
+	}
+}
+
+/** URIs that are not valid or do not identify a private key are rejected */
+func TestParsePKCS11URIErrors(t *testing.T) {
+	defer withPKCS11ModuleEnv("")()
+	const module = "?module-path=/lib/p11.so"
+	tests := []string{
+		"token=admins;object=admin1" + module,
+		"pkcs11:token=admins" + module,
+		"pkcs11:object=admin1",
+		"pkcs11:object" + module,
+		"pkcs11:=admin1" + module,
+		"pkcs11:object=admin1;object=admin2" + module,
+		"pkcs11:object=admin1;color=red" + module,
+		"pkcs11:object=admin1;type=public" + module,
+		"pkcs11:object=admin1;slot-id=two" + module,
+		"pkcs11:object=admin1;slot-id=-1" + module,
+		"pkcs11:object=admin%ZZ" + module,
+		"pkcs11:object=admin1" + module + "&color=red",
+	}
+	for _, uri := range tests {
+		_, err := common.ParsePKCS11URI(uri)
+		if err == nil {
+			t.Errorf("ParsePKCS11URI(%s) succeeded", uri)
+		}
+	}
+
+	// The PIN in the query is not included in messages
+	_, err := common.ParsePKCS11URI(
+		"pkcs11:token=admins" + module + "&pin-value=secret1234")
+	if err == nil {
+		t.Fatal("ParsePKCS11URI succeeded without an object")
+	}
+	if strings.Contains(err.Error(), "secret1234") {
+		t.Errorf("The PIN appears in the message: %v", err)
+	}
+}
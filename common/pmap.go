@@ -7,12 +7,16 @@
 //
 // Date          Initials        Description
 // 12/08/2020    CLH             T390301 - Add minimal touch functions
+// 10/18/2026    CLH             Add Contains, fix Load for tags with A-F
+// 10/18/2026    CLH             Generate entries in a repeatable order
 
 package common
 
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"sort"
+	"strings"
 )
 
 /*----------------------------------------------------------------------------*/
@@ -109,7 +113,8 @@ func (pm ParameterMap) Load(data []byte) (ParameterMap, error) {
 			return pm, err
 		}
 
-		tag := "0x" + hex.EncodeToString(octbytes[0:2])
+		// Tag constants use uppercase hexadecimal digits
+		tag := "0x" + strings.ToUpper(hex.EncodeToString(octbytes[0:2]))
 		auxInt := hex.EncodeToString(octbytes[2:6])
 		value := octbytes[2:]
 
@@ -175,6 +180,42 @@ func (pm ParameterMap) GetDataUsingIndex(tag string, index uint32) []byte {
 	return data[4:]
 }
 
+/*----------------------------------------------------------------------------*/
+/* Reports whether a parameter map contains an entry.                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string -- tag identifying the parameter to look for                        */
+/* uint32 -- index value to combine with the tag.  Ignored for entries that   */
+/*    cannot repeat.                                                          */
+/*                                                                            */
+/* Output:                                                                    */
+/* bool -- true if the map entry exists                                       */
+/*----------------------------------------------------------------------------*/
+func (pm ParameterMap) Contains(tag string, index uint32) bool {
+
+	auxInt := Uint32To4ByteSlice(index)
+
+	switch tag {
+	// Entries that can repeat
+	case PMTAG_DOMAIN_ADMIN_SKIS,
+		PMTAG_DOMAIN_ADMIN_CERTS,
+		PMTAG_DOMAIN_QUERY_INFO,
+		PMTAG_DOMAIN_ATTRIBUTES,
+		PMTAG_DOMAIN_TRANSACTION_COUNTER,
+		PMTAG_OA_CERTIFICATE,
+		PMTAG_DOMAIN_CONTROL_POINTS,
+		PMTAG_ENCR_KEY_PART,
+		PMTAG_SIGNATURE_ENCR_KEY_PART,
+		PMTAG_KPH_CERTIFICATE:
+
+		return pm.pMap[tag+"+"+hex.EncodeToString(auxInt)] != nil
+
+	// Entries that cannot repeat
+	default:
+		return pm.pMap[tag] != nil
+	}
+}
+
 /*----------------------------------------------------------------------------*/
 /* Adds a value to a parameter map.                                           */
 /*                                                                            */
@@ -220,8 +261,17 @@ func (pm ParameterMap) Put(tag string, index uint32, data []byte) {
 /* []byte -- ASN.1 sequence                                                   */
 /*----------------------------------------------------------------------------*/
 func (pm ParameterMap) GenerateBytes() []byte {
+	// Map iteration order is random.  Sort the keys so the same map always
+	// generates the same bytes.
+	keys := make([]string, 0, len(pm.pMap))
+	for key := range pm.pMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	elements := make([][]byte, 0)
-	for key, value := range pm.pMap {
+	for _, key := range keys {
+		value := pm.pMap[key]
 		tag, err := hex.DecodeString(key[2:6])
 		if err != nil {
 			panic("Invalid tag string in parameter map")
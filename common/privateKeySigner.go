@@ -0,0 +1,156 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Use common signature key file functions
+// 10/18/2026    CLH             Blind the RSA private key operation
+
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Signer for a P521 EC or 2048-bit RSA private key held in memory.  Used for */
+/* signature key files, and for keys an application manages itself.           */
+/*----------------------------------------------------------------------------*/
+type PrivateKeySigner struct {
+	key     crypto.PrivateKey
+	keyType string
+	ski     []byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a private key.                                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- an *ecdsa.PrivateKey on the P521 curve, or an *rsa.PrivateKey with  */
+/*    a 2048-bit modulus and a public exponent of 65537                       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *PrivateKeySigner -- signs using the private key                           */
+/* error -- reports an unsupported key                                        */
+/*----------------------------------------------------------------------------*/
+func NewPrivateKeySigner(key crypto.PrivateKey) (*PrivateKeySigner, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		if k.Curve == nil || k.Curve.Params().Name != "P-521" {
+			return nil, errors.New("Only P521 EC keys and 2048-bit RSA keys " +
+				"can be used as signature keys.")
+		}
+		return &PrivateKeySigner{
+			key:     k,
+			keyType: KEY_TYPE_P521EC,
+			ski:     CalculateECKeyHash(k.PublicKey),
+		}, nil
+	case *rsa.PrivateKey:
+		// Administrator certificates assume a public exponent of 65537
+		if k.N.BitLen() != 2048 || k.E != 65537 {
+			return nil, errors.New("Only P521 EC keys and 2048-bit RSA keys " +
+				"with a public exponent of 65537 can be used as signature keys.")
+		}
+		ski, err := CalculateRSAKeyHash(k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &PrivateKeySigner{key: k, keyType: KEY_TYPE_RSA2048, ski: ski}, nil
+	default:
+		return nil, errors.New("Only P521 EC keys and 2048-bit RSA keys " +
+			"can be used as signature keys.")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for the signature key in a signature key file on the      */
+/* local workstation.  The file is read and the signature key decrypted when  */
+/* the signer is created.                                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* sigkey -- the full path and name of the signature key file                 */
+/* sigkeyToken -- the file password                                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *PrivateKeySigner -- signs using the signature key in the file             */
+/* error -- reports an unreadable file, an invalid password, or a file whose  */
+/*    saved Subject Key Identifier does not match the key                     */
+/*----------------------------------------------------------------------------*/
+func NewKeyFileSigner(sigkey string, sigkeyToken string) (*PrivateKeySigner,
+	error) {
+
+	// Read the signature key file and decrypt the signature key
+	skfields, err := ReadSignatureKeyFile(sigkey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecryptSignatureKey(skfields, sigkeyToken)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewPrivateKeySigner(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the calculated and saved SKIs
+	if hex.EncodeToString(signer.ski) != strings.ToLower(skfields["ski"]) {
+		return nil, errors.New("Miscompare on saved and calculated Subject Key Identifier.")
+	}
+	return signer, nil
+}
+
+/** Returns the Subject Key Identifier of the private key */
+func (s *PrivateKeySigner) SKI() []byte {
+	return s.ski
+}
+
+/** Returns KEY_TYPE_P521EC or KEY_TYPE_RSA2048 */
+func (s *PrivateKeySigner) KeyType() string {
+	return s.keyType
+}
+
+/** Returns the public key for the private key */
+func (s *PrivateKeySigner) PublicKey() crypto.PublicKey {
+	switch k := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using the private key.  See Signer.Sign for the format of the   */
+/* signature.                                                                 */
+/*----------------------------------------------------------------------------*/
+func (s *PrivateKeySigner) Sign(data []byte) ([]byte, error) {
+	switch k := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		hash := sha512.Sum512(data)
+		r, sv, err := ecdsa.Sign(rand.Reader, k, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		// Represent the signature as an ASN.1 sequence
+		return asn1.Marshal(ECSignature{R: r, S: sv})
+	case *rsa.PrivateKey:
+		hash := sha256.Sum256(data)
+		return SignDigestANSIX931(hash[:], k)
+	}
+	return nil, errors.New("Unsupported signature key type.")
+}
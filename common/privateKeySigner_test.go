@@ -0,0 +1,181 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** 2048-bit RSA key shared by the tests, since it is slow to generate */
+var testRSAKey *rsa.PrivateKey
+var testRSAKeyOnce sync.Once
+
+func rsaTestKey(t *testing.T) *rsa.PrivateKey {
+	testRSAKeyOnce.Do(func() {
+		testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if testRSAKey == nil {
+		t.Fatal("Error generating an RSA key")
+	}
+	return testRSAKey
+}
+
+/** Returns a new P521 EC key */
+func p521TestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+/** P521 EC and 2048-bit RSA signatures verify with the public key */
+func TestPrivateKeySigner(t *testing.T) {
+	ecKey := p521TestKey(t)
+	rsaKey := rsaTestKey(t)
+	rsaSKI, _ := common.CalculateRSAKeyHash(rsaKey.PublicKey)
+	tests := []struct {
+		key     interface{}
+		keyType string
+		ski     []byte
+	}{
+		{ecKey, common.KEY_TYPE_P521EC, common.CalculateECKeyHash(ecKey.PublicKey)},
+		{rsaKey, common.KEY_TYPE_RSA2048, rsaSKI},
+	}
+	data := []byte("administrative command")
+	for _, test := range tests {
+		signer, err := common.NewPrivateKeySigner(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.KeyType() != test.keyType {
+			t.Errorf("KeyType returned %s, expected %s", signer.KeyType(),
+				test.keyType)
+		}
+		if !bytes.Equal(signer.SKI(), test.ski) || len(signer.SKI()) != 32 {
+			t.Errorf("Unexpected %s SKI %X", test.keyType, signer.SKI())
+		}
+		signature, err := signer.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !common.VerifySignature(signer.PublicKey(), data, signature) {
+			t.Errorf("%s signature did not verify", test.keyType)
+		}
+		if common.VerifySignature(signer.PublicKey(), []byte("other"),
+			signature) {
+			t.Errorf("%s signature verified for other data", test.keyType)
+		}
+	}
+
+	// RSA signatures are always 256 bytes, as Signature256 returns them
+	signer, _ := common.NewPrivateKeySigner(rsaKey)
+	for i := 0; i < 8; i++ {
+		signature, _ := signer.Sign([]byte{byte(i)})
+		if len(signature) != 256 {
+			t.Errorf("RSA signature of %d bytes", len(signature))
+		}
+	}
+}
+
+/** Keys other than P521 EC and 2048-bit RSA keys are rejected */
+func TestPrivateKeySignerUnsupportedKeys(t *testing.T) {
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsa1024Key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	rsaKey := rsaTestKey(t)
+	exponent3Key := *rsaKey
+	exponent3Key.PublicKey.E = 3
+	_, ed25519Key, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, key := range []interface{}{p256Key, rsa1024Key, &exponent3Key,
+		ed25519Key, nil} {
+		_, err := common.NewPrivateKeySigner(key)
+		if err == nil {
+			t.Errorf("NewPrivateKeySigner accepted a %T", key)
+		}
+	}
+}
+
+/** Signature key files are read, decrypted, and checked against their SKI */
+func TestKeyFileSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := p521TestKey(t)
+	path := filepath.Join(dir, "admin1.sigkey")
+	skfields, err := common.EncryptSignatureKey(key, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = common.WriteSignatureKeyFile(path, skfields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := common.NewKeyFileSigner(path, "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signer.SKI(), common.CalculateECKeyHash(key.PublicKey)) {
+		t.Errorf("Key file signer has SKI %X", signer.SKI())
+	}
+	signature, err := signer.Sign([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.VerifySignature(&key.PublicKey, []byte("data"), signature) {
+		t.Error("Key file signature did not verify")
+	}
+
+	_, err = common.NewKeyFileSigner(path, "password2")
+	if err == nil {
+		t.Error("NewKeyFileSigner accepted the wrong password")
+	}
+	_, err = common.NewKeyFileSigner(filepath.Join(dir, "missing"),
+		"password1")
+	if err == nil {
+		t.Error("NewKeyFileSigner accepted a missing file")
+	}
+
+	// A saved SKI that does not match the key is reported.  Version 2 files
+	// authenticate the SKI, so the check is reached using a version 1 file.
+	skfields, err = common.EncryptSignatureKey(key, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := p521TestKey(t)
+	skfields["ski"] = hex.EncodeToString(
+		common.CalculateECKeyHash(otherKey.PublicKey))
+	badPath := filepath.Join(dir, "bad.sigkey")
+	common.WriteSignatureKeyFile(badPath, skfields, false)
+	_, err = common.NewKeyFileSigner(badPath, "password1")
+	if err == nil {
+		t.Error("NewKeyFileSigner accepted a file with the wrong SKI")
+	}
+}
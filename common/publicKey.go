@@ -0,0 +1,282 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add VerifySignature
+// 10/18/2026    CLH             Add CheckDilithiumSignatureLength
+
+package common
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Decodes a signature key public key returned by a signing service.  The     */
+/* format is detected from the value:                                         */
+/*                                                                            */
+/* - a JSON Web Key object (kty EC with crv P-521, or kty RSA)                */
+/* - a PEM string holding a PUBLIC KEY (SubjectPublicKeyInfo) or an RSA       */
+/*   PUBLIC KEY (PKCS #1) block                                               */
+/* - a base64 string holding a DER SubjectPublicKeyInfo, a DER PKCS #1 RSA    */
+/*   public key, or the ASN.1 sequence of the X and Y coordinates of a P521   */
+/*   EC key used by version 1 signing services                                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* value -- the publickey field of the response, decoded from JSON            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* crypto.PublicKey -- an *ecdsa.PublicKey on the P521 curve, or an           */
+/*    *rsa.PublicKey with a 2048-bit modulus and a public exponent of 65537   */
+/* error -- reports a value that cannot be decoded or a key that cannot be    */
+/*    used as a signature key                                                 */
+/*----------------------------------------------------------------------------*/
+func ParsePublicKey(value interface{}) (crypto.PublicKey, error) {
+	var key crypto.PublicKey
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		key, err = parseJWK(v)
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "-----BEGIN") {
+			key, err = parsePEMPublicKey(v)
+		} else {
+			var der []byte
+			der, err = base64.StdEncoding.DecodeString(v)
+			if err == nil {
+				key, err = parseDERPublicKey(der)
+			}
+		}
+	default:
+		err = errors.New("publickey is not a string or a JSON Web Key")
+	}
+	if err != nil {
+		return nil, errors.New("Invalid public key.\nMessage: " + err.Error())
+	}
+	_, err = signatureKeyType(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns KEY_TYPE_P521EC or KEY_TYPE_RSA2048 for a public key, or an error  */
+/* if the key cannot be used as a signature key.                              */
+/*----------------------------------------------------------------------------*/
+func signatureKeyType(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != nil && k.Curve.Params().Name == "P-521" {
+			return KEY_TYPE_P521EC, nil
+		}
+	case *rsa.PublicKey:
+		// Administrator certificates assume a public exponent of 65537
+		if k.N.BitLen() == 2048 && k.E == 65537 {
+			return KEY_TYPE_RSA2048, nil
+		}
+	}
+	return "", errors.New("Only P521 EC keys and 2048-bit RSA keys " +
+		"with a public exponent of 65537 can be used as signature keys.")
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Subject Key Identifier of a signature key public key           */
+/*----------------------------------------------------------------------------*/
+func publicKeySKI(key crypto.PublicKey) ([]byte, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return CalculateECKeyHash(*k), nil
+	case *rsa.PublicKey:
+		return CalculateRSAKeyHash(*k)
+	}
+	return nil, errors.New("Unsupported signature key type.")
+}
+
+/** Decodes a PEM encoded public key */
+func parsePEMPublicKey(s string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("PEM decode failed")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, errors.New("unsupported PEM block type " + block.Type)
+}
+
+/** Decodes a DER encoded public key in one of the supported forms */
+func parseDERPublicKey(der []byte) (crypto.PublicKey, error) {
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+	// The EC point and PKCS #1 forms are both sequences of two integers
+	var point ECPublicKey
+	rest, err := asn1.Unmarshal(der, &point)
+	if err == nil && len(rest) == 0 {
+		if key, err := newP521PublicKey(point.X, point.Y); err == nil {
+			return key, nil
+		}
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not a SubjectPublicKeyInfo, PKCS #1 RSA " +
+		"public key, or P521 EC point")
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes a JSON Web Key (RFC 7517).  Only the public key members are used.  */
+/*----------------------------------------------------------------------------*/
+func parseJWK(jwk map[string]interface{}) (crypto.PublicKey, error) {
+	member := func(name string) (*big.Int, error) {
+		s, ok := jwk[name].(string)
+		if !ok {
+			return nil, errors.New("JSON Web Key member " + name +
+				" not found")
+		}
+		value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, errors.New("JSON Web Key member " + name +
+				" is not base64url encoded")
+		}
+		return new(big.Int).SetBytes(value), nil
+	}
+
+	switch jwk["kty"] {
+	case "EC":
+		if jwk["crv"] != "P-521" {
+			return nil, errors.New("JSON Web Key curve is not P-521")
+		}
+		x, err := member("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := member("y")
+		if err != nil {
+			return nil, err
+		}
+		return newP521PublicKey(x, y)
+	case "RSA":
+		n, err := member("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := member("e")
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("JSON Web Key exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	}
+	return nil, errors.New("unsupported JSON Web Key type")
+}
+
+/** Creates a P521 EC public key, checking that the point is on the curve */
+func newP521PublicKey(x *big.Int, y *big.Int) (*ecdsa.PublicKey, error) {
+	if x == nil || y == nil || !elliptic.P521().IsOnCurve(x, y) {
+		return nil, errors.New("the point is not on the P521 curve")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P521(), X: x, Y: y}, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks a signature returned by a signer against the public key.  See       */
+/* Signer.Sign for the signature formats.                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- the signature key public key                                        */
+/* digest -- SHA-512 hash of the data for EC keys, SHA-256 hash for RSA keys  */
+/* signature -- the signature                                                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* bool -- true if the signature is valid                                     */
+/*----------------------------------------------------------------------------*/
+func verifyDigestSignature(key crypto.PublicKey, digest []byte,
+	signature []byte) bool {
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		var sig ECSignature
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+			return false
+		}
+		return ecdsa.Verify(k, digest, sig.R, sig.S)
+	case *rsa.PublicKey:
+		if len(signature) != 256 {
+			return false
+		}
+		m := new(big.Int).SetBytes(signature)
+		if m.Cmp(k.N) >= 0 {
+			return false
+		}
+		m.Exp(m, big.NewInt(int64(k.E)), k.N)
+		expected := PadANSIX931(digest, 0, len(digest), 2048)
+		return bytes.Equal(m.Bytes(), expected)
+	}
+	return false
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks a signature made by a signer over data, using the public key of the */
+/* signer.  The hash of the data is calculated as described for Signer.Sign.  */
+/*                                                                            */
+/* Go has no Dilithium implementation, so Dilithium signatures cannot be      */
+/* verified and false is returned for Dilithium keys.  See                    */
+/* CheckDilithiumSignatureLength.                                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- the public key returned by Signer.PublicKey                         */
+/* data -- the data that was signed                                           */
+/* signature -- the signature returned by Signer.Sign                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* bool -- true if the signature is valid                                     */
+/*----------------------------------------------------------------------------*/
+func VerifySignature(key crypto.PublicKey, data []byte,
+	signature []byte) bool {
+
+	switch key.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha512.Sum512(data)
+		return verifyDigestSignature(key, hash[:], signature)
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(data)
+		return verifyDigestSignature(key, hash[:], signature)
+	}
+	return false
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks only that a signature has the length of a Dilithium round 2 (8,7)   */
+/* signature.  The signature itself is not verified, since Go has no          */
+/* Dilithium implementation; the crypto module verifies it when the signed    */
+/* command is processed.                                                      */
+/*----------------------------------------------------------------------------*/
+func CheckDilithiumSignatureLength(signature []byte) bool {
+	return len(signature) == DILITHIUM_R2_87_SIGNATURE_LENGTH
+}
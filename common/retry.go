@@ -0,0 +1,256 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Keep the original error when retries stop
+// 10/18/2026    CLH             Keep the OA root keys of wrapped transports
+
+package common
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Controls how requests that fail with a transient error are retried.        */
+/*                                                                            */
+/* The delay before the first retry is InitialDelay.  Each later delay is     */
+/* Multiplier times the previous one, up to MaxDelay.  Each delay is then     */
+/* randomly adjusted by up to plus or minus Jitter times the delay, so        */
+/* clients that fail together do not retry together.                          */
+/*                                                                            */
+/* The zero value makes a single attempt with no retries.                     */
+/*----------------------------------------------------------------------------*/
+type RetryPolicy struct {
+	MaxAttempts  int           // total attempts, including the first
+	InitialDelay time.Duration // delay before the first retry
+	MaxDelay     time.Duration // upper limit on any delay, 0 for no limit
+	Multiplier   float64       // growth factor, values below 1 treated as 1
+	Jitter       float64       // fraction of each delay to randomize, 0 to 1
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a retry policy suitable for the TKE REST API: four attempts,       */
+/* starting with a one second delay that doubles each time.                   */
+/*----------------------------------------------------------------------------*/
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  4,
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+	}
+}
+
+/** Random source for jitter.  rand.Rand is not safe for concurrent use. */
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var jitterMutex sync.Mutex
+
+/*----------------------------------------------------------------------------*/
+/* Returns the delay before a retry.                                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* retry -- which retry the delay is for, 1 for the first retry               */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* time.Duration -- the delay, including jitter                               */
+/*----------------------------------------------------------------------------*/
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialDelay)
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+		if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		jitterMutex.Lock()
+		factor := 1 + jitter*(2*jitterRand.Float64()-1)
+		jitterMutex.Unlock()
+		delay *= factor
+	}
+	return time.Duration(delay)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Waits for the delay before a retry.                                        */
+/*                                                                            */
+/* Returns ctx.Err() if ctx is cancelled or its deadline passes before the    */
+/* delay is over.                                                             */
+/*----------------------------------------------------------------------------*/
+func (p RetryPolicy) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.Delay(retry))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Runs an operation, retrying it while it fails with a transient error.      */
+/*                                                                            */
+/* Only use this for operations that are safe to repeat, such as queries.     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- cancels the retries.  Cancellation is also reported by the          */
+/*    operation itself if it uses ctx.                                        */
+/* policy -- controls the number of attempts and the delays between them      */
+/* operation -- the operation to run                                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- the error from the last attempt.  If ctx is cancelled while       */
+/*    waiting to retry, the error from the last attempt is returned together  */
+/*    with ctx.Err(), see NewRetryStoppedError.                               */
+/*----------------------------------------------------------------------------*/
+func Retry(ctx context.Context, policy RetryPolicy,
+	operation func() error) error {
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || !IsTransientError(err) ||
+			attempt >= policy.MaxAttempts {
+			return err
+		}
+		waitErr := policy.Wait(ctx, attempt)
+		if waitErr != nil {
+			return NewRetryStoppedError(err, waitErr)
+		}
+	}
+}
+
+/** Error that may not recur if the request is sent again */
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e transientError) Unwrap() error {
+	return e.err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Marks an error as transient.  Transports use this for network errors and   */
+/* server errors that may not recur if the request is sent again.             */
+/*----------------------------------------------------------------------------*/
+func NewTransientError(err error) error {
+	return transientError{err: err}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether an error was marked as transient by NewTransientError      */
+/*----------------------------------------------------------------------------*/
+func IsTransientError(err error) bool {
+	var transient transientError
+	return errors.As(err, &transient)
+}
+
+/** Error from a request whose retries were stopped while waiting */
+type retryStoppedError struct {
+	err     error
+	waitErr error
+}
+
+func (e retryStoppedError) Error() string {
+	return e.err.Error() + "\nRetries were stopped: " + e.waitErr.Error()
+}
+
+/** Returns the reason the retries were stopped, such as context.Canceled */
+func (e retryStoppedError) Unwrap() error {
+	return e.waitErr
+}
+
+/** Matches the error from the last attempt as well as the wrapped error */
+func (e retryStoppedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns an error for a request that failed and was not retried because     */
+/* waiting for the retry failed, usually because the context was cancelled.   */
+/* errors.Is reports a match for either error, and IsTransientError reports   */
+/* false, so the request is not retried again.                                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* err -- the error from the last attempt                                     */
+/* waitErr -- the error returned by RetryPolicy.Wait                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- the combined error                                                */
+/*----------------------------------------------------------------------------*/
+func NewRetryStoppedError(err error, waitErr error) error {
+	return retryStoppedError{err: err, waitErr: waitErr}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Implemented by transports that carry a retry policy                        */
+/*----------------------------------------------------------------------------*/
+type RetryPolicyHolder interface {
+	GetRetryPolicy() RetryPolicy
+}
+
+/** Transport with a retry policy attached, see WithRetryPolicy */
+type retryPolicyTransport struct {
+	Transport
+	policy RetryPolicy
+}
+
+func (t retryPolicyTransport) GetRetryPolicy() RetryPolicy {
+	return t.policy
+}
+
+/** Keeps the OA root keys of the wrapped transport trusted */
+func (t retryPolicyTransport) GetOARootKeys() []OARootKey {
+	return GetOARootKeys(t.Transport)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Attaches a retry policy to a transport.                                    */
+/*                                                                            */
+/* The returned transport sends each request once, exactly as tr does.  The   */
+/* ep11cmds functions use the attached policy to decide how to retry: queries */
+/* are simply sent again, while signed commands are only sent again after     */
+/* checking that the first attempt did not take effect.                       */
+/*----------------------------------------------------------------------------*/
+func WithRetryPolicy(tr Transport, policy RetryPolicy) Transport {
+	return retryPolicyTransport{Transport: tr, policy: policy}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the retry policy attached to a transport.  Transports without one  */
+/* get the zero policy, which makes a single attempt.                         */
+/*----------------------------------------------------------------------------*/
+func GetRetryPolicy(tr Transport) RetryPolicy {
+	holder, ok := tr.(RetryPolicyHolder)
+	if ok {
+		return holder.GetRetryPolicy()
+	}
+	return RetryPolicy{}
+}
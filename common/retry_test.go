@@ -0,0 +1,133 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+/** Delays grow by the multiplier up to the maximum, within the jitter */
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second,
+		Multiplier: 2}
+	expected := []time.Duration{time.Second, 2 * time.Second,
+		4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if policy.Delay(i+1) != delay {
+			t.Errorf("Retry %d has delay %v, expected %v", i+1,
+				policy.Delay(i+1), delay)
+		}
+	}
+
+	policy.Multiplier = 0.5
+	if policy.Delay(3) != time.Second {
+		t.Errorf("Multiplier below 1 gave delay %v", policy.Delay(3))
+	}
+
+	policy.Multiplier = 2
+	policy.Jitter = 0.25
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(2)
+		if delay < 1500*time.Millisecond || delay > 2500*time.Millisecond {
+			t.Fatalf("Delay %v is outside the jitter range", delay)
+		}
+	}
+}
+
+/** Retry repeats transient failures only, up to MaxAttempts */
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}
+	tests := []struct {
+		name     string
+		err      error
+		failures int
+		attempts int
+		success  bool
+	}{
+		{"transient then success", NewTransientError(errTest), 2, 3, true},
+		{"transient every time", NewTransientError(errTest), 5, 3, false},
+		{"not transient", errTest, 5, 1, false},
+		{"wrapped transient", fmt.Errorf("request: %w",
+			NewTransientError(errTest)), 1, 2, true},
+	}
+	for _, test := range tests {
+		attempts := 0
+		err := Retry(context.Background(), policy, func() error {
+			attempts++
+			if attempts <= test.failures {
+				return test.err
+			}
+			return nil
+		})
+		if attempts != test.attempts || (err == nil) != test.success {
+			t.Errorf("%s: %d attempts, error %v", test.name, attempts, err)
+		}
+		if err != nil && !errors.Is(err, errTest) {
+			t.Errorf("%s: error %v does not wrap the original error",
+				test.name, err)
+		}
+	}
+
+	attempts := 0
+	err := Retry(context.Background(), RetryPolicy{}, func() error {
+		attempts++
+		return NewTransientError(errTest)
+	})
+	if attempts != 1 || err == nil {
+		t.Errorf("Zero policy made %d attempts", attempts)
+	}
+}
+
+/** Cancelling while waiting returns both the last error and ctx.Err() */
+func TestRetryCancelledWhileWaiting(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := Retry(ctx, policy, func() error {
+		attempts++
+		cancel()
+		return NewTransientError(errTest)
+	})
+	if attempts != 1 {
+		t.Errorf("%d attempts were made", attempts)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTest) {
+		t.Errorf("Error %v does not match both errors", err)
+	}
+	if IsTransientError(err) {
+		t.Error("Stopped retries reported as transient")
+	}
+}
+
+/** Error classification sees through wrapping */
+func TestErrorUnwrap(t *testing.T) {
+	transient := NewTransientError(errTest)
+	if !errors.Is(transient, errTest) {
+		t.Error("Transient error does not wrap the original error")
+	}
+	if !IsTransientError(fmt.Errorf("wrapped: %w", transient)) {
+		t.Error("Wrapped transient error not recognized")
+	}
+	if IsTransientError(errTest) {
+		t.Error("Plain error reported as transient")
+	}
+	unauthorized := unauthorizedError{err: errTest}
+	if !errors.Is(unauthorized, errTest) ||
+		!IsUnauthorizedError(fmt.Errorf("wrapped: %w", unauthorized)) {
+		t.Error("Unauthorized error not recognized through wrapping")
+	}
+}
@@ -7,10 +7,21 @@
 //
 // Date          Initials        Description
 // 04/19/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Allow the signing service URL to be set
+// 10/18/2026    CLH             Add the Signer interface
+// 10/18/2026    CLH             Support signature keys in PKCS #11 tokens
+// 10/18/2026    CLH             Resolve key URIs using registered schemes
+// 10/18/2026    CLH             Add Dilithium signature keys
+// 10/18/2026    CLH             Add NewSignerWithClient
+// 10/18/2026    CLH             Add NewSigners
+// 10/18/2026    CLH             Add CloseSigners and stop sharing PKCS #11
+//                               signers
+// 10/18/2026    CLH             Blind the RSA private key operation
 
 package common
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
@@ -20,15 +31,218 @@ import (
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
-	"io/ioutil"
+	"io"
 	"math/big"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 
+/** Signing service URL set by SetSigningServiceURL */
+var signingServiceURL string
+var signingServiceURLMutex sync.Mutex
+
+/*----------------------------------------------------------------------------*/
+/* Sets the URL of the signing service used to sign administrative commands,  */
+/* overriding the TKE_SIGNSERV_URL environment variable.  The setting applies */
+/* to all service instances.                                                  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ssURL -- the signing service URL, or "" to use TKE_SIGNSERV_URL again      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports an invalid URL, see CheckBaseURL                          */
+/*----------------------------------------------------------------------------*/
+func SetSigningServiceURL(ssURL string) error {
+	if ssURL != "" {
+		var err error
+		ssURL, err = CheckBaseURL(ssURL)
+		if err != nil {
+			return err
+		}
+	}
+	signingServiceURLMutex.Lock()
+	defer signingServiceURLMutex.Unlock()
+	signingServiceURL = ssURL
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the URL of the signing service, or "" if signature keys are in     */
+/* files on the local workstation.  The URL set by SetSigningServiceURL is    */
+/* used if there is one, otherwise the TKE_SIGNSERV_URL environment variable. */
+/*----------------------------------------------------------------------------*/
+func GetSigningServiceURL() string {
+	signingServiceURLMutex.Lock()
+	defer signingServiceURLMutex.Unlock()
+	if signingServiceURL != "" {
+		return signingServiceURL
+	}
+	return os.Getenv("TKE_SIGNSERV_URL")
+}
+
+/** Key types reported by Signer.KeyType */
+const (
+	KEY_TYPE_P521EC          = "p521ec"
+	KEY_TYPE_RSA2048         = "rsa2048"
+	KEY_TYPE_DILITHIUM_R2_87 = "dilithium-r2-8-7"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Signs administrative commands and administrator certificates using the     */
+/* signature key of one administrator.                                        */
+/*                                                                            */
+/* The TKE SDK provides signers for signature key files (NewKeyFileSigner),   */
+/* private keys held in memory (NewPrivateKeySigner), and signing services    */
+/* (NewSigningServiceSigner).  Other key stores can be used by implementing   */
+/* this interface.                                                            */
+/*----------------------------------------------------------------------------*/
+type Signer interface {
+
+	// Returns the 32-byte Subject Key Identifier of the signature key.  For a
+	// P521 EC key this is the SHA-256 hash of the uncompressed public key
+	// point, see CalculateECKeyHash.  For a 2048-bit RSA key this is the
+	// SHA-256 hash of the ASN.1 encoded modulus and public exponent, see
+	// CalculateRSAKeyHash.  For a Dilithium key this is the SHA-256 hash of
+	// the ASN.1 encoded rho and t1, see CalculateDilithiumKeyHash.
+	SKI() []byte
+
+	// Returns KEY_TYPE_P521EC, KEY_TYPE_RSA2048, or KEY_TYPE_DILITHIUM_R2_87
+	KeyType() string
+
+	// Returns the public key, an *ecdsa.PublicKey for a P521 EC key, an
+	// *rsa.PublicKey for a 2048-bit RSA key, or a *DilithiumPublicKey for a
+	// Dilithium key
+	PublicKey() crypto.PublicKey
+
+	// Signs data.  A P521 EC key signs the SHA-512 hash of the data and
+	// returns an ASN.1 sequence of the two INTEGERs R and S.  A 2048-bit RSA
+	// key signs the SHA-256 hash of the data with ANSI X9.31 padding and
+	// returns a 256-byte signature, as Signature256 does.  A Dilithium key
+	// signs the data itself and returns the 4668-byte Dilithium signature.
+	Sign(data []byte) ([]byte, error)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signer for the Key and Token fields of an administrator in the */
+/* resource block.  If sigkey starts with a key URI scheme registered using   */
+/* RegisterSignerScheme, such as file:, signsvc:, or pkcs11:, the signer is   */
+/* created for the scheme.  Otherwise, if a signing service URL is set, the   */
+/* key is accessed using the signing service.  Otherwise sigkey is the name   */
+/* of a signature key file on the local workstation and sigkeyToken is its    */
+/* password.                                                                  */
+/*                                                                            */
+/* Signers for keys in PKCS #11 tokens keep a session open with the token.    */
+/* Close the signer using CloseSigners when it is no longer needed.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* sigkey string -- identifies the signature key to use                       */
+/* sigkeyToken string -- authentication token for the signature key           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* Signer -- signs using the signature key                                    */
+/* error -- reports any error accessing the signature key                     */
+/*----------------------------------------------------------------------------*/
+func NewSigner(sigkey string, sigkeyToken string) (Signer, error) {
+	return NewSignerWithClient(nil, sigkey, sigkeyToken)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as NewSigner, but requests to a signing service or to Vault are sent  */
+/* using httpClient, or DefaultHTTPClient if httpClient is nil.  Signers for  */
+/* schemes registered using RegisterSignerScheme do not use httpClient.       */
+/*----------------------------------------------------------------------------*/
+func NewSignerWithClient(httpClient *http.Client, sigkey string,
+	sigkeyToken string) (Signer, error) {
+
+	signer, isKeyURI, err := newKeyURISigner(httpClient, sigkey, sigkeyToken)
+	if isKeyURI {
+		// Already resolved using the scheme
+	} else if ssURL := GetSigningServiceURL(); ssURL != "" {
+		signer, err = NewSigningServiceSignerWithClient(httpClient, ssURL,
+			sigkey, sigkeyToken)
+	} else {
+		signer, err = NewKeyFileSigner(sigkey, sigkeyToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signers for the sigkeys, sigkeySkis, and sigkeyTokens          */
+/* parameters taken by functions that predate the Signer interface.  Each     */
+/* signer is created using NewSigner, and must have the Subject Key           */
+/* Identifier given for it.  Close the signers using CloseSigners when they   */
+/* are no longer needed.                                                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []string sigkeys -- identifies the signature keys to use                   */
+/* []string sigkeySkis -- the Subject Key Identifiers for the signature keys, */
+/*     as hexadecimal strings                                                 */
+/* []string sigkeyTokens -- authentication tokens for the signature keys      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []Signer -- one signer for each signature key                              */
+/* error -- reports any error accessing the signature keys                    */
+/*----------------------------------------------------------------------------*/
+func NewSigners(sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) ([]Signer, error) {
+
+	if len(sigkeySkis) != len(sigkeys) || len(sigkeyTokens) != len(sigkeys) {
+		return nil, errors.New("The number of signature keys, Subject Key " +
+			"Identifiers, and signature key tokens must be the same.")
+	}
+	signers := make([]Signer, 0, len(sigkeys))
+	for i := range sigkeys {
+		signer, err := NewSigner(sigkeys[i], sigkeyTokens[i])
+		if err != nil {
+			CloseSigners(signers)
+			return nil, err
+		}
+		signers = append(signers, signer)
+		if !strings.EqualFold(hex.EncodeToString(signer.SKI()),
+			sigkeySkis[i]) {
+			CloseSigners(signers)
+			return nil, errors.New("The Subject Key Identifier " +
+				sigkeySkis[i] + " does not match signature key " +
+				sigkeys[i] + ".")
+		}
+	}
+	return signers, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Closes the signers that implement io.Closer, such as signers for keys in   */
+/* PKCS #11 tokens.  Other signers hold no resources and are ignored.         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []Signer -- the signers to close.  Nil entries are ignored.                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- the first error closing a signer.  All signers are closed.        */
+/*----------------------------------------------------------------------------*/
+func CloseSigners(signers []Signer) error {
+	var firstErr error
+	for _, signer := range signers {
+		closer, ok := signer.(io.Closer)
+		if !ok {
+			continue
+		}
+		err := closer.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 /** Used to create an ASN.1 sequence representing an EC signature */
 type ECSignature struct {
 	R *big.Int
@@ -36,9 +250,11 @@ type ECSignature struct {
 }
 
 /*----------------------------------------------------------------------------*/
-/* Signs the input data.  Checks the TKE_SIGNSERV_URL environment variable.   */
-/* If set, uses a signing service provided by the user to sign the data.      */
-/* Otherwise, assumes signature keys are in files on the local workstation.   */
+/* Signs the input data.  Keys identified by key URIs are resolved using the  */
+/* scheme, see NewSigner.  Otherwise, if a signing service URL is set, either */
+/* by SetSigningServiceURL or the TKE_SIGNSERV_URL environment variable, uses */
+/* a signing service provided by the user to sign the data.  Otherwise,       */
+/* assumes signature keys are in files on the local workstation.              */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* dataToSign []byte -- the data to be signed                                 */
@@ -51,9 +267,18 @@ type ECSignature struct {
 /*----------------------------------------------------------------------------*/
 func SignWithSignatureKey(dataToSign []byte, sigkey string, sigkeyToken string) ([]byte, error) {
 
-	// Check if the environment variable is set indicating a signing service
-	// should be used
-	ssURL := os.Getenv("TKE_SIGNSERV_URL")
+	// Keys identified by key URIs are resolved using the scheme
+	if KeyURIScheme(sigkey) != "" {
+		signer, err := NewSigner(sigkey, sigkeyToken)
+		if err != nil {
+			return nil, err
+		}
+		defer CloseSigners([]Signer{signer})
+		return signer.Sign(dataToSign)
+	}
+
+	// Check if a signing service should be used
+	ssURL := GetSigningServiceURL()
 	if ssURL != "" {
 		// Use the signing service to create the signature
 		encodedData := base64.StdEncoding.EncodeToString(dataToSign)
@@ -87,38 +312,11 @@ func SignWithSignatureKey(dataToSign []byte, sigkey string, sigkeyToken string)
 /* error -- any error encountered                                             */
 /*----------------------------------------------------------------------------*/
 func SignWithSignatureKeyFile(dataToSign []byte, sigkey string, sigkeyToken string) ([]byte, error) {
-
-	// Read the signature key file
-	data, err := ioutil.ReadFile(sigkey)
+	signer, err := NewKeyFileSigner(sigkey, sigkeyToken)
 	if err != nil {
 		return nil, err
 	}
-	var skfields map[string]string
-	err = json.Unmarshal(data, &skfields)
-	if err != nil {
-		panic(err)
-	}
-
-	// Derive the encryption key from the password and salt field
-	aeskey, _ := Derive_aes_key(sigkeyToken, skfields["seaSalt"])
-
-	// Decrypt the signature key
-	enckey, err := hex.DecodeString(skfields["enckey"])
-	if err != nil {
-		panic(err)
-	}
-	pemBytes, err := Decrypt(enckey, aeskey)
-	if err != nil {
-		return nil, errors.New("Invalid password.")
-	}
-
-	if skfields["keyType"] == "p521ec" {
-		// Sign using a P521 EC signature key
-		return SignWithP521ECKey(dataToSign, pemBytes, skfields["ski"])
-	} else {
-		// Sign using a 2048-bit RSA signature key
-		return SignWithRSA2048Key(dataToSign, pemBytes, skfields["ski"])
-	}
+	return signer.Sign(dataToSign)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -162,40 +360,91 @@ func SignWithRSA2048Key(dataToSign []byte, pemBytes []byte, savedSKI string) ([]
 		return nil, errors.New("Miscompare on saved and calculated Subject Key Identifier.")
 	}
 
-	return Signature256(dataToSign, rsaKey), nil
+	hash := sha256.Sum256(dataToSign)
+	return SignDigestANSIX931(hash[:], rsaKey)
 }
 
 /*----------------------------------------------------------------------------*/
-/* Calculate a 256 byte RSA signature                                         */
+/* Calculate a 256 byte RSA signature.  Returns nil if the signature cannot   */
+/* be made; use SignDigestANSIX931 to get the reason.                         */
 /*----------------------------------------------------------------------------*/
 func Signature256(dataToSign []byte, rsaKey *rsa.PrivateKey) []byte {
 	hash := sha256.Sum256(dataToSign)
-	//------------------------------------------------------------------------//
-	// The Go crypto/rsa package doesn't support encryption, decryption,
-	// or signing functions without some type of padding.
-	//
-	// https://stackoverflow.com/questions/40870178/golang-rsa-decrypt-no-padding
-	//
-	// This Stack Overflow post suggests using the following code to sign
-	// something without padding:
-	//
-	// c := new(big.Int).SetBytes(cipherText)
-	// plainText := c.Exp(c, privateKey.D, privateKey.N).Bytes()
-	//------------------------------------------------------------------------//
-	c := new(big.Int).SetBytes(PadANSIX931(hash[:], 0, len(hash), 2048))
-	signature := c.Exp(c, rsaKey.D, rsaKey.N).Bytes()
-	if len(signature) < 256 {
-		// Add leading zeroes
-		newsig := make([]byte, 0)
-		for i:=0; i<(256-len(signature)); i++ {
-			newsig = append(newsig, 0x00)
-		}
-		newsig = append(newsig, signature...)
-		return newsig
+	signature, err := SignDigestANSIX931(hash[:], rsaKey)
+	if err != nil {
+		return nil
 	}
 	return signature
 }
 
+/*----------------------------------------------------------------------------*/
+/* Signs a SHA-256 hash with a 2048-bit RSA private key.  The hash is put in  */
+/* ANSI X9.31 format and the raw RSA private key operation is applied to it.  */
+/*                                                                            */
+/* The Go crypto/rsa package doesn't support the private key operation        */
+/* without PKCS #1 padding, so it is done here the way crypto/rsa does it:    */
+/* the input is blinded with a random value before the private exponent is    */
+/* applied, so the time taken does not depend on the input, and the result    */
+/* is checked with the public key before it is returned.                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* digest -- the 32-byte SHA-256 hash to be signed                            */
+/* rsaKey -- a 2048-bit RSA private key                                       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the 256-byte RSA signature, with leading zeroes if needed        */
+/* error -- reports a hash of the wrong length, a key of the wrong size, or   */
+/*    a signature that does not verify                                        */
+/*----------------------------------------------------------------------------*/
+func SignDigestANSIX931(digest []byte, rsaKey *rsa.PrivateKey) ([]byte,
+	error) {
+
+	if len(digest) != sha256.Size {
+		return nil, errors.New("An RSA key signs a 32-byte SHA-256 hash, " +
+			"not " + strconv.Itoa(len(digest)) + " bytes.")
+	}
+	if rsaKey.N.BitLen() != 2048 {
+		return nil, errors.New("Only 2048-bit RSA keys can be used as " +
+			"signature keys.")
+	}
+	n := rsaKey.N
+	e := big.NewInt(int64(rsaKey.E))
+	m := new(big.Int).SetBytes(PadANSIX931(digest, 0, len(digest), 2048))
+
+	// Blind the input with r^e, for a random r that has an inverse mod N
+	var r, rInverse *big.Int
+	for {
+		var err error
+		r, err = rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() == 0 {
+			continue
+		}
+		rInverse = new(big.Int).ModInverse(r, n)
+		if rInverse != nil {
+			break
+		}
+	}
+	c := new(big.Int).Exp(r, e, n)
+	c.Mul(c, m)
+	c.Mod(c, n)
+
+	// Apply the private exponent and remove the blinding
+	s := new(big.Int).Exp(c, rsaKey.D, n)
+	s.Mul(s, rInverse)
+	s.Mod(s, n)
+
+	// Check the result, so a faulty calculation does not reveal the key
+	if new(big.Int).Exp(s, e, n).Cmp(m) != 0 {
+		return nil, errors.New("The RSA signature could not be verified.")
+	}
+	signature := make([]byte, 256)
+	sBytes := s.Bytes()
+	copy(signature[256-len(sBytes):], sBytes)
+	return signature, nil
+}
 
 const X931_PAD_BYTE byte = 0xBB
 const X931_SIG_HASH_ID_SHA256 byte = 0x34
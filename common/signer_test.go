@@ -0,0 +1,117 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Clears the signing service URL until the returned function is called */
+func withoutSigningService(t *testing.T) func() {
+	saved, wasSet := os.LookupEnv("TKE_SIGNSERV_URL")
+	os.Unsetenv("TKE_SIGNSERV_URL")
+	err := common.SetSigningServiceURL("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if wasSet {
+			os.Setenv("TKE_SIGNSERV_URL", saved)
+		}
+	}
+}
+
+/** Without a signing service, keys are read from signature key files */
+func TestNewSignerKeyFile(t *testing.T) {
+	defer withoutSigningService(t)()
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey := rsaTestKey(t)
+	path := filepath.Join(dir, "admin1.sigkey")
+	skfields, err := common.EncryptSignatureKey(rsaKey, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = common.WriteSignatureKeyFile(path, skfields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain path and a file URI name the same key
+	for _, sigkey := range []string{path, "file://" + path} {
+		signer, err := common.NewSigner(sigkey, "password1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.KeyType() != common.KEY_TYPE_RSA2048 {
+			t.Errorf("NewSigner(%s) returned a %s signer", sigkey,
+				signer.KeyType())
+		}
+		signature, err := common.SignWithSignatureKey([]byte("data"), sigkey,
+			"password1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !common.VerifySignature(&rsaKey.PublicKey, []byte("data"),
+			signature) {
+			t.Errorf("SignWithSignatureKey(%s) signature did not verify",
+				sigkey)
+		}
+	}
+
+	_, err = common.NewSigner(path, "wrong")
+	if err == nil {
+		t.Error("NewSigner accepted the wrong password")
+	}
+	_, err = common.SignWithSignatureKeyFile([]byte("data"), path, "wrong")
+	if err == nil {
+		t.Error("SignWithSignatureKeyFile accepted the wrong password")
+	}
+}
+
+/** ANSI X9.31 padding surrounds the hash with the expected bytes */
+func TestPadANSIX931(t *testing.T) {
+	hash := sha256.Sum256([]byte("data"))
+	padded := common.PadANSIX931(hash[:], 0, len(hash), 2048)
+	if len(padded) != 256 {
+		t.Fatalf("Padded hash is %d bytes, expected 256", len(padded))
+	}
+	expected := []byte{0x6B}
+	expected = append(expected, bytes.Repeat([]byte{0xBB}, 220)...)
+	expected = append(expected, 0xBA)
+	expected = append(expected, hash[:]...)
+	expected = append(expected, 0x34, 0xCC)
+	if !bytes.Equal(padded, expected) {
+		t.Errorf("Unexpected padded hash %X", padded)
+	}
+
+	// Signature256 enciphers the padded hash with the private key
+	rsaKey := rsaTestKey(t)
+	signature := common.Signature256([]byte("data"), rsaKey)
+	if len(signature) != 256 {
+		t.Errorf("Signature256 returned %d bytes", len(signature))
+	}
+	if !common.VerifySignature(&rsaKey.PublicKey, []byte("data"), signature) {
+		t.Error("Signature256 signature did not verify")
+	}
+}
@@ -0,0 +1,231 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Support signing service protocol version 2
+// 10/18/2026    CLH             Send requests with a given HTTP client
+// 10/18/2026    CLH             Do not remember failed version requests
+
+package common
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/rest"
+)
+
+/** Signing service protocol version found for each signing service URL */
+var signingServiceVersions = make(map[string]int)
+var signingServiceVersionsMutex sync.Mutex
+
+/*----------------------------------------------------------------------------*/
+/* Signer for a signature key held by a user-provided signing service.        */
+/*                                                                            */
+/* Version 1 signing services hold P521 EC keys and are sent the data to be   */
+/* signed.  Version 2 signing services also hold 2048-bit RSA keys, and are   */
+/* sent only the hash of the data.  Signatures are checked against the public */
+/* key before they are used.                                                  */
+/*----------------------------------------------------------------------------*/
+type SigningServiceSigner struct {
+	ssURL       string
+	sigkey      string
+	sigkeyToken string
+	httpClient  *http.Client
+	version     int
+	keyType     string
+	publicKey   crypto.PublicKey
+	ski         []byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a signature key held by a signing service.  The       */
+/* protocol version and the public key are read from the signing service when */
+/* the signer is created.  The protocol version is found once for each        */
+/* signing service URL.                                                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ssURL -- base URL for the signing service                                  */
+/* sigkey -- identifies the signature key to use                              */
+/* sigkeyToken -- authentication token for the signature key                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *SigningServiceSigner -- signs using the signing service                   */
+/* error -- reports any error reading the public key                          */
+/*----------------------------------------------------------------------------*/
+func NewSigningServiceSigner(ssURL string, sigkey string,
+	sigkeyToken string) (*SigningServiceSigner, error) {
+
+	return NewSigningServiceSignerWithClient(nil, ssURL, sigkey, sigkeyToken)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as NewSigningServiceSigner, but the requests to the signing service   */
+/* are sent using httpClient, or DefaultHTTPClient if httpClient is nil.      */
+/* Create the client with NewHTTPClient to set trusted CAs, a proxy,          */
+/* timeouts, or a client certificate for the signing service.                 */
+/*----------------------------------------------------------------------------*/
+func NewSigningServiceSignerWithClient(httpClient *http.Client, ssURL string,
+	sigkey string, sigkeyToken string) (*SigningServiceSigner, error) {
+
+	version, err := getSigningServiceVersion(httpClient, ssURL, sigkeyToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey crypto.PublicKey
+	if version == SIGNING_SERVICE_VERSION_1 {
+		var pubkey []byte
+		pubkey, err = getPublicKeyFromSigningService(httpClient, ssURL, sigkey,
+			sigkeyToken)
+		if err == nil {
+			publicKey, err = newP521PublicKey(new(big.Int).SetBytes(pubkey[1:67]),
+				new(big.Int).SetBytes(pubkey[67:133]))
+		}
+	} else {
+		var value interface{}
+		req := CreateGetPublicKeyV2Request(sigkeyToken, ssURL, sigkey)
+		value, err = submitQueryPublicKeyV2Request(httpClient, req)
+		if err == nil {
+			publicKey, err = ParsePublicKey(value)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyType, err := signatureKeyType(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	ski, err := publicKeySKI(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningServiceSigner{
+		ssURL:       ssURL,
+		sigkey:      sigkey,
+		sigkeyToken: sigkeyToken,
+		httpClient:  httpClient,
+		version:     version,
+		keyType:     keyType,
+		publicKey:   publicKey,
+		ski:         ski,
+	}, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signing service protocol version to use with a signing         */
+/* service, asking the signing service the first time.  Only versions that    */
+/* were found are remembered, so after an error the signing service is asked  */
+/* again.                                                                     */
+/*----------------------------------------------------------------------------*/
+func getSigningServiceVersion(httpClient *http.Client, ssURL string,
+	sigkeyToken string) (int, error) {
+
+	signingServiceVersionsMutex.Lock()
+	defer signingServiceVersionsMutex.Unlock()
+	if version, ok := signingServiceVersions[ssURL]; ok {
+		return version, nil
+	}
+	version, err := submitQueryVersionRequest(httpClient,
+		CreateGetVersionRequest(sigkeyToken, ssURL))
+	if err != nil {
+		return 0, err
+	}
+	signingServiceVersions[ssURL] = version
+	return version, nil
+}
+
+/** Returns the Subject Key Identifier of the signature key */
+func (s *SigningServiceSigner) SKI() []byte {
+	return s.ski
+}
+
+/** Returns KEY_TYPE_P521EC or KEY_TYPE_RSA2048 */
+func (s *SigningServiceSigner) KeyType() string {
+	return s.keyType
+}
+
+/** Returns the public key read from the signing service */
+func (s *SigningServiceSigner) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+/** Returns the signing service protocol version used by the signer */
+func (s *SigningServiceSigner) Version() int {
+	return s.version
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using the signing service.                                      */
+/*                                                                            */
+/* A version 1 signing service is sent the data, and calculates the SHA-512   */
+/* hash of the data and signs it.  A version 2 signing service is sent the    */
+/* SHA-512 hash for a P521 EC key, or the SHA-256 hash for an RSA key, and    */
+/* signs the hash.  RSA signing services add ANSI X9.31 formatting to the     */
+/* hash before signing it.  EC signatures may be returned either as an ASN.1  */
+/* sequence of R and S or as R followed by S.                                 */
+/*----------------------------------------------------------------------------*/
+func (s *SigningServiceSigner) Sign(data []byte) ([]byte, error) {
+	var digest []byte
+	var req *rest.Request
+	if s.keyType == KEY_TYPE_P521EC {
+		hash := sha512.Sum512(data)
+		digest = hash[:]
+		if s.version == SIGNING_SERVICE_VERSION_1 {
+			req = CreateSignDataRequest(s.sigkeyToken, s.ssURL, s.sigkey,
+				base64.StdEncoding.EncodeToString(data))
+		} else {
+			req = CreateSignDigestRequest(s.sigkeyToken, s.ssURL, s.sigkey,
+				"sha2-512", base64.StdEncoding.EncodeToString(digest))
+		}
+	} else {
+		hash := sha256.Sum256(data)
+		digest = hash[:]
+		req = CreateSignDigestRequest(s.sigkeyToken, s.ssURL, s.sigkey,
+			"sha2-256", base64.StdEncoding.EncodeToString(digest))
+	}
+
+	encodedSignature, err := submitSignDataRequest(s.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, errors.New("Error requesting a signature over supplied " +
+			"data.\nsignature is not base64 encoded")
+	}
+
+	var sequence ECSignature
+	_, err = asn1.Unmarshal(signature, &sequence)
+	if s.keyType == KEY_TYPE_P521EC && err != nil && len(signature) == 132 {
+		// Convert R followed by S to an ASN.1 sequence
+		signature, err = asn1.Marshal(ECSignature{
+			R: new(big.Int).SetBytes(signature[0:66]),
+			S: new(big.Int).SetBytes(signature[66:132]),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !verifyDigestSignature(s.publicKey, digest, signature) {
+		return nil, errors.New("The signature returned by the signing " +
+			"service is not valid for signature key " + s.sigkey +
+			".\nSigning service protocol version: " + strconv.Itoa(s.version))
+	}
+	return signature, nil
+}
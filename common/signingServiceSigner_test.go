@@ -0,0 +1,450 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test version request errors
+
+package common_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Formats in which the stand-in signing service returns public keys */
+const (
+	formatSPKI     = "spki"      // base64 DER SubjectPublicKeyInfo
+	formatPEM      = "pem"       // PEM PUBLIC KEY
+	formatPKCS1PEM = "pkcs1-pem" // PEM RSA PUBLIC KEY
+	formatPKCS1    = "pkcs1"     // base64 DER PKCS #1 RSA public key
+	formatPoint    = "point"     // base64 DER sequence of X and Y
+	formatJWK      = "jwk"       // JSON Web Key
+)
+
+/** Stand-in for a signing service holding one signature key */
+type testSigningService struct {
+	key       crypto.Signer
+	version1  bool   // no /version endpoint and no /v2 requests
+	format    string // format of the public key
+	rawEC     bool   // return EC signatures as R followed by S
+	wrongSign bool   // sign a different hash
+	token     string
+
+	mutex    sync.Mutex
+	requests []map[string]string // bodies of the sign requests
+}
+
+func (ss *testSigningService) ServeHTTP(w http.ResponseWriter,
+	r *http.Request) {
+
+	if r.Header.Get("Authorization") != ss.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "bad token"})
+		return
+	}
+	var response interface{}
+	switch {
+	case r.URL.Path == "/version" && !ss.version1:
+		response = map[string]interface{}{"versions": []int{1, 2}}
+	case r.URL.Path == "/keys/key1" && ss.version1:
+		response = map[string]interface{}{"publickey": ss.publicKey()}
+	case r.URL.Path == "/v2/keys/key1" && !ss.version1:
+		response = map[string]interface{}{"publickey": ss.publicKey()}
+	case r.URL.Path == "/sign/key1" && ss.version1,
+		r.URL.Path == "/v2/sign/key1" && !ss.version1:
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		ss.mutex.Lock()
+		ss.requests = append(ss.requests, request)
+		ss.mutex.Unlock()
+		signature := ss.sign(request)
+		if signature == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": "bad request"})
+			return
+		}
+		response = map[string]string{
+			"signature": base64.StdEncoding.EncodeToString(signature)}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+/** Returns the publickey field in the format of the stand-in */
+func (ss *testSigningService) publicKey() interface{} {
+	switch k := ss.key.Public().(type) {
+	case *ecdsa.PublicKey:
+		x := make([]byte, 66)
+		y := make([]byte, 66)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		switch ss.format {
+		case formatPoint:
+			der, _ := asn1.Marshal(common.ECPublicKey{X: k.X, Y: k.Y})
+			return base64.StdEncoding.EncodeToString(der)
+		case formatJWK:
+			return map[string]string{"kty": "EC", "crv": "P-521",
+				"x": base64.RawURLEncoding.EncodeToString(x),
+				"y": base64.RawURLEncoding.EncodeToString(y)}
+		}
+	case *rsa.PublicKey:
+		switch ss.format {
+		case formatPKCS1:
+			return base64.StdEncoding.EncodeToString(
+				x509.MarshalPKCS1PublicKey(k))
+		case formatPKCS1PEM:
+			return string(pem.EncodeToMemory(&pem.Block{
+				Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(k)}))
+		case formatJWK:
+			return map[string]string{"kty": "RSA",
+				"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e": "AQAB"}
+		}
+	}
+	der, _ := x509.MarshalPKIXPublicKey(ss.key.Public())
+	if ss.format == formatPEM {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY",
+			Bytes: der}))
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs the data of a version 1 request or the digest of a version 2         */
+/* request, as a signing service does.  Returns nil for a request that is not */
+/* valid.                                                                     */
+/*----------------------------------------------------------------------------*/
+func (ss *testSigningService) sign(request map[string]string) []byte {
+	var digest []byte
+	if ss.version1 {
+		input, err := base64.StdEncoding.DecodeString(request["input"])
+		if err != nil || request["hash_algorithm"] != "sha2-512" {
+			return nil
+		}
+		hash := sha512.Sum512(input)
+		digest = hash[:]
+	} else {
+		var err error
+		digest, err = base64.StdEncoding.DecodeString(request["digest"])
+		if err != nil {
+			return nil
+		}
+	}
+	if ss.wrongSign {
+		digest = append([]byte(nil), digest...)
+		digest[0] ^= 1
+	}
+
+	switch k := ss.key.(type) {
+	case *ecdsa.PrivateKey:
+		if len(digest) != 64 || (!ss.version1 &&
+			request["hash_algorithm"] != "sha2-512") {
+			return nil
+		}
+		r, s, _ := ecdsa.Sign(rand.Reader, k, digest)
+		if ss.rawEC {
+			signature := make([]byte, 132)
+			r.FillBytes(signature[0:66])
+			s.FillBytes(signature[66:132])
+			return signature
+		}
+		signature, _ := asn1.Marshal(common.ECSignature{R: r, S: s})
+		return signature
+	case *rsa.PrivateKey:
+		if len(digest) != 32 || request["hash_algorithm"] != "sha2-256" {
+			return nil
+		}
+		m := new(big.Int).SetBytes(common.PadANSIX931(digest, 0, 32, 2048))
+		signature := make([]byte, 256)
+		m.Exp(m, k.D, k.N).FillBytes(signature)
+		return signature
+	}
+	return nil
+}
+
+/** Returns the bodies of the sign requests received */
+func (ss *testSigningService) signRequests() []map[string]string {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	return append([]map[string]string(nil), ss.requests...)
+}
+
+/** Version 1 signing services are sent the data and hold P521 EC keys */
+func TestSigningServiceSignerV1(t *testing.T) {
+	ss := &testSigningService{key: p521TestKey(t), version1: true,
+		token: "Bearer token1"}
+	server := httptest.NewServer(ss)
+	defer server.Close()
+
+	signer, err := common.NewSigningServiceSigner(server.URL, "key1",
+		"Bearer token1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Version() != common.SIGNING_SERVICE_VERSION_1 ||
+		signer.KeyType() != common.KEY_TYPE_P521EC {
+		t.Errorf("Version %d and key type %s, expected 1 and %s",
+			signer.Version(), signer.KeyType(), common.KEY_TYPE_P521EC)
+	}
+	ecKey := ss.key.(*ecdsa.PrivateKey)
+	if !bytes.Equal(signer.SKI(), common.CalculateECKeyHash(ecKey.PublicKey)) {
+		t.Errorf("Unexpected SKI %X", signer.SKI())
+	}
+
+	data := []byte("administrative command")
+	signature, err := signer.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.VerifySignature(&ecKey.PublicKey, data, signature) {
+		t.Error("Version 1 signature did not verify")
+	}
+	requests := ss.signRequests()
+	if len(requests) != 1 || requests[0]["input"] !=
+		base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("Unexpected version 1 sign requests %v", requests)
+	}
+
+	// The older functions reach the same signing service
+	pubkey, err := common.SubmitQueryPublicKeyRequest(
+		common.CreateGetPublicKeyRequest("Bearer token1", server.URL, "key1"))
+	if err != nil || pubkey == "" {
+		t.Errorf("SubmitQueryPublicKeyRequest returned %q, %v", pubkey, err)
+	}
+}
+
+/** Version 2 signing services accept every public key format */
+func TestSigningServiceSignerV2Formats(t *testing.T) {
+	ecKey := p521TestKey(t)
+	rsaKey := rsaTestKey(t)
+	tests := []struct {
+		key     crypto.Signer
+		format  string
+		keyType string
+	}{
+		{ecKey, formatSPKI, common.KEY_TYPE_P521EC},
+		{ecKey, formatPEM, common.KEY_TYPE_P521EC},
+		{ecKey, formatPoint, common.KEY_TYPE_P521EC},
+		{ecKey, formatJWK, common.KEY_TYPE_P521EC},
+		{rsaKey, formatSPKI, common.KEY_TYPE_RSA2048},
+		{rsaKey, formatPEM, common.KEY_TYPE_RSA2048},
+		{rsaKey, formatPKCS1, common.KEY_TYPE_RSA2048},
+		{rsaKey, formatPKCS1PEM, common.KEY_TYPE_RSA2048},
+		{rsaKey, formatJWK, common.KEY_TYPE_RSA2048},
+	}
+	data := []byte("administrative command")
+	for _, test := range tests {
+		ss := &testSigningService{key: test.key, format: test.format,
+			token: "Bearer token1"}
+		server := httptest.NewServer(ss)
+
+		signer, err := common.NewSigningServiceSigner(server.URL, "key1",
+			"Bearer token1")
+		if err != nil {
+			server.Close()
+			t.Fatalf("%s %s: %v", test.keyType, test.format, err)
+		}
+		if signer.Version() != common.SIGNING_SERVICE_VERSION_2 ||
+			signer.KeyType() != test.keyType {
+			t.Errorf("%s %s: version %d and key type %s", test.keyType,
+				test.format, signer.Version(), signer.KeyType())
+		}
+		signature, err := signer.Sign(data)
+		if err != nil {
+			t.Errorf("%s %s: %v", test.keyType, test.format, err)
+		} else if !common.VerifySignature(test.key.Public(), data,
+			signature) {
+			t.Errorf("%s %s: signature did not verify", test.keyType,
+				test.format)
+		}
+
+		// Only the hash of the data is sent
+		var digest []byte
+		if test.keyType == common.KEY_TYPE_P521EC {
+			hash := sha512.Sum512(data)
+			digest = hash[:]
+		} else {
+			hash := sha256.Sum256(data)
+			digest = hash[:]
+		}
+		requests := ss.signRequests()
+		if len(requests) != 1 || requests[0]["input"] != "" ||
+			requests[0]["digest"] != base64.StdEncoding.EncodeToString(digest) {
+			t.Errorf("%s %s: unexpected sign requests %v", test.keyType,
+				test.format, requests)
+		}
+		server.Close()
+	}
+}
+
+/** EC signatures returned as R followed by S are converted to ASN.1 */
+func TestSigningServiceSignerRawECSignature(t *testing.T) {
+	ss := &testSigningService{key: p521TestKey(t), rawEC: true,
+		token: "Bearer token1"}
+	server := httptest.NewServer(ss)
+	defer server.Close()
+
+	signer, err := common.NewSigningServiceSigner(server.URL, "key1",
+		"Bearer token1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := signer.Sign([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sequence common.ECSignature
+	rest, err := asn1.Unmarshal(signature, &sequence)
+	if err != nil || len(rest) != 0 {
+		t.Errorf("Signature is not an ASN.1 sequence: %X", signature)
+	}
+	if !common.VerifySignature(ss.key.Public(), []byte("data"), signature) {
+		t.Error("Converted signature did not verify")
+	}
+}
+
+/** Signatures, tokens, and keys the signer cannot use are reported */
+func TestSigningServiceSignerErrors(t *testing.T) {
+	for _, version1 := range []bool{true, false} {
+		ss := &testSigningService{key: p521TestKey(t), version1: version1,
+			wrongSign: true, token: "Bearer token1"}
+		server := httptest.NewServer(ss)
+		signer, err := common.NewSigningServiceSigner(server.URL, "key1",
+			"Bearer token1")
+		if err != nil {
+			server.Close()
+			t.Fatal(err)
+		}
+		_, err = signer.Sign([]byte("data"))
+		if err == nil || !strings.Contains(err.Error(), "not valid") {
+			t.Errorf("Sign returned %v for a signature over other data "+
+				"(version 1: %v)", err, version1)
+		}
+
+		_, err = common.NewSigningServiceSigner(server.URL, "key1",
+			"Bearer other")
+		if err == nil {
+			t.Errorf("Signer was created with the wrong token "+
+				"(version 1: %v)", version1)
+		}
+		_, err = common.NewSigningServiceSigner(server.URL, "key2",
+			"Bearer token1")
+		if err == nil {
+			t.Errorf("Signer was created for an unknown key "+
+				"(version 1: %v)", version1)
+		}
+		server.Close()
+	}
+
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsa1024Key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	unsupported := []*testSigningService{
+		{key: p256Key, token: "t"},
+		{key: rsa1024Key, token: "t"},
+		// Version 1 signing services hold only P521 EC keys
+		{key: rsaTestKey(t), version1: true, token: "t"},
+	}
+	for _, ss := range unsupported {
+		server := httptest.NewServer(ss)
+		_, err := common.NewSigningServiceSigner(server.URL, "key1", "t")
+		if err == nil {
+			t.Errorf("Signer was created for a %T (version 1: %v)", ss.key,
+				ss.version1)
+		}
+		server.Close()
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Only a missing /version endpoint means a version 1 signing service.  Other */
+/* errors are reported, and are not remembered for the signing service URL.   */
+/*----------------------------------------------------------------------------*/
+func TestSigningServiceVersionErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		version int // 0 if an error is expected
+	}{
+		{http.StatusNotFound, common.SIGNING_SERVICE_VERSION_1},
+		{http.StatusMethodNotAllowed, common.SIGNING_SERVICE_VERSION_1},
+		{http.StatusUnauthorized, 0},
+		{http.StatusInternalServerError, 0},
+		{http.StatusOK, 0}, // the response is not JSON
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte("not JSON"))
+			}))
+		version, err := common.SubmitQueryVersionRequest(
+			common.CreateGetVersionRequest("Bearer token1", server.URL))
+		server.Close()
+		if version != test.version || (err == nil) != (test.version != 0) {
+			t.Errorf("Status %d returned version %d, %v", test.status,
+				version, err)
+		}
+	}
+
+	// A version 2 signing service whose first /version request fails is
+	// asked again
+	ss := &testSigningService{key: p521TestKey(t), token: "Bearer token1"}
+	var mutex sync.Mutex
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			fail := r.URL.Path == "/version" && failures > 0
+			if fail {
+				failures--
+			}
+			mutex.Unlock()
+			if fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			ss.ServeHTTP(w, r)
+		}))
+	defer server.Close()
+
+	_, err := common.NewSigningServiceSigner(server.URL, "key1",
+		"Bearer token1")
+	if err == nil {
+		t.Fatal("The failed /version request was not reported")
+	}
+	signer, err := common.NewSigningServiceSigner(server.URL, "key1",
+		"Bearer token1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Version() != common.SIGNING_SERVICE_VERSION_2 {
+		t.Errorf("Version %d, expected 2", signer.Version())
+	}
+}
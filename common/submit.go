@@ -7,11 +7,22 @@
 //
 // Date          Initials        Description
 // 07/04/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Report transient errors
+// 10/18/2026    CLH             Reuse a configurable HTTP client
+// 10/18/2026    CLH             Report rejected authentication tokens
+// 10/18/2026    CLH             Add signing service version 2 requests
+// 10/18/2026    CLH             Unwrap rejected token errors
+// 10/18/2026    CLH             Send signing service requests with a given client
+// 10/18/2026    CLH             Assume signing service version 1 only for a
+//                               missing /version endpoint
 
 package common
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -30,35 +41,39 @@ type Location struct {
 /* program.                                                                   */
 /*                                                                            */
 /* Returns the HTPResponse string from the TKE catcher program.               */
+/* If ctx is cancelled or its deadline passes before the response arrives,    */
+/* ctx.Err() is returned.  Network errors and server errors (5xx status       */
+/* codes) are marked as transient, see IsTransientError.                      */
 /*----------------------------------------------------------------------------*/
-func SubmitHTPRequest(req *rest.Request) (htpResponse string, err error) {
+func SubmitHTPRequestWithContext(ctx context.Context,
+	req *rest.Request) (htpResponse string, err error) {
+
+	return submitHTPRequest(ctx, nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitHTPRequestWithContext, but sends the request using the       */
+/* specified HTTP client, or the default HTTP client if httpClient is nil.    */
+/*----------------------------------------------------------------------------*/
+func submitHTPRequest(ctx context.Context, httpClient *http.Client,
+	req *rest.Request) (htpResponse string, err error) {
 
 	var outmap map[string]string
 
-	// Create client with default TLS handshake timeout of 10 seconds
-	client := rest.NewClient()
-
-	// Create client with no TLS handshake timeout
-	//	tr := &http.Transport{
-	//		TLSHandshakeTimeout: 0,
-	// for 30 second timeout use 30 * 1000 * 1000 * 1000
-	//	}
-	//	dfcl := &http.Client{
-	//		Transport: tr,
-	//	}
-	//	client := &rest.Client{
-	//		HTTPClient: dfcl,
-	//		DefaultHeader: make(http.Header),
-	//	}
-
-	_, err = client.Do(req, &outmap, nil)
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
+
+	_, err = client.DoWithContext(ctx, req, &outmap, nil)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		t1, ok := err.(*rest.ErrorResponse)
 		if ok {
-			return "", errors.New(
+			return "", statusError(t1, errors.New(
 				"Error sending HTPRequest to target service instance." +
 					"\nStatus code: " + strconv.Itoa(t1.StatusCode) +
-					"\n" + getMessageText(t1))
+					"\n" + getMessageText(t1)))
 		} else if strings.Contains(err.Error(), "no such host") {
 			return "", errors.New(
 				"Error sending HTPRequest to target service instance." +
@@ -69,9 +84,9 @@ func SubmitHTPRequest(req *rest.Request) (htpResponse string, err error) {
 					"Services online documentation to determine the regions and " +
 					"locations where the service is available.")
 		} else {
-			return "", errors.New(
+			return "", NewTransientError(errors.New(
 				"Error sending HTPRequest to target service instance." +
-					"\nMessage: " + err.Error())
+					"\nMessage: " + err.Error()))
 		}
 	}
 	resp := outmap["response"]
@@ -83,6 +98,50 @@ func SubmitHTPRequest(req *rest.Request) (htpResponse string, err error) {
 	return resp, nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitHTPRequestWithContext, using the background context          */
+/*----------------------------------------------------------------------------*/
+func SubmitHTPRequest(req *rest.Request) (htpResponse string, err error) {
+	return SubmitHTPRequestWithContext(context.Background(), req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Marks the error for an HTTP error response as transient if the status code */
+/* indicates a server error, or as unauthorized for status code 401           */
+/*----------------------------------------------------------------------------*/
+func statusError(rsp *rest.ErrorResponse, err error) error {
+	if rsp.StatusCode >= 500 {
+		return NewTransientError(err)
+	}
+	if rsp.StatusCode == http.StatusUnauthorized {
+		return unauthorizedError{err: err}
+	}
+	return err
+}
+
+/** Error for a request whose authentication token was rejected */
+type unauthorizedError struct {
+	err error
+}
+
+func (e unauthorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e unauthorizedError) Unwrap() error {
+	return e.err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether a request failed because the TKE REST API rejected its     */
+/* authentication token (status code 401).  The request did not reach the     */
+/* crypto unit.                                                               */
+/*----------------------------------------------------------------------------*/
+func IsUnauthorizedError(err error) bool {
+	var unauthorized unauthorizedError
+	return errors.As(err, &unauthorized)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Submits the GET /hsms request that queries the Cloud for the domains       */
 /* associated with a crypto instance.                                         */
@@ -100,6 +159,29 @@ func SubmitHTPRequest(req *rest.Request) (htpResponse string, err error) {
 func SubmitQueryDomainsRequest(req *rest.Request) ([]string, []string,
 	[]string, []string, error) {
 
+	return SubmitQueryDomainsRequestWithContext(context.Background(), req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitQueryDomainsRequest, but the request is sent using ctx.  If  */
+/* ctx is cancelled or its deadline passes before the response arrives,       */
+/* ctx.Err() is returned.  Network errors and server errors are marked as     */
+/* transient.                                                                 */
+/*----------------------------------------------------------------------------*/
+func SubmitQueryDomainsRequestWithContext(ctx context.Context,
+	req *rest.Request) ([]string, []string, []string, []string, error) {
+
+	return submitQueryDomainsRequest(ctx, nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitQueryDomainsRequestWithContext, but sends the request using  */
+/* the specified HTTP client, or the default HTTP client if httpClient is     */
+/* nil.                                                                       */
+/*----------------------------------------------------------------------------*/
+func submitQueryDomainsRequest(ctx context.Context, httpClient *http.Client,
+	req *rest.Request) ([]string, []string, []string, []string, error) {
+
 	/*
 	 * The format of the response for a GET /hsms request is:
 	 *
@@ -137,34 +219,24 @@ func SubmitQueryDomainsRequest(req *rest.Request) ([]string, []string,
 
 	var outmap = make(map[string]interface{})
 
-	// Create client with default TLS handshake timeout of 10 seconds
-	client := rest.NewClient()
-
-	// Create client with no TLS handshake timeout
-	//	tr := &http.Transport{
-	//		TLSHandshakeTimeout: 0,
-	// for 30 second timeout use 30 * 1000 * 1000 * 1000
-	//	}
-	//	dfcl := &http.Client{
-	//		Transport: tr,
-	//	}
-	//	client := &rest.Client{
-	//		HTTPClient: dfcl,
-	//		DefaultHeader: make(http.Header),
-	//	}
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
 
-	_, err := client.Do(req, &outmap, nil)
+	_, err := client.DoWithContext(ctx, req, &outmap, nil)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, nil, nil, ctx.Err()
+		}
 		t1, ok := err.(*rest.ErrorResponse)
 		if ok {
-			return nil, nil, nil, nil, errors.New(
+			return nil, nil, nil, nil, statusError(t1, errors.New(
 				"Error querying crypto units." +
 					"\nStatus code: " + strconv.Itoa(t1.StatusCode) +
-					"\n" + getMessageText(t1))
+					"\n" + getMessageText(t1)))
 		} else {
-			return nil, nil, nil, nil, errors.New(
+			return nil, nil, nil, nil, NewTransientError(errors.New(
 				"Error querying crypto units." +
-					"\nMessage: " + err.Error())
+					"\nMessage: " + err.Error()))
 		}
 	}
 
@@ -451,6 +523,15 @@ func convertStringToIndex(theString string) (int, error) {
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
 func SubmitQueryPublicKeyRequest(req *rest.Request) (string, error) {
+	return submitQueryPublicKeyRequest(nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitQueryPublicKeyRequest, but sends the request using           */
+/* httpClient, or the default HTTP client if httpClient is nil                */
+/*----------------------------------------------------------------------------*/
+func submitQueryPublicKeyRequest(httpClient *http.Client,
+	req *rest.Request) (string, error) {
 
 	/*
 	 * The format of the response for a GET /hsms request is:
@@ -463,21 +544,8 @@ func SubmitQueryPublicKeyRequest(req *rest.Request) (string, error) {
 
 	var outmap = make(map[string]interface{})
 
-	// Create client with default TLS handshake timeout of 10 seconds
-	client := rest.NewClient()
-
-	// Create client with no TLS handshake timeout
-	//	tr := &http.Transport{
-	//		TLSHandshakeTimeout: 0,
-	// for 30 second timeout use 30 * 1000 * 1000 * 1000
-	//	}
-	//	dfcl := &http.Client{
-	//		Transport: tr,
-	//	}
-	//	client := &rest.Client{
-	//		HTTPClient: dfcl,
-	//		DefaultHeader: make(http.Header),
-	//	}
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
 
 	_, err := client.Do(req, &outmap, nil)
 	if err != nil {
@@ -522,6 +590,15 @@ func SubmitQueryPublicKeyRequest(req *rest.Request) (string, error) {
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
 func SubmitSignDataRequest(req *rest.Request) (string, error) {
+	return submitSignDataRequest(nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitSignDataRequest, but sends the request using                 */
+/* httpClient, or the default HTTP client if httpClient is nil                */
+/*----------------------------------------------------------------------------*/
+func submitSignDataRequest(httpClient *http.Client,
+	req *rest.Request) (string, error) {
 
 	/*
 	 * The format of the response for a POST /sign request is:
@@ -534,21 +611,8 @@ func SubmitSignDataRequest(req *rest.Request) (string, error) {
 
 	var outmap = make(map[string]interface{})
 
-	// Create client with default TLS handshake timeout of 10 seconds
-	client := rest.NewClient()
-
-	// Create client with no TLS handshake timeout
-	//	tr := &http.Transport{
-	//		TLSHandshakeTimeout: 0,
-	// for 30 second timeout use 30 * 1000 * 1000 * 1000
-	//	}
-	//	dfcl := &http.Client{
-	//		Transport: tr,
-	//	}
-	//	client := &rest.Client{
-	//		HTTPClient: dfcl,
-	//		DefaultHeader: make(http.Header),
-	//	}
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
 
 	_, err := client.Do(req, &outmap, nil)
 	if err != nil {
@@ -581,3 +645,140 @@ func SubmitSignDataRequest(req *rest.Request) (string, error) {
 	return signature_string, nil
 }
 
+
+/** Signing service protocol versions */
+const (
+	SIGNING_SERVICE_VERSION_1 = 1
+	SIGNING_SERVICE_VERSION_2 = 2
+)
+
+/*----------------------------------------------------------------------------*/
+/* Submits a GET /version request to a signing service to find the highest    */
+/* version of the signing service protocol supported by both the signing      */
+/* service and the TKE SDK.                                                   */
+/*                                                                            */
+/* Input:                                                                     */
+/* *rest.Request -- the GET /version request for the signing service          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* int -- SIGNING_SERVICE_VERSION_2, or SIGNING_SERVICE_VERSION_1 if the      */
+/*    signing service has no /version endpoint (status 404 or 405) or does    */
+/*    not list version 2                                                      */
+/* error -- reports a signing service that cannot be reached, any other       */
+/*    error status, or a response that is not JSON                            */
+/*----------------------------------------------------------------------------*/
+func SubmitQueryVersionRequest(req *rest.Request) (int, error) {
+	return submitQueryVersionRequest(nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitQueryVersionRequest, but sends the request using             */
+/* httpClient, or the default HTTP client if httpClient is nil                */
+/*----------------------------------------------------------------------------*/
+func submitQueryVersionRequest(httpClient *http.Client,
+	req *rest.Request) (int, error) {
+
+	/*
+	 * The format of the response for a GET /version request is:
+	 *
+	 * {
+	 *     "versions": [1, 2]
+	 * }
+	 *
+	 */
+
+	var outmap = make(map[string]interface{})
+
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
+
+	_, err := client.Do(req, &outmap, nil)
+	if err != nil {
+		// Version 1 signing services do not have a /version endpoint.  Other
+		// errors, such as a rejected token or a server error, are reported
+		// so that they are not taken as version 1.
+		t1, ok := err.(*rest.ErrorResponse)
+		if ok && (t1.StatusCode == http.StatusNotFound ||
+			t1.StatusCode == http.StatusMethodNotAllowed) {
+			return SIGNING_SERVICE_VERSION_1, nil
+		} else if ok {
+			return 0, errors.New(
+				"Error requesting signing service version." +
+					"\nStatus code: " + strconv.Itoa(t1.StatusCode) +
+					"\nMessage: " + t1.Message)
+		}
+		return 0, errors.New(
+			"Error requesting signing service version." +
+				"\nMessage: " + err.Error())
+	}
+
+	versions, _ := outmap["versions"].([]interface{})
+	for _, version := range versions {
+		if number, ok := version.(float64); ok &&
+			int(number) == SIGNING_SERVICE_VERSION_2 {
+			return SIGNING_SERVICE_VERSION_2, nil
+		}
+	}
+	return SIGNING_SERVICE_VERSION_1, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Submits a GET /v2/keys request to a version 2 signing service to retrieve  */
+/* the public part of a signature key.                                        */
+/*                                                                            */
+/* Input:                                                                     */
+/* *rest.Request -- the GET /v2/keys request for the signing service          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* interface{} -- the public key, either a string or a JSON Web Key object.   */
+/*    See ParsePublicKey.                                                     */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func SubmitQueryPublicKeyV2Request(req *rest.Request) (interface{}, error) {
+	return submitQueryPublicKeyV2Request(nil, req)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitQueryPublicKeyV2Request, but sends the request using         */
+/* httpClient, or the default HTTP client if httpClient is nil                */
+/*----------------------------------------------------------------------------*/
+func submitQueryPublicKeyV2Request(httpClient *http.Client,
+	req *rest.Request) (interface{}, error) {
+
+	/*
+	 * The format of the response for a GET /v2/keys request is:
+	 *
+	 * {
+	 *     "publickey": <PEM string, base64 encoded DER string, or JSON Web Key>
+	 * }
+	 *
+	 */
+
+	var outmap = make(map[string]interface{})
+
+	// Reuse connections from earlier requests
+	client := newRESTClient(httpClient)
+
+	_, err := client.Do(req, &outmap, nil)
+	if err != nil {
+		t1, ok := err.(*rest.ErrorResponse)
+		if ok {
+			return nil, errors.New(
+				"Error requesting public part of signature key." +
+					"\nStatus code: " + strconv.Itoa(t1.StatusCode) +
+					"\nMessage: " + t1.Message)
+		} else {
+			return nil, errors.New(
+				"Error requesting public part of signature key." +
+					"\nMessage: " + err.Error())
+		}
+	}
+
+	pubkey := outmap["publickey"]
+	if pubkey == nil {
+		return nil, errors.New(
+			"Error requesting public part of signature key." +
+				"\npublickey not found")
+	}
+	return pubkey, nil
+}
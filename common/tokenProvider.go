@@ -0,0 +1,331 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add a fallback HTTP client
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Supplies the authentication tokens sent to the TKE REST API.               */
+/*                                                                            */
+/* A long Update can outlive a single token, so HTTPTransport asks for a      */
+/* token before each request rather than holding one for its lifetime.       */
+/*----------------------------------------------------------------------------*/
+type TokenProvider interface {
+
+	// Returns the value for the Authorization header, for example
+	// "Bearer eyJraWQ...".  Implementations should return a cached token
+	// while it remains valid and obtain a new one before it expires.
+	Token(ctx context.Context) (string, error)
+
+	// Reports that a token returned by Token was rejected.  The next call
+	// to Token should obtain a new token.
+	Invalidate(token string)
+}
+
+/** TokenProvider that always returns the same token */
+type staticTokenProvider struct {
+	token string
+}
+
+func (p staticTokenProvider) Token(ctx context.Context) (string, error) {
+	return p.token, nil
+}
+
+func (p staticTokenProvider) Invalidate(token string) {
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a token provider that always returns the same token.  This is how  */
+/* CommonInputs.AuthToken is used.                                            */
+/*----------------------------------------------------------------------------*/
+func NewStaticTokenProvider(token string) TokenProvider {
+	return staticTokenProvider{token: token}
+}
+
+/** URL of the IBM Cloud IAM token service */
+const DEFAULT_IAM_URL = "https://iam.cloud.ibm.com"
+
+/** File holding the compute resource token in an IBM Cloud Kubernetes pod */
+const DEFAULT_CR_TOKEN_FILE = "/var/run/secrets/tokens/vault-token"
+
+/** Grant types for the IAM token service */
+const (
+	iamGrantTypeAPIKey  = "urn:ibm:params:oauth:grant-type:apikey"
+	iamGrantTypeCRToken = "urn:ibm:params:oauth:grant-type:cr-token"
+)
+
+/** Minimum time before expiration at which a token is refreshed */
+const iamMinimumRefreshWindow = 60 * time.Second
+
+/*----------------------------------------------------------------------------*/
+/* Token provider that obtains tokens from the IBM Cloud IAM token service.   */
+/*                                                                            */
+/* A token is refreshed once 80 percent of its lifetime has passed, or a      */
+/* minute before it expires if that is earlier.  If a refresh fails while the */
+/* cached token is still valid, the cached token is returned.                 */
+/*                                                                            */
+/* Create one with NewIAMAPIKeyTokenProvider or                               */
+/* NewComputeResourceTokenProvider.  IAMURL and HTTPClient may be changed     */
+/* before the first call to Token, for example to use a stand-in IAM service  */
+/* for testing.                                                               */
+/*----------------------------------------------------------------------------*/
+type IAMTokenProvider struct {
+	IAMURL     string       // base URL of the IAM token service
+	HTTPClient *http.Client // nil to use the fallback client, if set, or
+	                        // DefaultHTTPClient
+
+	// Returns the form parameters identifying the credential
+	grantParameters func() (url.Values, error)
+
+	mutex          sync.Mutex
+	fallbackClient *http.Client
+	token          string
+	expiration time.Time
+	refreshAt  time.Time
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a token provider that exchanges an IBM Cloud API key for tokens.   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* apiKey -- the IBM Cloud API key of a user or service ID                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *IAMTokenProvider -- the new token provider                                */
+/*----------------------------------------------------------------------------*/
+func NewIAMAPIKeyTokenProvider(apiKey string) *IAMTokenProvider {
+	return &IAMTokenProvider{
+		IAMURL: DEFAULT_IAM_URL,
+		grantParameters: func() (url.Values, error) {
+			if apiKey == "" {
+				return nil, errors.New("No IBM Cloud API key provided")
+			}
+			params := url.Values{}
+			params.Set("grant_type", iamGrantTypeAPIKey)
+			params.Set("apikey", apiKey)
+			return params, nil
+		},
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a token provider that exchanges a compute resource token for       */
+/* tokens of a trusted profile.                                               */
+/*                                                                            */
+/* The compute resource token file is read each time a token is obtained,     */
+/* since the platform replaces the file before the token in it expires.       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* crTokenFile -- file holding the compute resource token, or "" for          */
+/*    DEFAULT_CR_TOKEN_FILE                                                   */
+/* profileID -- ID of the trusted profile to use.  May be "" if profileName   */
+/*    is given.                                                               */
+/* profileName -- name of the trusted profile to use.  May be "" if profileID */
+/*    is given.                                                               */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *IAMTokenProvider -- the new token provider                                */
+/*----------------------------------------------------------------------------*/
+func NewComputeResourceTokenProvider(crTokenFile string, profileID string,
+	profileName string) *IAMTokenProvider {
+
+	if crTokenFile == "" {
+		crTokenFile = DEFAULT_CR_TOKEN_FILE
+	}
+	return &IAMTokenProvider{
+		IAMURL: DEFAULT_IAM_URL,
+		grantParameters: func() (url.Values, error) {
+			if profileID == "" && profileName == "" {
+				return nil, errors.New("No trusted profile ID or name provided")
+			}
+			crToken, err := ioutil.ReadFile(crTokenFile)
+			if err != nil {
+				return nil, errors.New("Error reading compute resource " +
+					"token file " + crTokenFile + "\nMessage: " + err.Error())
+			}
+			params := url.Values{}
+			params.Set("grant_type", iamGrantTypeCRToken)
+			params.Set("cr_token", strings.TrimSpace(string(crToken)))
+			if profileID != "" {
+				params.Set("profile_id", profileID)
+			} else {
+				params.Set("profile_name", profileName)
+			}
+			return params, nil
+		},
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a token, obtaining a new one from the IAM token service if there   */
+/* is no cached token or it is due to be refreshed.                           */
+/*----------------------------------------------------------------------------*/
+func (p *IAMTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	if p.token != "" && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+
+	token, expiresIn, err := p.requestToken(ctx)
+	if err != nil {
+		if p.token != "" && now.Before(p.expiration) {
+			return p.token, nil
+		}
+		return "", err
+	}
+
+	lifetime := time.Duration(expiresIn) * time.Second
+	refreshWindow := lifetime / 5
+	if refreshWindow < iamMinimumRefreshWindow {
+		refreshWindow = iamMinimumRefreshWindow
+	}
+	p.token = token
+	p.expiration = now.Add(lifetime)
+	p.refreshAt = p.expiration.Add(-refreshWindow)
+	return p.token, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sets the HTTP client used for requests to the IAM token service when       */
+/* HTTPClient is nil.  The tkesdk functions set this to                       */
+/* CommonInputs.HTTPClient, so that IAM requests use the same trusted CAs and */
+/* proxy as requests to the TKE REST API.                                     */
+/*----------------------------------------------------------------------------*/
+func (p *IAMTokenProvider) SetFallbackHTTPClient(client *http.Client) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.fallbackClient = client
+}
+
+/*----------------------------------------------------------------------------*/
+/* Discards the cached token if it is the token that was rejected             */
+/*----------------------------------------------------------------------------*/
+func (p *IAMTokenProvider) Invalidate(token string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if token == p.token {
+		p.token = ""
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Requests a token from the IAM token service.                               */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the Authorization header value, token type and access token      */
+/* int64 -- the lifetime of the token in seconds                              */
+/* error -- reports any error.  Network errors and server errors are marked   */
+/*    as transient.                                                           */
+/*----------------------------------------------------------------------------*/
+func (p *IAMTokenProvider) requestToken(ctx context.Context) (string, int64,
+	error) {
+
+	params, err := p.grantParameters()
+	if err != nil {
+		return "", 0, err
+	}
+
+	iamURL := strings.TrimRight(p.IAMURL, "/") + "/identity/token"
+	req, err := http.NewRequestWithContext(ctx, "POST", iamURL,
+		strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := p.HTTPClient
+	if client == nil {
+		client = p.fallbackClient
+	}
+	if client == nil {
+		client = DefaultHTTPClient()
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		return "", 0, NewTransientError(errors.New(
+			"Error requesting an IAM token.\nMessage: " + err.Error()))
+	}
+	defer rsp.Body.Close()
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return "", 0, NewTransientError(errors.New(
+			"Error requesting an IAM token.\nMessage: " + err.Error()))
+	}
+
+	/*
+	 * The format of the response from the IAM token service is:
+	 *
+	 * {
+	 *     "access_token": "<token>",
+	 *     "refresh_token": "<token>",
+	 *     "token_type": "Bearer",
+	 *     "expires_in": 3600,
+	 *     "expiration": 1700000000
+	 * }
+	 *
+	 * Errors are reported as:
+	 *
+	 * {
+	 *     "errorCode": "BXNIM0415E",
+	 *     "errorMessage": "Provided API key could not be found."
+	 * }
+	 */
+	var tokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	jsonErr := json.Unmarshal(body, &tokenResponse)
+
+	if rsp.StatusCode != http.StatusOK {
+		err = errors.New("Error requesting an IAM token." +
+			"\nStatus code: " + strconv.Itoa(rsp.StatusCode) +
+			"\nMessage: " + tokenResponse.ErrorCode + " " +
+			tokenResponse.ErrorMessage)
+		if rsp.StatusCode >= 500 || rsp.StatusCode == 429 {
+			err = NewTransientError(err)
+		}
+		return "", 0, err
+	}
+	if jsonErr != nil || tokenResponse.AccessToken == "" ||
+		tokenResponse.ExpiresIn <= 0 {
+		return "", 0, errors.New("Error requesting an IAM token." +
+			"\nThe response did not contain an access token")
+	}
+
+	tokenType := tokenResponse.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + tokenResponse.AccessToken,
+		tokenResponse.ExpiresIn, nil
+}
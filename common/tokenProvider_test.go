@@ -0,0 +1,324 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Stand-in for the IBM Cloud IAM token service */
+type testIAM struct {
+	mutex     sync.Mutex
+	requests  []url.Values
+	expiresIn int64
+	status    int // when not 0, returned in place of a token
+}
+
+func (iam *testIAM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	iam.mutex.Lock()
+	defer iam.mutex.Unlock()
+	if r.Method != "POST" || r.URL.Path != "/identity/token" ||
+		r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	r.ParseForm()
+	iam.requests = append(iam.requests, r.PostForm)
+	if iam.status != 0 {
+		w.WriteHeader(iam.status)
+		json.NewEncoder(w).Encode(map[string]string{
+			"errorCode":    "BXNIM0415E",
+			"errorMessage": "Provided API key could not be found.",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "token" + strconv.Itoa(len(iam.requests)),
+		"token_type":   "Bearer",
+		"expires_in":   iam.expiresIn,
+	})
+}
+
+/** Returns the form parameters of the requests received */
+func (iam *testIAM) received() []url.Values {
+	iam.mutex.Lock()
+	defer iam.mutex.Unlock()
+	return append([]url.Values(nil), iam.requests...)
+}
+
+/** Sets the status code returned, or 0 to return tokens */
+func (iam *testIAM) setStatus(status int) {
+	iam.mutex.Lock()
+	defer iam.mutex.Unlock()
+	iam.status = status
+}
+
+/** API keys are exchanged for tokens, which are cached until refreshed */
+func TestIAMAPIKeyTokenProvider(t *testing.T) {
+	iam := &testIAM{expiresIn: 3600}
+	server := httptest.NewServer(iam)
+	defer server.Close()
+
+	p := common.NewIAMAPIKeyTokenProvider("my-api-key")
+	p.IAMURL = server.URL + "/"
+	ctx := context.Background()
+
+	token, err := p.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "Bearer token1" {
+		t.Errorf("Token returned %q, expected %q", token, "Bearer token1")
+	}
+	token, err = p.Token(ctx)
+	if err != nil || token != "Bearer token1" {
+		t.Errorf("Token returned %q, %v, expected the cached token", token, err)
+	}
+	requests := iam.received()
+	if len(requests) != 1 {
+		t.Fatalf("%d IAM requests sent, expected 1", len(requests))
+	}
+	if requests[0].Get("grant_type") !=
+		"urn:ibm:params:oauth:grant-type:apikey" ||
+		requests[0].Get("apikey") != "my-api-key" {
+		t.Errorf("Unexpected IAM request parameters %v", requests[0])
+	}
+
+	// Invalidating a different token keeps the cached token
+	p.Invalidate("Bearer other")
+	token, _ = p.Token(ctx)
+	if token != "Bearer token1" {
+		t.Errorf("Token returned %q after invalidating another token", token)
+	}
+
+	// Invalidating the cached token obtains a new one
+	p.Invalidate("Bearer token1")
+	token, err = p.Token(ctx)
+	if err != nil || token != "Bearer token2" {
+		t.Errorf("Token returned %q, %v after Invalidate, expected %q",
+			token, err, "Bearer token2")
+	}
+
+	_, err = common.NewIAMAPIKeyTokenProvider("").Token(ctx)
+	if err == nil {
+		t.Error("Token succeeded without an API key")
+	}
+}
+
+/** Short-lived tokens are refreshed, and kept while a refresh fails */
+func TestIAMTokenRefresh(t *testing.T) {
+
+	// A minute before expiration is earlier than 80 percent of 30 seconds,
+	// so every call refreshes the token
+	iam := &testIAM{expiresIn: 30}
+	server := httptest.NewServer(iam)
+	defer server.Close()
+
+	p := common.NewIAMAPIKeyTokenProvider("my-api-key")
+	p.IAMURL = server.URL
+	ctx := context.Background()
+
+	first, err := p.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("Token was not refreshed, both calls returned %q", first)
+	}
+
+	// The cached token remains valid, so it is returned when a refresh fails
+	iam.setStatus(http.StatusInternalServerError)
+	token, err := p.Token(ctx)
+	if err != nil || token != second {
+		t.Errorf("Token returned %q, %v while IAM was failing, expected %q",
+			token, err, second)
+	}
+
+	// Without a cached token the error is returned
+	p.Invalidate(second)
+	_, err = p.Token(ctx)
+	if err == nil {
+		t.Fatal("Token succeeded while IAM was failing")
+	}
+	if !common.IsTransientError(err) {
+		t.Errorf("Server error was not transient: %v", err)
+	}
+
+	iam.setStatus(http.StatusBadRequest)
+	_, err = p.Token(ctx)
+	if err == nil {
+		t.Fatal("Token succeeded for a rejected API key")
+	}
+	if common.IsTransientError(err) {
+		t.Errorf("Rejected API key was transient: %v", err)
+	}
+
+	iam.setStatus(0)
+	_, err = p.Token(ctx)
+	if err != nil {
+		t.Errorf("Token failed once IAM recovered: %v", err)
+	}
+}
+
+/** Compute resource tokens are read from their file for each request */
+func TestComputeResourceTokenProvider(t *testing.T) {
+	iam := &testIAM{expiresIn: 30}
+	server := httptest.NewServer(iam)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "crtoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	crTokenFile := filepath.Join(dir, "vault-token")
+	ioutil.WriteFile(crTokenFile, []byte("cr-token-1\n"), 0600)
+
+	p := common.NewComputeResourceTokenProvider(crTokenFile, "Profile-1", "")
+	p.IAMURL = server.URL
+	ctx := context.Background()
+	_, err = p.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The platform replaces the file before the token in it expires
+	ioutil.WriteFile(crTokenFile, []byte("cr-token-2"), 0600)
+	_, err = p.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := iam.received()
+	if len(requests) != 2 {
+		t.Fatalf("%d IAM requests sent, expected 2", len(requests))
+	}
+	for i, expected := range []string{"cr-token-1", "cr-token-2"} {
+		params := requests[i]
+		if params.Get("grant_type") !=
+			"urn:ibm:params:oauth:grant-type:cr-token" ||
+			params.Get("cr_token") != expected ||
+			params.Get("profile_id") != "Profile-1" ||
+			params.Get("profile_name") != "" {
+			t.Errorf("Unexpected IAM request parameters %v", params)
+		}
+	}
+
+	p = common.NewComputeResourceTokenProvider(crTokenFile, "", "my-profile")
+	p.IAMURL = server.URL
+	p.Token(ctx)
+	requests = iam.received()
+	if requests[len(requests)-1].Get("profile_name") != "my-profile" {
+		t.Errorf("Profile name not sent: %v", requests[len(requests)-1])
+	}
+
+	p = common.NewComputeResourceTokenProvider(crTokenFile, "", "")
+	p.IAMURL = server.URL
+	_, err = p.Token(ctx)
+	if err == nil {
+		t.Error("Token succeeded without a trusted profile")
+	}
+	p = common.NewComputeResourceTokenProvider(
+		filepath.Join(dir, "missing"), "Profile-1", "")
+	p.IAMURL = server.URL
+	_, err = p.Token(ctx)
+	if err == nil {
+		t.Error("Token succeeded without a compute resource token file")
+	}
+}
+
+/** Token provider that counts the tokens invalidated */
+type countingTokenProvider struct {
+	common.TokenProvider
+	mutex       sync.Mutex
+	invalidated []string
+}
+
+func (p *countingTokenProvider) Invalidate(token string) {
+	p.mutex.Lock()
+	p.invalidated = append(p.invalidated, token)
+	p.mutex.Unlock()
+	p.TokenProvider.Invalidate(token)
+}
+
+/** A rejected token is invalidated and the request sent with a new token */
+func TestHTTPTransportRenewsRejectedToken(t *testing.T) {
+	em := newTestEmulator(t)
+	defer em.Close()
+
+	// The TKE REST API rejects the first token issued
+	iam := &testIAM{expiresIn: 3600}
+	iamServer := httptest.NewServer(iam)
+	defer iamServer.Close()
+	var mutex sync.Mutex
+	rejected := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer token1" {
+				mutex.Lock()
+				rejected++
+				mutex.Unlock()
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			em.ServeHTTP(w, r)
+		}))
+	defer server.Close()
+
+	iamProvider := common.NewIAMAPIKeyTokenProvider("my-api-key")
+	iamProvider.IAMURL = iamServer.URL
+	tokens := &countingTokenProvider{TokenProvider: iamProvider}
+	tr := common.NewHTTPTransport("", server.URL)
+	tr.Tokens = tokens
+
+	hsminfo, err := tkesdk.Query(tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: common.WithOARootKeys(tr, em.OARootKey())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hsminfo) != 2 {
+		t.Errorf("Query returned %d crypto units, expected 2", len(hsminfo))
+	}
+	if rejected == 0 {
+		t.Error("The first token was never sent")
+	}
+	if len(tokens.invalidated) == 0 ||
+		tokens.invalidated[0] != "Bearer token1" {
+		t.Errorf("Invalidated %v, expected the first token",
+			tokens.invalidated)
+	}
+
+	// A token that is rejected again is reported as unauthorized
+	stuck := common.NewHTTPTransport("", server.URL)
+	stuck.Tokens = common.NewStaticTokenProvider("Bearer token1")
+	_, _, _, _, err = stuck.QueryDomains(context.Background(), "instance1")
+	if !common.IsUnauthorizedError(err) {
+		t.Errorf("QueryDomains returned %v, expected an unauthorized error",
+			err)
+	}
+}
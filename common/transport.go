@@ -0,0 +1,162 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Pluggable transport for HTPRequests
+// 10/18/2026    CLH             Add context parameters
+// 10/18/2026    CLH             Add HTTP client to HTTPTransport
+// 10/18/2026    CLH             Add token provider to HTTPTransport
+
+package common
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Sends requests to the crypto units assigned to a crypto instance.          */
+/*                                                                            */
+/* The default implementation is HTTPTransport, which uses the TKE REST API   */
+/* of the IBM Cloud.  Other implementations can be supplied to direct the     */
+/* requests elsewhere, for example to a mock or a local emulator.             */
+/*----------------------------------------------------------------------------*/
+type Transport interface {
+
+	// Lists the crypto units (domains) for a crypto instance.
+	//
+	// Returns the hsm_ids, locations, serial numbers, and hsm_types of
+	// the crypto units, in the same format as SubmitQueryDomainsRequest.
+	// If ctx is cancelled or its deadline passes, ctx.Err() is returned.
+	QueryDomains(ctx context.Context, cryptoInstance string) ([]string,
+		[]string, []string, []string, error)
+
+	// Sends an HTPRequest to the crypto unit identified by hsmId and
+	// returns the HTPResponse string.  If ctx is cancelled or its
+	// deadline passes, ctx.Err() is returned.
+	SubmitHTPRequest(ctx context.Context, cryptoInstance string,
+		hsmId string, htpRequest string) (string, error)
+}
+
+/** Transport that uses the TKE REST API of the IBM Cloud */
+type HTTPTransport struct {
+	AuthToken string
+	URLStart  string
+	Client    *http.Client  // nil to use DefaultHTTPClient
+	Tokens    TokenProvider // when set, used in place of AuthToken
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a transport that uses the TKE REST API                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for requests                       */
+/* urlStart -- the base URL to use for requests, see GetBaseURL               */
+/*----------------------------------------------------------------------------*/
+func NewHTTPTransport(authToken string, urlStart string) *HTTPTransport {
+	return &HTTPTransport{AuthToken: authToken, URLStart: urlStart}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Lists the crypto units for a crypto instance using GET /hsms               */
+/*----------------------------------------------------------------------------*/
+func (t *HTTPTransport) QueryDomains(ctx context.Context,
+	cryptoInstance string) ([]string, []string, []string, []string, error) {
+
+	var hsmIds, locations, serialNums, hsmTypes []string
+	err := t.withToken(ctx, func(authToken string) error {
+		req := CreateGetHsmsRequest(authToken, t.URLStart, cryptoInstance)
+		var err error
+		hsmIds, locations, serialNums, hsmTypes, err =
+			submitQueryDomainsRequest(ctx, t.Client, req)
+		return err
+	})
+	return hsmIds, locations, serialNums, hsmTypes, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends an HTPRequest to a crypto unit using POST /hsms                      */
+/*----------------------------------------------------------------------------*/
+func (t *HTTPTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	var htpResponse string
+	err := t.withToken(ctx, func(authToken string) error {
+		req := CreatePostHsmsRequest(
+			authToken, t.URLStart, cryptoInstance, hsmId, htpRequest)
+		var err error
+		htpResponse, err = submitHTPRequest(ctx, t.Client, req)
+		return err
+	})
+	return htpResponse, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends a request with the authentication token for the transport.           */
+/*                                                                            */
+/* When the transport has a token provider and the token is rejected, the     */
+/* token is invalidated and the request is sent once more with a new token.   */
+/* A rejected request never reaches the crypto unit, so this is safe even for */
+/* signed administrative commands.                                            */
+/*----------------------------------------------------------------------------*/
+func (t *HTTPTransport) withToken(ctx context.Context,
+	send func(authToken string) error) error {
+
+	if t.Tokens == nil {
+		return send(t.AuthToken)
+	}
+	token, err := t.Tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	err = send(token)
+	if !IsUnauthorizedError(err) {
+		return err
+	}
+
+	t.Tokens.Invalidate(token)
+	newToken, tokenErr := t.Tokens.Token(ctx)
+	if tokenErr != nil || newToken == token {
+		// No different token to try
+		return err
+	}
+	return send(newToken)
+}
+
+/** Context that keeps the values of its parent but is never cancelled */
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a context with the same values as ctx that is never cancelled and  */
+/* has no deadline.                                                           */
+/*                                                                            */
+/* Used once a signed administrative command has been sent, so that its       */
+/* response is always collected.  The standard library only gained            */
+/* context.WithoutCancel in Go 1.21.                                          */
+/*----------------------------------------------------------------------------*/
+func WithoutCancel(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
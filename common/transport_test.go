@@ -0,0 +1,103 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Creates an emulator with two crypto units in service instance1 */
+func newTestEmulator(t *testing.T) *emulator.Emulator {
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = em.AddCryptoUnit("instance1", "recovery",
+		"[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX8P)
+	if err == nil {
+		_, err = em.AddCryptoUnit("instance1", "operational",
+			"[us-south].[AZ2-CS2].[00].[04]", emulator.MODEL_CEX7P)
+	}
+	if err != nil {
+		em.Close()
+		t.Fatal(err)
+	}
+	return em
+}
+
+/** HTTPTransport lists and reaches crypto units through the TKE REST API */
+func TestHTTPTransport(t *testing.T) {
+	em := newTestEmulator(t)
+	defer em.Close()
+	server := httptest.NewServer(em)
+	defer server.Close()
+
+	tr := common.NewHTTPTransport("Bearer token", server.URL)
+	ctx := context.Background()
+	ids, locations, serials, types, err := tr.QueryDomains(ctx, "instance1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eids, elocations, eserials, etypes, _ := em.QueryDomains(ctx, "instance1")
+	if !reflect.DeepEqual(ids, eids) ||
+		!reflect.DeepEqual(locations, elocations) ||
+		!reflect.DeepEqual(serials, eserials) ||
+		!reflect.DeepEqual(types, etypes) {
+		t.Errorf("QueryDomains returned %v %v %v %v, expected %v %v %v %v",
+			ids, locations, serials, types, eids, elocations, eserials, etypes)
+	}
+
+	// Requests to the crypto units go through SubmitHTPRequest
+	hsminfo, err := tkesdk.Query(tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: common.WithOARootKeys(tr, em.OARootKey())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hsminfo) != 2 {
+		t.Errorf("Query returned %d crypto units, expected 2", len(hsminfo))
+	}
+
+	_, err = tr.SubmitHTPRequest(ctx, "instance1", "no-such-hsm", "00")
+	if err == nil {
+		t.Error("SubmitHTPRequest succeeded for an unknown crypto unit")
+	}
+	_, _, _, _, err = tr.QueryDomains(ctx, "no-such-instance")
+	if err == nil {
+		t.Error("QueryDomains succeeded for an unknown service instance")
+	}
+}
+
+/** WithoutCancel keeps values but ignores cancellation of its parent */
+func TestWithoutCancel(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithTimeout(
+		context.WithValue(context.Background(), key{}, "value"), time.Hour)
+	cancel()
+
+	ctx := common.WithoutCancel(parent)
+	if ctx.Err() != nil || ctx.Done() != nil {
+		t.Error("Context is cancelled")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Context has a deadline")
+	}
+	if ctx.Value(key{}) != "value" {
+		t.Error("Context lost the value of its parent")
+	}
+}
@@ -0,0 +1,385 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/rest"
+)
+
+/** Default mount paths of the Vault transit secrets engine and AppRole */
+const (
+	DEFAULT_VAULT_TRANSIT_MOUNT = "transit"
+	DEFAULT_VAULT_APPROLE_MOUNT = "approle"
+)
+
+/** Vault transit key type that can be used as a signature key */
+const vaultKeyTypeP521 = "ecdsa-p521"
+
+/** Minimum time before expiration at which an AppRole token is replaced */
+const vaultMinimumRefreshWindow = 30 * time.Second
+
+/*----------------------------------------------------------------------------*/
+/* Identifies a signature key held by the transit secrets engine of a         */
+/* HashiCorp Vault server, and how to authenticate to Vault.                  */
+/*                                                                            */
+/* If RoleID is set, AppRole authentication is used with RoleID and SecretID. */
+/* Otherwise Token is used, or the VAULT_TOKEN environment variable if Token  */
+/* is "".                                                                     */
+/*----------------------------------------------------------------------------*/
+type VaultConfig struct {
+	Address      string       // base URL, such as https://vault:8200
+	Namespace    string       // Vault Enterprise namespace, or ""
+	Mount        string       // transit mount path, "" for the default
+	KeyName      string       // name of the transit key
+	KeyVersion   int          // key version to sign with, 0 for latest
+	Token        string       // Vault token for token authentication
+	RoleID       string       // role ID for AppRole authentication
+	SecretID     string       // secret ID for AppRole authentication
+	AppRoleMount string       // AppRole mount path, "" for the default
+	HTTPClient   *http.Client // nil to use DefaultHTTPClient
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signer for a P521 EC signature key held by the transit secrets engine of a */
+/* HashiCorp Vault server.  The key must have the type ecdsa-p521.  The       */
+/* SHA-512 hash of the data is sent to Vault to be signed, and signatures are */
+/* checked against the public key before they are used.                       */
+/*                                                                            */
+/* With AppRole authentication, the signer logs in when it is created and     */
+/* again before the token expires or after Vault rejects it.                  */
+/*----------------------------------------------------------------------------*/
+type VaultSigner struct {
+	config     VaultConfig
+	keyVersion int
+	publicKey  *ecdsa.PublicKey
+	ski        []byte
+
+	mutex     sync.Mutex
+	token     string
+	refreshAt time.Time // zero if the token is not replaced
+}
+
+/** Body of a Vault response holding a data object */
+type vaultDataResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+/** Fields of a transit key read using GET /v1/{mount}/keys/{name} */
+type vaultTransitKey struct {
+	Type          string `json:"type"`
+	LatestVersion int    `json:"latest_version"`
+	Keys          map[string]struct {
+		PublicKey string `json:"public_key"`
+	} `json:"keys"`
+}
+
+/** Fields of an AppRole login response */
+type vaultLoginResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signer for a signature key held by a Vault transit secrets       */
+/* engine.  The signer authenticates to Vault and reads the public key when   */
+/* it is created.                                                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* config -- identifies the Vault server, the transit key, and the            */
+/*    credentials to use                                                      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *VaultSigner -- signs using the transit key                                */
+/* error -- reports an invalid configuration, a failed login, or a key that   */
+/*    cannot be used as a signature key                                       */
+/*----------------------------------------------------------------------------*/
+func NewVaultSigner(config VaultConfig) (*VaultSigner, error) {
+	address, err := CheckBaseURL(config.Address)
+	if err != nil {
+		return nil, err
+	}
+	config.Address = address
+	config.Mount = strings.Trim(config.Mount, "/")
+	if config.Mount == "" {
+		config.Mount = DEFAULT_VAULT_TRANSIT_MOUNT
+	}
+	config.AppRoleMount = strings.Trim(config.AppRoleMount, "/")
+	if config.AppRoleMount == "" {
+		config.AppRoleMount = DEFAULT_VAULT_APPROLE_MOUNT
+	}
+	if config.KeyName == "" {
+		return nil, errors.New("No Vault transit key name provided")
+	}
+	if config.KeyVersion < 0 {
+		return nil, errors.New("Invalid Vault transit key version: " +
+			strconv.Itoa(config.KeyVersion))
+	}
+	if config.RoleID == "" && config.Token == "" {
+		config.Token = os.Getenv("VAULT_TOKEN")
+		if config.Token == "" {
+			return nil, errors.New("No Vault token or AppRole credentials " +
+				"provided")
+		}
+	}
+
+	s := &VaultSigner{config: config, token: config.Token}
+	err = s.readPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the transit key and checks that it can be used as a signature key.   */
+/*----------------------------------------------------------------------------*/
+func (s *VaultSigner) readPublicKey() error {
+	var key vaultTransitKey
+	err := s.send(func(token string) *rest.Request {
+		req := rest.GetRequest(s.config.Address + "/v1/" + s.config.Mount +
+			"/keys/" + url.PathEscape(s.config.KeyName))
+		return s.withHeaders(req, token)
+	}, &key)
+	if err != nil {
+		return errors.New("Error reading Vault transit key " +
+			s.config.KeyName + "\nMessage: " + err.Error())
+	}
+	if key.Type != vaultKeyTypeP521 {
+		return errors.New("Vault transit key " + s.config.KeyName +
+			" has type " + key.Type + ".  Only " + vaultKeyTypeP521 +
+			" keys can be used as signature keys.")
+	}
+
+	version := s.config.KeyVersion
+	if version == 0 {
+		version = key.LatestVersion
+	}
+	entry, ok := key.Keys[strconv.Itoa(version)]
+	if !ok {
+		return errors.New("Version " + strconv.Itoa(version) + " of Vault " +
+			"transit key " + s.config.KeyName + " is not available.")
+	}
+	publicKey, err := parsePEMPublicKey(entry.PublicKey)
+	if err == nil {
+		_, err = signatureKeyType(publicKey)
+	}
+	ecKey, ok := publicKey.(*ecdsa.PublicKey)
+	if err == nil && !ok {
+		err = errors.New("not an EC public key")
+	}
+	if err != nil {
+		return errors.New("Invalid public key for Vault transit key " +
+			s.config.KeyName + "\nMessage: " + err.Error())
+	}
+
+	s.keyVersion = version
+	s.publicKey = ecKey
+	s.ski = CalculateECKeyHash(*ecKey)
+	return nil
+}
+
+/** Returns the Subject Key Identifier of the signature key */
+func (s *VaultSigner) SKI() []byte {
+	return s.ski
+}
+
+/** Returns KEY_TYPE_P521EC */
+func (s *VaultSigner) KeyType() string {
+	return KEY_TYPE_P521EC
+}
+
+/** Returns the public key read from Vault */
+func (s *VaultSigner) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+/** Returns the version of the transit key used to sign */
+func (s *VaultSigner) KeyVersion() int {
+	return s.keyVersion
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using the transit key.  The SHA-512 hash of the data is sent to */
+/* Vault with prehashed set, and Vault returns an ASN.1 sequence of R and S.  */
+/*----------------------------------------------------------------------------*/
+func (s *VaultSigner) Sign(data []byte) ([]byte, error) {
+	hash := sha512.Sum512(data)
+	body := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(hash[:]),
+		"prehashed":            true,
+		"marshaling_algorithm": "asn1",
+		"key_version":          s.keyVersion,
+	}
+	var result struct {
+		Signature string `json:"signature"`
+	}
+	err := s.send(func(token string) *rest.Request {
+		req := rest.PostRequest(s.config.Address + "/v1/" + s.config.Mount +
+			"/sign/" + url.PathEscape(s.config.KeyName) + "/sha2-512")
+		req.Body(body)
+		return s.withHeaders(req, token)
+	}, &result)
+	if err != nil {
+		return nil, errors.New("Error signing with Vault transit key " +
+			s.config.KeyName + "\nMessage: " + err.Error())
+	}
+
+	// The signature has the form vault:v<version>:<base64 signature>
+	parts := strings.Split(result.Signature, ":")
+	var signature []byte
+	if len(parts) == 3 && parts[0] == "vault" {
+		signature, err = base64.StdEncoding.DecodeString(parts[2])
+	} else {
+		err = errors.New("unexpected signature format")
+	}
+	if err != nil || !verifyDigestSignature(s.publicKey, hash[:], signature) {
+		return nil, errors.New("The signature returned by Vault is not " +
+			"valid for transit key " + s.config.KeyName + ".")
+	}
+	return signature, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends a request to Vault and decodes the data object of the response into  */
+/* result.  With AppRole authentication, logs in first if there is no current */
+/* token, and logs in again and resends the request once if Vault rejects the */
+/* token.                                                                     */
+/*----------------------------------------------------------------------------*/
+func (s *VaultSigner) send(newRequest func(token string) *rest.Request,
+	result interface{}) error {
+
+	token, err := s.currentToken(false)
+	if err != nil {
+		return err
+	}
+	err = s.sendOnce(newRequest(token), result)
+	if rsp, ok := err.(*rest.ErrorResponse); ok && s.config.RoleID != "" &&
+		rsp.StatusCode == http.StatusForbidden {
+		// The token may have expired or been revoked
+		token, err = s.currentToken(true)
+		if err != nil {
+			return err
+		}
+		err = s.sendOnce(newRequest(token), result)
+	}
+	if rsp, ok := err.(*rest.ErrorResponse); ok {
+		return vaultStatusError(rsp)
+	}
+	return err
+}
+
+/** Sends one request and decodes the data object of the response */
+func (s *VaultSigner) sendOnce(req *rest.Request, result interface{}) error {
+	var response vaultDataResponse
+	_, err := newRESTClient(s.config.HTTPClient).Do(req, &response, nil)
+	if err != nil {
+		return err
+	}
+	if len(response.Data) == 0 {
+		return errors.New("data not found in the Vault response")
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+/** Adds the token and namespace headers to a request */
+func (s *VaultSigner) withHeaders(req *rest.Request,
+	token string) *rest.Request {
+
+	req.Set("X-Vault-Token", token)
+	if s.config.Namespace != "" {
+		req.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+	return req
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Vault token to use.  With AppRole authentication, logs in if   */
+/* there is no token, the token is due to be replaced, or relogin is set.     */
+/*----------------------------------------------------------------------------*/
+func (s *VaultSigner) currentToken(relogin bool) (string, error) {
+	if s.config.RoleID == "" {
+		return s.token, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token != "" && !relogin &&
+		(s.refreshAt.IsZero() || time.Now().Before(s.refreshAt)) {
+		return s.token, nil
+	}
+
+	body := map[string]string{
+		"role_id":   s.config.RoleID,
+		"secret_id": s.config.SecretID,
+	}
+	req := rest.PostRequest(s.config.Address + "/v1/auth/" +
+		s.config.AppRoleMount + "/login")
+	req.Body(body)
+	if s.config.Namespace != "" {
+		req.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+	var login vaultLoginResponse
+	_, err := newRESTClient(s.config.HTTPClient).Do(req, &login, nil)
+	if rsp, ok := err.(*rest.ErrorResponse); ok {
+		err = vaultStatusError(rsp)
+	}
+	if err == nil && login.Auth.ClientToken == "" {
+		err = errors.New("client_token not found in the Vault response")
+	}
+	if err != nil {
+		return "", errors.New("Error logging in to Vault using AppRole." +
+			"\nMessage: " + err.Error())
+	}
+
+	s.token = login.Auth.ClientToken
+	s.refreshAt = time.Time{}
+	if login.Auth.LeaseDuration > 0 {
+		lifetime := time.Duration(login.Auth.LeaseDuration) * time.Second
+		refreshWindow := lifetime / 5
+		if refreshWindow < vaultMinimumRefreshWindow {
+			refreshWindow = vaultMinimumRefreshWindow
+		}
+		s.refreshAt = time.Now().Add(lifetime - refreshWindow)
+	}
+	return s.token, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns an error for a Vault error response, using the messages in the     */
+/* errors array of the response when there is one.                            */
+/*----------------------------------------------------------------------------*/
+func vaultStatusError(rsp *rest.ErrorResponse) error {
+	message := rsp.Message
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal([]byte(rsp.Message), &body) == nil &&
+		len(body.Errors) > 0 {
+		message = strings.Join(body.Errors, "; ")
+	}
+	return errors.New("Status code: " + strconv.Itoa(rsp.StatusCode) +
+		"\nMessage: " + message)
+}
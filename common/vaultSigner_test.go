@@ -0,0 +1,391 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package common_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Stand-in for a Vault server with the transit secrets engine mounted at     */
+/* transit and AppRole authentication mounted at approle.  The transit key    */
+/* admin1 has one version for each entry in keys.                             */
+/*----------------------------------------------------------------------------*/
+type testVault struct {
+	keys      []*ecdsa.PrivateKey
+	keyType   string // "" for ecdsa-p521
+	namespace string
+	roleID    string
+	secretID  string
+	lease     int    // lease duration of AppRole tokens, in seconds
+	signature string // returned in place of the signature, if set
+	wrongSign bool   // sign using a different key
+
+	mutex   sync.Mutex
+	tokens  map[string]bool // tokens that are accepted
+	logins  int
+	signing []map[string]interface{} // bodies of the sign requests
+}
+
+func newTestVault(t *testing.T, versions int) *testVault {
+	v := &testVault{tokens: map[string]bool{"root-token": true},
+		roleID: "role1", secretID: "secret1", lease: 3600}
+	for i := 0; i < versions; i++ {
+		v.keys = append(v.keys, p521TestKey(t))
+	}
+	return v
+}
+
+/** Makes every token issued so far invalid, as when tokens are revoked */
+func (v *testVault) revokeTokens() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.tokens = make(map[string]bool)
+}
+
+func (v *testVault) loginCount() int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.logins
+}
+
+func (v *testVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	if r.Header.Get("X-Vault-Namespace") != v.namespace {
+		reply(http.StatusNotFound, map[string][]string{
+			"errors": {"no handler for route"}})
+		return
+	}
+
+	if r.Method == "POST" && r.URL.Path == "/v1/auth/approle/login" {
+		var login map[string]string
+		json.NewDecoder(r.Body).Decode(&login)
+		if login["role_id"] != v.roleID || login["secret_id"] != v.secretID {
+			reply(http.StatusBadRequest, map[string][]string{
+				"errors": {"invalid role or secret ID"}})
+			return
+		}
+		v.logins++
+		token := "approle-token-" + strconv.Itoa(v.logins)
+		v.tokens[token] = true
+		reply(http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": token, "lease_duration": v.lease}})
+		return
+	}
+
+	if !v.tokens[r.Header.Get("X-Vault-Token")] {
+		reply(http.StatusForbidden, map[string][]string{
+			"errors": {"permission denied"}})
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/transit/keys/admin1":
+		keys := make(map[string]interface{})
+		for i, key := range v.keys {
+			der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			keys[strconv.Itoa(i+1)] = map[string]string{"public_key": string(
+				pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}
+		}
+		keyType := v.keyType
+		if keyType == "" {
+			keyType = "ecdsa-p521"
+		}
+		reply(http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"type": keyType, "latest_version": len(v.keys), "keys": keys}})
+
+	case r.Method == "POST" && r.URL.Path == "/v1/transit/sign/admin1/sha2-512":
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		v.signing = append(v.signing, request)
+		version, _ := request["key_version"].(float64)
+		digest, err := base64.StdEncoding.DecodeString(
+			request["input"].(string))
+		if err != nil || request["prehashed"] != true || version < 1 ||
+			int(version) > len(v.keys) {
+			reply(http.StatusBadRequest, map[string][]string{
+				"errors": {"invalid request"}})
+			return
+		}
+		key := v.keys[int(version)-1]
+		if v.wrongSign {
+			key, _ = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		}
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+		der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+		signature := "vault:v" + strconv.Itoa(int(version)) + ":" +
+			base64.StdEncoding.EncodeToString(der)
+		if v.signature != "" {
+			signature = v.signature
+		}
+		reply(http.StatusOK, map[string]interface{}{"data": map[string]string{
+			"signature": signature}})
+
+	default:
+		reply(http.StatusNotFound, map[string][]string{
+			"errors": {"no handler for route"}})
+	}
+}
+
+/** Token authentication, key versions, and Vault Enterprise namespaces */
+func TestVaultSignerToken(t *testing.T) {
+	vault := newTestVault(t, 2)
+	vault.namespace = "team1"
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	config := common.VaultConfig{Address: server.URL, Namespace: "team1",
+		KeyName: "admin1", Token: "root-token"}
+	signer, err := common.NewVaultSigner(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The latest version is used by default
+	if signer.KeyVersion() != 2 || signer.KeyType() != common.KEY_TYPE_P521EC ||
+		!bytes.Equal(signer.SKI(),
+			common.CalculateECKeyHash(vault.keys[1].PublicKey)) {
+		t.Errorf("Signer has version %d and SKI %X", signer.KeyVersion(),
+			signer.SKI())
+	}
+
+	data := []byte("administrative command")
+	signature, err := signer.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.VerifySignature(&vault.keys[1].PublicKey, data, signature) {
+		t.Error("The signature did not verify")
+	}
+	hash := sha512.Sum512(data)
+	request := vault.signing[0]
+	if request["input"] != base64.StdEncoding.EncodeToString(hash[:]) ||
+		request["marshaling_algorithm"] != "asn1" ||
+		request["key_version"] != float64(2) {
+		t.Errorf("Unexpected sign request %v", request)
+	}
+
+	// An earlier version can be chosen
+	config.KeyVersion = 1
+	signer, err = common.NewVaultSigner(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err = signer.Sign(data)
+	if err != nil || !common.VerifySignature(&vault.keys[0].PublicKey, data,
+		signature) {
+		t.Errorf("Signing with version 1 failed: %v", err)
+	}
+	config.KeyVersion = 3
+	if _, err := common.NewVaultSigner(config); err == nil {
+		t.Error("A key version that does not exist was accepted")
+	}
+	config.KeyVersion = 0
+
+	// VAULT_TOKEN is used when no token is given
+	saved, wasSet := os.LookupEnv("VAULT_TOKEN")
+	os.Setenv("VAULT_TOKEN", "root-token")
+	config.Token = ""
+	_, err = common.NewVaultSigner(config)
+	os.Unsetenv("VAULT_TOKEN")
+	if wasSet {
+		os.Setenv("VAULT_TOKEN", saved)
+	}
+	if err != nil {
+		t.Errorf("VAULT_TOKEN was not used: %v", err)
+	}
+
+	// Rejected tokens are reported with the Vault error message, and are
+	// not retried without AppRole
+	config.Token = "other-token"
+	_, err = common.NewVaultSigner(config)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("NewVaultSigner returned %v for a bad token", err)
+	}
+	vault.revokeTokens()
+	if _, err := signer.Sign(data); err == nil {
+		t.Error("Sign succeeded with a revoked token")
+	}
+	if vault.loginCount() != 0 {
+		t.Error("A token signer logged in using AppRole")
+	}
+}
+
+/** AppRole signers log in when created and again after a 403 response */
+func TestVaultSignerAppRole(t *testing.T) {
+	vault := newTestVault(t, 1)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	config := common.VaultConfig{Address: server.URL, KeyName: "admin1",
+		RoleID: "role1", SecretID: "secret1"}
+	signer, err := common.NewVaultSigner(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("administrative command")
+	if _, err := signer.Sign(data); err != nil {
+		t.Fatal(err)
+	}
+	if vault.loginCount() != 1 {
+		t.Errorf("Logged in %d times, expected once", vault.loginCount())
+	}
+
+	vault.revokeTokens()
+	signature, err := signer.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.VerifySignature(&vault.keys[0].PublicKey, data, signature) {
+		t.Error("The signature after logging in again did not verify")
+	}
+	if vault.loginCount() != 2 {
+		t.Errorf("Logged in %d times, expected twice", vault.loginCount())
+	}
+
+	// Tokens close to expiring are replaced before they are used
+	vault.lease = 10
+	signer, err = common.NewVaultSigner(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.Sign(data)
+	if vault.loginCount() != 4 {
+		t.Errorf("Logged in %d times, expected 4", vault.loginCount())
+	}
+
+	config.SecretID = "secret2"
+	_, err = common.NewVaultSigner(config)
+	if err == nil || !strings.Contains(err.Error(), "AppRole") {
+		t.Errorf("NewVaultSigner returned %v for a bad secret ID", err)
+	}
+}
+
+/** Signatures that are malformed or do not verify are rejected */
+func TestVaultSignerSignatureChecks(t *testing.T) {
+	vault := newTestVault(t, 1)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	signer, err := common.NewVaultSigner(common.VaultConfig{
+		Address: server.URL, KeyName: "admin1", Token: "root-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vault.wrongSign = true
+	if _, err := signer.Sign([]byte("data")); err == nil {
+		t.Error("A signature from another key was accepted")
+	}
+	vault.wrongSign = false
+	for _, signature := range []string{
+		"v1:" + base64.StdEncoding.EncodeToString([]byte{0x30, 0x00}),
+		"vault:v1",
+		"vault:v1:not base64!",
+		"vault:v1:" + base64.StdEncoding.EncodeToString([]byte{0x30, 0x00}),
+	} {
+		vault.signature = signature
+		if _, err := signer.Sign([]byte("data")); err == nil {
+			t.Errorf("Signature %q was accepted", signature)
+		}
+	}
+}
+
+/** Only ecdsa-p521 transit keys can be used */
+func TestVaultSignerKeyTypes(t *testing.T) {
+	vault := newTestVault(t, 1)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	config := common.VaultConfig{Address: server.URL, KeyName: "admin1",
+		Token: "root-token"}
+
+	for _, keyType := range []string{"ecdsa-p256", "ecdsa-p384", "rsa-2048",
+		"ed25519", "aes256-gcm96"} {
+		vault.keyType = keyType
+		_, err := common.NewVaultSigner(config)
+		if err == nil || !strings.Contains(err.Error(), keyType) {
+			t.Errorf("NewVaultSigner returned %v for key type %s", err,
+				keyType)
+		}
+	}
+
+	// A P256 key reported as ecdsa-p521 is also rejected
+	vault.keyType = ""
+	vault.keys[0], _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := common.NewVaultSigner(config); err == nil {
+		t.Error("A P256 public key was accepted")
+	}
+
+	for _, bad := range []common.VaultConfig{
+		{Address: "vault:8200", KeyName: "admin1", Token: "root-token"},
+		{Address: server.URL, Token: "root-token"},
+		{Address: server.URL, KeyName: "admin1", Token: "root-token",
+			KeyVersion: -1},
+	} {
+		if _, err := common.NewVaultSigner(bad); err == nil {
+			t.Errorf("NewVaultSigner accepted %+v", bad)
+		}
+	}
+}
+
+/** Vault key URIs create Vault signers, with a token or AppRole */
+func TestVaultKeyURISigner(t *testing.T) {
+	vault := newTestVault(t, 2)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+	host := server.Listener.Addr().String()
+
+	signer, err := common.NewSigner("vault+http://"+host+
+		"/transit/admin1?version=1", "root-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultSigner, ok := signer.(*common.VaultSigner)
+	if !ok || vaultSigner.KeyVersion() != 1 {
+		t.Errorf("NewSigner returned a %T", signer)
+	}
+
+	signature, err := common.SignWithSignatureKey([]byte("data"),
+		"vault+http://"+host+"/admin1?role_id=role1", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.VerifySignature(&vault.keys[1].PublicKey, []byte("data"),
+		signature) {
+		t.Error("The AppRole signature did not verify")
+	}
+	if vault.loginCount() != 1 {
+		t.Errorf("Logged in %d times, expected once", vault.loginCount())
+	}
+}
@@ -0,0 +1,581 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Support M policies greater than 1
+// 10/18/2026    CLH             Keep only the latest importer key
+// 10/18/2026    CLH             Combine complete key parts by exclusive or
+
+package emulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"math/big"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/Logicalis/asn1"
+)
+
+/** Length of a KPH certificate in the proprietary TKE format */
+const KPH_CERTIFICATE_LENGTH = 309
+
+/*----------------------------------------------------------------------------*/
+/* Processes a signed administrative command.                                 */
+/*                                                                            */
+/* The administrative domain, module identifier, transaction counter, and     */
+/* signatures are checked before the command is run.  Once the signatures are */
+/* accepted, the transaction counter in the command becomes the current       */
+/* transaction counter for the domain, even if the command itself fails.      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ds -- the target domain                                                    */
+/* req -- the xcpAdminReq                                                     */
+/* adminBlk -- the decoded xcpAdminBlk from the xcpAdminReq                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* adminResult -- command output, return code, and reason code                */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) processAdminCommand(ds *domainState,
+	req ep11cmds.AdminReq, adminBlk ep11cmds.AdminBlk) adminResult {
+
+	result := cm.checkAdminBlk(ds, adminBlk)
+	if result.returnCode != rcOK {
+		return result
+	}
+
+	// The enveloping import request is not signed.  Each key part is in
+	// a separately signed request.
+	if common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_IMPORT_WK) {
+		return cm.importWK(ds, adminBlk)
+	}
+
+	required, result := ds.requiredSignatures(adminBlk)
+	if result.returnCode != rcOK {
+		return result
+	}
+	result = ds.checkSignatures(req.AdminBlock, req.SignerInfo, required)
+	if result.returnCode != rcOK {
+		return result
+	}
+	ds.transactionCounter = append([]byte(nil), adminBlk.TransactionCounter...)
+
+	input := adminBlk.CmdInput
+	switch {
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_DOM_ADMIN_LOGIN):
+		return ds.addAdmin(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_DOM_ADMIN_LOGOUT):
+		return ds.removeAdmin(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_DOM_SET_ATTR):
+		return ds.setAttributes(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_DOM_ZEROIZE):
+		err := ds.zeroize()
+		if err != nil {
+			panic(err)
+		}
+		return adminOK(nil)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_DOM_CONTROLPOINT_ADD):
+		if len(input) == 0 || len(input) > CONTROL_POINTS_LENGTH {
+			return adminFailed(rcBadArguments, rsnNone)
+		}
+		for i := range input {
+			ds.controlPoints[i] |= input[i]
+		}
+		return adminOK(nil)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_GEN_IMPORTER):
+		return ds.generateImporterKey(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_GEN_WK):
+		return ds.generateWK()
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_EXPORT_WK):
+		return cm.exportWK(ds, ds.currentWK, input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_EXPORT_NEXT_WK):
+		return cm.exportWK(ds, ds.pendingWK, input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_COMMIT_WK):
+		return ds.commitWK(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_FINALIZE_WK):
+		return ds.finalizeWK(input)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_CLEAR_WK):
+		ds.currentWK = nil
+		return adminOK(nil)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_CLEAR_NEXT_WK):
+		ds.pendingWK = nil
+		ds.pendingCommitted = false
+		return adminOK(nil)
+	default:
+		return adminFailed(rcUnsupported, rsnNone)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks the administrative domain, module identifier, and transaction       */
+/* counter in an xcpAdminBlk for a command                                    */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) checkAdminBlk(ds *domainState,
+	adminBlk ep11cmds.AdminBlk) adminResult {
+
+	if !common.ByteSlicesAreEqual(adminBlk.DomainID, ds.domainID()) {
+		return adminFailed(rcBadDomain, rsnNone)
+	}
+	if !common.ByteSlicesAreEqual(adminBlk.ModuleID, cm.moduleID) {
+		return adminFailed(rcBadArguments, rsnNone)
+	}
+	// The transaction counter must increase with each command
+	if len(adminBlk.TransactionCounter) != TRANSACTION_COUNTER_LENGTH {
+		return adminFailed(rcBadTransactionCounter, rsnNone)
+	}
+	var newCounter, oldCounter big.Int
+	newCounter.SetBytes(adminBlk.TransactionCounter)
+	oldCounter.SetBytes(ds.transactionCounter)
+	if newCounter.Cmp(&oldCounter) <= 0 {
+		return adminFailed(rcBadTransactionCounter, rsnNone)
+	}
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the number of signatures needed for a command.                     */
+/*                                                                            */
+/* In imprint mode commands need no signatures, except for the command that   */
+/* sets the signature threshold and ends imprint mode.  That command must be  */
+/* signed by as many administrators as the new signature threshold.           */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) requiredSignatures(adminBlk ep11cmds.AdminBlk) (uint32,
+	adminResult) {
+
+	cmdID := adminBlk.CmdID
+	if ds.inImprintMode() {
+		if common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_DOM_SET_ATTR) {
+			attributes, ok := parseAttributes(adminBlk.CmdInput)
+			if !ok {
+				return 0, adminFailed(rcBadArguments, rsnNone)
+			}
+			return attributes[ep11cmds.XCP_ADMINT_SIGN_THR], adminOK(nil)
+		}
+		return 0, adminOK(nil)
+	}
+
+	switch {
+	case common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_DOM_ADMIN_LOGOUT):
+		return ds.revocationThreshold, adminOK(nil)
+	case common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_DOM_ZEROIZE):
+		if ds.permissions&permitZeroize1Sign != 0 {
+			return 1, adminOK(nil)
+		}
+		return ds.signatureThreshold, adminOK(nil)
+	case common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_GEN_IMPORTER),
+		common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_GEN_WK),
+		common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_FINALIZE_WK),
+		common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_CLEAR_NEXT_WK),
+		common.ByteSlicesAreEqual(cmdID, ep11cmds.XCP_ADM_IMPORT_WK):
+		return 1, adminOK(nil)
+	default:
+		return ds.signatureThreshold, adminOK(nil)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Parses a list of (attribute identifier, value) pairs.  Returns false if    */
+/* the list is malformed.                                                     */
+/*----------------------------------------------------------------------------*/
+func parseAttributes(input []byte) (map[uint32]uint32, bool) {
+	if len(input)%8 != 0 {
+		return nil, false
+	}
+	attributes := make(map[uint32]uint32)
+	for i := 0; i < len(input); i += 8 {
+		id := uint32(common.FourByteSliceToInt(input[i : i+4]))
+		attributes[id] = uint32(common.FourByteSliceToInt(input[i+4 : i+8]))
+	}
+	return attributes, true
+}
+
+/*----------------------------------------------------------------------------*/
+/* Installs a domain administrator                                            */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) addAdmin(input []byte) adminResult {
+	var cert ep11cmds.Certificate
+	_, err := asn1.Decode(input, &cert)
+	if err != nil {
+		return adminFailed(rcInvalidData, rsnNone)
+	}
+	ski := cert.GetSKI()
+	hash := sha256.Sum256(cert.GetPublicKey())
+	if len(ski) != 32 || !common.ByteSlicesAreEqual(ski, hash[:]) {
+		return adminFailed(rcInvalidData, rsnNone)
+	}
+	if ds.findAdmin(ski) >= 0 {
+		return adminFailed(rcAdminExists, rsnAdminExists)
+	}
+	if len(ds.adminSKIs) >= MAX_DOMAIN_ADMINS {
+		return adminFailed(rcTooManyAdmins, rsnTooManyAdmins)
+	}
+	ds.adminSKIs = append(ds.adminSKIs, append([]byte(nil), ski...))
+	ds.adminCerts = append(ds.adminCerts, append([]byte(nil), input...))
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Removes a domain administrator.  Outside of imprint mode, enough           */
+/* administrators must remain to satisfy both signature thresholds.           */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) removeAdmin(ski []byte) adminResult {
+	index := ds.findAdmin(ski)
+	if index < 0 {
+		return adminFailed(rcInvalidData, rsnAdminNotFound)
+	}
+	remaining := uint32(len(ds.adminSKIs) - 1)
+	if !ds.inImprintMode() && (remaining < ds.signatureThreshold ||
+		remaining < ds.revocationThreshold) {
+		return adminFailed(rcInconsistent, rsnBelowThreshold)
+	}
+	ds.adminSKIs = append(ds.adminSKIs[:index], ds.adminSKIs[index+1:]...)
+	ds.adminCerts = append(ds.adminCerts[:index], ds.adminCerts[index+1:]...)
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sets domain attributes.  Attributes not in the input are unchanged.        */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) setAttributes(input []byte) adminResult {
+	attributes, ok := parseAttributes(input)
+	if !ok {
+		return adminFailed(rcBadArguments, rsnNone)
+	}
+
+	newSigThr := ds.signatureThreshold
+	newRevThr := ds.revocationThreshold
+	newPermissions := ds.permissions
+	newMode := ds.operationalMode
+	if value, found := attributes[ep11cmds.XCP_ADMINT_SIGN_THR]; found {
+		newSigThr = value
+	}
+	if value, found := attributes[ep11cmds.XCP_ADMINT_REVOKE_THR]; found {
+		newRevThr = value
+	}
+	if value, found := attributes[ep11cmds.XCP_ADMINT_PERMITS]; found {
+		newPermissions = value
+	}
+	if value, found := attributes[ep11cmds.XCP_ADMINT_MODE]; found {
+		newMode = value
+	}
+
+	imprintMode := ds.inImprintMode()
+	if newSigThr == 0 && !imprintMode {
+		return adminFailed(rcInconsistent, rsnSigThr0)
+	}
+	if newSigThr > 0 && newRevThr == 0 {
+		if imprintMode {
+			return adminFailed(rcInconsistent, rsnExitImprintRevThr0)
+		}
+		return adminFailed(rcInconsistent, rsnRevThr0)
+	}
+	if newSigThr > uint32(len(ds.adminSKIs)) {
+		return adminFailed(rcInconsistent, rsnSigThrTooHigh)
+	}
+	if newRevThr > uint32(len(ds.adminSKIs)) {
+		return adminFailed(rcInconsistent, rsnRevThrTooHigh)
+	}
+
+	// Once the change control bit is reset, neither it nor the do not
+	// disturb bit can be changed
+	if ds.permissions&permitChangeDoNotDisturb == 0 {
+		if newPermissions&permitChangeDoNotDisturb != 0 {
+			return adminFailed(rcInconsistent, rsnSetControlBit)
+		}
+		if (newPermissions^ds.permissions)&permitDoNotDisturb != 0 {
+			return adminFailed(rcInconsistent, rsnControlBitReset)
+		}
+	}
+
+	ds.signatureThreshold = newSigThr
+	ds.revocationThreshold = newRevThr
+	ds.permissions = newPermissions
+	ds.operationalMode = newMode
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates an importer key.  Only P521 EC importer keys are supported.  As  */
+/* in a crypto module, only the latest importer key is kept.                  */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) generateImporterKey(input []byte) adminResult {
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if len(input) != 4 {
+		return adminFailed(rcBadArguments, rsnNone)
+	}
+	if common.FourByteSliceToInt(input) != ep11cmds.XCP_IMPRKEY_EC_P521 {
+		return adminFailed(rcUnsupported, rsnNone)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	ds.importerKey = key
+
+	var pubKey ep11cmds.PublicKeyECP521
+	pubKey.Algorithm.ObjID = asn1.Oid{1, 2, 840, 10045, 2, 1}
+	pubKey.Algorithm.ObjID2 = asn1.Oid{1, 3, 132, 0, 35}
+	pubKey.ThePublicKey = append([]byte{0x00, 0x04}, padTo66(key.X.Bytes())...)
+	pubKey.ThePublicKey = append(pubKey.ThePublicKey, padTo66(key.Y.Bytes())...)
+	output, err := asn1.Encode(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return adminOK(output)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates a random wrapping key.  The current wrapping key register is     */
+/* filled if empty, otherwise the pending wrapping key register.  Returns the */
+/* verification pattern of the new wrapping key.                              */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) generateWK() adminResult {
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if ds.permissions&permitWKRandom == 0 {
+		return adminFailed(rcNotAllowed, rsnRandomWKNotAllowed)
+	}
+	if ds.currentWK != nil && ds.pendingWK != nil {
+		return adminFailed(rcNotAllowed, rsnNone)
+	}
+
+	wk := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, wk)
+	if err != nil {
+		panic(err)
+	}
+	if ds.currentWK == nil {
+		ds.currentWK = wk
+	} else {
+		ds.pendingWK = wk
+		ds.pendingCommitted = false
+	}
+	return adminOK(common.Calc_vp(wk))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports a wrapping key register.                                           */
+/*                                                                            */
+/* The input is a parameter map with the M policy and the KPH certificates.   */
+/* The output is a parameter map with one encrypted key part for each KPH     */
+/* certificate, each with an OA signature.  The wrapping key is split so that */
+/* any M of the key parts can be combined to recover it, see                  */
+/* encryptWKKeyParts.                                                         */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) exportWK(ds *domainState, wk []byte,
+	input []byte) adminResult {
+
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if ds.permissions&permitWKExport == 0 {
+		return adminFailed(rcNotAllowed, rsnExportNotAllowed)
+	}
+	if wk == nil {
+		return adminFailed(rcNotFound, rsnNone)
+	}
+
+	var pMap common.ParameterMap
+	pMap, err := pMap.Load(input)
+	if err != nil {
+		return adminFailed(rcBadArguments, rsnNone)
+	}
+	if !pMap.Contains(common.PMTAG_M_POLICY, 0) {
+		return adminFailed(rcMissingArguments, rsnNone)
+	}
+
+	kphKeys := make([]ecdsa.PublicKey, 0)
+	var index uint32
+	for index = 0; pMap.Contains(common.PMTAG_KPH_CERTIFICATE, index); index++ {
+		kphKey, ok := parseKPHCertificate(
+			pMap.GetDataUsingIndex(common.PMTAG_KPH_CERTIFICATE, index))
+		if !ok {
+			return adminFailed(rcBadDomain, rsnBadKPHCertificate)
+		}
+		kphKeys = append(kphKeys, kphKey)
+	}
+	if len(kphKeys) == 0 {
+		return adminFailed(rcMissingArguments, rsnNone)
+	}
+	mPolicy := int(pMap.GetAuxInt(common.PMTAG_M_POLICY))
+	if mPolicy < 1 || mPolicy > len(kphKeys) || len(kphKeys) > 255 {
+		return adminFailed(rcBadArguments, rsnNone)
+	}
+
+	recipientInfos, err := encryptWKKeyParts(wk, mPolicy, kphKeys)
+	if err != nil {
+		panic(err)
+	}
+	outputMap := common.NewParameterMap()
+	for i, recipientInfo := range recipientInfos {
+		outputMap.Put(common.PMTAG_ENCR_KEY_PART, uint32(i), recipientInfo)
+		outputMap.Put(common.PMTAG_SIGNATURE_ENCR_KEY_PART, uint32(i),
+			cm.oaSignerInfo(recipientInfo))
+	}
+	return adminOK(outputMap.GenerateBytes())
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the P521 EC public key from a KPH certificate in the proprietary   */
+/* TKE format.  Returns false if the certificate is not valid.                */
+/*----------------------------------------------------------------------------*/
+func parseKPHCertificate(cert []byte) (ecdsa.PublicKey, bool) {
+	var pubKey ecdsa.PublicKey
+	if len(cert) != KPH_CERTIFICATE_LENGTH || cert[36] != 'K' ||
+		cert[44] != 0x04 {
+		return pubKey, false
+	}
+	pubKey.Curve = elliptic.P521()
+	pubKey.X = new(big.Int).SetBytes(cert[45:111])
+	pubKey.Y = new(big.Int).SetBytes(cert[111:177])
+	if !pubKey.Curve.IsOnCurve(pubKey.X, pubKey.Y) {
+		return pubKey, false
+	}
+	return pubKey, true
+}
+
+/*----------------------------------------------------------------------------*/
+/* Loads the pending wrapping key register from encrypted key parts.          */
+/*                                                                            */
+/* The command input is a set of concatenated xcpAdminReq, one for each key   */
+/* part.  Each must be signed and must use the same administrative domain,    */
+/* module identifier, and transaction counter as the enveloping request.      */
+/*                                                                            */
+/* Each key part must be encrypted under the latest importer key of the       */
+/* domain.  The key parts are combined by combineKeyParts, depending on the   */
+/* marking in their user key material:                                        */
+/*                                                                            */
+/* - all exported by the emulator with an M policy of 1: the key parts must   */
+/*   be equal, and each is the wrapping key                                   */
+/* - all exported by the emulator with an M policy greater than 1: at least   */
+/*   M different key parts are combined using Shamir secret sharing           */
+/* - none exported by the emulator, such as key parts supplied by a customer: */
+/*   the key parts are combined by exclusive or                               */
+/* - a mixture: the command fails with rcInvalidData                          */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) importWK(ds *domainState,
+	adminBlk ep11cmds.AdminBlk) adminResult {
+
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if ds.permissions&permitWKImport == 0 {
+		return adminFailed(rcNotAllowed, rsnImportNotAllowed)
+	}
+
+	keyParts := make([][]byte, 0)
+	infos := make([]keyPartInfo, 0)
+	marked := make([]bool, 0)
+	rest := adminBlk.CmdInput
+	for len(rest) > 0 {
+		var partReq ep11cmds.AdminReq
+		var err error
+		rest, err = asn1.Decode(rest, &partReq)
+		if err != nil {
+			return adminFailed(rcBadArguments, rsnNone)
+		}
+		var partBlk ep11cmds.AdminBlk
+		_, err = asn1.Decode(partReq.AdminBlock, &partBlk)
+		if err != nil ||
+			!common.ByteSlicesAreEqual(partBlk.CmdID, ep11cmds.XCP_ADM_IMPORT_WK) ||
+			!common.ByteSlicesAreEqual(partBlk.DomainID, adminBlk.DomainID) ||
+			!common.ByteSlicesAreEqual(partBlk.ModuleID, adminBlk.ModuleID) ||
+			!common.ByteSlicesAreEqual(partBlk.TransactionCounter,
+				adminBlk.TransactionCounter) {
+			return adminFailed(rcBadArguments, rsnNone)
+		}
+		result := ds.checkSignatures(partReq.AdminBlock, partReq.SignerInfo, 1)
+		if result.returnCode != rcOK {
+			return result
+		}
+
+		_, ukm, ski, _, err := ep11cmds.ParseRecipientInfoP521EC(
+			partBlk.CmdInput)
+		if err != nil {
+			return adminFailed(rcBadArguments, rsnNone)
+		}
+		if ds.importerKey == nil || !common.ByteSlicesAreEqual(ski,
+			common.CalculateECKeyHash(ds.importerKey.PublicKey)) {
+			return adminFailed(rcNotFound, rsnNone)
+		}
+		keyPart, err := ep11cmds.DecryptKeyPartP521EC(partBlk.CmdInput,
+			ds.importerKey)
+		if err != nil {
+			return adminFailed(rcInvalidData, rsnNone)
+		}
+		info, isMarked := keyPartMarking(ukm)
+		keyParts = append(keyParts, keyPart)
+		infos = append(infos, info)
+		marked = append(marked, isMarked)
+	}
+	ds.transactionCounter = append([]byte(nil), adminBlk.TransactionCounter...)
+
+	if len(keyParts) == 0 {
+		return adminFailed(rcMissingArguments, rsnNone)
+	}
+	if len(keyParts) == 1 && ds.permissions&permitWK1Part == 0 {
+		return adminFailed(rcNotAllowed, rsnOnePartNotAllowed)
+	}
+	wk, ok := combineKeyParts(keyParts, infos, marked)
+	if !ok {
+		return adminFailed(rcInvalidData, rsnNone)
+	}
+	ds.pendingWK = wk
+	ds.pendingCommitted = false
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Commits the pending wrapping key register.  The input is the verification  */
+/* pattern of the pending wrapping key.                                       */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) commitWK(vp []byte) adminResult {
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if ds.pendingWK == nil {
+		return adminFailed(rcNotFound, rsnNone)
+	}
+	if !common.ByteSlicesAreEqual(vp, common.Calc_vp(ds.pendingWK)) {
+		return adminFailed(rcVPMismatch, rsnVPMismatch)
+	}
+	ds.pendingCommitted = true
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Moves the committed pending wrapping key to the current wrapping key       */
+/* register.  The input is the verification pattern of the pending wrapping   */
+/* key.                                                                       */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) finalizeWK(vp []byte) adminResult {
+	if ds.inImprintMode() {
+		return adminFailed(rcImprintMode, rsnNone)
+	}
+	if ds.pendingWK == nil {
+		return adminFailed(rcNotFound, rsnNone)
+	}
+	if !common.ByteSlicesAreEqual(vp, common.Calc_vp(ds.pendingWK)) {
+		return adminFailed(rcVPMismatch, rsnVPMismatch)
+	}
+	if !ds.pendingCommitted {
+		return adminFailed(rcNotAllowed, rsnNone)
+	}
+	ds.currentWK = ds.pendingWK
+	ds.pendingWK = nil
+	ds.pendingCommitted = false
+	return adminOK(nil)
+}
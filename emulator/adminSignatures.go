@@ -0,0 +1,230 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package emulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	goasn1 "encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/Logicalis/asn1"
+)
+
+/** One administrator signature from the SignerInfo of an xcpAdminReq */
+type adminSignature struct {
+	ski                []byte
+	digestAlgorithm    []byte
+	signatureAlgorithm []byte
+	signature          []byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks the administrator signatures on a command.                          */
+/*                                                                            */
+/* Every signature must be from an installed administrator and must be valid. */
+/* The number of different administrators who signed must be at least the    */
+/* number of signatures required.                                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* signedData -- the xcpAdminBlk sequence that was signed                     */
+/* signerInfo -- concatenated SignerInfo sequences, one for each signature    */
+/* required -- number of signatures needed                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* adminResult -- return code and reason code from checking the signatures    */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) checkSignatures(signedData []byte, signerInfo []byte,
+	required uint32) adminResult {
+
+	signatures, err := parseSignerInfo(signerInfo)
+	if err != nil {
+		return adminFailed(rcMalformedSignature, rsnNone)
+	}
+
+	signers := make(map[string]bool)
+	for _, sig := range signatures {
+		index := ds.findAdmin(sig.ski)
+		if index < 0 {
+			return adminFailed(rcUnauthorizedSigner, rsnNone)
+		}
+		var cert ep11cmds.Certificate
+		_, err = asn1.Decode(ds.adminCerts[index], &cert)
+		if err != nil {
+			panic(err)
+		}
+		result := verifySignature(cert.GetPublicKey(), sig, signedData)
+		if result.returnCode != rcOK {
+			return result
+		}
+		signers[hex.EncodeToString(sig.ski)] = true
+	}
+
+	if uint32(len(signers)) < required {
+		return adminFailed(rcNotEnoughSignatures, rsnNone)
+	}
+	return adminOK(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Splits concatenated SignerInfo sequences into their fields.                */
+/*                                                                            */
+/* Each SignerInfo has the form:                                              */
+/* SEQUENCE { INTEGER version, [0] SKI, SEQUENCE digestAlgorithm,             */
+/*            SEQUENCE signatureAlgorithm, OCTET STRING signature }           */
+/*----------------------------------------------------------------------------*/
+func parseSignerInfo(signerInfo []byte) (signatures []adminSignature,
+	err error) {
+
+	// Truncated input shows up as an index out of range
+	defer func() {
+		if r := recover(); r != nil {
+			signatures = nil
+			err = errors.New("Malformed SignerInfo: " + fmt.Sprint(r))
+		}
+	}()
+
+	signatures = make([]adminSignature, 0)
+	offset := 0
+	for offset < len(signerInfo) {
+		fields, err := common.Asn1GetSequenceBytes(signerInfo, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset, err = common.Asn1SkipSequence(signerInfo, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		var sig adminSignature
+		next, err := common.Asn1SkipInteger(fields, 0)
+		if err != nil {
+			return nil, err
+		}
+		if fields[next] != common.ASN1_CONTEXT_SPECIFIC_TAG {
+			return nil, errors.New("Expected SKI not found")
+		}
+		length, err := common.Asn1GetLength(fields, next+1)
+		if err != nil {
+			return nil, err
+		}
+		next, err = common.Asn1SkipLength(fields, next+1)
+		if err != nil {
+			return nil, err
+		}
+		sig.ski = fields[next : next+length]
+		next += length
+
+		sig.digestAlgorithm, err = common.Asn1GetSequenceBytes(fields, next)
+		if err != nil {
+			return nil, err
+		}
+		next, err = common.Asn1SkipSequence(fields, next)
+		if err != nil {
+			return nil, err
+		}
+		sig.signatureAlgorithm, err = common.Asn1GetSequenceBytes(fields, next)
+		if err != nil {
+			return nil, err
+		}
+		next, err = common.Asn1SkipSequence(fields, next)
+		if err != nil {
+			return nil, err
+		}
+		sig.signature, err = common.Asn1GetOctetStringBytes(fields, next)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, sig)
+	}
+	return signatures, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies one administrator signature.                                      */
+/*                                                                            */
+/* P521 EC signatures use SHA-512 and are an encoded ECDSA (r, s) sequence.   */
+/* 2048-bit RSA signatures use SHA-256 with ANSI X9.31 padding.               */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* publicKey -- public key from the administrator certificate                 */
+/* sig -- the signature to be verified                                        */
+/* signedData -- the data that was signed                                     */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* adminResult -- return code and reason code from verifying the signature    */
+/*----------------------------------------------------------------------------*/
+func verifySignature(publicKey []byte, sig adminSignature,
+	signedData []byte) adminResult {
+
+	if hasPrefix(sig.digestAlgorithm, ep11cmds.OID_sha512) &&
+		hasPrefix(sig.signatureAlgorithm, ep11cmds.OID_ecdsaWithSHA512) {
+
+		if len(publicKey) != 133 || publicKey[0] != 0x04 {
+			return adminFailed(rcInvalidSignature, rsnNone)
+		}
+		var pubKey ecdsa.PublicKey
+		pubKey.Curve = elliptic.P521()
+		pubKey.X = new(big.Int).SetBytes(publicKey[1:67])
+		pubKey.Y = new(big.Int).SetBytes(publicKey[67:133])
+
+		var ecSig ep11cmds.ECSignature
+		_, err := goasn1.Unmarshal(sig.signature, &ecSig)
+		if err != nil {
+			return adminFailed(rcMalformedSignature, rsnNone)
+		}
+		hash := sha512.Sum512(signedData)
+		if !ecdsa.Verify(&pubKey, hash[:], ecSig.R, ecSig.S) {
+			return adminFailed(rcInvalidSignature, rsnNone)
+		}
+		return adminOK(nil)
+	}
+
+	if hasPrefix(sig.digestAlgorithm, ep11cmds.OID_sha256) &&
+		hasPrefix(sig.signatureAlgorithm, ep11cmds.OID_rsaEncryption) {
+
+		var modAndExp ep11cmds.ModAndExp
+		_, err := asn1.Decode(publicKey, &modAndExp)
+		if err != nil {
+			return adminFailed(rcInvalidSignature, rsnNone)
+		}
+		if len(sig.signature) != 256 {
+			return adminFailed(rcMalformedSignature, rsnNone)
+		}
+		modulus := new(big.Int).SetBytes(modAndExp.Modulus)
+		s := new(big.Int).SetBytes(sig.signature)
+		s.Exp(s, big.NewInt(int64(modAndExp.Exponent)), modulus)
+		hash := sha256.Sum256(signedData)
+		expected := new(big.Int).SetBytes(
+			common.PadANSIX931(hash[:], 0, len(hash), 2048))
+		if s.Cmp(expected) != 0 {
+			return adminFailed(rcInvalidSignature, rsnNone)
+		}
+		return adminOK(nil)
+	}
+
+	return adminFailed(rcMalformedSignature, rsnNone)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether a byte slice begins with a given prefix                    */
+/*----------------------------------------------------------------------------*/
+func hasPrefix(data []byte, prefix []byte) bool {
+	return len(data) >= len(prefix) &&
+		common.ByteSlicesAreEqual(data[0:len(prefix)], prefix)
+}
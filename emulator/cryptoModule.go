@@ -0,0 +1,234 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Keep only the latest importer key
+
+package emulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Highest domain index supported by an emulated crypto module */
+const MAX_DOMAIN_INDEX = 84
+
+/** Maximum number of administrators that can be installed in a domain */
+const MAX_DOMAIN_ADMINS = 8
+
+/** Number of bytes in the domain control point set */
+const CONTROL_POINTS_LENGTH = 16
+
+/** Number of bytes in the transaction counter */
+const TRANSACTION_COUNTER_LENGTH = 16
+
+/** Domain permissions tested by the emulator */
+const (
+	permitWKImport            uint32 = 0x00000001
+	permitWKExport            uint32 = 0x00000002
+	permitWK1Part             uint32 = 0x00000004
+	permitWKRandom            uint32 = 0x00000008
+	permitZeroize1Sign        uint32 = 0x00000040
+	permitDoNotDisturb        uint32 = 0x00002000
+	permitChangeDoNotDisturb  uint32 = 0x80000000
+	initialDomainPermissions  uint32 = permitChangeDoNotDisturb
+	changeablePermissionsMask uint32 = 0x7FFFFFFF
+)
+
+/** An emulated EP11 crypto module */
+type cryptoModule struct {
+	partialLocation   string
+	cryptoModuleIndex int
+	serialNum         string
+	moduleID          []byte
+	model             string
+
+	// OA signature keys and certificates.  Index 0 is the current epoch
+	// key, index 1 is its parent.  The parent certificate is signed by the
+	// root key of the emulator.
+	oaKeys  []*ecdsa.PrivateKey
+	oaCerts [][]byte
+
+	domains map[int]*domainState
+}
+
+/** State of one domain in an emulated crypto module */
+type domainState struct {
+	index              int
+	instanceID         []byte
+	transactionCounter []byte
+
+	// Installed administrators, parallel slices
+	adminSKIs  [][]byte
+	adminCerts [][]byte
+
+	signatureThreshold  uint32
+	revocationThreshold uint32
+	permissions         uint32
+	operationalMode     uint32
+	standards           uint32
+	controlPoints       []byte
+
+	currentWK        []byte
+	pendingWK        []byte
+	pendingCommitted bool
+
+	// Latest importer key generated in the domain, nil if none
+	importerKey *ecdsa.PrivateKey
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an emulated crypto module with a new OA certificate chain.         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* partialLocation -- location of the crypto module, without domain index     */
+/* cryptoModuleIndex -- crypto module index from the location                 */
+/* serialNum -- 8-character serial number                                     */
+/* model -- MODEL_CEX7P or MODEL_CEX8P                                        */
+/* rootKey -- the key used to sign the topmost OA certificate                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *cryptoModule -- the new crypto module                                     */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func newCryptoModule(partialLocation string, cryptoModuleIndex int,
+	serialNum string, model string, rootKey *ecdsa.PrivateKey) (*cryptoModule, error) {
+
+	if len(serialNum) != 8 {
+		return nil, errors.New("Serial number must be 8 characters")
+	}
+
+	cm := &cryptoModule{
+		partialLocation:   partialLocation,
+		cryptoModuleIndex: cryptoModuleIndex,
+		serialNum:         serialNum,
+		model:             model,
+		domains:           make(map[int]*domainState),
+	}
+	// Serial number padded with blanks to 16 bytes
+	cm.moduleID = []byte(serialNum + "        ")
+
+	for i := 0; i < 2; i++ {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		cm.oaKeys = append(cm.oaKeys, key)
+	}
+
+	// Build the chain from the top down: root --> parent --> epoch key
+	signers := []*ecdsa.PrivateKey{cm.oaKeys[1], rootKey}
+	cm.oaCerts = make([][]byte, 2)
+	for i := 1; i >= 0; i-- {
+		var cert []byte
+		var err error
+		if model == MODEL_CEX8P {
+			cert, err = createOA3Certificate(cm.oaKeys[i].PublicKey,
+				signers[i], serialNum)
+		} else {
+			cert, err = createOA2Certificate(cm.oaKeys[i].PublicKey,
+				signers[i], serialNum)
+		}
+		if err != nil {
+			return nil, err
+		}
+		cm.oaCerts[i] = cert
+	}
+	return cm, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Adds a domain in imprint mode to an emulated crypto module                 */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) addDomain(index int) (*domainState, error) {
+	if index > MAX_DOMAIN_INDEX {
+		return nil, errors.New("Domain index " + strconv.Itoa(index) +
+			" is out of range, maximum is " + strconv.Itoa(MAX_DOMAIN_INDEX))
+	}
+	ds, found := cm.domains[index]
+	if found {
+		return ds, nil
+	}
+	ds = &domainState{
+		index:              index,
+		transactionCounter: make([]byte, TRANSACTION_COUNTER_LENGTH),
+	}
+	err := ds.zeroize()
+	if err != nil {
+		return nil, err
+	}
+	cm.domains[index] = ds
+	return ds, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the domain to its initial state.                                   */
+/*                                                                            */
+/* Administrators, attributes, wrapping keys, and importer keys are removed   */
+/* and a new domain instance identifier is assigned.  The transaction counter */
+/* is preserved so previously signed commands cannot be replayed.             */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) zeroize() error {
+	instanceID := make([]byte, 4)
+	_, err := io.ReadFull(rand.Reader, instanceID)
+	if err != nil {
+		return err
+	}
+	ds.instanceID = instanceID
+	ds.adminSKIs = make([][]byte, 0)
+	ds.adminCerts = make([][]byte, 0)
+	ds.signatureThreshold = 0
+	ds.revocationThreshold = 0
+	ds.permissions = initialDomainPermissions
+	ds.operationalMode = 0
+	ds.standards = 0
+	ds.controlPoints = make([]byte, CONTROL_POINTS_LENGTH)
+	for i := range ds.controlPoints {
+		ds.controlPoints[i] = 0xFF
+	}
+	ds.currentWK = nil
+	ds.pendingWK = nil
+	ds.pendingCommitted = false
+	ds.importerKey = nil
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the 8-byte administrative domain identifier: the domain index      */
+/* followed by the domain instance identifier                                 */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) domainID() []byte {
+	domainID := common.Uint32To4ByteSlice(uint32(ds.index))
+	return append(domainID, ds.instanceID...)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the index of an administrator in the domain, or -1 if not found    */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) findAdmin(ski []byte) int {
+	for i := range ds.adminSKIs {
+		if common.ByteSlicesAreEqual(ds.adminSKIs[i], ski) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether the domain is in imprint mode                              */
+/*----------------------------------------------------------------------------*/
+func (ds *domainState) inImprintMode() bool {
+	return ds.signatureThreshold == 0
+}
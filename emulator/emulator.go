@@ -0,0 +1,489 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add context parameters
+// 10/18/2026    CLH             Trust the OA root key per transport
+// 10/18/2026    CLH             Create stand-in OA signature keys
+
+/*----------------------------------------------------------------------------*/
+/* Package emulator implements a local emulator for the crypto units assigned */
+/* to HPCS service instances.                                                 */
+/*                                                                            */
+/* The emulator can be used in two ways:                                      */
+/* 1. In process, as a common.Transport.  Set CommonInputs.Transport to the   */
+/*    Emulator to direct the TKE SDK functions to it.                         */
+/* 2. As an http.Handler serving GET /v1/tke/{instance}/hsms and              */
+/*    POST /v1/tke/{instance}/hsms/{hsm_id}, the same requests handled by the */
+/*    TKE REST API of the IBM Cloud.  Use httptest.NewServer or http.Serve    */
+/*    and point the HTTPTransport at the server URL.                          */
+/*                                                                            */
+/* HTPRequests using the XPNUM rule are decoded and the EP11 administrative   */
+/* commands and queries they contain are executed against emulated domain    */
+/* state.  Responses are signed by a P521 EC OA key chain generated by the    */
+/* emulator.  The Emulator trusts the root key of the chain when used as a    */
+/* common.Transport.  Over HTTP, attach the key returned by OARootKey to the  */
+/* transport using common.WithOARootKeys, or set CommonInputs.OARootKeys.     */
+/*                                                                            */
+/* The emulator is intended for testing.  It holds all keys in memory and     */
+/* makes no attempt to protect them.                                          */
+/*----------------------------------------------------------------------------*/
+package emulator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Emulates a CEX7P crypto module, with OA certificates in OA2 format */
+const MODEL_CEX7P = "CEX7P"
+
+/** Emulates a CEX8P crypto module, with OA certificates in OA3 format */
+const MODEL_CEX8P = "CEX8P"
+
+/** Returned for requests sent after Close */
+var errClosed = errors.New("The emulator is closed.")
+
+/** An emulator for the crypto units of one or more service instances */
+type Emulator struct {
+	mutex   sync.Mutex
+	rootKey *ecdsa.PrivateKey
+	rootSKI []byte
+	modules []*cryptoModule
+	hsms    []*hsmEntry
+	closed  bool
+}
+
+/** A crypto unit (domain) assigned to a service instance */
+type hsmEntry struct {
+	cryptoInstance string
+	hsmId          string
+	hsmType        string
+	location       string
+	module         *cryptoModule
+	domainIndex    int
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an emulator with no crypto units.                                  */
+/*                                                                            */
+/* A P521 EC root key for signing OA certificates is generated.  Call Close   */
+/* when the emulator is no longer needed.                                     */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *Emulator -- the new emulator                                              */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func NewEmulator() (*Emulator, error) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	em := &Emulator{rootKey: rootKey}
+	em.rootSKI = common.CalculateECKeyHash(rootKey.PublicKey)
+	return em, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Stops the emulator.  Requests sent to it afterwards fail.                  */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) Close() {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.closed = true
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the root key of the OA certificate chains of the emulated crypto   */
+/* modules.  Attach it to a transport using common.WithOARootKeys to verify   */
+/* the OA certificate chains of the emulator when it is reached over HTTP.    */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) OARootKey() common.OARootKey {
+	return common.OARootKey{
+		SKI: em.rootSKI,
+		X:   padTo66(em.rootKey.PublicKey.X.Bytes()),
+		Y:   padTo66(em.rootKey.PublicKey.Y.Bytes())}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the root key of the emulator, so OA certificate chains read using  */
+/* the Emulator as a transport are verified.  Implements                      */
+/* common.OARootKeyHolder.                                                    */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) GetOARootKeys() []common.OARootKey {
+	return []common.OARootKey{em.OARootKey()}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a P521 EC key with an OA certificate chain signed by the root key  */
+/* of the emulator, standing in for the OA signature key of a crypto module.  */
+/* Tests can sign data with the key, such as key parts built outside the      */
+/* emulator, and save the chain with it.  The key is not used by any          */
+/* emulated crypto unit.                                                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* model -- MODEL_CEX7P or MODEL_CEX8P, the format of the OA certificates     */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *ecdsa.PrivateKey -- the OA signature key                                  */
+/* [][]byte -- its OA certificate chain, starting with the epoch certificate  */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) NewOASignatureKey(model string) (*ecdsa.PrivateKey,
+	[][]byte, error) {
+
+	if model != MODEL_CEX7P && model != MODEL_CEX8P {
+		return nil, nil, errors.New("Invalid crypto module model: " + model)
+	}
+	module, err := newCryptoModule("", 0, "EMU00000", model, em.rootKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return module.oaKeys[0], module.oaCerts, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Assigns an emulated crypto unit to a service instance.                     */
+/*                                                                            */
+/* Crypto units whose locations differ only in the domain index are placed   */
+/* in the same emulated crypto module and share its serial number and OA      */
+/* certificate chain.                                                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* cryptoInstance -- identifies the HPCS service instance                     */
+/* hsmType -- "operational", "recovery", or "failover"                        */
+/* location -- location of the crypto unit, in the format                     */
+/*    [zone].[host].[crypto module index].[domain index]                      */
+/* model -- MODEL_CEX7P or MODEL_CEX8P                                        */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the hsm_id assigned to the crypto unit                           */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) AddCryptoUnit(cryptoInstance string, hsmType string,
+	location string, model string) (string, error) {
+
+	if hsmType != "operational" && hsmType != "recovery" &&
+		hsmType != "failover" {
+		return "", errors.New("Invalid crypto unit type: " + hsmType)
+	}
+	if model != MODEL_CEX7P && model != MODEL_CEX8P {
+		return "", errors.New("Invalid crypto module model: " + model)
+	}
+	cryptoModuleIndex, domainIndex, err := parseLocation(location)
+	if err != nil {
+		return "", err
+	}
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+
+	partialLocation := common.GetPartialLocation(location)
+	var module *cryptoModule
+	for _, m := range em.modules {
+		if m.partialLocation == partialLocation {
+			module = m
+			break
+		}
+	}
+	if module == nil {
+		serialNum := fmt.Sprintf("EMU%05d", len(em.modules)+1)
+		module, err = newCryptoModule(partialLocation, cryptoModuleIndex,
+			serialNum, model, em.rootKey)
+		if err != nil {
+			return "", err
+		}
+		em.modules = append(em.modules, module)
+	} else if module.model != model {
+		return "", errors.New("Crypto module at " + partialLocation +
+			" is already defined with model " + module.model)
+	}
+
+	for _, hsm := range em.hsms {
+		if hsm.module == module && hsm.domainIndex == domainIndex {
+			return "", errors.New("Crypto unit at " + location +
+				" is already assigned")
+		}
+	}
+	_, err = module.addDomain(domainIndex)
+	if err != nil {
+		return "", err
+	}
+
+	hsmId, err := newHsmId()
+	if err != nil {
+		return "", err
+	}
+	em.hsms = append(em.hsms, &hsmEntry{
+		cryptoInstance: cryptoInstance,
+		hsmId:          hsmId,
+		hsmType:        hsmType,
+		location:       location,
+		module:         module,
+		domainIndex:    domainIndex})
+	return hsmId, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Lists the crypto units for a crypto instance.  Implements                  */
+/* common.Transport.                                                          */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) QueryDomains(ctx context.Context,
+	cryptoInstance string) ([]string, []string, []string, []string, error) {
+
+	if ctx.Err() != nil {
+		return nil, nil, nil, nil, ctx.Err()
+	}
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	if em.closed {
+		return nil, nil, nil, nil, errClosed
+	}
+
+	hsm_ids := make([]string, 0)
+	locations := make([]string, 0)
+	serial_nums := make([]string, 0)
+	hsm_types := make([]string, 0)
+
+	// Same order as SubmitQueryDomainsRequest: operational, then recovery,
+	// then failover crypto units
+	for _, hsmType := range []string{"operational", "recovery", "failover"} {
+		for _, hsm := range em.hsms {
+			if hsm.cryptoInstance == cryptoInstance && hsm.hsmType == hsmType {
+				hsm_ids = append(hsm_ids, hsm.hsmId)
+				locations = append(locations, hsm.location)
+				serial_nums = append(serial_nums, hsm.module.serialNum)
+				hsm_types = append(hsm_types, hsm.hsmType)
+			}
+		}
+	}
+	if len(hsm_ids) == 0 {
+		return nil, nil, nil, nil, errors.New(
+			"Error querying crypto units." +
+				"\nNo crypto units found for service instance " +
+				cryptoInstance)
+	}
+	return hsm_ids, locations, serial_nums, hsm_types, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Processes an HTPRequest sent to a crypto unit and returns the              */
+/* HTPResponse.  Implements common.Transport.                                 */
+/*                                                                            */
+/* Requests are processed immediately, so ctx is only checked before the      */
+/* request is processed.                                                      */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) SubmitHTPRequest(ctx context.Context, cryptoInstance string,
+	hsmId string, htpRequest string) (string, error) {
+
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	if em.closed {
+		return "", errClosed
+	}
+
+	hsm := em.findHsm(cryptoInstance, hsmId)
+	if hsm == nil {
+		return "", errors.New(
+			"Error sending HTPRequest to target service instance." +
+				"\nCrypto unit " + hsmId + " not found in service instance " +
+				cryptoInstance)
+	}
+	return hsm.module.processHTPRequest(hsm.domainIndex, htpRequest), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Serves the GET /hsms and POST /hsms/{hsm_id} requests of the TKE REST API. */
+/*                                                                            */
+/* The Authorization header is not checked.                                   */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	// Path is /v1/tke/{instance}/hsms or /v1/tke/{instance}/hsms/{hsm_id}
+	if !strings.HasPrefix(r.URL.Path, "/v1/tke/") {
+		http.Error(w, "Resource not found", http.StatusNotFound)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/tke/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "hsms" {
+		http.Error(w, "Resource not found", http.StatusNotFound)
+		return
+	}
+	cryptoInstance := parts[0]
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		em.serveQueryDomains(w, r, cryptoInstance)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body map[string]string
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, &body)
+	}
+	if err != nil || body["request"] == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	htpResponse, err := em.SubmitHTPRequest(r.Context(), cryptoInstance,
+		parts[2], body["request"])
+	if err == errClosed {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Crypto unit not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]string{"response": htpResponse})
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes the response to GET /hsms                                           */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) serveQueryDomains(w http.ResponseWriter, r *http.Request,
+	cryptoInstance string) {
+
+	hsm_ids, locations, serial_nums, hsm_types, err :=
+		em.QueryDomains(r.Context(), cryptoInstance)
+	if err == errClosed {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Service instance not found", http.StatusNotFound)
+		return
+	}
+
+	// The arrays must be present even when empty
+	arrays := map[string][]interface{}{
+		"operational": make([]interface{}, 0),
+		"recovery":    make([]interface{}, 0),
+		"failover":    make([]interface{}, 0),
+	}
+	for i := range hsm_ids {
+		arrays[hsm_types[i]] = append(arrays[hsm_types[i]],
+			map[string]interface{}{
+				"hsm_id":        hsm_ids[i],
+				"location":      locations[i],
+				"state":         "ACTIVE",
+				"version":       1,
+				"serial_number": serial_nums[i],
+			})
+	}
+	writeJSON(w, map[string]interface{}{
+		"metadata": []interface{}{
+			map[string]interface{}{
+				"collectionType":  "application/vnd.ibm.tke.hsm+json",
+				"collectionTotal": len(hsm_ids),
+			},
+		},
+		"hsms":          arrays["operational"],
+		"source_hsms":   arrays["recovery"],
+		"failover_hsms": arrays["failover"],
+	})
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes a JSON response body                                                */
+/*----------------------------------------------------------------------------*/
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the crypto unit with the given hsm_id, or nil if not found         */
+/*----------------------------------------------------------------------------*/
+func (em *Emulator) findHsm(cryptoInstance string, hsmId string) *hsmEntry {
+	for _, hsm := range em.hsms {
+		if hsm.cryptoInstance == cryptoInstance && hsm.hsmId == hsmId {
+			return hsm
+		}
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the crypto module index and domain index from a location string.   */
+/* Unlike common.GetDomainIndexFromLocation, reports errors instead of        */
+/* panicking.                                                                 */
+/*----------------------------------------------------------------------------*/
+func parseLocation(location string) (int, int, error) {
+	parts := strings.Split(location, ".")
+	if len(parts) != 4 {
+		return 0, 0, errors.New("Invalid location format: " + location)
+	}
+	indexes := make([]int, 2)
+	for i, part := range parts[2:] {
+		if len(part) < 3 || part[0] != '[' || part[len(part)-1] != ']' {
+			return 0, 0, errors.New("Invalid location format: " + location)
+		}
+		index, err := strconv.Atoi(part[1 : len(part)-1])
+		if err != nil || index < 0 {
+			return 0, 0, errors.New("Invalid location format: " + location)
+		}
+		indexes[i] = index
+	}
+	return indexes[0], indexes[1], nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates a random hsm_id in the format of a UUID                          */
+/*----------------------------------------------------------------------------*/
+func newHsmId() (string, error) {
+	b := make([]byte, 16)
+	_, err := io.ReadFull(rand.Reader, b)
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0F) | 0x40
+	b[8] = (b[8] & 0x3F) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" +
+		h[20:32], nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Left pads a big-endian value with zeroes to 66 bytes                       */
+/*----------------------------------------------------------------------------*/
+func padTo66(value []byte) []byte {
+	padded := make([]byte, 66)
+	copy(padded[66-len(value):], value)
+	return padded
+}
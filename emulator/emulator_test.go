@@ -0,0 +1,517 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test key part marking and importer keys
+// 10/18/2026    CLH             Test Close
+// 10/18/2026    CLH             Test the authToken and urlStart functions
+
+package emulator_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+const testInstance = "instance1"
+
+/*----------------------------------------------------------------------------*/
+/* Creates an emulator with one recovery and one operational crypto unit,     */
+/* runs Update with a signature threshold of 2, and returns the emulator, the */
+/* CommonInputs, the signers of the administrators, and the domains.  The     */
+/* caller must close the emulator.                                            */
+/*----------------------------------------------------------------------------*/
+func newInitializedInstance(t *testing.T) (*emulator.Emulator,
+	tkesdk.CommonInputs, []common.Signer, []common.DomainEntry) {
+
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = em.AddCryptoUnit(testInstance, "recovery",
+		"[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX8P)
+	if err == nil {
+		_, err = em.AddCryptoUnit(testInstance, "operational",
+			"[us-south].[AZ2-CS2].[00].[04]", emulator.MODEL_CEX7P)
+	}
+	if err != nil {
+		em.Close()
+		t.Fatal(err)
+	}
+	ci := tkesdk.CommonInputs{InstanceId: testInstance, Transport: em}
+
+	hc := tkesdk.HsmConfig{SignatureThreshold: 2, RevocationThreshold: 2,
+		MasterKeyParts: 1, MasterKeyPartsRequired: 1}
+	signers := make([]common.Signer, 2)
+	for i := range signers {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err == nil {
+			signers[i], err = common.NewPrivateKeySigner(key)
+		}
+		if err != nil {
+			em.Close()
+			t.Fatal(err)
+		}
+		hc.Admins = append(hc.Admins, tkesdk.AdminInfo{
+			Name: "admin" + string(rune('1'+i)), Signer: signers[i]})
+	}
+	problems, err := tkesdk.Update(ci, hc)
+	if err == nil && len(problems) > 0 {
+		t.Errorf("Update reported problems: %v", problems)
+	}
+	domains, err2 := tkesdk.GetDomains(ci)
+	if err == nil {
+		err = err2
+	}
+	if err != nil || len(problems) > 0 {
+		em.Close()
+		t.Fatal(err)
+	}
+	return em, ci, signers, domains
+}
+
+/** Returns the first domain of the given type */
+func domainOfType(t *testing.T, domains []common.DomainEntry,
+	hsmType string) common.DomainEntry {
+
+	for _, de := range domains {
+		if de.Type == hsmType {
+			return de
+		}
+	}
+	t.Fatal("No " + hsmType + " crypto unit")
+	return common.DomainEntry{}
+}
+
+/** Update installs the administrators and a shared random master key */
+func TestUpdate(t *testing.T) {
+	em, ci, _, _ := newInitializedInstance(t)
+	defer em.Close()
+
+	hsminfo, err := tkesdk.Query(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hsminfo) != 2 {
+		t.Fatalf("Query returned %d crypto units, expected 2", len(hsminfo))
+	}
+	for _, hsm := range hsminfo {
+		if hsm.SignatureThreshold != 2 || hsm.RevocationThreshold != 2 {
+			t.Errorf("Crypto unit %s has thresholds %d and %d",
+				hsm.HsmLocation, hsm.SignatureThreshold,
+				hsm.RevocationThreshold)
+		}
+		if len(hsm.Admins) != 2 {
+			t.Errorf("Crypto unit %s has %d administrators", hsm.HsmLocation,
+				len(hsm.Admins))
+		}
+		if hsm.CurrentMKStatus != "Valid" || hsm.NewMKStatus != "Empty" {
+			t.Errorf("Crypto unit %s has master key status %q and %q",
+				hsm.HsmLocation, hsm.CurrentMKStatus, hsm.NewMKStatus)
+		}
+		if hsm.CurrentMKVP[:56] != hsminfo[0].CurrentMKVP[:56] {
+			t.Error("Crypto units have different master keys")
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A master key exported from the recovery crypto unit under an importer key  */
+/* of the operational crypto unit is imported into its new master key         */
+/* register.                                                                  */
+/*----------------------------------------------------------------------------*/
+func TestExportImportWK(t *testing.T) {
+	em, _, signers, domains := newInitializedInstance(t)
+	defer em.Close()
+	recovery := domainOfType(t, domains, "recovery")
+	operational := domainOfType(t, domains, "operational")
+
+	recoveryInfo, err := ep11cmds.QueryDomainInfoWithTransport(em, recovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importerKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithTransport(em,
+		operational, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfile := ep11cmds.ExportWKParameterFile(ep11cmds.KPHCert(importerKey))
+
+	// Exporting needs the signature threshold and a recovery crypto unit
+	_, err = ep11cmds.ExportWKWithTransport(em, recovery, pfile, signers[:1])
+	if err == nil {
+		t.Error("Master key exported with one signature")
+	}
+	_, err = ep11cmds.ExportWKWithTransport(em, operational, pfile, signers)
+	if err == nil {
+		t.Error("Master key exported from an operational crypto unit")
+	}
+
+	pdata, err := ep11cmds.ExportWKWithTransport(em, recovery, pfile, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyParts, err := ep11cmds.ParseEncryptedKeyParts(pdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyParts) != 1 {
+		t.Fatalf("Export returned %d key parts, expected 1", len(keyParts))
+	}
+	err = ep11cmds.VerifyKeyPartSignature(keyParts[0], recovery)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// A key part encrypted for a different importer key is rejected
+	otherKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ep11cmds.EncryptKeyPartP521EC(otherKey.PublicKey,
+		make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ep11cmds.ImportWKWithTransport(
+		em, operational, [][]byte{other}, signers[:1])
+	if err == nil {
+		t.Error("Key part for a different importer key was imported")
+	}
+
+	err = ep11cmds.ImportWKWithTransport(em, operational,
+		[][]byte{keyParts[0].RecipientInfo}, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	operationalInfo, err := ep11cmds.QueryDomainInfoWithTransport(
+		em, operational)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operationalInfo.NewMKStatus != ep11cmds.NMK_STATUS_FULL_UNCOMMITTED {
+		t.Errorf("New master key register status is %d",
+			operationalInfo.NewMKStatus)
+	}
+	if !bytes.Equal(operationalInfo.NewMKVP[:28],
+		recoveryInfo.CurrentMKVP[:28]) {
+		t.Error("Imported master key differs from the exported master key")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Key parts exported with an M policy are marked in their user key material, */
+/* and are combined on import according to the marking                        */
+/*----------------------------------------------------------------------------*/
+func TestImportWKKeyParts(t *testing.T) {
+	em, _, signers, domains := newInitializedInstance(t)
+	defer em.Close()
+	recovery := domainOfType(t, domains, "recovery")
+	operational := domainOfType(t, domains, "operational")
+	recoveryInfo, err := ep11cmds.QueryDomainInfoWithTransport(em, recovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Exports the master key under one new importer key of the operational
+	// crypto unit
+	export := func(parts int, mPolicy int) []ep11cmds.EncryptedKeyPart {
+		importerKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithTransport(
+			em, operational, signers[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		kphcerts := make([][]byte, parts)
+		for i := range kphcerts {
+			kphcerts[i] = ep11cmds.KPHCert(importerKey)
+		}
+		keyParts, err := ep11cmds.ExportWKKeyParts(em, recovery, kphcerts,
+			mPolicy, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keyParts
+	}
+	// Imports key parts and returns the new master key verification pattern
+	load := func(keyParts ...[]byte) ([]byte, error) {
+		err := ep11cmds.ImportWKWithTransport(
+			em, operational, keyParts, signers[:1])
+		if err != nil {
+			return nil, err
+		}
+		info, err := ep11cmds.QueryDomainInfoWithTransport(em, operational)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.NewMKVP, nil
+	}
+	isMasterKey := func(vp []byte) bool {
+		return len(vp) >= 28 &&
+			bytes.Equal(vp[:28], recoveryInfo.CurrentMKVP[:28])
+	}
+
+	// The user key material records the M policy and x value
+	keyParts := export(3, 2)
+	for i, keyPart := range keyParts {
+		_, ukm, _, _, err := ep11cmds.ParseRecipientInfoP521EC(
+			keyPart.RecipientInfo)
+		if err != nil || ukm[8] != 2 || ukm[9] != byte(i+1) {
+			t.Errorf("Key part %d has user key material %X", i, ukm)
+		}
+	}
+
+	// Any two of three key parts give the master key, and one does not
+	for _, pair := range [][2]int{{0, 1}, {0, 2}, {2, 1}} {
+		vp, err := load(keyParts[pair[0]].RecipientInfo,
+			keyParts[pair[1]].RecipientInfo)
+		if err != nil || !isMasterKey(vp) {
+			t.Errorf("Key parts %v loaded %X, %v", pair, vp, err)
+		}
+	}
+	if _, err := load(keyParts[0].RecipientInfo); err == nil {
+		t.Error("One of two needed key parts was imported")
+	}
+	if _, err := load(keyParts[0].RecipientInfo,
+		keyParts[0].RecipientInfo); err == nil {
+		t.Error("The same key part was imported twice")
+	}
+
+	// Key parts exported with an M policy of 1 are each the master key
+	keyParts = export(2, 1)
+	vp, err := load(keyParts[0].RecipientInfo, keyParts[1].RecipientInfo)
+	if err != nil || !isMasterKey(vp) {
+		t.Errorf("Key parts with an M policy of 1 loaded %X, %v", vp, err)
+	}
+
+	// Unmarked key parts are combined by exclusive or, and cannot be mixed
+	// with marked key parts
+	importerKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithTransport(em,
+		operational, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	part1 := bytes.Repeat([]byte{0x5A}, 32)
+	part2 := bytes.Repeat([]byte{0x0F}, 32)
+	customer1, _ := ep11cmds.EncryptKeyPartP521EC(importerKey, part1)
+	customer2, _ := ep11cmds.EncryptKeyPartP521EC(importerKey, part2)
+	vp, err = load(customer1, customer2)
+	if err != nil || !bytes.Equal(vp[:28], common.Calc_vp(
+		bytes.Repeat([]byte{0x55}, 32))[:28]) {
+		t.Errorf("Customer key parts loaded %X, %v", vp, err)
+	}
+	kphcerts := [][]byte{ep11cmds.KPHCert(importerKey),
+		ep11cmds.KPHCert(importerKey)}
+	keyParts, err = ep11cmds.ExportWKKeyParts(em, recovery, kphcerts, 1,
+		signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(keyParts[0].RecipientInfo, customer1); err == nil {
+		t.Error("A marked and an unmarked key part were imported together")
+	}
+
+	// Only the latest importer key is kept
+	export(1, 1)
+	if _, err := load(keyParts[0].RecipientInfo,
+		keyParts[1].RecipientInfo); err == nil {
+		t.Error("Key parts for an earlier importer key were imported")
+	}
+}
+
+/** The HTTP handler serves the TKE REST API paths and rejects others */
+func TestServeHTTP(t *testing.T) {
+	em, _, _, domains := newInitializedInstance(t)
+	defer em.Close()
+	server := httptest.NewServer(em)
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/v1/tke/" + testInstance + "/hsms", "", http.StatusOK},
+		{"GET", "/v1/tke/unknown/hsms", "", http.StatusNotFound},
+		{"GET", "/v2/tke/" + testInstance + "/hsms", "", http.StatusNotFound},
+		{"GET", "/v1/tke/" + testInstance + "/other", "", http.StatusNotFound},
+		{"PUT", "/v1/tke/" + testInstance + "/hsms", "",
+			http.StatusMethodNotAllowed},
+		{"GET", "/v1/tke/" + testInstance + "/hsms/" + domains[0].Hsm_id, "",
+			http.StatusMethodNotAllowed},
+		{"POST", "/v1/tke/" + testInstance + "/hsms/" + domains[0].Hsm_id,
+			"{}", http.StatusBadRequest},
+		{"POST", "/v1/tke/" + testInstance + "/hsms/unknown",
+			`{"request":"x"}`, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, server.URL+test.path,
+			strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode != test.status {
+			t.Errorf("%s %s returned %d, expected %d", test.method,
+				test.path, rsp.StatusCode, test.status)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* The functions taking an authority token, a base URL, and signature key     */
+/* files send their requests over HTTP and sign using the key files           */
+/*----------------------------------------------------------------------------*/
+func TestAuthTokenFunctions(t *testing.T) {
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer em.Close()
+	_, err = em.AddCryptoUnit(testInstance, "operational",
+		"[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX7P)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domains, err := tkesdk.GetDomains(tkesdk.CommonInputs{
+		InstanceId: testInstance, Transport: em})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(em)
+	defer server.Close()
+
+	sigkey := filepath.Join(t.TempDir(), "admin1.sigkey")
+	ski, err := tkesdk.CreateSignatureKeyFile(sigkey, common.KEY_TYPE_P521EC,
+		"password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tkesdk.CreateAdminCertFromFile(sigkey, ski, "password",
+		"admin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first administrator is added in imprint mode, without signatures
+	err = ep11cmds.AddDomainAdmin("token", server.URL, domains[0], cert,
+		nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admins, err := ep11cmds.QueryDomainAdmins("token", server.URL,
+		domains[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(admins) != 1 {
+		t.Errorf("The domain has %d administrators", len(admins))
+	}
+	err = tkesdk.SetDomainAttributes("token", server.URL, domains[0], 1, 1,
+		[]string{sigkey}, []string{ski}, []string{"password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, _, err := ep11cmds.QueryDomainAttributes("token", server.URL,
+		domains[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.SignatureThreshold != 1 {
+		t.Errorf("The signature threshold is %d", attrs.SignatureThreshold)
+	}
+
+	// The Subject Key Identifier must match the signature key
+	otherSKI := strings.Repeat("00", 32)
+	err = ep11cmds.ZeroizeDomain("token", server.URL, domains[0],
+		[]string{sigkey}, []string{otherSKI}, []string{"password"})
+	if err == nil {
+		t.Error("A signature key was used with the wrong SKI")
+	}
+	admins, err = ep11cmds.QueryDomainAdmins("token", server.URL,
+		domains[0])
+	if err != nil || len(admins) != 1 {
+		t.Errorf("The domain has %d administrators: %v", len(admins), err)
+	}
+}
+
+/** Requests sent after Close fail, in process and over HTTP */
+func TestClose(t *testing.T) {
+	em, ci, _, domains := newInitializedInstance(t)
+	server := httptest.NewServer(em)
+	defer server.Close()
+	em.Close()
+
+	if _, err := tkesdk.Query(ci); err == nil {
+		t.Error("Query succeeded after Close")
+	}
+	_, err := em.SubmitHTPRequest(context.Background(), testInstance,
+		domains[0].Hsm_id, "00")
+	if err == nil {
+		t.Error("SubmitHTPRequest succeeded after Close")
+	}
+	rsp, err := http.Get(server.URL + "/v1/tke/" + testInstance + "/hsms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /hsms after Close returned %d", rsp.StatusCode)
+	}
+}
+
+/** AddCryptoUnit rejects invalid and duplicate crypto units */
+func TestAddCryptoUnitErrors(t *testing.T) {
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer em.Close()
+	location := "[us-south].[AZ1-CS1].[00].[03]"
+	if _, err = em.AddCryptoUnit(testInstance, "recovery", location,
+		emulator.MODEL_CEX8P); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hsmType  string
+		location string
+		model    string
+	}{
+		{"backup", "[us-south].[AZ1-CS1].[00].[04]", emulator.MODEL_CEX8P},
+		{"operational", "[us-south].[AZ1-CS1].[00].[04]", "CEX6P"},
+		{"operational", "[us-south].[AZ1-CS1]", emulator.MODEL_CEX8P},
+		{"operational", location, emulator.MODEL_CEX8P},
+		{"operational", "[us-south].[AZ1-CS1].[00].[04]",
+			emulator.MODEL_CEX7P},
+	}
+	for _, test := range tests {
+		_, err = em.AddCryptoUnit(testInstance, test.hsmType, test.location,
+			test.model)
+		if err == nil {
+			t.Errorf("Crypto unit %s %s %s was added", test.hsmType,
+				test.location, test.model)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Sign encrypted key parts with the OA key
+
+package emulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	goasn1 "encoding/asn1"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/Logicalis/asn1"
+)
+
+/** EP11 return codes reported by the emulator */
+const (
+	rcOK                    uint32 = 0
+	rcBadDomain             uint32 = 3
+	rcBadArguments          uint32 = 7
+	rcInvalidData           uint32 = 32
+	rcMissingArguments      uint32 = 33
+	rcNotAllowed            uint32 = 80
+	rcUnsupported           uint32 = 84
+	rcNotFound              uint32 = 96
+	rcVPMismatch            uint32 = 101
+	rcBadTransactionCounter uint32 = 163
+	rcImprintMode           uint32 = 179
+	rcInvalidSignature      uint32 = 192
+	rcMalformedSignature    uint32 = 193
+	rcNotEnoughSignatures   uint32 = 208
+	rcInconsistent          uint32 = 209
+	rcAdminExists           uint32 = 256
+	rcUnauthorizedSigner    uint32 = 257
+	rcTooManyAdmins         uint32 = 261
+)
+
+/** EP11 reason codes reported by the emulator */
+const (
+	rsnNone               uint32 = 0
+	rsnAdminNotFound      uint32 = 32
+	rsnAdminExists        uint32 = 38
+	rsnTooManyAdmins      uint32 = 39
+	rsnVPMismatch         uint32 = 48
+	rsnRandomWKNotAllowed uint32 = 52
+	rsnExitImprintRevThr0 uint32 = 56
+	rsnSigThrTooHigh      uint32 = 57
+	rsnRevThrTooHigh      uint32 = 58
+	rsnCertNotFound       uint32 = 60
+	rsnBelowThreshold     uint32 = 66
+	rsnSetControlBit      uint32 = 69
+	rsnControlBitReset    uint32 = 70
+	rsnSigThr0            uint32 = 76
+	rsnRevThr0            uint32 = 77
+	rsnImportNotAllowed   uint32 = 80
+	rsnOnePartNotAllowed  uint32 = 81
+	rsnBadKPHCertificate  uint32 = 102
+	rsnExportNotAllowed   uint32 = 131
+)
+
+/** Outcome of an administrative command or query */
+type adminResult struct {
+	output     []byte
+	returnCode uint32
+	reasonCode uint32
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a successful adminResult                                           */
+/*----------------------------------------------------------------------------*/
+func adminOK(output []byte) adminResult {
+	return adminResult{output, rcOK, rsnNone}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a failing adminResult                                              */
+/*----------------------------------------------------------------------------*/
+func adminFailed(returnCode uint32, reasonCode uint32) adminResult {
+	return adminResult{nil, returnCode, reasonCode}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Processes the payload of an EP11 request CPRB and returns the payload for  */
+/* the response CPRB.                                                         */
+/*                                                                            */
+/* m_admin requests (administrative commands and queries) and get_xcp_info   */
+/* requests for module and domain information are supported.                  */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) processEP11Request(ds *domainState, request []byte) []byte {
+
+	sequence, err := common.Asn1GetSequenceBytes(request, 0)
+	if err != nil {
+		return adminErrorResponse(ep11cmds.FNID_ADMIN,
+			common.Uint32To4ByteSlice(uint32(ds.index)), rcBadArguments)
+	}
+	functionID, err := common.Asn1GetOctetStringBytes(sequence, 0)
+	if err != nil {
+		return adminErrorResponse(ep11cmds.FNID_ADMIN,
+			common.Uint32To4ByteSlice(uint32(ds.index)), rcBadArguments)
+	}
+
+	if common.ByteSlicesAreEqual(functionID, ep11cmds.FNID_GET_XCP_INFO) {
+		var req ep11cmds.XCPReq
+		_, err = asn1.Decode(request, &req)
+		if err != nil {
+			return adminErrorResponse(functionID,
+				common.Uint32To4ByteSlice(uint32(ds.index)), rcBadArguments)
+		}
+		return cm.processXCPInfoRequest(ds, req)
+	}
+
+	if !common.ByteSlicesAreEqual(functionID, ep11cmds.FNID_ADMIN) {
+		return adminErrorResponse(functionID,
+			common.Uint32To4ByteSlice(uint32(ds.index)), rcUnsupported)
+	}
+
+	var req ep11cmds.AdminReq
+	_, err = asn1.Decode(request, &req)
+	if err != nil {
+		return adminErrorResponse(functionID,
+			common.Uint32To4ByteSlice(uint32(ds.index)), rcBadArguments)
+	}
+	var adminBlk ep11cmds.AdminBlk
+	_, err = asn1.Decode(req.AdminBlock, &adminBlk)
+	if err != nil || len(adminBlk.CmdID) != 4 {
+		return adminErrorResponse(functionID, req.DomainID, rcBadArguments)
+	}
+
+	var result adminResult
+	if adminBlk.CmdID[0] == 0x00 && adminBlk.CmdID[1] == 0x01 {
+		result = cm.processAdminQuery(ds, adminBlk)
+	} else {
+		result = cm.processAdminCommand(ds, req, adminBlk)
+	}
+
+	// Build the xcpAdminRspBlk.  For errors, the reason code is returned
+	// in the first four bytes of the command output.
+	var rspBlk ep11cmds.AdminRspBlk
+	rspBlk.CmdID = adminBlk.CmdID
+	rspBlk.DomainID = adminBlk.DomainID
+	if len(adminBlk.DomainID) == 8 &&
+		common.FourByteSliceToInt(adminBlk.DomainID[0:4]) == ds.index {
+		// Report the current domain instance identifier.  Signed commands
+		// must include it.
+		rspBlk.DomainID = ds.domainID()
+	}
+	rspBlk.ModuleID = cm.moduleID
+	rspBlk.TransactionCounter = ds.transactionCounter
+	rspBlk.ReturnCode = common.Uint32To4ByteSlice(result.returnCode)
+	if result.returnCode == rcOK {
+		rspBlk.CmdOutput = result.output
+	} else if result.reasonCode != rsnNone {
+		rspBlk.CmdOutput = common.Uint32To4ByteSlice(result.reasonCode)
+	}
+	return cm.signedAdminResponse(req.DomainID, rspBlk)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Processes an administrative query.  Queries are not signed.                */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) processAdminQuery(ds *domainState,
+	adminBlk ep11cmds.AdminBlk) adminResult {
+
+	input := adminBlk.CmdInput
+
+	// OA certificates belong to the crypto module, not the domain
+	if common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADMQ_DEVICE_CERT) {
+		if len(input) == 0 {
+			return adminOK(common.Uint32To4ByteSlice(uint32(len(cm.oaCerts))))
+		}
+		if len(input) != 4 {
+			return adminFailed(rcBadArguments, rsnNone)
+		}
+		certIndex := common.FourByteSliceToInt(input)
+		if certIndex < 0 || certIndex >= len(cm.oaCerts) {
+			return adminFailed(rcNotFound, rsnCertNotFound)
+		}
+		return adminOK(cm.oaCerts[certIndex])
+	}
+
+	if len(adminBlk.DomainID) < 4 ||
+		common.FourByteSliceToInt(adminBlk.DomainID[0:4]) != ds.index {
+		return adminFailed(rcBadDomain, rsnNone)
+	}
+
+	switch {
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADMQ_DOMADMIN):
+		if len(input) == 0 {
+			skis := make([]byte, 0)
+			for _, ski := range ds.adminSKIs {
+				skis = append(skis, ski...)
+			}
+			return adminOK(skis)
+		}
+		index := ds.findAdmin(input)
+		if index < 0 {
+			return adminFailed(rcInvalidData, rsnAdminNotFound)
+		}
+		return adminOK(ds.adminCerts[index])
+
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADMQ_DOM_ATTRS):
+		attributes := make([]byte, 0)
+		attributes = appendAttribute(attributes, ep11cmds.XCP_ADMINT_SIGN_THR,
+			ds.signatureThreshold)
+		attributes = appendAttribute(attributes, ep11cmds.XCP_ADMINT_REVOKE_THR,
+			ds.revocationThreshold)
+		attributes = appendAttribute(attributes, ep11cmds.XCP_ADMINT_PERMITS,
+			ds.permissions)
+		attributes = appendAttribute(attributes, ep11cmds.XCP_ADMINT_MODE,
+			ds.operationalMode)
+		attributes = appendAttribute(attributes, ep11cmds.XCP_ADMINT_STD,
+			ds.standards)
+		return adminOK(attributes)
+
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADMQ_DOM_CTRLPOINTS):
+		return adminOK(append([]byte(nil), ds.controlPoints...))
+
+	default:
+		return adminFailed(rcUnsupported, rsnNone)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Appends an (attribute identifier, value) pair to a list of attributes      */
+/*----------------------------------------------------------------------------*/
+func appendAttribute(attributes []byte, id uint32, value uint32) []byte {
+	attributes = append(attributes, common.Uint32To4ByteSlice(id)...)
+	return append(attributes, common.Uint32To4ByteSlice(value)...)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Processes get_xcp_info for module or domain information.  The response is */
+/* not signed.                                                                */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) processXCPInfoRequest(ds *domainState,
+	req ep11cmds.XCPReq) []byte {
+
+	var rsp ep11cmds.XCPRsp
+	rsp.CmdId = req.CmdId
+	rsp.DomainID = req.DomainID
+	rsp.ReturnCode = common.Uint32To4ByteSlice(rcOK)
+
+	if len(req.DomainID) != 4 ||
+		common.FourByteSliceToInt(req.DomainID) != ds.index {
+		rsp.ReturnCode = common.Uint32To4ByteSlice(rcBadDomain)
+	} else if common.ByteSlicesAreEqual(req.CmdSubtype, ep11cmds.CK_IBM_XCPQ_MODULE) {
+		payload := make([]byte, 196)
+		copy(payload[108:124], cm.moduleID)
+		rsp.Payload = payload
+	} else if common.ByteSlicesAreEqual(req.CmdSubtype, ep11cmds.CK_IBM_XCPQ_DOMAIN) {
+		payload := make([]byte, 80)
+		copy(payload[0:4], common.Uint32To4ByteSlice(uint32(ds.index)))
+		var flags byte
+		if ds.currentWK != nil {
+			copy(payload[4:36], common.Calc_vp(ds.currentWK))
+			flags |= 0x02
+		}
+		if ds.pendingWK != nil {
+			copy(payload[36:68], common.Calc_vp(ds.pendingWK))
+			flags |= 0x04
+			if ds.pendingCommitted {
+				flags |= 0x08
+			}
+		}
+		payload[71] = flags
+		copy(payload[72:76], common.Uint32To4ByteSlice(ds.operationalMode))
+		copy(payload[76:80], common.Uint32To4ByteSlice(1))
+		rsp.Payload = payload
+	} else {
+		rsp.ReturnCode = common.Uint32To4ByteSlice(rcBadArguments)
+	}
+
+	if rsp.Payload == nil {
+		rsp.Payload = make([]byte, 0)
+	}
+	data, err := asn1.Encode(rsp)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an xcpAdminRsp containing only a return code.  Used for errors     */
+/* found before an xcpAdminRspBlk can be built.                               */
+/*----------------------------------------------------------------------------*/
+func adminErrorResponse(functionID []byte, domainID []byte,
+	returnCode uint32) []byte {
+
+	var rsp ep11cmds.AdminRspError
+	rsp.CmdID = functionID
+	rsp.DomainID = domainID
+	rsp.ReturnCode = common.Uint32To4ByteSlice(returnCode)
+	data, err := asn1.Encode(rsp)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an xcpAdminRsp containing an xcpAdminRspBlk signed by the current  */
+/* OA epoch key of the crypto module                                          */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) signedAdminResponse(domainID []byte,
+	rspBlk ep11cmds.AdminRspBlk) []byte {
+
+	rspBlkSeq, err := asn1.Encode(rspBlk)
+	if err != nil {
+		panic(err)
+	}
+
+	var rsp ep11cmds.AdminRsp
+	rsp.CmdID = ep11cmds.FNID_ADMIN
+	rsp.DomainID = domainID
+	rsp.ReturnCode = common.Uint32To4ByteSlice(rcOK)
+	rsp.AdminRspBlk = rspBlkSeq
+	rsp.SignerInfo = cm.oaSignerInfo(rspBlkSeq)
+	data, err := asn1.Encode(rsp)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data with the current OA epoch key of the crypto module and returns  */
+/* the SignerInfo                                                             */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) oaSignerInfo(data []byte) []byte {
+	epochKey := cm.oaKeys[0]
+	hash := sha512.Sum512(data)
+	r, s, err := ecdsa.Sign(rand.Reader, epochKey, hash[:])
+	if err != nil {
+		panic(err)
+	}
+	var ecSig ep11cmds.ECSignature
+	ecSig.R = r
+	ecSig.S = s
+	signature, err := goasn1.Marshal(ecSig)
+	if err != nil {
+		panic(err)
+	}
+
+	signerInfoFields := make([][]byte, 5)
+	signerInfoFields[0] = ep11cmds.VERSION_3
+	signerInfoFields[1] = common.Asn1FormOctetString(
+		common.CalculateECKeyHash(epochKey.PublicKey))
+	signerInfoFields[1][0] = common.ASN1_CONTEXT_SPECIFIC_TAG
+	algIdFields := make([][]byte, 2)
+	algIdFields[0] = ep11cmds.OID_sha512
+	algIdFields[1] = ep11cmds.ASN1_NULL
+	signerInfoFields[2] = common.Asn1FormSequence(algIdFields)
+	algIdFields[0] = ep11cmds.OID_ecdsaWithSHA512
+	signerInfoFields[3] = common.Asn1FormSequence(algIdFields)
+	signerInfoFields[4] = common.Asn1FormOctetString(signature)
+	return common.Asn1FormSequence(signerInfoFields)
+}
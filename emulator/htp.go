@@ -0,0 +1,108 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Use the htp package to encode and decode messages
+// 10/18/2026    CLH             Report HTPResponses that cannot be encoded
+
+package emulator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+)
+
+/** Program identifier reported in HTPResponse error information */
+const HTP_PROGRAM_ID = "EMULATOR"
+
+/** HTPResponse error type for syntax errors detected by the TKE catcher */
+const HTP_ERROR_TYPE_SYNTAX = 9
+
+/** HTPResponse return codes used by the emulator */
+const (
+	htpInvalidMessageLength  = 27
+	htpCryptoModuleIndexNaN  = 40
+	htpCryptoModuleIndexOOR  = 41
+	htpDomainIndexOOR        = 5
+	htpInvalidHexData        = 43
+)
+
+/*----------------------------------------------------------------------------*/
+/* Processes an HTPRequest for a domain in the crypto module and returns the  */
+/* HTPResponse.                                                               */
+/*                                                                            */
+/* Only the XPNUM rule, used to send EP11 request CPRBs, is supported.        */
+/* Requests that cannot be parsed are reported using the error information    */
+/* section of the HTPResponse, the same as the TKE catcher program.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* domainIndex -- index of the domain assigned to the crypto unit             */
+/* htpRequest -- the HTPRequest string                                        */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPResponse string                                           */
+/*----------------------------------------------------------------------------*/
+func (cm *cryptoModule) processHTPRequest(domainIndex int,
+	htpRequest string) (htpResponse string) {
+
+	// Index errors in malformed EP11 requests are reported instead of
+	// taking down the caller
+	defer func() {
+		if r := recover(); r != nil {
+			htpResponse = htpErrorResponse(HTP_ERROR_TYPE_SYNTAX,
+				htpInvalidHexData, fmt.Sprint(r))
+		}
+	}()
+
+	request, err := htp.UnmarshalHTPRequest(htpRequest)
+	if err != nil {
+		returnCode := htpInvalidMessageLength
+		if syntaxErr, ok := err.(*htp.SyntaxError); ok {
+			switch syntaxErr.Field {
+			case htp.FIELD_CRYPTO_MODULE_INDEX:
+				returnCode = htpCryptoModuleIndexNaN
+			case htp.FIELD_CPRB:
+				returnCode = htpInvalidHexData
+			}
+		}
+		return htpErrorResponse(HTP_ERROR_TYPE_SYNTAX, returnCode, err.Error())
+	}
+	if request.CryptoModuleIndex != cm.cryptoModuleIndex {
+		return htpErrorResponse(HTP_ERROR_TYPE_SYNTAX,
+			htpCryptoModuleIndexOOR, strconv.Itoa(request.CryptoModuleIndex))
+	}
+	if int(request.CPRB.DomainIndex) != domainIndex {
+		return htpErrorResponse(HTP_ERROR_TYPE_SYNTAX,
+			htpDomainIndexOOR, "Domain not assigned to crypto unit")
+	}
+
+	ds := cm.domains[domainIndex]
+	payload := cm.processEP11Request(ds, request.CPRB.Payload)
+	htpResponse, err = htp.NewHTPResponse(
+		htp.NewCPRB(domainIndex, payload)).Marshal()
+	if err != nil {
+		return htpErrorResponse(HTP_ERROR_TYPE_SYNTAX, 0, err.Error())
+	}
+	return htpResponse
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTPResponse reporting an error                                  */
+/*----------------------------------------------------------------------------*/
+func htpErrorResponse(errorType int, returnCode int, errorText string) string {
+	htpResponse, err := htp.NewHTPErrorResponse(errorType, returnCode,
+		HTP_PROGRAM_ID, errorText).Marshal()
+	if err != nil {
+		// Report the return code that does not fit using return code 0,
+		// which always does
+		return htpErrorResponse(HTP_ERROR_TYPE_SYNTAX, 0, err.Error())
+	}
+	return htpResponse
+}
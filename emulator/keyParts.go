@@ -0,0 +1,160 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Mark key parts in the user key material
+// 10/18/2026    CLH             Use the emulator's own secret sharing
+
+package emulator
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"io"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Wrapping keys are exported as key parts using shamirSplit, so that any M   */
+/* of the key parts can be combined to recover the wrapping key.  Key part n  */
+/* is the share at x = n + 1.                                                 */
+/*                                                                            */
+/* Crypto modules use a proprietary key part format.  This format is the      */
+/* emulator's stand-in for it, for testing only: the TKE SDK never            */
+/* interprets key parts, and passes them and their user key material back to  */
+/* the crypto module unchanged, which combines them.  The emulator records    */
+/* the M policy and x value of each key part it exports in the user key       */
+/* material (ukm) of the RecipientInfo, which is not secret:                  */
+/*                                                                            */
+/*   8 bytes -- keyPartMarker                                                 */
+/*   1 byte  -- M policy                                                      */
+/*   1 byte  -- x value                                                       */
+/*  30 bytes -- random                                                        */
+/*                                                                            */
+/* Key parts whose user key material does not start with keyPartMarker were   */
+/* not exported by the emulator, such as key parts supplied by a customer.    */
+/*----------------------------------------------------------------------------*/
+
+/** Start of the user key material of key parts exported by the emulator */
+var keyPartMarker = []byte("TKEEMUKP")
+
+/** M policy and x value of a key part exported by the emulator */
+type keyPartInfo struct {
+	mPolicy int
+	x       byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Splits a wrapping key into one key part for each KPH key, any m of which   */
+/* can be combined to recover the wrapping key, and encrypts each key part    */
+/* under its KPH key.  Returns the RecipientInfo of each key part.            */
+/*----------------------------------------------------------------------------*/
+func encryptWKKeyParts(wk []byte, m int,
+	kphKeys []ecdsa.PublicKey) ([][]byte, error) {
+
+	parts, err := shamirSplit(wk, m, len(kphKeys))
+	if err != nil {
+		return nil, err
+	}
+	recipientInfos := make([][]byte, len(parts))
+	for i, part := range parts {
+		ukm := make([]byte, 40)
+		copy(ukm, keyPartMarker)
+		ukm[8] = byte(m)
+		ukm[9] = byte(i + 1)
+		_, err = io.ReadFull(rand.Reader, ukm[10:])
+		if err != nil {
+			return nil, err
+		}
+		recipientInfos[i], err = ep11cmds.EncryptKeyPartP521ECWithUKM(
+			kphKeys[i], part, ukm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recipientInfos, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the M policy and x value recorded in the user key material of a    */
+/* RecipientInfo, and false if the key part was not exported by the emulator. */
+/*----------------------------------------------------------------------------*/
+func keyPartMarking(ukm []byte) (keyPartInfo, bool) {
+	if len(ukm) != 40 || !bytes.HasPrefix(ukm, keyPartMarker) {
+		return keyPartInfo{}, false
+	}
+	return keyPartInfo{mPolicy: int(ukm[8]), x: ukm[9]}, true
+}
+
+/*----------------------------------------------------------------------------*/
+/* Combines decrypted key parts into a wrapping key:                          */
+/*                                                                            */
+/* - Key parts exported by the emulator with an M policy of 1 each hold the   */
+/*   complete wrapping key, and must be equal.                                */
+/* - Key parts exported by the emulator with an M policy greater than 1 are   */
+/*   combined using shamirCombine.  All must have the same M policy, there    */
+/*   must be at least M of them, and no key part can be repeated.             */
+/* - Key parts not exported by the emulator are combined by exclusive or.     */
+/*                                                                            */
+/* Returns false for a mixture of key parts exported by the emulator and      */
+/* other key parts, or for key parts that do not meet the rules above.        */
+/*----------------------------------------------------------------------------*/
+func combineKeyParts(parts [][]byte, infos []keyPartInfo,
+	marked []bool) ([]byte, bool) {
+
+	markedCount := 0
+	for i := range parts {
+		if len(parts[i]) != len(parts[0]) {
+			return nil, false
+		}
+		if marked[i] {
+			markedCount++
+		}
+	}
+
+	switch {
+	case markedCount == 0:
+		wk := make([]byte, len(parts[0]))
+		for _, part := range parts {
+			for i := range wk {
+				wk[i] ^= part[i]
+			}
+		}
+		return wk, true
+	case markedCount != len(parts):
+		return nil, false
+	}
+
+	m := infos[0].mPolicy
+	x := make([]byte, len(parts))
+	for i, info := range infos {
+		if info.mPolicy != m {
+			return nil, false
+		}
+		x[i] = info.x
+	}
+	if m == 1 {
+		for _, part := range parts {
+			if !common.ByteSlicesAreEqual(part, parts[0]) {
+				return nil, false
+			}
+		}
+		return parts[0], true
+	}
+	if len(parts) < m {
+		return nil, false
+	}
+	wk, err := shamirCombine(parts, x)
+	if err != nil {
+		return nil, false
+	}
+	return wk, true
+}
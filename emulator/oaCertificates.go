@@ -0,0 +1,243 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package emulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/* The OA certificates built here follow the layouts parsed by
+ * ep11cmds.OA2CertificateX and ep11cmds.OA3CertificateX.  Only the fields
+ * those structures and the OA certificate chain verification depend on are
+ * filled in with meaningful values.  OA3 certificates carry no Dilithium
+ * key or signature; those fields are zero. */
+
+/** Total length of an OA2 certificate built by the emulator */
+const OA2_CERTIFICATE_LENGTH = 584
+
+/** Adapter type reported in emulated OA certificates */
+var EMULATOR_ADAPTER_TYPE = []byte("EMULATOR")
+
+/** Tags and lengths preceding the x and y coordinates in an SPKI */
+var spkiPrefix, _ = hex.DecodeString(
+	"30819b" + "3010" + "06072a8648ce3d0201" + "06052b81040023" +
+		"03818600" + "04")
+
+/** Tags and lengths preceding R in an OA certificate SignerInfo */
+var oaSignatureRPrefix = []byte{0x04, 0x81, 0x8b, 0x30, 0x81, 0x88, 0x02, 0x42}
+
+/** Tag and length preceding S in an OA certificate SignerInfo */
+var oaSignatureSPrefix = []byte{0x02, 0x42}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an OA certificate in the format used by the CEX6P and CEX7P.       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* subjectKey -- the public key certified by the certificate                  */
+/* signerKey -- the private key used to sign the certificate                  */
+/* serialNum -- serial number of the crypto module                            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the OA certificate                                               */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func createOA2Certificate(subjectKey ecdsa.PublicKey,
+	signerKey *ecdsa.PrivateKey, serialNum string) ([]byte, error) {
+
+	cert := make([]byte, 0, OA2_CERTIFICATE_LENGTH)
+
+	// Header: magic byte, section count, byte count, section lengths
+	header := make([]byte, 32)
+	header[0] = 0x45
+	header[3] = 5
+	copy(header[4:8], common.Uint32To4ByteSlice(OA2_CERTIFICATE_LENGTH))
+	copy(header[8:12], common.Uint32To4ByteSlice(4))
+	copy(header[12:16], common.Uint32To4ByteSlice(173))
+	copy(header[16:20], common.Uint32To4ByteSlice(0))
+	copy(header[20:24], common.Uint32To4ByteSlice(158))
+	copy(header[24:28], common.Uint32To4ByteSlice(207))
+	cert = append(cert, header...)
+
+	// Info section, 4 bytes plus 4 pad bytes
+	cert = append(cert, make([]byte, 8)...)
+
+	// Metadata, 173 bytes plus 3 pad bytes
+	metaData := make([]byte, 176)
+	copy(metaData[0:5], []byte{0x30, 0x81, 0xAA, 0x02, 0x04})
+	copy(metaData[5:9], common.Uint32To4ByteSlice(1))
+	copy(metaData[9:11], []byte{0x04, 0x04})
+	copy(metaData[15:17], []byte{0x04, 0x08})
+	copy(metaData[17:25], EMULATOR_ADAPTER_TYPE)
+	copy(metaData[25:27], []byte{0x04, 0x0C})
+	copy(metaData[27:39], adapterID(serialNum))
+	copy(metaData[39:41], []byte{0x04, 0x20})
+	copy(metaData[73:75], []byte{0x02, 0x04})
+	copy(metaData[79:81], []byte{0x02, 0x04})
+	copy(metaData[85:87], []byte{0x04, 0x20})
+	copy(metaData[87:119], common.CalculateECKeyHash(subjectKey))
+	copy(metaData[119:121], []byte{0x04, 0x20})
+	copy(metaData[121:153], common.CalculateECKeyHash(signerKey.PublicKey))
+	copy(metaData[153:155], []byte{0x04, 0x10})
+	copy(metaData[155:171], signingTime())
+	copy(metaData[171:173], []byte{0x04, 0x00})
+	cert = append(cert, metaData...)
+
+	// SPKI, 158 bytes plus 2 pad bytes
+	cert = append(cert, p521Spki(subjectKey)...)
+	cert = append(cert, 0x00, 0x00)
+
+	// SignerInfo, 207 bytes plus 1 pad byte
+	r, s, err := signSHA512(signerKey, cert)
+	if err != nil {
+		return nil, err
+	}
+	cert = append(cert, 0x30, 0x81, 0xCC)
+	cert = append(cert, ep11cmds.VERSION_3...)
+	cert = append(cert, 0x80, 0x20)
+	cert = append(cert, common.CalculateECKeyHash(signerKey.PublicKey)...)
+	cert = append(cert, 0x30, 0x0B)
+	cert = append(cert, ep11cmds.OID_sha512...)
+	cert = append(cert, 0x30, 0x0A)
+	cert = append(cert, ep11cmds.OID_ecdsaWithSHA512...)
+	cert = append(cert, oaSignatureRPrefix...)
+	cert = append(cert, r...)
+	cert = append(cert, oaSignatureSPrefix...)
+	cert = append(cert, s...)
+	cert = append(cert, 0x00)
+
+	return cert, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an OA certificate in the format used by the CEX8P.                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* subjectKey -- the public key certified by the certificate                  */
+/* signerKey -- the private key used to sign the certificate                  */
+/* serialNum -- serial number of the crypto module                            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the OA certificate                                               */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func createOA3Certificate(subjectKey ecdsa.PublicKey,
+	signerKey *ecdsa.PrivateKey, serialNum string) ([]byte, error) {
+
+	cert := make([]byte, ep11cmds.CEX8_OA_CERTIFICATE_LENGTH)
+	copy(cert[0:4], []byte{0x30, 0x82, 0x1F, 0x21})
+
+	// Metadata, 95 bytes
+	metaData := cert[4:99]
+	copy(metaData[0:4], []byte{0x30, 0x5D, 0x02, 0x04})
+	copy(metaData[4:8], common.Uint32To4ByteSlice(1))
+	copy(metaData[8:10], []byte{0x04, 0x03})
+	copy(metaData[13:15], []byte{0x04, 0x08})
+	copy(metaData[15:23], EMULATOR_ADAPTER_TYPE)
+	copy(metaData[23:25], []byte{0x04, 0x0C})
+	copy(metaData[25:37], adapterID(serialNum))
+	copy(metaData[37:39], []byte{0x04, 0x20})
+	copy(metaData[71:73], []byte{0x02, 0x04})
+	copy(metaData[77:79], []byte{0x04, 0x10})
+	copy(metaData[79:95], signingTime())
+
+	// Auxiliary data, 83 bytes
+	auxData := cert[99:182]
+	copy(auxData[0:4], []byte{0x30, 0x51, 0x02, 0x01})
+	auxData[4] = 1
+	copy(auxData[5:7], []byte{0x04, 0x40})
+	copy(auxData[71:73], []byte{0x02, 0x04})
+	copy(auxData[77:79], []byte{0x02, 0x04})
+
+	// SignerInfo, everything else.  Only the ECC part is filled in.
+	signerInfo := cert[182:]
+	copy(signerInfo[0:4], []byte{0x30, 0x82, 0x1E, 0x6B})
+	copy(signerInfo[4:7], ep11cmds.VERSION_3)
+	copy(signerInfo[7:9], []byte{0x80, 0x20})
+	copy(signerInfo[9:41], common.CalculateECKeyHash(signerKey.PublicKey))
+	copy(signerInfo[41:43], []byte{0x30, 0x0B})
+	copy(signerInfo[43:54], ep11cmds.OID_sha512)
+	copy(signerInfo[54:61], []byte{0xA0, 0x82, 0x01, 0x2A, 0x30, 0x81, 0x89})
+
+	keyInfo := signerInfo[61:198]
+	copy(keyInfo[0:2], []byte{0x02, 0x04})
+	copy(keyInfo[2:6], common.Uint32To4ByteSlice(1))
+	copy(keyInfo[6:8], []byte{0x04, 0x01})
+	copy(keyInfo[9:11], []byte{0x02, 0x04})
+	copy(keyInfo[15:17], []byte{0x02, 0x04})
+	copy(keyInfo[21:23], []byte{0x04, 0x20})
+	copy(keyInfo[23:55], common.CalculateECKeyHash(subjectKey))
+	copy(keyInfo[55:57], []byte{0x04, 0x50})
+
+	copy(signerInfo[198:356], p521Spki(subjectKey))
+
+	// The ECC signature covers the metadata, auxiliary data, and ECC key
+	eccBody := make([]byte, 0)
+	eccBody = append(eccBody, metaData...)
+	eccBody = append(eccBody, auxData...)
+	eccBody = append(eccBody, signerInfo[54:356]...)
+	r, s, err := signSHA512(signerKey, eccBody)
+	if err != nil {
+		return nil, err
+	}
+	copy(signerInfo[356:358], []byte{0x30, 0x0A})
+	copy(signerInfo[358:368], ep11cmds.OID_ecdsaWithSHA512)
+	copy(signerInfo[368:376], oaSignatureRPrefix)
+	copy(signerInfo[376:442], r)
+	copy(signerInfo[442:444], oaSignatureSPrefix)
+	copy(signerInfo[444:510], s)
+
+	return cert, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the SubjectPublicKeyInfo for a P521 EC public key (158 bytes)      */
+/*----------------------------------------------------------------------------*/
+func p521Spki(pubKey ecdsa.PublicKey) []byte {
+	spki := make([]byte, 0, 158)
+	spki = append(spki, spkiPrefix...)
+	spki = append(spki, padTo66(pubKey.X.Bytes())...)
+	spki = append(spki, padTo66(pubKey.Y.Bytes())...)
+	return spki
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs the SHA-512 hash of the input data with a P521 EC key and returns R  */
+/* and S, each padded to 66 bytes                                             */
+/*----------------------------------------------------------------------------*/
+func signSHA512(key *ecdsa.PrivateKey, data []byte) ([]byte, []byte, error) {
+	hash := sha512.Sum512(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return padTo66(r.Bytes()), padTo66(s.Bytes()), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the 12-byte adapter identifier for an OA certificate               */
+/*----------------------------------------------------------------------------*/
+func adapterID(serialNum string) []byte {
+	return []byte(serialNum + "    ")
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the 16-byte signing time for an OA certificate                     */
+/*----------------------------------------------------------------------------*/
+func signingTime() []byte {
+	return []byte(time.Now().UTC().Format("20060102150405") + "Z ")
+}
@@ -0,0 +1,146 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Move from common, for use by the emulator only
+
+package emulator
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Splits a secret into n shares, any m of which can be combined to recover   */
+/* the secret.                                                                */
+/*                                                                            */
+/* Shamir secret sharing is used over GF(2^8) with the AES polynomial, one    */
+/* random polynomial of degree m - 1 for each byte of the secret.  Share i is */
+/* the value of the polynomials at x = i + 1.  With an m of 1, every share is */
+/* the secret.                                                                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte -- the secret                                                       */
+/* int -- m, the number of shares needed to recover the secret                */
+/* int -- n, the number of shares, at most 255                                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- the shares, each the same length as the secret                 */
+/* error -- reports invalid values of m and n                                 */
+/*----------------------------------------------------------------------------*/
+func shamirSplit(secret []byte, m int, n int) ([][]byte, error) {
+	if n < 1 || n > 255 || m < 1 || m > n {
+		return nil, errors.New("Invalid secret sharing policy, " +
+			strconv.Itoa(m) + " of " + strconv.Itoa(n) + " shares.")
+	}
+
+	// Random polynomial coefficients for each byte, constant term first
+	coefficients := make([][]byte, len(secret))
+	for i := range secret {
+		coefficients[i] = make([]byte, m)
+		coefficients[i][0] = secret[i]
+		_, err := io.ReadFull(rand.Reader, coefficients[i][1:])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shares := make([][]byte, n)
+	for j := range shares {
+		x := byte(j + 1)
+		shares[j] = make([]byte, len(secret))
+		for i := range secret {
+			// Horner's rule
+			var y byte
+			for k := m - 1; k >= 0; k-- {
+				y = gfMultiply(y, x) ^ coefficients[i][k]
+			}
+			shares[j][i] = y
+		}
+	}
+	return shares, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Combines shares created by shamirSplit to recover the secret.  At least m  */
+/* shares must be provided; with more than m, all of them must be valid       */
+/* shares of the same secret.                                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* [][]byte -- the shares                                                     */
+/* []byte -- the x value of each share, the index of the share plus 1         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the secret                                                       */
+/* error -- reports shares of different lengths, or x values that are zero    */
+/*      or repeated                                                           */
+/*----------------------------------------------------------------------------*/
+func shamirCombine(shares [][]byte, x []byte) ([]byte, error) {
+	if len(shares) == 0 || len(shares) != len(x) {
+		return nil, errors.New("One x value is needed for each share.")
+	}
+	seen := make(map[byte]bool)
+	for j, share := range shares {
+		if x[j] == 0 || seen[x[j]] {
+			return nil, errors.New("Share x values must be different and " +
+				"not zero.")
+		}
+		seen[x[j]] = true
+		if len(share) != len(shares[0]) {
+			return nil, errors.New("Shares must have the same length.")
+		}
+	}
+
+	// Lagrange interpolation at x = 0
+	secret := make([]byte, len(shares[0]))
+	for j := range shares {
+		// Lagrange basis polynomial for share j, evaluated at 0.
+		// Subtraction is exclusive or in GF(2^8).
+		var numerator, denominator byte = 1, 1
+		for k := range shares {
+			if k != j {
+				numerator = gfMultiply(numerator, x[k])
+				denominator = gfMultiply(denominator, x[k]^x[j])
+			}
+		}
+		basis := gfMultiply(numerator, gfInverse(denominator))
+		for i := range secret {
+			secret[i] ^= gfMultiply(shares[j][i], basis)
+		}
+	}
+	return secret, nil
+}
+
+/** Multiplies in GF(2^8) using the AES polynomial */
+func gfMultiply(a byte, b byte) byte {
+	var product byte
+	for b != 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1B
+		}
+		b >>= 1
+	}
+	return product
+}
+
+/** Returns the multiplicative inverse in GF(2^8), a^254 */
+func gfInverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gfMultiply(result, a)
+	}
+	return result
+}
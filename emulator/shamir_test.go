@@ -0,0 +1,114 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Move from common, for use by the emulator only
+
+package emulator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Shares of the secret 0x53 on the line y = 0x53 + 0xCA x in GF(2^8), worked */
+/* by hand: 0x53 ^ 0xCA = 0x99, and 0xCA * 2 = 0x8F so 0x53 ^ 0x8F = 0xDC     */
+/*----------------------------------------------------------------------------*/
+func TestShamirCombineKnownShares(t *testing.T) {
+	secret, err := shamirCombine([][]byte{{0x99}, {0xDC}},
+		[]byte{1, 2})
+	if err != nil || !bytes.Equal(secret, []byte{0x53}) {
+		t.Errorf("shamirCombine returned %X, %v", secret, err)
+	}
+	secret, err = shamirCombine([][]byte{{0xDC}, {0x99}},
+		[]byte{2, 1})
+	if err != nil || !bytes.Equal(secret, []byte{0x53}) {
+		t.Errorf("shamirCombine in the other order returned %X, %v", secret,
+			err)
+	}
+}
+
+/** Any M of N shares recover the secret, and fewer do not */
+func TestShamirSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	shares, err := shamirSplit(secret, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("shamirSplit returned %d shares", len(shares))
+	}
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				combined, err := shamirCombine(
+					[][]byte{shares[a], shares[b], shares[c]},
+					[]byte{byte(a + 1), byte(b + 1), byte(c + 1)})
+				if err != nil || !bytes.Equal(combined, secret) {
+					t.Errorf("Shares %d, %d, %d returned %X, %v", a+1, b+1,
+						c+1, combined, err)
+				}
+			}
+		}
+	}
+
+	// All five shares give the same secret
+	combined, err := shamirCombine(shares, []byte{1, 2, 3, 4, 5})
+	if err != nil || !bytes.Equal(combined, secret) {
+		t.Errorf("All shares returned %X, %v", combined, err)
+	}
+
+	combined, err = shamirCombine(shares[:2], []byte{1, 2})
+	if err != nil || bytes.Equal(combined, secret) {
+		t.Error("Two of the shares recovered the secret")
+	}
+
+	// With an M of 1 every share is the secret
+	shares, err = shamirSplit(secret, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, share := range shares {
+		if !bytes.Equal(share, secret) {
+			t.Errorf("Share %d is not the secret", i+1)
+		}
+	}
+}
+
+/** Invalid policies and shares are rejected */
+func TestShamirChecks(t *testing.T) {
+	secret := make([]byte, 32)
+	for _, policy := range [][2]int{{0, 3}, {4, 3}, {1, 0}, {2, 256}} {
+		_, err := shamirSplit(secret, policy[0], policy[1])
+		if err == nil {
+			t.Errorf("shamirSplit accepted %d of %d shares", policy[0],
+				policy[1])
+		}
+	}
+
+	share := make([]byte, 32)
+	tests := []struct {
+		shares [][]byte
+		x      []byte
+	}{
+		{nil, nil},
+		{[][]byte{share, share}, []byte{1}},
+		{[][]byte{share, share}, []byte{1, 1}},
+		{[][]byte{share, share}, []byte{0, 1}},
+		{[][]byte{share, share[:16]}, []byte{1, 2}},
+	}
+	for i, test := range tests {
+		if _, err := shamirCombine(test.shares, test.x); err == nil {
+			t.Errorf("shamirCombine accepted test %d", i+1)
+		}
+	}
+}
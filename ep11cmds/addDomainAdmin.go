@@ -7,13 +7,58 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Adds a domain administrator                                                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain where an administrator is to be added */
+/* []byte -- certificate containing the public key for the administrator      */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func AddDomainAdminWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	cert []byte, signers []common.Signer) error {
+
+	htpRequestString, err := AddDomainAdminReqWithContext(
+		ctx, tr, de, cert, signers)
+	if err != nil {
+		return err
+	}
+
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as AddDomainAdminWithContext, using the background context            */
+/*----------------------------------------------------------------------------*/
+func AddDomainAdminWithTransport(tr common.Transport, de common.DomainEntry,
+	cert []byte, signers []common.Signer) error {
+
+	return AddDomainAdminWithContext(context.Background(), tr, de, cert,
+		signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Adds a domain administrator                                                */
 /*                                                                            */
@@ -33,26 +78,53 @@ func AddDomainAdmin(authToken string, urlStart string, de common.DomainEntry,
 	cert []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) error {
 
-	htpRequestString, err := AddDomainAdminReq(
-		authToken, urlStart, de, cert, sigkeys, sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
+	defer common.CloseSigners(signers)
+	return AddDomainAdminWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, cert, signers)
+}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for adding a domain administrator                   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose current wrapping key register   */
+/*    is to be exported                                                       */
+/* []byte -- certificate containing the public key for the administrator to   */
+/*    be added                                                                */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPRequest string with the signed CPRB for the command       */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func AddDomainAdminReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	cert []byte, signers []common.Signer) (string, error) {
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
+	var adminBlk AdminBlk
+	adminBlk.CmdID = XCP_ADM_DOM_ADMIN_LOGIN
+	// administrative domain filled in later
+	// module ID filled in later
+	// transaction counter filled in later
+	// the certificate is the payload
+	adminBlk.CmdInput = cert
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
 
-	_, err = buildAdminRspBlk(htpResponseString, de)
-	if err != nil {
-		return err
-	}
+/*----------------------------------------------------------------------------*/
+/* Same as AddDomainAdminReqWithContext, using the background context         */
+/*----------------------------------------------------------------------------*/
+func AddDomainAdminReqWithTransport(tr common.Transport, de common.DomainEntry,
+	cert []byte, signers []common.Signer) (string, error) {
 
-	return nil
+	return AddDomainAdminReqWithContext(context.Background(), tr, de, cert,
+		signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -77,13 +149,11 @@ func AddDomainAdminReq(authToken string, urlStart string, de common.DomainEntry,
 	cert []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) (string, error) {
 
-	var adminBlk AdminBlk
-	adminBlk.CmdID = XCP_ADM_DOM_ADMIN_LOGIN
-	// administrative domain filled in later
-	// module ID filled in later
-	// transaction counter filled in later
-	// the certificate is the payload
-	adminBlk.CmdInput = cert
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return AddDomainAdminReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, cert, signers)
 }
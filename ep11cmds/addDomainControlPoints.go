@@ -7,13 +7,58 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Adds domain control points                                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose attributes are to be set        */
+/* []byte -- bit mask of control points to be enabled.  16 bytes are expected.*/
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func AddDomainControlPointsWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	cpsToSet []byte, signers []common.Signer) error {
+
+	htpRequestString, err := AddDomainControlPointsReqWithContext(
+		ctx, tr, de, cpsToSet, signers)
+	if err != nil {
+		return err
+	}
+
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as AddDomainControlPointsWithContext, using the background context    */
+/*----------------------------------------------------------------------------*/
+func AddDomainControlPointsWithTransport(tr common.Transport,
+	de common.DomainEntry, cpsToSet []byte, signers []common.Signer) error {
+
+	return AddDomainControlPointsWithContext(context.Background(), tr, de,
+		cpsToSet, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Adds domain control points                                                 */
 /*                                                                            */
@@ -33,26 +78,40 @@ func AddDomainControlPoints(authToken string, urlStart string, de common.DomainE
 	cpsToSet []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) error {
 
-	htpRequestString, err := AddDomainControlPointsReq(
-		authToken, urlStart, de, cpsToSet, sigkeys, sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
+	defer common.CloseSigners(signers)
+	return AddDomainControlPointsWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, cpsToSet, signers)
+}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for adding domain control points                    */
+/*----------------------------------------------------------------------------*/
+func AddDomainControlPointsReqWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, cpsToSet []byte, signers []common.Signer) (string, error) {
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
+	var adminBlk AdminBlk
+	adminBlk.CmdID = XCP_ADM_DOM_CONTROLPOINT_ADD
+	// administrative domain filled in later
+	// module ID filled in later
+	// transaction counter filled in later
+	adminBlk.CmdInput = cpsToSet
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
 
-	_, err = buildAdminRspBlk(htpResponseString, de)
-	if err != nil {
-		return err
-	}
+/*----------------------------------------------------------------------------*/
+/* Same as AddDomainControlPointsReqWithContext, using the background context */
+/*----------------------------------------------------------------------------*/
+func AddDomainControlPointsReqWithTransport(tr common.Transport,
+	de common.DomainEntry, cpsToSet []byte, signers []common.Signer) (string,
+	error) {
 
-	return nil
+	return AddDomainControlPointsReqWithContext(context.Background(), tr, de,
+		cpsToSet, signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -62,12 +121,11 @@ func AddDomainControlPointsReq(authToken string, urlStart string,
 	de common.DomainEntry, cpsToSet []byte, sigkeys []string,
 	sigkeySkis []string, sigkeyTokens []string) (string, error) {
 
-	var adminBlk AdminBlk
-	adminBlk.CmdID = XCP_ADM_DOM_CONTROLPOINT_ADD
-	// administrative domain filled in later
-	// module ID filled in later
-	// transaction counter filled in later
-	adminBlk.CmdInput = cpsToSet
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return AddDomainControlPointsReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, cpsToSet, signers)
 }
@@ -0,0 +1,107 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add Dilithium signature keys
+// 10/18/2026    CLH             Reject signers from SignerQuorum.Signers
+// 10/18/2026    CLH             Add ParseAdminCert
+
+package ep11cmds
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/Logicalis/asn1"
+)
+
+/** Public key algorithms of administrator certificates */
+var (
+	oidECPublicKey     = asn1.Oid{1, 2, 840, 10045, 2, 1}
+	oidRSAEncryption   = asn1.Oid{1, 2, 840, 113549, 1, 1, 1}
+	oidDilithiumR2_8_7 = asn1.Oid{1, 3, 6, 1, 4, 1, 2, 267, 1, 8, 7}
+)
+
+/*----------------------------------------------------------------------------*/
+/* Creates an administrator certificate for a signature key.  The form of     */
+/* the certificate depends on the key type of the signer.                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* common.Signer signer -- the signature key.  The certificate holds its      */
+/*     public key and is signed using it.                                     */
+/* string adminName -- the administrator name, up to 30 characters            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the administrator certificate                                    */
+/* error -- reports any error                                                 */
+/*----------------------------------------------------------------------------*/
+func CreateAdminCert(signer common.Signer, adminName string) ([]byte, error) {
+	if IsQuorumSigner(signer) {
+		return nil, errQuorumSigner
+	}
+	switch signer.KeyType() {
+	case common.KEY_TYPE_P521EC:
+		return CreateAdminCertP521ECWithSigner(signer, adminName)
+	case common.KEY_TYPE_RSA2048:
+		return CreateAdminCertRSA2048(signer, adminName)
+	case common.KEY_TYPE_DILITHIUM_R2_87:
+		return CreateAdminCertDilithium(signer, adminName)
+	default:
+		return nil, errors.New("Unsupported signature key type: " +
+			signer.KeyType())
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Subject Key Identifier, administrator name, and key type of an */
+/* administrator certificate created by CreateAdminCert.  Lets commands that  */
+/* add administrators be planned without access to their signature keys.     */
+/* The certificate signature is not checked here; the crypto module checks it */
+/* when the administrator is added.                                           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte -- the administrator certificate                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the 32-byte Subject Key Identifier of the signature key          */
+/* string -- the administrator name, without trailing blanks                  */
+/* string -- the key type, one of the common.KEY_TYPE_... constants           */
+/* error -- reports a certificate that cannot be decoded                      */
+/*----------------------------------------------------------------------------*/
+func ParseAdminCert(cert []byte) ([]byte, string, string, error) {
+	var decoded Certificate
+	rest, err := asn1.Decode(cert, &decoded)
+	if err == nil && len(rest) != 0 {
+		err = errors.New("unexpected data after the certificate")
+	}
+	if err == nil &&
+		len(decoded.TheBody.TheExtensions.TheSeq1.TheSeq2.SKI) != 34 {
+		err = errors.New("Subject Key Identifier is not 32 bytes")
+	}
+	if err != nil {
+		return nil, "", "", errors.New("Invalid administrator certificate." +
+			"\nMessage: " + err.Error())
+	}
+
+	var keyType string
+	algorithm := decoded.TheBody.ThePublicKey.Algorithm.ObjID
+	switch {
+	case algorithm.Cmp(oidECPublicKey) == 0:
+		keyType = common.KEY_TYPE_P521EC
+	case algorithm.Cmp(oidRSAEncryption) == 0:
+		keyType = common.KEY_TYPE_RSA2048
+	case algorithm.Cmp(oidDilithiumR2_8_7) == 0:
+		keyType = common.KEY_TYPE_DILITHIUM_R2_87
+	default:
+		return nil, "", "", errors.New("Invalid administrator certificate." +
+			"\nUnsupported public key algorithm: " + algorithm.String())
+	}
+	adminName := strings.TrimRight(string(decoded.GetAdminName()), " ")
+	return decoded.GetSKI(), adminName, keyType, nil
+}
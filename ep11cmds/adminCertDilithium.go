@@ -0,0 +1,163 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package ep11cmds
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Creates an administrator certificate containing a Dilithium round 2 (8,7)  */
+/* public key.  The certificate has the same issuer, validity, subject, and   */
+/* subject key identifier fields as the P521 EC administrator certificate,    */
+/* with the dilithium-r2-8-7 algorithm and the public key in the form used    */
+/* in CEX8 OA certificates.                                                   */
+/*                                                                            */
+/* Dilithium administrators can only be added to crypto units whose OA        */
+/* certificate holds a Dilithium key, see DilithiumAdminsSupported.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* common.Signer signer -- the Dilithium signature key.  The certificate      */
+/*     holds its public key and is signed using it.                           */
+/* string adminName -- the administrator name                                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the administrator certificate                                    */
+/* error -- reports any error                                                 */
+/*----------------------------------------------------------------------------*/
+func CreateAdminCertDilithium(signer common.Signer,
+	adminName string) ([]byte, error) {
+
+	publicKey, ok := signer.PublicKey().(*common.DilithiumPublicKey)
+	if !ok || signer.KeyType() != common.KEY_TYPE_DILITHIUM_R2_87 {
+		return nil, errors.New("The signature key is not a Dilithium round 2 " +
+			"(8,7) key.")
+	}
+
+	// Start from the P521 EC template, which has the fields that do not
+	// depend on the key type
+	certBase, err := hex.DecodeString(baseTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the administrator name in two places
+	if len(adminName) > 30 {
+		return nil, errors.New("Administrator name is too long.")
+	}
+	nameBuffer := []byte(adminName)
+	nameLen := len(nameBuffer)
+	if nameLen < 30 {
+		nameBuffer = append(nameBuffer, setNewSliceToValue(30-nameLen, 0x20)...)
+	}
+	copy(certBase[admin_name_1_offset:admin_name_1_offset+30], nameBuffer[:])
+	copy(certBase[admin_name_2_offset:admin_name_2_offset+30], nameBuffer[:])
+
+	// Add the subject key identifier
+	copy(certBase[ski_offset:ski_offset+32], signer.SKI())
+
+	// Replace the signature algorithm and the subject public key info
+	signatureOid := make([][]byte, 1)
+	signatureOid[0] = OID_dilithium_r2_8_7
+	algId := common.Asn1FormSequence(signatureOid)
+	body := make([]byte, 0)
+	body = append(body, certBase[4:12]...)   // version and serial number
+	body = append(body, algId...)
+	body = append(body, certBase[24:204]...) // issuer, validity, and subject
+	body = append(body, publicKey.SPKI()...)
+	body = append(body, certBase[362:]...)   // subject key identifier
+	bodyElements := make([][]byte, 1)
+	bodyElements[0] = body
+	certBody := common.Asn1FormSequence(bodyElements)
+
+	// Calculate the Dilithium signature
+	signature, err := signer.Sign(certBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble the final certificate
+	elements := make([][]byte, 3)
+	elements[0] = certBody
+	elements[1] = algId
+	elements[2] = common.Asn1FormBitString(signature)
+	return common.Asn1FormSequence(elements), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Dilithium OA public key in a CEX8 OA certificate, or nil if    */
+/* the certificate holds no Dilithium key.                                    */
+/*----------------------------------------------------------------------------*/
+func (cert *OA3CertificateX) DilithiumPublicKey() *common.DilithiumPublicKey {
+	allZero := true
+	for _, b := range cert.DilithiumPublicT1 {
+		if b != 0 {
+			allZero = false
+			break
+		}
+	}
+	if allZero {
+		return nil
+	}
+	key, err := common.NewDilithiumPublicKey(cert.DilithiumPublicNonce,
+		cert.DilithiumPublicT1)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether administrators with Dilithium signature keys can be added  */
+/* to a crypto unit.  Only CEX8 crypto modules whose OA certificate holds a   */
+/* Dilithium key support them.                                                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the request     */
+/* common.Transport -- the transport used to send requests to the crypto unit */
+/* DomainEntry -- identifies the crypto unit                                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* bool -- true if Dilithium administrators are supported                     */
+/* error -- reports any error reading the OA certificate                      */
+/*----------------------------------------------------------------------------*/
+func DilithiumAdminsSupportedWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry) (bool, error) {
+
+	certbytes, err := QueryDeviceCertificateWithContext(ctx, tr, de, 0)
+	if err != nil {
+		return false, err
+	}
+	if len(certbytes) != CEX8_OA_CERTIFICATE_LENGTH &&
+		len(certbytes) != CEX8_MB_CERTIFICATE_LENGTH {
+		// Not a CEX8 crypto module
+		return false, nil
+	}
+	var cert OA3CertificateX
+	err = cert.Init(certbytes)
+	if err != nil {
+		return false, err
+	}
+	return cert.DilithiumPublicKey() != nil, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as DilithiumAdminsSupportedWithContext, using the background context  */
+/*----------------------------------------------------------------------------*/
+func DilithiumAdminsSupported(tr common.Transport,
+	de common.DomainEntry) (bool, error) {
+
+	return DilithiumAdminsSupportedWithContext(context.Background(), tr, de)
+}
@@ -0,0 +1,197 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package ep11cmds
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Stand-in for a Dilithium signer.  Go has no Dilithium implementation, so   */
+/* the "signature" is the SHA-256 hash of the data repeated to the length of  */
+/* a Dilithium signature, which lets the tests check what was signed.         */
+/*----------------------------------------------------------------------------*/
+type testDilithiumSigner struct {
+	key    *common.DilithiumPublicKey
+	length int // signature length, 0 for the Dilithium signature length
+}
+
+func newTestDilithiumSigner(t *testing.T) *testDilithiumSigner {
+	rho := make([]byte, common.DILITHIUM_R2_87_RHO_LENGTH)
+	t1 := make([]byte, common.DILITHIUM_R2_87_T1_LENGTH)
+	rand.Read(rho)
+	rand.Read(t1)
+	key, err := common.NewDilithiumPublicKey(rho, t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testDilithiumSigner{key: key}
+}
+
+func (s *testDilithiumSigner) SKI() []byte {
+	return common.CalculateDilithiumKeyHash(*s.key)
+}
+
+func (s *testDilithiumSigner) KeyType() string {
+	return common.KEY_TYPE_DILITHIUM_R2_87
+}
+
+func (s *testDilithiumSigner) PublicKey() crypto.PublicKey {
+	return s.key
+}
+
+func (s *testDilithiumSigner) Sign(data []byte) ([]byte, error) {
+	length := s.length
+	if length == 0 {
+		length = common.DILITHIUM_R2_87_SIGNATURE_LENGTH
+	}
+	hash := sha256.Sum256(data)
+	return bytes.Repeat(hash[:], length/32+1)[:length], nil
+}
+
+/** Used to decode a Dilithium administrator certificate */
+type testDilithiumCert struct {
+	TBS struct {
+		Raw          asn1.RawContent
+		Version      int `asn1:"optional,explicit,default:0,tag:0"`
+		SerialNumber asn1.RawValue
+		Algorithm    asn1.RawValue
+		Issuer       asn1.RawValue
+		Validity     asn1.RawValue
+		Subject      asn1.RawValue
+		PublicKey    asn1.RawValue
+		Extensions   asn1.RawValue `asn1:"optional,explicit,tag:3"`
+	}
+	Algorithm struct {
+		Algorithm asn1.ObjectIdentifier
+	}
+	Signature asn1.BitString
+}
+
+/*----------------------------------------------------------------------------*/
+/* The certificate has the P521 EC certificate's names and SKI, the Dilithium */
+/* public key, and a signature over the certificate body                      */
+/*----------------------------------------------------------------------------*/
+func TestCreateAdminCertDilithium(t *testing.T) {
+	signer := newTestDilithiumSigner(t)
+	cert, err := CreateAdminCert(signer, "admin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded testDilithiumCert
+	rest, err := asn1.Unmarshal(cert, &decoded)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("The certificate could not be decoded: %v", err)
+	}
+	dilithiumOID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 2, 267, 1, 8, 7}
+	if !decoded.Algorithm.Algorithm.Equal(dilithiumOID) {
+		t.Errorf("Unexpected signature algorithm %v",
+			decoded.Algorithm.Algorithm)
+	}
+	if !bytes.Equal(decoded.TBS.PublicKey.FullBytes, signer.key.SPKI()) {
+		t.Error("The certificate does not hold the Dilithium public key")
+	}
+	expectedSignature, _ := signer.Sign(decoded.TBS.Raw)
+	if !bytes.Equal(decoded.Signature.RightAlign(), expectedSignature) {
+		t.Error("The signature is not over the certificate body")
+	}
+	if !bytes.Contains(decoded.TBS.Extensions.Bytes, signer.SKI()) {
+		t.Error("The certificate does not hold the subject key identifier")
+	}
+
+	// The issuer and subject are the same as in a P521 EC certificate
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	ecSigner, _ := common.NewPrivateKeySigner(ecKey)
+	ecCert, err := CreateAdminCert(ecSigner, "admin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ecDecoded testDilithiumCert
+	asn1.Unmarshal(ecCert, &ecDecoded)
+	if !bytes.Equal(decoded.TBS.Issuer.FullBytes,
+		ecDecoded.TBS.Issuer.FullBytes) ||
+		!bytes.Equal(decoded.TBS.Subject.FullBytes,
+			ecDecoded.TBS.Subject.FullBytes) {
+		t.Error("The names differ from the P521 EC certificate")
+	}
+	if !bytes.Contains(decoded.TBS.Subject.FullBytes,
+		[]byte("admin1                        ")) {
+		t.Error("The subject does not hold the padded administrator name")
+	}
+
+	// EC signers and names that are too long are rejected
+	if _, err := CreateAdminCertDilithium(ecSigner, "admin1"); err == nil {
+		t.Error("CreateAdminCertDilithium accepted an EC signer")
+	}
+	_, err = CreateAdminCertDilithium(signer, "administrator name 31 characters")
+	if err == nil {
+		t.Error("CreateAdminCertDilithium accepted a 31-character name")
+	}
+}
+
+/** Dilithium SignerInfo holds the SKI, the algorithm, and the signature */
+func TestCreateDilithiumSignerInfo(t *testing.T) {
+	signer := newTestDilithiumSigner(t)
+	data := []byte("administrative command")
+	signerInfo, err := CreateSignerInfoWithSigners(
+		data, []common.Signer{signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSignature, _ := signer.Sign(data)
+	expected := common.Asn1FormSequence([][]byte{
+		VERSION_3,
+		append([]byte{common.ASN1_CONTEXT_SPECIFIC_TAG, 0x20}, signer.SKI()...),
+		common.Asn1FormSequence([][]byte{OID_sha512, ASN1_NULL}),
+		common.Asn1FormSequence([][]byte{OID_dilithium_r2_8_7, ASN1_NULL}),
+		common.Asn1FormOctetString(expectedSignature),
+	})
+	if !bytes.Equal(signerInfo, expected) {
+		t.Errorf("Unexpected SignerInfo %X", signerInfo)
+	}
+
+	// Signatures of the wrong length are rejected
+	signer.length = common.DILITHIUM_R2_87_SIGNATURE_LENGTH - 1
+	if _, err := CreateSignerInfoWithSigners(
+		data, []common.Signer{signer}); err == nil {
+		t.Error("A short Dilithium signature was accepted")
+	}
+}
+
+/** Only OA certificates with a Dilithium key support Dilithium admins */
+func TestOA3CertificateXDilithiumPublicKey(t *testing.T) {
+	signer := newTestDilithiumSigner(t)
+	cert := OA3CertificateX{DilithiumPublicNonce: signer.key.Rho,
+		DilithiumPublicT1: signer.key.T1}
+	key := cert.DilithiumPublicKey()
+	if key == nil || !bytes.Equal(key.T1, signer.key.T1) {
+		t.Error("The Dilithium public key was not returned")
+	}
+
+	cert.DilithiumPublicT1 = make([]byte, common.DILITHIUM_R2_87_T1_LENGTH)
+	if cert.DilithiumPublicKey() != nil {
+		t.Error("A key was returned for a certificate with no Dilithium key")
+	}
+	cert.DilithiumPublicT1 = signer.key.T1[1:]
+	if cert.DilithiumPublicKey() != nil {
+		t.Error("A key was returned for a short t1")
+	}
+}
@@ -7,15 +7,14 @@
 //
 // Date          Initials        Description
 // 05/04/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Keep the private key and signing service
+//                               variants
 
 package ep11cmds
 
 import (
 	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
-	"crypto/sha512"
-	"encoding/asn1"
 	"encoding/hex"
 	"errors"
 	"math/big"
@@ -98,14 +97,21 @@ type ECSignature struct {
 /* Creates an administrator certificate containing a P521 EC public key.      */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* ecdsa.PrivateKey ecKey -- the EC private key                               */
+/* common.Signer signer -- the P521 EC signature key.  The certificate holds  */
+/*     its public key and is signed using it.                                 */
 /* string adminName -- the administrator name                                 */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* []byte -- the administrator certificate                                    */
 /* error -- reports any error                                                 */
 /*----------------------------------------------------------------------------*/
-func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, error) {
+func CreateAdminCertP521ECWithSigner(signer common.Signer,
+	adminName string) ([]byte, error) {
+
+	publicKey, ok := signer.PublicKey().(*ecdsa.PublicKey)
+	if !ok || signer.KeyType() != common.KEY_TYPE_P521EC {
+		return nil, errors.New("The signature key is not a P521 EC key.")
+	}
 
 	// Copy the template
 	certBase, err := hex.DecodeString(baseTemplate)
@@ -127,13 +133,13 @@ func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, er
 
 	// Add the EC public key
 	certBase[public_key_offset + 1] = 0x04  // compression byte
-	bytes := ecKey.PublicKey.X.Bytes()
+	bytes := publicKey.X.Bytes()
 	length := len(bytes)
 	// copy the X coordinate
 	for i:=0; i<length; i++ {
 		certBase[public_key_offset + 2 + (66 - length) + i] = bytes[i]
 	}
-	bytes = ecKey.PublicKey.Y.Bytes()
+	bytes = publicKey.Y.Bytes()
 	length = len(bytes)
 	// copy the Y coordinate
 	for i:=0; i<length; i++ {
@@ -141,21 +147,10 @@ func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, er
 	}
 
 	// Add the subject key identifier
-	ski := common.CalculateECKeyHash(ecKey.PublicKey)
-	copy(certBase[ski_offset:ski_offset+32], ski[:])
-
-	// Calculate the signature
-	hash := sha512.Sum512(certBase)
-	r, s, err := ecdsa.Sign(rand.Reader, &ecKey, hash[:])
-	if err != nil {
-		return nil, err
-	}
+	copy(certBase[ski_offset:ski_offset+32], signer.SKI())
 
-	var ecSig ECSignature
-	ecSig.R = r
-	ecSig.S = s
-	// Represent the signature as an ASN.1 sequence
-	signature, err := asn1.Marshal(ecSig)
+	// Calculate the signature, an ASN.1 sequence of two INTEGERs
+	signature, err := signer.Sign(certBase)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +165,26 @@ func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, er
 	return common.Asn1FormSequence(elements), nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Creates an administrator certificate containing a P521 EC public key.      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ecdsa.PrivateKey ecKey -- the EC private key                               */
+/* string adminName -- the administrator name                                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the administrator certificate                                    */
+/* error -- reports any error                                                 */
+/*----------------------------------------------------------------------------*/
+func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, error) {
+
+	signer, err := common.NewPrivateKeySigner(&ecKey)
+	if err != nil {
+		return nil, err
+	}
+	return CreateAdminCertP521ECWithSigner(signer, adminName)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates an administrator certificate containing a P521 EC public key       */
 /* using a signing service.                                                   */
@@ -187,53 +202,9 @@ func CreateAdminCertP521EC(ecKey ecdsa.PrivateKey, adminName string) ([]byte, er
 func CreateAdminCertUsingSigningService(ssURL string, sigkey string,
 	sigkeyToken string, adminName string) ([]byte, error) {
 
-	// Copy the template
-	certBase, err := hex.DecodeString(baseTemplate)
+	signer, err := common.NewSigningServiceSigner(ssURL, sigkey, sigkeyToken)
 	if err != nil {
 		return nil, err
 	}
-
-	// Add the administrator name in two places
-	if len(adminName) > 30 {
-		return nil, errors.New("Administrator name is too long.")
-	}
-	nameBuffer := []byte(adminName)
-	nameLen := len(nameBuffer)
-	if nameLen < 30 {
-		nameBuffer = append(nameBuffer, setNewSliceToValue(30-nameLen, 0x20)...)
-	}
-	copy(certBase[admin_name_1_offset:admin_name_1_offset+30], nameBuffer[:])
-	copy(certBase[admin_name_2_offset:admin_name_2_offset+30], nameBuffer[:])
-
-	// Add the EC public key
-
-	// Use the signing service to get the EC public key
-	pubkey, err := common.GetPublicKeyFromSigningService(
-		ssURL, sigkey, sigkeyToken)
-	if err != nil {
-		return make([]byte, 0), err
-	}
-
-	// Add the EC public key
-	copy(certBase[public_key_offset + 1 : public_key_offset + 1 + 133], pubkey)
-
-	// Add the subject key identifier
-	ski := sha256.Sum256(pubkey[:])
-	copy(certBase[ski_offset:ski_offset+32], ski[:])
-
-	// Calculate the signature
-	hash := sha512.Sum512(certBase)
-	signature, err := common.SignWithSignatureKey(hash[:], sigkey, sigkeyToken)
-	if err != nil {
-		return make([]byte, 0), err
-	}
-
-	// Assemble the final certificate
-	elements := make([][]byte, 3)
-	elements[0] = certBase
-	signatureOid := make([][]byte, 1)
-	signatureOid[0] = OID_ecdsaWithSHA512
-	elements[1] = common.Asn1FormSequence(signatureOid)
-	elements[2] = common.Asn1FormBitString(signature)
-	return common.Asn1FormSequence(elements), nil
+	return CreateAdminCertP521ECWithSigner(signer, adminName)
 }
@@ -7,6 +7,8 @@
 //
 // Date          Initials        Description
 // 05/26/2020    CLH             T372621 - Support P521 EC signature keys
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Keep SetSignature for RSA private keys
 
 package ep11cmds
 
@@ -161,26 +163,89 @@ func (cert *CertificateRSA2048) SetPublicKey(publicKey []byte) {
 /* Operates on CertificateRSA2048 (both input and output).                    */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* rsa.PrivateKey rsaKey -- the RSA private key to use to create the          */
-/*     signature.                                                             */
+/* common.Signer signer -- the 2048-bit RSA signature key to use to create    */
+/*     the signature.                                                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any error creating the signature                          */
 /*----------------------------------------------------------------------------*/
-func (cert *CertificateRSA2048) SetSignature(rsaKey *rsa.PrivateKey) {
+func (cert *CertificateRSA2048) SetSignatureWithSigner(
+	signer common.Signer) error {
+
 	encoded, err := asn1.Encode(*cert)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	var bytesToSign []byte
-	copy(bytesToSign, encoded[4:550])
+	bytesToSign := encoded[4:550]
 
-	signature := common.Signature256(bytesToSign, rsaKey)
+	signature, err := signer.Sign(bytesToSign)
+	if err != nil {
+		return err
+	}
 	if len(signature) == 256 {
 		cert.Signature = setNewSliceToValue(257, 0)
 		copy(cert.Signature[1:], signature)
 	} else if len(signature) == 257 {
 		cert.Signature = signature
 	} else {
-		panic(errors.New("Signature length is not valid"))
+		return errors.New("Signature length is not valid")
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sets the signature field in an administrator certificate containing a      */
+/* 2048-bit RSA public key.                                                   */
+/*                                                                            */
+/* Operates on CertificateRSA2048 (both input and output).                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* rsa.PrivateKey rsaKey -- the RSA private key to use to create the          */
+/*     signature.                                                             */
+/*----------------------------------------------------------------------------*/
+func (cert *CertificateRSA2048) SetSignature(rsaKey *rsa.PrivateKey) {
+	signer, err := common.NewPrivateKeySigner(rsaKey)
+	if err == nil {
+		err = cert.SetSignatureWithSigner(signer)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an administrator certificate containing a 2048-bit RSA public key. */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* common.Signer signer -- the 2048-bit RSA signature key.  The certificate   */
+/*     holds its public key and is signed using it.                           */
+/* string adminName -- the administrator name                                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the administrator certificate                                    */
+/* error -- reports any error                                                 */
+/*----------------------------------------------------------------------------*/
+func CreateAdminCertRSA2048(signer common.Signer, adminName string) ([]byte, error) {
+
+	publicKey, ok := signer.PublicKey().(*rsa.PublicKey)
+	if !ok || signer.KeyType() != common.KEY_TYPE_RSA2048 {
+		return nil, errors.New("The signature key is not a 2048-bit RSA key.")
+	}
+	if len(adminName) > 30 {
+		return nil, errors.New("Administrator name is too long.")
+	}
+
+	var cert CertificateRSA2048
+	cert.Initialize()
+	cert.SetAdminName([]byte(adminName))
+	pubKey := []byte{0}
+	pubKey = append(pubKey, publicKey.N.Bytes()...)
+	cert.SetPublicKey(pubKey)
+	err := cert.SetSignatureWithSigner(signer)
+	if err != nil {
+		return nil, err
 	}
+	return asn1.Encode(cert)
 }
 
 /*----------------------------------------------------------------------------*/
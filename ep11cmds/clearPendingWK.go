@@ -7,13 +7,57 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Clears the pending wrapping key register                                   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose pending wrapping key register   */
+/*    is to be cleared                                                        */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ClearPendingWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
+
+	htpRequestString, err := ClearPendingWKReqWithContext(ctx, tr, de,
+		signers)
+	if err != nil {
+		return err
+	}
+
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ClearPendingWKWithContext, using the background context            */
+/*----------------------------------------------------------------------------*/
+func ClearPendingWKWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
+
+	return ClearPendingWKWithContext(context.Background(), tr, de, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Clears the pending wrapping key register                                   */
 /*                                                                            */
@@ -32,33 +76,21 @@ import (
 func ClearPendingWK(authToken string, urlStart string, de common.DomainEntry,
 	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
 
-	htpRequestString, err := ClearPendingWKReq(authToken, urlStart, de,
-		sigkeys, sigkeySkis, sigkeyTokens)
-	if err != nil {
-		return err
-	}
-
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
-
-	_, err = buildAdminRspBlk(htpResponseString, de)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer common.CloseSigners(signers)
+	return ClearPendingWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
 }
 
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for clearing the pending wrapping key register      */
 /*----------------------------------------------------------------------------*/
-func ClearPendingWKReq(authToken string, urlStart string, de common.DomainEntry,
-	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+func ClearPendingWKReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_CLEAR_NEXT_WK
@@ -66,6 +98,30 @@ func ClearPendingWKReq(authToken string, urlStart string, de common.DomainEntry,
 	// module ID filled in later
 	// transaction counter filled in later
 	// no payload
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ClearPendingWKReqWithContext, using the background context         */
+/*----------------------------------------------------------------------------*/
+func ClearPendingWKReqWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
+
+	return ClearPendingWKReqWithContext(context.Background(), tr, de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for clearing the pending wrapping key register      */
+/*----------------------------------------------------------------------------*/
+func ClearPendingWKReq(authToken string, urlStart string, de common.DomainEntry,
+	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return ClearPendingWKReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
 }
@@ -7,10 +7,17 @@
 //
 // Date          Initials        Description
 // 05/04/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
@@ -18,46 +25,109 @@ import (
 /* Commits the pending wrapping key register                                  */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain whose pending wrapping key register   */
 /*    is to be committed                                                      */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func CommitPendingWK(authToken string, urlStart string, de common.DomainEntry,
-	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
+func CommitPendingWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
 
 	// Get the verification pattern for the pending wrapping key register
-	domainInfo, err := QueryDomainInfo(authToken, urlStart, de)
+	domainInfo, err := QueryDomainInfoWithContext(ctx, tr, de)
 	if err != nil {
 		return err
 	}
 
-	htpRequestString, err := CommitPendingWKReq(authToken, urlStart, de,
-		domainInfo.NewMKVP, sigkeys, sigkeySkis, sigkeyTokens)
+	htpRequestString, err := CommitPendingWKReqWithContext(ctx, tr, de,
+		domainInfo.NewMKVP, signers)
 	if err != nil {
 		return err
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
+/*----------------------------------------------------------------------------*/
+/* Same as CommitPendingWKWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func CommitPendingWKWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
+
+	return CommitPendingWKWithContext(context.Background(), tr, de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Commits the pending wrapping key register                                  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain whose pending wrapping key register   */
+/*    is to be committed                                                      */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func CommitPendingWK(authToken string, urlStart string, de common.DomainEntry,
+	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
 
-	_, err = buildAdminRspBlk(htpResponseString, de)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
+	defer common.CloseSigners(signers)
+	return CommitPendingWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for committing the pending wrapping key register    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose pending wrapping key register   */
+/*    is to be committed                                                      */
+/* []byte -- the verification pattern of the pending wrapping key register    */
+/*    to be committed                                                         */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPRequest string with the signed CPRB for the command       */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func CommitPendingWKReqWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, vp []byte, signers []common.Signer) (string, error) {
+
+	var adminBlk AdminBlk
+	adminBlk.CmdID = XCP_ADM_COMMIT_WK
+	// administrative domain filled in later
+	// module ID filled in later
+	// transaction counter filled in later
+	adminBlk.CmdInput = vp
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as CommitPendingWKReqWithContext, using the background context        */
+/*----------------------------------------------------------------------------*/
+func CommitPendingWKReqWithTransport(tr common.Transport,
+	de common.DomainEntry, vp []byte, signers []common.Signer) (string, error) {
 
-	return nil
+	return CommitPendingWKReqWithContext(context.Background(), tr, de, vp,
+		signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -82,12 +152,11 @@ func CommitPendingWKReq(authToken string, urlStart string,
 	de common.DomainEntry, vp []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) (string, error) {
 
-	var adminBlk AdminBlk
-	adminBlk.CmdID = XCP_ADM_COMMIT_WK
-	// administrative domain filled in later
-	// module ID filled in later
-	// transaction counter filled in later
-	adminBlk.CmdInput = vp
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return CommitPendingWKReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, vp, signers)
 }
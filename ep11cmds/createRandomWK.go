@@ -7,10 +7,16 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
@@ -28,31 +34,30 @@ import (
 /* register are not empty, an error is returned.                              */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain where a random value is to be loaded  */
 /*    in one of the wrapping key registers                                    */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /* []byte -- the verification pattern of the generated master key value       */
 /*----------------------------------------------------------------------------*/
-func CreateRandomWK(authToken string, urlStart string, de common.DomainEntry,
-	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (error, []byte) {
+func CreateRandomWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (error, []byte) {
 
-	htpRequestString, err := CreateRandomWKReq(authToken, urlStart, de,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	htpRequestString, err := CreateRandomWKReqWithContext(ctx, tr, de,
+		signers)
 	if err != nil {
 		return err, nil
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+		htpRequestString)
 	if err != nil {
 		return err, nil
 	}
@@ -66,8 +71,26 @@ func CreateRandomWK(authToken string, urlStart string, de common.DomainEntry,
 }
 
 /*----------------------------------------------------------------------------*/
-/* Creates the HTPRequest for loading a random value in one of the wrapping   */
-/* key registers                                                              */
+/* Same as CreateRandomWKWithContext, using the background context            */
+/*----------------------------------------------------------------------------*/
+func CreateRandomWKWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (error, []byte) {
+
+	return CreateRandomWKWithContext(context.Background(), tr, de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Loads a random value in one of the wrapping key registers.                 */
+/*                                                                            */
+/* If the current wrapping key register is empty, it is loaded with a random  */
+/* value.                                                                     */
+/*                                                                            */
+/* If the current wrapping key register is not empty but the pending wrapping */
+/* key register is empty, the pending wrapping key register is loaded with a  */
+/* random value.                                                              */
+/*                                                                            */
+/* If both the current wrapping key register and pending wrapping key         */
+/* register are not empty, an error is returned.                              */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* authToken -- the authority token to use for the request                    */
@@ -79,11 +102,39 @@ func CreateRandomWK(authToken string, urlStart string, de common.DomainEntry,
 /* []string -- authentication tokens for the signature keys                   */
 /*                                                                            */
 /* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/* []byte -- the verification pattern of the generated master key value       */
+/*----------------------------------------------------------------------------*/
+func CreateRandomWK(authToken string, urlStart string, de common.DomainEntry,
+	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (error, []byte) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return err, nil
+	}
+	defer common.CloseSigners(signers)
+	return CreateRandomWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for loading a random value in one of the wrapping   */
+/* key registers                                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain where a random value is to be loaded  */
+/*    in one of the wrapping key registers                                    */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
 /* string -- the HTPRequest string with the signed CPRB for the command       */
 /* error -- reports any errors                                                */
 /*----------------------------------------------------------------------------*/
-func CreateRandomWKReq(authToken string, urlStart string, de common.DomainEntry,
-	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+func CreateRandomWKReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_GEN_WK
@@ -91,6 +142,44 @@ func CreateRandomWKReq(authToken string, urlStart string, de common.DomainEntry,
 	// module ID filled in later
 	// transaction counter filled in later
 	// no input parameters
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as CreateRandomWKReqWithContext, using the background context         */
+/*----------------------------------------------------------------------------*/
+func CreateRandomWKReqWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
+
+	return CreateRandomWKReqWithContext(context.Background(), tr, de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for loading a random value in one of the wrapping   */
+/* key registers                                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain where a random value is to be loaded  */
+/*    in one of the wrapping key registers                                    */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPRequest string with the signed CPRB for the command       */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func CreateRandomWKReq(authToken string, urlStart string, de common.DomainEntry,
+	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return CreateRandomWKReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
 }
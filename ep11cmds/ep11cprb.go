@@ -8,10 +8,24 @@
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
 // 11/11/2022    CLH             T444610 - Support 4770 crypto modules
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Retry requests after transient errors
+// 10/18/2026    CLH             Use the htp package to encode and decode messages
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Add verifyOASignature
+// 10/18/2026    CLH             Stop retries when the context is cancelled
+// 10/18/2026    CLH             Return error information as received
+// 10/18/2026    CLH             Add submitSignedCommand
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Return ErrResponseLost for commands without
+//                               output
+// 10/18/2026    CLH             Close the signers created from signature keys
+// 10/18/2026    CLH             Stop waiting for signers when ctx ends
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha512"
@@ -19,6 +33,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
 	"github.com/Logicalis/asn1"
 	"math/big"
 	"strconv"
@@ -27,6 +42,14 @@ import (
 
 var delimiter byte = ';'
 
+/** Reported when a signed command reached the crypto module but its
+    response was lost.  The command is not sent again.  The domain
+    transaction counter advances before the command is executed, so the
+    command may still have been rejected, and any outputs are not known. */
+var ErrResponseLost = errors.New("The command was received by the crypto " +
+	"module, but the response was lost." +
+	"\nCheck the state of the crypto module before continuing.")
+
 /** Represents an EP11 xcpAdminBlk */
 type AdminBlk struct {
 	CmdID              []byte
@@ -380,39 +403,14 @@ type CPRB struct {
 /* Create a CPRB to send to a host system                                     */
 /*----------------------------------------------------------------------------*/
 func NewCPRB(domainIndex int, payloadParm []byte) []byte {
-	newSlice := make([]byte, 0, 512)
-	newSlice = append(newSlice, 0, 32) // CPRB length
-	newSlice = append(newSlice, 4) // version
-	newSlice = append(newSlice, 0, 0) // reserved field 1
-	newSlice = append(newSlice, 0x80) // flags byte
-	newSlice = append(newSlice, 'T', '4') // CPRB subtype
-	newSlice = append(newSlice, 0, 0, 0, 0) // partition ID
-	newSlice = append(newSlice, common.Uint32To4ByteSlice(uint32(domainIndex))...) // domain index
-	newSlice = append(newSlice, 0, 0, 0, 0) // return code
-	newSlice = append(newSlice, 0, 0, 0, 0, 0, 0, 0, 0) // reserved field 2
-	newSlice = append(newSlice, common.Uint32To4ByteSlice(uint32(len(payloadParm)))...) // payload length
-	newSlice = append(newSlice, payloadParm...)
-
-	return newSlice
+	return htp.NewCPRB(domainIndex, payloadParm).Marshal()
 }
 
 /*----------------------------------------------------------------------------*/
 /* Create a HTPRequest specifying the XPNUM rule                              */
 /*----------------------------------------------------------------------------*/
 func NewXPNUMRequest(cryptoModuleIndex int, domainIndex int, sequence []byte) string {
-	var stringBuilder strings.Builder
-	reqHeader1 := ";PCI request;XPNUM   ;"
-	cmiString := strconv.Itoa(cryptoModuleIndex)
-	reqHeader2 := ";        ;"
-	cprb := strings.ToUpper(hex.EncodeToString(NewCPRB(domainIndex, sequence)))
-	reqLength := len(reqHeader1) + len(cmiString) + len(reqHeader2) + len(cprb)
-	reqLengthString := strconv.Itoa(reqLength)
-	stringBuilder.WriteString(reqLengthString)
-	stringBuilder.WriteString(reqHeader1)
-	stringBuilder.WriteString(cmiString)
-	stringBuilder.WriteString(reqHeader2)
-	stringBuilder.WriteString(cprb)
-	return stringBuilder.String()
+	return htp.NewHTPRequest(cryptoModuleIndex, domainIndex, sequence).Marshal()
 }
 
 /*----------------------------------------------------------------------------*/
@@ -453,13 +451,13 @@ func CreateQueryHTPRequest(cryptoModuleIndex int, domainIndex int,
 /* The number of signature keys provided indicates the number of signatures   */
 /* that need to be collected for the command.                                 */
 /*----------------------------------------------------------------------------*/
-func CreateSignedHTPRequest(authToken string, urlStart string, de common.DomainEntry,
-	adminBlock AdminBlk, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) (string, error) {
+func CreateSignedHTPRequestWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	adminBlock AdminBlk, signers []common.Signer) (string, error) {
 
 	// Issue Query Domain Attributes to get the administrative domain, the
 	// module identifier, and the transaction counter.
-	_, adminRspBlk, err := QueryDomainAttributes(authToken, urlStart, de)
+	_, adminRspBlk, err := QueryDomainAttributesWithContext(ctx, tr, de)
 	if err != nil {
 		return "", err
 	}
@@ -476,8 +474,8 @@ func CreateSignedHTPRequest(authToken string, urlStart string, de common.DomainE
 		panic(err)
 	}
 
-	signerInfo, err := CreateSignerInfo(adminBlockSeq, sigkeys, sigkeySkis,
-		sigkeyTokens)
+	signerInfo, err := CreateSignerInfoWithContext(ctx, adminBlockSeq,
+		signers)
 	if err != nil {
 		return "", err
 	}
@@ -498,6 +496,163 @@ func CreateSignedHTPRequest(authToken string, urlStart string, de common.DomainE
 	return NewXPNUMRequest(de.GetCryptoModuleIndex(), de.GetDomainIndex(), adminReqSeq), nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as CreateSignedHTPRequestWithContext, using the background context    */
+/*----------------------------------------------------------------------------*/
+func CreateSignedHTPRequestWithTransport(tr common.Transport,
+	de common.DomainEntry, adminBlock AdminBlk,
+	signers []common.Signer) (string, error) {
+
+	return CreateSignedHTPRequestWithContext(context.Background(), tr, de,
+		adminBlock, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest string for a signed command.                        */
+/*                                                                            */
+/* For a signed command, the administrative domain, the module identifier,    */
+/* and the transaction counter are determined by issuing a Query Domain       */
+/* Attributes command.                                                        */
+/*                                                                            */
+/* The number of signature keys provided indicates the number of signatures   */
+/* that need to be collected for the command.                                 */
+/*----------------------------------------------------------------------------*/
+func CreateSignedHTPRequest(authToken string, urlStart string, de common.DomainEntry,
+	adminBlock AdminBlk, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return CreateSignedHTPRequestWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, adminBlock, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends the HTPRequest for a query, retrying after transient errors as       */
+/* allowed by the retry policy of the transport.  Queries do not change the   */
+/* state of the domain, so they can always be sent again.                     */
+/*----------------------------------------------------------------------------*/
+func submitQueryHTPRequest(ctx context.Context, tr common.Transport,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	var htpResponse string
+	err := common.Retry(ctx, common.GetRetryPolicy(tr), func() error {
+		var err error
+		htpResponse, err = tr.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+			htpRequest)
+		return err
+	})
+	return htpResponse, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends the HTPRequest for a signed command.                                 */
+/*                                                                            */
+/* If ctx is already cancelled the command is not sent.  Once it is sent, the */
+/* response is awaited regardless of ctx.  Abandoning a signed command would  */
+/* leave it unknown whether the crypto module executed it.                    */
+/*                                                                            */
+/* After a transient error the command is sent again only if it did not take  */
+/* effect, as allowed by the retry policy of the transport.  The crypto       */
+/* module saves the transaction counter of each command it accepts, so the    */
+/* domain transaction counter is read using Query Domain Attributes:          */
+/* - If it matches the counter in the command, the command was received and  */
+/*   ErrResponseLost is returned.  Whether it succeeded is not known.         */
+/* - If it is one less, the command was not received and is sent again.       */
+/* - Otherwise another command was processed in the meantime and the          */
+/*   original error is returned.                                              */
+/*                                                                            */
+/* Cancelling ctx while waiting to send the command again stops the retries.  */
+/* As when the attempts run out, the outcome of the command is then unknown,  */
+/* and the original error is returned together with ctx.Err().                */
+/*----------------------------------------------------------------------------*/
+func submitSignedHTPRequest(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, htpRequest string) (string, error) {
+
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	waitCtx := ctx
+	ctx = common.WithoutCancel(ctx)
+	policy := common.GetRetryPolicy(tr)
+
+	for attempt := 1; ; attempt++ {
+		htpResponse, err := tr.SubmitHTPRequest(ctx, de.Crypto_instance_id,
+			de.Hsm_id, htpRequest)
+		if err == nil || !common.IsTransientError(err) ||
+			attempt >= policy.MaxAttempts {
+			return htpResponse, err
+		}
+		waitErr := policy.Wait(waitCtx, attempt)
+		if waitErr != nil {
+			return "", common.NewRetryStoppedError(err, waitErr)
+		}
+
+		sentCounter, counterErr := getTransactionCounter(htpRequest)
+		if counterErr != nil {
+			return "", err
+		}
+		_, adminRspBlk, queryErr := QueryDomainAttributesWithContext(ctx,
+			tr, de)
+		if queryErr != nil {
+			return "", err
+		}
+		if common.ByteSlicesAreEqual(adminRspBlk.TransactionCounter,
+			sentCounter) {
+			return "", ErrResponseLost
+		}
+		if !common.ByteSlicesAreEqual(
+			IncrementTransactionCounter(adminRspBlk.TransactionCounter),
+			sentCounter) {
+			return "", err
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends the HTPRequest for a signed command that returns no output, and      */
+/* checks the response.                                                       */
+/*                                                                            */
+/* ErrResponseLost is returned when the response is lost after the command    */
+/* was received.  The crypto module may have rejected the command, so the     */
+/* caller must query the domain to find out whether it took effect.           */
+/*----------------------------------------------------------------------------*/
+func submitSignedCommand(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, htpRequest string) error {
+
+	htpResponse, err := submitSignedHTPRequest(ctx, tr, de, htpRequest)
+	if err != nil {
+		return err
+	}
+	_, err = buildAdminRspBlk(htpResponse, de)
+	return err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the transaction counter from the xcpAdminBlk of the xcpAdminReq in */
+/* an HTPRequest created by NewXPNUMRequest                                   */
+/*----------------------------------------------------------------------------*/
+func getTransactionCounter(htpRequest string) ([]byte, error) {
+	request, err := htp.UnmarshalHTPRequest(htpRequest)
+	if err != nil {
+		return nil, err
+	}
+	var adminReq AdminReq
+	_, err = asn1.Decode(request.CPRB.Payload, &adminReq)
+	if err != nil {
+		return nil, err
+	}
+	var adminBlk AdminBlk
+	_, err = asn1.Decode(adminReq.AdminBlock, &adminBlk)
+	if err != nil {
+		return nil, err
+	}
+	return adminBlk.TransactionCounter, nil
+}
+
 /*----------------------------------------------------------------------------*/
 /* Increments the transaction counter                                         */
 /*----------------------------------------------------------------------------*/
@@ -628,52 +783,11 @@ func buildAdminRspBlk(htpResponse string, de common.DomainEntry) (AdminRspBlk, e
 	// in the DomainEntry is set to "not available".
 	if de.Public_key != "not available" {
 		// For all other cases, verify the OA signature in the xcpAdminRsp
-
-		// Get the ECC SignerInfo from the xcpAdminRsp
-		signerInfo, err := GetECCSignerInfo(adminRsp.SignerInfo) //@T444610CLH
+		err = verifyOASignature(adminRsp.AdminRspBlk, adminRsp.SignerInfo,
+			de, "xcpAdminRsp")
 		if err != nil {
 			return adminRspBlk, err
 		}
-
-		// Check for expected signature algorithm type
-		if !common.ByteSlicesAreEqual(
-				signerInfo.SignatureAlgorithmID, OID_ecdsaWithSHA512) {
-			return adminRspBlk, errors.New("Unsupported signature algorithm in xcpAdminRsp")	
-		}
-
-		// Calculate the SHA-512 hash of the xcpAdminRspBlk
-		hasher := sha512.New()
-		hasher.Write(adminRsp.AdminRspBlk)
-		sha512hash := hasher.Sum(nil)
-
-		// Get the public key from the DomainEntry
-		publicKey, err := hex.DecodeString(de.Public_key)
-		if err != nil {
-			panic(err)
-		}
-		var x, y big.Int
-		qlen := binary.BigEndian.Uint16(publicKey[24:26])
-		if len(publicKey) == CEX6P_PUBLIC_KEY_LENGTH {
-			// Handle public key from CEX6P
-			x.SetBytes(publicKey[1 : 67])
-			y.SetBytes(publicKey[67 : 133])
-		} else if len(publicKey) == int(26 + qlen) {
-			// Handle public key from CEX5P
-			pointLen := (qlen - 1) / 2
-			x.SetBytes(publicKey[27 : 27 + pointLen])
-			y.SetBytes(publicKey[27 + pointLen :])
-		} else {
-			return adminRspBlk, errors.New("Unrecognized format of crypto module public key")
-		}
-		pubkey := ecdsa.PublicKey{Curve: elliptic.P521(), X: &x, Y: &y} 
-
-		// Verify the signature
-		var r, s big.Int
-		r.SetBytes(signerInfo.SignatureR)
-		s.SetBytes(signerInfo.SignatureS)
-		if !ecdsa.Verify(&pubkey, sha512hash, &r, &s) {
-			return adminRspBlk, errors.New("Invalid signature in xcpAdminRsp")
-		}
 	}
 
 	// Decode the xcpAdminRspBlk sequence
@@ -713,6 +827,70 @@ func buildAdminRspBlk(htpResponse string, de common.DomainEntry) (AdminRspBlk, e
 	return adminRspBlk, nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Verifies an OA signature made by the crypto module of a domain.            */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte signedData -- the data that was signed                              */
+/* []byte signerInfoBytes -- SignerInfo holding the OA signature              */
+/* DomainEntry de -- identifies the domain, and holds the public key of the   */
+/*    crypto module                                                           */
+/* string what -- names the signed data in error messages                     */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports an invalid or unsupported signature                       */
+/*----------------------------------------------------------------------------*/
+func verifyOASignature(signedData []byte, signerInfoBytes []byte,
+	de common.DomainEntry, what string) error {
+
+	// Get the ECC SignerInfo
+	signerInfo, err := GetECCSignerInfo(signerInfoBytes) //@T444610CLH
+	if err != nil {
+		return err
+	}
+
+	// Check for expected signature algorithm type
+	if !common.ByteSlicesAreEqual(
+			signerInfo.SignatureAlgorithmID, OID_ecdsaWithSHA512) {
+		return errors.New("Unsupported signature algorithm in " + what)
+	}
+
+	// Calculate the SHA-512 hash of the signed data
+	hasher := sha512.New()
+	hasher.Write(signedData)
+	sha512hash := hasher.Sum(nil)
+
+	// Get the public key from the DomainEntry
+	publicKey, err := hex.DecodeString(de.Public_key)
+	if err != nil {
+		panic(err)
+	}
+	var x, y big.Int
+	qlen := binary.BigEndian.Uint16(publicKey[24:26])
+	if len(publicKey) == CEX6P_PUBLIC_KEY_LENGTH {
+		// Handle public key from CEX6P
+		x.SetBytes(publicKey[1 : 67])
+		y.SetBytes(publicKey[67 : 133])
+	} else if len(publicKey) == int(26 + qlen) {
+		// Handle public key from CEX5P
+		pointLen := (qlen - 1) / 2
+		x.SetBytes(publicKey[27 : 27 + pointLen])
+		y.SetBytes(publicKey[27 + pointLen :])
+	} else {
+		return errors.New("Unrecognized format of crypto module public key")
+	}
+	pubkey := ecdsa.PublicKey{Curve: elliptic.P521(), X: &x, Y: &y} 
+
+	// Verify the signature
+	var r, s big.Int
+	r.SetBytes(signerInfo.SignatureR)
+	s.SetBytes(signerInfo.SignatureS)
+	if !ecdsa.Verify(&pubkey, sha512hash, &r, &s) {
+		return errors.New("Invalid signature in " + what)
+	}
+	return nil
+}
+
 // rsp error type
 // rsp return code
 // rsp reason code
@@ -737,24 +915,23 @@ func buildAdminRspBlk(htpResponse string, de common.DomainEntry) (AdminRspBlk, e
 func ParseResponse(theRsp string) (RspInfo, string, error) {
 	var rspInfo RspInfo
 
-	stringParts := strings.Split(theRsp, ";")
-	// ignore stringParts[0]
-	if len(stringParts) < 2 {
-		return rspInfo, "", errors.New("Invalid HTPResponse, error information not found.")
-	}
-	if len(stringParts[1]) < 156 {
-		return rspInfo, "", errors.New("Invalid HTPResponse, invalid error information.")
+	response, err := htp.UnmarshalHTPResponse(theRsp)
+	if err != nil {
+		return rspInfo, "", err
 	}
-	rspInfo.ErrorType = stringParts[1][0:2]
-	rspInfo.ReturnCode = stringParts[1][2:10]
-	rspInfo.ReasonCode = stringParts[1][10:18]
-	rspInfo.ProgramID = stringParts[1][18:26]
-	rspInfo.ErrorLocation = stringParts[1][26:56]
-	rspInfo.ErrorText = stringParts[1][56:156]
-	if rspInfo.ErrorType != "00" || len(stringParts) < 3 {
+	// The error information fields are returned as they appear in the
+	// HTPResponse.  UnmarshalHTPResponse has checked they are present.
+	errorInfo := theRsp[strings.Index(theRsp, htp.DELIMITER)+1:]
+	rspInfo.ErrorType = errorInfo[0:2]
+	rspInfo.ReturnCode = errorInfo[2:10]
+	rspInfo.ReasonCode = errorInfo[10:18]
+	rspInfo.ProgramID = errorInfo[18:26]
+	rspInfo.ErrorLocation = errorInfo[26:56]
+	rspInfo.ErrorText = errorInfo[56:156]
+	if response.ErrorInfo.ErrorType != 0 || response.CPRB == nil {
 		return rspInfo, "", nil
 	}
-	return rspInfo, stringParts[2], nil
+	return rspInfo, strings.ToUpper(hex.EncodeToString(response.CPRB.Marshal())), nil
 }
 
 /*
@@ -762,54 +939,36 @@ Remove the front part of the HTPResponse (including the CPRB) and just
 return the payload
 */
 func GetRspPayload(theRsp string) ([]byte, error) {
-	var asn1Data []byte
 	// Extract error information and the EP11 response CPRB
-	rspInfo, cprbString, err := ParseResponse(theRsp)
+	response, err := htp.UnmarshalHTPResponse(theRsp)
 	if err != nil {
-		return asn1Data, err
+		return nil, err
 	}
 	// Exit if the TKE catcher program reports an error
-	if rspInfo.ErrorType != "00" {
-		return asn1Data, errors.New(
+	if response.ErrorInfo.ErrorType != 0 {
+		rspInfo, _, _ := ParseResponse(theRsp)
+		return nil, errors.New(
 			"HTPResponse error." +
 				"\nError message:  " + GetErrorMsg(rspInfo.ErrorType, rspInfo.ReturnCode, rspInfo.ReasonCode) +
-				"\nProgram ID:     " + rspInfo.ProgramID +
-				"\nError location: " + rspInfo.ErrorLocation +
-				"\nError text:     " + rspInfo.ErrorText)
+				"\nProgram ID:     " + response.ErrorInfo.ProgramID +
+				"\nError location: " + response.ErrorInfo.ErrorLocation +
+				"\nError text:     " + response.ErrorInfo.ErrorText)
 	}
-	cprbData, err2 := hex.DecodeString(cprbString)
-	if err2 != nil {
-		return asn1Data, err2
+	if response.CPRB == nil {
+		return nil, errors.New("Invalid HTPResponse, EP11 response CPRB not found.")
 	}
-	// Parse the CPRB
-	cprb := cprbData[0:32]
-	var returnCPRB = new(CPRB)
-	copy(returnCPRB.length[:], cprb[0:2])
-	returnCPRB.version = cprb[2]
-	copy(returnCPRB.reserved1[:], cprb[3:5])
-	returnCPRB.flags = cprb[5]
-	copy(returnCPRB.subtype[:], cprb[6:8])
-	copy(returnCPRB.partitionID[:], cprb[8:12])
-	copy(returnCPRB.domainID[:], cprb[12:16])
-	copy(returnCPRB.returnCode[:], cprb[16:20])
 	// Exit if return code in the CPRB is not zero.
-	returnCode := int(binary.BigEndian.Uint16(cprb[16:18]))
-	reasonCode := int(binary.BigEndian.Uint16(cprb[18:20]))
 	// TKEHTP.send breaks the four-byte field in the CPRB into a
 	// return code and a reason code.
+	returnCode := response.CPRB.GetReturnCode()
+	reasonCode := response.CPRB.GetReasonCode()
 	if returnCode != 0 || reasonCode != 0 {
-		return asn1Data, errors.New(
+		return nil, errors.New(
 			"Error reported in EP11 CPRB." +
 				"\nReturn code: " + strconv.Itoa(returnCode) +
 				"\nReason code: " + strconv.Itoa(reasonCode))
 	}
-	copy(returnCPRB.reserved2[:], cprb[20:28])
-	copy(returnCPRB.payloadLength[:], cprb[28:32])
-	asn1DataLength := common.FourByteSliceToInt(cprb[28:32])
-	asn1DataEndOffset := asn1DataLength + 32
-
-	asn1Data = cprbData[32:asn1DataEndOffset]
-	return asn1Data, nil
+	return response.CPRB.Payload, nil
 }
 
 /*----------------------------------------------------------------------------*/
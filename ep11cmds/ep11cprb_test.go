@@ -0,0 +1,212 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test commands with and without output
+// 10/18/2026    CLH             Test rejected commands whose response was lost
+
+package ep11cmds_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+var errDropped = errors.New("connection reset")
+
+/*----------------------------------------------------------------------------*/
+/* Transport that fails one request with a transient error.  The request is   */
+/* either dropped before it reaches the crypto unit, or passed on and its     */
+/* response dropped.                                                          */
+/*----------------------------------------------------------------------------*/
+type flakyTransport struct {
+	common.Transport
+	failAt   int                // request to fail, 1 for the first
+	deliver  bool               // pass the failed request on
+	onFail   context.CancelFunc // called when the request fails, if set
+	requests int
+}
+
+func (f *flakyTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	f.requests++
+	if f.requests != f.failAt {
+		return f.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+			htpRequest)
+	}
+	if f.deliver {
+		f.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId, htpRequest)
+	}
+	if f.onFail != nil {
+		f.onFail()
+	}
+	return "", common.NewTransientError(errDropped)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an emulator with one crypto unit in imprint mode, and returns the  */
+/* domain and the number of requests sent to create a signed Zeroize Domain   */
+/* request.  The caller must close the emulator.                              */
+/*----------------------------------------------------------------------------*/
+func newImprintDomain(t *testing.T) (*emulator.Emulator, common.DomainEntry,
+	int) {
+
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = em.AddCryptoUnit("instance1", "recovery",
+		"[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX8P)
+	if err != nil {
+		em.Close()
+		t.Fatal(err)
+	}
+	domains, err := tkesdk.GetDomains(tkesdk.CommonInputs{
+		InstanceId: "instance1", Transport: em})
+	if err != nil {
+		em.Close()
+		t.Fatal(err)
+	}
+	counter := &flakyTransport{Transport: em}
+	_, err = ep11cmds.ZeroizeDomainReqWithTransport(counter, domains[0], nil)
+	if err != nil {
+		em.Close()
+		t.Fatal(err)
+	}
+	return em, domains[0], counter.requests
+}
+
+var fastRetry = common.RetryPolicy{MaxAttempts: 3,
+	InitialDelay: time.Millisecond}
+
+/** A signed command that did not reach the crypto unit is sent again */
+func TestSignedCommandResent(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	flaky := &flakyTransport{Transport: em, failAt: queries + 1}
+
+	err := ep11cmds.ZeroizeDomainWithTransport(
+		common.WithRetryPolicy(flaky, fastRetry), de, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transaction counter is read once before resending
+	if flaky.requests != queries+3 {
+		t.Errorf("%d requests were sent, expected %d", flaky.requests,
+			queries+3)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A signed command without output that was received is not sent again, and   */
+/* ErrResponseLost is returned since its outcome is not known.                */
+/*----------------------------------------------------------------------------*/
+func TestSignedCommandNotRepeated(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	flaky := &flakyTransport{Transport: em, failAt: queries + 1,
+		deliver: true}
+
+	err := ep11cmds.ZeroizeDomainWithTransport(
+		common.WithRetryPolicy(flaky, fastRetry), de, nil)
+	if err != ep11cmds.ErrResponseLost {
+		t.Fatalf("Error is %v, expected ErrResponseLost", err)
+	}
+	if flaky.requests != queries+2 {
+		t.Errorf("%d requests were sent, expected %d", flaky.requests,
+			queries+2)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A command rejected by the crypto module after its transaction counter was  */
+/* accepted is not reported as successful when its response is lost.  Commit  */
+/* is rejected in imprint mode.                                               */
+/*----------------------------------------------------------------------------*/
+func TestRejectedCommandResponseLost(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	// Commit first queries the verification pattern of the pending key
+	flaky := &flakyTransport{Transport: em, failAt: queries + 2,
+		deliver: true}
+
+	err := ep11cmds.CommitPendingWKWithTransport(
+		common.WithRetryPolicy(flaky, fastRetry), de, nil)
+	if err != ep11cmds.ErrResponseLost {
+		t.Fatalf("Error is %v, expected ErrResponseLost", err)
+	}
+	err = ep11cmds.CommitPendingWKWithTransport(em, de, nil)
+	if err == nil || err == ep11cmds.ErrResponseLost {
+		t.Fatalf("Error is %v, expected the command to be rejected", err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A signed command with output that took effect is not sent again, and       */
+/* ErrResponseLost is returned since the output is not known.                 */
+/*----------------------------------------------------------------------------*/
+func TestSignedCommandWithOutputNotRepeated(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	flaky := &flakyTransport{Transport: em, failAt: queries + 1,
+		deliver: true}
+
+	err, _ := ep11cmds.CreateRandomWKWithTransport(common.WithRetryPolicy(flaky,
+		fastRetry), de, nil)
+	if err != ep11cmds.ErrResponseLost {
+		t.Fatalf("Error is %v, expected ErrResponseLost", err)
+	}
+	if flaky.requests != queries+2 {
+		t.Errorf("%d requests were sent, expected %d", flaky.requests,
+			queries+2)
+	}
+}
+
+/** Without a retry policy the transient error is returned */
+func TestSignedCommandNoRetryPolicy(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	flaky := &flakyTransport{Transport: em, failAt: queries + 1}
+
+	err := ep11cmds.ZeroizeDomainWithTransport(flaky, de, nil)
+	if !errors.Is(err, errDropped) || flaky.requests != queries+1 {
+		t.Errorf("Error %v after %d requests", err, flaky.requests)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Cancelling the context while waiting to resend a signed command stops the  */
+/* retries and returns both the original error and the context error.         */
+/*----------------------------------------------------------------------------*/
+func TestSignedCommandCancelledWhileWaiting(t *testing.T) {
+	em, de, queries := newImprintDomain(t)
+	defer em.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	flaky := &flakyTransport{Transport: em, failAt: queries + 1,
+		onFail: cancel}
+	policy := common.RetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour}
+
+	err := ep11cmds.ZeroizeDomainWithContext(ctx,
+		common.WithRetryPolicy(flaky, policy), de, nil)
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errDropped) {
+		t.Errorf("Error %v does not match both errors", err)
+	}
+	if flaky.requests != queries+1 {
+		t.Errorf("%d requests were sent, expected %d", flaky.requests,
+			queries+1)
+	}
+}
@@ -7,15 +7,79 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Describe exports with several key parts
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Exports the current wrapping key register                                  */
+/*                                                                            */
+/* The parameter file sets the number of key parts and the M policy (number   */
+/* of key parts needed to reconstruct the key).  ExportWKKeyParts builds the  */
+/* parameter file from a set of KPH certificates and an M policy, and returns */
+/* the encrypted key parts as EncryptedKeyPart values.                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose current wrapping key register   */
+/*    is to be exported                                                       */
+/* []byte -- parameter file with format described in section 5.3 ("Serialized */
+/*    module state") of the EP11 wire formats document.  Contains inputs to   */
+/*    the Export WK command, such as the M policy and KPH certificates to use.*/
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- parameter map with the encrypted key parts and their OA          */
+/*    signatures, see ParseEncryptedKeyParts                                  */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ExportWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) ([]byte, error) {
+
+	htpRequestString, err := ExportWKReqWithContext(ctx, tr, de, pfile,
+		signers)
+	if err != nil {
+		return nil, err
+	}
+
+	htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+		htpRequestString)
+	if err != nil {
+		return nil, err
+	}
+
+	adminRspBlk, err := buildAdminRspBlk(htpResponseString, de)
+	if err != nil {
+		return nil, err
+	}
+
+	return adminRspBlk.CmdOutput, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ExportWKWithContext, using the background context                  */
+/*----------------------------------------------------------------------------*/
+func ExportWKWithTransport(tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) ([]byte, error) {
+
+	return ExportWKWithContext(context.Background(), tr, de, pfile, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Exports the current wrapping key register using a single key part          */
 /*                                                                            */
@@ -45,26 +109,52 @@ func ExportWK(authToken string, urlStart string, de common.DomainEntry,
 	pfile []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) ([]byte, error) {
 
-	htpRequestString, err := ExportWKReq(authToken, urlStart, de, pfile,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return nil, err
 	}
+	defer common.CloseSigners(signers)
+	return ExportWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, pfile, signers)
+}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for exporting the current wrapping key register     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose current wrapping key register   */
+/*    is to be exported                                                       */
+/* []byte -- parameter file with format described in section 5.3 ("Serialized */
+/*    module state") of the EP11 wire formats document.  Contains inputs to   */
+/*    the Export WK command, such as the M policy and KPH certificates to use.*/
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPRequest string with the signed CPRB for the command       */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func ExportWKReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) (string, error) {
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return nil, err
-	}
+	var adminBlk AdminBlk
+	adminBlk.CmdID = XCP_ADM_EXPORT_WK
+	// administrative domain filled in later
+	// module ID filled in later
+	// transaction counter filled in later
+	adminBlk.CmdInput = pfile
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
 
-	adminRspBlk, err := buildAdminRspBlk(htpResponseString, de)
-	if err != nil {
-		return nil, err
-	}
+/*----------------------------------------------------------------------------*/
+/* Same as ExportWKReqWithContext, using the background context               */
+/*----------------------------------------------------------------------------*/
+func ExportWKReqWithTransport(tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) (string, error) {
 
-	return adminRspBlk.CmdOutput, nil
+	return ExportWKReqWithContext(context.Background(), tr, de, pfile, signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -90,49 +180,47 @@ func ExportWKReq(authToken string, urlStart string, de common.DomainEntry,
 	pfile []byte, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) (string, error) {
 
-	var adminBlk AdminBlk
-	adminBlk.CmdID = XCP_ADM_EXPORT_WK
-	// administrative domain filled in later
-	// module ID filled in later
-	// transaction counter filled in later
-	adminBlk.CmdInput = pfile
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return ExportWKReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, pfile, signers)
 }
 
 /*----------------------------------------------------------------------------*/
 /* Exports the pending wrapping key register                                  */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain whose pending wrapping key register   */
 /*    is to be exported                                                       */
 /* []byte -- parameter file with format described in section 5.3 ("Serialized */
 /*    module state") of the EP11 wire formats document.  Contains inputs to   */
 /*    the Export WK command, such as the M policy and KPH certificates to use.*/
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
-/* []byte -- raw encrypted key parts                                          */
+/* []byte -- parameter map with the encrypted key parts and their OA          */
+/*    signatures, see ParseEncryptedKeyParts                                  */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func ExportPendingWK(authToken string, urlStart string, de common.DomainEntry,
-	pfile []byte, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) ([]byte, error) {
+func ExportPendingWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) ([]byte, error) {
 
-	htpRequestString, err := ExportPendingWKReq(authToken, urlStart, de, pfile,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	htpRequestString, err := ExportPendingWKReqWithContext(ctx, tr, de, pfile,
+		signers)
 	if err != nil {
 		return nil, err
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+		htpRequestString)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +234,17 @@ func ExportPendingWK(authToken string, urlStart string, de common.DomainEntry,
 }
 
 /*----------------------------------------------------------------------------*/
-/* Creates the HTPRequest for exporting the pending wrapping key register     */
+/* Same as ExportPendingWKWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWKWithTransport(tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) ([]byte, error) {
+
+	return ExportPendingWKWithContext(context.Background(), tr, de, pfile,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports the pending wrapping key register                                  */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* authToken -- the authority token to use for the request                    */
@@ -161,12 +259,42 @@ func ExportPendingWK(authToken string, urlStart string, de common.DomainEntry,
 /* []string -- authentication tokens for the signature keys                   */
 /*                                                                            */
 /* Outputs:                                                                   */
+/* []byte -- raw encrypted key parts                                          */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWK(authToken string, urlStart string, de common.DomainEntry,
+	pfile []byte, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) ([]byte, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer common.CloseSigners(signers)
+	return ExportPendingWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, pfile, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for exporting the pending wrapping key register     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose pending wrapping key register   */
+/*    is to be exported                                                       */
+/* []byte -- parameter file with format described in section 5.3 ("Serialized */
+/*    module state") of the EP11 wire formats document.  Contains inputs to   */
+/*    the Export WK command, such as the M policy and KPH certificates to use.*/
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
 /* string -- the HTPRequest string with the signed CPRB for the command       */
 /* error -- reports any errors                                                */
 /*----------------------------------------------------------------------------*/
-func ExportPendingWKReq(authToken string, urlStart string, de common.DomainEntry,
-	pfile []byte, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) (string, error) {
+func ExportPendingWKReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_EXPORT_NEXT_WK
@@ -174,8 +302,50 @@ func ExportPendingWKReq(authToken string, urlStart string, de common.DomainEntry
 	// module ID filled in later
 	// transaction counter filled in later
 	adminBlk.CmdInput = pfile
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ExportPendingWKReqWithContext, using the background context        */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWKReqWithTransport(tr common.Transport, de common.DomainEntry,
+	pfile []byte, signers []common.Signer) (string, error) {
+
+	return ExportPendingWKReqWithContext(context.Background(), tr, de, pfile,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for exporting the pending wrapping key register     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain whose pending wrapping key register   */
+/*    is to be exported                                                       */
+/* []byte -- parameter file with format described in section 5.3 ("Serialized */
+/*    module state") of the EP11 wire formats document.  Contains inputs to   */
+/*    the Export WK command, such as the M policy and KPH certificates to use.*/
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the HTPRequest string with the signed CPRB for the command       */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWKReq(authToken string, urlStart string, de common.DomainEntry,
+	pfile []byte, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return ExportPendingWKReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, pfile, signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -0,0 +1,234 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add VerifyKeyPartSignature
+// 10/18/2026    CLH             Require an OA signature for every key part
+
+package ep11cmds
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* An encrypted key part returned by Export WK.                               */
+/*----------------------------------------------------------------------------*/
+type EncryptedKeyPart struct {
+	Index         uint32 // index of the KPH certificate used to encrypt it
+	RecipientInfo []byte // the encrypted key part, for ImportWK
+	Signature     []byte // SignerInfo with the OA signature over the
+	                     // RecipientInfo, nil if none was returned
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports the current wrapping key register as N key parts, any M of which   */
+/* can be imported to reconstruct the wrapping key.                           */
+/*                                                                            */
+/* The OA signature returned with each encrypted key part is verified using   */
+/* the public key of the crypto module in the DomainEntry.                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose current wrapping key register   */
+/*    is to be exported                                                       */
+/* [][]byte -- KPH certificates, one for each key part, from KPHCert.  The    */
+/*    same certificate can be used for more than one key part.                */
+/* int -- the M policy, the number of key parts needed to reconstruct the     */
+/*    wrapping key                                                            */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []EncryptedKeyPart -- the encrypted key parts, in the order of the KPH     */
+/*    certificates                                                            */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ExportWKKeyPartsWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry, kphcerts [][]byte,
+	mPolicy int, signers []common.Signer) ([]EncryptedKeyPart, error) {
+
+	pfile, err := ExportWKKeyPartsParameterFile(kphcerts, mPolicy)
+	if err != nil {
+		return nil, err
+	}
+	pdata, err := ExportWKWithContext(ctx, tr, de, pfile, signers)
+	if err != nil {
+		return nil, err
+	}
+	return checkExportedKeyParts(pdata, len(kphcerts), de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ExportWKKeyPartsWithContext, using the background context          */
+/*----------------------------------------------------------------------------*/
+func ExportWKKeyParts(tr common.Transport, de common.DomainEntry,
+	kphcerts [][]byte, mPolicy int,
+	signers []common.Signer) ([]EncryptedKeyPart, error) {
+
+	return ExportWKKeyPartsWithContext(context.Background(), tr, de,
+		kphcerts, mPolicy, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports the pending wrapping key register as N key parts, any M of which   */
+/* can be imported to reconstruct the wrapping key.  See                      */
+/* ExportWKKeyPartsWithContext for the inputs and outputs.                    */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWKKeyPartsWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry, kphcerts [][]byte,
+	mPolicy int, signers []common.Signer) ([]EncryptedKeyPart, error) {
+
+	pfile, err := ExportWKKeyPartsParameterFile(kphcerts, mPolicy)
+	if err != nil {
+		return nil, err
+	}
+	pdata, err := ExportPendingWKWithContext(ctx, tr, de, pfile, signers)
+	if err != nil {
+		return nil, err
+	}
+	return checkExportedKeyParts(pdata, len(kphcerts), de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ExportPendingWKKeyPartsWithContext, using the background context   */
+/*----------------------------------------------------------------------------*/
+func ExportPendingWKKeyParts(tr common.Transport, de common.DomainEntry,
+	kphcerts [][]byte, mPolicy int,
+	signers []common.Signer) ([]EncryptedKeyPart, error) {
+
+	return ExportPendingWKKeyPartsWithContext(context.Background(), tr, de,
+		kphcerts, mPolicy, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Construct a parameter file for an Export WK request with several key       */
+/* parts.  See ExportWKParameterFile for the parameter file format.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* [][]byte -- KPH certificates, one for each key part                        */
+/* int -- the M policy, between 1 and the number of KPH certificates          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the parameter file                                               */
+/* error -- reports an invalid M policy or number of KPH certificates         */
+/*----------------------------------------------------------------------------*/
+func ExportWKKeyPartsParameterFile(kphcerts [][]byte,
+	mPolicy int) ([]byte, error) {
+
+	if len(kphcerts) == 0 || len(kphcerts) > 255 {
+		return nil, errors.New("Between 1 and 255 KPH certificates must be " +
+			"provided to export a wrapping key.")
+	}
+	if mPolicy < 1 || mPolicy > len(kphcerts) {
+		return nil, errors.New("Invalid M policy " + strconv.Itoa(mPolicy) +
+			".  The M policy must be between 1 and the number of KPH " +
+			"certificates, " + strconv.Itoa(len(kphcerts)) + ".")
+	}
+	pMap := common.NewParameterMap()
+	pMap.Put(common.PMTAG_M_POLICY, uint32(mPolicy), make([]byte, 0))
+	for i, kphcert := range kphcerts {
+		pMap.Put(common.PMTAG_KPH_CERTIFICATE, uint32(i), kphcert)
+	}
+	pMap.Put(common.PMTAG_STATE_SCOPE, 0x0000000C, make([]byte, 0))
+		// Don't include KPH and OA certificates in output, as in
+		// ExportWKParameterFile
+	return pMap.GenerateBytes(), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the encrypted key parts and their OA signatures from the output of */
+/* Export WK.                                                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte -- the parameter map returned by ExportWK or ExportPendingWK        */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []EncryptedKeyPart -- the encrypted key parts, in index order              */
+/* error -- reports a parameter map that cannot be parsed                     */
+/*----------------------------------------------------------------------------*/
+func ParseEncryptedKeyParts(pdata []byte) ([]EncryptedKeyPart, error) {
+	var pMap common.ParameterMap
+	pMap, err := pMap.Load(pdata)
+	if err != nil {
+		return nil, err
+	}
+	keyParts := make([]EncryptedKeyPart, 0)
+	var index uint32
+	for index = 0; pMap.Contains(common.PMTAG_ENCR_KEY_PART, index); index++ {
+		keyPart := EncryptedKeyPart{Index: index, RecipientInfo: pMap.
+			GetDataUsingIndex(common.PMTAG_ENCR_KEY_PART, index)}
+		if pMap.Contains(common.PMTAG_SIGNATURE_ENCR_KEY_PART, index) {
+			keyPart.Signature = pMap.GetDataUsingIndex(
+				common.PMTAG_SIGNATURE_ENCR_KEY_PART, index)
+		}
+		keyParts = append(keyParts, keyPart)
+	}
+	return keyParts, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Parses the output of Export WK, checks that one key part was returned for  */
+/* each KPH certificate, and verifies the OA signatures of the key parts.     */
+/* Every key part must have an OA signature.                                  */
+/*----------------------------------------------------------------------------*/
+func checkExportedKeyParts(pdata []byte, expected int,
+	de common.DomainEntry) ([]EncryptedKeyPart, error) {
+
+	keyParts, err := ParseEncryptedKeyParts(pdata)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyParts) != expected {
+		return nil, errors.New("Export WK returned " +
+			strconv.Itoa(len(keyParts)) + " encrypted key parts, but " +
+			strconv.Itoa(expected) + " were requested.")
+	}
+	for _, keyPart := range keyParts {
+		err = VerifyKeyPartSignature(keyPart, de)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keyParts, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies the OA signature returned with an encrypted key part.  Only the   */
+/* Public_key field of the DomainEntry is used, so key parts kept in a file   */
+/* can be checked against the crypto module public key saved with them.       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* EncryptedKeyPart -- the key part and its OA signature                      */
+/* DomainEntry -- holds the public key of the crypto module that exported the */
+/*    key part                                                                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports a missing or invalid signature                            */
+/*----------------------------------------------------------------------------*/
+func VerifyKeyPartSignature(keyPart EncryptedKeyPart,
+	de common.DomainEntry) error {
+
+	what := "encrypted key part " + strconv.Itoa(int(keyPart.Index))
+	if keyPart.Signature == nil {
+		return errors.New("No OA signature for " + what)
+	}
+	publicKey, err := hex.DecodeString(de.Public_key)
+	if err != nil || len(publicKey) < 26 {
+		return errors.New("Invalid crypto module public key for " + what)
+	}
+	return verifyOASignature(keyPart.RecipientInfo, keyPart.Signature, de,
+		what)
+}
@@ -0,0 +1,69 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package ep11cmds
+
+import (
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** The parameter file holds the M policy and one KPH certificate per part */
+func TestExportWKKeyPartsParameterFile(t *testing.T) {
+	kphcerts := [][]byte{{0x01}, {0x02}, {0x03}}
+	pfile, err := ExportWKKeyPartsParameterFile(kphcerts, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pMap common.ParameterMap
+	pMap, err = pMap.Load(pfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pMap.GetAuxInt(common.PMTAG_M_POLICY) != 2 {
+		t.Errorf("M policy %d", pMap.GetAuxInt(common.PMTAG_M_POLICY))
+	}
+	for i := range kphcerts {
+		if !pMap.Contains(common.PMTAG_KPH_CERTIFICATE, uint32(i)) {
+			t.Errorf("KPH certificate %d is missing", i)
+		}
+	}
+
+	for _, mPolicy := range []int{0, 4} {
+		if _, err := ExportWKKeyPartsParameterFile(kphcerts,
+			mPolicy); err == nil {
+			t.Errorf("M policy %d was accepted", mPolicy)
+		}
+	}
+	if _, err := ExportWKKeyPartsParameterFile(nil, 1); err == nil {
+		t.Error("No KPH certificates were accepted")
+	}
+}
+
+/** Exported key parts without an OA signature are rejected */
+func TestCheckExportedKeyPartsNeedsSignatures(t *testing.T) {
+	pMap := common.NewParameterMap()
+	pMap.Put(common.PMTAG_ENCR_KEY_PART, 0, []byte{0x30, 0x00})
+	pdata := pMap.GenerateBytes()
+
+	keyParts, err := ParseEncryptedKeyParts(pdata)
+	if err != nil || len(keyParts) != 1 || keyParts[0].Signature != nil {
+		t.Fatalf("ParseEncryptedKeyParts returned %v, %v", keyParts, err)
+	}
+	if _, err = checkExportedKeyParts(pdata, 1,
+		common.DomainEntry{}); err == nil {
+		t.Error("A key part without an OA signature was accepted")
+	}
+	if _, err = checkExportedKeyParts(pdata, 2,
+		common.DomainEntry{}); err == nil {
+		t.Error("One key part was accepted when two were requested")
+	}
+}
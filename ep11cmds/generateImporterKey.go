@@ -7,10 +7,15 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rsa"
@@ -31,36 +36,33 @@ const XCP_IMPRKEY_EC_P521 = 3 //@T390301CLH
 /* Generates a 2048-bit RSA importer key.                                     */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain whose attributes are to be set        */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* rsa.PublicKey -- the public part of the generated 2048-bit RSA key         */
 /* []byte -- the Subject Key Identifier of the RSA public key                 */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func Generate2048RSAImporterKey(authToken string, urlStart string,
-	de common.DomainEntry, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) (rsa.PublicKey, []byte, error) {
+func Generate2048RSAImporterKeyWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) (rsa.PublicKey, []byte, error) {
 
 	var pubKey rsa.PublicKey
 	var ski []byte
 
-	htpRequestString, err := GenerateImporterKeyRequest(
-		authToken, urlStart, de, XCP_IMPRKEY_RSA_2048, sigkeys, sigkeySkis,
-		sigkeyTokens)
+	htpRequestString, err := GenerateImporterKeyRequestWithContext(
+		ctx, tr, de, XCP_IMPRKEY_RSA_2048, signers)
 	if err != nil {
 		return pubKey, ski, err
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+		htpRequestString)
 	if err != nil {
 		return pubKey, ski, err
 	}
@@ -68,6 +70,47 @@ func Generate2048RSAImporterKey(authToken string, urlStart string,
 	return Generate2048RSAImporterKeyResponse(htpResponseString, de)
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as Generate2048RSAImporterKeyWithContext, using the                   */
+/* background context                                                         */
+/*----------------------------------------------------------------------------*/
+func Generate2048RSAImporterKeyWithTransport(tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) (rsa.PublicKey, []byte,
+	error) {
+
+	return Generate2048RSAImporterKeyWithContext(context.Background(), tr, de,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates a 2048-bit RSA importer key.                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain whose attributes are to be set        */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* rsa.PublicKey -- the public part of the generated 2048-bit RSA key         */
+/* []byte -- the Subject Key Identifier of the RSA public key                 */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func Generate2048RSAImporterKey(authToken string, urlStart string,
+	de common.DomainEntry, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) (rsa.PublicKey, []byte, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return rsa.PublicKey{}, nil, err
+	}
+	defer common.CloseSigners(signers)
+	return Generate2048RSAImporterKeyWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Parse a generate importer key response for an RSA 2048 importer key.       */
 /*                                                                            */
@@ -122,36 +165,33 @@ func (info *RecipientInfo2048) Initialize(ski []byte, encrKey []byte) {
 /* Generates a P521 EC importer key.                                          */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain whose attributes are to be set        */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* ecdsa.PublicKey -- the public part of the generated P521 EC key            */
 /* []byte -- the Subject Key Identifier of the EC public key                  */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func GenerateP521ECImporterKey(authToken string, urlStart string,
-	de common.DomainEntry, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) (ecdsa.PublicKey, []byte, error) {
+func GenerateP521ECImporterKeyWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) (ecdsa.PublicKey, []byte, error) {
 
 	var pubKey ecdsa.PublicKey
 	var ski []byte
 
-	htpRequestString, err := GenerateImporterKeyRequest(
-		authToken, urlStart, de, XCP_IMPRKEY_EC_P521, sigkeys, sigkeySkis,
-		sigkeyTokens)
+	htpRequestString, err := GenerateImporterKeyRequestWithContext(
+		ctx, tr, de, XCP_IMPRKEY_EC_P521, signers)
 	if err != nil {
 		return pubKey, ski, err
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+		htpRequestString)
 	if err != nil {
 		return pubKey, ski, err
 	}
@@ -159,6 +199,46 @@ func GenerateP521ECImporterKey(authToken string, urlStart string,
 	return GenerateP521ECImporterKeyResponse(htpResponseString, de)
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as GenerateP521ECImporterKeyWithContext, using the background context */
+/*----------------------------------------------------------------------------*/
+func GenerateP521ECImporterKeyWithTransport(tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) (ecdsa.PublicKey, []byte,
+	error) {
+
+	return GenerateP521ECImporterKeyWithContext(context.Background(), tr, de,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates a P521 EC importer key.                                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain whose attributes are to be set        */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* ecdsa.PublicKey -- the public part of the generated P521 EC key            */
+/* []byte -- the Subject Key Identifier of the EC public key                  */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func GenerateP521ECImporterKey(authToken string, urlStart string,
+	de common.DomainEntry, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) (ecdsa.PublicKey, []byte, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return ecdsa.PublicKey{}, nil, err
+	}
+	defer common.CloseSigners(signers)
+	return GenerateP521ECImporterKeyWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Parse the response from a generate importer key request when a P521 EC     */
 /* key was requested.                                                         */
@@ -203,9 +283,9 @@ func GenerateP521ECImporterKeyResponse(htpResponse string, de common.DomainEntry
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for generating a domain importer key                */
 /*----------------------------------------------------------------------------*/
-func GenerateImporterKeyRequest(authToken string, urlStart string, de common.DomainEntry,
-	importerKeyType uint32, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) (string, error) {
+func GenerateImporterKeyRequestWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	importerKeyType uint32, signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_GEN_IMPORTER
@@ -214,6 +294,34 @@ func GenerateImporterKeyRequest(authToken string, urlStart string, de common.Dom
 	// transaction counter filled in later
 	adminBlk.CmdInput = common.Uint32To4ByteSlice(importerKeyType)
 
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as GenerateImporterKeyRequestWithContext, using the                   */
+/* background context                                                         */
+/*----------------------------------------------------------------------------*/
+func GenerateImporterKeyRequestWithTransport(tr common.Transport,
+	de common.DomainEntry, importerKeyType uint32,
+	signers []common.Signer) (string, error) {
+
+	return GenerateImporterKeyRequestWithContext(context.Background(), tr, de,
+		importerKeyType, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for generating a domain importer key                */
+/*----------------------------------------------------------------------------*/
+func GenerateImporterKeyRequest(authToken string, urlStart string, de common.DomainEntry,
+	importerKeyType uint32, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return GenerateImporterKeyRequestWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, importerKeyType,
+		signers)
 }
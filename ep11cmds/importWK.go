@@ -7,10 +7,18 @@
 //
 // Date          Initials        Description
 // 05/04/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
+// 10/18/2026    CLH             Stop waiting for signers when ctx ends
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/Logicalis/asn1"
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
@@ -19,22 +27,22 @@ import (
 /* Loads the new wrapping key register.                                       */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain whose new wrapping key register is    */
 /*    to be loaded.                                                           */
 /* [][]byte -- array of recipient info, one entry per key part                */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*    Only one signature is needed for this command.                          */
 /*                                                                            */
 /* Output:                                                                    */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func ImportWK(authToken string, urlStart string, de common.DomainEntry,
-	recipientInfo [][]byte, sigkeys []string, sigkeySkis []string,
-	sigkeyTokens []string) error {
+func ImportWKWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	recipientInfo [][]byte, signers []common.Signer) error {
 
 	// Create a concatenated set of signed xcpAdminReq, one for each
 	// key part.
@@ -43,7 +51,7 @@ func ImportWK(authToken string, urlStart string, de common.DomainEntry,
 	// Issue Query Domain Attributes to get the administrative domain,
 	// the module identifier, the transaction counter, and the
 	// signature thresholds
-	_, adminRspBlk, err := QueryDomainAttributes(authToken, urlStart, de)
+	_, adminRspBlk, err := QueryDomainAttributesWithContext(ctx, tr, de)
 	if err != nil {
 		return err
 	}
@@ -67,8 +75,8 @@ func ImportWK(authToken string, urlStart string, de common.DomainEntry,
 		}
 
 		// Sign the admin block
-		signerInfo, err := CreateSignerInfo(adminBlockSeq, sigkeys,
-			sigkeySkis, sigkeyTokens)
+		signerInfo, err := CreateSignerInfoWithContext(ctx, adminBlockSeq,
+			signers)
 		if err != nil {
 			return err
 		}
@@ -110,20 +118,48 @@ func ImportWK(authToken string, urlStart string, de common.DomainEntry,
 	xpNumRequest := NewXPNUMRequest(de.GetCryptoModuleIndex(),
 		de.GetDomainIndex(), bigAdminReqSeq)
 
-	xpNumReq := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, xpNumRequest)
+	return submitSignedCommand(ctx, tr, de, xpNumRequest)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ImportWKWithContext, using the background context                  */
+/*----------------------------------------------------------------------------*/
+func ImportWKWithTransport(tr common.Transport, de common.DomainEntry,
+	recipientInfo [][]byte, signers []common.Signer) error {
 
-	htpResponse, err := common.SubmitHTPRequest(xpNumReq)
-	if err != nil {
-		return err
-	}
+	return ImportWKWithContext(context.Background(), tr, de, recipientInfo,
+		signers)
+}
 
-	_, err = ImportWKResponse(htpResponse, de)
+/*----------------------------------------------------------------------------*/
+/* Loads the new wrapping key register.                                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain whose new wrapping key register is    */
+/*    to be loaded.                                                           */
+/* [][]byte -- array of recipient info, one entry per key part                */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*    Only one signature is needed for this command.                          */
+/*                                                                            */
+/* Output:                                                                    */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ImportWK(authToken string, urlStart string, de common.DomainEntry,
+	recipientInfo [][]byte, sigkeys []string, sigkeySkis []string,
+	sigkeyTokens []string) error {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer common.CloseSigners(signers)
+	return ImportWKWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, recipientInfo,
+		signers)
 }
 
 /*----------------------------------------------------------------------------*/
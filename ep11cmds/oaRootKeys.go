@@ -0,0 +1,37 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package ep11cmds
+
+import (
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Returns the coordinates of an OA root key attached to a transport using    */
+/* common.WithOARootKeys, or provided by the transport itself.                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* tr -- the transport used to read the OA certificate chain                  */
+/* ski -- subject key identifier of the root key to look for                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- x coordinate of the root public key                              */
+/* []byte -- y coordinate of the root public key                              */
+/* bool -- true if the root key was found                                     */
+/*----------------------------------------------------------------------------*/
+func findOARootKey(tr common.Transport, ski []byte) ([]byte, []byte, bool) {
+	for _, key := range common.GetOARootKeys(tr) {
+		if common.ByteSlicesAreEqual(key.SKI, ski) {
+			return key.X, key.Y, true
+		}
+	}
+	return nil, nil, false
+}
@@ -7,11 +7,16 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Answer from saved OA certificate chains
 
 package ep11cmds
 
 import (
+	"context"
 	"encoding/binary"
+	"strconv"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
@@ -20,8 +25,8 @@ import (
 /* Reads an OA certificate                                                    */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the crypto module and domain to be queried       */
 /* certificateIndex -- index into the certificate chain                       */
 /*    0 = currently active epoch key, 1 = its parent, etc.                    */
@@ -30,16 +35,20 @@ import (
 /* []byte -- the returned OA certificate                                      */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDeviceCertificate(authToken string, urlStart string,
+func QueryDeviceCertificateWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry, certificateIndex uint32) ([]byte, error) {
 
+	saved, ok := tr.(oaCertificateTransport)
+	if ok {
+		return saved.certificate(certificateIndex)
+	}
+
 	htpRequestString := QueryDeviceCertificateReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex(), certificateIndex)
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +61,84 @@ func QueryDeviceCertificate(authToken string, urlStart string,
 	return adminRspBlk.CmdOutput, nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryDeviceCertificateWithContext, using the background context    */
+/*----------------------------------------------------------------------------*/
+func QueryDeviceCertificateWithTransport(tr common.Transport,
+	de common.DomainEntry, certificateIndex uint32) ([]byte, error) {
+
+	return QueryDeviceCertificateWithContext(context.Background(), tr, de,
+		certificateIndex)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads an OA certificate                                                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the crypto module and domain to be queried       */
+/* certificateIndex -- index into the certificate chain                       */
+/*    0 = currently active epoch key, 1 = its parent, etc.                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the returned OA certificate                                      */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDeviceCertificate(authToken string, urlStart string,
+	de common.DomainEntry, certificateIndex uint32) ([]byte, error) {
+
+	return QueryDeviceCertificateWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, certificateIndex)
+}
+
+/** Transport with a saved OA certificate chain, see WithOACertificates */
+type oaCertificateTransport struct {
+	common.Transport
+	chain [][]byte
+}
+
+/** Keeps the OA root keys of the wrapped transport trusted */
+func (t oaCertificateTransport) GetOARootKeys() []common.OARootKey {
+	return common.GetOARootKeys(t.Transport)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a copy of a saved OA certificate, or the error a crypto module     */
+/* returns for a certificate index past the end of its chain                  */
+/*----------------------------------------------------------------------------*/
+func (t oaCertificateTransport) certificate(index uint32) ([]byte, error) {
+	if uint64(index) >= uint64(len(t.chain)) {
+		return nil, NewVerbError("OA certificate "+
+			strconv.FormatUint(uint64(index), 10)+" is not in the saved OA "+
+			"certificate chain.", 96, 60)
+	}
+	return append([]byte(nil), t.chain[index]...), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Attaches a saved OA certificate chain to a transport.                      */
+/*                                                                            */
+/* QueryDeviceCertificateWithContext returns the saved certificates for       */
+/* requests sent using the returned transport instead of reading them from    */
+/* a crypto module, so VerifyOA3CertificateWithContext and the other OA       */
+/* certificate chain checks can verify a chain saved earlier, such as the     */
+/* chain recorded in a master key escrow.  The chain still has to end with a  */
+/* certificate signed by an IBM root key or a root key attached to tr.        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* tr -- the transport whose OA root keys are trusted                         */
+/* chain -- the OA certificates, 0 = epoch certificate, 1 = its parent, etc.  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* common.Transport -- the transport with the saved chain attached            */
+/*----------------------------------------------------------------------------*/
+func WithOACertificates(tr common.Transport,
+	chain [][]byte) common.Transport {
+
+	return oaCertificateTransport{Transport: tr, chain: chain}
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest to return a specific OA certificate                 */
 /*                                                                            */
@@ -82,24 +169,23 @@ func QueryDeviceCertificateReq(cryptoModuleIndex int, domainIndex int,
 /* Returns the number of OA certificates in the OA certificate chain          */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the crypto module and domain to be queried       */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* uint32 -- the number of certificates in the OA certificate chain           */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryNumberDeviceCertificates(authToken string, urlStart string,
+func QueryNumberDeviceCertificatesWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) (uint32, error) {
 
 	htpRequestString := QueryNumberDeviceCertificatesReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex())
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		return 0, err
 	}
@@ -112,6 +198,36 @@ func QueryNumberDeviceCertificates(authToken string, urlStart string,
 	return binary.BigEndian.Uint32(adminRspBlk.CmdOutput), nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryNumberDeviceCertificatesWithContext, using the                */
+/* background context                                                         */
+/*----------------------------------------------------------------------------*/
+func QueryNumberDeviceCertificatesWithTransport(tr common.Transport,
+	de common.DomainEntry) (uint32, error) {
+
+	return QueryNumberDeviceCertificatesWithContext(context.Background(), tr,
+		de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the number of OA certificates in the OA certificate chain          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the crypto module and domain to be queried       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* uint32 -- the number of certificates in the OA certificate chain           */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryNumberDeviceCertificates(authToken string, urlStart string,
+	de common.DomainEntry) (uint32, error) {
+
+	return QueryNumberDeviceCertificatesWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest to return the number of OA certificates in the OA   */
 /* certificate chain                                                          */
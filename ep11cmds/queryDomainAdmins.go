@@ -7,10 +7,13 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"strings"
@@ -23,8 +26,8 @@ import (
 /* Queries the domain administrators.                                         */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain to be queried                         */
 /*                                                                            */
 /* Outputs:                                                                   */
@@ -32,16 +35,15 @@ import (
 /*    administrator installed in the domain                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDomainAdmins(authToken string, urlStart string,
+func QueryDomainAdminsWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) ([][]byte, error) {
 
 	htpRequestString := QueryDomainAdminsReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex(), nil)
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString) //@TxxxxxxCLH
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString) //@TxxxxxxCLH
 	if err != nil {
 		return nil, err
 	}
@@ -51,28 +53,56 @@ func QueryDomainAdmins(authToken string, urlStart string,
 }
 
 /*----------------------------------------------------------------------------*/
-/* Retrieves the name of a domain administrator.                              */
+/* Same as QueryDomainAdminsWithContext, using the background context         */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAdminsWithTransport(tr common.Transport,
+	de common.DomainEntry) ([][]byte, error) {
+
+	return QueryDomainAdminsWithContext(context.Background(), tr, de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Queries the domain administrators.                                         */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* authToken -- the authority token to use for the request                    */
 /* urlStart -- the base URL to use for the request                            */
 /* DomainEntry -- identifies the domain to be queried                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- array of Subject Key Identifiers (SKIs), one for each          */
+/*    administrator installed in the domain                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAdmins(authToken string, urlStart string,
+	de common.DomainEntry) ([][]byte, error) {
+
+	return QueryDomainAdminsWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Retrieves the name of a domain administrator.                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain to be queried                         */
 /* []byte -- Subject Key Identifier of the domain administrator of interest   */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* string -- name of the domain administrator                                 */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDomainAdminName(authToken string, urlStart string,
+func QueryDomainAdminNameWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry, ski []byte) (string, error) {
 
 	htpRequestString := QueryDomainAdminsReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex(), ski)
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		return "", err
 	}
@@ -81,6 +111,35 @@ func QueryDomainAdminName(authToken string, urlStart string,
 	return strings.TrimSpace(string(cert.TheBody.TheIssuer.CommonName.TheName.PrintableString)), nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryDomainAdminNameWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAdminNameWithTransport(tr common.Transport,
+	de common.DomainEntry, ski []byte) (string, error) {
+
+	return QueryDomainAdminNameWithContext(context.Background(), tr, de, ski)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Retrieves the name of a domain administrator.                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain to be queried                         */
+/* []byte -- Subject Key Identifier of the domain administrator of interest   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- name of the domain administrator                                 */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAdminName(authToken string, urlStart string,
+	de common.DomainEntry, ski []byte) (string, error) {
+
+	return QueryDomainAdminNameWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, ski)
+}
+
 /**
 Create a query domain administrators request.  The aSKI parameter may contain the
 SKI for an administrator or be nil.
@@ -7,10 +7,13 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"encoding/binary"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
@@ -43,8 +46,8 @@ const XCP_ADMINT_STD = 5
 /* Queries the domain attributes                                              */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain to be queried                         */
 /*                                                                            */
 /* Outputs:                                                                   */
@@ -55,7 +58,8 @@ const XCP_ADMINT_STD = 5
 /*    that should be used for the signed command.                             */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDomainAttributes(authToken string, urlStart string,
+func QueryDomainAttributesWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) (DomainAttributes, AdminRspBlk, error) {
 
 	var domainAttributes DomainAttributes
@@ -64,10 +68,8 @@ func QueryDomainAttributes(authToken string, urlStart string,
 	htpRequestString := QueryDomainAttributesReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex())
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		return domainAttributes, adminRspBlk, err
 	}
@@ -110,6 +112,38 @@ func QueryDomainAttributes(authToken string, urlStart string,
 	return domainAttributes, adminRspBlk, nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryDomainAttributesWithContext, using the background context     */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAttributesWithTransport(tr common.Transport,
+	de common.DomainEntry) (DomainAttributes, AdminRspBlk, error) {
+
+	return QueryDomainAttributesWithContext(context.Background(), tr, de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Queries the domain attributes                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain to be queried                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* DomainAttributes -- structure with the domain attributes                   */
+/* AdminRspBlk -- the xcpAdminRspBlk from the query.  The Query Domain        */
+/*    Attributes command is issued before each signed command to determine    */
+/*    the administrative domain,  module ID, and transaction counter fields   */
+/*    that should be used for the signed command.                             */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDomainAttributes(authToken string, urlStart string,
+	de common.DomainEntry) (DomainAttributes, AdminRspBlk, error) {
+
+	return QueryDomainAttributesWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for querying domain attributes                      */
 /*----------------------------------------------------------------------------*/
@@ -7,10 +7,14 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
@@ -18,24 +22,23 @@ import (
 /* Queries the domain control points                                          */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain to be queried                         */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* []byte -- the domain control points (16 bytes long)                        */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDomainControlPoints(authToken string, urlStart string,
+func QueryDomainControlPointsWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) ([]byte, error) {
 
 	htpRequestString := QueryDomainControlPointsReq(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex())
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +51,34 @@ func QueryDomainControlPoints(authToken string, urlStart string,
 	return adminRspBlk.CmdOutput, nil
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryDomainControlPointsWithContext, using the background context  */
+/*----------------------------------------------------------------------------*/
+func QueryDomainControlPointsWithTransport(tr common.Transport,
+	de common.DomainEntry) ([]byte, error) {
+
+	return QueryDomainControlPointsWithContext(context.Background(), tr, de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Queries the domain control points                                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain to be queried                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the domain control points (16 bytes long)                        */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDomainControlPoints(authToken string, urlStart string,
+	de common.DomainEntry) ([]byte, error) {
+
+	return QueryDomainControlPointsWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for querying domain control points                  */
 /*----------------------------------------------------------------------------*/
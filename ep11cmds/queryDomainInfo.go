@@ -7,10 +7,13 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"errors"
 
 	"github.com/Logicalis/asn1"
@@ -33,8 +36,8 @@ type DomainInfoRspInfo struct {
 /* Queries the domain master key register status and verification pattern     */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain to be queried                         */
 /*                                                                            */
 /* Outputs:                                                                   */
@@ -42,16 +45,15 @@ type DomainInfoRspInfo struct {
 /*    new and current master key registers                                    */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryDomainInfo(authToken string, urlStart string,
+func QueryDomainInfoWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) (DomainInfoRspInfo, error) {
 
 	htpRequestString := QueryDomainInfoRequest(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex())
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		var dummy DomainInfoRspInfo
 		return dummy, err
@@ -60,6 +62,35 @@ func QueryDomainInfo(authToken string, urlStart string,
 	return QueryDomainInfoRsp(htpResponseString)
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryDomainInfoWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func QueryDomainInfoWithTransport(tr common.Transport,
+	de common.DomainEntry) (DomainInfoRspInfo, error) {
+
+	return QueryDomainInfoWithContext(context.Background(), tr, de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Queries the domain master key register status and verification pattern     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain to be queried                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* DomainInfoRspInfo -- contains the status and verification patterns of the  */
+/*    new and current master key registers                                    */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryDomainInfo(authToken string, urlStart string,
+	de common.DomainEntry) (DomainInfoRspInfo, error) {
+
+	return QueryDomainInfoWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
 func QueryDomainInfoRequest(cryptoModuleIndex int, domainIndex int) string {
 
 	var req XCPReq
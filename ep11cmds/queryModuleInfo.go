@@ -7,10 +7,13 @@
 //
 // Date          Initials        Description
 // 04/07/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"errors"
 
 	"github.com/Logicalis/asn1"
@@ -37,24 +40,23 @@ type ModuleInfoRspInfo struct {
 /* Issues get_xcp_info to retrieve module information                         */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the crypto module and domain to be queried       */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* ModuleInfoRspInfo -- returned data from the query                          */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func QueryModuleInfo(authToken string, urlStart string,
+func QueryModuleInfoWithContext(ctx context.Context,
+	tr common.Transport,
 	de common.DomainEntry) (ModuleInfoRspInfo, error) {
 
 	htpRequestString := QueryModuleInfoRequest(
 		de.GetCryptoModuleIndex(), de.GetDomainIndex())
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
+	htpResponseString, err := submitQueryHTPRequest(ctx, tr,
+		de.Crypto_instance_id, de.Hsm_id, htpRequestString)
 	if err != nil {
 		var dummy ModuleInfoRspInfo
 		return dummy, err
@@ -62,6 +64,34 @@ func QueryModuleInfo(authToken string, urlStart string,
 	return QueryModuleInfoRsp(htpResponseString)
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryModuleInfoWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func QueryModuleInfoWithTransport(tr common.Transport,
+	de common.DomainEntry) (ModuleInfoRspInfo, error) {
+
+	return QueryModuleInfoWithContext(context.Background(), tr, de)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Issues get_xcp_info to retrieve module information                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the crypto module and domain to be queried       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* ModuleInfoRspInfo -- returned data from the query                          */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func QueryModuleInfo(authToken string, urlStart string,
+	de common.DomainEntry) (ModuleInfoRspInfo, error) {
+
+	return QueryModuleInfoWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for get_xcp_info with a request for module          */
 /* information                                                                */
@@ -7,11 +7,22 @@
 //
 // Date          Initials        Description
 // 05/27/2020    CLH             T390301 - Add minimal touch functions
+// 10/18/2026    CLH             Encrypt and decrypt key parts
+// 10/18/2026    CLH             Separate the KDF from the shared info
+// 10/18/2026    CLH             Add EncryptKeyPartP521ECWithUKM
+// 10/18/2026    CLH             Add ReencryptKeyPartP521EC
+// 10/18/2026    CLH             Say where ReencryptKeyPartP521EC may be used
 
 package ep11cmds
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"errors"
+	"io"
+	"math/big"
 	"strconv"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
@@ -162,3 +173,256 @@ func CreateRecipientInfoP521EC(publicKey []byte, ukm []byte, ski []byte,
 	finalResult = append(finalResult, common.Asn1FormSequence(recipientInfoFields)...)
 	return finalResult, nil
 }
+
+/*----------------------------------------------------------------------------*/
+/* Extracts the fields from a RecipientInfo created for a P521 EC importer    */
+/* key.  See the sample RecipientInfo above for the layout.                   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte recipientInfo -- ASN.1 sequence for the RecipientInfo               */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the P521 EC public key of the originator (133 bytes)             */
+/* []byte -- user key material (40 bytes)                                     */
+/* []byte -- subject key identifier of the importer key (32 bytes)            */
+/* []byte -- the encrypted key part (40 bytes)                                */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func ParseRecipientInfoP521EC(recipientInfo []byte) ([]byte, []byte, []byte,
+	[]byte, error) {
+
+	if len(recipientInfo) != 313 ||
+		recipientInfo[0] != common.ASN1_SEQUENCE_TAG ||
+		recipientInfo[7] != A0_TAG ||
+		recipientInfo[10] != A1_TAG ||
+		recipientInfo[25] != common.ASN1_BIT_STRING_TAG ||
+		recipientInfo[162] != A1_TAG ||
+		recipientInfo[164] != common.ASN1_OCTET_STRING_TAG ||
+		recipientInfo[235] != A0_TAG ||
+		recipientInfo[237] != common.ASN1_OCTET_STRING_TAG ||
+		recipientInfo[271] != common.ASN1_OCTET_STRING_TAG {
+		return nil, nil, nil, nil, errors.New(
+			"Invalid recipient info for P521 EC importer key, length = " +
+				strconv.Itoa(len(recipientInfo)) + ".")
+	}
+	if !common.ByteSlicesAreEqual(recipientInfo[16:25], OID_ecPublicKey) ||
+		!common.ByteSlicesAreEqual(recipientInfo[208:216], OID_stdDH_sha256kdf) ||
+		!common.ByteSlicesAreEqual(recipientInfo[218:229], OID_aes256_wrap) {
+		return nil, nil, nil, nil, errors.New(
+			"Unsupported algorithm in recipient info for P521 EC importer key.")
+	}
+	return recipientInfo[29:162], recipientInfo[166:206],
+		recipientInfo[239:271], recipientInfo[273:313], nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encrypts a key part for import using a P521 EC importer key.               */
+/*                                                                            */
+/* An ephemeral P521 EC key is generated to act as the originator key.  The   */
+/* key encrypting key is derived from the ECDH shared secret and random user  */
+/* key material using the stdDH-sha256kdf scheme, and is used to wrap the key */
+/* part with aes256-wrap.                                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ecdsa.PublicKey importerKey -- public part of the P521 EC importer key     */
+/* []byte keyPart -- the 32-byte AES key part to be encrypted                 */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- ASN.1 sequence for the RecipientInfo                             */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func EncryptKeyPartP521EC(importerKey ecdsa.PublicKey, keyPart []byte) ([]byte, error) {
+
+	ukm := make([]byte, 40)
+	_, err := io.ReadFull(rand.Reader, ukm)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKeyPartP521ECWithUKM(importerKey, keyPart, ukm)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as EncryptKeyPartP521EC, using the given 40 bytes of user key         */
+/* material instead of random bytes.  The user key material is not secret,    */
+/* and is returned by ParseRecipientInfoP521EC.                               */
+/*----------------------------------------------------------------------------*/
+func EncryptKeyPartP521ECWithUKM(importerKey ecdsa.PublicKey, keyPart []byte,
+	ukm []byte) ([]byte, error) {
+
+	if len(keyPart) != 32 {
+		return nil, errors.New(
+			"Error encrypting key part.  Invalid key part length, length = " +
+				strconv.Itoa(len(keyPart)) + ".")
+	}
+
+	originatorKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedX, _ := elliptic.P521().ScalarMult(
+		importerKey.X, importerKey.Y, originatorKey.D.Bytes())
+	kek := deriveKeyEncryptingKey(sharedX, ukm)
+	encryptedKeyPart, err := common.AESKeyWrap(kek, keyPart)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateRecipientInfoP521EC(
+		p521PublicKeyBytes(originatorKey.PublicKey), ukm,
+		common.CalculateECKeyHash(importerKey), encryptedKeyPart)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decrypts a key part from a RecipientInfo using a P521 EC importer key.     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte recipientInfo -- ASN.1 sequence for the RecipientInfo               */
+/* *ecdsa.PrivateKey importerKey -- the P521 EC importer key                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the decrypted key part                                           */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func DecryptKeyPartP521EC(recipientInfo []byte,
+	importerKey *ecdsa.PrivateKey) ([]byte, error) {
+
+	publicKey, ukm, ski, encryptedKeyPart, err :=
+		ParseRecipientInfoP521EC(recipientInfo)
+	if err != nil {
+		return nil, err
+	}
+	if !common.ByteSlicesAreEqual(ski,
+		common.CalculateECKeyHash(importerKey.PublicKey)) {
+		return nil, errors.New(
+			"Recipient info was not created for this importer key.")
+	}
+
+	var x, y big.Int
+	x.SetBytes(publicKey[1:67])
+	y.SetBytes(publicKey[67:133])
+	if publicKey[0] != 0x04 || !elliptic.P521().IsOnCurve(&x, &y) {
+		return nil, errors.New(
+			"Invalid originator public key in recipient info.")
+	}
+
+	sharedX, _ := elliptic.P521().ScalarMult(&x, &y, importerKey.D.Bytes())
+	kek := deriveKeyEncryptingKey(sharedX, ukm)
+	return common.AESKeyUnwrap(kek, encryptedKeyPart)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encrypts a key part exported from a crypto module under another P521 EC    */
+/* key, as a key part holder does before the key part is imported.  The key   */
+/* part is decrypted with the key of the key part holder and encrypted under  */
+/* the importer key, keeping the user key material of the original            */
+/* RecipientInfo.  The key part and its user key material are passed on       */
+/* unchanged, so the crypto module that imports it can combine it with other  */
+/* key parts in its own format.  The decrypted key part is cleared before the */
+/* function returns.                                                          */
+/*                                                                            */
+/* The key part is in the clear in this process while it is encrypted again,  */
+/* so call this only where the key of the key part holder is kept, with one   */
+/* key part at a time.  When tkesdk copies a master key between crypto units  */
+/* it exports the key parts directly under the target importer key instead.   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte recipientInfo -- the RecipientInfo of the exported key part         */
+/* *ecdsa.PrivateKey holderKey -- the P521 EC key the key part was exported   */
+/*    under                                                                   */
+/* ecdsa.PublicKey importerKey -- public part of the P521 EC importer key     */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- ASN.1 sequence for the new RecipientInfo                         */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func ReencryptKeyPartP521EC(recipientInfo []byte, holderKey *ecdsa.PrivateKey,
+	importerKey ecdsa.PublicKey) ([]byte, error) {
+
+	_, ukm, _, _, err := ParseRecipientInfoP521EC(recipientInfo)
+	if err != nil {
+		return nil, err
+	}
+	keyPart, err := DecryptKeyPartP521EC(recipientInfo, holderKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range keyPart {
+			keyPart[i] = 0
+		}
+	}()
+	return EncryptKeyPartP521ECWithUKM(importerKey, keyPart, ukm)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Derives the key encrypting key for a RecipientInfo using the               */
+/* stdDH-sha256kdf scheme.                                                    */
+/*                                                                            */
+/* The ANSI X9.63 key derivation function is applied to the ECDH shared       */
+/* secret, using the DER encoding of ECC-CMS-SharedInfo (RFC 5753) as the     */
+/* shared information.                                                        */
+/*----------------------------------------------------------------------------*/
+func deriveKeyEncryptingKey(sharedX *big.Int, ukm []byte) []byte {
+
+	// The shared secret is the x coordinate padded to 66 bytes
+	z := make([]byte, 66)
+	xbytes := sharedX.Bytes()
+	copy(z[66-len(xbytes):], xbytes)
+
+	return x963KDF(z, eccCMSSharedInfo(ukm))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the DER encoding of ECC-CMS-SharedInfo (RFC 5753, section 7.2) for */
+/* an aes256-wrap key encrypting key:                                         */
+/*                                                                            */
+/* SEQUENCE {                                                                 */
+/*   keyInfo      AlgorithmIdentifier (aes256-wrap, NULL),                    */
+/*   entityUInfo  [0] EXPLICIT OCTET STRING (ukm),                            */
+/*   suppPubInfo  [2] EXPLICIT OCTET STRING (key length in bits, 256) }       */
+/*----------------------------------------------------------------------------*/
+func eccCMSSharedInfo(ukm []byte) []byte {
+	keyInfoFields := make([][]byte, 2)
+	keyInfoFields[0] = OID_aes256_wrap
+	keyInfoFields[1] = ASN1_NULL
+
+	entityUInfo := common.Asn1FormOctetString(common.Asn1FormOctetString(ukm))
+	entityUInfo[0] = A0_TAG
+
+	suppPubInfo := common.Asn1FormOctetString(
+		common.Asn1FormOctetString(common.Uint32To4ByteSlice(256)))
+	suppPubInfo[0] = 0xA2
+
+	sharedInfoFields := make([][]byte, 3)
+	sharedInfoFields[0] = common.Asn1FormSequence(keyInfoFields)
+	sharedInfoFields[1] = entityUInfo
+	sharedInfoFields[2] = suppPubInfo
+	return common.Asn1FormSequence(sharedInfoFields)
+}
+
+/*----------------------------------------------------------------------------*/
+/* The ANSI X9.63 key derivation function using SHA-256, for a 256-bit key.   */
+/* A single hash is enough:                                                   */
+/* SHA_256( Z || 00000001 || SharedInfo )                                     */
+/*----------------------------------------------------------------------------*/
+func x963KDF(z []byte, sharedInfo []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write(z)
+	hasher.Write(common.Uint32To4ByteSlice(1))
+	hasher.Write(sharedInfo)
+	return hasher.Sum(nil)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the uncompressed form of a P521 EC public key (133 bytes)          */
+/*----------------------------------------------------------------------------*/
+func p521PublicKeyBytes(pubKey ecdsa.PublicKey) []byte {
+	publicBytes := make([]byte, 133)
+	publicBytes[0] = 0x04
+	xbytes := pubKey.X.Bytes()
+	copy(publicBytes[1+66-len(xbytes):67], xbytes)
+	ybytes := pubKey.Y.Bytes()
+	copy(publicBytes[67+66-len(ybytes):133], ybytes)
+	return publicBytes
+}
@@ -0,0 +1,209 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test EncryptKeyPartP521ECWithUKM
+// 10/18/2026    CLH             Test ReencryptKeyPartP521EC
+
+package ep11cmds
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+/*----------------------------------------------------------------------------*/
+/* Known answers for the ANSI X9.63 KDF with SHA-256, from the NIST CAVS      */
+/* ansx963_2001.rsp test file.  x963KDF returns the first 32 bytes of the key */
+/* data, so longer key data is compared up to 32 bytes.                       */
+/*----------------------------------------------------------------------------*/
+func TestX963KDFVectors(t *testing.T) {
+	vectors := []struct {
+		z          string
+		sharedInfo string
+		keyData    string
+	}{
+		{"96c05619d56c328ab95fe84b18264b08725b85e33fd34f08", "",
+			"443024c3dae66b95e6f5670601558f71"},
+		{"22518b10e70f2a3f243810ae3254139efbee04aa57c7af7d",
+			"75eef81aa3041e33b80971203d2c0c52",
+			"c498af77161cc59f2962b9a713e2b215152d139766ce34a776df11866a69bf2e"},
+	}
+	for i, v := range vectors {
+		expected := mustDecodeHex(t, v.keyData)
+		key := x963KDF(mustDecodeHex(t, v.z), mustDecodeHex(t, v.sharedInfo))
+		if len(key) != 32 || !bytes.Equal(key[:len(expected)], expected) {
+			t.Errorf("Vector %d: key data is %x, expected %x", i, key,
+				expected)
+		}
+	}
+}
+
+/** ECC-CMS-SharedInfo matches a DER encoding written out from RFC 5753 */
+func TestECCCMSSharedInfo(t *testing.T) {
+	ukm := bytes.Repeat([]byte{0x11}, 40)
+	expected := mustDecodeHex(t, "3043"+
+		"300D 0609 60864801650304012D 0500"+ // aes256-wrap, NULL
+		"A02A 0428 "+strings.Repeat("11", 40)+ // [0] entityUInfo
+		"A206 0404 00000100") // [2] suppPubInfo, 256 bits
+	sharedInfo := eccCMSSharedInfo(ukm)
+	if !bytes.Equal(sharedInfo, expected) {
+		t.Errorf("Shared info is %X, expected %X", sharedInfo, expected)
+	}
+}
+
+/** Key parts encrypted for an importer key decrypt only with that key */
+func TestEncryptKeyPartP521EC(t *testing.T) {
+	importerKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPart := make([]byte, 32)
+	rand.Read(keyPart)
+
+	recipientInfo, err := EncryptKeyPartP521EC(importerKey.PublicKey, keyPart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipientInfo) != 313 {
+		t.Fatalf("RecipientInfo is %d bytes, expected 313",
+			len(recipientInfo))
+	}
+	decrypted, err := DecryptKeyPartP521EC(recipientInfo, importerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, keyPart) {
+		t.Error("Decrypted key part differs from the original")
+	}
+
+	if _, err = DecryptKeyPartP521EC(recipientInfo, otherKey); err == nil {
+		t.Error("Key part decrypted with a different importer key")
+	}
+	changed := append([]byte{}, recipientInfo...)
+	changed[180] ^= 0x01 // user key material
+	if _, err = DecryptKeyPartP521EC(changed, importerKey); err == nil {
+		t.Error("Key part decrypted with changed user key material")
+	}
+	if _, err = EncryptKeyPartP521EC(importerKey.PublicKey,
+		keyPart[:16]); err == nil {
+		t.Error("A 16-byte key part was encrypted")
+	}
+}
+
+/** ParseRecipientInfoP521EC returns the fields CreateRecipientInfoP521EC set */
+func TestRecipientInfoLayout(t *testing.T) {
+	publicKey := append([]byte{0x04}, bytes.Repeat([]byte{0x22}, 132)...)
+	ukm := bytes.Repeat([]byte{0x33}, 40)
+	ski := bytes.Repeat([]byte{0x44}, 32)
+	encrypted := bytes.Repeat([]byte{0x55}, 40)
+	recipientInfo, err := CreateRecipientInfoP521EC(publicKey, ukm, ski,
+		encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, u, s, e, err := ParseRecipientInfoP521EC(recipientInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, publicKey) || !bytes.Equal(u, ukm) ||
+		!bytes.Equal(s, ski) || !bytes.Equal(e, encrypted) {
+		t.Error("Parsed fields differ from the fields of the RecipientInfo")
+	}
+	if _, _, _, _, err = ParseRecipientInfoP521EC(
+		recipientInfo[:312]); err == nil {
+		t.Error("A truncated RecipientInfo was parsed")
+	}
+}
+
+/** The user key material given is used and returned when parsing */
+func TestEncryptKeyPartP521ECWithUKM(t *testing.T) {
+	importerKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPart := bytes.Repeat([]byte{0x66}, 32)
+	ukm := bytes.Repeat([]byte{0x77}, 40)
+	recipientInfo, err := EncryptKeyPartP521ECWithUKM(importerKey.PublicKey,
+		keyPart, ukm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, parsedUKM, _, _, err := ParseRecipientInfoP521EC(recipientInfo)
+	if err != nil || !bytes.Equal(parsedUKM, ukm) {
+		t.Errorf("Parsed user key material %X, %v", parsedUKM, err)
+	}
+	decrypted, err := DecryptKeyPartP521EC(recipientInfo, importerKey)
+	if err != nil || !bytes.Equal(decrypted, keyPart) {
+		t.Errorf("Decrypted key part %X, %v", decrypted, err)
+	}
+	if _, err = EncryptKeyPartP521ECWithUKM(importerKey.PublicKey, keyPart,
+		ukm[:32]); err == nil {
+		t.Error("32 bytes of user key material were accepted")
+	}
+}
+
+/** Reencrypted key parts keep their value and user key material */
+func TestReencryptKeyPartP521EC(t *testing.T) {
+	holderKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importerKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPart := bytes.Repeat([]byte{0x88}, 32)
+	ukm := bytes.Repeat([]byte{0x99}, 40)
+	exported, err := EncryptKeyPartP521ECWithUKM(holderKey.PublicKey,
+		keyPart, ukm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reencrypted, err := ReencryptKeyPartP521EC(exported, holderKey,
+		importerKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, parsedUKM, ski, _, err := ParseRecipientInfoP521EC(reencrypted)
+	if err != nil || !bytes.Equal(parsedUKM, ukm) {
+		t.Errorf("Parsed user key material %X, %v", parsedUKM, err)
+	}
+	if !bytes.Equal(ski, common.CalculateECKeyHash(importerKey.PublicKey)) {
+		t.Error("The key part was not encrypted under the importer key")
+	}
+	decrypted, err := DecryptKeyPartP521EC(reencrypted, importerKey)
+	if err != nil || !bytes.Equal(decrypted, keyPart) {
+		t.Errorf("Decrypted key part %X, %v", decrypted, err)
+	}
+
+	if _, err = ReencryptKeyPartP521EC(exported, importerKey,
+		importerKey.PublicKey); err == nil {
+		t.Error("A key part was decrypted with the wrong key")
+	}
+}
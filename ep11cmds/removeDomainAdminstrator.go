@@ -7,10 +7,16 @@
 //
 // Date          Initials        Description
 // 04/29/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
 	"encoding/hex"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
@@ -20,20 +26,20 @@ import (
 /* Removes an administrator                                                   */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies the domain with the administrator to be removed  */
 /* string -- the Subject Key Identifier of the administator to be removed     */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func RemoveDomainAdministrator(authToken string, urlStart string,
-	de common.DomainEntry, ski string, sigkeys []string,
-	sigkeySkis []string, sigkeyTokens []string) error {
+func RemoveDomainAdministratorWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, ski string, signers []common.Signer) error {
 
 	// Convert from hexadecimal string to []byte
 	skibytes, err := hex.DecodeString(ski)
@@ -41,39 +47,90 @@ func RemoveDomainAdministrator(authToken string, urlStart string,
 		return err
 	}
 
-	htpRequestString, err := RemoveDomainAdminReq(authToken, urlStart, de,
-		skibytes, sigkeys, sigkeySkis, sigkeyTokens)
+	htpRequestString, err := RemoveDomainAdminReqWithContext(ctx, tr, de,
+		skibytes, signers)
 	if err != nil {
 		return err
 	}
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
+/*----------------------------------------------------------------------------*/
+/* Same as RemoveDomainAdministratorWithContext, using the background context */
+/*----------------------------------------------------------------------------*/
+func RemoveDomainAdministratorWithTransport(tr common.Transport,
+	de common.DomainEntry, ski string, signers []common.Signer) error {
 
-	_, err = buildAdminRspBlk(htpResponseString, de)
+	return RemoveDomainAdministratorWithContext(context.Background(), tr, de,
+		ski, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Removes an administrator                                                   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies the domain with the administrator to be removed  */
+/* string -- the Subject Key Identifier of the administator to be removed     */
+/* []string -- identifies the signature keys to use to sign the command       */
+/* []string -- the Subject Key Identifiers for the signature keys             */
+/* []string -- authentication tokens for the signature keys                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func RemoveDomainAdministrator(authToken string, urlStart string,
+	de common.DomainEntry, ski string, sigkeys []string,
+	sigkeySkis []string, sigkeyTokens []string) error {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer common.CloseSigners(signers)
+	return RemoveDomainAdministratorWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, ski, signers)
 }
 
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for removing a domain administrator                 */
 /*----------------------------------------------------------------------------*/
-func RemoveDomainAdminReq(authToken string, urlStart string,
-	de common.DomainEntry, ski []byte, sigkeys []string,
-	sigkeySkis []string, sigkeyTokens []string) (string, error) {
+func RemoveDomainAdminReqWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, ski []byte, signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_DOM_ADMIN_LOGOUT
 	// DomainID, ModuleID, and TransactionCounter get filled in later when sending the request
 	adminBlk.CmdInput = ski
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as RemoveDomainAdminReqWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func RemoveDomainAdminReqWithTransport(tr common.Transport,
+	de common.DomainEntry, ski []byte, signers []common.Signer) (string,
+	error) {
+
+	return RemoveDomainAdminReqWithContext(context.Background(), tr, de, ski,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for removing a domain administrator                 */
+/*----------------------------------------------------------------------------*/
+func RemoveDomainAdminReq(authToken string, urlStart string,
+	de common.DomainEntry, ski []byte, sigkeys []string,
+	sigkeySkis []string, sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return RemoveDomainAdminReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, ski, signers)
 }
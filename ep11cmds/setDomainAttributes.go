@@ -7,13 +7,61 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Add SetDomainAttributesCmdInput
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Sets the domain attributes                                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain whose attributes are to be set        */
+/* DomainAttributes -- new set of attributes to be loaded in the domain       */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesWithContext(ctx context.Context,
+	tr common.Transport,
+	de common.DomainEntry, newAttributes DomainAttributes,
+	signers []common.Signer) error {
+
+	htpRequestString, err := SetDomainAttributesReqWithContext(
+		ctx, tr, de, newAttributes, signers)
+	if err != nil {
+		return err
+	}
+
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SetDomainAttributesWithContext, using the background context       */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesWithTransport(tr common.Transport,
+	de common.DomainEntry, newAttributes DomainAttributes,
+	signers []common.Signer) error {
+
+	return SetDomainAttributesWithContext(context.Background(), tr, de,
+		newAttributes, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Sets the domain attributes                                                 */
 /*                                                                            */
@@ -33,27 +81,60 @@ func SetDomainAttributes(authToken string, urlStart string,
 	de common.DomainEntry, newAttributes DomainAttributes,
 	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
 
-	htpRequestString, err := SetDomainAttributesReq(
-		authToken, urlStart, de, newAttributes, sigkeys, sigkeySkis,
-		sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
+	defer common.CloseSigners(signers)
+	return SetDomainAttributesWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, newAttributes,
+		signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for setting the domain attributes                   */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	newAttributes DomainAttributes, signers []common.Signer) (string, error) {
 
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
+	var adminBlk AdminBlk
+	adminBlk.CmdID = XCP_ADM_DOM_SET_ATTR
+	// administrative domain filled in later
+	// module ID filled in later
+	// transaction counter filled in later
+	adminBlk.CmdInput = SetDomainAttributesCmdInput(newAttributes)
 
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
 
-	_, err = buildAdminRspBlk(htpResponseString, de)
-	if err != nil {
-		return err
-	}
+/*----------------------------------------------------------------------------*/
+/* Assembles the command input for setting the domain attributes.  The        */
+/* signature threshold, revocation signature threshold, permissions, and      */
+/* operational mode are all set.                                              */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesCmdInput(newAttributes DomainAttributes) []byte {
+	cmdInput := make([]byte, 4*8)
+	copy(cmdInput[0:4], []byte{0x00, 0x00, 0x00, 0x01})
+	copy(cmdInput[4:8], common.Uint32To4ByteSlice(newAttributes.SignatureThreshold))
+	copy(cmdInput[8:12], []byte{0x00, 0x00, 0x00, 0x02})
+	copy(cmdInput[12:16], common.Uint32To4ByteSlice(newAttributes.RevocationSignatureThreshold))
+	copy(cmdInput[16:20], []byte{0x00, 0x00, 0x00, 0x03})
+	copy(cmdInput[20:24], common.Uint32To4ByteSlice(newAttributes.Permissions))
+	copy(cmdInput[24:28], []byte{0x00, 0x00, 0x00, 0x04})
+	copy(cmdInput[28:32], common.Uint32To4ByteSlice(newAttributes.OperationalMode))
+	return cmdInput
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SetDomainAttributesReqWithContext, using the background context    */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesReqWithTransport(tr common.Transport,
+	de common.DomainEntry, newAttributes DomainAttributes,
+	signers []common.Signer) (string, error) {
 
-	return nil
+	return SetDomainAttributesReqWithContext(context.Background(), tr, de,
+		newAttributes, signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -63,22 +144,12 @@ func SetDomainAttributesReq(authToken string, urlStart string, de common.DomainE
 	newAttributes DomainAttributes, sigkeys []string, sigkeySkis []string,
 	sigkeyTokens []string) (string, error) {
 
-	var adminBlk AdminBlk
-	adminBlk.CmdID = XCP_ADM_DOM_SET_ATTR
-	// administrative domain filled in later
-	// module ID filled in later
-	// transaction counter filled in later
-	// assemble the payload
-	adminBlk.CmdInput = make([]byte, 4*8)
-	copy(adminBlk.CmdInput[0:4], []byte{0x00, 0x00, 0x00, 0x01})
-	copy(adminBlk.CmdInput[4:8], common.Uint32To4ByteSlice(newAttributes.SignatureThreshold))
-	copy(adminBlk.CmdInput[8:12], []byte{0x00, 0x00, 0x00, 0x02})
-	copy(adminBlk.CmdInput[12:16], common.Uint32To4ByteSlice(newAttributes.RevocationSignatureThreshold))
-	copy(adminBlk.CmdInput[16:20], []byte{0x00, 0x00, 0x00, 0x03})
-	copy(adminBlk.CmdInput[20:24], common.Uint32To4ByteSlice(newAttributes.Permissions))
-	copy(adminBlk.CmdInput[24:28], []byte{0x00, 0x00, 0x00, 0x04})
-	copy(adminBlk.CmdInput[28:32], common.Uint32To4ByteSlice(newAttributes.OperationalMode))
-
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return SetDomainAttributesReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, newAttributes,
+		signers)
 }
@@ -8,15 +8,21 @@
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
 // 11/11/2022    CLH             T444610 - Support 4770 crypto modules
+// 10/18/2026    CLH             Get signing service URL from common
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Add Dilithium signature keys
+// 10/18/2026    CLH             Sign concurrently and support signer quorums
+// 10/18/2026    CLH             Keep the sigkeys, sigkeySkis, and sigkeyTokens
+//                               variants
+// 10/18/2026    CLH             Close the signers created from signature keys
+// 10/18/2026    CLH             Stop waiting for signers when ctx ends
 
 package ep11cmds
 
 import (
-	"encoding/hex"
-	"encoding/json"
+	"context"
 	"errors"
-	"io/ioutil"
-	"os"
+	"strconv"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
@@ -71,6 +77,45 @@ var OID_dilithium_r2_8_7 = []byte {
 };
 //#E@T444610CLH
 
+/*----------------------------------------------------------------------------*/
+/* Signs the input data using a set of signature keys.  A set of concatenated */
+/* SignerInfo structures is returned, one for each signature key.  Each       */
+/* SignerInfo structure is an ASN.1 sequence.                                 */
+/*                                                                            */
+/* The signature keys are asked to sign at the same time, and each signature  */
+/* is checked against the public key of its signature key.  Signers returned  */
+/* by SignerQuorum.Signers are replaced by the first valid signatures from    */
+/* the quorum.  A SignatureCollectionError listing the signature keys that    */
+/* failed is returned if not enough valid signatures are collected.  If ctx   */
+/* ends first, the SignatureCollectionError also lists the signature keys     */
+/* that have not answered.                                                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context ctx -- limits how long to wait for the signature keys      */
+/* []byte dataToSign -- the data to be signed                                 */
+/* []common.Signer signers -- the signature keys to be used                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- a set of concatenated SignerInfo structures, one for each        */
+/*     signature                                                              */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func CreateSignerInfoWithContext(ctx context.Context, dataToSign []byte,
+	signers []common.Signer) ([]byte, error) {
+
+	return collectSignerInfo(ctx, dataToSign, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as CreateSignerInfoWithContext, using the background context          */
+/*----------------------------------------------------------------------------*/
+func CreateSignerInfoWithSigners(dataToSign []byte,
+	signers []common.Signer) ([]byte, error) {
+
+	return CreateSignerInfoWithContext(context.Background(), dataToSign,
+		signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Signs the input data using a set of signature keys.  A set of concatenated */
 /* SignerInfo structures is returned, one for each signature key.  Each       */
@@ -90,58 +135,52 @@ var OID_dilithium_r2_8_7 = []byte {
 func CreateSignerInfo(dataToSign []byte, sigkeys []string,
 	sigkeySkis []string, sigkeyTokens []string) ([]byte, error) {
 
-	// Check if the environment variable is set indicating a signing service
-	// should be used
-	ssURL := os.Getenv("TKE_SIGNSERV_URL")
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer common.CloseSigners(signers)
+	return CreateSignerInfoWithSigners(dataToSign, signers)
+}
 
-	finalResult := make([]byte, 0)
-	for i := 0; i < len(sigkeys); i++ {
+/*----------------------------------------------------------------------------*/
+/* Creates a set of ASN.1 elements to be made into an ASN.1 sequence that     */
+/* forms the SignerInfo for data signed by a P521 EC signature key.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte dataToSign -- the data to be signed                                 */
+/* common.Signer signer -- the signature key to use                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- a set of ASN.1 elements that will form SignerInfo containing   */
+/*     an EC signature                                                        */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func CreateP521ECSignerInfoFieldsWithSigner(dataToSign []byte,
+	signer common.Signer) ([][]byte, error) {
 
-		var signerInfoFields [][]byte
-		var err error
-		if ssURL != "" {
-			// Only P521 EC keys are supported when signing service is used
-			signerInfoFields, err = CreateP521ECSignerInfoFields(
-				dataToSign, sigkeys[i], sigkeySkis[i], sigkeyTokens[i])
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			// Signature key files may contain either 2048-bit RSA or P521 EC
-			// signature keys
+	signerInfoFields := make([][]byte, 5)
+	signerInfoFields[0] = VERSION_3
 
-			// Read the signature key file
-			data, err := ioutil.ReadFile(sigkeys[i])
-			if err != nil {
-				return nil, err
-			}
-			var skfields map[string]string
-			err = json.Unmarshal(data, &skfields)
-			if err != nil {
-				panic(err)
-			}
+	signerInfoFields[1] = common.Asn1FormOctetString(signer.SKI())
+	signerInfoFields[1][0] = common.ASN1_CONTEXT_SPECIFIC_TAG //hack
 
-			if skfields["keyType"] == "p521ec" {
-				// Use a P521 EC signature key
-				signerInfoFields, err = CreateP521ECSignerInfoFields(
-					dataToSign, sigkeys[i], sigkeySkis[i], sigkeyTokens[i])
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				// Use a 2048-bit RSA signature key
-				signerInfoFields, err = Create2048RSASignerInfoFields(
-					dataToSign, sigkeys[i], sigkeySkis[i], sigkeyTokens[i])
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	algIdFields := make([][]byte, 2)
+	algIdFields[0] = OID_sha512
+	algIdFields[1] = ASN1_NULL
+	signerInfoFields[2] = common.Asn1FormSequence(algIdFields)
 
-		// Append this ASN.1 sequence to the final result
-		finalResult = append(finalResult, common.Asn1FormSequence(signerInfoFields)...)
+	algIdFields[0] = OID_ecdsaWithSHA512
+	// algIdFields[1] is still ASN1_NULL
+	signerInfoFields[3] = common.Asn1FormSequence(algIdFields)
+
+	signature, err := signer.Sign(dataToSign)
+	if err != nil {
+		return nil, err
 	}
-	return finalResult, nil
+	signerInfoFields[4] = common.Asn1FormOctetString(signature)
+
+	return signerInfoFields, nil
 }
 
 /*----------------------------------------------------------------------------*/
@@ -162,27 +201,47 @@ func CreateSignerInfo(dataToSign []byte, sigkeys []string,
 func CreateP521ECSignerInfoFields(dataToSign []byte, sigkey string,
 	sigkeySki string, sigkeyToken string) ([][]byte, error) {
 
-	signerInfoFields := make([][]byte, 5)
-	signerInfoFields[0] = VERSION_3
-
-	ski, err := hex.DecodeString(sigkeySki)
+	signers, err := common.NewSigners([]string{sigkey}, []string{sigkeySki},
+		[]string{sigkeyToken})
 	if err != nil {
 		return nil, err
 	}
-	signerInfoFields[1] = common.Asn1FormOctetString(ski)
+	defer common.CloseSigners(signers)
+	return CreateP521ECSignerInfoFieldsWithSigner(dataToSign, signers[0])
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a set of ASN.1 elements to be made into an ASN.1 sequence that     */
+/* forms the SignerInfo for data signed by a 2048-bit RSA signature key.      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte dataToSign -- the data to be signed                                 */
+/* common.Signer signer -- the signature key to use                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- a set of ASN.1 elements that will form SignerInfo containing   */
+/*     an RSA signature                                                       */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func Create2048RSASignerInfoFieldsWithSigner(dataToSign []byte,
+	signer common.Signer) ([][]byte, error) {
+
+	signerInfoFields := make([][]byte, 5)
+	signerInfoFields[0] = VERSION_3
+
+	signerInfoFields[1] = common.Asn1FormOctetString(signer.SKI())
 	signerInfoFields[1][0] = common.ASN1_CONTEXT_SPECIFIC_TAG //hack
 
 	algIdFields := make([][]byte, 2)
-	algIdFields[0] = OID_sha512
+	algIdFields[0] = OID_sha256
 	algIdFields[1] = ASN1_NULL
 	signerInfoFields[2] = common.Asn1FormSequence(algIdFields)
 
-	algIdFields[0] = OID_ecdsaWithSHA512
+	algIdFields[0] = OID_rsaEncryption
 	// algIdFields[1] is still ASN1_NULL
 	signerInfoFields[3] = common.Asn1FormSequence(algIdFields)
 
-	signature, err := common.SignWithSignatureKey(
-		dataToSign, sigkey, sigkeyToken)
+	signature, err := signer.Sign(dataToSign)
 	if err != nil {
 		return nil, err
 	}
@@ -209,30 +268,57 @@ func CreateP521ECSignerInfoFields(dataToSign []byte, sigkey string,
 func Create2048RSASignerInfoFields(dataToSign []byte, sigkey string,
 	sigkeySki string, sigkeyToken string) ([][]byte, error) {
 
-	signerInfoFields := make([][]byte, 5)
-	signerInfoFields[0] = VERSION_3
-
-	ski, err := hex.DecodeString(sigkeySki)
+	signers, err := common.NewSigners([]string{sigkey}, []string{sigkeySki},
+		[]string{sigkeyToken})
 	if err != nil {
 		return nil, err
 	}
-	signerInfoFields[1] = common.Asn1FormOctetString(ski)
+	defer common.CloseSigners(signers)
+	return Create2048RSASignerInfoFieldsWithSigner(dataToSign, signers[0])
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a set of ASN.1 elements to be made into an ASN.1 sequence that     */
+/* forms the SignerInfo for data signed by a Dilithium round 2 (8,7)          */
+/* signature key.  Dilithium signs the data itself, so the digest algorithm   */
+/* is not used in calculating the signature.  SHA-512 is reported, as for     */
+/* P521 EC keys.                                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []byte dataToSign -- the data to be signed                                 */
+/* common.Signer signer -- the signature key to use                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- a set of ASN.1 elements that will form SignerInfo containing   */
+/*     a Dilithium signature                                                  */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func CreateDilithiumSignerInfoFields(dataToSign []byte,
+	signer common.Signer) ([][]byte, error) {
+
+	signerInfoFields := make([][]byte, 5)
+	signerInfoFields[0] = VERSION_3
+
+	signerInfoFields[1] = common.Asn1FormOctetString(signer.SKI())
 	signerInfoFields[1][0] = common.ASN1_CONTEXT_SPECIFIC_TAG //hack
 
 	algIdFields := make([][]byte, 2)
-	algIdFields[0] = OID_sha256
+	algIdFields[0] = OID_sha512
 	algIdFields[1] = ASN1_NULL
 	signerInfoFields[2] = common.Asn1FormSequence(algIdFields)
 
-	algIdFields[0] = OID_rsaEncryption
+	algIdFields[0] = OID_dilithium_r2_8_7
 	// algIdFields[1] is still ASN1_NULL
 	signerInfoFields[3] = common.Asn1FormSequence(algIdFields)
 
-	signature, err := common.SignWithSignatureKey(
-		dataToSign, sigkey, sigkeyToken)
+	signature, err := signer.Sign(dataToSign)
 	if err != nil {
 		return nil, err
 	}
+	if len(signature) != common.DILITHIUM_R2_87_SIGNATURE_LENGTH {
+		return nil, errors.New("Invalid Dilithium signature length: " +
+			strconv.Itoa(len(signature)))
+	}
 	signerInfoFields[4] = common.Asn1FormOctetString(signature)
 
 	return signerInfoFields, nil
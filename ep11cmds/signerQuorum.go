@@ -0,0 +1,385 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Reject quorum signers outside CreateSignerInfo
+// 10/18/2026    CLH             Check only the length of Dilithium signatures
+// 10/18/2026    CLH             Reject signers with the same SKI
+// 10/18/2026    CLH             Stop waiting for signers when ctx ends
+
+package ep11cmds
+
+import (
+	"context"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* A set of signature keys, any of which may sign a command.  When a command  */
+/* needs fewer signatures than there are signature keys, all of the signature */
+/* keys are asked to sign at the same time and the first valid signatures     */
+/* returned are used, so a signer that is slow or cannot be reached does not  */
+/* stop the command while enough other signers are available.                 */
+/*                                                                            */
+/* The signature keys are listed in order of preference.  Signing requests    */
+/* are started in that order, and the SignerInfo of the signatures used are   */
+/* placed in the command in that order.  Signers asked after the quorum is    */
+/* met are not cancelled, but their signatures are not used.                  */
+/*----------------------------------------------------------------------------*/
+type SignerQuorum struct {
+	signers []common.Signer // in order of preference
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a quorum from a set of signature keys.                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* []common.Signer signers -- the signature keys that may sign, most          */
+/*     preferred first                                                        */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *SignerQuorum -- the quorum                                                */
+/*----------------------------------------------------------------------------*/
+func NewSignerQuorum(signers []common.Signer) *SignerQuorum {
+	return &SignerQuorum{signers: append([]common.Signer(nil), signers...)}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signers to pass to a function that sends a signed command,     */
+/* when the command needs the given number of signatures from the quorum.     */
+/* The returned signers stand for signatures collected by CreateSignerInfo;   */
+/* they cannot be used to sign anything themselves, or be used with signing   */
+/* bundles.  They can be combined with other signers, whose signatures are    */
+/* then always needed, and the quorum signature keys those signers hold are   */
+/* not asked again.                                                           */
+/*                                                                            */
+/* The returned signers have no SKI, key type, or public key.  Functions that */
+/* need those, such as CreateAdminCert and SigningBundle.Sign, return an      */
+/* error when given one; see IsQuorumSigner.                                  */
+/*----------------------------------------------------------------------------*/
+func (q *SignerQuorum) Signers(needed int) []common.Signer {
+	slots := make([]common.Signer, needed)
+	for i := range slots {
+		slots[i] = &quorumSlot{quorum: q}
+	}
+	return slots
+}
+
+/** Stands for one signature to be collected from a SignerQuorum */
+type quorumSlot struct {
+	quorum *SignerQuorum
+}
+
+/** Reported when a signer from SignerQuorum.Signers is used elsewhere */
+var errQuorumSigner = errors.New("Signers returned by SignerQuorum.Signers " +
+	"can only be used to send administrative commands.")
+
+/*----------------------------------------------------------------------------*/
+/* Reports whether a signer was returned by SignerQuorum.Signers.  Such       */
+/* signers can only be passed to functions that send administrative           */
+/* commands, or to CreateSignerInfo.                                          */
+/*----------------------------------------------------------------------------*/
+func IsQuorumSigner(signer common.Signer) bool {
+	_, ok := signer.(*quorumSlot)
+	return ok
+}
+
+func (s *quorumSlot) SKI() []byte {
+	return nil
+}
+
+func (s *quorumSlot) KeyType() string {
+	return ""
+}
+
+func (s *quorumSlot) PublicKey() crypto.PublicKey {
+	return nil
+}
+
+func (s *quorumSlot) Sign(data []byte) ([]byte, error) {
+	return nil, errQuorumSigner
+}
+
+/** Identifies a signature key that did not return a valid signature */
+type SignerFailure struct {
+	SKI string // Subject Key Identifier, as a hexadecimal string
+	Err error  // why no valid signature was returned
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returned when not enough valid signatures can be collected for a command.  */
+/* Failures lists each signature key that was asked and did not return a      */
+/* valid signature.  If the context ended first, Err is the context error     */
+/* and Unanswered lists the signature keys that had not answered.             */
+/*----------------------------------------------------------------------------*/
+type SignatureCollectionError struct {
+	Needed     int // number of signatures needed for the command
+	Collected  int // number of valid signatures collected
+	Failures   []SignerFailure
+	Unanswered []string // SKIs, as hexadecimal strings
+	Err        error    // the context error, if the context ended
+}
+
+func (e *SignatureCollectionError) Error() string {
+	message := "Only " + strconv.Itoa(e.Collected) + " of the " +
+		strconv.Itoa(e.Needed) + " signatures needed for the command " +
+		"could be collected."
+	if len(e.Failures) == 0 && e.Err == nil {
+		message += "\nNot enough signature keys were provided."
+	}
+	for _, failure := range e.Failures {
+		message += "\nSignature key " + failure.SKI + ": " +
+			failure.Err.Error()
+	}
+	if e.Err != nil {
+		message += "\nStopped waiting for signatures: " + e.Err.Error()
+	}
+	for _, ski := range e.Unanswered {
+		message += "\nSignature key " + ski + " did not answer."
+	}
+	return message
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the context error if the context ended, or else the error of the   */
+/* first signature key that failed, if any                                    */
+/*----------------------------------------------------------------------------*/
+func (e *SignatureCollectionError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e.Failures[0].Err
+}
+
+/** Signature keys from which a number of signatures are needed */
+type signatureGroup struct {
+	candidates  []common.Signer
+	needed      int
+	signerInfos [][]byte // SignerInfo for each candidate, nil if not used
+	answered    []bool
+	valid       int
+	failed      int
+}
+
+/** Result of asking one signature key to sign */
+type signatureResult struct {
+	group      int
+	index      int
+	signerInfo []byte
+	err        error
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using a set of signers that may include signers returned by     */
+/* SignerQuorum.Signers, asking all signature keys at the same time.  Returns */
+/* the concatenated SignerInfo structures, ordinary signers first in the      */
+/* order given, followed by the signatures from each quorum.  Signers that    */
+/* are not part of a quorum must have different SKIs; the crypto module would */
+/* count their signatures only once.  This is checked before any signer is    */
+/* asked to sign.                                                             */
+/*                                                                            */
+/* When ctx ends before enough signatures are collected, the signers that     */
+/* have not answered are reported and left to finish on their own.            */
+/*----------------------------------------------------------------------------*/
+func collectSignerInfo(ctx context.Context, dataToSign []byte,
+	signers []common.Signer) ([]byte, error) {
+
+	// Every signer that is not part of a quorum must sign
+	groups := make([]*signatureGroup, 0)
+	usedSKIs := make(map[string]bool)
+	for _, signer := range signers {
+		if _, ok := signer.(*quorumSlot); ok {
+			continue
+		}
+		ski := hex.EncodeToString(signer.SKI())
+		if usedSKIs[ski] {
+			return nil, errors.New("More than one signer has the Subject " +
+				"Key Identifier " + ski + ".  Each signature on a command " +
+				"must be made with a different signature key.")
+		}
+		usedSKIs[ski] = true
+		groups = append(groups, &signatureGroup{
+			candidates: []common.Signer{signer}, needed: 1})
+	}
+
+	// Each quorum provides one signature for each of its signers
+	quorumGroups := make(map[*SignerQuorum]*signatureGroup)
+	for _, signer := range signers {
+		slot, ok := signer.(*quorumSlot)
+		if !ok {
+			continue
+		}
+		group := quorumGroups[slot.quorum]
+		if group == nil {
+			group = &signatureGroup{}
+			for _, candidate := range slot.quorum.signers {
+				if IsQuorumSigner(candidate) {
+					// A quorum cannot be made of another quorum
+					return nil, errQuorumSigner
+				}
+				ski := hex.EncodeToString(candidate.SKI())
+				if !usedSKIs[ski] {
+					usedSKIs[ski] = true
+					group.candidates = append(group.candidates, candidate)
+				}
+			}
+			quorumGroups[slot.quorum] = group
+			groups = append(groups, group)
+		}
+		group.needed++
+	}
+
+	needed := 0
+	asked := 0
+	for _, group := range groups {
+		needed += group.needed
+		asked += len(group.candidates)
+	}
+	for _, group := range groups {
+		if len(group.candidates) < group.needed {
+			return nil, &SignatureCollectionError{Needed: needed}
+		}
+	}
+
+	// Ask every signature key to sign
+	results := make(chan signatureResult, asked)
+	for g, group := range groups {
+		group.signerInfos = make([][]byte, len(group.candidates))
+		group.answered = make([]bool, len(group.candidates))
+		for i, candidate := range group.candidates {
+			go func(g int, i int, signer common.Signer) {
+				signerInfo, err := createOneSignerInfo(dataToSign, signer)
+				results <- signatureResult{g, i, signerInfo, err}
+			}(g, i, candidate)
+		}
+	}
+
+	// Use the first valid signatures returned for each group
+	collected := 0
+	failures := make([]SignerFailure, 0)
+	for collected < needed {
+		var result signatureResult
+		select {
+		case result = <-results:
+		case <-ctx.Done():
+			return nil, &SignatureCollectionError{Needed: needed,
+				Collected: collected, Failures: failures,
+				Unanswered: unanswered(groups), Err: ctx.Err()}
+		}
+		group := groups[result.group]
+		group.answered[result.index] = true
+		if group.valid == group.needed {
+			// More signatures than needed
+			continue
+		}
+		if result.err != nil {
+			failures = append(failures, SignerFailure{
+				SKI: hex.EncodeToString(
+					group.candidates[result.index].SKI()),
+				Err: result.err})
+			group.failed++
+			if len(group.candidates)-group.failed < group.needed {
+				return nil, &SignatureCollectionError{Needed: needed,
+					Collected: collected, Failures: failures}
+			}
+			continue
+		}
+		group.signerInfos[result.index] = result.signerInfo
+		group.valid++
+		collected++
+	}
+
+	finalResult := make([]byte, 0)
+	for _, group := range groups {
+		for _, signerInfo := range group.signerInfos {
+			finalResult = append(finalResult, signerInfo...)
+		}
+	}
+	return finalResult, nil
+}
+
+/** Returns the SKIs of the signature keys that have not answered */
+func unanswered(groups []*signatureGroup) []string {
+	skis := make([]string, 0)
+	for _, group := range groups {
+		for i, candidate := range group.candidates {
+			if !group.answered[i] {
+				skis = append(skis, hex.EncodeToString(candidate.SKI()))
+			}
+		}
+	}
+	return skis
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs data using one signature key and returns the SignerInfo.  EC and RSA */
+/* signatures are verified using the public key of the signature key.         */
+/* Dilithium signatures cannot be verified by the SDK, so only their length   */
+/* is checked; the crypto module verifies them.                               */
+/*----------------------------------------------------------------------------*/
+func createOneSignerInfo(dataToSign []byte, signer common.Signer) ([]byte,
+	error) {
+
+	checked := checkedSigner{signer}
+	var signerInfoFields [][]byte
+	var err error
+	switch signer.KeyType() {
+	case common.KEY_TYPE_P521EC:
+		signerInfoFields, err = CreateP521ECSignerInfoFieldsWithSigner(
+			dataToSign, checked)
+	case common.KEY_TYPE_RSA2048:
+		signerInfoFields, err = Create2048RSASignerInfoFieldsWithSigner(
+			dataToSign, checked)
+	case common.KEY_TYPE_DILITHIUM_R2_87:
+		signerInfoFields, err = CreateDilithiumSignerInfoFields(dataToSign,
+			checked)
+	default:
+		err = errors.New("Unsupported signature key type: " +
+			signer.KeyType())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return common.Asn1FormSequence(signerInfoFields), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signer that checks each signature before it is used: EC and RSA signatures */
+/* are verified against the public key, and Dilithium signatures are only     */
+/* checked for length.                                                        */
+/*----------------------------------------------------------------------------*/
+type checkedSigner struct {
+	common.Signer
+}
+
+func (s checkedSigner) Sign(data []byte) ([]byte, error) {
+	signature, err := s.Signer.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	if s.KeyType() == common.KEY_TYPE_DILITHIUM_R2_87 {
+		if !common.CheckDilithiumSignatureLength(signature) {
+			return nil, errors.New("The signature does not have the length " +
+				"of a Dilithium round 2 (8,7) signature.")
+		}
+		return signature, nil
+	}
+	if !common.VerifySignature(s.PublicKey(), data, signature) {
+		return nil, errors.New("The signature is not valid for the public " +
+			"key of the signature key.")
+	}
+	return signature, nil
+}
@@ -0,0 +1,328 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test signers that do not answer before ctx ends
+
+package ep11cmds
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Signer that can fail, wait before signing, or sign using another key */
+type testQuorumSigner struct {
+	common.Signer
+	err       error
+	wait      chan struct{} // closed to let the signer return, if not nil
+	wrongSign bool
+
+	mutex sync.Mutex
+	calls int
+}
+
+func newTestQuorumSigners(t *testing.T, count int) []*testQuorumSigner {
+	signers := make([]*testQuorumSigner, count)
+	for i := range signers {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, _ := common.NewPrivateKeySigner(key)
+		signers[i] = &testQuorumSigner{Signer: signer}
+	}
+	return signers
+}
+
+func (s *testQuorumSigner) Sign(data []byte) ([]byte, error) {
+	s.mutex.Lock()
+	s.calls++
+	s.mutex.Unlock()
+	if s.wait != nil {
+		<-s.wait
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.wrongSign {
+		data = append([]byte("other "), data...)
+	}
+	return s.Signer.Sign(data)
+}
+
+func (s *testQuorumSigner) callCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls
+}
+
+/** Returns the signers as common.Signer values */
+func asSigners(signers ...*testQuorumSigner) []common.Signer {
+	result := make([]common.Signer, len(signers))
+	for i, signer := range signers {
+		result[i] = signer
+	}
+	return result
+}
+
+/** Returns the SKIs in concatenated SignerInfo structures, in order */
+func signerInfoSKIs(t *testing.T, signerInfos []byte) [][]byte {
+	skis := make([][]byte, 0)
+	for len(signerInfos) > 0 {
+		var signerInfo asn1.RawValue
+		var err error
+		signerInfos, err = asn1.Unmarshal(signerInfos, &signerInfo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Version 3, then the SKI with a context specific tag
+		skis = append(skis, signerInfo.Bytes[5:37])
+	}
+	return skis
+}
+
+/*----------------------------------------------------------------------------*/
+/* A quorum uses the first valid signatures, so signers that fail, return a   */
+/* signature that is not valid, or do not answer do not stop the command      */
+/*----------------------------------------------------------------------------*/
+func TestSignerQuorumSkipsFailedSigners(t *testing.T) {
+	signers := newTestQuorumSigners(t, 4)
+	signers[0].err = errors.New("signing service unavailable")
+	signers[1].wrongSign = true
+	quorum := NewSignerQuorum(asSigners(signers...))
+	signerInfos, err := CreateSignerInfoWithSigners(
+		[]byte("command"), quorum.Signers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skis := signerInfoSKIs(t, signerInfos)
+	if len(skis) != 2 || !bytes.Equal(skis[0], signers[2].SKI()) ||
+		!bytes.Equal(skis[1], signers[3].SKI()) {
+		t.Errorf("Unexpected SignerInfo SKIs %X", skis)
+	}
+
+	// One signature does not wait for a signer that does not answer
+	signers[3].wait = make(chan struct{})
+	defer close(signers[3].wait)
+	signerInfos, err = CreateSignerInfoWithSigners(
+		[]byte("command"), quorum.Signers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skis = signerInfoSKIs(t, signerInfos)
+	if len(skis) != 1 || !bytes.Equal(skis[0], signers[2].SKI()) {
+		t.Errorf("Unexpected SignerInfo SKIs %X", skis)
+	}
+}
+
+/** The SignerInfo of the signatures used are in order of preference */
+func TestSignerQuorumPreferenceOrder(t *testing.T) {
+	signers := newTestQuorumSigners(t, 3)
+	// The most preferred signer answers last
+	signers[0].wait = make(chan struct{})
+	time.AfterFunc(20*time.Millisecond, func() { close(signers[0].wait) })
+	quorum := NewSignerQuorum(asSigners(signers...))
+	signerInfos, err := CreateSignerInfoWithSigners(
+		[]byte("command"), quorum.Signers(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skis := signerInfoSKIs(t, signerInfos)
+	for i, signer := range signers {
+		if !bytes.Equal(skis[i], signer.SKI()) {
+			t.Errorf("SignerInfo %d has SKI %X, expected %X", i, skis[i],
+				signer.SKI())
+		}
+	}
+}
+
+/** Signers that must sign are asked once, even when also in the quorum */
+func TestSignerQuorumWithRequiredSigners(t *testing.T) {
+	signers := newTestQuorumSigners(t, 3)
+	quorum := NewSignerQuorum(asSigners(signers...))
+	required := signers[0]
+	signerInfos, err := CreateSignerInfoWithSigners([]byte("command"),
+		append([]common.Signer{required}, quorum.Signers(2)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skis := signerInfoSKIs(t, signerInfos)
+	if len(skis) != 3 || !bytes.Equal(skis[0], required.SKI()) {
+		t.Errorf("Unexpected SignerInfo SKIs %X", skis)
+	}
+	if required.callCount() != 1 {
+		t.Errorf("The required signer was asked %d times",
+			required.callCount())
+	}
+
+	// The required signer leaves only two quorum signers for three slots
+	_, err = CreateSignerInfoWithSigners([]byte("command"),
+		append([]common.Signer{required}, quorum.Signers(3)...))
+	var collectionErr *SignatureCollectionError
+	if !errors.As(err, &collectionErr) || collectionErr.Needed != 4 ||
+		len(collectionErr.Failures) != 0 {
+		t.Errorf("CreateSignerInfo returned %v", err)
+	}
+}
+
+/** Each signer that fails is reported when too few signatures are valid */
+func TestSignerQuorumFailures(t *testing.T) {
+	signers := newTestQuorumSigners(t, 3)
+	unavailable := errors.New("signing service unavailable")
+	signers[0].err = unavailable
+	signers[2].wrongSign = true
+	quorum := NewSignerQuorum(asSigners(signers...))
+	_, err := CreateSignerInfoWithSigners([]byte("command"), quorum.Signers(2))
+
+	var collectionErr *SignatureCollectionError
+	if !errors.As(err, &collectionErr) {
+		t.Fatalf("CreateSignerInfo returned %v", err)
+	}
+	if collectionErr.Needed != 2 || len(collectionErr.Failures) != 2 {
+		t.Errorf("Unexpected error %+v", collectionErr)
+	}
+	if collectionErr.Collected > 1 {
+		t.Errorf("%d signatures were reported as collected",
+			collectionErr.Collected)
+	}
+	failedSKIs := make(map[string]bool)
+	for _, failure := range collectionErr.Failures {
+		failedSKIs[failure.SKI] = true
+	}
+	for _, i := range []int{0, 2} {
+		if !failedSKIs[hex.EncodeToString(signers[i].SKI())] {
+			t.Errorf("Signer %d was not reported", i+1)
+		}
+	}
+	if errors.Unwrap(err) != collectionErr.Failures[0].Err {
+		t.Error("The first failure is not returned by Unwrap")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Collection stops when the context ends, and the signers that have not      */
+/* answered are reported                                                      */
+/*----------------------------------------------------------------------------*/
+func TestSignerQuorumContextEnds(t *testing.T) {
+	signers := newTestQuorumSigners(t, 3)
+	signers[0].wait = make(chan struct{})
+	defer close(signers[0].wait)
+	unavailable := errors.New("signing service unavailable")
+	signers[2].err = unavailable
+	quorum := NewSignerQuorum(asSigners(signers...))
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	_, err := CreateSignerInfoWithContext(ctx, []byte("command"),
+		quorum.Signers(2))
+
+	var collectionErr *SignatureCollectionError
+	if !errors.As(err, &collectionErr) {
+		t.Fatalf("CreateSignerInfo returned %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("The context error is not returned by Unwrap: %v", err)
+	}
+	if collectionErr.Collected != 1 || len(collectionErr.Failures) != 1 ||
+		collectionErr.Failures[0].Err != unavailable {
+		t.Errorf("Unexpected error %+v", collectionErr)
+	}
+	ski := hex.EncodeToString(signers[0].SKI())
+	if len(collectionErr.Unanswered) != 1 ||
+		collectionErr.Unanswered[0] != ski {
+		t.Errorf("Unanswered signers %v, expected %s",
+			collectionErr.Unanswered, ski)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signers returned by SignerQuorum.Signers are rejected everywhere except    */
+/* when sending commands, rather than standing for a key they do not have     */
+/*----------------------------------------------------------------------------*/
+func TestQuorumSignersRejected(t *testing.T) {
+	signers := newTestQuorumSigners(t, 2)
+	quorum := NewSignerQuorum(asSigners(signers...))
+	slot := quorum.Signers(1)[0]
+	if !IsQuorumSigner(slot) || IsQuorumSigner(signers[0]) {
+		t.Error("IsQuorumSigner did not identify the quorum signer")
+	}
+
+	if _, err := slot.Sign([]byte("data")); err != errQuorumSigner {
+		t.Errorf("Sign returned %v", err)
+	}
+	if _, err := CreateAdminCert(slot, "admin1"); err != errQuorumSigner {
+		t.Errorf("CreateAdminCert returned %v", err)
+	}
+	bundle := &SigningBundle{Commands: []BundleCommand{
+		{AdminBlock: "3000", SignaturesNeeded: 1}}}
+	if err := bundle.Sign(slot); err != errQuorumSigner {
+		t.Errorf("SigningBundle.Sign returned %v", err)
+	}
+	if len(bundle.Commands[0].SignerInfos) != 0 {
+		t.Error("The quorum signed the signing bundle")
+	}
+
+	// A quorum cannot be made from the signers of another quorum
+	nested := NewSignerQuorum(quorum.Signers(2))
+	_, err := CreateSignerInfoWithSigners([]byte("command"), nested.Signers(1))
+	if err != errQuorumSigner {
+		t.Errorf("CreateSignerInfo for a nested quorum returned %v", err)
+	}
+	for _, signer := range signers {
+		if signer.callCount() != 0 {
+			t.Error("A signer of the inner quorum was asked to sign")
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signers that are not part of a quorum and have the same SKI are rejected   */
+/* before any signer is asked to sign                                         */
+/*----------------------------------------------------------------------------*/
+func TestDuplicateSignersRejected(t *testing.T) {
+	signers := newTestQuorumSigners(t, 2)
+	duplicate := &testQuorumSigner{Signer: signers[0].Signer}
+	_, err := CreateSignerInfoWithSigners([]byte("command"),
+		asSigners(signers[0], signers[1], duplicate))
+	if err == nil {
+		t.Fatal("CreateSignerInfo accepted two signers with the same SKI")
+	}
+	var collectionErr *SignatureCollectionError
+	if errors.As(err, &collectionErr) {
+		t.Errorf("CreateSignerInfo returned %v", err)
+	}
+	for _, signer := range append(signers, duplicate) {
+		if signer.callCount() != 0 {
+			t.Error("A signer was asked to sign")
+		}
+	}
+
+	// A quorum member with the SKI of a required signer is not asked again
+	quorum := NewSignerQuorum(asSigners(duplicate, signers[1]))
+	_, err = CreateSignerInfoWithSigners([]byte("command"),
+		append(asSigners(signers[0]), quorum.Signers(1)...))
+	if err != nil {
+		t.Errorf("CreateSignerInfo returned %v", err)
+	}
+	if duplicate.callCount() != 0 {
+		t.Error("The quorum member with the SKI of a required signer signed")
+	}
+}
@@ -0,0 +1,507 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Return the output of each command submitted
+// 10/18/2026    CLH             Reject signers from SignerQuorum.Signers
+// 10/18/2026    CLH             Mark commands whose response was lost
+
+package ep11cmds
+
+import (
+	"context"
+	stdasn1 "encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/Logicalis/asn1"
+)
+
+/** Version of the signing bundle file format */
+const SIGNING_BUNDLE_VERSION = 1
+
+/*----------------------------------------------------------------------------*/
+/* An administrative command to be included in a signing bundle.  The         */
+/* domain, module ID, and transaction counter of the xcpAdminBlk are filled   */
+/* in when the bundle is prepared.                                            */
+/*----------------------------------------------------------------------------*/
+type PlannedCommand struct {
+	Domain           common.DomainEntry
+	CmdID            []byte // XCP_ADM_... command identifier
+	CmdInput         []byte // command input, or nil for commands with none
+	Description      string // shown to the administrators signing the bundle
+	SignaturesNeeded int    // signature threshold, or 0 in imprint mode
+}
+
+/*----------------------------------------------------------------------------*/
+/* A set of unsigned administrative commands, written to a file so that they  */
+/* can be signed on workstations with no connection to the crypto units.      */
+/* Byte fields are hexadecimal strings.                                       */
+/*----------------------------------------------------------------------------*/
+type SigningBundle struct {
+	Version  int             `json:"version"`
+	Created  string          `json:"created"`
+	Commands []BundleCommand `json:"commands"`
+}
+
+/*----------------------------------------------------------------------------*/
+/* An administrative command in a signing bundle.  Submitted is set when the  */
+/* crypto module accepted the command.  ResponseLost is set instead when the  */
+/* command reached the crypto module but its response was lost, so it is not  */
+/* known whether the command took effect.                                     */
+/*----------------------------------------------------------------------------*/
+type BundleCommand struct {
+	Description        string             `json:"description"`
+	Domain             common.DomainEntry `json:"domain"`
+	TransactionCounter string             `json:"transaction_counter"`
+	AdminBlock         string             `json:"admin_block"` // data to sign
+	SignaturesNeeded   int                `json:"signatures_needed"`
+	SignerInfos        []string           `json:"signer_infos"`
+	Submitted          bool               `json:"submitted"`
+	ResponseLost       bool               `json:"response_lost,omitempty"`
+	Output             string             `json:"output,omitempty"` // after submission
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reported when a command in a signing bundle no longer has the transaction  */
+/* counter the domain expects, because another command was processed by the   */
+/* domain after the bundle was prepared.  The command cannot be submitted.    */
+/*----------------------------------------------------------------------------*/
+type StaleTransactionCounterError struct {
+	Index    int    // index of the command in the signing bundle
+	Location string // domain location
+	Expected string // transaction counter in the signing bundle
+	Current  string // transaction counter of the next command for the domain
+}
+
+func (e *StaleTransactionCounterError) Error() string {
+	return "Command " + strconv.Itoa(e.Index+1) + " in the signing bundle " +
+		"cannot be submitted to domain " + e.Location + ".\nThe domain " +
+		"expects transaction counter " + e.Current + ", but the command was " +
+		"signed with transaction counter " + e.Expected + ".\nAnother " +
+		"command was processed by the domain after the signing bundle was " +
+		"prepared.  Prepare and sign a new signing bundle."
+}
+
+/*----------------------------------------------------------------------------*/
+/* Prepares a signing bundle for a list of administrative commands.           */
+/*                                                                            */
+/* Query Domain Attributes is issued once for each domain to get the          */
+/* administrative domain, the module ID, and the transaction counter.  The    */
+/* transaction counter of each command is predicted from the commands before  */
+/* it for the same domain, so the commands must be submitted in order and no  */
+/* other commands may be sent to the domains until the bundle is submitted.   */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation of the queries                    */
+/* common.Transport -- sends the queries to the crypto units                  */
+/* []PlannedCommand -- the commands, in the order they are to be submitted    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *SigningBundle -- the unsigned commands                                    */
+/* error -- reports any error encountered                                     */
+/*----------------------------------------------------------------------------*/
+func PrepareSigningBundleWithContext(ctx context.Context, tr common.Transport,
+	commands []PlannedCommand) (*SigningBundle, error) {
+
+	bundle := &SigningBundle{
+		Version:  SIGNING_BUNDLE_VERSION,
+		Created:  time.Now().UTC().Format(time.RFC3339),
+		Commands: make([]BundleCommand, 0, len(commands)),
+	}
+	// Latest xcpAdminRspBlk or predicted xcpAdminBlk fields for each domain
+	domains := make(map[string]*AdminRspBlk)
+
+	for i := 0; i < len(commands); i++ {
+		de := commands[i].Domain
+		key := de.Hsm_id + "\n" + strconv.Itoa(de.GetDomainIndex())
+		previous, ok := domains[key]
+		if !ok {
+			_, adminRspBlk, err := QueryDomainAttributesWithContext(ctx, tr, de)
+			if err != nil {
+				return nil, err
+			}
+			previous = &adminRspBlk
+			domains[key] = previous
+		}
+		if commands[i].SignaturesNeeded < 0 {
+			return nil, errors.New("The number of signatures needed for " +
+				"command " + strconv.Itoa(i+1) + " cannot be negative.")
+		}
+
+		var adminBlk AdminBlk
+		adminBlk.CmdID = commands[i].CmdID
+		adminBlk.DomainID = previous.DomainID
+		adminBlk.ModuleID = previous.ModuleID
+		adminBlk.TransactionCounter =
+			IncrementTransactionCounter(previous.TransactionCounter)
+		adminBlk.CmdInput = commands[i].CmdInput
+		if adminBlk.CmdInput == nil {
+			adminBlk.CmdInput = []byte{}
+		}
+		previous.TransactionCounter = adminBlk.TransactionCounter
+
+		adminBlockSeq, err := asn1.Encode(adminBlk)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Commands = append(bundle.Commands, BundleCommand{
+			Description:        commands[i].Description,
+			Domain:             de,
+			TransactionCounter: hex.EncodeToString(adminBlk.TransactionCounter),
+			AdminBlock:         hex.EncodeToString(adminBlockSeq),
+			SignaturesNeeded:   commands[i].SignaturesNeeded,
+			SignerInfos:        []string{},
+		})
+	}
+	return bundle, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as PrepareSigningBundleWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func PrepareSigningBundle(tr common.Transport,
+	commands []PlannedCommand) (*SigningBundle, error) {
+
+	return PrepareSigningBundleWithContext(context.Background(), tr, commands)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs every command in a signing bundle that still needs signatures and    */
+/* has not already been signed by the signature key.  Commands sent in        */
+/* imprint mode need no signatures, and are not signed.  Used on the offline  */
+/* workstation holding the signature key.                                     */
+/*----------------------------------------------------------------------------*/
+func (b *SigningBundle) Sign(signer common.Signer) error {
+	if IsQuorumSigner(signer) {
+		return errQuorumSigner
+	}
+	for i := 0; i < len(b.Commands); i++ {
+		command := &b.Commands[i]
+		if command.Submitted || command.ResponseLost ||
+			len(command.SignerInfos) >= command.SignaturesNeeded {
+			continue
+		}
+		signed, err := command.signedBy(signer.SKI())
+		if err != nil {
+			return err
+		}
+		if signed {
+			continue
+		}
+		adminBlockSeq, err := hex.DecodeString(command.AdminBlock)
+		if err != nil {
+			return errors.New("Invalid admin block for command " +
+				strconv.Itoa(i+1) + " in the signing bundle.")
+		}
+		signerInfo, err := CreateSignerInfoWithSigners(adminBlockSeq,
+			[]common.Signer{signer})
+		if err != nil {
+			return err
+		}
+		command.SignerInfos = append(command.SignerInfos,
+			hex.EncodeToString(signerInfo))
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Adds SignerInfo created offline by other tools to a command in a signing   */
+/* bundle.  The SignerInfo signs the admin block of the command, using        */
+/* ecdsaWithSHA512 for P521 EC keys or ANSI X9.31 formatting of a SHA-256     */
+/* hash for RSA keys.                                                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* int -- index of the command in the signing bundle                          */
+/* []byte -- one or more concatenated DER encoded SignerInfo sequences        */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports SignerInfo that cannot be decoded, or a signature key     */
+/*    that has already signed the command                                     */
+/*----------------------------------------------------------------------------*/
+func (b *SigningBundle) AddSignerInfo(index int, signerInfo []byte) error {
+	if index < 0 || index >= len(b.Commands) {
+		return errors.New("The signing bundle has no command " +
+			strconv.Itoa(index+1) + ".")
+	}
+	command := &b.Commands[index]
+	if command.Submitted || command.ResponseLost {
+		return errors.New("Command " + strconv.Itoa(index+1) +
+			" in the signing bundle has already been submitted.")
+	}
+	for len(signerInfo) > 0 {
+		var raw stdasn1.RawValue
+		rest, err := stdasn1.Unmarshal(signerInfo, &raw)
+		if err != nil {
+			return errors.New("Invalid SignerInfo.\nMessage: " + err.Error())
+		}
+		one := signerInfo[0 : len(signerInfo)-len(rest)]
+		ski, err := signerInfoSKI(one)
+		if err != nil {
+			return err
+		}
+		signed, err := command.signedBy(ski)
+		if err != nil {
+			return err
+		}
+		if signed {
+			return errors.New("Command " + strconv.Itoa(index+1) +
+				" in the signing bundle has already been signed by the " +
+				"signature key with Subject Key Identifier " +
+				hex.EncodeToString(ski) + ".")
+		}
+		command.SignerInfos = append(command.SignerInfos,
+			hex.EncodeToString(one))
+		signerInfo = rest
+	}
+	return nil
+}
+
+/** Returns true if a command has SignerInfo from a signature key */
+func (c *BundleCommand) signedBy(ski []byte) (bool, error) {
+	for _, s := range c.SignerInfos {
+		signerInfo, err := hex.DecodeString(s)
+		if err != nil {
+			return false, errors.New("Invalid SignerInfo in the signing " +
+				"bundle.\nMessage: " + err.Error())
+		}
+		existing, err := signerInfoSKI(signerInfo)
+		if err != nil {
+			return false, err
+		}
+		if common.ByteSlicesAreEqual(existing, ski) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Subject Key Identifier from a single DER encoded SignerInfo,   */
+/* checking that the SignerInfo has the form created by CreateSignerInfo.     */
+/*----------------------------------------------------------------------------*/
+func signerInfoSKI(signerInfo []byte) ([]byte, error) {
+	var fields struct {
+		Version            int
+		SubjectKeyID       []byte `asn1:"tag:0"`
+		DigestAlgorithm    stdasn1.RawValue
+		SignatureAlgorithm stdasn1.RawValue
+		Signature          []byte
+	}
+	rest, err := stdasn1.Unmarshal(signerInfo, &fields)
+	if err == nil && len(rest) != 0 {
+		err = errors.New("unexpected data after SignerInfo")
+	}
+	if err == nil && fields.Version != 3 {
+		err = errors.New("SignerInfo version is not 3")
+	}
+	if err == nil && len(fields.SubjectKeyID) != 32 {
+		err = errors.New("Subject Key Identifier is not 32 bytes")
+	}
+	if err != nil {
+		return nil, errors.New("Invalid SignerInfo.\nMessage: " + err.Error())
+	}
+	return fields.SubjectKeyID, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes a signing bundle to a file.  The file does not hold secrets, but is */
+/* readable only by the owner to keep it from being changed by others.        */
+/*----------------------------------------------------------------------------*/
+func (b *SigningBundle) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads a signing bundle from a file written by SigningBundle.Save           */
+/*----------------------------------------------------------------------------*/
+func LoadSigningBundle(path string) (*SigningBundle, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var bundle SigningBundle
+	err = json.Unmarshal(data, &bundle)
+	if err != nil {
+		return nil, errors.New("Invalid signing bundle file " + path +
+			"\nMessage: " + err.Error())
+	}
+	if bundle.Version != SIGNING_BUNDLE_VERSION {
+		return nil, errors.New("Invalid signing bundle file " + path +
+			"\nUnsupported version: " + strconv.Itoa(bundle.Version))
+	}
+	return &bundle, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Submits the signed commands in a signing bundle, in order.                 */
+/*                                                                            */
+/* Before each command is sent, Query Domain Attributes is used to check that */
+/* the domain and crypto module are those the bundle was prepared for and     */
+/* that the transaction counter of the command is the next one the domain     */
+/* expects.  A StaleTransactionCounterError is returned if it is not.         */
+/* Commands are marked as submitted as they complete, and the command output  */
+/* returned by the crypto module is saved with them, so the bundle should be  */
+/* saved again after an error.                                                */
+/*                                                                            */
+/* If the response to a command is lost after the command reached the crypto  */
+/* module, the command is marked with ResponseLost and ErrResponseLost is     */
+/* returned.  It is not known whether the command took effect, so the         */
+/* remaining commands are not sent, now or when the bundle is submitted       */
+/* again.  Query the crypto units and prepare a new bundle for the commands   */
+/* that are still needed.                                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation of the commands                   */
+/* common.Transport -- sends the commands to the crypto units                 */
+/* *SigningBundle -- the signed commands                                      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- the command output of each command in the signing bundle, in   */
+/*    the same order.  Entries are nil for commands that have not completed,  */
+/*    whose response was lost, or that return no output.  Also returned with  */
+/*    an error, for the commands that completed before it.                    */
+/* error -- reports a command without enough signatures, a stale transaction  */
+/*    counter, a lost response, or any error returned by the crypto module    */
+/*----------------------------------------------------------------------------*/
+func SubmitSigningBundleWithContext(ctx context.Context, tr common.Transport,
+	b *SigningBundle) ([][]byte, error) {
+
+	// Check all signatures are present before anything is sent
+	for i := 0; i < len(b.Commands); i++ {
+		command := b.Commands[i]
+		if !command.Submitted &&
+			len(command.SignerInfos) < command.SignaturesNeeded {
+			return b.outputs(), errors.New("Command " + strconv.Itoa(i+1) +
+				" in the signing bundle (" + command.Description + ") has " +
+				strconv.Itoa(len(command.SignerInfos)) + " of the " +
+				strconv.Itoa(command.SignaturesNeeded) +
+				" signatures needed.")
+		}
+	}
+
+	for i := 0; i < len(b.Commands); i++ {
+		command := &b.Commands[i]
+		if command.Submitted {
+			continue
+		}
+		if command.ResponseLost {
+			return b.outputs(), errors.New("The response to command " +
+				strconv.Itoa(i+1) + " in the signing bundle (" +
+				command.Description + ") was lost, so it is not known " +
+				"whether the command took effect.\nQuery the crypto units " +
+				"and prepare a new signing bundle for the commands that " +
+				"are still needed.")
+		}
+		de := command.Domain
+		adminBlockSeq, err := hex.DecodeString(command.AdminBlock)
+		if err != nil {
+			return b.outputs(), errors.New("Invalid admin block for " +
+				"command " + strconv.Itoa(i+1) + " in the signing bundle.")
+		}
+		var adminBlk AdminBlk
+		_, err = asn1.Decode(adminBlockSeq, &adminBlk)
+		if err != nil || len(adminBlk.DomainID) < 4 {
+			return b.outputs(), errors.New("Invalid admin block for " +
+				"command " + strconv.Itoa(i+1) + " in the signing bundle.")
+		}
+		signerInfo := make([]byte, 0)
+		for _, s := range command.SignerInfos {
+			one, err := hex.DecodeString(s)
+			if err != nil {
+				return b.outputs(), errors.New("Invalid SignerInfo for " +
+					"command " + strconv.Itoa(i+1) + " in the signing bundle.")
+			}
+			signerInfo = append(signerInfo, one...)
+		}
+
+		// Check the command is the next one the domain expects
+		_, adminRspBlk, err := QueryDomainAttributesWithContext(ctx, tr, de)
+		if err != nil {
+			return b.outputs(), err
+		}
+		if !common.ByteSlicesAreEqual(adminRspBlk.DomainID, adminBlk.DomainID) ||
+			!common.ByteSlicesAreEqual(adminRspBlk.ModuleID, adminBlk.ModuleID) {
+			return b.outputs(), errors.New("Command " + strconv.Itoa(i+1) +
+				" in the signing bundle cannot be submitted to domain " +
+				de.Location + ".\nThe signing bundle was prepared for a " +
+				"different crypto module or domain.")
+		}
+		expected := IncrementTransactionCounter(adminRspBlk.TransactionCounter)
+		if !common.ByteSlicesAreEqual(expected, adminBlk.TransactionCounter) {
+			return b.outputs(), &StaleTransactionCounterError{
+				Index:    i,
+				Location: de.Location,
+				Expected: command.TransactionCounter,
+				Current:  hex.EncodeToString(expected),
+			}
+		}
+
+		// Create the xcpAdminReq sequence
+		var adminReq AdminReq
+		adminReq.CmdID = FNID_ADMIN
+		adminReq.DomainID = adminBlk.DomainID[0:4]
+		adminReq.AdminBlock = adminBlockSeq
+		adminReq.SignerInfo = signerInfo
+
+		adminReqSeq, err := asn1.Encode(adminReq)
+		if err != nil {
+			return b.outputs(), err
+		}
+		htpRequestString := NewXPNUMRequest(de.GetCryptoModuleIndex(),
+			de.GetDomainIndex(), adminReqSeq)
+
+		htpResponseString, err := submitSignedHTPRequest(ctx, tr, de,
+			htpRequestString)
+		if err == ErrResponseLost {
+			// The command reached the crypto module and must not be
+			// submitted again, but it may have been rejected
+			command.ResponseLost = true
+		}
+		if err != nil {
+			return b.outputs(), err
+		}
+		adminRspBlk, err = buildAdminRspBlk(htpResponseString, de)
+		if err != nil {
+			return b.outputs(), err
+		}
+		command.Submitted = true
+		command.Output = hex.EncodeToString(adminRspBlk.CmdOutput)
+	}
+	return b.outputs(), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitSigningBundleWithContext, using the background context       */
+/*----------------------------------------------------------------------------*/
+func SubmitSigningBundle(tr common.Transport,
+	b *SigningBundle) ([][]byte, error) {
+
+	return SubmitSigningBundleWithContext(context.Background(), tr, b)
+}
+
+/** Returns the command output saved for each command in a signing bundle */
+func (b *SigningBundle) outputs() [][]byte {
+	outputs := make([][]byte, len(b.Commands))
+	for i := 0; i < len(b.Commands); i++ {
+		output, err := hex.DecodeString(b.Commands[i].Output)
+		if err == nil && len(output) > 0 {
+			outputs[i] = output
+		}
+	}
+	return outputs
+}
@@ -7,20 +7,21 @@
 //
 // Date          Initials        Description
 // 12/08/2020    CLH             T390301 - Add minimal touch functions
+// 10/18/2026    CLH             Initialize messages safely for concurrent use
 
 package ep11cmds
 
 import (
 	"strings"
+	"sync"
 )
 
 var msgMap map[string]string
 var msgInit bool = false
+var msgOnce sync.Once
 
 func GetErrorMsg(errorType string, returnCode string, reasonCode string) string {
-	if !msgInit {
-		initializeMsgs()
-	}
+	msgOnce.Do(initializeMsgs)
 	errorType = strings.TrimSpace(errorType)
 	returnCode = strings.TrimSpace(returnCode)
 	reasonCode = strings.TrimSpace(reasonCode)
@@ -44,9 +45,7 @@ func GetErrorMsg(errorType string, returnCode string, reasonCode string) string
 /* If no message is found, returns an empty string.                           */
 /*----------------------------------------------------------------------------*/
 func GetEP11ErrorMsg(returnCode string, reasonCode string) string {
-	if !msgInit {
-		initializeMsgs()
-	}
+	msgOnce.Do(initializeMsgs)
 	returnCode = strings.TrimSpace(returnCode)
 	reasonCode = strings.TrimSpace(reasonCode)
 	if reasonCode == "" {
@@ -7,10 +7,13 @@
 //
 // Date          Initials        Description
 // 05/12/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha512"
@@ -28,8 +31,8 @@ import (
 /* com.ibm.tke.model.xcp.XCPCryptoModuleClass                                 */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module             */
 /* DomainEntry -- identifies a domain assigned to the user.  The OA           */
 /*    certificate chain for the crypto module containing that domain is to    */
 /*    be verified.                                                            */
@@ -41,7 +44,8 @@ import (
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func VerifyCertificate(authToken string, urlStart string, de common.DomainEntry,
+func VerifyCertificateWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
 	certIndex uint32, aCertificate OACertificateX) error {
 
 	if aCertificate.HeaderTData != OA_NEW_CERT {
@@ -51,18 +55,57 @@ func VerifyCertificate(authToken string, urlStart string, de common.DomainEntry,
 	if aCertificate.BodyTPublic == OA_RSA {
 		return errors.New("The plug-in does not support OA certificates with an RSA public key")
 	} else if aCertificate.BodyTPublic == OA_ECC {
-		return verifyECCCertificate(authToken, urlStart, de, certIndex, aCertificate)
+		return verifyECCCertificate(ctx, tr, de, certIndex, aCertificate)
 	} else {
 		return errors.New("Unrecognized OA certificate key type")
 	}
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as VerifyCertificateWithContext, using the background context         */
+/*----------------------------------------------------------------------------*/
+func VerifyCertificateWithTransport(tr common.Transport, de common.DomainEntry,
+	certIndex uint32, aCertificate OACertificateX) error {
+
+	return VerifyCertificateWithContext(context.Background(), tr, de, certIndex,
+		aCertificate)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies an OA certificate returned by a CEX5P.                            */
+/*                                                                            */
+/* Adapted from a method of the same name in                                  */
+/* com.ibm.tke.model.xcp.XCPCryptoModuleClass                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies a domain assigned to the user.  The OA           */
+/*    certificate chain for the crypto module containing that domain is to    */
+/*    be verified.                                                            */
+/* certIndex -- index of the OA certificate to start with.  0 = currently     */
+/*    active epoch key, 1 = its parent, etc.  This method calls itself        */
+/*    recursively to read and verify the entire OA certificate chain.         */
+/* aCertificate -- the OA certificate to be verified                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func VerifyCertificate(authToken string, urlStart string, de common.DomainEntry,
+	certIndex uint32, aCertificate OACertificateX) error {
+
+	return VerifyCertificateWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, certIndex,
+		aCertificate)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Verifies an OA certificate containing an ECC public key.                   */
 /*                                                                            */
 /* Has the same inputs and outputs as the previous function.                  */
 /*----------------------------------------------------------------------------*/
-func verifyECCCertificate(authToken string, urlStart string, de common.DomainEntry,
+func verifyECCCertificate(ctx context.Context, tr common.Transport,
+	de common.DomainEntry,
 	certIndex uint32, aCertificate OACertificateX) error {
 
 	if common.ByteSlicesAreEqual(aCertificate.BodyCkoName, aCertificate.BodyParentName) {
@@ -72,7 +115,8 @@ func verifyECCCertificate(authToken string, urlStart string, de common.DomainEnt
 	var parentCert OACertificateX
 	var parentExists bool = false
 	var xbytes, ybytes []byte
-	parentCertData, err := QueryDeviceCertificate(authToken, urlStart, de, certIndex+1)
+	parentCertData, err := QueryDeviceCertificateWithContext(ctx, tr, de,
+		certIndex+1)
 	if err == nil {
 		parentExists = true
 		err = parentCert.Init(parentCertData)
@@ -137,7 +181,8 @@ func verifyECCCertificate(authToken string, urlStart string, de common.DomainEnt
 	// If a parent certificate was found, recursively call this function to
 	// verify the parent certificate
 	if parentExists {
-		return VerifyCertificate(authToken, urlStart, de, certIndex+1, parentCert)
+		return VerifyCertificateWithContext(ctx, tr, de, certIndex+1,
+			parentCert)
 	} else {
 		return nil
 	}
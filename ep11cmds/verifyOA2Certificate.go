@@ -7,10 +7,14 @@
 //
 // Date          Initials        Description
 // 05/12/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Accept root keys attached to the transport
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha512"
@@ -28,8 +32,10 @@ import (
 /* com.ibm.tke.model.xcp.XCPCryptoModuleClass                                 */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module.            */
+/*    OA root keys attached to it using common.WithOARootKeys are trusted     */
+/*    in addition to the IBM root keys.                                       */
 /* DomainEntry -- identifies a domain assigned to the user.  The OA           */
 /*    certificate chain for the crypto module containing that domain is to    */
 /*    be verified.                                                            */
@@ -41,7 +47,8 @@ import (
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func VerifyOA2Certificate(authToken string, urlStart string, de common.DomainEntry,
+func VerifyOA2CertificateWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
 	certIndex uint32, aCertificate OA2CertificateX) error {
 
 	if common.ByteSlicesAreEqual(aCertificate.MetaDataSubjectSKI, aCertificate.MetaDataSignerSKI) {
@@ -51,7 +58,8 @@ func VerifyOA2Certificate(authToken string, urlStart string, de common.DomainEnt
 	var parentCert OA2CertificateX
 	parentExists := false
 	var xbytes, ybytes []byte
-	parentCertData, err := QueryDeviceCertificate(authToken, urlStart, de, certIndex+1)
+	parentCertData, err := QueryDeviceCertificateWithContext(ctx, tr, de,
+		certIndex+1)
 	if err == nil {
 		parentExists = true
 		err = parentCert.Init(parentCertData)
@@ -123,7 +131,13 @@ func VerifyOA2Certificate(authToken string, urlStart string, de common.DomainEnt
 					panic(err)
 				}
 			} else {
-				return errors.New("Unrecognized IBM root key in OA certificate chain.")
+				// Check root keys attached to the transport
+				var found bool
+				xbytes, ybytes, found = findOARootKey(tr,
+					aCertificate.MetaDataSignerSKI)
+				if !found {
+					return errors.New("Unrecognized IBM root key in OA certificate chain.")
+				}
 			}
 		} else {
 			return err
@@ -150,8 +164,48 @@ func VerifyOA2Certificate(authToken string, urlStart string, de common.DomainEnt
 	// If a parent certificate was found, recursively call this function to
 	// verify the parent certificate
 	if parentExists {
-		return VerifyOA2Certificate(authToken, urlStart, de, certIndex+1, parentCert)
+		return VerifyOA2CertificateWithContext(ctx, tr, de, certIndex+1,
+			parentCert)
 	} else {
 		return nil
 	}
 }
+
+/*----------------------------------------------------------------------------*/
+/* Same as VerifyOA2CertificateWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func VerifyOA2CertificateWithTransport(tr common.Transport,
+	de common.DomainEntry, certIndex uint32,
+	aCertificate OA2CertificateX) error {
+
+	return VerifyOA2CertificateWithContext(context.Background(), tr, de,
+		certIndex, aCertificate)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies an OA certificate returned by a CEX6P or CEX7P.                   */
+/*                                                                            */
+/* Adapted from a method of the same name in                                  */
+/* com.ibm.tke.model.xcp.XCPCryptoModuleClass                                 */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies a domain assigned to the user.  The OA           */
+/*    certificate chain for the crypto module containing that domain is to    */
+/*    be verified.                                                            */
+/* certIndex -- index of the OA certificate to start with.  0 = currently     */
+/*    active epoch key, 1 = its parent, etc.  This method calls itself        */
+/*    recursively to read and verify the entire OA certificate chain.         */
+/* aCertificate -- the OA certificate to be verified                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func VerifyOA2Certificate(authToken string, urlStart string, de common.DomainEntry,
+	certIndex uint32, aCertificate OA2CertificateX) error {
+
+	return VerifyOA2CertificateWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, certIndex,
+		aCertificate)
+}
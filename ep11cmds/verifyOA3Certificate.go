@@ -7,10 +7,14 @@
 //
 // Date          Initials        Description
 // 01/09/2025    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Accept root keys attached to the transport
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
 
 package ep11cmds
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha512"
@@ -31,8 +35,10 @@ import (
 /* algorithms, only the ECC signature in a certificate can be verified.       */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- the authority token to use for the request                    */
-/* urlStart -- the base URL to use for the request                            */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto module.            */
+/*    OA root keys attached to it using common.WithOARootKeys are trusted     */
+/*    in addition to the IBM root keys.                                       */
 /* DomainEntry -- identifies a domain assigned to the user.  The OA           */
 /*    certificate chain for the crypto module containing that domain is to    */
 /*    be verified.                                                            */
@@ -44,7 +50,8 @@ import (
 /* Outputs:                                                                   */
 /* error -- reports any errors for the operation                              */
 /*----------------------------------------------------------------------------*/
-func VerifyOA3Certificate(authToken string, urlStart string, de common.DomainEntry,
+func VerifyOA3CertificateWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
 	certIndex uint32, aCertificate OA3CertificateX) error {
 
 	if common.ByteSlicesAreEqual(aCertificate.EccKeyMetaDataSubjectSKI,
@@ -55,7 +62,8 @@ func VerifyOA3Certificate(authToken string, urlStart string, de common.DomainEnt
 	var parentCert OA3CertificateX
 	parentExists := false
 	var xbytes, ybytes []byte
-	parentCertData, err := QueryDeviceCertificate(authToken, urlStart, de, certIndex+1)
+	parentCertData, err := QueryDeviceCertificateWithContext(ctx, tr, de,
+		certIndex+1)
 	if err == nil {
 		parentExists = true
 		err = parentCert.Init(parentCertData)
@@ -102,7 +110,13 @@ func VerifyOA3Certificate(authToken string, urlStart string, de common.DomainEnt
 					panic(err)
 				}
 			} else {
-				return errors.New("Unrecognized IBM root key in OA certificate chain.")
+				// Check root keys attached to the transport
+				var found bool
+				xbytes, ybytes, found = findOARootKey(tr,
+					aCertificate.SignerInfoEccSignerSKI)
+				if !found {
+					return errors.New("Unrecognized IBM root key in OA certificate chain.")
+				}
 			}
 	   	} else {
 	   		return err
@@ -130,8 +144,52 @@ func VerifyOA3Certificate(authToken string, urlStart string, de common.DomainEnt
 	// If a parent certificate was found, recursively call this function to
 	// verify the parent certificate
 	if parentExists {
-		return VerifyOA3Certificate(authToken, urlStart, de, certIndex+1, parentCert)
+		return VerifyOA3CertificateWithContext(ctx, tr, de, certIndex+1,
+			parentCert)
 	} else {
 		return nil
 	}
 }
+
+/*----------------------------------------------------------------------------*/
+/* Same as VerifyOA3CertificateWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func VerifyOA3CertificateWithTransport(tr common.Transport,
+	de common.DomainEntry, certIndex uint32,
+	aCertificate OA3CertificateX) error {
+
+	return VerifyOA3CertificateWithContext(context.Background(), tr, de,
+		certIndex, aCertificate)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies an OA certificate returned by a CEX8P.                            */
+/*                                                                            */
+/* Adapted from a method of the same name in                                  */
+/* com.ibm.tke.model.xcp.XCPCryptoModuleClass                                 */
+/*                                                                            */
+/* OA certificates for the 4770 contain both ECC and Dilithium public keys    */
+/* and signatures.  Without Go language support for Dilithium cryptographic   */
+/* algorithms, only the ECC signature in a certificate can be verified.       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* authToken -- the authority token to use for the request                    */
+/* urlStart -- the base URL to use for the request                            */
+/* DomainEntry -- identifies a domain assigned to the user.  The OA           */
+/*    certificate chain for the crypto module containing that domain is to    */
+/*    be verified.                                                            */
+/* certIndex -- index of the OA certificate to start with.  0 = currently     */
+/*    active epoch key, 1 = its parent, etc.  This method calls itself        */
+/*    recursively to read and verify the entire OA certificate chain.         */
+/* aCertificate -- the OA certificate to be verified                          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func VerifyOA3Certificate(authToken string, urlStart string, de common.DomainEntry,
+	certIndex uint32, aCertificate OA3CertificateX) error {
+
+	return VerifyOA3CertificateWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, certIndex,
+		aCertificate)
+}
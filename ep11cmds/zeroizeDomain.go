@@ -7,13 +7,56 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Adapt for TKE SDK
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Sign using common.Signer
+// 10/18/2026    CLH             Succeed when a lost response took effect
+// 10/18/2026    CLH             Keep the authToken and urlStart variants
+// 10/18/2026    CLH             Close the signers created from signature keys
 
 package ep11cmds
 
 import (
+	"context"
+
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 )
 
+/*----------------------------------------------------------------------------*/
+/* Zeroizes the domain                                                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests.  Once         */
+/*    the signed command is sent its response is awaited even if ctx          */
+/*    is cancelled, so the outcome of the command is always known.            */
+/* tr -- the transport used to send requests to the crypto module             */
+/* DomainEntry -- identifies the domain to be zeroized                        */
+/* []common.Signer -- the signature keys to use to sign the command           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any errors for the operation                              */
+/*----------------------------------------------------------------------------*/
+func ZeroizeDomainWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
+
+	htpRequestString, err := ZeroizeDomainReqWithContext(ctx, tr, de,
+		signers)
+	if err != nil {
+		return err
+	}
+
+	return submitSignedCommand(ctx, tr, de, htpRequestString)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ZeroizeDomainWithContext, using the background context             */
+/*----------------------------------------------------------------------------*/
+func ZeroizeDomainWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) error {
+
+	return ZeroizeDomainWithContext(context.Background(), tr, de, signers)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Zeroizes the domain                                                        */
 /*                                                                            */
@@ -31,33 +74,21 @@ import (
 func ZeroizeDomain(authToken string, urlStart string, de common.DomainEntry,
 	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
 
-	htpRequestString, err := ZeroizeDomainReq(authToken, urlStart, de,
-		sigkeys, sigkeySkis, sigkeyTokens)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
-
-	req := common.CreatePostHsmsRequest(
-		authToken, urlStart, de.Crypto_instance_id, de.Hsm_id, htpRequestString)
-
-	htpResponseString, err := common.SubmitHTPRequest(req)
-	if err != nil {
-		return err
-	}
-
-	_, err = buildAdminRspBlk(htpResponseString, de)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	defer common.CloseSigners(signers)
+	return ZeroizeDomainWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
 }
 
 /*----------------------------------------------------------------------------*/
 /* Creates the HTPRequest for zeroizing a domain                              */
 /*----------------------------------------------------------------------------*/
-func ZeroizeDomainReq(authToken string, urlStart string, de common.DomainEntry,
-	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+func ZeroizeDomainReqWithContext(ctx context.Context,
+	tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
 
 	var adminBlk AdminBlk
 	adminBlk.CmdID = XCP_ADM_DOM_ZEROIZE
@@ -65,6 +96,29 @@ func ZeroizeDomainReq(authToken string, urlStart string, de common.DomainEntry,
 	// module ID filled in later
 	// transaction counter filled in later
 	// no input parameters
-	return CreateSignedHTPRequest(authToken, urlStart, de, adminBlk, sigkeys,
-		sigkeySkis, sigkeyTokens)
+	return CreateSignedHTPRequestWithContext(ctx, tr, de, adminBlk, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ZeroizeDomainReqWithContext, using the background context          */
+/*----------------------------------------------------------------------------*/
+func ZeroizeDomainReqWithTransport(tr common.Transport, de common.DomainEntry,
+	signers []common.Signer) (string, error) {
+
+	return ZeroizeDomainReqWithContext(context.Background(), tr, de, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the HTPRequest for zeroizing a domain                              */
+/*----------------------------------------------------------------------------*/
+func ZeroizeDomainReq(authToken string, urlStart string, de common.DomainEntry,
+	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) (string, error) {
+
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
+	if err != nil {
+		return "", err
+	}
+	defer common.CloseSigners(signers)
+	return ZeroizeDomainReqWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), de, signers)
 }
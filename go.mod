@@ -4,5 +4,6 @@ go 1.13
 
 require (
 	github.com/Logicalis/asn1 v0.0.0-20190312173541-d60463189a56
+	github.com/miekg/pkcs11 v1.1.1
 	golang.org/x/crypto v0.0.0-20210711020723-a769d52b0f97
 )
@@ -0,0 +1,172 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+/*----------------------------------------------------------------------------*/
+/* Package htp encodes and decodes the messages exchanged with the TKE        */
+/* catcher program through the /hsms endpoints of the TKE REST API.           */
+/*                                                                            */
+/* An HTPRequest carries an EP11 request CPRB to a crypto module, and an      */
+/* HTPResponse carries error information and, if there was no error, the EP11 */
+/* response CPRB.  Each message type has a struct with a Marshal method and   */
+/* an Unmarshal function.  Unmarshal functions check every field and return   */
+/* a *SyntaxError describing the first problem found, so malformed messages   */
+/* are reported rather than causing a panic.                                  */
+/*----------------------------------------------------------------------------*/
+package htp
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+/** Length of the CPRB header that precedes the payload */
+const CPRB_HEADER_LENGTH = 32
+
+/** CPRB version used for EP11 requests */
+const CPRB_VERSION = 4
+
+/** CPRB flags used for EP11 requests */
+const CPRB_FLAGS = 0x80
+
+/** CPRB subtype used for EP11 requests */
+var CPRB_SUBTYPE = [2]byte{'T', '4'}
+
+/** Fields reported in a SyntaxError */
+const (
+	FIELD_LENGTH              = "length"
+	FIELD_REQUEST_TYPE        = "request type"
+	FIELD_RULE                = "rule"
+	FIELD_CRYPTO_MODULE_INDEX = "crypto module index"
+	FIELD_RESERVED            = "reserved field"
+	FIELD_ERROR_INFORMATION   = "error information"
+	FIELD_CPRB                = "CPRB"
+)
+
+/** Describes the first problem found when decoding a message */
+type SyntaxError struct {
+	Message string // the kind of message being decoded
+	Field   string // one of the FIELD_ constants
+	Reason  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "Invalid " + e.Message + ", " + e.Field + ": " + e.Reason
+}
+
+/*----------------------------------------------------------------------------*/
+/* An EP11 CPRB: a 32-byte header followed by a payload holding an ASN.1      */
+/* encoded EP11 request or response.                                          */
+/*                                                                            */
+/* The header length and payload length fields are not stored.  Marshal       */
+/* computes them, and Unmarshal checks them.                                  */
+/*----------------------------------------------------------------------------*/
+type CPRB struct {
+	Version     byte
+	Reserved1   [2]byte
+	Flags       byte
+	Subtype     [2]byte
+	PartitionID uint32
+	DomainIndex uint32
+	ReturnCode  uint32 // high 2 bytes return code, low 2 bytes reason code
+	Reserved2   [8]byte
+	Payload     []byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the CPRB for an EP11 request                                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* domainIndex -- index of the target domain in the crypto module             */
+/* payload -- the ASN.1 encoded EP11 request                                  */
+/*----------------------------------------------------------------------------*/
+func NewCPRB(domainIndex int, payload []byte) CPRB {
+	return CPRB{
+		Version:     CPRB_VERSION,
+		Flags:       CPRB_FLAGS,
+		Subtype:     CPRB_SUBTYPE,
+		DomainIndex: uint32(domainIndex),
+		Payload:     payload,
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the return code from the high two bytes of the return code field.  */
+/* The TKE host transaction program reports errors this way.                  */
+/*----------------------------------------------------------------------------*/
+func (c CPRB) GetReturnCode() int {
+	return int(c.ReturnCode >> 16)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the reason code from the low two bytes of the return code field    */
+/*----------------------------------------------------------------------------*/
+func (c CPRB) GetReasonCode() int {
+	return int(c.ReturnCode & 0xFFFF)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encodes the CPRB as bytes                                                  */
+/*----------------------------------------------------------------------------*/
+func (c CPRB) Marshal() []byte {
+	data := make([]byte, CPRB_HEADER_LENGTH, CPRB_HEADER_LENGTH+len(c.Payload))
+	binary.BigEndian.PutUint16(data[0:2], CPRB_HEADER_LENGTH)
+	data[2] = c.Version
+	copy(data[3:5], c.Reserved1[:])
+	data[5] = c.Flags
+	copy(data[6:8], c.Subtype[:])
+	binary.BigEndian.PutUint32(data[8:12], c.PartitionID)
+	binary.BigEndian.PutUint32(data[12:16], c.DomainIndex)
+	binary.BigEndian.PutUint32(data[16:20], c.ReturnCode)
+	copy(data[20:28], c.Reserved2[:])
+	binary.BigEndian.PutUint32(data[28:32], uint32(len(c.Payload)))
+	return append(data, c.Payload...)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes a CPRB.                                                            */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* data -- the encoded CPRB                                                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* CPRB -- the decoded CPRB.  The payload refers to the input data.           */
+/* error -- a *SyntaxError if the header is incomplete, the header length is  */
+/*    not 32, or the payload length does not match the data                   */
+/*----------------------------------------------------------------------------*/
+func UnmarshalCPRB(data []byte) (CPRB, error) {
+	var c CPRB
+	if len(data) < CPRB_HEADER_LENGTH {
+		return c, &SyntaxError{"CPRB", FIELD_LENGTH, "only " +
+			strconv.Itoa(len(data)) + " bytes, header needs " +
+			strconv.Itoa(CPRB_HEADER_LENGTH)}
+	}
+	headerLength := int(binary.BigEndian.Uint16(data[0:2]))
+	if headerLength != CPRB_HEADER_LENGTH {
+		return c, &SyntaxError{"CPRB", FIELD_LENGTH, "header length " +
+			strconv.Itoa(headerLength)}
+	}
+	payloadLength := uint64(binary.BigEndian.Uint32(data[28:32]))
+	if payloadLength != uint64(len(data)-CPRB_HEADER_LENGTH) {
+		return c, &SyntaxError{"CPRB", FIELD_LENGTH, "payload length " +
+			strconv.FormatUint(payloadLength, 10) + " but " +
+			strconv.Itoa(len(data)-CPRB_HEADER_LENGTH) +
+			" bytes follow the header"}
+	}
+	c.Version = data[2]
+	copy(c.Reserved1[:], data[3:5])
+	c.Flags = data[5]
+	copy(c.Subtype[:], data[6:8])
+	c.PartitionID = binary.BigEndian.Uint32(data[8:12])
+	c.DomainIndex = binary.BigEndian.Uint32(data[12:16])
+	c.ReturnCode = binary.BigEndian.Uint32(data[16:20])
+	copy(c.Reserved2[:], data[20:28])
+	c.Payload = data[CPRB_HEADER_LENGTH:]
+	return c, nil
+}
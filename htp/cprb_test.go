@@ -0,0 +1,60 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package htp_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+)
+
+/** CPRBs decode to the fields they were encoded from */
+func TestCPRB(t *testing.T) {
+	data, _ := hex.DecodeString(goldenCPRB)
+	cprb, err := htp.UnmarshalCPRB(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cprb.Marshal(), data) {
+		t.Errorf("CPRB changed when decoded and encoded again")
+	}
+
+	// The return code field holds a return code and a reason code
+	cprb.ReturnCode = 0x000C0021
+	if cprb.GetReturnCode() != 12 || cprb.GetReasonCode() != 33 {
+		t.Errorf("Return code %d and reason code %d, expected 12 and 33",
+			cprb.GetReturnCode(), cprb.GetReasonCode())
+	}
+}
+
+/** Incomplete or inconsistent CPRBs are rejected */
+func TestUnmarshalCPRBErrors(t *testing.T) {
+	data, _ := hex.DecodeString(goldenCPRB)
+	wrongHeaderLength := append([]byte(nil), data...)
+	wrongHeaderLength[1] = 31
+	tests := [][]byte{
+		nil,
+		data[0:31],
+		data[0 : len(data)-1],
+		append(append([]byte(nil), data...), 0),
+		wrongHeaderLength,
+	}
+	for _, test := range tests {
+		_, err := htp.UnmarshalCPRB(test)
+		syntaxErr, ok := err.(*htp.SyntaxError)
+		if !ok || syntaxErr.Field != htp.FIELD_LENGTH {
+			t.Errorf("UnmarshalCPRB(%X) returned %v, expected a length "+
+				"error", test, err)
+		}
+	}
+}
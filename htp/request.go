@@ -0,0 +1,123 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package htp
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+/** Separates the fields of HTPRequests and HTPResponses */
+const DELIMITER = ";"
+
+/** Request type field of an HTPRequest for a crypto module */
+const REQUEST_TYPE = "PCI request"
+
+/** Rule for sending an EP11 request CPRB, padded to 8 characters */
+const RULE_XPNUM = "XPNUM   "
+
+/** Contents of the reserved field of an HTPRequest */
+const RESERVED_FIELD = "        "
+
+/*----------------------------------------------------------------------------*/
+/* An HTPRequest using the XPNUM rule, which sends an EP11 request CPRB to a  */
+/* crypto module.                                                             */
+/*                                                                            */
+/* The encoded form is                                                        */
+/*   <length>;PCI request;XPNUM   ;<crypto module index>;        ;<CPRB>      */
+/* where the CPRB is in uppercase hexadecimal and the length counts the       */
+/* characters following the length field.                                     */
+/*----------------------------------------------------------------------------*/
+type HTPRequest struct {
+	CryptoModuleIndex int
+	CPRB              CPRB
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTPRequest for an EP11 request                                  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* cryptoModuleIndex -- index of the crypto module on the host system         */
+/* domainIndex -- index of the target domain in the crypto module             */
+/* payload -- the ASN.1 encoded EP11 request                                  */
+/*----------------------------------------------------------------------------*/
+func NewHTPRequest(cryptoModuleIndex int, domainIndex int,
+	payload []byte) HTPRequest {
+
+	return HTPRequest{
+		CryptoModuleIndex: cryptoModuleIndex,
+		CPRB:              NewCPRB(domainIndex, payload),
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encodes the HTPRequest as a string                                         */
+/*----------------------------------------------------------------------------*/
+func (r HTPRequest) Marshal() string {
+	rest := DELIMITER + REQUEST_TYPE + DELIMITER + RULE_XPNUM + DELIMITER +
+		strconv.Itoa(r.CryptoModuleIndex) + DELIMITER + RESERVED_FIELD +
+		DELIMITER + strings.ToUpper(hex.EncodeToString(r.CPRB.Marshal()))
+	return strconv.Itoa(len(rest)) + rest
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes an HTPRequest.                                                     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* s -- the encoded HTPRequest                                                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* HTPRequest -- the decoded HTPRequest                                       */
+/* error -- a *SyntaxError naming the first field found to be invalid         */
+/*----------------------------------------------------------------------------*/
+func UnmarshalHTPRequest(s string) (HTPRequest, error) {
+	var r HTPRequest
+	const message = "HTPRequest"
+
+	parts := strings.Split(s, DELIMITER)
+	if len(parts) != 6 {
+		return r, &SyntaxError{message, FIELD_LENGTH, strconv.Itoa(len(parts)) +
+			" fields found, 6 expected"}
+	}
+	length, err := strconv.Atoi(parts[0])
+	if err != nil || length != len(s)-len(parts[0]) {
+		return r, &SyntaxError{message, FIELD_LENGTH, "length field " +
+			strconv.Quote(parts[0]) + " but " +
+			strconv.Itoa(len(s)-len(parts[0])) + " characters follow it"}
+	}
+	if parts[1] != REQUEST_TYPE {
+		return r, &SyntaxError{message, FIELD_REQUEST_TYPE,
+			strconv.Quote(parts[1])}
+	}
+	if parts[2] != RULE_XPNUM {
+		return r, &SyntaxError{message, FIELD_RULE, strconv.Quote(parts[2]) +
+			" is not supported"}
+	}
+	r.CryptoModuleIndex, err = strconv.Atoi(parts[3])
+	if err != nil || r.CryptoModuleIndex < 0 {
+		return r, &SyntaxError{message, FIELD_CRYPTO_MODULE_INDEX,
+			strconv.Quote(parts[3]) + " is not a valid index"}
+	}
+	if parts[4] != RESERVED_FIELD {
+		return r, &SyntaxError{message, FIELD_RESERVED,
+			strconv.Quote(parts[4])}
+	}
+	cprbBytes, err := hex.DecodeString(parts[5])
+	if err != nil {
+		return r, &SyntaxError{message, FIELD_CPRB, "invalid hexadecimal data"}
+	}
+	r.CPRB, err = UnmarshalCPRB(cprbBytes)
+	if err != nil {
+		return r, &SyntaxError{message, FIELD_CPRB, err.Error()}
+	}
+	return r, nil
+}
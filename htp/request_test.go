@@ -0,0 +1,149 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package htp_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+)
+
+/** Payload used in the golden messages */
+var goldenPayload = []byte{0x30, 0x03, 0x02, 0x01, 0x05}
+
+/** CPRB for domain 14 holding goldenPayload, in hexadecimal */
+const goldenCPRB = "0020" + "04" + "0000" + "80" + "5434" + "00000000" +
+	"0000000E" + "00000000" + "0000000000000000" + "00000005" + "3003020105"
+
+/** HTPRequest for crypto module 3 holding goldenCPRB */
+const goldenHTPRequest = "107;PCI request;XPNUM   ;3;        ;" + goldenCPRB
+
+/*----------------------------------------------------------------------------*/
+/* The HTPRequest builder used by ep11cmds before the htp package was added,  */
+/* kept to check that the encoding has not changed                            */
+/*----------------------------------------------------------------------------*/
+func legacyXPNUMRequest(cryptoModuleIndex int, domainIndex int,
+	sequence []byte) string {
+
+	cprb := []byte{0, 32, 4, 0, 0, 0x80, 'T', '4', 0, 0, 0, 0}
+	cprb = append(cprb, byte(domainIndex>>24), byte(domainIndex>>16),
+		byte(domainIndex>>8), byte(domainIndex))
+	cprb = append(cprb, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	cprb = append(cprb, byte(len(sequence)>>24), byte(len(sequence)>>16),
+		byte(len(sequence)>>8), byte(len(sequence)))
+	cprb = append(cprb, sequence...)
+
+	reqHeader1 := ";PCI request;XPNUM   ;"
+	cmiString := strconv.Itoa(cryptoModuleIndex)
+	reqHeader2 := ";        ;"
+	cprbString := strings.ToUpper(hex.EncodeToString(cprb))
+	reqLength := len(reqHeader1) + len(cmiString) + len(reqHeader2) +
+		len(cprbString)
+	return strconv.Itoa(reqLength) + reqHeader1 + cmiString + reqHeader2 +
+		cprbString
+}
+
+/** HTPRequests are encoded exactly as before */
+func TestHTPRequestGolden(t *testing.T) {
+	request := htp.NewHTPRequest(3, 14, goldenPayload).Marshal()
+	if request != goldenHTPRequest {
+		t.Errorf("Marshal returned\n%s\nexpected\n%s", request,
+			goldenHTPRequest)
+	}
+	request = ep11cmds.NewXPNUMRequest(3, 14, goldenPayload)
+	if request != goldenHTPRequest {
+		t.Errorf("NewXPNUMRequest returned\n%s\nexpected\n%s", request,
+			goldenHTPRequest)
+	}
+	cprb := strings.ToUpper(hex.EncodeToString(
+		ep11cmds.NewCPRB(14, goldenPayload)))
+	if cprb != goldenCPRB {
+		t.Errorf("NewCPRB returned %s, expected %s", cprb, goldenCPRB)
+	}
+
+	payloads := [][]byte{nil, goldenPayload, bytes.Repeat([]byte{0xA5}, 300),
+		bytes.Repeat([]byte{0x5A}, 70000)}
+	for _, index := range []int{0, 3, 12, 255} {
+		for _, payload := range payloads {
+			expected := legacyXPNUMRequest(index, index+1, payload)
+			request := htp.NewHTPRequest(index, index+1, payload).Marshal()
+			if request != expected {
+				t.Errorf("HTPRequest for crypto module %d with a %d byte "+
+					"payload differs from the earlier encoding", index,
+					len(payload))
+			}
+		}
+	}
+}
+
+/** Decoding the golden HTPRequest recovers its fields */
+func TestUnmarshalHTPRequest(t *testing.T) {
+	request, err := htp.UnmarshalHTPRequest(goldenHTPRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.CryptoModuleIndex != 3 || request.CPRB.DomainIndex != 14 ||
+		!bytes.Equal(request.CPRB.Payload, goldenPayload) ||
+		request.CPRB.Version != htp.CPRB_VERSION ||
+		request.CPRB.Flags != htp.CPRB_FLAGS ||
+		request.CPRB.Subtype != htp.CPRB_SUBTYPE {
+		t.Errorf("Unexpected HTPRequest %+v", request)
+	}
+	if request.Marshal() != goldenHTPRequest {
+		t.Errorf("HTPRequest changed when decoded and encoded again")
+	}
+}
+
+/** Malformed HTPRequests are reported with the field at fault */
+func TestUnmarshalHTPRequestErrors(t *testing.T) {
+	tests := []struct {
+		request string
+		field   string
+	}{
+		{"", htp.FIELD_LENGTH},
+		{"10" + goldenHTPRequest[3:], htp.FIELD_LENGTH},
+		{goldenHTPRequest + ";", htp.FIELD_LENGTH},
+		{strings.Replace(goldenHTPRequest, "PCI request", "PCI requesT", 1),
+			htp.FIELD_REQUEST_TYPE},
+		{strings.Replace(goldenHTPRequest, "XPNUM   ", "XPMAX   ", 1),
+			htp.FIELD_RULE},
+		{strings.Replace(goldenHTPRequest, ";3;", ";X;", 1),
+			htp.FIELD_CRYPTO_MODULE_INDEX},
+		{strings.Replace(goldenHTPRequest, ";        ;", ";       X;", 1),
+			htp.FIELD_RESERVED},
+		{goldenHTPRequest[0:len(goldenHTPRequest)-1] + "G",
+			htp.FIELD_CPRB},
+		// Payload length larger than the data that follows
+		{strings.Replace(goldenHTPRequest, "00000005", "00000006", 1),
+			htp.FIELD_CPRB},
+		// Truncated CPRB header
+		{"63;PCI request;XPNUM   ;3;        ;" + goldenCPRB[0:30],
+			htp.FIELD_CPRB},
+	}
+	for _, test := range tests {
+		_, err := htp.UnmarshalHTPRequest(test.request)
+		syntaxErr, ok := err.(*htp.SyntaxError)
+		if !ok {
+			t.Errorf("UnmarshalHTPRequest(%q) returned %v, expected a "+
+				"SyntaxError", test.request, err)
+			continue
+		}
+		if syntaxErr.Field != test.field {
+			t.Errorf("UnmarshalHTPRequest(%q) reported field %q, "+
+				"expected %q", test.request, syntaxErr.Field, test.field)
+		}
+	}
+}
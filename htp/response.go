@@ -0,0 +1,238 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Reject numbers too wide for their fields
+
+package htp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/** Length of the error information section of an HTPResponse */
+const ERROR_INFO_LENGTH = 156
+
+/** Widths of the fields in the error information section */
+const (
+	errorTypeWidth     = 2
+	returnCodeWidth    = 8
+	reasonCodeWidth    = 8
+	programIDWidth     = 8
+	errorLocationWidth = 30
+	errorTextWidth     = 100
+)
+
+/*----------------------------------------------------------------------------*/
+/* The error information section of an HTPResponse.  An ErrorType of 0 means  */
+/* the TKE catcher program found no error.                                    */
+/*                                                                            */
+/* Text fields are padded with blanks when encoded and have trailing blanks   */
+/* removed when decoded.  Text longer than its field is truncated.            */
+/*----------------------------------------------------------------------------*/
+type ErrorInfo struct {
+	ErrorType     int
+	ReturnCode    int
+	ReasonCode    int
+	ProgramID     string
+	ErrorLocation string
+	ErrorText     string
+}
+
+/*----------------------------------------------------------------------------*/
+/* An HTPResponse.                                                            */
+/*                                                                            */
+/* The encoded form is                                                        */
+/*   <length>;<error information>[;<CPRB>]                                    */
+/* where the error information is 156 characters and the CPRB, present when   */
+/* the request was processed, is in uppercase hexadecimal.                    */
+/*----------------------------------------------------------------------------*/
+type HTPResponse struct {
+	ErrorInfo ErrorInfo
+	CPRB      *CPRB // nil if the response has no CPRB
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTPResponse containing an EP11 response CPRB                    */
+/*----------------------------------------------------------------------------*/
+func NewHTPResponse(cprb CPRB) HTPResponse {
+	return HTPResponse{CPRB: &cprb}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an HTPResponse reporting an error found by the TKE catcher program */
+/*----------------------------------------------------------------------------*/
+func NewHTPErrorResponse(errorType int, returnCode int, programID string,
+	errorText string) HTPResponse {
+
+	return HTPResponse{ErrorInfo: ErrorInfo{
+		ErrorType:  errorType,
+		ReturnCode: returnCode,
+		ProgramID:  programID,
+		ErrorText:  errorText,
+	}}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encodes the error information section.  Returns an error if a number does  */
+/* not fit in its field: the error type in 2 characters, and the return and   */
+/* reason codes in 8 characters each.                                         */
+/*----------------------------------------------------------------------------*/
+func (e ErrorInfo) Marshal() (string, error) {
+	var errorType string
+	if e.ErrorType == 0 {
+		errorType = "00"
+	} else {
+		errorType = fmt.Sprintf("%2d", e.ErrorType)
+	}
+	returnCode := fmt.Sprintf("%8d", e.ReturnCode)
+	reasonCode := fmt.Sprintf("%8d", e.ReasonCode)
+	if len(errorType) != errorTypeWidth {
+		return "", errors.New("Error type " + strconv.Itoa(e.ErrorType) +
+			" does not fit in " + strconv.Itoa(errorTypeWidth) + " characters")
+	}
+	if len(returnCode) != returnCodeWidth {
+		return "", errors.New("Return code " + strconv.Itoa(e.ReturnCode) +
+			" does not fit in " + strconv.Itoa(returnCodeWidth) + " characters")
+	}
+	if len(reasonCode) != reasonCodeWidth {
+		return "", errors.New("Reason code " + strconv.Itoa(e.ReasonCode) +
+			" does not fit in " + strconv.Itoa(reasonCodeWidth) + " characters")
+	}
+	return errorType + returnCode + reasonCode +
+		padText(e.ProgramID, programIDWidth) +
+		padText(e.ErrorLocation, errorLocationWidth) +
+		padText(e.ErrorText, errorTextWidth), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encodes the HTPResponse as a string.  Returns an error if the error        */
+/* information cannot be encoded.                                             */
+/*----------------------------------------------------------------------------*/
+func (r HTPResponse) Marshal() (string, error) {
+	errorInfo, err := r.ErrorInfo.Marshal()
+	if err != nil {
+		return "", err
+	}
+	rest := DELIMITER + errorInfo
+	if r.CPRB != nil {
+		rest += DELIMITER + strings.ToUpper(hex.EncodeToString(r.CPRB.Marshal()))
+	}
+	return strconv.Itoa(len(rest)) + rest, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes an HTPResponse.                                                    */
+/*                                                                            */
+/* The error information is read by position rather than by splitting on the  */
+/* delimiter, since the error text may contain the delimiter.  The length     */
+/* field must be a number, but as in earlier releases its value is not        */
+/* compared with the length of the response.                                  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* s -- the encoded HTPResponse                                               */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* HTPResponse -- the decoded HTPResponse                                     */
+/* error -- a *SyntaxError naming the first field found to be invalid         */
+/*----------------------------------------------------------------------------*/
+func UnmarshalHTPResponse(s string) (HTPResponse, error) {
+	var r HTPResponse
+	const message = "HTPResponse"
+
+	delimiterIndex := strings.Index(s, DELIMITER)
+	if delimiterIndex < 0 {
+		return r, &SyntaxError{message, FIELD_ERROR_INFORMATION, "not found"}
+	}
+	_, err := strconv.Atoi(s[0:delimiterIndex])
+	if err != nil {
+		return r, &SyntaxError{message, FIELD_LENGTH,
+			strconv.Quote(s[0:delimiterIndex]) + " is not a number"}
+	}
+	rest := s[delimiterIndex+1:]
+	if len(rest) < ERROR_INFO_LENGTH {
+		return r, &SyntaxError{message, FIELD_ERROR_INFORMATION,
+			strconv.Itoa(len(rest)) + " characters, " +
+				strconv.Itoa(ERROR_INFO_LENGTH) + " expected"}
+	}
+	r.ErrorInfo, err = unmarshalErrorInfo(rest[0:ERROR_INFO_LENGTH])
+	if err != nil {
+		return r, err
+	}
+
+	rest = rest[ERROR_INFO_LENGTH:]
+	if rest == "" {
+		return r, nil
+	}
+	if !strings.HasPrefix(rest, DELIMITER) {
+		return r, &SyntaxError{message, FIELD_ERROR_INFORMATION,
+			"longer than " + strconv.Itoa(ERROR_INFO_LENGTH) + " characters"}
+	}
+	cprbBytes, err := hex.DecodeString(rest[len(DELIMITER):])
+	if err != nil {
+		return r, &SyntaxError{message, FIELD_CPRB, "invalid hexadecimal data"}
+	}
+	cprb, err := UnmarshalCPRB(cprbBytes)
+	if err != nil {
+		return r, &SyntaxError{message, FIELD_CPRB, err.Error()}
+	}
+	r.CPRB = &cprb
+	return r, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Decodes the 156 character error information section                        */
+/*----------------------------------------------------------------------------*/
+func unmarshalErrorInfo(s string) (ErrorInfo, error) {
+	var e ErrorInfo
+	offset := 0
+	field := func(width int) string {
+		value := s[offset : offset+width]
+		offset += width
+		return value
+	}
+	number := func(width int, name string) (int, error) {
+		text := field(width)
+		value, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return 0, &SyntaxError{"HTPResponse", FIELD_ERROR_INFORMATION,
+				name + " " + strconv.Quote(text) + " is not a number"}
+		}
+		return value, nil
+	}
+
+	var err error
+	e.ErrorType, err = number(errorTypeWidth, "error type")
+	if err != nil {
+		return e, err
+	}
+	e.ReturnCode, err = number(returnCodeWidth, "return code")
+	if err != nil {
+		return e, err
+	}
+	e.ReasonCode, err = number(reasonCodeWidth, "reason code")
+	if err != nil {
+		return e, err
+	}
+	e.ProgramID = strings.TrimRight(field(programIDWidth), " ")
+	e.ErrorLocation = strings.TrimRight(field(errorLocationWidth), " ")
+	e.ErrorText = strings.TrimRight(field(errorTextWidth), " ")
+	return e, nil
+}
+
+/** Pads or truncates text to the width of its field */
+func padText(text string, width int) string {
+	if len(text) > width {
+		return text[0:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
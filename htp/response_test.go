@@ -0,0 +1,264 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package htp_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+)
+
+/** Error information reporting no error */
+const goldenNoError = "00" + "       0" + "       0" + "        " +
+	"                              " + "                                  " +
+	"                                                                  "
+
+/** HTPResponse holding goldenCPRB */
+const goldenHTPResponse = "232;" + goldenNoError + ";" + goldenCPRB
+
+/** HTPResponse reporting a syntax error found by the emulator */
+const goldenHTPErrorResponse = "157;" + " 9" + "      27" + "       0" +
+	"EMULATOR" + "                              " + "Unsupported HTPRequest" +
+	"                                                                      " +
+	"        "
+
+/*----------------------------------------------------------------------------*/
+/* The error information formatting used by the emulator before the htp       */
+/* package was added, kept to check that the encoding has not changed         */
+/*----------------------------------------------------------------------------*/
+func legacyErrorInfo(errorType int, returnCode int, reasonCode int,
+	programID string, errorText string) string {
+
+	var errorTypeString string
+	if errorType == 0 {
+		errorTypeString = "00"
+	} else {
+		errorTypeString = fmt.Sprintf("%2d", errorType)
+	}
+	if len(errorText) > 100 {
+		errorText = errorText[0:100]
+	}
+	return fmt.Sprintf("%s%8d%8d%-8s%-30s%-100s", errorTypeString,
+		returnCode, reasonCode, programID, "", errorText)
+}
+
+/*----------------------------------------------------------------------------*/
+/* The HTPResponse parsing used by ep11cmds.ParseResponse before the htp      */
+/* package was added                                                          */
+/*----------------------------------------------------------------------------*/
+func legacyParseResponse(theRsp string) (ep11cmds.RspInfo, string, error) {
+	var rspInfo ep11cmds.RspInfo
+
+	stringParts := strings.Split(theRsp, ";")
+	if len(stringParts) < 2 {
+		return rspInfo, "", errors.New("error information not found")
+	}
+	if len(stringParts[1]) < 156 {
+		return rspInfo, "", errors.New("invalid error information")
+	}
+	rspInfo.ErrorType = stringParts[1][0:2]
+	rspInfo.ReturnCode = stringParts[1][2:10]
+	rspInfo.ReasonCode = stringParts[1][10:18]
+	rspInfo.ProgramID = stringParts[1][18:26]
+	rspInfo.ErrorLocation = stringParts[1][26:56]
+	rspInfo.ErrorText = stringParts[1][56:156]
+	if rspInfo.ErrorType != "00" || len(stringParts) < 3 {
+		return rspInfo, "", nil
+	}
+	return rspInfo, stringParts[2], nil
+}
+
+/** HTPResponses are encoded exactly as before */
+func TestHTPResponseGolden(t *testing.T) {
+	cprb := htp.NewCPRB(14, goldenPayload)
+	response, err := htp.NewHTPResponse(cprb).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != goldenHTPResponse {
+		t.Errorf("Marshal returned\n%q\nexpected\n%q", response,
+			goldenHTPResponse)
+	}
+	response, err = htp.NewHTPErrorResponse(9, 27, "EMULATOR",
+		"Unsupported HTPRequest").Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != goldenHTPErrorResponse {
+		t.Errorf("Marshal returned\n%q\nexpected\n%q", response,
+			goldenHTPErrorResponse)
+	}
+
+	texts := []string{"", "Domain not assigned to crypto unit",
+		strings.Repeat("x", 100), strings.Repeat("y", 120)}
+	for _, errorType := range []int{0, 1, 9, 99} {
+		for _, returnCode := range []int{0, 27, 12345678} {
+			for _, text := range texts {
+				expected := legacyErrorInfo(errorType, returnCode, 0,
+					"EMULATOR", text)
+				errorInfo, err := htp.ErrorInfo{ErrorType: errorType,
+					ReturnCode: returnCode, ProgramID: "EMULATOR",
+					ErrorText: text}.Marshal()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if errorInfo != expected {
+					t.Errorf("Error information for %d %d %q differs from "+
+						"the earlier encoding", errorType, returnCode, text)
+				}
+			}
+		}
+	}
+}
+
+/** ParseResponse returns the same fields as before */
+func TestParseResponseGolden(t *testing.T) {
+	responses := []string{
+		goldenHTPResponse,
+		goldenHTPErrorResponse,
+		// Numbers with leading zeros and an error location, as sent by
+		// the TKE catcher program
+		"157;08000000120000000" + "3TKEHTP  " + "SEND" +
+			strings.Repeat(" ", 26) + strings.Repeat("z", 100),
+		// No error, but no CPRB
+		"157;" + goldenNoError,
+	}
+	for _, response := range responses {
+		rspInfo, cprb, err := ep11cmds.ParseResponse(response)
+		if err != nil {
+			t.Errorf("ParseResponse(%q) returned %v", response, err)
+			continue
+		}
+		legacyInfo, legacyCPRB, _ := legacyParseResponse(response)
+		if rspInfo != legacyInfo || cprb != legacyCPRB {
+			t.Errorf("ParseResponse(%q) returned\n%+v %q\nexpected\n%+v %q",
+				response, rspInfo, cprb, legacyInfo, legacyCPRB)
+		}
+	}
+
+	payload, err := ep11cmds.GetRspPayload(goldenHTPResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, goldenPayload) {
+		t.Errorf("GetRspPayload returned %X, expected %X", payload,
+			goldenPayload)
+	}
+	_, err = ep11cmds.GetRspPayload(goldenHTPErrorResponse)
+	if err == nil || !strings.Contains(err.Error(), "Unsupported HTPRequest") {
+		t.Errorf("GetRspPayload returned %v for an error response", err)
+	}
+}
+
+/** Decoding recovers the fields, including text holding the delimiter */
+func TestUnmarshalHTPResponse(t *testing.T) {
+	response, err := htp.UnmarshalHTPResponse(goldenHTPResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ErrorInfo != (htp.ErrorInfo{}) || response.CPRB == nil ||
+		!bytes.Equal(response.CPRB.Payload, goldenPayload) {
+		t.Errorf("Unexpected HTPResponse %+v", response)
+	}
+
+	errorInfo := htp.ErrorInfo{ErrorType: 8, ReturnCode: -12, ReasonCode: 3,
+		ProgramID: "TKEHTP", ErrorLocation: "SEND", ErrorText: "a;b;c"}
+	encoded, err := htp.HTPResponse{ErrorInfo: errorInfo}.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = htp.UnmarshalHTPResponse(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ErrorInfo != errorInfo || response.CPRB != nil {
+		t.Errorf("Decoded %+v, expected %+v", response.ErrorInfo, errorInfo)
+	}
+}
+
+/** Malformed HTPResponses are reported with the field at fault */
+func TestUnmarshalHTPResponseErrors(t *testing.T) {
+	tests := []struct {
+		response string
+		field    string
+	}{
+		{"", htp.FIELD_ERROR_INFORMATION},
+		{"x" + goldenHTPResponse[3:], htp.FIELD_LENGTH},
+		{"100;" + goldenNoError[0:100], htp.FIELD_ERROR_INFORMATION},
+		{"157;XX" + goldenNoError[2:], htp.FIELD_ERROR_INFORMATION},
+		{"157;" + goldenNoError + "x", htp.FIELD_ERROR_INFORMATION},
+		{"157;" + goldenNoError + ";XYZ", htp.FIELD_CPRB},
+		{"157;" + goldenNoError + ";" + goldenCPRB[0:40], htp.FIELD_CPRB},
+	}
+	for _, test := range tests {
+		_, err := htp.UnmarshalHTPResponse(test.response)
+		syntaxErr, ok := err.(*htp.SyntaxError)
+		if !ok {
+			t.Errorf("UnmarshalHTPResponse(%q) returned %v, expected a "+
+				"SyntaxError", test.response, err)
+			continue
+		}
+		if syntaxErr.Field != test.field {
+			t.Errorf("UnmarshalHTPResponse(%q) reported field %q, "+
+				"expected %q", test.response, syntaxErr.Field, test.field)
+		}
+	}
+}
+
+/** Numbers are rejected unless they fit in their fields */
+func TestErrorInfoRange(t *testing.T) {
+	valid := []htp.ErrorInfo{
+		{ErrorType: 99, ReturnCode: 99999999, ReasonCode: 99999999},
+		{ErrorType: -9, ReturnCode: -9999999, ReasonCode: -9999999},
+	}
+	for _, errorInfo := range valid {
+		encoded, err := errorInfo.Marshal()
+		if err != nil {
+			t.Errorf("Marshal(%+v) returned %v", errorInfo, err)
+			continue
+		}
+		if len(encoded) != htp.ERROR_INFO_LENGTH {
+			t.Errorf("Marshal(%+v) returned %d characters", errorInfo,
+				len(encoded))
+		}
+		response, err := htp.UnmarshalHTPResponse(
+			strconv.Itoa(len(encoded)+1) + ";" + encoded)
+		if err != nil || response.ErrorInfo != errorInfo {
+			t.Errorf("Decoded %+v, %v, expected %+v", response.ErrorInfo,
+				err, errorInfo)
+		}
+	}
+
+	invalid := []htp.ErrorInfo{
+		{ErrorType: 100},
+		{ErrorType: -10},
+		{ReturnCode: 100000000},
+		{ReturnCode: -10000000},
+		{ReasonCode: 100000000},
+		{ReasonCode: -10000000},
+	}
+	for _, errorInfo := range invalid {
+		_, err := errorInfo.Marshal()
+		if err == nil {
+			t.Errorf("Marshal(%+v) succeeded", errorInfo)
+		}
+		_, err = htp.HTPResponse{ErrorInfo: errorInfo}.Marshal()
+		if err == nil {
+			t.Errorf("HTPResponse.Marshal succeeded for %+v", errorInfo)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Save only the values of known headers
+
+/*----------------------------------------------------------------------------*/
+/* Package recorder records the requests sent to the TKE REST API and the     */
+/* responses received, and replays them later without network access.        */
+/*                                                                            */
+/* A Recorder is an http.RoundTripper that passes requests on to another HTTP */
+/* client and saves each exchange in a Cassette.  Tokens, API keys, and other */
+/* credentials are redacted before they are saved, and the bodies of          */
+/* exchanges with unknown endpoints are not saved.  A Replayer is an          */
+/* http.RoundTripper that answers requests from a Cassette.  Both are         */
+/* connected to the TKE SDK by setting CommonInputs.HTTPClient to the client  */
+/* returned by their Client method.                                           */
+/*                                                                            */
+/* Signed administrative commands contain ECDSA signatures that differ each   */
+/* time a command is created.  The Replayer matches HTPRequests on their      */
+/* content with the signatures removed, so a recorded Update can be replayed  */
+/* with freshly signed commands.                                              */
+/*----------------------------------------------------------------------------*/
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+/** Version of the cassette file format written by this package */
+const CASSETTE_VERSION = 1
+
+/** Value that replaces redacted header values, fields, and bodies */
+const REDACTED = "REDACTED"
+
+/** A set of recorded exchanges with the TKE REST API */
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+/** A single request and the response or error it produced */
+type Interaction struct {
+	Request  RecordedRequest   `json:"request"`
+	Response *RecordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+/** A recorded HTTP request */
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+/** A recorded HTTP response */
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads a cassette from a file.                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* path -- the cassette file                                                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *Cassette -- the cassette read from the file                               */
+/* error -- reports an unreadable file or an unsupported cassette version     */
+/*----------------------------------------------------------------------------*/
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	err = json.Unmarshal(data, &cassette)
+	if err != nil {
+		return nil, errors.New("Invalid cassette file " + path +
+			"\nMessage: " + err.Error())
+	}
+	if cassette.Version != CASSETTE_VERSION {
+		return nil, errors.New("Unsupported cassette version " +
+			strconv.Itoa(cassette.Version) + " in file " + path)
+	}
+	return &cassette, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes a cassette to a file.  The file is only readable by its owner,      */
+/* since the recorded responses describe the crypto units of the service      */
+/* instance.                                                                  */
+/*----------------------------------------------------------------------------*/
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a copy of a header with the values of all headers other than those */
+/* in recordedHeaders replaced by REDACTED                                    */
+/*----------------------------------------------------------------------------*/
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		if contains(recordedHeaders, http.CanonicalHeaderKey(name)) {
+			result[name] = append([]string(nil), values...)
+		} else {
+			result[name] = []string{REDACTED}
+		}
+	}
+	return result
+}
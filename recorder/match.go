@@ -0,0 +1,146 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Use the htp package to encode and decode messages
+
+package recorder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/Logicalis/asn1"
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Returns a string identifying a request for replay.                         */
+/*                                                                            */
+/* The body of a POST to the hsms endpoint holds an HTPRequest.  Its          */
+/* fingerprint leaves out everything that changes when the same command is    */
+/* signed again: the signer info of each xcpAdminReq, the signature of an     */
+/* administrator certificate, and the length fields that depend on the size   */
+/* of the signatures.  Other bodies are compared exactly.                     */
+/*----------------------------------------------------------------------------*/
+func requestFingerprint(method string, path string, body string) string {
+	fingerprint := method + " " + path
+	if body == "" {
+		return fingerprint
+	}
+
+	var postBody struct {
+		Request string `json:"request"`
+	}
+	err := json.Unmarshal([]byte(body), &postBody)
+	if err != nil || postBody.Request == "" {
+		return fingerprint + "\n" + body
+	}
+	return fingerprint + "\n" + htpRequestFingerprint(postBody.Request)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the fingerprint of an HTPRequest.  Requests that cannot be decoded */
+/* are returned unchanged.                                                    */
+/*----------------------------------------------------------------------------*/
+func htpRequestFingerprint(htpRequest string) string {
+	request, err := htp.UnmarshalHTPRequest(htpRequest)
+	if err != nil {
+		return htpRequest
+	}
+
+	// Skip the request length and the CPRB payload length
+	header := request.CPRB
+	header.Payload = nil
+	return strconv.Itoa(request.CryptoModuleIndex) + ";" +
+		hex.EncodeToString(header.Marshal()[0:28]) + ";" +
+		ep11RequestFingerprint(request.CPRB.Payload)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the fingerprint of an EP11 request.  For an xcpAdminReq the signer */
+/* info is left out.                                                          */
+/*----------------------------------------------------------------------------*/
+func ep11RequestFingerprint(request []byte) (fingerprint string) {
+	fingerprint = hex.EncodeToString(request)
+
+	// A request that cannot be decoded is compared exactly
+	defer func() {
+		if r := recover(); r != nil {
+			fingerprint = hex.EncodeToString(request)
+		}
+	}()
+
+	var adminReq ep11cmds.AdminReq
+	rest, err := asn1.Decode(request, &adminReq)
+	if err != nil || len(rest) != 0 ||
+		!common.ByteSlicesAreEqual(adminReq.CmdID, ep11cmds.FNID_ADMIN) {
+		return fingerprint
+	}
+	var adminBlk ep11cmds.AdminBlk
+	rest, err = asn1.Decode(adminReq.AdminBlock, &adminBlk)
+	if err != nil || len(rest) != 0 {
+		return fingerprint
+	}
+
+	cmdInput := hex.EncodeToString(adminBlk.CmdInput)
+	switch {
+	case common.ByteSlicesAreEqual(adminBlk.CmdID,
+		ep11cmds.XCP_ADM_DOM_ADMIN_LOGIN):
+		cmdInput = certificateFingerprint(adminBlk.CmdInput)
+	case common.ByteSlicesAreEqual(adminBlk.CmdID, ep11cmds.XCP_ADM_IMPORT_WK):
+		cmdInput = importWKFingerprint(adminBlk.CmdInput)
+	}
+
+	return "admin(" + hex.EncodeToString(adminReq.DomainID) + "," +
+		hex.EncodeToString(adminBlk.CmdID) + "," +
+		hex.EncodeToString(adminBlk.DomainID) + "," +
+		hex.EncodeToString(adminBlk.ModuleID) + "," +
+		hex.EncodeToString(adminBlk.TransactionCounter) + "," +
+		cmdInput + ")"
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the fingerprint of an administrator certificate, which is the      */
+/* certificate body without the signature                                     */
+/*----------------------------------------------------------------------------*/
+func certificateFingerprint(cert []byte) string {
+	certSeq, err := common.Asn1GetSequenceBytes(cert, 0)
+	if err != nil {
+		return hex.EncodeToString(cert)
+	}
+	bodyEnd, err := common.Asn1SkipSequence(certSeq, 0)
+	if err != nil {
+		return hex.EncodeToString(cert)
+	}
+	return "cert(" + hex.EncodeToString(certSeq[0:bodyEnd]) + ")"
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the fingerprint of the input to an import wrapping key command.    */
+/* The enveloping command holds one signed xcpAdminReq for each key part.     */
+/* The input to the command for a single key part is compared exactly.        */
+/*----------------------------------------------------------------------------*/
+func importWKFingerprint(cmdInput []byte) string {
+	var fingerprints []string
+	offset := 0
+	for offset < len(cmdInput) {
+		end, err := common.Asn1SkipSequence(cmdInput, offset)
+		if err != nil {
+			return hex.EncodeToString(cmdInput)
+		}
+		fingerprints = append(fingerprints,
+			ep11RequestFingerprint(cmdInput[offset:end]))
+		offset = end
+	}
+	return "parts(" + strings.Join(fingerprints, ",") + ")"
+}
@@ -0,0 +1,135 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Redact credentials in URLs and bodies
+
+package recorder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Records the exchanges made through an HTTP client */
+type Recorder struct {
+	mutex    sync.Mutex
+	client   *http.Client
+	cassette Cassette
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a recorder.                                                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* client -- the HTTP client that sends the requests, or nil to use           */
+/*    common.DefaultHTTPClient()                                              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *Recorder -- the new recorder, with an empty cassette                      */
+/*----------------------------------------------------------------------------*/
+func NewRecorder(client *http.Client) *Recorder {
+	if client == nil {
+		client = common.DefaultHTTPClient()
+	}
+	return &Recorder{
+		client:   client,
+		cassette: Cassette{Version: CASSETTE_VERSION},
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns an HTTP client that sends its requests through the recorder.  Set  */
+/* CommonInputs.HTTPClient or HTTPTransport.Client to this client.            */
+/*----------------------------------------------------------------------------*/
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r, Timeout: r.client.Timeout}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sends a request and records it with its response, with credentials         */
+/* redacted.  Implements http.RoundTripper.                                   */
+/*----------------------------------------------------------------------------*/
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	kind := endpointKind(req.Method, req.URL.EscapedPath())
+	var interaction Interaction
+	interaction.Request.Method = req.Method
+	interaction.Request.URL = redactURL(req.URL)
+	interaction.Request.Header = redactHeader(req.Header)
+
+	// Read the request body so it can be both recorded and sent
+	outReq := req.Clone(req.Context())
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		interaction.Request.Body = redactRequestBody(kind, string(body))
+		outReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	rsp, err := transport.RoundTrip(outReq)
+	if err != nil {
+		interaction.Error = err.Error()
+		r.add(interaction)
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		interaction.Error = err.Error()
+		r.add(interaction)
+		return nil, err
+	}
+	interaction.Response = &RecordedResponse{
+		StatusCode: rsp.StatusCode,
+		Header:     redactHeader(rsp.Header),
+		Body:       redactResponseBody(kind, string(body)),
+	}
+	r.add(interaction)
+
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	rsp.Request = req
+	return rsp, nil
+}
+
+/** Adds an interaction to the cassette */
+func (r *Recorder) add(interaction Interaction) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a copy of the cassette holding the exchanges recorded so far       */
+/*----------------------------------------------------------------------------*/
+func (r *Recorder) Cassette() *Cassette {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	cassette := Cassette{Version: r.cassette.Version}
+	cassette.Interactions = append([]Interaction(nil),
+		r.cassette.Interactions...)
+	return &cassette
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes the exchanges recorded so far to a cassette file                    */
+/*----------------------------------------------------------------------------*/
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
@@ -0,0 +1,280 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test redaction of credentials
+
+package recorder_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/recorder"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+const testToken = "Bearer secret-token"
+
+/** Creates an emulator, failing the test on an error */
+func newEmulator(t *testing.T) *emulator.Emulator {
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return em
+}
+
+/** Returns an HsmConfig with two administrators and a threshold of 2 */
+func newTestHsmConfig(t *testing.T) tkesdk.HsmConfig {
+	hc := tkesdk.HsmConfig{SignatureThreshold: 2, RevocationThreshold: 2,
+		MasterKeyParts: 1, MasterKeyPartsRequired: 1}
+	for _, name := range []string{"admin1", "admin2"} {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := common.NewPrivateKeySigner(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hc.Admins = append(hc.Admins, tkesdk.AdminInfo{Name: name,
+			Signer: signer})
+	}
+	return hc
+}
+
+/*----------------------------------------------------------------------------*/
+/* Records an Update and a Query of an emulated service instance served over  */
+/* HTTP, and returns the cassette and the result of the Query.  The OA root   */
+/* key of the emulator is needed to replay the cassette.                      */
+/*----------------------------------------------------------------------------*/
+func recordUpdate(t *testing.T, em *emulator.Emulator,
+	hc tkesdk.HsmConfig) (*recorder.Cassette, []tkesdk.HsmInfo) {
+
+	_, err := em.AddCryptoUnit("instance1", "recovery",
+		"[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX8P)
+	if err == nil {
+		_, err = em.AddCryptoUnit("instance1", "operational",
+			"[us-south].[AZ2-CS2].[00].[04]", emulator.MODEL_CEX7P)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(em)
+	defer server.Close()
+
+	rec := recorder.NewRecorder(nil)
+	ci := tkesdk.CommonInputs{InstanceId: "instance1", BaseURL: server.URL,
+		AuthToken: testToken, HTTPClient: rec.Client(),
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+	problems, err := tkesdk.Update(ci, hc)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("Update returned %v %v", problems, err)
+	}
+	hsminfo, err := tkesdk.Query(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Cassette(), hsminfo
+}
+
+/*----------------------------------------------------------------------------*/
+/* A recorded Update replays with freshly signed commands and no server, and  */
+/* the saved cassette holds no authentication token.                          */
+/*----------------------------------------------------------------------------*/
+func TestRecordAndReplay(t *testing.T) {
+	em := newEmulator(t)
+	hc := newTestHsmConfig(t)
+	cassette, recorded := recordUpdate(t, em, hc)
+	em.Close()
+
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "update.json")
+	if err = cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("Cassette contains the authentication token")
+	}
+	if !strings.Contains(string(data), recorder.REDACTED) {
+		t.Error("Authorization header was not recorded as redacted")
+	}
+
+	loaded, err := recorder.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := recorder.NewReplayer(loaded)
+	ci := tkesdk.CommonInputs{InstanceId: "instance1",
+		BaseURL: "https://tke.example.com", AuthToken: "Bearer other",
+		HTTPClient: replayer.Client(),
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+	problems, err := tkesdk.Update(ci, hc)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("Replayed Update returned %v %v", problems, err)
+	}
+	replayed, err := tkesdk.Query(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Error("Replayed Query differs from the recorded Query")
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d recorded interactions were not used",
+			replayer.Remaining())
+	}
+}
+
+/** Commands that differ from the recorded commands are not answered */
+func TestReplayRejectsDifferentCommands(t *testing.T) {
+	em := newEmulator(t)
+	cassette, _ := recordUpdate(t, em, newTestHsmConfig(t))
+	em.Close()
+
+	// Different administrators give different administrator certificates
+	replayer := recorder.NewReplayer(cassette)
+	ci := tkesdk.CommonInputs{InstanceId: "instance1",
+		BaseURL: "https://tke.example.com", HTTPClient: replayer.Client(),
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+	_, err := tkesdk.Update(ci, newTestHsmConfig(t))
+	if err == nil || !strings.Contains(err.Error(),
+		"No recorded interaction matches") {
+		t.Errorf("Update with other administrators returned %v", err)
+	}
+}
+
+/** Transport errors are recorded and replayed */
+func TestRecordError(t *testing.T) {
+	server := httptest.NewServer(nil)
+	url := server.URL
+	server.Close()
+
+	rec := recorder.NewRecorder(nil)
+	ci := tkesdk.CommonInputs{InstanceId: "instance1", BaseURL: url,
+		AuthToken: testToken, HTTPClient: rec.Client()}
+	if _, err := tkesdk.Query(ci); err == nil {
+		t.Fatal("Query succeeded with the server closed")
+	}
+	cassette := rec.Cassette()
+	if len(cassette.Interactions) != 1 ||
+		cassette.Interactions[0].Error == "" {
+		t.Fatalf("Cassette holds %+v", cassette.Interactions)
+	}
+
+	replayer := recorder.NewReplayer(cassette)
+	ci.HTTPClient = replayer.Client()
+	if _, err := tkesdk.Query(ci); err == nil {
+		t.Error("Replayed Query succeeded")
+	}
+	if replayer.Remaining() != 0 {
+		t.Error("Recorded error was not replayed")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Credentials sent to IAM, Vault, and other endpoints through the recorded   */
+/* client are not saved, and the redacted requests still replay.              */
+/*----------------------------------------------------------------------------*/
+func TestRecordRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Session", "secret-session")
+			switch r.URL.Path {
+			case "/identity/token":
+				w.Write([]byte(`{"access_token":"secret-access",` +
+					`"refresh_token":"secret-refresh","expires_in":3600}`))
+			case "/v1/auth/approle/login":
+				w.Write([]byte(`{"auth":{"client_token":"secret-vault",` +
+					`"lease_duration":600}}`))
+			default:
+				w.Write([]byte(`{"password":"secret-other"}`))
+			}
+		}))
+	defer server.Close()
+
+	send := func(client *http.Client, method string, url string,
+		body string) *http.Response {
+
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Api-Key", "secret-header")
+		rsp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+		return rsp
+	}
+	requests := []struct{ method, url, body string }{
+		{http.MethodPost, server.URL + "/identity/token",
+			"grant_type=urn%3Aibm%3Aparams%3Aoauth%3Agrant-type%3Aapikey" +
+				"&apikey=secret-apikey"},
+		{http.MethodPost, server.URL + "/v1/auth/approle/login",
+			`{"role_id":"secret-role","secret_id":"secret-id"}`},
+		{http.MethodGet, strings.Replace(server.URL, "http://",
+			"http://user:secret-password@", 1) + "/other?token=secret-query",
+			""},
+		{http.MethodPut, server.URL + "/other", `{"key":"secret-body"}`},
+	}
+
+	rec := recorder.NewRecorder(nil)
+	for _, r := range requests {
+		send(rec.Client(), r.method, r.url, r.body)
+	}
+	data, err := json.Marshal(rec.Cassette())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-") {
+		t.Errorf("Cassette contains a credential: %s", data)
+	}
+	for _, saved := range []string{"grant_type", "expires_in",
+		"lease_duration", "application/json"} {
+		if !strings.Contains(string(data), saved) {
+			t.Errorf("Cassette does not contain %s", saved)
+		}
+	}
+
+	replayer := recorder.NewReplayer(rec.Cassette())
+	for _, r := range requests {
+		rsp := send(replayer.Client(), r.method, r.url, r.body)
+		if rsp.StatusCode != http.StatusOK {
+			t.Errorf("Replayed %s %s returned %d", r.method, r.url,
+				rsp.StatusCode)
+		}
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d recorded interactions were not used",
+			replayer.Remaining())
+	}
+}
@@ -0,0 +1,198 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package recorder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+/*----------------------------------------------------------------------------*/
+/* The HTTP client given to the TKE SDK is also used to request IAM tokens,   */
+/* to log in to Vault, and to reach signing services.  Nothing that           */
+/* authenticates the caller is saved in a cassette:                           */
+/*                                                                            */
+/* - Only the values of the headers in recordedHeaders are saved.  The        */
+/*   values of all other headers are replaced by REDACTED.                    */
+/* - User information and query values are removed from URLs.                 */
+/* - Bodies of exchanges with the TKE REST API, signing services, and Vault   */
+/*   transit keys are saved as sent.  They hold administrative commands,      */
+/*   public keys, and signatures.                                             */
+/* - In the bodies of IAM token requests and Vault AppRole logins, the values */
+/*   of fields not known to be public are replaced by REDACTED.               */
+/* - Bodies of exchanges with any other endpoint are replaced by REDACTED.    */
+/*                                                                            */
+/* Requests are redacted in the same way before they are matched for replay.  */
+/*----------------------------------------------------------------------------*/
+
+/** Headers whose values are saved in a cassette */
+var recordedHeaders = []string{
+	"Accept",
+	"Content-Type",
+}
+
+/** Kinds of endpoints, which decide how bodies are redacted */
+const (
+	endpointOther = iota
+	endpointTKE
+	endpointSigning
+	endpointIAMToken
+	endpointVaultLogin
+)
+
+/** Paths of the endpoints whose bodies are saved or partly saved */
+var (
+	tkePath        = regexp.MustCompile(`/v1/tke/[^/]+/hsms(/[^/]+)?$`)
+	signingPath    = regexp.MustCompile(`/(keys|sign)/[^/]+(/sha2-512)?$`)
+	versionPath    = regexp.MustCompile(`/version$`)
+	iamTokenPath   = regexp.MustCompile(`/identity/token$`)
+	vaultLoginPath = regexp.MustCompile(`/v1/auth/.+/login$`)
+)
+
+/** Fields of IAM token requests that are saved */
+var iamRequestFields = []string{"grant_type"}
+
+/** Fields of IAM token responses that are saved */
+var iamResponseFields = []string{"token_type", "expires_in", "expiration",
+	"scope", "errorCode", "errorMessage", "errorDetails", "context"}
+
+/** Fields of Vault AppRole login responses that are saved */
+var vaultLoginResponseFields = []string{"lease_duration", "renewable",
+	"policies", "token_policies", "token_type", "errors", "warnings"}
+
+/** Returns the kind of endpoint a request with a method and path is sent to */
+func endpointKind(method string, path string) int {
+	switch {
+	case tkePath.MatchString(path):
+		return endpointTKE
+	case method == http.MethodPost && iamTokenPath.MatchString(path):
+		return endpointIAMToken
+	case method == http.MethodPost && vaultLoginPath.MatchString(path):
+		return endpointVaultLogin
+	case signingPath.MatchString(path):
+		return endpointSigning
+	case method == http.MethodGet && versionPath.MatchString(path):
+		return endpointSigning
+	}
+	return endpointOther
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a URL without user information, and with the value of each query   */
+/* parameter replaced by REDACTED                                             */
+/*----------------------------------------------------------------------------*/
+func redactURL(u *url.URL) string {
+	result := *u
+	result.User = nil
+	if result.RawQuery != "" {
+		query := result.Query()
+		for name := range query {
+			query[name] = []string{REDACTED}
+		}
+		result.RawQuery = query.Encode()
+	}
+	return result.String()
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the body of a request as it is saved in a cassette                 */
+/*----------------------------------------------------------------------------*/
+func redactRequestBody(kind int, body string) string {
+	if body == "" {
+		return body
+	}
+	switch kind {
+	case endpointTKE, endpointSigning:
+		return body
+	case endpointIAMToken:
+		form, err := url.ParseQuery(body)
+		if err != nil {
+			return REDACTED
+		}
+		for name := range form {
+			if !contains(iamRequestFields, name) {
+				form[name] = []string{REDACTED}
+			}
+		}
+		return form.Encode()
+	case endpointVaultLogin:
+		return redactJSON(body, nil)
+	}
+	return REDACTED
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the body of a response as it is saved in a cassette                */
+/*----------------------------------------------------------------------------*/
+func redactResponseBody(kind int, body string) string {
+	if body == "" {
+		return body
+	}
+	switch kind {
+	case endpointTKE, endpointSigning:
+		return body
+	case endpointIAMToken:
+		return redactJSON(body, iamResponseFields)
+	case endpointVaultLogin:
+		return redactJSON(body, vaultLoginResponseFields)
+	}
+	return REDACTED
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a JSON document with the value of each field replaced by REDACTED, */
+/* except for the fields named in keep.  Objects are redacted field by field  */
+/* at any depth.  A body that is not a JSON object is replaced by REDACTED.   */
+/*----------------------------------------------------------------------------*/
+func redactJSON(body string, keep []string) string {
+	var object map[string]json.RawMessage
+	if json.Unmarshal([]byte(body), &object) != nil || object == nil {
+		return REDACTED
+	}
+	data, err := json.Marshal(redactObject(object, keep))
+	if err != nil {
+		return REDACTED
+	}
+	return string(data)
+}
+
+/** Redacts the fields of a JSON object, see redactJSON */
+func redactObject(object map[string]json.RawMessage,
+	keep []string) map[string]interface{} {
+
+	result := make(map[string]interface{}, len(object))
+	for name, value := range object {
+		var inner map[string]json.RawMessage
+		switch {
+		case contains(keep, name):
+			result[name] = value
+		case json.Unmarshal(value, &inner) == nil && inner != nil:
+			result[name] = redactObject(inner, keep)
+		case string(value) == "null":
+			result[name] = nil
+		default:
+			result[name] = REDACTED
+		}
+	}
+	return result
+}
+
+/** Returns true if a list of names contains a name */
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Redact request bodies before matching them
+
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+/** Answers HTTP requests from a cassette */
+type Replayer struct {
+	mutex        sync.Mutex
+	cassette     *Cassette
+	fingerprints []string
+	used         []bool
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a replayer for a cassette.                                         */
+/*                                                                            */
+/* Each recorded interaction answers one request.  A request is answered by   */
+/* the first unused interaction with the same method, URL path, and body.     */
+/* Host names are ignored, HTPRequests are compared with their signatures     */
+/* removed, and other bodies are compared after they are redacted as they     */
+/* were when recorded.                                                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* cassette -- the recorded interactions                                      */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *Replayer -- the new replayer                                              */
+/*----------------------------------------------------------------------------*/
+func NewReplayer(cassette *Cassette) *Replayer {
+	r := &Replayer{
+		cassette:     cassette,
+		fingerprints: make([]string, len(cassette.Interactions)),
+		used:         make([]bool, len(cassette.Interactions)),
+	}
+	for i, interaction := range cassette.Interactions {
+		r.fingerprints[i] = requestFingerprint(interaction.Request.Method,
+			urlPath(interaction.Request.URL), interaction.Request.Body)
+	}
+	return r
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns an HTTP client that answers its requests from the cassette.  Set   */
+/* CommonInputs.HTTPClient or HTTPTransport.Client to this client.            */
+/*----------------------------------------------------------------------------*/
+func (r *Replayer) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Answers a request from the cassette.  Implements http.RoundTripper.        */
+/*                                                                            */
+/* A request with no matching interaction fails with an error naming the      */
+/* next unused interaction, which is usually the one the request was expected */
+/* to match.                                                                  */
+/*----------------------------------------------------------------------------*/
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	path := req.URL.EscapedPath()
+	fingerprint := requestFingerprint(req.Method, path,
+		redactRequestBody(endpointKind(req.Method, path), string(body)))
+
+	r.mutex.Lock()
+	index := -1
+	next := -1
+	for i := range r.fingerprints {
+		if r.used[i] {
+			continue
+		}
+		if next < 0 {
+			next = i
+		}
+		if r.fingerprints[i] == fingerprint {
+			index = i
+			r.used[i] = true
+			break
+		}
+	}
+	r.mutex.Unlock()
+
+	if index < 0 {
+		message := "No recorded interaction matches " + req.Method + " " +
+			req.URL.EscapedPath()
+		if next < 0 {
+			message += "\nAll " + strconv.Itoa(len(r.fingerprints)) +
+				" recorded interactions have been used"
+		} else {
+			message += "\nNext unused interaction is " + strconv.Itoa(next) +
+				": " + r.cassette.Interactions[next].Request.Method + " " +
+				urlPath(r.cassette.Interactions[next].Request.URL)
+		}
+		return nil, errors.New(message)
+	}
+
+	interaction := r.cassette.Interactions[index]
+	if interaction.Response == nil {
+		if interaction.Error != "" {
+			return nil, errors.New(interaction.Error)
+		}
+		return nil, errors.New("Recorded interaction " +
+			strconv.Itoa(index) + " has no response")
+	}
+	header := make(http.Header, len(interaction.Response.Header))
+	for name, values := range interaction.Response.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status: strconv.Itoa(interaction.Response.StatusCode) + " " +
+			http.StatusText(interaction.Response.StatusCode),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the number of recorded interactions that have not been used.       */
+/* After a complete replay this is zero.                                      */
+/*----------------------------------------------------------------------------*/
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := 0
+	for _, used := range r.used {
+		if !used {
+			count++
+		}
+	}
+	return count
+}
+
+/** Returns the escaped path of a recorded URL */
+func urlPath(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.EscapedPath()
+}
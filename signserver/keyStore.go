@@ -0,0 +1,186 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Sign RSA hashes using common.SignDigestANSIX931
+
+package signserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/*----------------------------------------------------------------------------*/
+/* A signature key held by the signing service.  Keys sign hashes rather than */
+/* data, since version 2 clients send only the hash.                          */
+/*----------------------------------------------------------------------------*/
+type Key interface {
+
+	// Returns the public key, an *ecdsa.PublicKey or an *rsa.PublicKey
+	PublicKey() crypto.PublicKey
+
+	// Signs a hash.  For a P521 EC key the hash is a SHA-512 hash and the
+	// signature is an ASN.1 sequence of R and S.  For a 2048-bit RSA key the
+	// hash is a SHA-256 hash, and the signature is the 256-byte result of
+	// the raw RSA private key operation on the hash in ANSI X9.31 format.
+	SignDigest(digest []byte) ([]byte, error)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Holds the signature keys of a signing service.  Implement this interface   */
+/* to keep signature keys somewhere other than in memory, such as in an HSM.  */
+/*----------------------------------------------------------------------------*/
+type KeyStore interface {
+
+	// Returns the signature key with the given name, or nil with no error if
+	// there is no such key
+	Key(name string) (Key, error)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a Key for a private key held in memory.                            */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- an *ecdsa.PrivateKey on the P521 curve, or an *rsa.PrivateKey with  */
+/*    a 2048-bit modulus and a public exponent of 65537                       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* Key -- signs using the private key                                         */
+/* error -- reports an unsupported key                                        */
+/*----------------------------------------------------------------------------*/
+func NewPrivateKey(key crypto.PrivateKey) (Key, error) {
+	// Use the checks made for signers
+	if _, err := common.NewPrivateKeySigner(key); err != nil {
+		return nil, err
+	}
+	return &privateKey{key: key}, nil
+}
+
+/** A P521 EC or 2048-bit RSA private key held in memory */
+type privateKey struct {
+	key crypto.PrivateKey
+}
+
+func (k *privateKey) PublicKey() crypto.PublicKey {
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	}
+	return nil
+}
+
+func (k *privateKey) SignDigest(digest []byte) ([]byte, error) {
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		if len(digest) != 64 {
+			return nil, errors.New("A P521 EC key signs a 64-byte SHA-512 " +
+				"hash, not " + strconv.Itoa(len(digest)) + " bytes.")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		return asn1.Marshal(common.ECSignature{R: r, S: s})
+	case *rsa.PrivateKey:
+		return common.SignDigestANSIX931(digest, key)
+	}
+	return nil, errors.New("Unsupported signature key type.")
+}
+
+/*----------------------------------------------------------------------------*/
+/* A KeyStore holding keys in memory.  Keys can be added from signature key   */
+/* files, or as private keys or other Key implementations.                    */
+/*----------------------------------------------------------------------------*/
+type MemoryKeyStore struct {
+	mutex sync.Mutex
+	keys  map[string]Key
+}
+
+/** Creates an empty MemoryKeyStore */
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string]Key)}
+}
+
+/** Returns the key with the given name, or nil if there is none */
+func (s *MemoryKeyStore) Key(name string) (Key, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.keys[name], nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Adds a key, replacing any key with the same name.  Key names may not be    */
+/* empty or contain a slash.                                                  */
+/*----------------------------------------------------------------------------*/
+func (s *MemoryKeyStore) Add(name string, key Key) error {
+	if name == "" || strings.Contains(name, "/") {
+		return errors.New("Invalid signature key name: " + name)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[name] = key
+	return nil
+}
+
+/** Adds a private key, see NewPrivateKey */
+func (s *MemoryKeyStore) AddPrivateKey(name string,
+	key crypto.PrivateKey) error {
+
+	k, err := NewPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return s.Add(name, k)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Adds the signature key in a signature key file.  The file is read and the  */
+/* key decrypted when it is added, so the password is not kept.               */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* name -- the key name used in signing service requests                      */
+/* sigkey -- the full path and name of the signature key file                 */
+/* sigkeyToken -- the file password                                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports an unreadable file, an invalid password, or a file whose  */
+/*    saved Subject Key Identifier does not match the key                     */
+/*----------------------------------------------------------------------------*/
+func (s *MemoryKeyStore) AddKeyFile(name string, sigkey string,
+	sigkeyToken string) error {
+
+	skfields, err := common.ReadSignatureKeyFile(sigkey)
+	if err != nil {
+		return err
+	}
+	key, err := common.DecryptSignatureKey(skfields, sigkeyToken)
+	if err != nil {
+		return err
+	}
+	signer, err := common.NewPrivateKeySigner(key)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(signer.SKI()) != strings.ToLower(skfields["ski"]) {
+		return errors.New("Miscompare on saved and calculated Subject Key Identifier.")
+	}
+	return s.AddPrivateKey(name, key)
+}
@@ -0,0 +1,423 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+/*----------------------------------------------------------------------------*/
+/* Package signserver implements a signing service for administrator          */
+/* signature keys, serving the requests sent by common.SigningServiceSigner:  */
+/*                                                                            */
+/*   GET  /version          -- the supported protocol versions                */
+/*   GET  /keys/{key}       -- version 1 public key, P521 EC keys only        */
+/*   POST /sign/{key}       -- version 1 signature over data                  */
+/*   GET  /v2/keys/{key}    -- version 2 public key, as a PEM string          */
+/*   POST /v2/sign/{key}    -- version 2 signature over a hash                */
+/*                                                                            */
+/* Signature keys are held in a KeyStore.  MemoryKeyStore holds keys read     */
+/* from signature key files or supplied as private keys; other key stores     */
+/* can be added by implementing the interface.  Each request for a key must   */
+/* present one of the bearer tokens registered for the key in the             */
+/* Authorization header.  Every request is recorded in an audit log, one JSON */
+/* object per line.  Tokens are never written to the audit log.               */
+/*----------------------------------------------------------------------------*/
+package signserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Token key name that allows access to every signature key */
+const ANY_KEY = "*"
+
+/** Largest request body accepted */
+const maxRequestBodySize = 1 << 20
+
+/** A signing service serving the signature keys in a KeyStore */
+type Server struct {
+	keys KeyStore
+
+	mutex    sync.Mutex
+	tokens   map[string][][]byte // key name --> SHA-256 hashes of tokens
+	auditLog io.Writer
+}
+
+/** An entry in the audit log */
+type AuditRecord struct {
+	Time          string `json:"time"`
+	RemoteAddress string `json:"remote_address"`
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	Operation     string `json:"operation"`
+	Version       int    `json:"version,omitempty"`
+	Key           string `json:"key,omitempty"`
+	SKI           string `json:"ski,omitempty"`
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	Digest        string `json:"digest,omitempty"` // hexadecimal hash signed
+	Status        int    `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signing service for the keys in a key store.  No tokens are      */
+/* registered, so no key can be used until AddToken is called.  The audit log */
+/* is written to standard error until SetAuditLog is called.                  */
+/*----------------------------------------------------------------------------*/
+func NewServer(keys KeyStore) *Server {
+	return &Server{
+		keys:     keys,
+		tokens:   make(map[string][][]byte),
+		auditLog: os.Stderr,
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Registers a bearer token that gives access to a signature key, or to all   */
+/* signature keys if the key name is ANY_KEY.  More than one token can be     */
+/* registered for a key.  Only a hash of the token is kept.                   */
+/*----------------------------------------------------------------------------*/
+func (s *Server) AddToken(name string, token string) error {
+	if token == "" {
+		return errors.New("A signing service token cannot be empty.")
+	}
+	hash := sha256.Sum256([]byte(token))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tokens[name] = append(s.tokens[name], hash[:])
+	return nil
+}
+
+/** Sets where the audit log is written, nil to discard it */
+func (s *Server) SetAuditLog(w io.Writer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.auditLog = w
+}
+
+/*----------------------------------------------------------------------------*/
+/* Serves requests using TLS on a TCP address.  TLS 1.2 or later is required. */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* addr -- the address to listen on, such as ":8443"                          */
+/* certFile -- PEM file holding the server certificate chain                  */
+/* keyFile -- PEM file holding the server private key                         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- the error that stopped the server                                 */
+/*----------------------------------------------------------------------------*/
+func (s *Server) ListenAndServeTLS(addr string, certFile string,
+	keyFile string) error {
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Serves requests without TLS on a TCP address.  Bearer tokens are sent in   */
+/* the clear, so this is only suitable for testing on the local workstation.  */
+/*----------------------------------------------------------------------------*/
+func (s *Server) ListenAndServe(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+/** Handles a signing service request */
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	record := AuditRecord{
+		Time:          time.Now().UTC().Format(time.RFC3339Nano),
+		RemoteAddress: remoteAddress(r),
+		Method:        r.Method,
+		Path:          r.URL.Path,
+	}
+	response, err := s.handle(r, &record)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := err.(*statusError); ok {
+			status = e.status
+		}
+		record.Status = status
+		record.Error = err.Error()
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+	} else {
+		record.Status = http.StatusOK
+		writeJSON(w, http.StatusOK, response)
+	}
+	s.audit(record)
+}
+
+/** An error reported with an HTTP status code */
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+/** Routes a request and returns the response body */
+func (s *Server) handle(r *http.Request,
+	record *AuditRecord) (interface{}, error) {
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	record.Version = common.SIGNING_SERVICE_VERSION_1
+	if parts[0] == "v2" {
+		record.Version = common.SIGNING_SERVICE_VERSION_2
+		parts = parts[1:]
+	}
+
+	switch {
+	case path == "version":
+		record.Version = 0
+		record.Operation = "version"
+		if r.Method != http.MethodGet {
+			return nil, methodNotAllowed()
+		}
+		return map[string][]int{"versions": []int{
+			common.SIGNING_SERVICE_VERSION_1,
+			common.SIGNING_SERVICE_VERSION_2}}, nil
+	case len(parts) == 2 && parts[0] == "keys":
+		record.Operation = "public_key"
+		if r.Method != http.MethodGet {
+			return nil, methodNotAllowed()
+		}
+		key, err := s.authorizedKey(r, parts[1], record)
+		if err != nil {
+			return nil, err
+		}
+		return publicKeyResponse(key, record.Version)
+	case len(parts) == 2 && parts[0] == "sign":
+		record.Operation = "sign"
+		if r.Method != http.MethodPost {
+			return nil, methodNotAllowed()
+		}
+		key, err := s.authorizedKey(r, parts[1], record)
+		if err != nil {
+			return nil, err
+		}
+		return signResponse(r, key, record)
+	}
+	return nil, &statusError{http.StatusNotFound, "Not found"}
+}
+
+/** Returns the error for a request with the wrong method */
+func methodNotAllowed() error {
+	return &statusError{http.StatusMethodNotAllowed, "Method not allowed"}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks the bearer token of a request against the tokens registered for a   */
+/* key, and returns the key.  The token is checked first, so a client without */
+/* a valid token cannot find out which keys exist.                            */
+/*----------------------------------------------------------------------------*/
+func (s *Server) authorizedKey(r *http.Request, name string,
+	record *AuditRecord) (Key, error) {
+
+	record.Key = name
+	token := r.Header.Get("Authorization")
+	if len(token) > 7 && strings.EqualFold(token[0:7], "Bearer ") {
+		token = token[7:]
+	}
+	hash := sha256.Sum256([]byte(token))
+	authorized := false
+	s.mutex.Lock()
+	for _, tokenHash := range append(s.tokens[name], s.tokens[ANY_KEY]...) {
+		if subtle.ConstantTimeCompare(hash[:], tokenHash) == 1 {
+			authorized = true
+		}
+	}
+	s.mutex.Unlock()
+	if token == "" || !authorized {
+		return nil, &statusError{http.StatusUnauthorized,
+			"The token is not valid for signature key " + name + "."}
+	}
+
+	key, err := s.keys.Key(name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, &statusError{http.StatusNotFound,
+			"Signature key " + name + " not found."}
+	}
+	ski, err := keySKI(key)
+	if err != nil {
+		return nil, err
+	}
+	record.SKI = hex.EncodeToString(ski)
+	return key, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the public key response.  Version 1 clients are sent the ASN.1     */
+/* sequence of the X and Y coordinates of a P521 EC key, base64 encoded, and  */
+/* version 2 clients a PEM PUBLIC KEY block.                                  */
+/*----------------------------------------------------------------------------*/
+func publicKeyResponse(key Key, version int) (interface{}, error) {
+	if version == common.SIGNING_SERVICE_VERSION_1 {
+		ecKey, ok := key.PublicKey().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, &statusError{http.StatusBadRequest, "Only P521 EC " +
+				"keys can be used with signing service protocol version 1."}
+		}
+		der, err := asn1.Marshal(struct{ X, Y *big.Int }{ecKey.X, ecKey.Y})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"publickey": base64.StdEncoding.EncodeToString(der)}, nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY",
+		Bytes: der})
+	return map[string]string{"publickey": string(publicKey)}, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs the data or hash in a sign request.  Version 1 requests hold the     */
+/* base64 encoded data in the input field, and version 2 requests the base64  */
+/* encoded hash in the digest field.  The hash algorithm must be sha2-512 for */
+/* P521 EC keys and sha2-256 for RSA keys.                                    */
+/*----------------------------------------------------------------------------*/
+func signResponse(r *http.Request, key Key,
+	record *AuditRecord) (interface{}, error) {
+
+	var body struct {
+		HashAlgorithm string `json:"hash_algorithm"`
+		Input         string `json:"input"`
+		Digest        string `json:"digest"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(nil, r.Body,
+		maxRequestBodySize)).Decode(&body)
+	if err != nil {
+		return nil, &statusError{http.StatusBadRequest,
+			"Invalid request body: " + err.Error()}
+	}
+	record.HashAlgorithm = body.HashAlgorithm
+
+	expected := "sha2-512"
+	digestLength := sha512.Size
+	if _, ok := key.PublicKey().(*rsa.PublicKey); ok {
+		expected = "sha2-256"
+		digestLength = sha256.Size
+		if record.Version == common.SIGNING_SERVICE_VERSION_1 {
+			return nil, &statusError{http.StatusBadRequest, "Only P521 EC " +
+				"keys can be used with signing service protocol version 1."}
+		}
+	}
+	if body.HashAlgorithm != expected {
+		return nil, &statusError{http.StatusBadRequest, "Signature key " +
+			record.Key + " requires hash algorithm " + expected + "."}
+	}
+
+	var digest []byte
+	if record.Version == common.SIGNING_SERVICE_VERSION_1 {
+		data, err := base64.StdEncoding.DecodeString(body.Input)
+		if err != nil {
+			return nil, &statusError{http.StatusBadRequest,
+				"The input is not base64 encoded."}
+		}
+		hash := sha512.Sum512(data)
+		digest = hash[:]
+	} else {
+		digest, err = base64.StdEncoding.DecodeString(body.Digest)
+		if err != nil {
+			return nil, &statusError{http.StatusBadRequest,
+				"The digest is not base64 encoded."}
+		}
+		if len(digest) != digestLength {
+			return nil, &statusError{http.StatusBadRequest,
+				"The digest is not a " + expected + " hash."}
+		}
+	}
+	record.Digest = hex.EncodeToString(digest)
+
+	signature, err := key.SignDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"signature": base64.StdEncoding.EncodeToString(signature)}, nil
+}
+
+/** Returns the Subject Key Identifier of a key */
+func keySKI(key Key) ([]byte, error) {
+	switch k := key.PublicKey().(type) {
+	case *ecdsa.PublicKey:
+		return common.CalculateECKeyHash(*k), nil
+	case *rsa.PublicKey:
+		return common.CalculateRSAKeyHash(*k)
+	}
+	return nil, errors.New("Unsupported signature key type.")
+}
+
+/** Writes a JSON response */
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+/** Writes a record to the audit log */
+func (s *Server) audit(record AuditRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.auditLog != nil {
+		s.auditLog.Write(append(line, '\n'))
+	}
+}
+
+/** Returns the address of the client, without the port */
+func remoteAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
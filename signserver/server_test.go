@@ -0,0 +1,495 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test RSA signatures made by SignDigest
+
+package signserver_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/signserver"
+)
+
+/** Collects the audit log written by a server */
+type auditBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *auditBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+/** Returns the audit records written so far */
+func (b *auditBuffer) records(t *testing.T) []signserver.AuditRecord {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	records := make([]signserver.AuditRecord, 0)
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()),
+		"\n") {
+		if line == "" {
+			continue
+		}
+		var record signserver.AuditRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("Invalid audit log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+/*----------------------------------------------------------------------------*/
+/* Test signing service with a P521 EC key named ec1 and a 2048-bit RSA key   */
+/* named rsa1.  token-ec1 gives access to ec1, token-rsa1 to rsa1, and        */
+/* token-all to every key.                                                    */
+/*----------------------------------------------------------------------------*/
+type testServer struct {
+	*httptest.Server
+	ecKey  *ecdsa.PrivateKey
+	rsaKey *rsa.PrivateKey
+	audit  *auditBuffer
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signserver.NewMemoryKeyStore()
+	if err := keys.AddPrivateKey("ec1", ecKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddPrivateKey("rsa1", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+	server := signserver.NewServer(keys)
+	server.AddToken("ec1", "token-ec1")
+	server.AddToken("rsa1", "token-rsa1")
+	server.AddToken(signserver.ANY_KEY, "token-all")
+	audit := &auditBuffer{}
+	server.SetAuditLog(audit)
+	return &testServer{Server: httptest.NewServer(server), ecKey: ecKey,
+		rsaKey: rsaKey, audit: audit}
+}
+
+/** Sends a request and returns the status and decoded response body */
+func (s *testServer) send(t *testing.T, method string, path string,
+	token string, body string) (int, map[string]interface{}, http.Header) {
+
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	var decoded map[string]interface{}
+	json.NewDecoder(rsp.Body).Decode(&decoded)
+	return rsp.StatusCode, decoded, rsp.Header
+}
+
+/** The SDK's signing service signer works with both key types */
+func TestServerWithSigningServiceSigner(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		key       string
+		token     string
+		keyType   string
+		publicKey interface{}
+	}{
+		{"ec1", "Bearer token-ec1", common.KEY_TYPE_P521EC,
+			&server.ecKey.PublicKey},
+		{"rsa1", "Bearer token-rsa1", common.KEY_TYPE_RSA2048,
+			&server.rsaKey.PublicKey},
+	}
+	for _, test := range tests {
+		signer, err := common.NewSigningServiceSigner(server.URL, test.key,
+			test.token)
+		if err != nil {
+			t.Fatalf("%s: %v", test.key, err)
+		}
+		if signer.Version() != common.SIGNING_SERVICE_VERSION_2 ||
+			signer.KeyType() != test.keyType {
+			t.Errorf("%s: version %d and key type %s", test.key,
+				signer.Version(), signer.KeyType())
+		}
+		data := []byte("administrative command")
+		signature, err := signer.Sign(data)
+		if err != nil {
+			t.Fatalf("%s: %v", test.key, err)
+		}
+		if !common.VerifySignature(test.publicKey, data, signature) {
+			t.Errorf("%s: the signature did not verify", test.key)
+		}
+	}
+
+	// Key URIs reach the server as well
+	signature, err := common.SignWithSignatureKey([]byte("data"),
+		"signsvc+http://"+server.Listener.Addr().String()+"/ec1",
+		"Bearer token-all")
+	if err != nil || !common.VerifySignature(&server.ecKey.PublicKey,
+		[]byte("data"), signature) {
+		t.Errorf("Signing using a key URI failed: %v", err)
+	}
+}
+
+/** Version 1 requests get the X and Y sequence and sign the data */
+func TestServerVersion1(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	encodedKey, err := common.SubmitQueryPublicKeyRequest(
+		common.CreateGetPublicKeyRequest("Bearer token-ec1", server.URL,
+			"ec1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := base64.StdEncoding.DecodeString(encodedKey)
+	var point struct{ X, Y *big.Int }
+	if _, err := asn1.Unmarshal(der, &point); err != nil ||
+		point.X.Cmp(server.ecKey.X) != 0 || point.Y.Cmp(server.ecKey.Y) != 0 {
+		t.Errorf("Unexpected version 1 public key %s", encodedKey)
+	}
+
+	data := []byte("administrative command")
+	encodedSignature, err := common.SubmitSignDataRequest(
+		common.CreateSignDataRequest("Bearer token-ec1", server.URL, "ec1",
+			base64.StdEncoding.EncodeToString(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, _ := base64.StdEncoding.DecodeString(encodedSignature)
+	if !common.VerifySignature(&server.ecKey.PublicKey, data, signature) {
+		t.Error("The version 1 signature did not verify")
+	}
+
+	// RSA keys need version 2
+	status, _, _ := server.send(t, "GET", "/keys/rsa1", "Bearer token-rsa1",
+		"")
+	if status != http.StatusBadRequest {
+		t.Errorf("Version 1 RSA public key request returned %d", status)
+	}
+	status, _, _ = server.send(t, "POST", "/sign/rsa1", "Bearer token-rsa1",
+		`{"hash_algorithm":"sha2-256","input":"ZGF0YQ=="}`)
+	if status != http.StatusBadRequest {
+		t.Errorf("Version 1 RSA sign request returned %d", status)
+	}
+}
+
+/** Version 2 sign requests hold a hash of the length for the key type */
+func TestServerVersion2Requests(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	sha256Hash := sha256.Sum256([]byte("data"))
+	sha512Hash := sha512.Sum512([]byte("data"))
+	digest256 := base64.StdEncoding.EncodeToString(sha256Hash[:])
+	digest512 := base64.StdEncoding.EncodeToString(sha512Hash[:])
+
+	tests := []struct {
+		key    string
+		body   string
+		status int
+	}{
+		{"ec1", `{"hash_algorithm":"sha2-512","digest":"` + digest512 + `"}`,
+			http.StatusOK},
+		{"rsa1", `{"hash_algorithm":"sha2-256","digest":"` + digest256 + `"}`,
+			http.StatusOK},
+		{"ec1", `{"hash_algorithm":"sha2-256","digest":"` + digest256 + `"}`,
+			http.StatusBadRequest},
+		{"rsa1", `{"hash_algorithm":"sha2-512","digest":"` + digest512 + `"}`,
+			http.StatusBadRequest},
+		{"ec1", `{"hash_algorithm":"sha2-512","digest":"` + digest256 + `"}`,
+			http.StatusBadRequest},
+		{"ec1", `{"hash_algorithm":"sha2-512","digest":"not base64!"}`,
+			http.StatusBadRequest},
+		{"ec1", `not JSON`, http.StatusBadRequest},
+	}
+	for i, test := range tests {
+		status, body, _ := server.send(t, "POST", "/v2/sign/"+test.key,
+			"Bearer token-all", test.body)
+		if status != test.status {
+			t.Errorf("Request %d returned %d, expected %d: %v", i+1, status,
+				test.status, body)
+		}
+	}
+
+	// The signature is over the hash sent
+	_, body, _ := server.send(t, "POST", "/v2/sign/ec1", "Bearer token-all",
+		`{"hash_algorithm":"sha2-512","digest":"`+digest512+`"}`)
+	signature, _ := base64.StdEncoding.DecodeString(body["signature"].(string))
+	if !common.VerifySignature(&server.ecKey.PublicKey, []byte("data"),
+		signature) {
+		t.Error("The version 2 signature did not verify")
+	}
+	status, body, _ := server.send(t, "GET", "/v2/keys/rsa1",
+		"Bearer token-rsa1", "")
+	if status != http.StatusOK || !strings.HasPrefix(
+		body["publickey"].(string), "-----BEGIN PUBLIC KEY-----") {
+		t.Errorf("Version 2 public key request returned %d, %v", status, body)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Each key needs one of its own tokens or a token for every key, and keys    */
+/* that do not exist are only reported to clients with a valid token          */
+/*----------------------------------------------------------------------------*/
+func TestServerAuthorization(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"/v2/keys/ec1", "Bearer token-ec1", http.StatusOK},
+		{"/v2/keys/ec1", "token-ec1", http.StatusOK},
+		{"/v2/keys/ec1", "bearer token-ec1", http.StatusOK},
+		{"/v2/keys/ec1", "Bearer token-all", http.StatusOK},
+		{"/v2/keys/ec1", "Bearer token-rsa1", http.StatusUnauthorized},
+		{"/v2/keys/ec1", "Bearer token-ec2", http.StatusUnauthorized},
+		{"/v2/keys/ec1", "Bearer ", http.StatusUnauthorized},
+		{"/v2/keys/ec1", "", http.StatusUnauthorized},
+		{"/v2/keys/ec2", "Bearer token-ec1", http.StatusUnauthorized},
+		{"/v2/keys/ec2", "Bearer token-all", http.StatusNotFound},
+		{"/keys/ec1", "Bearer token-rsa1", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		status, _, header := server.send(t, "GET", test.path, test.token, "")
+		if status != test.status {
+			t.Errorf("%s with token %q returned %d, expected %d", test.path,
+				test.token, status, test.status)
+		}
+		if status == http.StatusUnauthorized &&
+			header.Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s: no WWW-Authenticate header", test.path)
+		}
+	}
+
+	// A signer is not created with the wrong token
+	_, err := common.NewSigningServiceSigner(server.URL, "ec1",
+		"Bearer token-rsa1")
+	if err == nil {
+		t.Error("NewSigningServiceSigner succeeded with the wrong token")
+	}
+}
+
+/** Requests are routed by path and method */
+func TestServerRouting(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	status, body, _ := server.send(t, "GET", "/version", "", "")
+	versions, _ := json.Marshal(body["versions"])
+	if status != http.StatusOK || string(versions) != "[1,2]" {
+		t.Errorf("GET /version returned %d, %v", status, body)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"POST", "/version", http.StatusMethodNotAllowed},
+		{"POST", "/keys/ec1", http.StatusMethodNotAllowed},
+		{"DELETE", "/v2/keys/ec1", http.StatusMethodNotAllowed},
+		{"GET", "/sign/ec1", http.StatusMethodNotAllowed},
+		{"GET", "/v2/sign/ec1", http.StatusMethodNotAllowed},
+		{"GET", "/", http.StatusNotFound},
+		{"GET", "/keys", http.StatusNotFound},
+		{"GET", "/keys/ec1/extra", http.StatusNotFound},
+		{"GET", "/v3/keys/ec1", http.StatusNotFound},
+		{"GET", "/v2/version", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, _, _ := server.send(t, test.method, test.path,
+			"Bearer token-all", "")
+		if status != test.status {
+			t.Errorf("%s %s returned %d, expected %d", test.method,
+				test.path, status, test.status)
+		}
+	}
+}
+
+/** Every request is recorded, and tokens are never written to the log */
+func TestServerAuditLog(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	hash := sha512.Sum512([]byte("data"))
+	server.send(t, "POST", "/v2/sign/ec1", "Bearer token-ec1",
+		`{"hash_algorithm":"sha2-512","digest":"`+
+			base64.StdEncoding.EncodeToString(hash[:])+`"}`)
+	server.send(t, "GET", "/keys/ec1", "Bearer token-rsa1", "")
+
+	records := server.audit.records(t)
+	if len(records) != 2 {
+		t.Fatalf("%d audit records were written", len(records))
+	}
+	sign := records[0]
+	ski := hex.EncodeToString(common.CalculateECKeyHash(server.ecKey.PublicKey))
+	if sign.Operation != "sign" || sign.Version != 2 || sign.Key != "ec1" ||
+		sign.SKI != ski || sign.HashAlgorithm != "sha2-512" ||
+		sign.Digest != hex.EncodeToString(hash[:]) ||
+		sign.Status != http.StatusOK || sign.Method != "POST" ||
+		sign.RemoteAddress != "127.0.0.1" || sign.Time == "" {
+		t.Errorf("Unexpected sign record %+v", sign)
+	}
+	denied := records[1]
+	if denied.Operation != "public_key" || denied.Version != 1 ||
+		denied.Status != http.StatusUnauthorized || denied.SKI != "" ||
+		denied.Error == "" {
+		t.Errorf("Unexpected public key record %+v", denied)
+	}
+
+	server.audit.mutex.Lock()
+	log := server.audit.buf.String()
+	server.audit.mutex.Unlock()
+	if strings.Contains(log, "token-") {
+		t.Error("A token was written to the audit log")
+	}
+}
+
+/** Keys can be read from signature key files, and invalid keys are rejected */
+func TestMemoryKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	path := filepath.Join(dir, "admin1.sigkey")
+	skfields, err := common.EncryptSignatureKey(ecKey, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = common.WriteSignatureKeyFile(path, skfields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := signserver.NewMemoryKeyStore()
+	if err := keys.AddKeyFile("admin1", path, "wrong"); err == nil {
+		t.Error("AddKeyFile accepted the wrong password")
+	}
+	if err := keys.AddKeyFile("admin1", path, "password1"); err != nil {
+		t.Fatal(err)
+	}
+	key, err := keys.Key("admin1")
+	if err != nil || key == nil {
+		t.Fatalf("Key returned %v, %v", key, err)
+	}
+	if key.PublicKey().(*ecdsa.PublicKey).X.Cmp(ecKey.X) != 0 {
+		t.Error("The key file holds a different key")
+	}
+	if key, err := keys.Key("admin2"); key != nil || err != nil {
+		t.Errorf("Key returned %v, %v for a missing key", key, err)
+	}
+	if _, err := key.SignDigest(make([]byte, 32)); err == nil {
+		t.Error("A P521 EC key signed a 32-byte hash")
+	}
+
+	for _, name := range []string{"", "a/b"} {
+		if keys.Add(name, key) == nil {
+			t.Errorf("Add accepted key name %q", name)
+		}
+	}
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := signserver.NewPrivateKey(p256Key); err == nil {
+		t.Error("NewPrivateKey accepted a P256 key")
+	}
+	if signserver.NewServer(keys).AddToken("admin1", "") == nil {
+		t.Error("AddToken accepted an empty token")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* RSA keys sign the hash in ANSI X9.31 format, as Signature256 does, and a   */
+/* private key that gives a wrong result does not return a signature          */
+/*----------------------------------------------------------------------------*/
+func TestRSASignDigest(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := signserver.NewPrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("data")
+	digest := sha256.Sum256(data)
+	signature, err := key.SignDigest(digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RSA signatures in this format have no random part
+	m := new(big.Int).SetBytes(
+		common.PadANSIX931(digest[:], 0, len(digest), 2048))
+	expected := m.Exp(m, rsaKey.D, rsaKey.N).Bytes()
+	if len(signature) != 256 ||
+		!bytes.Equal(bytes.TrimLeft(signature, "\x00"), expected) {
+		t.Errorf("Unexpected signature %X", signature)
+	}
+	if !bytes.Equal(signature, common.Signature256(data, rsaKey)) {
+		t.Error("The signature differs from the Signature256 signature")
+	}
+	if _, err := key.SignDigest(digest[:31]); err == nil {
+		t.Error("An RSA key signed a 31-byte hash")
+	}
+
+	faulty := *rsaKey
+	faulty.D = new(big.Int).Add(rsaKey.D, big.NewInt(2))
+	key, err = signserver.NewPrivateKey(&faulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature, err := key.SignDigest(digest[:]); err == nil {
+		t.Errorf("A faulty private key returned signature %X", signature)
+	}
+}
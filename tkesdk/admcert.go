@@ -7,18 +7,16 @@
 //
 // Date          Initials        Description
 // 04/30/2021    CLH             Initial version
+// 10/18/2026    CLH             Use common.Signer
 
 package tkesdk
 
 import (
 	"crypto/x509"
 	"encoding/hex"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
-	"io/ioutil"
 
-	"github.com/Logicalis/asn1"
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
 )
@@ -43,48 +41,17 @@ import (
 func CreateAdminCertFromFile(sigkey string, ski string,
 	sigkeyToken string, adminName string) ([]byte, error) {
 
-	// Initialize an output variable
-	certBytes := make([]byte, 0)
-
-	// Read the signature key file
-	data, err := ioutil.ReadFile(sigkey)
-	if err != nil {
-		return certBytes, err
-	}
-	var fields map[string]string
-	err = json.Unmarshal(data, &fields)
+	// Read the signature key file and decrypt the signature key
+	signer, err := common.NewKeyFileSigner(sigkey, sigkeyToken)
 	if err != nil {
-		return certBytes, err
+		return make([]byte, 0), err
 	}
 
-	// Derive the encryption key from the file password and salt field
-	aeskey, _ := common.Derive_aes_key(sigkeyToken, fields["seaSalt"])
-
-	// Decrypt the signature key
-	enckey, err := hex.DecodeString(fields["enckey"])
-	if err != nil {
-		return certBytes, err
-	}
-	pemBytes, err := common.Decrypt(enckey, aeskey)
-	if err != nil {
-		return certBytes, errors.New("Invalid password for a signature key file")
-	}
-
-	// Process the signature key
-	if fields["keyType"] == "p521ec" {
-		// Process a P521 EC key
-		certBytes, err = CreateAdminCertForECKey(pemBytes, ski, adminName)
-		if err != nil {
-			return certBytes, err
-		}
-	} else {
-		// Process a 2048-bit RSA key
-		certBytes, err = CreateAdminCertForRSAKey(pemBytes, ski, adminName)
-		if err != nil {
-			return certBytes, err
-		}
+	// Compare the calculated and saved SKIs
+	if hex.EncodeToString(signer.SKI()) != ski {
+		return make([]byte, 0), errors.New("Miscompare on saved and calculated Subject Key Identifier.")
 	}
-	return certBytes, nil
+	return ep11cmds.CreateAdminCert(signer, adminName)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -114,27 +81,18 @@ func CreateAdminCertForRSAKey(pemBytes []byte, savedSKI string, adminName string
 		return nil, err
 	}
 
-	// Calculate the Subject Key Identifier	(SKI)
-	ski, err := common.CalculateRSAKeyHash(rsaKey.PublicKey)
+	signer, err := common.NewPrivateKeySigner(rsaKey)
 	if err != nil {
 		return nil, err
 	}
 
 	// Compare the calculated and saved SKIs
-	if hex.EncodeToString(ski) != savedSKI {
+	if hex.EncodeToString(signer.SKI()) != savedSKI {
 		return nil, errors.New("Miscompare on saved and calculated Subject Key Identifier.")
 	}
 
 	// Create an administrator certificate with the RSA public key
-	var cert ep11cmds.CertificateRSA2048
-	cert.Initialize()
-	cert.SetAdminName([]byte(adminName))
-	pubKey := []byte{0}
-	pubKey = append(pubKey, rsaKey.PublicKey.N.Bytes()...)
-	cert.SetPublicKey(pubKey)
-	cert.SetSignature(rsaKey)
-
-	return asn1.Encode(cert)
+	return ep11cmds.CreateAdminCertRSA2048(signer, adminName)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -164,14 +122,16 @@ func CreateAdminCertForECKey(pemBytes []byte, savedSKI string, adminName string)
 		return nil, err
 	}
 
-	// Calculate the Subject Key Identifier	(SKI)
-	ski := common.CalculateECKeyHash(ecKey.PublicKey)
+	signer, err := common.NewPrivateKeySigner(ecKey)
+	if err != nil {
+		return nil, err
+	}
 
 	// Compare the calculated and saved SKIs
-	if hex.EncodeToString(ski[:]) != savedSKI {
+	if hex.EncodeToString(signer.SKI()) != savedSKI {
 		return nil, errors.New("Miscompare on saved and calculated Subject Key Identifier.")
 	}
 
 	// Create an administrator certificate with the EC public key
-	return ep11cmds.CreateAdminCertP521EC(*ecKey, adminName)
+	return ep11cmds.CreateAdminCertP521ECWithSigner(signer, adminName)
 }
@@ -9,15 +9,33 @@
 // 05/03/2021    CLH             Initial version
 // 07/23/2021    CLH             Change message when a signature key cannot be used
 // 01/09/2025    CLH             Compare only first 28 bytes of MK verification pattern
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Add administrators in a repeatable order
+// 10/18/2026    CLH             Get signing service URL from common
+// 10/18/2026    CLH             Use common.Signer
+// 10/18/2026    CLH             Support signature keys in PKCS #11 tokens
+// 10/18/2026    CLH             Resolve key URIs using registered schemes
+// 10/18/2026    CLH             Check crypto unit support for Dilithium keys
+// 10/18/2026    CLH             Report Vault signature key problems
+// 10/18/2026    CLH             Check the signer preference order
+// 10/18/2026    CLH             Check the master key part policy
+// 10/18/2026    CLH             Create signers with CommonInputs.HTTPClient
+// 10/18/2026    CLH             Check transitions without signature keys
+// 10/18/2026    CLH             Require the master key part policy
+// 10/18/2026    CLH             Create signers once and close them afterwards
+// 10/18/2026    CLH             Default the key part policy to one key part
 
 package tkesdk
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
-	"os"
+	"sort"
 	"strings"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
 )
 
 /*----------------------------------------------------------------------------*/
@@ -25,6 +43,8 @@ import (
 /* state to final state is possible.                                          */
 /*                                                                            */
 /* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
 /* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
 /*      functions.  This includes: the API endpoint and region, the HPCS      */
 /*      service instance id, and an IBM Cloud authentication token.           */
@@ -39,36 +59,105 @@ import (
 /*      not possible                                                          */
 /* error -- identifies any error encountered when running the function        */
 /*----------------------------------------------------------------------------*/
-func CheckTransition(ci CommonInputs, hc HsmConfig) ([]string, error) {
+func CheckTransitionWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig) ([]string, error) {
+
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
 
 	// Check inputs in the resource block
-	problems, err := checkInputs(hc)
-	if err != nil {
-		return make([]string, 0), err
-	}
+	problems := checkInputs(hc, admins)
 	if len(problems) > 0 {
 		return problems, nil
 	}
 
 	// Read the initial configuration
-	hsminfo, _, _, err := internalQuery(ci)
+	hsminfo, tr, domains, err := internalQuery(ctx, ci)
+	if err != nil {
+		return make([]string, 0), err
+	}
+
+	// Identify what signature keys are in the resource block
+	suppliedSKIs, signerMap, adminNameMap, err := getSignatureKeys(admins)
 	if err != nil {
 		return make([]string, 0), err
 	}
 
 	// Check for invalid transitions
-	problems, err, _, _, _ = internalCheckTransition(ci, hc, hsminfo)
+	problems, err, _, addSKIs, _ := internalCheckTransition(hc,
+		hsminfo, suppliedSKIs, adminNameMap)
 	if err != nil {
 		return make([]string, 0), err
 	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
 
-	return problems, nil
+	// Check that administrators with Dilithium signature keys can be added
+	return checkDilithiumSupport(ctx, tr, domains, addSKIs,
+		signerKeyTypes(signerMap), adminNameMap)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as CheckTransitionWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func CheckTransition(ci CommonInputs, hc HsmConfig) ([]string, error) {
+	return CheckTransitionWithContext(context.Background(), ci, hc)
 }
 
 /*----------------------------------------------------------------------------*/
 /* Check for problems with the inputs specified by the user.                  */
 /*----------------------------------------------------------------------------*/
-func checkInputs(hc HsmConfig) ([]string, error) {
+func checkInputs(hc HsmConfig, admins *adminSigners) []string {
+
+	problems := checkConfig(hc)
+
+	allKeysValid := true
+	for i, admin := range hc.Admins {
+		if !validKey(admins.signers[i]) {
+			ssURL := common.GetSigningServiceURL()
+			scheme := common.KeyURIScheme(admin.Key)
+			if admin.Signer != nil {
+				problems = append(problems, "The signature key associated with " +
+					admin.Name + " could not be accessed.")
+			} else if scheme == "pkcs11" {
+				problems = append(problems, "The signature key associated with " +
+					admin.Name + " could not be accessed in the PKCS #11 token.  " +
+					"Check the PKCS #11 URI and the PIN.")
+			} else if scheme == "vault" || scheme == "vault+http" {
+				problems = append(problems, "The signature key associated with " +
+					admin.Name + " could not be accessed in Vault.  Check the " +
+					"Vault address, the transit key name, and the Vault token " +
+					"or AppRole secret ID.")
+			} else if scheme == "signsvc" || scheme == "signsvc+http" ||
+				(scheme == "" && ssURL != "") {
+				problems = append(problems, "The signature key associated with " +
+					admin.Name + " could not be accessed.  An attempt was made " +
+					"to use a signing service.  The signing service may not be " +
+					"running at the specified URL and port.")
+			} else {
+				problems = append(problems, "The signature key associated with " +
+					admin.Name + " could not be accessed.")
+			}
+			allKeysValid = false
+		}
+	}
+
+	if allKeysValid {
+		if !keysAreUnique(admins.signers) {
+			problems = append(problems, "Signature keys are not unique.  The same signature key is specified for more than one administrator.")
+		}
+	}
+
+	return problems
+}
+
+/*----------------------------------------------------------------------------*/
+/* Check for problems with the thresholds, administrator names, and other     */
+/* inputs that do not depend on the signature keys.                           */
+/*----------------------------------------------------------------------------*/
+func checkConfig(hc HsmConfig) []string {
 
 	problems := make([]string, 0)
 	if hc.SignatureThreshold < 1 || hc.SignatureThreshold > 8 {
@@ -87,52 +176,49 @@ func checkInputs(hc HsmConfig) ([]string, error) {
 		problems = append(problems, "No more than 8 administrators can be specified.")
 	}
 
-	allKeysValid := true
+	adminNames := make(map[string]bool)
 	for _, admin := range hc.Admins {
-		if len(admin.Name) > 30 {
-			problems = append(problems, "An administrator name is too long.  Names must be 30 characters or less.")
-		}
-		if !validKey(admin) {
-			ssURL := os.Getenv("TKE_SIGNSERV_URL")
-			if ssURL != "" {
-				problems = append(problems, "The signature key associated with " +
-					admin.Name + " could not be accessed.  An attempt was made " +
-					"to use a signing service.  The signing service may not be " +
-					"running at the specified URL and port.")
-			} else {
-				problems = append(problems, "The signature key associated with " +
-					admin.Name + " could not be accessed.")
-			}
-			allKeysValid = false
+		adminNames[admin.Name] = true
+	}
+	preferredNames := make(map[string]bool)
+	for _, name := range hc.SignerPreference {
+		if !adminNames[name] {
+			problems = append(problems, "The signer preference order " +
+				"includes " + name + ", which is not an administrator name.")
+		} else if preferredNames[name] {
+			problems = append(problems, "The signer preference order " +
+				"includes " + name + " more than once.")
 		}
+		preferredNames[name] = true
 	}
 
-	if allKeysValid {
-		uniqueKeys, err := keysAreUnique(hc.Admins)
-		if err != nil {
-			return problems, err
-		}
-		if !uniqueKeys {
-			problems = append(problems, "Signature keys are not unique.  The same signature key is specified for more than one administrator.")
+	// The key part policy fields count as 1 when they are not set
+	if _, _, err := keyPartPolicy(hc); err != nil {
+		if hc.MasterKeyParts < 0 || hc.MasterKeyParts > 255 {
+			problems = append(problems, "The number of master key parts must be an integer between 1 and 255.")
+		} else {
+			problems = append(problems, "The number of master key parts required must be an integer between 1 and the number of master key parts.")
 		}
 	}
 
-	return problems, nil
+	for _, admin := range hc.Admins {
+		if len(admin.Name) > 30 {
+			problems = append(problems, "An administrator name is too long.  Names must be 30 characters or less.")
+		}
+	}
+	return problems
 }
 
 /*----------------------------------------------------------------------------*/
 /* Checks whether the transition is allowed.                                  */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
-/*      functions.  This includes: the API endpoint and region, the HPCS      */
-/*      service instance id, and an IBM Cloud authentication token.           */
-/* HsmConfig -- A structure containing information from the hsm_config        */
-/*      section of the resource block for the HPCS service instance.  This    */
-/*      provides access to signature keys for signing commands to crypto      */
-/*      units.                                                                */
+/* HsmConfig -- the desired final thresholds                                  */
 /* []HsmInfo -- Contains information on the initial configuration of each     */
 /*      crypto unit.                                                          */
+/* map[string]bool -- set of the Subject Key Identifiers of the               */
+/*      administrators in the desired final configuration                     */
+/* map[string]string -- maps SKI --> administrator name                       */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* []string -- set of messages identifying either an invalid input or a       */
@@ -149,8 +235,9 @@ func checkInputs(hc HsmConfig) ([]string, error) {
 /* [][]string -- the Subject Key Identifiers of the existing administrators   */
 /*      to be removed from the crypto unit                                    */
 /*----------------------------------------------------------------------------*/
-func internalCheckTransition(ci CommonInputs, hc HsmConfig,
-	hsminfo []HsmInfo) ([]string, error, [][]string, [][]string, [][]string) {
+func internalCheckTransition(hc HsmConfig, hsminfo []HsmInfo,
+	finalSKIs map[string]bool, adminNameMap map[string]string) ([]string,
+	error, [][]string, [][]string, [][]string) {
 
 	// Initialize the output variables
 	problems := make([]string, 0)
@@ -170,12 +257,6 @@ func internalCheckTransition(ci CommonInputs, hc HsmConfig,
 		problems = append(problems, "The service instance does not contain any recovery crypto units.")
 	}
 
-	// Determine the desired final set of administrator SKIs for all crypto units
-	finalSKIs, _, _, adminNameMap, err := GetSignatureKeysFromResourceBlock(hc)
-	if err != nil {
-		return problems, err, allKeepSKIs, allAddSKIs, allRmvSKIs
-	}
-
 	// For each crypto unit, figure out what administrators we want to keep,
 	// what administrators we want to add, and what administrators we want
 	// to remove.  Determine whether the changes are possible.
@@ -207,6 +288,9 @@ func internalCheckTransition(ci CommonInputs, hc HsmConfig,
 				addSKIs = append(addSKIs, ski)
 			}
 		}
+		// Map iteration order is random.  Sort so the same commands are
+		// issued in the same order each time.
+		sort.Strings(addSKIs)
 
 		allKeepSKIs = append(allKeepSKIs, keepSKIs)
 		allAddSKIs  = append(allAddSKIs, addSKIs)
@@ -313,17 +397,82 @@ func internalCheckTransition(ci CommonInputs, hc HsmConfig,
 }
 
 /*----------------------------------------------------------------------------*/
-/* Checks whether a signature key can be used.                                */
+/* Checks that administrators with Dilithium signature keys are only added to */
+/* crypto units that support them.  The OA certificate is read only for       */
+/* crypto modules where a Dilithium administrator would be added.             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*      units                                                                 */
+/* []common.DomainEntry -- the crypto units assigned to the service instance  */
+/* [][]string -- the Subject Key Identifiers of new administrators to be      */
+/*      added to each crypto unit, from internalCheckTransition               */
+/* map[string]string -- maps SKI --> key type of the signature key            */
+/* map[string]string -- maps SKI --> administrator name                       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- messages identifying crypto units that do not support the      */
+/*      Dilithium administrators to be added                                  */
+/* error -- reports any error reading an OA certificate                       */
 /*----------------------------------------------------------------------------*/
-func validKey(ai AdminInfo) bool {
+func checkDilithiumSupport(ctx context.Context, tr common.Transport,
+	domains []common.DomainEntry, addSKIs [][]string,
+	keyTypes map[string]string,
+	adminNameMap map[string]string) ([]string, error) {
 
-	// Tries to sign some data.  If successful, the signature key can be used.
+	problems := make([]string, 0)
+	// Maps crypto module location --> Dilithium administrators supported
+	moduleSupport := make(map[string]bool)
+	for i, domain := range domains {
+		for _, ski := range addSKIs[i] {
+			if keyTypes[ski] != common.KEY_TYPE_DILITHIUM_R2_87 {
+				continue
+			}
+			partialLocation := common.GetPartialLocation(domain.Location)
+			supported, found := moduleSupport[partialLocation]
+			if !found {
+				var err error
+				supported, err = ep11cmds.DilithiumAdminsSupportedWithContext(
+					ctx, tr, domain)
+				if err != nil {
+					return make([]string, 0), err
+				}
+				moduleSupport[partialLocation] = supported
+			}
+			if !supported {
+				problems = append(problems, "Administrator "+
+					strings.TrimSpace(adminNameMap[ski])+" has a Dilithium "+
+					"signature key, but the crypto unit at "+domain.Location+
+					" does not support Dilithium administrators.")
+			}
+		}
+	}
+	return problems, nil
+}
 
-	// SignWithSignatureKey handles both signature keys stored in files and
-	// signature keys accessed by a signing service.
+/** Returns the key type of each signature key, for checkDilithiumSupport */
+func signerKeyTypes(signerMap map[string]common.Signer) map[string]string {
+	keyTypes := make(map[string]string)
+	for ski, signer := range signerMap {
+		keyTypes[ski] = signer.KeyType()
+	}
+	return keyTypes
+}
 
+/*----------------------------------------------------------------------------*/
+/* Checks whether a signature key can be used.  signer is nil if it could not */
+/* be created.                                                                */
+/*----------------------------------------------------------------------------*/
+func validKey(signer common.Signer) bool {
+
+	// Tries to sign some data.  If successful, the signature key can be used.
+
+	if signer == nil {
+		return false
+	}
 	dataToSign := make([]byte, 100)
-	_, err := common.SignWithSignatureKey(dataToSign, ai.Key, ai.Token)
+	_, err := signer.Sign(dataToSign)
 	return err == nil
 }
 
@@ -331,22 +480,18 @@ func validKey(ai AdminInfo) bool {
 /* Checks that a unique key is specified for each administrator.              */
 /*                                                                            */
 /* Input:                                                                     */
-/* []AdminInfo -- administrator signature key information from the Terraform  */
+/* []common.Signer -- the signers for the administrators in the Terraform     */
 /*     resource block                                                         */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* bool -- true if unique signature keys are specified, false if a signature  */
 /*     key is specified more than once                                        */
-/* error -- reports any error found during processing                         */
 /*----------------------------------------------------------------------------*/
-func keysAreUnique(admins []AdminInfo) (bool, error) {
+func keysAreUnique(signers []common.Signer) bool {
+
 	skis := make(map[string]bool)
-	for _, admin := range admins {
-		ski, err := GetSigKeySKI(admin.Key, admin.Token)
-		if err != nil {
-			return false, err
-		}
-		skis[ski] = true
+	for _, signer := range signers {
+		skis[hex.EncodeToString(signer.SKI())] = true
 	}
-	return len(skis) == len(admins), nil
+	return len(skis) == len(signers)
 }
@@ -0,0 +1,108 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Stand-in for a Dilithium signer, whose signatures are never checked */
+type testDilithiumSigner struct {
+	key *common.DilithiumPublicKey
+}
+
+func newTestDilithiumSigner(t *testing.T) *testDilithiumSigner {
+	rho := make([]byte, common.DILITHIUM_R2_87_RHO_LENGTH)
+	t1 := make([]byte, common.DILITHIUM_R2_87_T1_LENGTH)
+	rand.Read(rho)
+	rand.Read(t1)
+	key, err := common.NewDilithiumPublicKey(rho, t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testDilithiumSigner{key: key}
+}
+
+func (s *testDilithiumSigner) SKI() []byte {
+	return common.CalculateDilithiumKeyHash(*s.key)
+}
+
+func (s *testDilithiumSigner) KeyType() string {
+	return common.KEY_TYPE_DILITHIUM_R2_87
+}
+
+func (s *testDilithiumSigner) PublicKey() crypto.PublicKey {
+	return s.key
+}
+
+func (s *testDilithiumSigner) Sign(data []byte) ([]byte, error) {
+	return make([]byte, common.DILITHIUM_R2_87_SIGNATURE_LENGTH), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Dilithium administrators are reported as problems on crypto units whose OA */
+/* certificate holds no Dilithium key, and nothing is sent to the units       */
+/*----------------------------------------------------------------------------*/
+func TestDilithiumAdminsNotSupported(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The emulated crypto units have OA3 certificates without Dilithium keys
+	for _, domain := range domains {
+		supported, err := ep11cmds.DilithiumAdminsSupported(em, domain)
+		if err != nil || supported {
+			t.Errorf("DilithiumAdminsSupported for %s returned %v, %v",
+				domain.Location, supported, err)
+		}
+	}
+
+	hc := newTestHsmConfig(t)
+	hc.Admins[2] = tkesdk.AdminInfo{Name: "admin3",
+		Signer: newTestDilithiumSigner(t)}
+
+	for name, check := range map[string]func() ([]string, error){
+		"CheckTransition": func() ([]string, error) {
+			return tkesdk.CheckTransition(ci, hc)
+		},
+		"Update": func() ([]string, error) {
+			return tkesdk.Update(ci, hc)
+		},
+	} {
+		problems, err := check()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		found := 0
+		for _, problem := range problems {
+			if strings.Contains(problem, "admin3 has a Dilithium") {
+				found++
+			}
+		}
+		if found != len(domains) {
+			t.Errorf("%s reported %v", name, problems)
+		}
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if len(hsm.Admins) != 0 {
+			t.Errorf("Administrators were added to %s", hsm.HsmLocation)
+		}
+	}
+}
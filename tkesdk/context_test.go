@@ -0,0 +1,154 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Transport that cancels a context when a given request is submitted.  The   */
+/* request is still passed on.  Requests passed on after the cancellation     */
+/* with a context that is not cancelled are signed commands, which must be    */
+/* allowed to complete.                                                       */
+/*----------------------------------------------------------------------------*/
+type cancellingTransport struct {
+	common.Transport
+	mutex     sync.Mutex
+	cancel    context.CancelFunc
+	cancelAt  int
+	requests  int
+	completed int
+	failed    int
+}
+
+func (c *cancellingTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	c.mutex.Lock()
+	c.requests++
+	if c.requests == c.cancelAt {
+		c.cancel()
+	}
+	cancelled := c.requests >= c.cancelAt
+	c.mutex.Unlock()
+
+	htpResponse, err := c.Transport.SubmitHTPRequest(ctx, cryptoInstance,
+		hsmId, htpRequest)
+	if cancelled && ctx.Err() == nil {
+		c.mutex.Lock()
+		if err == nil {
+			c.completed++
+		} else {
+			c.failed++
+		}
+		c.mutex.Unlock()
+	}
+	return htpResponse, err
+}
+
+/** Functions return the context error without sending requests */
+func TestCancelledContext(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	counter := &countingTransport{Transport: em}
+	ci.Transport = counter
+	hc := newTestHsmConfig(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tkesdk.QueryWithContext(ctx, ci); err != context.Canceled {
+		t.Errorf("Query returned %v", err)
+	}
+	if _, err := tkesdk.UpdateWithContext(ctx, ci, hc); err != context.Canceled {
+		t.Errorf("Update returned %v", err)
+	}
+	if err := tkesdk.ZeroizeWithContext(ctx, ci, hc); err != context.Canceled {
+		t.Errorf("Zeroize returned %v", err)
+	}
+	if counter.requests != 0 {
+		t.Errorf("%d requests were sent with a cancelled context",
+			counter.requests)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(),
+		time.Now().Add(-time.Second))
+	defer cancel()
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ep11cmds.QueryDomainInfoWithContext(ctx, em, domains[0])
+	if err != context.DeadlineExceeded {
+		t.Errorf("QueryDomainInfo returned %v", err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Update is cancelled at each request in turn.  It stops with the context    */
+/* error, a signed command that was already sent completes, and running       */
+/* Update again finishes the configuration.                                   */
+/*----------------------------------------------------------------------------*/
+func TestUpdateCancelledBetweenCommands(t *testing.T) {
+	units := defaultTestUnits[:2]
+	hc := newTestHsmConfig(t)
+
+	// Count the requests sent by an uninterrupted Update
+	em, ci := newTestInstance(t, "instance1", units)
+	counter := &countingTransport{Transport: em}
+	ci.Transport = counter
+	mustUpdate(t, ci, hc)
+	em.Close()
+	total := counter.requests
+
+	signedCompleted := 0
+	for cancelAt := 1; cancelAt <= total; cancelAt += 3 {
+		em, ci := newTestInstance(t, "instance1", units)
+		ctx, cancel := context.WithCancel(context.Background())
+		tr := &cancellingTransport{Transport: em, cancel: cancel,
+			cancelAt: cancelAt}
+		ci.Transport = tr
+
+		problems, err := tkesdk.UpdateWithContext(ctx, ci, hc)
+		cancel()
+		if err != context.Canceled && (err != nil || len(problems) > 0) {
+			t.Errorf("Cancelled at request %d: Update returned %v %v",
+				cancelAt, problems, err)
+		}
+		if tr.failed > 0 {
+			t.Errorf("Cancelled at request %d: %d signed commands failed",
+				cancelAt, tr.failed)
+		}
+		signedCompleted += tr.completed
+
+		ci.Transport = em
+		mustUpdate(t, ci, hc)
+		for _, hsm := range mustQuery(t, ci) {
+			if len(hsm.Admins) != len(hc.Admins) ||
+				hsm.SignatureThreshold != hc.SignatureThreshold ||
+				hsm.CurrentMKStatus != "Valid" {
+				t.Errorf("Cancelled at request %d: crypto unit %s is not "+
+					"configured", cancelAt, hsm.HsmLocation)
+			}
+		}
+		em.Close()
+	}
+	if signedCompleted == 0 {
+		t.Error("No signed command was sent when the context was cancelled")
+	}
+}
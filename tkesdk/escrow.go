@@ -0,0 +1,495 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Combine key parts with an M policy locally
+// 10/18/2026    CLH             Recover the output of commands whose response was lost
+// 10/18/2026    CLH             Let the crypto units combine escrowed key parts
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Create signers once and close them afterwards
+// 10/18/2026    CLH             Verify the saved OA certificate chain and
+//                               restore through key part holders
+
+package tkesdk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/** Version of the master key escrow file format */
+const MASTER_KEY_ESCROW_VERSION = 1
+
+/*----------------------------------------------------------------------------*/
+/* A copy of the master key of a service instance, exported from a recovery   */
+/* crypto unit in key parts encrypted under P521 EC public keys held by the   */
+/* customer.  Byte fields are hexadecimal strings.                            */
+/*----------------------------------------------------------------------------*/
+type MasterKeyEscrow struct {
+	Version              int               `json:"version"`
+	Created              string            `json:"created"`
+	InstanceId           string            `json:"instance_id"`
+	SourceLocation       string            `json:"source_location"`
+	SourceSerialNum      string            `json:"source_serial_num"`
+	SourceOACertificates []string          `json:"source_oa_certificates"`
+		// OA certificate chain of the crypto module that exported the key
+		// parts, starting with the epoch certificate whose public key
+		// signed them
+	VerificationPattern  string            `json:"verification_pattern"`
+	MPolicy              int               `json:"m_policy"`
+		// Number of key parts needed to restore the master key
+	KeyParts             []EscrowedKeyPart `json:"key_parts"`
+}
+
+/** An encrypted key part in a master key escrow */
+type EscrowedKeyPart struct {
+	Index         int    `json:"index"`
+	RecipientSKI  string `json:"recipient_ski"` // SKI of the P521 EC key
+	RecipientInfo string `json:"recipient_info"`
+	OASignature   string `json:"oa_signature"` // SignerInfo
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports the current master key of a service instance for disaster          */
+/* recovery outside of IBM Cloud.                                             */
+/*                                                                            */
+/* The current master key register of a recovery crypto unit is exported with */
+/* one key part for each P521 EC public key provided, using a KPH certificate */
+/* for the public key.  Each key part can only be decrypted with the matching */
+/* private key.  The OA signature over each key part is verified before the   */
+/* escrow is returned.  The OA certificate chain of the crypto module is      */
+/* saved in the escrow so the OA signatures can be verified again when the    */
+/* escrow is restored.                                                        */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.  This includes: the API endpoint and region, the HPCS      */
+/*      service instance id, and an IBM Cloud authentication token.           */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*      section of the resource block for the HPCS service instance.  This    */
+/*      provides access to signature keys for signing commands to crypto      */
+/*      units.                                                                */
+/* []ecdsa.PublicKey -- the P521 EC public keys of the key part holders       */
+/* int -- the M policy, the number of key parts needed to restore the master  */
+/*      key.  With an M policy of 1, any one key part holder can restore it.  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *MasterKeyEscrow -- the escrow, to be saved with MasterKeyEscrow.Save      */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the master key cannot be exported                              */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func EscrowMasterKeyWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig, recipients []ecdsa.PublicKey,
+	mPolicy int) (*MasterKeyEscrow, []string, error) {
+
+	if len(recipients) == 0 || len(recipients) > 255 {
+		return nil, []string{"Between 1 and 255 public keys must be " +
+			"provided to escrow the master key."}, nil
+	}
+	if mPolicy < 1 || mPolicy > len(recipients) {
+		return nil, []string{"Invalid M policy " + strconv.Itoa(mPolicy) +
+			".  The M policy must be between 1 and the number of public " +
+			"keys, " + strconv.Itoa(len(recipients)) + "."}, nil
+	}
+	for i, recipient := range recipients {
+		if recipient.Curve != elliptic.P521() || recipient.X == nil ||
+			recipient.Y == nil {
+			return nil, []string{"Public key " + strconv.Itoa(i+1) +
+				" is not a P521 EC public key."}, nil
+		}
+	}
+
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+
+	hsminfo, tr, domains, signers, problems, err :=
+		prepareMasterKeyRotation(ctx, ci, hc, admins)
+	if err != nil || len(problems) > 0 {
+		return nil, problems, err
+	}
+
+	// Every crypto unit must have the same current master key
+	for i := range hsminfo {
+		if hsminfo[i].CurrentMKStatus == "Empty" {
+			problems = append(problems, "The current master key register "+
+				"of crypto unit "+hsminfo[i].HsmLocation+" is empty.")
+		} else if !sameMKVP(hsminfo[i].CurrentMKVP, hsminfo[0].CurrentMKVP) {
+			problems = append(problems, "Current master key registers are "+
+				"not set to the same value.  Complete any master key "+
+				"rotation before escrowing the master key.")
+			break
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	source := -1
+	for i := range hsminfo {
+		if hsminfo[i].HsmType == "recovery" {
+			source = i
+			break
+		}
+	}
+	if source < 0 {
+		return nil, []string{"No recovery crypto unit is assigned to the " +
+			"service instance.  The master key can only be exported from a " +
+			"recovery crypto unit."}, nil
+	}
+
+	// Save the OA certificate chain that certifies the OA signature key
+	chain, err := readOACertificateChain(ctx, tr, domains[source])
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+	certified, err := verifySavedOACertificateChain(ctx, tr, chain)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+	if certified.Public_key != domains[source].Public_key {
+		return nil, make([]string, 0), errors.New("The OA certificate " +
+			"chain of crypto unit " + hsminfo[source].HsmLocation + " does " +
+			"not certify its OA signature key.")
+	}
+
+	kphcerts := make([][]byte, len(recipients))
+	for i, recipient := range recipients {
+		kphcerts[i] = ep11cmds.KPHCert(recipient)
+	}
+	keyParts, err := exportKeyParts(ctx, tr, domains[source], false,
+		kphcerts, mPolicy, signers[source].threshold)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+
+	escrow := &MasterKeyEscrow{
+		Version:             MASTER_KEY_ESCROW_VERSION,
+		Created:             time.Now().UTC().Format(time.RFC3339),
+		InstanceId:          ci.InstanceId,
+		SourceLocation:      hsminfo[source].HsmLocation,
+		SourceSerialNum:     domains[source].Serial_num,
+		VerificationPattern: hsminfo[source].CurrentMKVP,
+		MPolicy:             mPolicy,
+		KeyParts:            make([]EscrowedKeyPart, 0, len(keyParts)),
+	}
+	for _, certbytes := range chain {
+		escrow.SourceOACertificates = append(escrow.SourceOACertificates,
+			hex.EncodeToString(certbytes))
+	}
+	for _, keyPart := range keyParts {
+		// The OA signatures were verified by ExportWKKeyParts
+		if int(keyPart.Index) >= len(recipients) {
+			return nil, make([]string, 0), errors.New("Export WK returned " +
+				"encrypted key part " + strconv.Itoa(int(keyPart.Index)) +
+				", but only " + strconv.Itoa(len(recipients)) + " public " +
+				"keys were provided.")
+		}
+		escrow.KeyParts = append(escrow.KeyParts, EscrowedKeyPart{
+			Index: int(keyPart.Index),
+			RecipientSKI: hex.EncodeToString(common.CalculateECKeyHash(
+				recipients[keyPart.Index])),
+			RecipientInfo: hex.EncodeToString(keyPart.RecipientInfo),
+			OASignature:   hex.EncodeToString(keyPart.Signature),
+		})
+	}
+	return escrow, make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as EscrowMasterKeyWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func EscrowMasterKey(ci CommonInputs, hc HsmConfig,
+	recipients []ecdsa.PublicKey,
+	mPolicy int) (*MasterKeyEscrow, []string, error) {
+
+	return EscrowMasterKeyWithContext(context.Background(), ci, hc,
+		recipients, mPolicy)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Restores a master key escrow into a service instance whose current master  */
+/* key registers are empty, such as a new service instance configured by      */
+/* Update with HsmConfig.NoRandomMasterKey set.                               */
+/*                                                                            */
+/* The OA certificate chain saved in the escrow is verified up to an IBM      */
+/* root key, as the OA certificate chains of the crypto units are, and the    */
+/* OA signature over each key part is verified using the public key of the    */
+/* epoch certificate in the chain.  The first M key parts with different      */
+/* indexes held by the key part holders provided are chosen.  These checks    */
+/* are made before anything is sent to the crypto units.                      */
+/*                                                                            */
+/* The TKE SDK does not combine the key parts.  In each crypto unit an        */
+/* importer key is generated, and each of the M key part holders decrypts     */
+/* its key part and encrypts it again under the importer key.  The key parts  */
+/* are imported together, so the crypto module combines them in its own key   */
+/* part format.  Each key part is in the clear only where the key of its      */
+/* holder is kept; for a PrivateKeyHolder that is this process.  The master   */
+/* key is then committed and finalized as for LoadMasterKeyFromParts.  The    */
+/* verification pattern of the master key is only known once the key parts    */
+/* are imported: it is checked against the verification pattern saved in the  */
+/* escrow before the new master key register is committed, and an error is    */
+/* returned if it differs.                                                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.  This includes: the API endpoint and region, the HPCS      */
+/*      service instance id, and an IBM Cloud authentication token.           */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*      section of the resource block for the HPCS service instance.  This    */
+/*      provides access to signature keys for signing commands to crypto      */
+/*      units.                                                                */
+/* *MasterKeyEscrow -- the escrow, from LoadMasterKeyEscrow                   */
+/* []KeyPartHolder -- the key part holders, such as PrivateKeyHolder values   */
+/*      from NewPrivateKeyHolder.  Key parts encrypted under other keys are   */
+/*      skipped.                                                              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the master key cannot be restored                              */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func RestoreMasterKeyWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig, escrow *MasterKeyEscrow,
+	holders []KeyPartHolder) ([]string, error) {
+
+	// The transport provides the OA root keys for the saved chain
+	tr, err := getTransport(ci)
+	if err != nil {
+		return make([]string, 0), err
+	}
+	keyParts, problems := selectEscrowedKeyParts(ctx, tr, escrow, holders)
+	if len(problems) > 0 {
+		return problems, nil
+	}
+	importer := func(ctx context.Context, tr common.Transport,
+		domain common.DomainEntry, signers []common.Signer) error {
+
+		return importEscrowedKeyParts(ctx, tr, domain, keyParts, signers)
+	}
+	return loadMasterKey(ctx, ci, hc, importer, escrow.VerificationPattern,
+		true)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as RestoreMasterKeyWithContext, using the background context          */
+/*----------------------------------------------------------------------------*/
+func RestoreMasterKey(ci CommonInputs, hc HsmConfig,
+	escrow *MasterKeyEscrow, holders []KeyPartHolder) ([]string, error) {
+
+	return RestoreMasterKeyWithContext(context.Background(), ci, hc, escrow,
+		holders)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Writes a master key escrow to a file.  The key parts are encrypted, but    */
+/* the file is readable only by the owner.                                    */
+/*----------------------------------------------------------------------------*/
+func (e *MasterKeyEscrow) Save(path string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads a master key escrow from a file written by MasterKeyEscrow.Save      */
+/*----------------------------------------------------------------------------*/
+func LoadMasterKeyEscrow(path string) (*MasterKeyEscrow, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var escrow MasterKeyEscrow
+	err = json.Unmarshal(data, &escrow)
+	if err != nil {
+		return nil, errors.New("Invalid master key escrow file " + path +
+			"\nMessage: " + err.Error())
+	}
+	if escrow.Version != MASTER_KEY_ESCROW_VERSION {
+		return nil, errors.New("Invalid master key escrow file " + path +
+			"\nUnsupported version: " + strconv.Itoa(escrow.Version))
+	}
+	return &escrow, nil
+}
+
+/** An encrypted key part from an escrow and its key part holder */
+type heldKeyPart struct {
+	recipientInfo []byte
+	holder        KeyPartHolder
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies an OA certificate chain saved earlier, without reading the chain  */
+/* from a crypto module.  The chain must end with a certificate signed by an  */
+/* IBM root key or an OA root key attached to tr.                             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* common.DomainEntry -- holds the public key of the epoch certificate, for   */
+/*      verifying OA signatures                                               */
+/* error -- reports a chain that cannot be verified                           */
+/*----------------------------------------------------------------------------*/
+func verifySavedOACertificateChain(ctx context.Context, tr common.Transport,
+	chain [][]byte) (common.DomainEntry, error) {
+
+	if len(chain) == 0 {
+		return common.DomainEntry{}, errors.New("No OA certificates")
+	}
+	publicKey, err := oaPublicKey(chain[0])
+	if err != nil {
+		return common.DomainEntry{}, err
+	}
+	de := common.DomainEntry{Public_key: publicKey}
+	err = verifyOACertificateChain(ctx,
+		ep11cmds.WithOACertificates(tr, chain), de, chain[0])
+	if err != nil {
+		return common.DomainEntry{}, err
+	}
+	return de, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies the OA certificate chain and the OA signatures of all key parts   */
+/* in a master key escrow and chooses the first M key parts with different    */
+/* indexes held by the key part holders provided.                             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []heldKeyPart -- the M encrypted key parts and their holders               */
+/* []string -- reasons the key parts cannot be used                           */
+/*----------------------------------------------------------------------------*/
+func selectEscrowedKeyParts(ctx context.Context, tr common.Transport,
+	escrow *MasterKeyEscrow, holders []KeyPartHolder) ([]heldKeyPart,
+	[]string) {
+
+	if escrow.MPolicy < 1 || len(escrow.VerificationPattern) < 56 {
+		return nil, []string{"The master key escrow has no M policy or " +
+			"verification pattern."}
+	}
+
+	chain := make([][]byte, len(escrow.SourceOACertificates))
+	for i, cert := range escrow.SourceOACertificates {
+		certbytes, err := hex.DecodeString(cert)
+		if err != nil {
+			return nil, []string{"Invalid OA certificate " + strconv.Itoa(i) +
+				" in the master key escrow."}
+		}
+		chain[i] = certbytes
+	}
+	source, err := verifySavedOACertificateChain(ctx, tr, chain)
+	if err != nil {
+		return nil, []string{"The OA certificate chain in the master key " +
+			"escrow cannot be verified.\nMessage: " + err.Error()}
+	}
+
+	// Verify every OA signature, not just those of the key parts used
+	recipientInfos := make([][]byte, len(escrow.KeyParts))
+	for i, escrowed := range escrow.KeyParts {
+		if escrowed.Index < 0 || escrowed.Index > 254 {
+			return nil, []string{"Invalid index " +
+				strconv.Itoa(escrowed.Index) + " for a key part in the " +
+				"master key escrow."}
+		}
+		recipientInfo, err := hex.DecodeString(escrowed.RecipientInfo)
+		if err != nil {
+			return nil, []string{"Invalid recipient info for key " +
+				"part " + strconv.Itoa(escrowed.Index) + " in the master key " +
+				"escrow."}
+		}
+		signature, err := hex.DecodeString(escrowed.OASignature)
+		if err != nil {
+			return nil, []string{"Invalid OA signature for key part " +
+				strconv.Itoa(escrowed.Index) + " in the master key escrow."}
+		}
+		err = ep11cmds.VerifyKeyPartSignature(ep11cmds.EncryptedKeyPart{
+			Index:         uint32(escrowed.Index),
+			RecipientInfo: recipientInfo,
+			Signature:     signature}, source)
+		if err != nil {
+			return nil, []string{err.Error()}
+		}
+		// The SKI in the signed RecipientInfo must match the SKI recorded
+		_, _, ski, _, err := ep11cmds.ParseRecipientInfoP521EC(recipientInfo)
+		if err != nil {
+			return nil, []string{"Invalid recipient info for key part " +
+				strconv.Itoa(escrowed.Index) + " in the master key escrow." +
+				"\nMessage: " + err.Error()}
+		}
+		if hex.EncodeToString(ski) != escrowed.RecipientSKI {
+			return nil, []string{"Key part " + strconv.Itoa(escrowed.Index) +
+				" in the master key escrow is not encrypted under the key " +
+				"with SKI " + escrowed.RecipientSKI + "."}
+		}
+		recipientInfos[i] = recipientInfo
+	}
+
+	keyParts := make([]heldKeyPart, 0, escrow.MPolicy)
+	used := make(map[int]bool)
+	for i, escrowed := range escrow.KeyParts {
+		if len(keyParts) == escrow.MPolicy {
+			break
+		}
+		if used[escrowed.Index] {
+			continue
+		}
+		for _, holder := range holders {
+			if hex.EncodeToString(holder.SKI()) == escrowed.RecipientSKI {
+				used[escrowed.Index] = true
+				keyParts = append(keyParts,
+					heldKeyPart{recipientInfos[i], holder})
+				break
+			}
+		}
+	}
+
+	if len(keyParts) < escrow.MPolicy {
+		return nil, []string{strconv.Itoa(escrow.MPolicy) + " key parts " +
+			"are needed to restore the master key, but key part holders " +
+			"were provided for only " + strconv.Itoa(len(keyParts)) + "."}
+	}
+	return keyParts, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates an importer key in a domain, encrypts escrowed key parts under   */
+/* it using their key part holders, and imports them into the new master key  */
+/* register.  The crypto module combines the key parts.                       */
+/*----------------------------------------------------------------------------*/
+func importEscrowedKeyParts(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, keyParts []heldKeyPart,
+	signers []common.Signer) error {
+
+	importerKey, err := generateImporterKey(ctx, tr, domain, signers)
+	if err != nil {
+		return err
+	}
+	recipientInfo := make([][]byte, len(keyParts))
+	for i, keyPart := range keyParts {
+		recipientInfo[i], err = keyPart.holder.ReencryptKeyPart(
+			keyPart.recipientInfo, importerKey)
+		if err != nil {
+			return err
+		}
+	}
+	return importMasterKey(ctx, tr, domain, recipientInfo, signers)
+}
@@ -0,0 +1,395 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test escrows of key parts the SDK cannot combine
+// 10/18/2026    CLH             Test the saved OA certificate chain and key
+//                               part holders
+
+package tkesdk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Returns the public keys and key part holders for P521 EC keys */
+func newTestRecipients(t *testing.T, count int) ([]ecdsa.PublicKey,
+	[]tkesdk.KeyPartHolder) {
+
+	publicKeys := make([]ecdsa.PublicKey, count)
+	holders := make([]tkesdk.KeyPartHolder, count)
+	for i := range holders {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKeys[i] = key.PublicKey
+		holders[i], err = tkesdk.NewPrivateKeyHolder(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return publicKeys, holders
+}
+
+/*----------------------------------------------------------------------------*/
+/* Escrows the master key of a configured service instance.  Also returns the */
+/* root key of the emulator, which the OA certificate chain in the escrow is  */
+/* verified against.                                                          */
+/*----------------------------------------------------------------------------*/
+func newTestEscrow(t *testing.T, recipients []ecdsa.PublicKey,
+	mPolicy int) (*tkesdk.MasterKeyEscrow, tkesdk.HsmConfig,
+	common.OARootKey) {
+
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	escrow, problems, err := tkesdk.EscrowMasterKey(ci, hc, recipients,
+		mPolicy)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("EscrowMasterKey returned %v %v", problems, err)
+	}
+	hsminfo := mustQuery(t, ci)
+	if !sameVP(escrow.VerificationPattern, hsminfo[0].CurrentMKVP) ||
+		escrow.SourceLocation != defaultTestUnits[0].location ||
+		escrow.SourceSerialNum == "" || escrow.MPolicy != mPolicy ||
+		len(escrow.SourceOACertificates) != 2 ||
+		len(escrow.KeyParts) != len(recipients) {
+		t.Errorf("Unexpected escrow from %s, serial number %s, with %d OA "+
+			"certificates, M policy %d and %d key parts",
+			escrow.SourceLocation, escrow.SourceSerialNum,
+			len(escrow.SourceOACertificates), escrow.MPolicy,
+			len(escrow.KeyParts))
+	}
+	return escrow, hc, em.OARootKey()
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a new service instance with empty master key registers, trusting   */
+/* the root key of the emulator an escrow was made with                       */
+/*----------------------------------------------------------------------------*/
+func newEmptyTestInstance(t *testing.T, hc tkesdk.HsmConfig,
+	escrowRoot common.OARootKey) (*countingTransport, tkesdk.CommonInputs,
+	func()) {
+
+	em, ci := newTestInstance(t, "instance2", defaultTestUnits)
+	ci.OARootKeys = append(ci.OARootKeys, escrowRoot)
+	hc.NoRandomMasterKey = true
+	mustUpdate(t, ci, hc)
+	counter := &countingTransport{Transport: em}
+	ci.Transport = counter
+	return counter, ci, em.Close
+}
+
+/*----------------------------------------------------------------------------*/
+/* A master key escrowed in three key parts, any two of which are needed, is  */
+/* saved, read back, and restored into a new service instance                 */
+/*----------------------------------------------------------------------------*/
+func TestEscrowAndRestoreMasterKey(t *testing.T) {
+	publicKeys, holders := newTestRecipients(t, 3)
+	escrow, hc, root := newTestEscrow(t, publicKeys, 2)
+
+	dir, err := ioutil.TempDir("", "escrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "master-key.json")
+	if err = escrow.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Escrow file mode %v, %v", info.Mode(), err)
+	}
+	loaded, err := tkesdk.LoadMasterKeyEscrow(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chosen := range [][]tkesdk.KeyPartHolder{
+		{holders[0], holders[2]},
+		{holders[2], holders[1]},
+	} {
+		_, ci, cleanup := newEmptyTestInstance(t, hc, root)
+		problems, err := tkesdk.RestoreMasterKey(ci, hc, loaded, chosen)
+		if err != nil || len(problems) > 0 {
+			t.Errorf("RestoreMasterKey returned %v %v", problems, err)
+		}
+		checkMasterKeys(t, mustQuery(t, ci), "Empty", "",
+			escrow.VerificationPattern)
+
+		// Restoring again changes nothing
+		problems, err = tkesdk.RestoreMasterKey(ci, hc, loaded, chosen)
+		if err != nil || len(problems) > 0 {
+			t.Errorf("RestoreMasterKey again returned %v %v", problems, err)
+		}
+		cleanup()
+	}
+}
+
+/** With an M policy of 1 any one key part holder can restore the master key */
+func TestRestoreMasterKeyOnePart(t *testing.T) {
+	publicKeys, holders := newTestRecipients(t, 2)
+	escrow, hc, root := newTestEscrow(t, publicKeys, 1)
+
+	_, ci, cleanup := newEmptyTestInstance(t, hc, root)
+	defer cleanup()
+	problems, err := tkesdk.RestoreMasterKey(ci, hc, escrow, holders[1:])
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("RestoreMasterKey returned %v %v", problems, err)
+	}
+	checkMasterKeys(t, mustQuery(t, ci), "Empty", "",
+		escrow.VerificationPattern)
+
+	// A service instance with a different master key is not restored into
+	em, ci := newTestInstance(t, "instance3", defaultTestUnits)
+	defer em.Close()
+	ci.OARootKeys = append(ci.OARootKeys, root)
+	mustUpdate(t, ci, hc)
+	problems, err = tkesdk.RestoreMasterKey(ci, hc, escrow, holders)
+	if err != nil || len(problems) != 1 {
+		t.Errorf("RestoreMasterKey into a configured service instance "+
+			"returned %v %v", problems, err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Escrows that cannot be restored are reported before anything is sent to    */
+/* the crypto units                                                           */
+/*----------------------------------------------------------------------------*/
+func TestRestoreMasterKeyChecks(t *testing.T) {
+	publicKeys, holders := newTestRecipients(t, 3)
+	escrow, hc, root := newTestEscrow(t, publicKeys, 2)
+	counter, ci, cleanup := newEmptyTestInstance(t, hc, root)
+	defer cleanup()
+
+	changed := func(change func(e *tkesdk.MasterKeyEscrow)) *tkesdk.
+		MasterKeyEscrow {
+
+		copied := *escrow
+		copied.SourceOACertificates = append([]string{},
+			escrow.SourceOACertificates...)
+		copied.KeyParts = append([]tkesdk.EscrowedKeyPart{},
+			escrow.KeyParts...)
+		change(&copied)
+		return &copied
+	}
+	// Another emulator has a different root key
+	other, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	_, otherChain, err := other.NewOASignatureKey(emulator.MODEL_CEX8P)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		escrow  *tkesdk.MasterKeyEscrow
+		holders []tkesdk.KeyPartHolder
+	}{
+		{"too few key part holders", escrow, holders[:1]},
+		{"the same key part twice", changed(func(e *tkesdk.MasterKeyEscrow) {
+			e.KeyParts[1] = e.KeyParts[0]
+		}), holders[:2]},
+		{"a key part for the wrong holder", changed(
+			func(e *tkesdk.MasterKeyEscrow) {
+				e.KeyParts[0].RecipientSKI = e.KeyParts[1].RecipientSKI
+			}), holders[1:2]},
+		{"an index out of range", changed(func(e *tkesdk.MasterKeyEscrow) {
+			e.KeyParts[2].Index = 300
+		}), holders},
+		{"a changed OA signature", changed(func(e *tkesdk.MasterKeyEscrow) {
+			e.KeyParts[2].OASignature = strings.Repeat("00", 100)
+		}), holders[:2]},
+		{"no M policy", changed(func(e *tkesdk.MasterKeyEscrow) {
+			e.MPolicy = 0
+		}), holders},
+		{"no OA certificate chain", changed(func(e *tkesdk.MasterKeyEscrow) {
+			e.SourceOACertificates = nil
+		}), holders},
+		{"a changed epoch certificate", changed(
+			func(e *tkesdk.MasterKeyEscrow) {
+				// Change the signing time in the signed metadata
+				cert, _ := hex.DecodeString(e.SourceOACertificates[0])
+				cert[90] ^= 0x01
+				e.SourceOACertificates[0] = hex.EncodeToString(cert)
+			}), holders},
+		{"a missing parent certificate", changed(
+			func(e *tkesdk.MasterKeyEscrow) {
+				e.SourceOACertificates = e.SourceOACertificates[:1]
+			}), holders},
+		{"a chain from an untrusted root", changed(
+			func(e *tkesdk.MasterKeyEscrow) {
+				e.SourceOACertificates = []string{
+					hex.EncodeToString(otherChain[0]),
+					hex.EncodeToString(otherChain[1])}
+			}), holders},
+	}
+	for _, test := range tests {
+		problems, err := tkesdk.RestoreMasterKey(ci, hc, test.escrow,
+			test.holders)
+		if err != nil || len(problems) != 1 {
+			t.Errorf("%s: RestoreMasterKey returned %v %v", test.name,
+				problems, err)
+		}
+	}
+
+	// The chain is only trusted with the root key of its emulator
+	untrusting := ci
+	untrusting.OARootKeys = ci.OARootKeys[:1]
+	problems, err := tkesdk.RestoreMasterKey(untrusting, hc, escrow, holders)
+	if err != nil || len(problems) != 1 {
+		t.Errorf("RestoreMasterKey without the root key returned %v %v",
+			problems, err)
+	}
+	if counter.requests != 0 || counter.queries != 0 {
+		t.Errorf("%d requests were sent", counter.requests+counter.queries)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* The key parts of an escrow are passed to the crypto units without being    */
+/* combined.  The escrow is built here from two customer key parts, which the */
+/* crypto units combine by exclusive or, signed by a stand-in for the OA      */
+/* signature key of the crypto module that exported them.                     */
+/*----------------------------------------------------------------------------*/
+func TestRestoreMasterKeyCombinedByCryptoUnits(t *testing.T) {
+	publicKeys, holders := newTestRecipients(t, 2)
+	source, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	moduleKey, chain, err := source.NewOASignatureKey(emulator.MODEL_CEX7P)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moduleSigner, err := common.NewPrivateKeySigner(moduleKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyParts := newTestKeyParts(t, 2)
+	vp, err := tkesdk.MasterKeyVerificationPattern(keyParts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	escrow := &tkesdk.MasterKeyEscrow{
+		Version:             tkesdk.MASTER_KEY_ESCROW_VERSION,
+		VerificationPattern: vp,
+		MPolicy:             2,
+	}
+	for _, cert := range chain {
+		escrow.SourceOACertificates = append(escrow.SourceOACertificates,
+			hex.EncodeToString(cert))
+	}
+	for i, keyPart := range keyParts {
+		recipientInfo, err := ep11cmds.EncryptKeyPartP521EC(publicKeys[i],
+			keyPart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := ep11cmds.CreateSignerInfoWithSigners(recipientInfo,
+			[]common.Signer{moduleSigner})
+		if err != nil {
+			t.Fatal(err)
+		}
+		escrow.KeyParts = append(escrow.KeyParts, tkesdk.EscrowedKeyPart{
+			Index: i,
+			RecipientSKI: hex.EncodeToString(
+				common.CalculateECKeyHash(publicKeys[i])),
+			RecipientInfo: hex.EncodeToString(recipientInfo),
+			OASignature:   hex.EncodeToString(signature),
+		})
+	}
+
+	hc := newTestHsmConfig(t)
+	_, ci, cleanup := newEmptyTestInstance(t, hc, source.OARootKey())
+	defer cleanup()
+	problems, err := tkesdk.RestoreMasterKey(ci, hc, escrow, holders)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("RestoreMasterKey returned %v %v", problems, err)
+	}
+	checkMasterKeys(t, mustQuery(t, ci), "Empty", "", vp)
+
+	// A verification pattern that does not match is found once the key
+	// parts are imported, and nothing is committed
+	escrow.VerificationPattern = vp[:55] + "x"
+	_, ci, cleanup = newEmptyTestInstance(t, hc, source.OARootKey())
+	defer cleanup()
+	problems, err = tkesdk.RestoreMasterKey(ci, hc, escrow, holders)
+	if err == nil {
+		t.Errorf("RestoreMasterKey with the wrong verification pattern "+
+			"returned %v", problems)
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if hsm.CurrentMKStatus != "Empty" ||
+			hsm.NewMKStatus == "Full Committed" {
+			t.Errorf("%s: master key registers %s and %s", hsm.HsmLocation,
+				hsm.CurrentMKStatus, hsm.NewMKStatus)
+		}
+	}
+}
+
+/** Invalid escrow inputs and files are rejected */
+func TestEscrowMasterKeyChecks(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	publicKeys, _ := newTestRecipients(t, 2)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name       string
+		recipients []ecdsa.PublicKey
+		mPolicy    int
+	}{
+		{"no public keys", nil, 1},
+		{"an M policy of 0", publicKeys, 0},
+		{"an M policy above the number of keys", publicKeys, 3},
+		{"a P256 key", []ecdsa.PublicKey{p256Key.PublicKey}, 1},
+	}
+	for _, test := range tests {
+		escrow, problems, err := tkesdk.EscrowMasterKey(ci, hc,
+			test.recipients, test.mPolicy)
+		if escrow != nil || err != nil || len(problems) != 1 {
+			t.Errorf("%s: EscrowMasterKey returned %v %v", test.name,
+				problems, err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "escrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "master-key.json")
+	for _, content := range []string{`{"version": 2}`, `not JSON`} {
+		ioutil.WriteFile(path, []byte(content), 0600)
+		if _, err := tkesdk.LoadMasterKeyEscrow(path); err == nil {
+			t.Errorf("LoadMasterKeyEscrow accepted %s", content)
+		}
+	}
+}
@@ -9,10 +9,15 @@
 // 05/12/2021    CLH             Initial version
 // 07/23/2021    CLH             Report original error when verifying OA cert chain
 // 11/11/2022    CLH             T444610 - Support 4770 crypto modules
+// 10/18/2026    CLH             Add context parameter
+// 10/18/2026    CLH             Retry after transient errors
+// 10/18/2026    CLH             Read crypto modules in parallel
+// 10/18/2026    CLH             Read whole OA certificate chains for escrows
 
 package tkesdk
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 
@@ -25,16 +30,18 @@ import (
 /* an HPCS service instance                                                   */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* authToken -- IBM Cloud authority token to use for requests                 */
-/* urlStart -- base URL to use for requests to the IBM Cloud                  */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto units              */
 /* cryptoInstance -- identifies the HPCS service instance to work with        */
+/* parallelism -- maximum number of crypto modules to read at once            */
 /*                                                                            */
 /* Outputs:                                                                   */
 /* []common.DomainEntry -- describes the crypto units assigned to the         */
 /*     service instance                                                       */
 /* error -- reports any error found during processing                         */
 /*----------------------------------------------------------------------------*/
-func getDomains(authToken string, urlStart string, cryptoInstance string) ([]common.DomainEntry, error) {
+func getDomains(ctx context.Context, tr common.Transport,
+	cryptoInstance string, parallelism int) ([]common.DomainEntry, error) {
 
 	// This function is based on code in tkefuncs/dlist.go.
 
@@ -42,8 +49,13 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 	domains := make([]common.DomainEntry, 0)
 
 	// Determine what crypto units are assigned to the service instance
-	req := common.CreateGetHsmsRequest(authToken, urlStart, cryptoInstance)
-	hsm_ids, locations, serial_nums, hsm_types, err := common.SubmitQueryDomainsRequest(req)
+	var hsm_ids, locations, serial_nums, hsm_types []string
+	err := common.Retry(ctx, common.GetRetryPolicy(tr), func() error {
+		var err error
+		hsm_ids, locations, serial_nums, hsm_types, err =
+			tr.QueryDomains(ctx, cryptoInstance)
+		return err
+	})
 	if err != nil {
 		return domains, err
 	}
@@ -57,11 +69,26 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 	// can map to the same crypto module (two paths to the same place),
 	// but this is the best we can do.
 
-	// For each crypto unit, read the OA certificate
+	// Find the first crypto unit for each crypto module
+	moduleIndexes := make([]int, 0)
+	moduleFound := make(map[string]bool)
 	for i := 0; i < len(hsm_ids); i++ {
 		partialLocation := common.GetPartialLocation(locations[i])
-		_, found := mapLocationSerialNum[partialLocation]
-		if !found {
+		if !moduleFound[partialLocation] {
+			moduleFound[partialLocation] = true
+			moduleIndexes = append(moduleIndexes, i)
+		}
+	}
+
+	// For each crypto module, read the OA certificate.  Results are
+	// stored by position so they do not depend on the order in which the
+	// crypto modules respond.
+	oaCerts := make([][]byte, len(moduleIndexes))
+	publicKeys := make([]string, len(moduleIndexes))
+	serialNums := make([]string, len(moduleIndexes))
+	err = common.RunParallel(ctx, parallelism, len(moduleIndexes),
+		func(m int) error {
+			i := moduleIndexes[m]
 			// Create a DomainEntry for querying the crypto module
 			de := common.DomainEntry{
 				0, // Domain_num -- don't care
@@ -72,54 +99,19 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 				"not available", // Public_key -- not available for initial read
 				hsm_types[i],
 				false} // Selected -- don't care
-			// Read the epoch OA certificate with the current OA
-			// signature key
-			certbytes, err := ep11cmds.QueryDeviceCertificate(
-				authToken, urlStart, de, 0)
-			if err != nil {
-				return domains, err
-			}
-			mapLocationOACert[partialLocation] = certbytes
-			//#B@T444610CLH
-			if len(certbytes) == ep11cmds.CEX8_OA_CERTIFICATE_LENGTH ||
-			   len(certbytes) == ep11cmds.CEX8_MB_CERTIFICATE_LENGTH {
-				// Handle OA certificate for the CEX8P
-				var cert ep11cmds.OA3CertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				mapLocationPublicKey[partialLocation] =
-					hex.EncodeToString(cert.SpkiPublicKey)
-			} else if certbytes[0] == 0x45 {
-			//#E@T444610CLH	
-				// Handle OA certificate for the CEX6P or CEX7P
-				var cert ep11cmds.OA2CertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				mapLocationPublicKey[partialLocation] =
-					hex.EncodeToString(cert.SpkiPublicKey)
-			} else {
-				// Handle OA certificate for the CEX5P
-				var cert ep11cmds.OACertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				mapLocationPublicKey[partialLocation] =
-					hex.EncodeToString(cert.PublicKey)
-			}
-			// Read the actual serial number for the crypto module
-			de.Public_key = mapLocationPublicKey[partialLocation]
-			// We want to check the OA signature on this query
-			_, resp, err := ep11cmds.QueryDomainAttributes(authToken, urlStart, de)
-			if err != nil {
-				return domains, err
-			}
-			mapLocationSerialNum[partialLocation] = resp.GetSerialNumber()
-		}
+			var err error
+			oaCerts[m], publicKeys[m], serialNums[m], err =
+				readOACertificate(ctx, tr, de)
+			return err
+		})
+	if err != nil {
+		return domains, err
+	}
+	for m, i := range moduleIndexes {
+		partialLocation := common.GetPartialLocation(locations[i])
+		mapLocationOACert[partialLocation] = oaCerts[m]
+		mapLocationPublicKey[partialLocation] = publicKeys[m]
+		mapLocationSerialNum[partialLocation] = serialNums[m]
 	}
 
 	// Check that actual and reported serial numbers match
@@ -136,17 +128,25 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 	// Create map of serial_num --> cert_chain_checked
 	certChainChecked := make(map[string]bool)
 	// The map allows us to avoid processing a crypto module more than
-	// once.  True means the OA certificate chain has already been
-	// verified.
+	// once.  True means the OA certificate chain will be verified.
 
+	verifyIndexes := make([]int, 0)
 	for i := 0; i < len(hsm_ids); i++ {
 		partialLocation := common.GetPartialLocation(locations[i])
 		serialNum := mapLocationSerialNum[partialLocation]
-		newkey := mapLocationPublicKey[partialLocation]
-
-		// Check if we have already tried to verify the OA certificate
-		// chain in this loop
 		if !certChainChecked[serialNum] {
+			certChainChecked[serialNum] = true
+			verifyIndexes = append(verifyIndexes, i)
+		}
+	}
+
+	err = common.RunParallel(ctx, parallelism, len(verifyIndexes),
+		func(v int) error {
+			i := verifyIndexes[v]
+			partialLocation := common.GetPartialLocation(locations[i])
+			serialNum := mapLocationSerialNum[partialLocation]
+			newkey := mapLocationPublicKey[partialLocation]
+
 			// Create a DomainEntry for verifying the OA certificate chain
 			de := common.DomainEntry{
 				0,              // Domain_num -- don't care
@@ -157,51 +157,11 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 				newkey,    // Public_key
 				hsm_types[i],
 				false} // Selected -- don't care
-			certbytes := mapLocationOACert[partialLocation]
-			//#B@T444610CLH
-			if len(certbytes) == ep11cmds.CEX8_OA_CERTIFICATE_LENGTH ||
-			   len(certbytes) == ep11cmds.CEX8_MB_CERTIFICATE_LENGTH {
-				// Handle OA certificate for the CEX8P
-				var cert ep11cmds.OA3CertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				err = ep11cmds.VerifyOA3Certificate(authToken, urlStart, de, 0, cert)
-				if err != nil {
-					return domains, err
-				} else {
-					certChainChecked[serialNum] = true
-				}
-			} else if certbytes[0] == 0x45 {
-			//#E@T444610CLH
-				// Handle OA certificate for the CEX6P or CEX7P
-				var cert ep11cmds.OA2CertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				err = ep11cmds.VerifyOA2Certificate(authToken, urlStart, de, 0, cert)
-				if err != nil {
-					return domains, err
-				} else {
-					certChainChecked[serialNum] = true
-				}
-			} else {
-				// Handle OA certificate for the CEX5P
-				var cert ep11cmds.OACertificateX
-				err = cert.Init(certbytes)
-				if err != nil {
-					return domains, err
-				}
-				err = ep11cmds.VerifyCertificate(authToken, urlStart, de, 0, cert)
-				if err != nil {
-					return domains, err
-				} else {
-					certChainChecked[serialNum] = true
-				}
-			}
-		}
+			return verifyOACertificateChain(ctx, tr, de,
+				mapLocationOACert[partialLocation])
+		})
+	if err != nil {
+		return domains, err
 	}
 
 	// Assemble the information to be returned
@@ -225,3 +185,164 @@ func getDomains(authToken string, urlStart string, cryptoInstance string) ([]com
 
 	return domains, nil
 }
+
+/*----------------------------------------------------------------------------*/
+/* Reads the OA certificate of a crypto module and the serial number of the   */
+/* crypto module                                                              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto units              */
+/* de -- a crypto unit in the crypto module                                   */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []byte -- the epoch OA certificate for the current OA signature key        */
+/* string -- the OA public key, represented as a hexadecimal string           */
+/* string -- the serial number of the crypto module                           */
+/* error -- reports any error found during processing                         */
+/*----------------------------------------------------------------------------*/
+func readOACertificate(ctx context.Context, tr common.Transport,
+	de common.DomainEntry) ([]byte, string, string, error) {
+
+	// Read the epoch OA certificate with the current OA
+	// signature key
+	certbytes, err := ep11cmds.QueryDeviceCertificateWithContext(ctx,
+		tr, de, 0)
+	if err != nil {
+		return nil, "", "", err
+	}
+	publicKey, err := oaPublicKey(certbytes)
+	if err != nil {
+		return nil, "", "", err
+	}
+	// Read the actual serial number for the crypto module
+	de.Public_key = publicKey
+	// We want to check the OA signature on this query
+	_, resp, err := ep11cmds.QueryDomainAttributesWithContext(ctx, tr, de)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return certbytes, publicKey, resp.GetSerialNumber(), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Verifies the OA certificate chain of a crypto module                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto units              */
+/* de -- a crypto unit in the crypto module, with the OA public key           */
+/* certbytes -- the OA certificate returned by readOACertificate              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any error found during processing                         */
+/*----------------------------------------------------------------------------*/
+func verifyOACertificateChain(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, certbytes []byte) error {
+
+	//#B@T444610CLH
+	if len(certbytes) == ep11cmds.CEX8_OA_CERTIFICATE_LENGTH ||
+	   len(certbytes) == ep11cmds.CEX8_MB_CERTIFICATE_LENGTH {
+		// Handle OA certificate for the CEX8P
+		var cert ep11cmds.OA3CertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return err
+		}
+		return ep11cmds.VerifyOA3CertificateWithContext(ctx, tr, de, 0, cert)
+	} else if certbytes[0] == 0x45 {
+	//#E@T444610CLH
+		// Handle OA certificate for the CEX6P or CEX7P
+		var cert ep11cmds.OA2CertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return err
+		}
+		return ep11cmds.VerifyOA2CertificateWithContext(ctx, tr, de, 0, cert)
+	} else {
+		// Handle OA certificate for the CEX5P
+		var cert ep11cmds.OACertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return err
+		}
+		return ep11cmds.VerifyCertificateWithContext(ctx, tr, de, 0, cert)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the public key certified by an OA certificate                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* certbytes -- an OA certificate in any of the supported formats             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the OA public key, represented as a hexadecimal string           */
+/* error -- reports any error found during processing                         */
+/*----------------------------------------------------------------------------*/
+func oaPublicKey(certbytes []byte) (string, error) {
+
+	if len(certbytes) == 0 {
+		return "", errors.New("Empty OA certificate")
+	}
+	//#B@T444610CLH
+	if len(certbytes) == ep11cmds.CEX8_OA_CERTIFICATE_LENGTH ||
+	   len(certbytes) == ep11cmds.CEX8_MB_CERTIFICATE_LENGTH {
+		// Handle OA certificate for the CEX8P
+		var cert ep11cmds.OA3CertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(cert.SpkiPublicKey), nil
+	} else if certbytes[0] == 0x45 {
+	//#E@T444610CLH
+		// Handle OA certificate for the CEX6P or CEX7P
+		var cert ep11cmds.OA2CertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(cert.SpkiPublicKey), nil
+	} else {
+		// Handle OA certificate for the CEX5P
+		var cert ep11cmds.OACertificateX
+		err := cert.Init(certbytes)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(cert.PublicKey), nil
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the whole OA certificate chain of a crypto module                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* ctx -- controls cancellation and deadlines for the requests                */
+/* tr -- the transport used to send requests to the crypto units              */
+/* de -- a crypto unit in the crypto module, with the OA public key           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* [][]byte -- the OA certificates, starting with the epoch certificate       */
+/* error -- reports any error found during processing                         */
+/*----------------------------------------------------------------------------*/
+func readOACertificateChain(ctx context.Context, tr common.Transport,
+	de common.DomainEntry) ([][]byte, error) {
+
+	chain := make([][]byte, 0)
+	for {
+		certbytes, err := ep11cmds.QueryDeviceCertificateWithContext(ctx,
+			tr, de, uint32(len(chain)))
+		if err != nil {
+			// Past the end of the chain
+			ve, ok := err.(ep11cmds.VerbError)
+			if ok && ve.ReturnCode() == 96 && ve.ReasonCode() == 60 &&
+				len(chain) > 0 {
+				return chain, nil
+			}
+			return nil, err
+		}
+		chain = append(chain, certbytes)
+	}
+}
@@ -0,0 +1,120 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/emulator"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** A crypto unit to add to an emulated service instance */
+type testUnit struct {
+	hsmType  string
+	location string
+	model    string
+}
+
+/** One recovery and two operational crypto units on different modules */
+var defaultTestUnits = []testUnit{
+	{"recovery", "[us-south].[AZ1-CS1].[00].[03]", emulator.MODEL_CEX8P},
+	{"operational", "[us-south].[AZ2-CS2].[00].[04]", emulator.MODEL_CEX8P},
+	{"operational", "[us-south].[AZ3-CS3].[00].[05]", emulator.MODEL_CEX7P},
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates an emulator holding one service instance with the given crypto     */
+/* units, and returns CommonInputs that send requests to it and trust its OA  */
+/* root key, even when Transport is replaced.  The caller must close the      */
+/* emulator.                                                                  */
+/*----------------------------------------------------------------------------*/
+func newTestInstance(t *testing.T, instance string,
+	units []testUnit) (*emulator.Emulator, tkesdk.CommonInputs) {
+
+	em, err := emulator.NewEmulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestUnits(t, em, instance, units)
+	return em, tkesdk.CommonInputs{InstanceId: instance, Transport: em,
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+}
+
+/** Adds crypto units to a service instance in an emulator */
+func addTestUnits(t *testing.T, em *emulator.Emulator, instance string,
+	units []testUnit) {
+
+	for _, unit := range units {
+		_, err := em.AddCryptoUnit(instance, unit.hsmType, unit.location,
+			unit.model)
+		if err != nil {
+			em.Close()
+			t.Fatal(err)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns administrators with P521 EC signature keys held in memory.         */
+/* Signature key files are not used, to avoid the cost of the key derivation. */
+/*----------------------------------------------------------------------------*/
+func newTestAdmins(t *testing.T, names ...string) []tkesdk.AdminInfo {
+	admins := make([]tkesdk.AdminInfo, len(names))
+	for i, name := range names {
+		key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := common.NewPrivateKeySigner(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		admins[i] = tkesdk.AdminInfo{Name: name, Signer: signer}
+	}
+	return admins
+}
+
+/** Returns an HsmConfig with a signature threshold of 2 and three admins */
+func newTestHsmConfig(t *testing.T) tkesdk.HsmConfig {
+	return tkesdk.HsmConfig{SignatureThreshold: 2, RevocationThreshold: 2,
+		MasterKeyParts: 1, MasterKeyPartsRequired: 1,
+		Admins: newTestAdmins(t, "admin1", "admin2", "admin3")}
+}
+
+/** Runs Update and fails the test if any problems are reported */
+func mustUpdate(t *testing.T, ci tkesdk.CommonInputs, hc tkesdk.HsmConfig) {
+	problems, err := tkesdk.Update(ci, hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("Update reported problems: %v", problems)
+	}
+}
+
+/** Queries the crypto units and fails the test on an error */
+func mustQuery(t *testing.T, ci tkesdk.CommonInputs) []tkesdk.HsmInfo {
+	hsminfo, err := tkesdk.Query(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hsminfo
+}
+
+/** Compares the first 28 bytes of two verification patterns in hexadecimal */
+func sameVP(vp1 string, vp2 string) bool {
+	return len(vp1) >= 56 && len(vp2) >= 56 && vp1[:56] == vp2[:56]
+}
@@ -0,0 +1,87 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Holds the P521 EC private key that key parts in a master key escrow are    */
+/* encrypted under, and prepares those key parts for import into a crypto     */
+/* unit.                                                                      */
+/*                                                                            */
+/* RestoreMasterKey never needs the private key itself.  The TKE SDK provides */
+/* a key part holder for private keys held in memory (NewPrivateKeyHolder).   */
+/* Keys kept in an HSM or a key management service can be used by             */
+/* implementing this interface, so that each key part is in the clear only    */
+/* where the key of its holder is kept.                                       */
+/*----------------------------------------------------------------------------*/
+type KeyPartHolder interface {
+
+	// Returns the 32-byte Subject Key Identifier of the P521 EC key, the
+	// SHA-256 hash of the uncompressed public key point, see
+	// common.CalculateECKeyHash
+	SKI() []byte
+
+	// Decrypts a key part, given as the RecipientInfo it was exported in,
+	// and encrypts it under the public key of an importer key generated in a
+	// crypto unit, keeping the user key material, as
+	// ep11cmds.ReencryptKeyPartP521EC does.  Returns the new RecipientInfo.
+	ReencryptKeyPart(recipientInfo []byte,
+		importerKey ecdsa.PublicKey) ([]byte, error)
+}
+
+/** Key part holder for a P521 EC private key held in memory */
+type PrivateKeyHolder struct {
+	key *ecdsa.PrivateKey
+	ski []byte
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a key part holder for a private key.                               */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* key -- an *ecdsa.PrivateKey on the P521 curve                              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *PrivateKeyHolder -- prepares key parts using the private key              */
+/* error -- reports an unsupported key                                        */
+/*----------------------------------------------------------------------------*/
+func NewPrivateKeyHolder(key *ecdsa.PrivateKey) (*PrivateKeyHolder, error) {
+	if key == nil || key.Curve == nil || key.Curve.Params().Name != "P-521" {
+		return nil, errors.New("Only P521 EC keys can hold key parts.")
+	}
+	return &PrivateKeyHolder{
+		key: key,
+		ski: common.CalculateECKeyHash(key.PublicKey),
+	}, nil
+}
+
+/** Returns the SKI of the P521 EC key */
+func (h *PrivateKeyHolder) SKI() []byte {
+	return h.ski
+}
+
+/*----------------------------------------------------------------------------*/
+/* Encrypts a key part under an importer key using                            */
+/* ep11cmds.ReencryptKeyPartP521EC.  The key part is in the clear in this     */
+/* process while it is encrypted again.                                       */
+/*----------------------------------------------------------------------------*/
+func (h *PrivateKeyHolder) ReencryptKeyPart(recipientInfo []byte,
+	importerKey ecdsa.PublicKey) ([]byte, error) {
+
+	return ep11cmds.ReencryptKeyPartP521EC(recipientInfo, h.key, importerKey)
+}
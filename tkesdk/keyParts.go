@@ -0,0 +1,174 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Use one importer key for all key parts
+// 10/18/2026    CLH             Recover the output of commands whose response was lost
+// 10/18/2026    CLH             Export each key part to its own key part holder
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Export key parts under the target importer key
+//                               and default to one key part
+
+package tkesdk
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Imports M encrypted key parts into the new master key register of a        */
+/* domain.  The key parts can be any M of the key parts returned by           */
+/* ep11cmds.ExportWKKeyParts or ep11cmds.ExportPendingWKKeyParts, and must be */
+/* encrypted under the latest importer key generated in the target domain.    */
+/* A crypto unit keeps only its latest importer key.  Key parts exported to   */
+/* key part holders must each be encrypted again under that key by their      */
+/* holder, wherever the key of the holder is kept.  The crypto module         */
+/* combines the key parts.                                                    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto unit                                               */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*      unit                                                                  */
+/* DomainEntry -- identifies the domain whose new master key register is to   */
+/*      be loaded                                                             */
+/* []ep11cmds.EncryptedKeyPart -- the available key parts.  The first M key   */
+/*      parts with different indexes are imported.                            */
+/* int -- the M policy the key parts were exported with                       */
+/* []common.Signer -- the signature keys to use to sign the command.  Only    */
+/*      one signature is needed.                                              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports too few key parts or any error importing them             */
+/*----------------------------------------------------------------------------*/
+func ImportKeyPartsWithContext(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, keyParts []ep11cmds.EncryptedKeyPart,
+	mPolicy int, signers []common.Signer) error {
+
+	if mPolicy < 1 {
+		return errors.New("Invalid M policy " + strconv.Itoa(mPolicy) + ".")
+	}
+	recipientInfo := make([][]byte, 0)
+	used := make(map[uint32]bool)
+	for _, keyPart := range keyParts {
+		if len(recipientInfo) == mPolicy {
+			break
+		}
+		if used[keyPart.Index] {
+			continue
+		}
+		used[keyPart.Index] = true
+		recipientInfo = append(recipientInfo, keyPart.RecipientInfo)
+	}
+	if len(recipientInfo) < mPolicy {
+		return errors.New(strconv.Itoa(mPolicy) + " different key parts " +
+			"are needed to import the master key, but only " +
+			strconv.Itoa(len(recipientInfo)) + " were provided.")
+	}
+	return importMasterKey(ctx, tr, domain, recipientInfo, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as ImportKeyPartsWithContext, using the background context            */
+/*----------------------------------------------------------------------------*/
+func ImportKeyParts(tr common.Transport, domain common.DomainEntry,
+	keyParts []ep11cmds.EncryptedKeyPart, mPolicy int,
+	signers []common.Signer) error {
+
+	return ImportKeyPartsWithContext(context.Background(), tr, domain,
+		keyParts, mPolicy, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Copies a master key from a recovery crypto unit to the new master key      */
+/* register of another crypto unit, using the key part policy in the          */
+/* HsmConfig.                                                                 */
+/*                                                                            */
+/* An importer key is generated in the target domain and every key part is    */
+/* exported directly under it, so only the target crypto module can decrypt   */
+/* the key parts.  M of them are then imported together and combined by the   */
+/* target crypto module.                                                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*      units                                                                 */
+/* DomainEntry -- the recovery crypto unit to export the master key from      */
+/* DomainEntry -- the crypto unit to load the master key into                 */
+/* bool -- true to export the new master key register of the recovery crypto  */
+/*      unit, false to export its current master key register                 */
+/* HsmConfig -- sets the number of key parts and the M policy                 */
+/* []common.Signer -- the signature keys to use to sign the export command    */
+/* []common.Signer -- the signature keys to use to sign commands to the       */
+/*      target crypto unit                                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any error copying the master key                          */
+/*----------------------------------------------------------------------------*/
+func copyMasterKey(ctx context.Context, tr common.Transport,
+	source common.DomainEntry, target common.DomainEntry, pending bool,
+	hc HsmConfig, sourceSigners []common.Signer,
+	targetSigners []common.Signer) error {
+
+	parts, mPolicy, err := keyPartPolicy(hc)
+	if err != nil {
+		return err
+	}
+
+	// Generate an importer key in the target domain.  A crypto unit keeps
+	// only its latest importer key, so no other importer key is generated
+	// in the target domain before the key parts are imported.
+	importerKey, err := generateImporterKey(ctx, tr, target, targetSigners)
+	if err != nil {
+		return err
+	}
+	kphcerts := make([][]byte, parts)
+	for i := range kphcerts {
+		kphcerts[i] = ep11cmds.KPHCert(importerKey)
+	}
+
+	// Export the master key from the recovery crypto unit and import it
+	keyParts, err := exportKeyParts(ctx, tr, source, pending, kphcerts,
+		mPolicy, sourceSigners)
+	if err != nil {
+		return err
+	}
+	return ImportKeyPartsWithContext(ctx, tr, target, keyParts, mPolicy,
+		targetSigners)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the number of key parts to use when copying a master key and the   */
+/* M policy.  Fields of the HsmConfig that are not set count as 1.            */
+/*----------------------------------------------------------------------------*/
+func keyPartPolicy(hc HsmConfig) (int, int, error) {
+	parts := hc.MasterKeyParts
+	if parts == 0 {
+		parts = 1
+	}
+	mPolicy := keyPartsRequired(hc)
+	if parts < 1 || parts > 255 || mPolicy < 1 || mPolicy > parts {
+		return 0, 0, errors.New("Invalid master key part policy, " +
+			strconv.Itoa(mPolicy) + " of " + strconv.Itoa(parts) + " key " +
+			"parts.")
+	}
+	return parts, mPolicy, nil
+}
+
+/** Returns the M policy in the HsmConfig, or 1 if it is not set */
+func keyPartsRequired(hc HsmConfig) int {
+	if hc.MasterKeyPartsRequired == 0 {
+		return 1
+	}
+	return hc.MasterKeyPartsRequired
+}
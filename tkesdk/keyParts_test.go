@@ -0,0 +1,310 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Check that key parts go to different holders
+// 10/18/2026    CLH             Check that key parts go to the importer key,
+//                               the default policy, and the permissions
+
+package tkesdk_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+	"github.com/Logicalis/asn1"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Transport that records the KPH certificates of each Export WK command,     */
+/* for key parts exported from either master key register                     */
+/*----------------------------------------------------------------------------*/
+type kphRecordingTransport struct {
+	common.Transport
+	mutex    sync.Mutex
+	kphcerts [][][]byte
+}
+
+func (k *kphRecordingTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	request, err := htp.UnmarshalHTPRequest(htpRequest)
+	if err == nil {
+		var adminReq ep11cmds.AdminReq
+		var adminBlk ep11cmds.AdminBlk
+		_, err = asn1.Decode(request.CPRB.Payload, &adminReq)
+		if err == nil {
+			_, err = asn1.Decode(adminReq.AdminBlock, &adminBlk)
+		}
+		if err == nil && (common.ByteSlicesAreEqual(adminBlk.CmdID,
+			ep11cmds.XCP_ADM_EXPORT_WK) || common.ByteSlicesAreEqual(
+			adminBlk.CmdID, ep11cmds.XCP_ADM_EXPORT_NEXT_WK)) {
+
+			var pMap common.ParameterMap
+			pMap, err = pMap.Load(adminBlk.CmdInput)
+			certs := make([][]byte, 0)
+			var i uint32
+			for ; err == nil &&
+				pMap.Contains(common.PMTAG_KPH_CERTIFICATE, i); i++ {
+				certs = append(certs, pMap.GetDataUsingIndex(
+					common.PMTAG_KPH_CERTIFICATE, i))
+			}
+			k.mutex.Lock()
+			k.kphcerts = append(k.kphcerts, certs)
+			k.mutex.Unlock()
+		}
+	}
+	return k.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+		htpRequest)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Update and RotateMasterKey copy the master key in several key parts, all   */
+/* exported under the importer key of the target crypto unit                  */
+/*----------------------------------------------------------------------------*/
+func TestCopyMasterKeyInKeyParts(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	recording := &kphRecordingTransport{Transport: ci.Transport}
+	ci.Transport = recording
+	hc := newTestHsmConfig(t)
+	hc.MasterKeyParts = 3
+	hc.MasterKeyPartsRequired = 2
+
+	mustUpdate(t, ci, hc)
+	hsminfo := mustQuery(t, ci)
+	for _, hsm := range hsminfo {
+		if hsm.CurrentMKStatus != "Valid" ||
+			!sameVP(hsm.CurrentMKVP, hsminfo[0].CurrentMKVP) {
+			t.Errorf("%s: current master key register %s %s",
+				hsm.HsmLocation, hsm.CurrentMKStatus, hsm.CurrentMKVP)
+		}
+	}
+
+	mustRotate(t, ci, hc)
+	hsminfo = mustQuery(t, ci)
+	for _, hsm := range hsminfo {
+		if hsm.NewMKStatus != "Full Committed" ||
+			!sameVP(hsm.NewMKVP, hsminfo[0].NewMKVP) {
+			t.Errorf("%s: new master key register %s %s", hsm.HsmLocation,
+				hsm.NewMKStatus, hsm.NewMKVP)
+		}
+	}
+
+	// Each export uses the importer key generated for it in the target
+	// crypto unit for every key part, so no key part can be decrypted
+	// outside the target crypto module
+	if len(recording.kphcerts) != 2*(len(defaultTestUnits)-1) {
+		t.Errorf("%d Export WK commands were sent",
+			len(recording.kphcerts))
+	}
+	seen := make(map[string]bool)
+	for _, certs := range recording.kphcerts {
+		if len(certs) != 3 {
+			t.Errorf("Export WK had %d KPH certificates", len(certs))
+			continue
+		}
+		for _, cert := range certs[1:] {
+			if !bytes.Equal(cert, certs[0]) {
+				t.Error("Key parts were exported under different keys")
+			}
+		}
+		if seen[hex.EncodeToString(certs[0])] {
+			t.Error("An importer key was used for two exports")
+		}
+		seen[hex.EncodeToString(certs[0])] = true
+	}
+}
+
+/** Checks whether the domains allow importing a single key part */
+func checkSingleKeyPartPermission(t *testing.T, ci tkesdk.CommonInputs,
+	allowed bool) {
+
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, domain := range domains {
+		attrs, _, err := ep11cmds.QueryDomainAttributesWithTransport(
+			ci.Transport, domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (attrs.Permissions&0x00000004 != 0) != allowed {
+			t.Errorf("Domain %s has permissions %08X", domain.Type,
+				attrs.Permissions)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* An M policy of 2 or more prohibits importing a master key in a single key  */
+/* part, and an M policy of 1 allows it again                                 */
+/*----------------------------------------------------------------------------*/
+func TestKeyPartPolicyPermissions(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[:2])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	hc.MasterKeyParts = 3
+	hc.MasterKeyPartsRequired = 2
+	mustUpdate(t, ci, hc)
+	checkSingleKeyPartPermission(t, ci, false)
+
+	// Importing a master key in a single key part is refused
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, target := domains[0], domains[1]
+	if recovery.Type != "recovery" {
+		recovery, target = target, recovery
+	}
+	signers := []common.Signer{hc.Admins[0].Signer, hc.Admins[1].Signer}
+	importerKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithTransport(
+		em, target, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyParts, err := ep11cmds.ExportWKKeyParts(em, recovery,
+		[][]byte{ep11cmds.KPHCert(importerKey)}, 1, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tkesdk.ImportKeyParts(em, target, keyParts, 1, signers[:1])
+	var verbErr ep11cmds.VerbError
+	if !errors.As(err, &verbErr) || verbErr.ReturnCode() != 80 ||
+		verbErr.ReasonCode() != 81 {
+		t.Errorf("Importing a single key part returned %v", err)
+	}
+
+	hc.MasterKeyParts = 1
+	hc.MasterKeyPartsRequired = 1
+	mustUpdate(t, ci, hc)
+	checkSingleKeyPartPermission(t, ci, true)
+}
+
+/*----------------------------------------------------------------------------*/
+/* A key part policy that is not set counts as 1 of 1, and an invalid policy  */
+/* is reported                                                                */
+/*----------------------------------------------------------------------------*/
+func TestKeyPartPolicyDefault(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	for _, policy := range [][2]int{{0, 2}, {2, 3}, {-1, 1}, {256, 1},
+		{3, -1}} {
+
+		hc := newTestHsmConfig(t)
+		hc.MasterKeyParts = policy[0]
+		hc.MasterKeyPartsRequired = policy[1]
+		problems, err := tkesdk.Update(ci, hc)
+		if err != nil || len(problems) == 0 {
+			t.Errorf("Update with %d of %d key parts returned %v %v",
+				policy[1], policy[0], problems, err)
+		}
+	}
+	if hsminfo := mustQuery(t, ci); len(hsminfo[0].Admins) != 0 {
+		t.Error("Administrators were added")
+	}
+
+	hc := newTestHsmConfig(t)
+	hc.MasterKeyParts = 0
+	hc.MasterKeyPartsRequired = 0
+	mustUpdate(t, ci, hc)
+	hsminfo := mustQuery(t, ci)
+	for _, hsm := range hsminfo {
+		if hsm.CurrentMKStatus != "Valid" ||
+			!sameVP(hsm.CurrentMKVP, hsminfo[0].CurrentMKVP) {
+			t.Errorf("%s: current master key register %s %s",
+				hsm.HsmLocation, hsm.CurrentMKStatus, hsm.CurrentMKVP)
+		}
+	}
+	checkSingleKeyPartPermission(t, ci, true)
+}
+
+/** ImportKeyParts imports the first M key parts with different indexes */
+func TestImportKeyParts(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[:2])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, target := domains[0], domains[1]
+	if recovery.Type != "recovery" {
+		recovery, target = target, recovery
+	}
+	signers := []common.Signer{hc.Admins[0].Signer, hc.Admins[1].Signer}
+
+	// Export a key part to each key part holder, and have each encrypt its
+	// key part under the importer key of the target crypto unit
+	holderKeys := make([]*ecdsa.PrivateKey, 3)
+	kphcerts := make([][]byte, len(holderKeys))
+	for i := range holderKeys {
+		holderKeys[i], err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kphcerts[i] = ep11cmds.KPHCert(holderKeys[i].PublicKey)
+	}
+	keyParts, err := ep11cmds.ExportWKKeyParts(em, recovery, kphcerts, 2,
+		signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importerKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithTransport(
+		em, target, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range keyParts {
+		keyParts[i].RecipientInfo, err = ep11cmds.ReencryptKeyPartP521EC(
+			keyParts[i].RecipientInfo, holderKeys[keyParts[i].Index],
+			importerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A repeated key part is skipped
+	err = tkesdk.ImportKeyParts(em, target, []ep11cmds.EncryptedKeyPart{
+		keyParts[2], keyParts[2], keyParts[0]}, 2, signers[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := ep11cmds.QueryDomainInfoWithTransport(em, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameVP(hex.EncodeToString(info.NewMKVP),
+		mustQuery(t, ci)[0].CurrentMKVP) {
+		t.Error("The imported master key differs from the exported one")
+	}
+
+	err = tkesdk.ImportKeyParts(em, target, []ep11cmds.EncryptedKeyPart{
+		keyParts[1], keyParts[1]}, 2, signers[:1])
+	if err == nil {
+		t.Error("One key part was imported when two are needed")
+	}
+	err = tkesdk.ImportKeyParts(em, target, keyParts, 0, signers[:1])
+	if err == nil {
+		t.Error("An M policy of 0 was accepted")
+	}
+}
@@ -0,0 +1,300 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Share loading with RestoreMasterKey
+// 10/18/2026    CLH             Recover the output of commands whose response was lost
+// 10/18/2026    CLH             Import key parts through a keyPartImporter
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Create signers once and close them afterwards
+
+package tkesdk
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Loads a master key from key parts supplied by the customer into every      */
+/* crypto unit of a service instance.  The master key is the exclusive or of  */
+/* the key parts.                                                             */
+/*                                                                            */
+/* An importer key is generated in each crypto unit, the key parts are        */
+/* encrypted under it, and the key parts are imported into the new master key */
+/* register and committed.  The verification pattern of every new master key  */
+/* register is checked against the verification pattern calculated from the   */
+/* key parts.                                                                 */
+/*                                                                            */
+/* If the current master key registers are empty, as left by Update when      */
+/* HsmConfig.NoRandomMasterKey is set, the new master key registers are also  */
+/* finalized and the function loads the initial master key.  Otherwise the    */
+/* key parts replace the current master key: when the function returns with   */
+/* no problems and no error, reencrypt the key stores of the service instance */
+/* under the new master key, then call FinalizeMasterKeyRotation.             */
+/*                                                                            */
+/* If the function is interrupted, call it again with the same key parts.     */
+/* Crypto units that already hold the new master key are not loaded again.    */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.  This includes: the API endpoint and region, the HPCS      */
+/*      service instance id, and an IBM Cloud authentication token.           */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*      section of the resource block for the HPCS service instance.  This    */
+/*      provides access to signature keys for signing commands to crypto      */
+/*      units.  The administrators and thresholds are not changed.            */
+/* [][]byte -- the 32-byte AES key parts of the master key                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the master key cannot be loaded                                */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func LoadMasterKeyFromPartsWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig, keyParts [][]byte) ([]string, error) {
+
+	newVP, err := MasterKeyVerificationPattern(keyParts)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	importer := func(ctx context.Context, tr common.Transport,
+		domain common.DomainEntry, signers []common.Signer) error {
+
+		return importCustomerKeyParts(ctx, tr, domain, keyParts, signers)
+	}
+	return loadMasterKey(ctx, ci, hc, importer, newVP, false)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as LoadMasterKeyFromPartsWithContext, using the background context    */
+/*----------------------------------------------------------------------------*/
+func LoadMasterKeyFromParts(ci CommonInputs, hc HsmConfig,
+	keyParts [][]byte) ([]string, error) {
+
+	return LoadMasterKeyFromPartsWithContext(context.Background(), ci, hc,
+		keyParts)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Imports the key parts of a master key into the new master key register of  */
+/* a domain, signing with one signature key                                   */
+/*----------------------------------------------------------------------------*/
+type keyPartImporter func(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, signers []common.Signer) error
+
+/*----------------------------------------------------------------------------*/
+/* Loads a master key from key parts into every crypto unit, as described for */
+/* LoadMasterKeyFromPartsWithContext.                                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines                     */
+/* CommonInputs -- identifies the service instance                            */
+/* HsmConfig -- provides the signature keys                                   */
+/* keyPartImporter -- imports the key parts into a crypto unit               */
+/* string -- the expected verification pattern of the master key              */
+/* bool -- true if the current master key registers must be empty             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- reasons the master key cannot be loaded                        */
+/* error -- identifies any error encountered                                  */
+/*----------------------------------------------------------------------------*/
+func loadMasterKey(ctx context.Context, ci CommonInputs, hc HsmConfig,
+	importer keyPartImporter, newVP string, emptyOnly bool) ([]string, error) {
+
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+
+	hsminfo, tr, domains, signers, problems, err :=
+		prepareMasterKeyRotation(ctx, ci, hc, admins)
+	if err != nil || len(problems) > 0 {
+		return problems, err
+	}
+
+	// The initial master key is being loaded if no current master key
+	// register holds a different master key
+	initial := true
+	for i := range hsminfo {
+		if hsminfo[i].CurrentMKStatus != "Empty" &&
+			!sameMKVP(hsminfo[i].CurrentMKVP, newVP) {
+
+			initial = false
+		}
+	}
+	if emptyOnly && !initial {
+		return []string{"The current master key registers of the service " +
+			"instance are not empty.  A master key can only be restored " +
+			"into crypto units with empty current master key registers."},
+			nil
+	}
+
+	for i := range hsminfo {
+		finalized := hsminfo[i].NewMKStatus == "Empty" &&
+			hsminfo[i].CurrentMKStatus != "Empty" &&
+			sameMKVP(hsminfo[i].CurrentMKVP, newVP)
+		if !initial && hsminfo[i].CurrentMKStatus == "Empty" {
+			problems = append(problems, "The current master key register "+
+				"of crypto unit "+hsminfo[i].HsmLocation+" is empty, but "+
+				"other crypto units have a current master key.  Clear the "+
+				"current master key registers before loading the master key "+
+				"from key parts.")
+		} else if !initial && finalized {
+			problems = append(problems, "The master key from the key parts "+
+				"is already the current master key in crypto unit "+
+				hsminfo[i].HsmLocation+".  Use FinalizeMasterKeyRotation to "+
+				"complete the master key change.")
+		} else if hsminfo[i].NewMKStatus != "Empty" &&
+			!sameMKVP(hsminfo[i].NewMKVP, newVP) {
+
+			problems = append(problems, "The new master key register of "+
+				"crypto unit "+hsminfo[i].HsmLocation+" is set to a different "+
+				"value.  Clear the new master key register before loading "+
+				"the master key from key parts.")
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	for i, domain := range domains {
+		if hsminfo[i].NewMKStatus == "Empty" &&
+			sameMKVP(hsminfo[i].CurrentMKVP, newVP) {
+			// Already finalized
+			continue
+		}
+
+		// Encrypt the key parts under an importer key generated in the
+		// crypto unit and load them in the new master key register
+		if hsminfo[i].NewMKStatus == "Empty" {
+			err = importer(ctx, tr, domain, signers[i].single)
+			if err != nil {
+				return make([]string, 0), err
+			}
+		}
+
+		// Check that the imported value is the master key from the key parts
+		hsminfo[i], err = checkNewMKVP(ctx, tr, domain, hsminfo[i], newVP)
+		if err != nil {
+			return make([]string, 0), err
+		}
+
+		if hsminfo[i].NewMKStatus != "Full Committed" {
+			err = commitPendingMasterKey(ctx, tr, domain, signers[i].threshold)
+			if err != nil {
+				return make([]string, 0), err
+			}
+		}
+	}
+
+	for i, domain := range domains {
+		if hsminfo[i].NewMKStatus == "Empty" &&
+			sameMKVP(hsminfo[i].CurrentMKVP, newVP) {
+			// Already finalized
+			continue
+		}
+
+		// Check that every crypto unit has the same committed new master key
+		hsminfo[i], err = checkNewMKVP(ctx, tr, domain, hsminfo[i], newVP)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		if hsminfo[i].NewMKStatus != "Full Committed" {
+			return make([]string, 0), errors.New("The new master key " +
+				"register of crypto unit " + hsminfo[i].HsmLocation +
+				" was not committed.")
+		}
+		if !initial {
+			// Finalized by FinalizeMasterKeyRotation after the key stores
+			// are reencrypted
+			continue
+		}
+
+		err = finalizeMasterKey(ctx, tr, domain, signers[i].single)
+		if err != nil {
+			return make([]string, 0), err
+		}
+
+		// Check that the new master key is now the current master key
+		domainInfo, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr,
+			domain)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		if !sameMKVP(hex.EncodeToString(domainInfo.CurrentMKVP), newVP) {
+			return make([]string, 0), errors.New("The current master key " +
+				"of crypto unit " + hsminfo[i].HsmLocation + " does not " +
+				"have the verification pattern of the key parts after the " +
+				"new master key register was finalized.")
+		}
+	}
+
+	return make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Calculates the verification pattern of the master key formed from a set of */
+/* key parts.  The master key is the exclusive or of the key parts.           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* [][]byte -- the 32-byte AES key parts of the master key                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the verification pattern, as a hexadecimal string that can be    */
+/*      compared with HsmInfo.CurrentMKVP and HsmInfo.NewMKVP                 */
+/* error -- reports missing key parts or a key part with an invalid length    */
+/*----------------------------------------------------------------------------*/
+func MasterKeyVerificationPattern(keyParts [][]byte) (string, error) {
+	if len(keyParts) == 0 {
+		return "", errors.New("No master key parts were provided.")
+	}
+	masterKey := make([]byte, 32)
+	for i, keyPart := range keyParts {
+		if len(keyPart) != 32 {
+			return "", errors.New("Master key part " + strconv.Itoa(i+1) +
+				" has length " + strconv.Itoa(len(keyPart)) + ".  Master key " +
+				"parts must be 32 bytes long.")
+		}
+		for j := range masterKey {
+			masterKey[j] ^= keyPart[j]
+		}
+	}
+	return hex.EncodeToString(common.Calc_vp(masterKey)), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates an importer key in a domain, encrypts the customer key parts     */
+/* under it, and imports them into the new master key register.               */
+/*----------------------------------------------------------------------------*/
+func importCustomerKeyParts(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, keyParts [][]byte,
+	signers []common.Signer) error {
+
+	importerKey, err := generateImporterKey(ctx, tr, domain, signers)
+	if err != nil {
+		return err
+	}
+	recipientInfo := make([][]byte, len(keyParts))
+	for i, keyPart := range keyParts {
+		recipientInfo[i], err = ep11cmds.EncryptKeyPartP521EC(importerKey,
+			keyPart)
+		if err != nil {
+			return err
+		}
+	}
+	return importMasterKey(ctx, tr, domain, recipientInfo, signers)
+}
@@ -0,0 +1,164 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Returns random 32-byte key parts */
+func newTestKeyParts(t *testing.T, count int) [][]byte {
+	keyParts := make([][]byte, count)
+	for i := range keyParts {
+		keyParts[i] = make([]byte, 32)
+		if _, err := rand.Read(keyParts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keyParts
+}
+
+/** The verification pattern is that of the exclusive or of the key parts */
+func TestMasterKeyVerificationPattern(t *testing.T) {
+	keyParts := [][]byte{bytes.Repeat([]byte{0x5A}, 32),
+		bytes.Repeat([]byte{0x0F}, 32), bytes.Repeat([]byte{0xF0}, 32)}
+	vp, err := tkesdk.MasterKeyVerificationPattern(keyParts)
+	expected := hex.EncodeToString(common.Calc_vp(
+		bytes.Repeat([]byte{0xA5}, 32)))
+	if err != nil || vp != expected {
+		t.Errorf("MasterKeyVerificationPattern returned %s, %v", vp, err)
+	}
+
+	if _, err = tkesdk.MasterKeyVerificationPattern(nil); err == nil {
+		t.Error("No key parts were accepted")
+	}
+	keyParts[1] = keyParts[1][:16]
+	if _, err = tkesdk.MasterKeyVerificationPattern(keyParts); err == nil {
+		t.Error("A 16-byte key part was accepted")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Customer key parts are loaded as the initial master key of crypto units    */
+/* left empty by Update, and every crypto unit gets the locally calculated    */
+/* verification pattern                                                       */
+/*----------------------------------------------------------------------------*/
+func TestLoadMasterKeyFromParts(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	hc.NoRandomMasterKey = true
+	mustUpdate(t, ci, hc)
+	for _, hsm := range mustQuery(t, ci) {
+		if hsm.CurrentMKStatus != "Empty" {
+			t.Fatalf("Update loaded a master key in %s", hsm.HsmLocation)
+		}
+	}
+
+	keyParts := newTestKeyParts(t, 3)
+	vp, err := tkesdk.MasterKeyVerificationPattern(keyParts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		// Loading again changes nothing
+		problems, err := tkesdk.LoadMasterKeyFromParts(ci, hc, keyParts)
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("LoadMasterKeyFromParts returned %v %v", problems, err)
+		}
+		checkMasterKeys(t, mustQuery(t, ci), "Empty", "", vp)
+	}
+
+	problems, err := tkesdk.LoadMasterKeyFromParts(ci, hc,
+		[][]byte{keyParts[0][:16]})
+	if err != nil || len(problems) != 1 {
+		t.Errorf("LoadMasterKeyFromParts with a 16-byte key part returned "+
+			"%v %v", problems, err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Key parts loaded into a service instance with a master key replace it as a */
+/* master key rotation, finalized after the key stores are reencrypted        */
+/*----------------------------------------------------------------------------*/
+func TestLoadMasterKeyFromPartsReplacesMasterKey(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	oldVP := mustQuery(t, ci)[0].CurrentMKVP
+
+	keyParts := newTestKeyParts(t, 2)
+	vp, _ := tkesdk.MasterKeyVerificationPattern(keyParts)
+	problems, err := tkesdk.LoadMasterKeyFromParts(ci, hc, keyParts)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("LoadMasterKeyFromParts returned %v %v", problems, err)
+	}
+	hsminfo := mustQuery(t, ci)
+	checkMasterKeys(t, hsminfo, "Full Committed", vp, oldVP)
+	if phase := tkesdk.MasterKeyRotationPhase(hsminfo); phase !=
+		tkesdk.MK_ROTATION_COMMITTED {
+		t.Errorf("Phase after LoadMasterKeyFromParts is %s", phase)
+	}
+
+	// Different key parts cannot replace the new master key
+	problems, err = tkesdk.LoadMasterKeyFromParts(ci, hc,
+		newTestKeyParts(t, 2))
+	if err != nil || len(problems) != len(defaultTestUnits) {
+		t.Errorf("LoadMasterKeyFromParts with other key parts returned %v %v",
+			problems, err)
+	}
+
+	mustFinalize(t, ci, hc)
+	checkMasterKeys(t, mustQuery(t, ci), "Empty", "", vp)
+}
+
+/** An interrupted load completes when called again with the same key parts */
+func TestLoadMasterKeyFromPartsResumes(t *testing.T) {
+	units := defaultTestUnits[:2]
+	hc := newTestHsmConfig(t)
+	hc.NoRandomMasterKey = true
+	keyParts := newTestKeyParts(t, 2)
+	vp, _ := tkesdk.MasterKeyVerificationPattern(keyParts)
+
+	for cancelAt := 1; cancelAt <= 25; cancelAt += 4 {
+		em, ci := newTestInstance(t, "instance1", units)
+		mustUpdate(t, ci, hc)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		tr := &cancellingTransport{Transport: em, cancel: cancel,
+			cancelAt: cancelAt}
+		ci.Transport = tr
+		problems, err := tkesdk.LoadMasterKeyFromPartsWithContext(ctx, ci, hc,
+			keyParts)
+		cancel()
+		if err != context.Canceled && (err != nil || len(problems) > 0) {
+			t.Errorf("Cancelled at request %d: %v %v", cancelAt, problems,
+				err)
+		}
+
+		ci.Transport = em
+		problems, err = tkesdk.LoadMasterKeyFromParts(ci, hc, keyParts)
+		if err != nil || len(problems) > 0 {
+			t.Errorf("Cancelled at request %d: resuming returned %v %v",
+				cancelAt, problems, err)
+		}
+		checkMasterKeys(t, mustQuery(t, ci), "Empty", "", vp)
+		em.Close()
+	}
+}
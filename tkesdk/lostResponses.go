@@ -0,0 +1,286 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Confirm commands without output by querying
+//                               the domain
+
+package tkesdk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* The signed commands below return output that the workflows need.  When    */
+/* the response to one of them is lost after the command took effect,         */
+/* ep11cmds returns ErrResponseLost.  Rather than stopping a workflow with    */
+/* the domain partly configured, the output is recovered:                     */
+/*                                                                            */
+/* - Generate Random WK: the verification pattern is read from the master    */
+/*   key register that was filled, if one was.                                */
+/* - Generate Importer Key: a new importer key is generated.  It replaces the */
+/*   key whose public key was lost.                                           */
+/* - Export WK: the master key is exported again.  Exporting does not change  */
+/*   the master key registers.                                                */
+/*                                                                            */
+/* The domain transaction counter advances before a command is executed, so  */
+/* a command whose response is lost may still have been rejected.  For the    */
+/* commands without output, the domain is queried for the state the command   */
+/* should have produced.  ErrResponseLost is returned if it is not found.     */
+/*----------------------------------------------------------------------------*/
+
+/*----------------------------------------------------------------------------*/
+/* Generates a random master key in a domain and returns its verification     */
+/* pattern.  The crypto module fills the current master key register if it   */
+/* is empty, and the new master key register otherwise.                       */
+/*----------------------------------------------------------------------------*/
+func createRandomMasterKey(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) ([]byte, error) {
+
+	// Read the master key registers, to find which one a command whose
+	// response is lost filled
+	before, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr, de)
+	if err != nil {
+		return nil, err
+	}
+	err, vp := ep11cmds.CreateRandomWKWithContext(ctx, tr, de, signers)
+	if !errors.Is(err, ep11cmds.ErrResponseLost) {
+		return vp, err
+	}
+
+	// The command was accepted, but it may still have failed.  The
+	// outcome is read even if ctx is cancelled, since the command was sent.
+	after, queryErr := ep11cmds.QueryDomainInfoWithContext(
+		common.WithoutCancel(ctx), tr, de)
+	switch {
+	case queryErr != nil:
+		return nil, err
+	case before.CurrentMKStatus == ep11cmds.MK_STATUS_EMPTY &&
+		after.CurrentMKStatus == ep11cmds.CMK_STATUS_VALID:
+		return after.CurrentMKVP, nil
+	case before.NewMKStatus == ep11cmds.MK_STATUS_EMPTY &&
+		after.NewMKStatus == ep11cmds.NMK_STATUS_FULL_UNCOMMITTED:
+		return after.NewMKVP, nil
+	}
+	return nil, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Generates a P521 EC importer key in a domain and returns its public key    */
+/*----------------------------------------------------------------------------*/
+func generateImporterKey(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) (ecdsa.PublicKey, error) {
+
+	pubKey, _, err := ep11cmds.GenerateP521ECImporterKeyWithContext(ctx, tr,
+		de, signers)
+	if errors.Is(err, ep11cmds.ErrResponseLost) {
+		pubKey, _, err = ep11cmds.GenerateP521ECImporterKeyWithContext(ctx,
+			tr, de, signers)
+	}
+	return pubKey, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Exports the current or pending master key of a domain in key parts.  See   */
+/* ep11cmds.ExportWKKeyParts for the inputs and outputs.                      */
+/*----------------------------------------------------------------------------*/
+func exportKeyParts(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, pending bool, kphcerts [][]byte, mPolicy int,
+	signers []common.Signer) ([]ep11cmds.EncryptedKeyPart, error) {
+
+	export := ep11cmds.ExportWKKeyPartsWithContext
+	if pending {
+		export = ep11cmds.ExportPendingWKKeyPartsWithContext
+	}
+	keyParts, err := export(ctx, tr, de, kphcerts, mPolicy, signers)
+	if errors.Is(err, ep11cmds.ErrResponseLost) {
+		keyParts, err = export(ctx, tr, de, kphcerts, mPolicy, signers)
+	}
+	return keyParts, err
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns nil if err is ErrResponseLost and tookEffect shows that the        */
+/* command produced the expected domain state.  Otherwise err is returned.    */
+/* The domain is queried even if ctx is cancelled, since the command was      */
+/* sent.                                                                      */
+/*----------------------------------------------------------------------------*/
+func confirmCommand(ctx context.Context, err error,
+	tookEffect func(ctx context.Context) (bool, error)) error {
+
+	if !errors.Is(err, ep11cmds.ErrResponseLost) {
+		return err
+	}
+	done, queryErr := tookEffect(common.WithoutCancel(ctx))
+	if queryErr != nil || !done {
+		return err
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a function that checks the master key registers of a domain        */
+/*----------------------------------------------------------------------------*/
+func masterKeyStatusIs(tr common.Transport, de common.DomainEntry,
+	check func(ep11cmds.DomainInfoRspInfo) bool) func(
+	context.Context) (bool, error) {
+
+	return func(ctx context.Context) (bool, error) {
+		domainInfo, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr, de)
+		if err != nil {
+			return false, err
+		}
+		return check(domainInfo), nil
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a function that checks whether an administrator is installed in a  */
+/* domain                                                                     */
+/*----------------------------------------------------------------------------*/
+func adminIsInstalled(tr common.Transport, de common.DomainEntry,
+	ski string, installed bool) func(context.Context) (bool, error) {
+
+	return func(ctx context.Context) (bool, error) {
+		skis, err := ep11cmds.QueryDomainAdminsWithContext(ctx, tr, de)
+		if err != nil {
+			return false, err
+		}
+		for _, adminSKI := range skis {
+			if strings.EqualFold(hex.EncodeToString(adminSKI), ski) {
+				return installed, nil
+			}
+		}
+		return !installed, nil
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Commits the master key in the new master key register of a domain          */
+/*----------------------------------------------------------------------------*/
+func commitPendingMasterKey(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) error {
+
+	err := ep11cmds.CommitPendingWKWithContext(ctx, tr, de, signers)
+	return confirmCommand(ctx, err, masterKeyStatusIs(tr, de,
+		func(domainInfo ep11cmds.DomainInfoRspInfo) bool {
+			return domainInfo.NewMKStatus ==
+				ep11cmds.NMK_STATUS_FULL_COMMITTED
+		}))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Moves the committed new master key of a domain to the current master key   */
+/* register                                                                   */
+/*----------------------------------------------------------------------------*/
+func finalizeMasterKey(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) error {
+
+	err := ep11cmds.FinalizeWKWithContext(ctx, tr, de, signers)
+	return confirmCommand(ctx, err, masterKeyStatusIs(tr, de,
+		func(domainInfo ep11cmds.DomainInfoRspInfo) bool {
+			return domainInfo.NewMKStatus == ep11cmds.MK_STATUS_EMPTY &&
+				domainInfo.CurrentMKStatus == ep11cmds.CMK_STATUS_VALID
+		}))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Loads a master key encrypted in key parts in the new master key register   */
+/* of a domain.  A lost response is only confirmed if the register was empty  */
+/* before, since a rejected import leaves a previously loaded key in place.   */
+/*----------------------------------------------------------------------------*/
+func importMasterKey(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, recipientInfo [][]byte,
+	signers []common.Signer) error {
+
+	before, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr, de)
+	if err != nil {
+		return err
+	}
+	err = ep11cmds.ImportWKWithContext(ctx, tr, de, recipientInfo, signers)
+	return confirmCommand(ctx, err, masterKeyStatusIs(tr, de,
+		func(domainInfo ep11cmds.DomainInfoRspInfo) bool {
+			return before.NewMKStatus == ep11cmds.MK_STATUS_EMPTY &&
+				domainInfo.NewMKStatus ==
+					ep11cmds.NMK_STATUS_FULL_UNCOMMITTED
+		}))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Zeroizes a domain, removing its administrators and master keys             */
+/*----------------------------------------------------------------------------*/
+func zeroizeDomain(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, signers []common.Signer) error {
+
+	err := ep11cmds.ZeroizeDomainWithContext(ctx, tr, de, signers)
+	registersEmpty := masterKeyStatusIs(tr, de,
+		func(domainInfo ep11cmds.DomainInfoRspInfo) bool {
+			return domainInfo.CurrentMKStatus == ep11cmds.MK_STATUS_EMPTY &&
+				domainInfo.NewMKStatus == ep11cmds.MK_STATUS_EMPTY
+		})
+	return confirmCommand(ctx, err, func(ctx context.Context) (bool, error) {
+		skis, queryErr := ep11cmds.QueryDomainAdminsWithContext(ctx, tr, de)
+		if queryErr != nil || len(skis) > 0 {
+			return false, queryErr
+		}
+		return registersEmpty(ctx)
+	})
+}
+
+/*----------------------------------------------------------------------------*/
+/* Installs an administrator in a domain                                      */
+/*----------------------------------------------------------------------------*/
+func addDomainAdmin(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, ski string, cert []byte,
+	signers []common.Signer) error {
+
+	err := ep11cmds.AddDomainAdminWithContext(ctx, tr, de, cert, signers)
+	return confirmCommand(ctx, err, adminIsInstalled(tr, de, ski, true))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Removes an administrator from a domain                                     */
+/*----------------------------------------------------------------------------*/
+func removeDomainAdmin(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, ski string, signers []common.Signer) error {
+
+	err := ep11cmds.RemoveDomainAdministratorWithContext(ctx, tr, de, ski,
+		signers)
+	return confirmCommand(ctx, err, adminIsInstalled(tr, de, ski, false))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Sets the attributes of a domain                                            */
+/*----------------------------------------------------------------------------*/
+func setDomainAttributes(ctx context.Context, tr common.Transport,
+	de common.DomainEntry, domainAttributes ep11cmds.DomainAttributes,
+	signers []common.Signer) error {
+
+	err := ep11cmds.SetDomainAttributesWithContext(ctx, tr, de,
+		domainAttributes, signers)
+	return confirmCommand(ctx, err, func(ctx context.Context) (bool, error) {
+		current, _, err := ep11cmds.QueryDomainAttributesWithContext(ctx,
+			tr, de)
+		if err != nil {
+			return false, err
+		}
+		return current.SignatureThreshold ==
+			domainAttributes.SignatureThreshold &&
+			current.RevocationSignatureThreshold ==
+				domainAttributes.RevocationSignatureThreshold &&
+			current.Permissions == domainAttributes.Permissions, nil
+	})
+}
@@ -0,0 +1,210 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Cover the commands without output
+
+package tkesdk_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/htp"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+	"github.com/Logicalis/asn1"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Transport that passes on the first signed command with a given command     */
+/* identifier and then drops its response, as if the connection failed after  */
+/* the crypto unit executed the command                                       */
+/*----------------------------------------------------------------------------*/
+type losingTransport struct {
+	common.Transport
+	mutex   sync.Mutex
+	cmdID   []byte
+	dropped bool
+}
+
+func (l *losingTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	htpResponse, err := l.Transport.SubmitHTPRequest(ctx, cryptoInstance,
+		hsmId, htpRequest)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.dropped ||
+		!common.ByteSlicesAreEqual(adminCommandID(htpRequest), l.cmdID) {
+		return htpResponse, err
+	}
+	l.dropped = true
+	return "", common.NewTransientError(errors.New("connection reset"))
+}
+
+/** Returns the command identifier of a signed command, or nil for a query */
+func adminCommandID(htpRequest string) []byte {
+	request, err := htp.UnmarshalHTPRequest(htpRequest)
+	if err != nil {
+		return nil
+	}
+	var adminReq ep11cmds.AdminReq
+	if _, err = asn1.Decode(request.CPRB.Payload, &adminReq); err != nil ||
+		!common.ByteSlicesAreEqual(adminReq.CmdID, ep11cmds.FNID_ADMIN) {
+		return nil
+	}
+	var adminBlk ep11cmds.AdminBlk
+	if _, err = asn1.Decode(adminReq.AdminBlock, &adminBlk); err != nil {
+		return nil
+	}
+	return adminBlk.CmdID
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns CommonInputs that lose the response to the first command with a    */
+/* given identifier, and the transport, to check that a response was lost     */
+/*----------------------------------------------------------------------------*/
+func losingInputs(ci tkesdk.CommonInputs,
+	cmdID []byte) (tkesdk.CommonInputs, *losingTransport) {
+
+	losing := &losingTransport{Transport: ci.Transport, cmdID: cmdID}
+	ci.Transport = losing
+	ci.Retry = &common.RetryPolicy{MaxAttempts: 3,
+		InitialDelay: time.Millisecond}
+	return ci, losing
+}
+
+/*----------------------------------------------------------------------------*/
+/* Update, RotateMasterKey, and LoadMasterKeyFromParts complete when the      */
+/* response to a command that took effect is lost, for commands with and      */
+/* without output                                                             */
+/*----------------------------------------------------------------------------*/
+func TestLostResponses(t *testing.T) {
+	rotate := func(t *testing.T, ci tkesdk.CommonInputs,
+		hc tkesdk.HsmConfig) ([]string, error) {
+
+		return tkesdk.RotateMasterKey(ci, hc)
+	}
+	load := func(t *testing.T, ci tkesdk.CommonInputs,
+		hc tkesdk.HsmConfig) ([]string, error) {
+
+		return tkesdk.LoadMasterKeyFromParts(ci, hc, newTestKeyParts(t, 2))
+	}
+	finalize := func(t *testing.T, ci tkesdk.CommonInputs,
+		hc tkesdk.HsmConfig) ([]string, error) {
+
+		problems, err := tkesdk.RotateMasterKey(ci, hc)
+		if err != nil || len(problems) > 0 {
+			return problems, err
+		}
+		return tkesdk.FinalizeMasterKeyRotation(ci, hc)
+	}
+	removeAdmin := func(t *testing.T, ci tkesdk.CommonInputs,
+		hc tkesdk.HsmConfig) ([]string, error) {
+
+		hc.Admins = hc.Admins[:2]
+		return tkesdk.Update(ci, hc)
+	}
+	tests := []struct {
+		name  string
+		cmdID []byte
+		run   func(t *testing.T, ci tkesdk.CommonInputs,
+			hc tkesdk.HsmConfig) ([]string, error)
+		noRandomMasterKey bool
+	}{
+		{"Update add administrator", ep11cmds.XCP_ADM_DOM_ADMIN_LOGIN, nil,
+			false},
+		{"Update generate master key", ep11cmds.XCP_ADM_GEN_WK, nil, false},
+		{"Update generate importer key", ep11cmds.XCP_ADM_GEN_IMPORTER, nil,
+			false},
+		{"Update export master key", ep11cmds.XCP_ADM_EXPORT_WK, nil, false},
+		{"Update commit master key", ep11cmds.XCP_ADM_COMMIT_WK, nil, false},
+		{"Update finalize master key", ep11cmds.XCP_ADM_FINALIZE_WK, nil,
+			false},
+		{"Update set attributes", ep11cmds.XCP_ADM_DOM_SET_ATTR, nil, false},
+		{"Update remove administrator", ep11cmds.XCP_ADM_DOM_ADMIN_LOGOUT,
+			removeAdmin, false},
+		{"RotateMasterKey generate master key", ep11cmds.XCP_ADM_GEN_WK,
+			rotate, false},
+		{"RotateMasterKey export master key", ep11cmds.XCP_ADM_EXPORT_NEXT_WK,
+			rotate, false},
+		{"RotateMasterKey import master key", ep11cmds.XCP_ADM_IMPORT_WK,
+			rotate, false},
+		{"FinalizeMasterKeyRotation finalize master key",
+			ep11cmds.XCP_ADM_FINALIZE_WK, finalize, false},
+		{"LoadMasterKeyFromParts import master key",
+			ep11cmds.XCP_ADM_IMPORT_WK, load, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+			defer em.Close()
+			hc := newTestHsmConfig(t)
+			hc.NoRandomMasterKey = test.noRandomMasterKey
+			if test.run != nil {
+				mustUpdate(t, ci, hc)
+			}
+
+			losingCI, losing := losingInputs(ci, test.cmdID)
+			var problems []string
+			var err error
+			if test.run == nil {
+				problems, err = tkesdk.Update(losingCI, hc)
+			} else {
+				problems, err = test.run(t, losingCI, hc)
+			}
+			if err != nil || len(problems) > 0 {
+				t.Fatalf("Returned %v %v", problems, err)
+			}
+			if !losing.dropped {
+				t.Fatal("No response was lost")
+			}
+
+			hsminfo := mustQuery(t, ci)
+			for _, hsm := range hsminfo[1:] {
+				if hsm.CurrentMKStatus != hsminfo[0].CurrentMKStatus ||
+					!sameVP(hsm.CurrentMKVP, hsminfo[0].CurrentMKVP) ||
+					hsm.NewMKStatus != hsminfo[0].NewMKStatus {
+					t.Errorf("%s: master keys differ from %s",
+						hsm.HsmLocation, hsminfo[0].HsmLocation)
+				}
+			}
+			if hsminfo[0].CurrentMKStatus != "Valid" {
+				t.Errorf("Current master key register is %s",
+					hsminfo[0].CurrentMKStatus)
+			}
+		})
+	}
+}
+
+/** Zeroize completes when the response to a Zeroize Domain command is lost */
+func TestLostZeroizeResponse(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+
+	losingCI, losing := losingInputs(ci, ep11cmds.XCP_ADM_DOM_ZEROIZE)
+	err := tkesdk.Zeroize(losingCI, hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !losing.dropped {
+		t.Fatal("No response was lost")
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if len(hsm.Admins) > 0 || hsm.CurrentMKStatus != "Empty" {
+			t.Errorf("%s was not zeroized", hsm.HsmLocation)
+		}
+	}
+}
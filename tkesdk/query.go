@@ -7,11 +7,28 @@
 //
 // Date          Initials        Description
 // 05/07/2021    CLH             Initial version
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Add retry policy
+// 10/18/2026    CLH             Add HTTP client
+// 10/18/2026    CLH             Add base URL override
+// 10/18/2026    CLH             Add token provider
+// 10/18/2026    CLH             Query crypto units in parallel
+// 10/18/2026    CLH             Add AdminInfo.Signer
+// 10/18/2026    CLH             Add HsmConfig.SignerPreference
+// 10/18/2026    CLH             Add master key part policy
+// 10/18/2026    CLH             Add HsmConfig.NoRandomMasterKey
+// 10/18/2026    CLH             Use HTTPClient for signing services and IAM
+// 10/18/2026    CLH             Require the master key part policy
+// 10/18/2026    CLH             Add CommonInputs.OARootKeys
+// 10/18/2026    CLH             Add AdminInfo.Certificate
+// 10/18/2026    CLH             Default the key part policy to one key part
 
 package tkesdk
 
 import (
+	"context"
 	"encoding/hex"
+	"net/http"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
@@ -26,8 +43,55 @@ import (
 type CommonInputs struct {
 	Region      string
 	ApiEndpoint string
+		// One of cloud.ibm.com, test.cloud.ibm.com, private.cloud.ibm.com,
+		// and private.test.cloud.ibm.com, or a URL template such as
+		// https://tke.{region}.example.com where {region} is replaced by
+		// the Region field.
+	BaseURL     string
+		// Optional.  The base URL of the TKE REST API, such as
+		// https://tke.us-south.hs-crypto.cloud.ibm.com.  When set, it is
+		// used in place of the ApiEndpoint and Region fields, for virtual
+		// private endpoints and other URLs that do not follow the pattern
+		// of the public endpoints.
 	AuthToken   string
+	Tokens      common.TokenProvider
+		// Optional.  Supplies authentication tokens for requests to the
+		// TKE REST API in place of AuthToken, for example
+		// common.NewIAMAPIKeyTokenProvider.  Tokens are refreshed before
+		// they expire, so an Update can outlast a single token.
 	InstanceId  string
+	Transport   common.Transport
+		// Optional.  Used to send requests to the crypto units.  When nil,
+		// requests are sent to the TKE REST API using the ApiEndpoint,
+		// Region, and AuthToken fields.
+	Retry       *common.RetryPolicy
+		// Optional.  Controls retries after transient errors, such as
+		// network errors.  When nil, requests are not retried.
+		// common.DefaultRetryPolicy is a reasonable choice.
+	HTTPClient  *http.Client
+		// Optional.  Used for requests to the TKE REST API when Transport
+		// is nil, for requests to signing services and Vault made by the
+		// signers created from AdminInfo.Key, and for requests to IAM made
+		// by a common.IAMTokenProvider in Tokens whose HTTPClient is nil.
+		// Create it with common.NewHTTPClient to set trusted CAs, a proxy,
+		// timeouts, or a client certificate.  When nil,
+		// common.DefaultHTTPClient is used, which can be replaced using
+		// common.SetDefaultHTTPClient.  Signers supplied in
+		// AdminInfo.Signer and signers for key URI schemes registered
+		// with common.RegisterSignerScheme do not use it.
+	Parallelism int
+		// Optional.  The maximum number of crypto modules or crypto units
+		// queried at once when reading the configuration of the service
+		// instance.  Values below 2 query them one at a time.  Results are
+		// returned in the same order either way.  Administrative commands
+		// are always sent one at a time.
+	RateLimiter *common.RateLimiter
+		// Optional.  Limits the rate of requests sent to the crypto units.
+		// A single limiter can be shared by several service instances.
+	OARootKeys  []common.OARootKey
+		// Optional.  Root keys accepted at the top of the OA certificate
+		// chains of the crypto units in addition to the IBM root keys, for
+		// testing with emulated crypto units, see Emulator.OARootKey.
 }
 
 // Structure containing information on an installed administrator
@@ -65,30 +129,73 @@ type AdminInfo struct {
 		// For initial development, this will be the file password.
 		// When user-defined signing services are supported, the signing
 		// service will define how this field is set.
+	Signer common.Signer
+		// Signs using the administrator signature key.  When set, Key and
+		// Token are not used.  Allows signature keys to be kept in key
+		// stores the TKE SDK does not support directly.
+	Certificate []byte
+		// Administrator certificate created by ep11cmds.CreateAdminCert.
+		// Used by PlanUpdate, which does not access signature keys, in
+		// place of Key, Token, and Signer.  Other functions ignore it.
 }
 
 // Structure representing the hsm_config section of a resource block
 type HsmConfig struct {
-	SignatureThreshold  int
-	RevocationThreshold int
-	Admins              []AdminInfo
+	SignatureThreshold     int
+	RevocationThreshold    int
+	Admins                 []AdminInfo
+	SignerPreference       []string
+		// Administrator names, most preferred first, giving the order in
+		// which signature keys are asked to sign commands and their
+		// signatures are used.  Administrators not listed follow in the
+		// order of Admins.  Optional.
+	MasterKeyParts         int
+	MasterKeyPartsRequired int
+		// Optional.  When a master key is copied between crypto units it
+		// is exported as MasterKeyParts key parts, and any
+		// MasterKeyPartsRequired of them are imported.  Fields that are
+		// not set count as 1, so by default the master key is copied in
+		// one key part.  Set MasterKeyPartsRequired to 2 or more so that
+		// the master key is never transported in a single key part; the
+		// domains then do not allow a master key to be imported in a
+		// single key part.
+	NoRandomMasterKey      bool
+		// Optional.  When set, Update leaves the current master key
+		// registers empty if they are all empty, rather than loading a
+		// random master key, so that a master key can be loaded from
+		// customer key parts using LoadMasterKeyFromParts.
 }
 
 /*----------------------------------------------------------------------------*/
 /* Collects and returns information on how the crypto units assigned to a     */
 /* service instance are configured.                                           */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.                                                            */
 /*----------------------------------------------------------------------------*/
-func Query(ci CommonInputs) ([]HsmInfo, error) {
-	hsmInfo, _, _, err := internalQuery(ci)
+func QueryWithContext(ctx context.Context, ci CommonInputs) ([]HsmInfo, error) {
+	hsmInfo, _, _, err := internalQuery(ctx, ci)
 	return hsmInfo, err
 }
 
+/*----------------------------------------------------------------------------*/
+/* Same as QueryWithContext, using the background context                     */
+/*----------------------------------------------------------------------------*/
+func Query(ci CommonInputs) ([]HsmInfo, error) {
+	return QueryWithContext(context.Background(), ci)
+}
+
 /*----------------------------------------------------------------------------*/
 /* Function used internally to query the crypto unit configuration.           */
 /*                                                                            */
 /* Returns additional information used by other TKE SDK functions.            */
 /*                                                                            */
 /* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
 /* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
 /*      functions.  This includes: the API endpoint and region, the HPCS      */
 /*      service instance id, and an IBM Cloud authentication token.           */
@@ -96,13 +203,15 @@ func Query(ci CommonInputs) ([]HsmInfo, error) {
 /* Outputs:                                                                   */
 /* []HsmInfo -- an array of structures with the current configuration         */
 /*      settings for each crypto unit in the service instance                 */
-/* string -- the base URL to use for requests to the IBM Cloud                */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*      units                                                                 */
 /* []common.DomainEntry -- identifies the set of crypto units assigned to     */
 /*      the service instance                                                  */
 /* error -- reports an error encountered when running the function, nil if    */
 /*      no error found                                                        */
 /*----------------------------------------------------------------------------*/
-func internalQuery(ci CommonInputs) ([]HsmInfo, string, []common.DomainEntry, error) {
+func internalQuery(ctx context.Context, ci CommonInputs) ([]HsmInfo,
+	common.Transport, []common.DomainEntry, error) {
 
 	// Create an empty output array
 	hsmInfo := make([]HsmInfo, 0)
@@ -110,67 +219,99 @@ func internalQuery(ci CommonInputs) ([]HsmInfo, string, []common.DomainEntry, er
 	// Create an empty domains array
 	domains := make([]common.DomainEntry, 0)
 
-	// Determine the base URL for sending requests to the cloud
-	urlStart, err := common.GetBaseURL(ci.ApiEndpoint, ci.Region)
+	// Determine how to send requests to the crypto units
+	tr, err := getTransport(ci)
 	if err != nil {
-		return hsmInfo, "", domains, err
+		return hsmInfo, nil, domains, err
 	}
 
 	// Query to see what crypto units are assigned to the service instance
-	domains, err = getDomains(ci.AuthToken, urlStart, ci.InstanceId)
+	domains, err = getDomains(ctx, tr, ci.InstanceId, ci.Parallelism)
 	if err != nil {
-		return hsmInfo, urlStart, domains, err
+		return hsmInfo, tr, domains, err
 	}
 
-	for _, domain := range domains {
+	// Query each crypto unit.  Results are stored by position so the order
+	// of hsmInfo matches the order of domains.
+	queriedHsms := make([]HsmInfo, len(domains))
+	err = common.RunParallel(ctx, ci.Parallelism, len(domains),
+		func(i int) error {
+			var err error
+			queriedHsms[i], err = queryHsm(ctx, tr, domains[i])
+			return err
+		})
+	if err != nil {
+		return hsmInfo, tr, domains, err
+	}
+	hsmInfo = queriedHsms
 
-		// Create an empty structure for this domain
-		nextHsm := HsmInfo{}
+	return hsmInfo, tr, domains, nil
+}
 
-		nextHsm.HsmId = domain.Hsm_id
-		nextHsm.HsmLocation = domain.Location
-		nextHsm.HsmType = domain.Type
+/*----------------------------------------------------------------------------*/
+/* Queries the configuration of a single crypto unit.                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto unit                                               */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*      unit                                                                  */
+/* common.DomainEntry -- identifies the crypto unit                           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* HsmInfo -- the current configuration settings for the crypto unit          */
+/* error -- reports the first error encountered                               */
+/*----------------------------------------------------------------------------*/
+func queryHsm(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry) (HsmInfo, error) {
 
-		// Query the signature thresholds
-		domAttr, _, err := ep11cmds.QueryDomainAttributes(ci.AuthToken, urlStart, domain)
-		if err != nil {
-			return hsmInfo, urlStart, domains, err
-		}
-		nextHsm.SignatureThreshold = int(domAttr.SignatureThreshold)
-		nextHsm.RevocationThreshold = int(domAttr.RevocationSignatureThreshold)
+	// Create an empty structure for this domain
+	nextHsm := HsmInfo{}
 
-		// Query domain administrators
-		domAdminSKIs, err := ep11cmds.QueryDomainAdmins(ci.AuthToken, urlStart, domain)
-		if err != nil {
-			return hsmInfo, urlStart, domains, err
-		}
-		nextHsm.Admins = make([]ReturnedAdminInfo, len(domAdminSKIs))
-		for j := 0; j < len(domAdminSKIs); j++ {
-			nextHsm.Admins[j].AdminSKI = hex.EncodeToString(domAdminSKIs[j])
-
-			name, err := ep11cmds.QueryDomainAdminName(ci.AuthToken, urlStart, domain, domAdminSKIs[j])
-			if err != nil {
-				return hsmInfo, urlStart, domains, err
-			}
-			nextHsm.Admins[j].AdminName = name
-		}
+	nextHsm.HsmId = domain.Hsm_id
+	nextHsm.HsmLocation = domain.Location
+	nextHsm.HsmType = domain.Type
 
-		// Query master key register state and verification pattern
-		domainInfo, err := ep11cmds.QueryDomainInfo(ci.AuthToken, urlStart, domain)
+	// Query the signature thresholds
+	domAttr, _, err := ep11cmds.QueryDomainAttributesWithContext(ctx, tr,
+		domain)
+	if err != nil {
+		return nextHsm, err
+	}
+	nextHsm.SignatureThreshold = int(domAttr.SignatureThreshold)
+	nextHsm.RevocationThreshold = int(domAttr.RevocationSignatureThreshold)
+
+	// Query domain administrators
+	domAdminSKIs, err := ep11cmds.QueryDomainAdminsWithContext(ctx, tr,
+		domain)
+	if err != nil {
+		return nextHsm, err
+	}
+	nextHsm.Admins = make([]ReturnedAdminInfo, len(domAdminSKIs))
+	for j := 0; j < len(domAdminSKIs); j++ {
+		nextHsm.Admins[j].AdminSKI = hex.EncodeToString(domAdminSKIs[j])
+
+		name, err := ep11cmds.QueryDomainAdminNameWithContext(ctx, tr,
+			domain, domAdminSKIs[j])
 		if err != nil {
-			return hsmInfo, urlStart, domains, err
+			return nextHsm, err
 		}
+		nextHsm.Admins[j].AdminName = name
+	}
 
-		nextHsm.NewMKStatus = convertMKStatusToString(domainInfo.NewMKStatus)
-		nextHsm.NewMKVP = hex.EncodeToString(domainInfo.NewMKVP)
+	// Query master key register state and verification pattern
+	domainInfo, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr, domain)
+	if err != nil {
+		return nextHsm, err
+	}
 
-		nextHsm.CurrentMKStatus = convertMKStatusToString(domainInfo.CurrentMKStatus)
-		nextHsm.CurrentMKVP = hex.EncodeToString(domainInfo.CurrentMKVP)
+	nextHsm.NewMKStatus = convertMKStatusToString(domainInfo.NewMKStatus)
+	nextHsm.NewMKVP = hex.EncodeToString(domainInfo.NewMKVP)
 
-		hsmInfo = append(hsmInfo, nextHsm)
-	}
+	nextHsm.CurrentMKStatus = convertMKStatusToString(domainInfo.CurrentMKStatus)
+	nextHsm.CurrentMKVP = hex.EncodeToString(domainInfo.CurrentMKVP)
 
-	return hsmInfo, urlStart, domains, nil
+	return nextHsm, nil
 }
 
 /*----------------------------------------------------------------------------*/
@@ -189,3 +330,50 @@ func convertMKStatusToString(status int) string {
 		return ""
 	}
 }
+
+/*----------------------------------------------------------------------------*/
+/* Returns the transport to use for sending requests to the crypto units.     */
+/*                                                                            */
+/* Uses the transport in the CommonInputs if one is provided.  Otherwise a    */
+/* transport for the TKE REST API is created using the base URL, or the API   */
+/* endpoint and region, and the token provider or authentication token in the */
+/* CommonInputs.  The rate limiter, retry policy, and OA root keys in the     */
+/* CommonInputs, if any, are attached to the transport.                       */
+/*----------------------------------------------------------------------------*/
+func getTransport(ci CommonInputs) (common.Transport, error) {
+	tr := ci.Transport
+	if tr == nil {
+		// Determine the base URL for sending requests to the cloud
+		var urlStart string
+		var err error
+		if ci.BaseURL != "" {
+			urlStart, err = common.CheckBaseURL(ci.BaseURL)
+		} else {
+			urlStart, err = common.GetBaseURL(ci.ApiEndpoint, ci.Region)
+		}
+		if err != nil {
+			return nil, err
+		}
+		httpTransport := common.NewHTTPTransport(ci.AuthToken, urlStart)
+		httpTransport.Client = ci.HTTPClient
+		httpTransport.Tokens = ci.Tokens
+		iamTokens, ok := ci.Tokens.(*common.IAMTokenProvider)
+		if ok && ci.HTTPClient != nil {
+			iamTokens.SetFallbackHTTPClient(ci.HTTPClient)
+		}
+		tr = httpTransport
+	}
+
+	if ci.RateLimiter != nil {
+		tr = common.WithRateLimiter(tr, ci.RateLimiter)
+	}
+
+	if ci.Retry != nil {
+		tr = common.WithRetryPolicy(tr, *ci.Retry)
+	}
+
+	if len(ci.OARootKeys) > 0 {
+		tr = common.WithOARootKeys(tr, ci.OARootKeys...)
+	}
+	return tr, nil
+}
@@ -0,0 +1,169 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Test CommonInputs.HTTPClient
+// 10/18/2026    CLH             Test CommonInputs.BaseURL
+
+package tkesdk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Transport that counts the requests passed to another transport */
+type countingTransport struct {
+	common.Transport
+	mutex    sync.Mutex
+	queries  int
+	requests int
+}
+
+func (c *countingTransport) QueryDomains(ctx context.Context,
+	cryptoInstance string) ([]string, []string, []string, []string, error) {
+
+	c.mutex.Lock()
+	c.queries++
+	c.mutex.Unlock()
+	return c.Transport.QueryDomains(ctx, cryptoInstance)
+}
+
+func (c *countingTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	c.mutex.Lock()
+	c.requests++
+	c.mutex.Unlock()
+	return c.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+		htpRequest)
+}
+
+/** Transport that fails every request */
+type failingTransport struct{}
+
+var errTransportDown = errors.New("transport down")
+
+func (failingTransport) QueryDomains(ctx context.Context,
+	cryptoInstance string) ([]string, []string, []string, []string, error) {
+
+	return nil, nil, nil, nil, errTransportDown
+}
+
+func (failingTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	return "", errTransportDown
+}
+
+/** Query sends every request through CommonInputs.Transport */
+func TestQueryUsesTransport(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	counter := &countingTransport{Transport: em}
+	ci.Transport = counter
+
+	hsminfo := mustQuery(t, ci)
+	if len(hsminfo) != len(defaultTestUnits) {
+		t.Fatalf("Query returned %d crypto units, expected %d",
+			len(hsminfo), len(defaultTestUnits))
+	}
+	if counter.queries != 1 || counter.requests == 0 {
+		t.Errorf("Transport used for %d queries and %d requests",
+			counter.queries, counter.requests)
+	}
+	for _, hsm := range hsminfo {
+		if hsm.SignatureThreshold != 0 || hsm.CurrentMKStatus != "Empty" {
+			t.Errorf("Crypto unit %s is not in its initial state: %+v",
+				hsm.HsmLocation, hsm)
+		}
+	}
+}
+
+/** Errors from the transport are returned to the caller */
+func TestQueryReturnsTransportError(t *testing.T) {
+	ci := tkesdk.CommonInputs{InstanceId: "instance1",
+		Transport: failingTransport{}}
+	_, err := tkesdk.Query(ci)
+	if err != errTransportDown {
+		t.Errorf("Query returned %v, expected %v", err, errTransportDown)
+	}
+}
+
+/** Without a transport, an unknown API endpoint is reported */
+func TestQueryWithoutTransportChecksEndpoint(t *testing.T) {
+	ci := tkesdk.CommonInputs{InstanceId: "instance1",
+		ApiEndpoint: "cloud.example.com", Region: "us-south"}
+	_, err := tkesdk.Query(ci)
+	if err == nil {
+		t.Error("Query accepted an unknown API endpoint")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* CommonInputs.HTTPClient is used for requests to the TKE REST API and for   */
+/* the IAM token requests of an IAMTokenProvider with no HTTP client.  The    */
+/* TLS test server is not trusted by the default HTTP client.                 */
+/*----------------------------------------------------------------------------*/
+func TestQueryUsesHTTPClient(t *testing.T) {
+	em, _ := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/identity/token" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"abc",` +
+					`"token_type":"Bearer","expires_in":3600}`))
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			em.ServeHTTP(w, r)
+		}))
+	defer server.Close()
+
+	tokens := common.NewIAMAPIKeyTokenProvider("apikey")
+	tokens.IAMURL = server.URL
+	ci := tkesdk.CommonInputs{InstanceId: "instance1", BaseURL: server.URL,
+		Tokens: tokens, HTTPClient: server.Client(),
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+	hsminfo := mustQuery(t, ci)
+	if len(hsminfo) != len(defaultTestUnits) {
+		t.Errorf("Query returned %d crypto units, expected %d",
+			len(hsminfo), len(defaultTestUnits))
+	}
+}
+
+/** CommonInputs.BaseURL replaces the API endpoint and region */
+func TestQueryUsesBaseURL(t *testing.T) {
+	em, _ := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	server := httptest.NewServer(em)
+	defer server.Close()
+
+	ci := tkesdk.CommonInputs{InstanceId: "instance1",
+		ApiEndpoint: "cloud.example.com", BaseURL: server.URL + "/",
+		AuthToken: "Bearer token",
+		OARootKeys: []common.OARootKey{em.OARootKey()}}
+	if len(mustQuery(t, ci)) != len(defaultTestUnits) {
+		t.Error("Query did not reach the crypto units at the base URL")
+	}
+	ci.BaseURL = "tke.example.com"
+	if _, err := tkesdk.Query(ci); err == nil {
+		t.Error("Query accepted a base URL without a scheme")
+	}
+}
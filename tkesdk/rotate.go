@@ -0,0 +1,494 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Copy the master key in several key parts
+// 10/18/2026    CLH             Share checks with LoadMasterKeyFromParts
+// 10/18/2026    CLH             Create signers with CommonInputs.HTTPClient
+// 10/18/2026    CLH             Recover the output of commands whose response was lost
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Create signers once and close them afterwards
+
+package tkesdk
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+// Phases of a master key rotation, see MasterKeyRotationPhase
+const (
+	MK_ROTATION_NOT_STARTED = "Not Started"
+	MK_ROTATION_IN_PROGRESS = "In Progress"
+	MK_ROTATION_COMMITTED   = "Committed"
+	MK_ROTATION_FINALIZING  = "Finalizing"
+	MK_ROTATION_BLOCKED     = "Blocked"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Rotates the master key of a service instance, up to the point where key    */
+/* stores must be reencrypted.                                                */
+/*                                                                            */
+/* A new random master key is generated in the new master key register of a   */
+/* recovery crypto unit.  It is exported to every other crypto unit using an  */
+/* importer key generated in that crypto unit, and the new master key         */
+/* registers are then committed everywhere.  The verification pattern of      */
+/* every new master key register is checked before the function returns.      */
+/*                                                                            */
+/* When the function returns with no problems and no error, reencrypt the     */
+/* key stores of the service instance under the new master key, then call     */
+/* FinalizeMasterKeyRotation to make it the current master key.               */
+/*                                                                            */
+/* The phase reached is kept in the master key registers of the crypto units. */
+/* If the function is interrupted, call it again to continue from where it    */
+/* stopped.  New master key registers that are already loaded or committed    */
+/* are not loaded or committed again.                                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.  This includes: the API endpoint and region, the HPCS      */
+/*      service instance id, and an IBM Cloud authentication token.           */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*      section of the resource block for the HPCS service instance.  This    */
+/*      provides access to signature keys for signing commands to crypto      */
+/*      units.  The administrators and thresholds are not changed.            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the master key cannot be rotated                               */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func RotateMasterKeyWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig) ([]string, error) {
+
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+
+	hsminfo, tr, domains, signers, problems, err :=
+		prepareMasterKeyRotation(ctx, ci, hc, admins)
+	if err != nil || len(problems) > 0 {
+		return problems, err
+	}
+
+	phase, newVP, problems := masterKeyRotationState(hsminfo)
+	switch phase {
+	case MK_ROTATION_BLOCKED:
+		return problems, nil
+	case MK_ROTATION_FINALIZING:
+		return []string{"The new master key is already the current master " +
+			"key in some crypto units.  Use FinalizeMasterKeyRotation to " +
+			"complete the master key rotation."}, nil
+	}
+
+	// Choose the recovery crypto unit to export the new master key from
+	source := -1
+	for i := range hsminfo {
+		if hsminfo[i].HsmType == "recovery" &&
+			(phase == MK_ROTATION_NOT_STARTED ||
+				sameMKVP(hsminfo[i].NewMKVP, newVP)) {
+
+			source = i
+			break
+		}
+	}
+	if source < 0 {
+		// Checked by masterKeyRotationState
+		return make([]string, 0), errors.New("No recovery crypto unit " +
+			"found to export the new master key")
+	}
+
+	//--------------------------------------------------------------------------
+	// Phase 1: generate a new master key in the recovery crypto unit
+	//--------------------------------------------------------------------------
+
+	if phase == MK_ROTATION_NOT_STARTED {
+		vp, err := createRandomMasterKey(ctx, tr, domains[source],
+			signers[source].single)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		newVP = hex.EncodeToString(vp)
+		hsminfo[source].NewMKStatus = "Full Uncommitted"
+		hsminfo[source].NewMKVP = newVP
+	}
+
+	//--------------------------------------------------------------------------
+	// Phase 2: load the new master key in the other crypto units
+	//--------------------------------------------------------------------------
+
+	for i, domain := range domains {
+		if hsminfo[i].NewMKStatus != "Empty" {
+			continue
+		}
+
+		// Copy the new master key from the recovery crypto unit
+		err = copyMasterKey(ctx, tr, domains[source], domain, true, hc,
+			signers[source].threshold, signers[i].single)
+		if err != nil {
+			return make([]string, 0), err
+		}
+
+		// Check that the imported value is the new master key
+		hsminfo[i], err = checkNewMKVP(ctx, tr, domain, hsminfo[i], newVP)
+		if err != nil {
+			return make([]string, 0), err
+		}
+	}
+
+	//--------------------------------------------------------------------------
+	// Phase 3: commit the new master key in every crypto unit
+	//--------------------------------------------------------------------------
+
+	for i, domain := range domains {
+		if hsminfo[i].NewMKStatus == "Full Committed" {
+			continue
+		}
+		err = commitPendingMasterKey(ctx, tr, domain, signers[i].threshold)
+		if err != nil {
+			return make([]string, 0), err
+		}
+	}
+
+	// Check that every crypto unit has the same committed new master key
+	for i, domain := range domains {
+		hsminfo[i], err = checkNewMKVP(ctx, tr, domain, hsminfo[i], newVP)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		if hsminfo[i].NewMKStatus != "Full Committed" {
+			return make([]string, 0), errors.New("The new master key " +
+				"register of crypto unit " + hsminfo[i].HsmLocation +
+				" was not committed.")
+		}
+	}
+
+	return make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as RotateMasterKeyWithContext, using the background context           */
+/*----------------------------------------------------------------------------*/
+func RotateMasterKey(ci CommonInputs, hc HsmConfig) ([]string, error) {
+	return RotateMasterKeyWithContext(context.Background(), ci, hc)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Completes a master key rotation started by RotateMasterKey, by making the  */
+/* committed new master key the current master key in every crypto unit.      */
+/* Call this only after the key stores of the service instance have been      */
+/* reencrypted under the new master key.                                      */
+/*                                                                            */
+/* If the function is interrupted, call it again.  Crypto units where the new */
+/* master key is already the current master key are skipped.                  */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.  This includes: the API endpoint and region, the HPCS      */
+/*      service instance id, and an IBM Cloud authentication token.           */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*      section of the resource block for the HPCS service instance.  This    */
+/*      provides access to signature keys for signing commands to crypto      */
+/*      units.                                                                */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the master key rotation cannot be completed                    */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func FinalizeMasterKeyRotationWithContext(ctx context.Context,
+	ci CommonInputs, hc HsmConfig) ([]string, error) {
+
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+
+	hsminfo, tr, domains, signers, problems, err :=
+		prepareMasterKeyRotation(ctx, ci, hc, admins)
+	if err != nil || len(problems) > 0 {
+		return problems, err
+	}
+
+	phase, newVP, problems := masterKeyRotationState(hsminfo)
+	switch phase {
+	case MK_ROTATION_BLOCKED:
+		return problems, nil
+	case MK_ROTATION_NOT_STARTED:
+		return []string{"No master key rotation is in progress."}, nil
+	case MK_ROTATION_IN_PROGRESS:
+		return []string{"The new master key is not committed in every " +
+			"crypto unit.  Use RotateMasterKey to complete the previous " +
+			"phases of the master key rotation."}, nil
+	}
+
+	for i, domain := range domains {
+		if hsminfo[i].NewMKStatus == "Empty" {
+			// Already finalized
+			continue
+		}
+		err = finalizeMasterKey(ctx, tr, domain, signers[i].single)
+		if err != nil {
+			return make([]string, 0), err
+		}
+
+		// Check that the new master key is now the current master key
+		domainInfo, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr,
+			domain)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		if !sameMKVP(hex.EncodeToString(domainInfo.CurrentMKVP), newVP) {
+			return make([]string, 0), errors.New("The current master key " +
+				"of crypto unit " + hsminfo[i].HsmLocation + " does not " +
+				"have the verification pattern of the new master key " +
+				"after the new master key register was finalized.")
+		}
+	}
+
+	return make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as FinalizeMasterKeyRotationWithContext, using the background context */
+/*----------------------------------------------------------------------------*/
+func FinalizeMasterKeyRotation(ci CommonInputs, hc HsmConfig) ([]string,
+	error) {
+
+	return FinalizeMasterKeyRotationWithContext(context.Background(), ci, hc)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the phase of a master key rotation, from the configuration of the  */
+/* crypto units returned by Query:                                            */
+/*                                                                            */
+/* MK_ROTATION_NOT_STARTED -- no new master key registers are loaded          */
+/* MK_ROTATION_IN_PROGRESS -- a new master key is loaded in some crypto       */
+/*      units but not committed in all of them; call RotateMasterKey          */
+/* MK_ROTATION_COMMITTED -- the new master key is committed in every crypto   */
+/*      unit; reencrypt the key stores, then call FinalizeMasterKeyRotation   */
+/* MK_ROTATION_FINALIZING -- the new master key is the current master key in  */
+/*      some crypto units; call FinalizeMasterKeyRotation                     */
+/* MK_ROTATION_BLOCKED -- the master key registers are in a state that the    */
+/*      TKE SDK cannot continue from; RotateMasterKey reports why             */
+/*----------------------------------------------------------------------------*/
+func MasterKeyRotationPhase(hsminfo []HsmInfo) string {
+	phase, _, _ := masterKeyRotationState(hsminfo)
+	return phase
+}
+
+/** Signature keys to use for commands to one crypto unit */
+type unitSigners struct {
+	single    []common.Signer // for commands needing one signature
+	threshold []common.Signer // for commands needing the signature threshold
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks the inputs for a master key rotation, reads the configuration of    */
+/* the crypto units, and assembles the signature keys to use for each crypto  */
+/* unit.  Only signature keys for installed administrators are used.  The     */
+/* signers are taken from admins, which the caller closes.                    */
+/*----------------------------------------------------------------------------*/
+func prepareMasterKeyRotation(ctx context.Context, ci CommonInputs,
+	hc HsmConfig, admins *adminSigners) ([]HsmInfo, common.Transport,
+	[]common.DomainEntry, []unitSigners, []string, error) {
+
+	// Check inputs in the resource block
+	problems := checkInputs(hc, admins)
+	if len(problems) > 0 {
+		return nil, nil, nil, nil, problems, nil
+	}
+
+	// Read the initial configuration
+	hsminfo, tr, domains, err := internalQuery(ctx, ci)
+	if err != nil {
+		return nil, nil, nil, nil, make([]string, 0), err
+	}
+
+	// Identify what signature keys are in the resource block
+	_, signerMap, adminNameMap, err := getSignatureKeys(admins)
+	if err != nil {
+		return nil, nil, nil, nil, make([]string, 0), err
+	}
+	signerOrder := signerPreferenceOrder(hc, adminNameMap)
+
+	signers := make([]unitSigners, len(hsminfo))
+	for i := range hsminfo {
+		if hsminfo[i].SignatureThreshold == 0 {
+			problems = append(problems, "Crypto unit "+
+				hsminfo[i].HsmLocation+" is in imprint mode.  Use Update "+
+				"to install administrators before changing the master key.")
+			continue
+		}
+		installedSKIs := make([]string, 0)
+		for _, admin := range hsminfo[i].Admins {
+			if _, ok := signerMap[admin.AdminSKI]; ok {
+				installedSKIs = append(installedSKIs, admin.AdminSKI)
+			}
+		}
+		if len(installedSKIs) < hsminfo[i].SignatureThreshold {
+			problems = append(problems, "Not enough signature keys for "+
+				"installed administrators are provided in the resource "+
+				"block to meet the signature threshold of crypto unit "+
+				hsminfo[i].HsmLocation+".")
+			continue
+		}
+		signers[i].single = collectSigKeys(installedSKIs, signerOrder,
+			signerMap, 1)
+		signers[i].threshold = collectSigKeys(installedSKIs, signerOrder,
+			signerMap, hsminfo[i].SignatureThreshold)
+	}
+	return hsminfo, tr, domains, signers, problems, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Determines the phase of a master key rotation from the master key          */
+/* registers of the crypto units.                                             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- the phase, one of the MK_ROTATION constants                      */
+/* string -- verification pattern of the new master key, as a hexadecimal     */
+/*      string, or "" if no new master key register is loaded                 */
+/* []string -- reasons the rotation cannot continue, when the phase is        */
+/*      MK_ROTATION_BLOCKED                                                   */
+/*----------------------------------------------------------------------------*/
+func masterKeyRotationState(hsminfo []HsmInfo) (string, string, []string) {
+
+	problems := make([]string, 0)
+
+	// Every current master key register must already be set
+	for i := range hsminfo {
+		if hsminfo[i].CurrentMKStatus == "Empty" {
+			problems = append(problems, "The current master key register "+
+				"of crypto unit "+hsminfo[i].HsmLocation+" is empty.  Use "+
+				"Update to load the master key before rotating it.")
+		}
+	}
+
+	// All loaded new master key registers must hold the same value
+	newVP := ""
+	for i := range hsminfo {
+		if hsminfo[i].NewMKStatus == "Empty" {
+			continue
+		}
+		if newVP == "" {
+			newVP = hsminfo[i].NewMKVP
+		} else if !sameMKVP(hsminfo[i].NewMKVP, newVP) {
+			problems = append(problems, "New master key registers are set "+
+				"in multiple crypto units but are not set to the same "+
+				"value.  Clear the new master key registers before "+
+				"rotating the master key.")
+			break
+		}
+	}
+	if len(problems) > 0 {
+		return MK_ROTATION_BLOCKED, newVP, problems
+	}
+
+	// Crypto units where the new master key is already the current master
+	// key have been finalized.  All other crypto units must still have the
+	// same current master key.
+	finalized := 0
+	committed := 0
+	recoveryLoaded := false
+	anyRecovery := false
+	oldVP := ""
+	for i := range hsminfo {
+		if hsminfo[i].HsmType == "recovery" {
+			anyRecovery = true
+			if hsminfo[i].NewMKStatus != "Empty" {
+				recoveryLoaded = true
+			}
+		}
+		if newVP != "" && hsminfo[i].NewMKStatus == "Empty" &&
+			sameMKVP(hsminfo[i].CurrentMKVP, newVP) {
+
+			finalized++
+			continue
+		}
+		if hsminfo[i].NewMKStatus == "Full Committed" {
+			committed++
+		}
+		if oldVP == "" {
+			oldVP = hsminfo[i].CurrentMKVP
+		} else if !sameMKVP(hsminfo[i].CurrentMKVP, oldVP) {
+			problems = append(problems, "Current master key registers "+
+				"are set in multiple crypto units but are not set to the "+
+				"same value.")
+			return MK_ROTATION_BLOCKED, newVP, problems
+		}
+	}
+
+	switch {
+	case !anyRecovery:
+		problems = append(problems, "The service instance does not "+
+			"contain any recovery crypto units.")
+	case newVP == "":
+		return MK_ROTATION_NOT_STARTED, newVP, problems
+	case finalized > 0 && finalized+committed == len(hsminfo):
+		return MK_ROTATION_FINALIZING, newVP, problems
+	case finalized > 0:
+		problems = append(problems, "The new master key is the current "+
+			"master key in some crypto units but is not committed in all "+
+			"other crypto units.")
+	case committed == len(hsminfo):
+		return MK_ROTATION_COMMITTED, newVP, problems
+	case !recoveryLoaded:
+		problems = append(problems, "The new master key register is set "+
+			"in operational crypto units but not in any recovery crypto "+
+			"unit, so the new master key cannot be copied to the other "+
+			"crypto units.  Clear the new master key registers before "+
+			"rotating the master key.")
+	default:
+		return MK_ROTATION_IN_PROGRESS, newVP, problems
+	}
+	return MK_ROTATION_BLOCKED, newVP, problems
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the new master key register of a crypto unit, checks that it holds   */
+/* the new master key, and returns the HsmInfo with the register updated.     */
+/*----------------------------------------------------------------------------*/
+func checkNewMKVP(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, hsm HsmInfo, newVP string) (HsmInfo, error) {
+
+	domainInfo, err := ep11cmds.QueryDomainInfoWithContext(ctx, tr, domain)
+	if err != nil {
+		return hsm, err
+	}
+	hsm.NewMKStatus = convertMKStatusToString(domainInfo.NewMKStatus)
+	hsm.NewMKVP = hex.EncodeToString(domainInfo.NewMKVP)
+	if hsm.NewMKStatus == "Empty" || !sameMKVP(hsm.NewMKVP, newVP) {
+		return hsm, errors.New("The new master key register of crypto unit " +
+			hsm.HsmLocation + " does not have the verification pattern of " +
+			"the new master key.")
+	}
+	return hsm, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Compares two master key verification patterns in hexadecimal.  Only the    */
+/* first 28 bytes are compared, as in internalCheckTransition.                */
+/*----------------------------------------------------------------------------*/
+func sameMKVP(vp1 string, vp2 string) bool {
+	if len(vp1) < 56 || len(vp2) < 56 {
+		return vp1 == vp2
+	}
+	return vp1[0:56] == vp2[0:56]
+}
@@ -0,0 +1,293 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Checks that every crypto unit has the given new and current master keys */
+func checkMasterKeys(t *testing.T, hsminfo []tkesdk.HsmInfo,
+	newStatus string, newVP string, currentVP string) {
+
+	for _, hsm := range hsminfo {
+		if hsm.NewMKStatus != newStatus ||
+			(newVP != "" && !sameVP(hsm.NewMKVP, newVP)) {
+			t.Errorf("%s: new master key register %s %s", hsm.HsmLocation,
+				hsm.NewMKStatus, hsm.NewMKVP)
+		}
+		if hsm.CurrentMKStatus != "Valid" ||
+			!sameVP(hsm.CurrentMKVP, currentVP) {
+			t.Errorf("%s: current master key register %s %s",
+				hsm.HsmLocation, hsm.CurrentMKStatus, hsm.CurrentMKVP)
+		}
+	}
+}
+
+/** Runs RotateMasterKey and fails the test if any problems are reported */
+func mustRotate(t *testing.T, ci tkesdk.CommonInputs, hc tkesdk.HsmConfig) {
+	problems, err := tkesdk.RotateMasterKey(ci, hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("RotateMasterKey reported problems: %v", problems)
+	}
+}
+
+/** Runs FinalizeMasterKeyRotation and fails the test on any problems */
+func mustFinalize(t *testing.T, ci tkesdk.CommonInputs, hc tkesdk.HsmConfig) {
+	problems, err := tkesdk.FinalizeMasterKeyRotation(ci, hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("FinalizeMasterKeyRotation reported problems: %v", problems)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A rotation commits the same new master key in every crypto unit and leaves */
+/* the current master key in use until it is finalized                        */
+/*----------------------------------------------------------------------------*/
+func TestRotateMasterKey(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	hsminfo := mustQuery(t, ci)
+	oldVP := hsminfo[0].CurrentMKVP
+	if phase := tkesdk.MasterKeyRotationPhase(hsminfo); phase !=
+		tkesdk.MK_ROTATION_NOT_STARTED {
+		t.Errorf("Phase before the rotation is %s", phase)
+	}
+
+	mustRotate(t, ci, hc)
+	hsminfo = mustQuery(t, ci)
+	newVP := hsminfo[0].NewMKVP
+	checkMasterKeys(t, hsminfo, "Full Committed", newVP, oldVP)
+	if sameVP(newVP, oldVP) {
+		t.Error("The new master key is the old master key")
+	}
+	if phase := tkesdk.MasterKeyRotationPhase(hsminfo); phase !=
+		tkesdk.MK_ROTATION_COMMITTED {
+		t.Errorf("Phase after RotateMasterKey is %s", phase)
+	}
+
+	// Running it again changes nothing
+	mustRotate(t, ci, hc)
+	checkMasterKeys(t, mustQuery(t, ci), "Full Committed", newVP, oldVP)
+
+	mustFinalize(t, ci, hc)
+	hsminfo = mustQuery(t, ci)
+	checkMasterKeys(t, hsminfo, "Empty", "", newVP)
+	if phase := tkesdk.MasterKeyRotationPhase(hsminfo); phase !=
+		tkesdk.MK_ROTATION_NOT_STARTED {
+		t.Errorf("Phase after FinalizeMasterKeyRotation is %s", phase)
+	}
+	problems, err := tkesdk.FinalizeMasterKeyRotation(ci, hc)
+	if err != nil || len(problems) != 1 {
+		t.Errorf("FinalizeMasterKeyRotation with no rotation returned %v %v",
+			problems, err)
+	}
+
+	// A second rotation starts from the new current master key
+	mustRotate(t, ci, hc)
+	mustFinalize(t, ci, hc)
+	hsminfo = mustQuery(t, ci)
+	checkMasterKeys(t, hsminfo, "Empty", "", hsminfo[0].CurrentMKVP)
+	if sameVP(hsminfo[0].CurrentMKVP, newVP) {
+		t.Error("The second rotation did not change the master key")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A rotation is interrupted at every fifth request.  Running the functions   */
+/* again completes the rotation with one new master key in every crypto unit. */
+/*----------------------------------------------------------------------------*/
+func TestRotateMasterKeyResumes(t *testing.T) {
+	units := defaultTestUnits[:2]
+	hc := newTestHsmConfig(t)
+
+	// Count the requests sent by an uninterrupted rotation
+	em, ci := newTestInstance(t, "instance1", units)
+	mustUpdate(t, ci, hc)
+	counter := &countingTransport{Transport: em}
+	ci.Transport = counter
+	mustRotate(t, ci, hc)
+	mustFinalize(t, ci, hc)
+	em.Close()
+	total := counter.requests
+
+	for cancelAt := 1; cancelAt <= total; cancelAt += 5 {
+		em, ci := newTestInstance(t, "instance1", units)
+		mustUpdate(t, ci, hc)
+		oldVP := mustQuery(t, ci)[0].CurrentMKVP
+
+		ctx, cancel := context.WithCancel(context.Background())
+		tr := &cancellingTransport{Transport: em, cancel: cancel,
+			cancelAt: cancelAt}
+		ci.Transport = tr
+		problems, err := tkesdk.RotateMasterKeyWithContext(ctx, ci, hc)
+		if err == nil && len(problems) == 0 {
+			_, err = tkesdk.FinalizeMasterKeyRotationWithContext(ctx, ci, hc)
+		}
+		cancel()
+		if err != context.Canceled && err != nil {
+			t.Errorf("Cancelled at request %d: %v %v", cancelAt, problems,
+				err)
+		}
+		if tr.failed > 0 {
+			t.Errorf("Cancelled at request %d: %d signed commands failed",
+				cancelAt, tr.failed)
+		}
+
+		// Continue from the phase reached
+		ci.Transport = em
+		hsminfo := mustQuery(t, ci)
+		phase := tkesdk.MasterKeyRotationPhase(hsminfo)
+		if phase == tkesdk.MK_ROTATION_BLOCKED {
+			t.Errorf("Cancelled at request %d: the rotation is blocked",
+				cancelAt)
+			em.Close()
+			continue
+		}
+		if phase == tkesdk.MK_ROTATION_NOT_STARTED &&
+			!sameVP(hsminfo[0].CurrentMKVP, oldVP) {
+			// The rotation was already finalized
+			em.Close()
+			continue
+		}
+		if phase != tkesdk.MK_ROTATION_FINALIZING {
+			mustRotate(t, ci, hc)
+		}
+		mustFinalize(t, ci, hc)
+		hsminfo = mustQuery(t, ci)
+		checkMasterKeys(t, hsminfo, "Empty", "", hsminfo[0].CurrentMKVP)
+		if sameVP(hsminfo[0].CurrentMKVP, oldVP) {
+			t.Errorf("Cancelled at request %d: the master key was not "+
+				"changed", cancelAt)
+		}
+		em.Close()
+	}
+}
+
+/** Crypto units that cannot take part in a rotation are reported */
+func TestRotateMasterKeyProblems(t *testing.T) {
+	hc := newTestHsmConfig(t)
+
+	// Crypto units in imprint mode
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	problems, err := tkesdk.RotateMasterKey(ci, hc)
+	if err != nil || len(problems) != len(defaultTestUnits) ||
+		!strings.Contains(problems[0], "imprint mode") {
+		t.Errorf("RotateMasterKey in imprint mode returned %v %v", problems,
+			err)
+	}
+
+	// Not enough signature keys for the installed administrators
+	mustUpdate(t, ci, hc)
+	fewer := hc
+	fewer.Admins = hc.Admins[:1]
+	fewer.SignatureThreshold = 1
+	fewer.RevocationThreshold = 1
+	problems, err = tkesdk.RotateMasterKey(ci, fewer)
+	if err != nil || len(problems) == 0 {
+		t.Errorf("RotateMasterKey with one signature key returned %v %v",
+			problems, err)
+	}
+
+	// No rotation has been started
+	problems, err = tkesdk.FinalizeMasterKeyRotation(ci, hc)
+	if err != nil || len(problems) != 1 ||
+		!strings.Contains(problems[0], "No master key rotation") {
+		t.Errorf("FinalizeMasterKeyRotation returned %v %v", problems, err)
+	}
+}
+
+/** The phase is read from the master key registers of the crypto units */
+func TestMasterKeyRotationPhase(t *testing.T) {
+	oldVP := strings.Repeat("11", 32)
+	newVP := strings.Repeat("22", 32)
+	otherVP := strings.Repeat("33", 32)
+	unit := func(hsmType string, newStatus string, newMKVP string,
+		currentVP string) tkesdk.HsmInfo {
+
+		return tkesdk.HsmInfo{HsmType: hsmType, NewMKStatus: newStatus,
+			NewMKVP: newMKVP, CurrentMKStatus: "Valid",
+			CurrentMKVP: currentVP}
+	}
+
+	tests := []struct {
+		name    string
+		hsminfo []tkesdk.HsmInfo
+		phase   string
+	}{
+		{"not started", []tkesdk.HsmInfo{
+			unit("recovery", "Empty", "", oldVP),
+			unit("operational", "Empty", "", oldVP)},
+			tkesdk.MK_ROTATION_NOT_STARTED},
+		{"loaded in the recovery crypto unit", []tkesdk.HsmInfo{
+			unit("recovery", "Full Uncommitted", newVP, oldVP),
+			unit("operational", "Empty", "", oldVP)},
+			tkesdk.MK_ROTATION_IN_PROGRESS},
+		{"partly committed", []tkesdk.HsmInfo{
+			unit("recovery", "Full Committed", newVP, oldVP),
+			unit("operational", "Full Uncommitted", newVP, oldVP)},
+			tkesdk.MK_ROTATION_IN_PROGRESS},
+		{"committed", []tkesdk.HsmInfo{
+			unit("recovery", "Full Committed", newVP, oldVP),
+			unit("operational", "Full Committed", newVP, oldVP)},
+			tkesdk.MK_ROTATION_COMMITTED},
+		{"partly finalized", []tkesdk.HsmInfo{
+			unit("recovery", "Empty", "", newVP),
+			unit("operational", "Full Committed", newVP, oldVP)},
+			tkesdk.MK_ROTATION_FINALIZING},
+		{"finalized before committed everywhere", []tkesdk.HsmInfo{
+			unit("recovery", "Empty", "", newVP),
+			unit("operational", "Full Uncommitted", newVP, oldVP)},
+			tkesdk.MK_ROTATION_BLOCKED},
+		{"different new master keys", []tkesdk.HsmInfo{
+			unit("recovery", "Full Uncommitted", newVP, oldVP),
+			unit("operational", "Full Uncommitted", otherVP, oldVP)},
+			tkesdk.MK_ROTATION_BLOCKED},
+		{"different current master keys", []tkesdk.HsmInfo{
+			unit("recovery", "Empty", "", oldVP),
+			unit("operational", "Empty", "", otherVP)},
+			tkesdk.MK_ROTATION_BLOCKED},
+		{"loaded only in an operational crypto unit", []tkesdk.HsmInfo{
+			unit("recovery", "Empty", "", oldVP),
+			unit("operational", "Full Uncommitted", newVP, oldVP)},
+			tkesdk.MK_ROTATION_BLOCKED},
+		{"no recovery crypto unit", []tkesdk.HsmInfo{
+			unit("operational", "Empty", "", oldVP)},
+			tkesdk.MK_ROTATION_BLOCKED},
+	}
+	for _, test := range tests {
+		if phase := tkesdk.MasterKeyRotationPhase(test.hsminfo); phase !=
+			test.phase {
+			t.Errorf("%s: phase %s, expected %s", test.name, phase,
+				test.phase)
+		}
+	}
+
+	empty := unit("recovery", "Empty", "", oldVP)
+	empty.CurrentMKStatus = "Empty"
+	if phase := tkesdk.MasterKeyRotationPhase([]tkesdk.HsmInfo{
+		empty}); phase != tkesdk.MK_ROTATION_BLOCKED {
+		t.Errorf("Phase with an empty current master key is %s", phase)
+	}
+}
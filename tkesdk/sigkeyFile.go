@@ -0,0 +1,278 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Add version 2 signature key files
+// 10/18/2026    CLH             Report Dilithium keys as unsupported
+// 10/18/2026    CLH             Write version 1 files unless migrated
+
+package tkesdk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+)
+
+/** Signature key file information that can be read without the password */
+type SignatureKeyFileInfo struct {
+	SKI     string // Subject Key Identifier, as a hexadecimal string
+	KeyType string // common.KEY_TYPE_P521EC or common.KEY_TYPE_RSA2048
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signature key file holding a new administrator signature key.    */
+/* The file is written as a version 1 file, so the TKE CLI plug-in can read   */
+/* it, and is readable only by the owner.  An existing file is not replaced.  */
+/* Use MigrateSignatureKeyFile to rewrite the file as a version 2 file.       */
+/*                                                                            */
+/* Dilithium keys are not supported, since Go has no Dilithium                */
+/* implementation; see common.DilithiumPublicKey.                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string sigkey -- the full path and name of the signature key file          */
+/* string keyType -- common.KEY_TYPE_P521EC or common.KEY_TYPE_RSA2048        */
+/* string sigkeyToken -- the file password                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- Subject Key Identifier of the new signature key, represented as  */
+/*     a hexadecimal string                                                   */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func CreateSignatureKeyFile(sigkey string, keyType string,
+	sigkeyToken string) (string, error) {
+
+	var key crypto.PrivateKey
+	var err error
+	switch keyType {
+	case common.KEY_TYPE_P521EC:
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case common.KEY_TYPE_RSA2048:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case common.KEY_TYPE_DILITHIUM_R2_87:
+		return "", errors.New("Dilithium signature keys cannot be held in " +
+			"signature key files.  Use a PKCS #11 token that supports " +
+			"Dilithium keys.")
+	default:
+		return "", errors.New("Unsupported signature key type: " + keyType)
+	}
+	if err != nil {
+		return "", err
+	}
+	return writeNewSignatureKeyFile(sigkey, key, sigkeyToken)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates a signature key file holding an existing private key.  The key is  */
+/* read from PEM data in one of these forms:                                  */
+/*                                                                            */
+/* - a PRIVATE KEY block holding a PKCS #8 private key                        */
+/* - an EC PRIVATE KEY block holding a SEC 1 private key                      */
+/* - an RSA PRIVATE KEY block holding a PKCS #1 private key                   */
+/*                                                                            */
+/* Only P521 EC keys and 2048-bit RSA keys with a public exponent of 65537    */
+/* can be imported.  Encrypted PEM blocks are not supported.  The file is     */
+/* written as a version 1 file, as for CreateSignatureKeyFile.                */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string sigkey -- the full path and name of the signature key file          */
+/* []byte pemBytes -- the PEM encoded private key                             */
+/* string sigkeyToken -- the file password                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* string -- Subject Key Identifier of the signature key, represented as a    */
+/*     hexadecimal string                                                     */
+/* error -- reports any errors                                                */
+/*----------------------------------------------------------------------------*/
+func ImportSignatureKeyFile(sigkey string, pemBytes []byte,
+	sigkeyToken string) (string, error) {
+
+	pemBlock, _ := pem.Decode(pemBytes)
+	if pemBlock == nil {
+		return "", errors.New("PEM decode of private key failed.")
+	}
+	if _, ok := pemBlock.Headers["Proc-Type"]; ok {
+		return "", errors.New("Encrypted PEM private keys are not supported.")
+	}
+
+	var key crypto.PrivateKey
+	var err error
+	switch pemBlock.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(pemBlock.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
+	default:
+		return "", errors.New("Unsupported PEM block type: " + pemBlock.Type)
+	}
+	if err != nil {
+		return "", errors.New("Invalid private key.\nMessage: " + err.Error())
+	}
+	return writeNewSignatureKeyFile(sigkey, key, sigkeyToken)
+}
+
+/** Encrypts a private key and writes it to a new version 1 key file */
+func writeNewSignatureKeyFile(sigkey string, key crypto.PrivateKey,
+	sigkeyToken string) (string, error) {
+
+	skfields, err := common.EncryptSignatureKey(key, sigkeyToken,
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		return "", err
+	}
+	err = common.WriteSignatureKeyFile(sigkey, skfields, false)
+	if err != nil {
+		return "", err
+	}
+	return skfields["ski"], nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Changes the password of a signature key file.  The signature key is        */
+/* encrypted again using a new salt, and the file keeps its version, so a     */
+/* version 1 file can still be read by the TKE CLI plug-in.  Fields other     */
+/* than the encrypted key and its metadata are kept.                          */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string sigkey -- the full path and name of the signature key file          */
+/* string oldSigkeyToken -- the current file password                         */
+/* string newSigkeyToken -- the new file password                             */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports an invalid password or any error writing the file         */
+/*----------------------------------------------------------------------------*/
+func ChangeSignatureKeyFilePassword(sigkey string, oldSigkeyToken string,
+	newSigkeyToken string) error {
+
+	skfields, err := common.ReadSignatureKeyFile(sigkey)
+	if err != nil {
+		return err
+	}
+	version, err := common.SignatureKeyFileVersion(skfields)
+	if err != nil {
+		return err
+	}
+	key, err := common.DecryptSignatureKey(skfields, oldSigkeyToken)
+	if err != nil {
+		return err
+	}
+	newFields, err := common.EncryptSignatureKey(key, newSigkeyToken, version)
+	if err != nil {
+		return err
+	}
+
+	return replaceSignatureKeyFile(sigkey, skfields, newFields)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Rewrites a version 1 signature key file as a version 2 file, using a key   */
+/* derived with scrypt.  The password is not changed.  Files that are already */
+/* version 2 are not changed.  This is the only function that writes version  */
+/* 2 files; the TKE CLI plug-in cannot read them.                             */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string sigkey -- the full path and name of the signature key file          */
+/* string sigkeyToken -- the file password                                    */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* bool -- true if the file was rewritten                                     */
+/* error -- reports an invalid password or any error writing the file         */
+/*----------------------------------------------------------------------------*/
+func MigrateSignatureKeyFile(sigkey string, sigkeyToken string) (bool, error) {
+	skfields, err := common.ReadSignatureKeyFile(sigkey)
+	if err != nil {
+		return false, err
+	}
+	version, err := common.SignatureKeyFileVersion(skfields)
+	if err != nil {
+		return false, err
+	}
+	if version == common.SIGNATURE_KEY_FILE_VERSION_2 {
+		return false, nil
+	}
+	key, err := common.DecryptSignatureKey(skfields, sigkeyToken)
+	if err != nil {
+		return false, err
+	}
+	newFields, err := common.EncryptSignatureKey(key, sigkeyToken,
+		common.SIGNATURE_KEY_FILE_VERSION_2)
+	if err != nil {
+		return false, err
+	}
+	err = replaceSignatureKeyFile(sigkey, skfields, newFields)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Replaces the encrypted key and its metadata in a signature key file with   */
+/* newly encrypted fields.  Other fields, such as those written by the TKE    */
+/* CLI plug-in, are kept.                                                     */
+/*----------------------------------------------------------------------------*/
+func replaceSignatureKeyFile(sigkey string, skfields map[string]string,
+	newFields map[string]string) error {
+
+	// Compare the calculated and saved SKIs
+	if newFields["ski"] != strings.ToLower(skfields["ski"]) {
+		return errors.New("Miscompare on saved and calculated Subject Key Identifier.")
+	}
+	for _, name := range []string{"version", "keyType", "ski", "kdf",
+		"scryptN", "scryptR", "scryptP", "seaSalt", "enckey"} {
+		delete(skfields, name)
+	}
+	for name, value := range newFields {
+		skfields[name] = value
+	}
+	return common.WriteSignatureKeyFile(sigkey, skfields, true)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Subject Key Identifier and key type of a signature key file.   */
+/* The signature key is not decrypted, so no password is needed.              */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* string sigkey -- the full path and name of the signature key file          */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* SignatureKeyFileInfo -- the SKI and key type saved in the file             */
+/* error -- reports an unreadable file or an invalid SKI                      */
+/*----------------------------------------------------------------------------*/
+func GetSignatureKeyFileInfo(sigkey string) (SignatureKeyFileInfo, error) {
+	var info SignatureKeyFileInfo
+	skfields, err := common.ReadSignatureKeyFile(sigkey)
+	if err != nil {
+		return info, err
+	}
+	ski := skfields["ski"]
+	if _, err := hex.DecodeString(ski); err != nil || len(ski) != 64 {
+		return info, errors.New("Invalid Subject Key Identifier, length = " +
+			strconv.Itoa(len(ski)))
+	}
+	info.SKI = strings.ToLower(ski)
+	if skfields["keyType"] == common.KEY_TYPE_P521EC {
+		info.KeyType = common.KEY_TYPE_P521EC
+	} else {
+		// Files without the P521 EC key type hold 2048-bit RSA keys
+		info.KeyType = common.KEY_TYPE_RSA2048
+	}
+	return info, nil
+}
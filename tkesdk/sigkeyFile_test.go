@@ -0,0 +1,381 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+
+package tkesdk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Returns a temporary directory and a function to remove it */
+func tempKeyDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sigkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+/** Reads the fields of a signature key file, failing the test on an error */
+func readKeyFileFields(t *testing.T, path string) map[string]string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]string
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+/** New signature key files can be used and inspected without the password */
+func TestCreateSignatureKeyFile(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+
+	for _, keyType := range []string{common.KEY_TYPE_P521EC,
+		common.KEY_TYPE_RSA2048} {
+
+		path := filepath.Join(dir, keyType+".sigkey")
+		ski, err := tkesdk.CreateSignatureKeyFile(path, keyType, "password1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Errorf("%s file has mode %v", keyType, stat.Mode().Perm())
+		}
+		fields := readKeyFileFields(t, path)
+		for _, name := range []string{"keyType", "enckey", "seaSalt", "ski"} {
+			if fields[name] == "" {
+				t.Errorf("%s file has no %s field", keyType, name)
+			}
+		}
+		// New files can be read by the TKE CLI plug-in
+		if version, err := common.SignatureKeyFileVersion(fields); err != nil ||
+			version != common.SIGNATURE_KEY_FILE_VERSION_1 {
+			t.Errorf("%s file is version %d, expected version 1", keyType,
+				version)
+		}
+
+		info, err := tkesdk.GetSignatureKeyFileInfo(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.SKI != ski || info.KeyType != keyType {
+			t.Errorf("GetSignatureKeyFileInfo returned %+v, expected %s %s",
+				info, ski, keyType)
+		}
+
+		// The key signs, and administrator certificates can be made for it
+		signer, err := common.NewKeyFileSigner(path, "password1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(signer.SKI()) != ski ||
+			signer.KeyType() != keyType {
+			t.Errorf("Key file signer has SKI %X and key type %s",
+				signer.SKI(), signer.KeyType())
+		}
+		_, err = tkesdk.CreateAdminCertFromFile(path, ski, "password1",
+			"admin1")
+		if err != nil {
+			t.Errorf("CreateAdminCertFromFile for %s: %v", keyType, err)
+		}
+
+		// An existing file is not replaced
+		_, err = tkesdk.CreateSignatureKeyFile(path, keyType, "password2")
+		if err == nil {
+			t.Errorf("CreateSignatureKeyFile replaced the %s file", keyType)
+		}
+		if readKeyFileFields(t, path)["ski"] != ski {
+			t.Errorf("The %s file was changed", keyType)
+		}
+	}
+
+	// Other key types and empty passwords are rejected
+	tests := []struct {
+		keyType  string
+		password string
+	}{
+		{common.KEY_TYPE_DILITHIUM_R2_87, "password1"},
+		{"P256EC", "password1"},
+		{common.KEY_TYPE_P521EC, ""},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, "bad"+strconv.Itoa(i)+".sigkey")
+		_, err := tkesdk.CreateSignatureKeyFile(path, test.keyType,
+			test.password)
+		if err == nil {
+			t.Errorf("CreateSignatureKeyFile accepted key type %q and "+
+				"password %q", test.keyType, test.password)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("A file was left for key type %q", test.keyType)
+		}
+	}
+}
+
+/** PKCS #8, SEC 1, and PKCS #1 private keys can be imported */
+func TestImportSignatureKeyFile(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	rsaPKCS8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	rsaSKI, _ := common.CalculateRSAKeyHash(rsaKey.PublicKey)
+	ecSKI := hex.EncodeToString(common.CalculateECKeyHash(ecKey.PublicKey))
+
+	tests := []struct {
+		block pem.Block
+		ski   string
+	}{
+		{pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, ecSKI},
+		{pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, ecSKI},
+		{pem.Block{Type: "PRIVATE KEY", Bytes: rsaPKCS8},
+			hex.EncodeToString(rsaSKI)},
+		{pem.Block{Type: "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+			hex.EncodeToString(rsaSKI)},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, "import"+strconv.Itoa(i)+".sigkey")
+		ski, err := tkesdk.ImportSignatureKeyFile(path,
+			pem.EncodeToMemory(&test.block), "password1")
+		if err != nil {
+			t.Fatalf("%s: %v", test.block.Type, err)
+		}
+		if ski != test.ski {
+			t.Errorf("%s imported with SKI %s, expected %s", test.block.Type,
+				ski, test.ski)
+		}
+		signer, err := common.NewKeyFileSigner(path, "password1")
+		if err != nil || hex.EncodeToString(signer.SKI()) != test.ski {
+			t.Errorf("%s: the imported key could not be used: %v",
+				test.block.Type, err)
+		}
+	}
+
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256DER, _ := x509.MarshalECPrivateKey(p256Key)
+	badInputs := [][]byte{
+		[]byte("not PEM"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p256DER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1,
+			Headers: map[string]string{"Proc-Type": "4,ENCRYPTED"}}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: sec1}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}}),
+	}
+	for i, input := range badInputs {
+		path := filepath.Join(dir, "bad"+strconv.Itoa(i)+".sigkey")
+		_, err := tkesdk.ImportSignatureKeyFile(path, input, "password1")
+		if err == nil {
+			t.Errorf("ImportSignatureKeyFile accepted input %d", i)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Changing the password keeps the key, the version, and the other fields of  */
+/* the file                                                                   */
+/*----------------------------------------------------------------------------*/
+func TestChangeSignatureKeyFilePassword(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "admin1.sigkey")
+	ski, err := tkesdk.CreateSignatureKeyFile(path, common.KEY_TYPE_P521EC,
+		"password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fields written by other tools are kept
+	fields := readKeyFileFields(t, path)
+	fields["comment"] = "admin1 key"
+	data, _ := json.Marshal(fields)
+	ioutil.WriteFile(path, data, 0600)
+
+	err = tkesdk.ChangeSignatureKeyFilePassword(path, "wrong", "password2")
+	if err == nil {
+		t.Fatal("The password was changed using the wrong password")
+	}
+	err = tkesdk.ChangeSignatureKeyFilePassword(path, "password1",
+		"password2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := common.NewKeyFileSigner(path, "password1"); err == nil {
+		t.Error("The old password still works")
+	}
+	signer, err := common.NewKeyFileSigner(path, "password2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(signer.SKI()) != ski {
+		t.Errorf("The key changed: SKI %X, expected %s", signer.SKI(), ski)
+	}
+	fields = readKeyFileFields(t, path)
+	if fields["comment"] != "admin1 key" {
+		t.Errorf("The comment field was not kept: %v", fields)
+	}
+	if _, ok := fields["version"]; ok {
+		t.Errorf("A version 1 file was upgraded: %v", fields)
+	}
+	stat, _ := os.Stat(path)
+	if stat.Mode().Perm() != 0600 {
+		t.Errorf("The rewritten file has mode %v", stat.Mode().Perm())
+	}
+
+	// A migrated file stays version 2
+	if _, err = tkesdk.MigrateSignatureKeyFile(path, "password2"); err != nil {
+		t.Fatal(err)
+	}
+	err = tkesdk.ChangeSignatureKeyFilePassword(path, "password2",
+		"password3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields = readKeyFileFields(t, path)
+	if fields["version"] != "2" {
+		t.Errorf("A version 2 file was not kept as version 2: %v", fields)
+	}
+	if _, err := common.NewKeyFileSigner(path, "password3"); err != nil {
+		t.Error(err)
+	}
+}
+
+/** Files written by the TKE CLI plug-in may have no key type field */
+func TestGetSignatureKeyFileInfo(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+
+	ski := "00112233445566778899AABBCCDDEEFF00112233445566778899AABBCCDDEEFF"
+	tests := []struct {
+		fields  map[string]string
+		keyType string
+	}{
+		{map[string]string{"ski": ski, "enckey": "00", "seaSalt": "00"},
+			common.KEY_TYPE_RSA2048},
+		{map[string]string{"ski": ski, "keyType": common.KEY_TYPE_P521EC},
+			common.KEY_TYPE_P521EC},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, "info"+strconv.Itoa(i)+".sigkey")
+		data, _ := json.Marshal(test.fields)
+		ioutil.WriteFile(path, data, 0600)
+		info, err := tkesdk.GetSignatureKeyFileInfo(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.KeyType != test.keyType || info.SKI != "00112233445566778899"+
+			"aabbccddeeff00112233445566778899aabbccddeeff" {
+			t.Errorf("GetSignatureKeyFileInfo returned %+v", info)
+		}
+	}
+
+	for i, bad := range []string{"", "0011", ski[:62] + "ZZ"} {
+		path := filepath.Join(dir, "bad"+strconv.Itoa(i)+".sigkey")
+		data, _ := json.Marshal(map[string]string{"ski": bad})
+		ioutil.WriteFile(path, data, 0600)
+		if _, err := tkesdk.GetSignatureKeyFileInfo(path); err == nil {
+			t.Errorf("GetSignatureKeyFileInfo accepted SKI %q", bad)
+		}
+	}
+	if _, err := tkesdk.GetSignatureKeyFileInfo(
+		filepath.Join(dir, "missing")); err == nil {
+		t.Error("GetSignatureKeyFileInfo accepted a missing file")
+	}
+}
+
+/** Version 1 files can be used, and are rewritten as version 2 files */
+func TestMigrateSignatureKeyFile(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	fields, err := common.EncryptSignatureKey(key, "password1",
+		common.SIGNATURE_KEY_FILE_VERSION_1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields["comment"] = "admin1 key"
+	path := filepath.Join(dir, "admin1.sigkey")
+	err = common.WriteSignatureKeyFile(path, fields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ski := fields["ski"]
+
+	// Version 1 files can be inspected and used without being migrated
+	info, err := tkesdk.GetSignatureKeyFileInfo(path)
+	if err != nil || info.SKI != ski {
+		t.Errorf("GetSignatureKeyFileInfo returned %+v, %v", info, err)
+	}
+	_, err = tkesdk.CreateAdminCertFromFile(path, ski, "password1", "admin1")
+	if err != nil {
+		t.Errorf("CreateAdminCertFromFile for a version 1 file: %v", err)
+	}
+
+	before, _ := ioutil.ReadFile(path)
+	migrated, err := tkesdk.MigrateSignatureKeyFile(path, "wrong")
+	if err == nil || migrated {
+		t.Fatal("The file was migrated using the wrong password")
+	}
+	after, _ := ioutil.ReadFile(path)
+	if string(before) != string(after) {
+		t.Error("The file was changed using the wrong password")
+	}
+
+	migrated, err = tkesdk.MigrateSignatureKeyFile(path, "password1")
+	if err != nil || !migrated {
+		t.Fatalf("MigrateSignatureKeyFile returned %v, %v", migrated, err)
+	}
+	fields = readKeyFileFields(t, path)
+	if fields["version"] != "2" || fields["kdf"] != "scrypt" ||
+		fields["ski"] != ski || fields["comment"] != "admin1 key" {
+		t.Errorf("Unexpected fields after migration: %v", fields)
+	}
+	signer, err := common.NewKeyFileSigner(path, "password1")
+	if err != nil || hex.EncodeToString(signer.SKI()) != ski {
+		t.Errorf("The migrated key could not be used: %v", err)
+	}
+
+	migrated, err = tkesdk.MigrateSignatureKeyFile(path, "password1")
+	if err != nil || migrated {
+		t.Errorf("A version 2 file was migrated again: %v, %v", migrated, err)
+	}
+}
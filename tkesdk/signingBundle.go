@@ -0,0 +1,350 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Return the output of each command submitted
+// 10/18/2026    CLH             Add PlanUpdate
+// 10/18/2026    CLH             Plan from administrator certificates only
+// 10/18/2026    CLH             Close the signer after signing a bundle file
+// 10/18/2026    CLH             Use the M policy for the key part permission
+
+package tkesdk
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+)
+
+/*----------------------------------------------------------------------------*/
+/* Returns the crypto units assigned to a service instance, for use in the    */
+/* PlannedCommands of a signing bundle.                                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.                                                            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []common.DomainEntry -- describes the crypto units assigned to the         */
+/*     service instance                                                       */
+/* error -- reports any error found during processing                         */
+/*----------------------------------------------------------------------------*/
+func GetDomainsWithContext(ctx context.Context,
+	ci CommonInputs) ([]common.DomainEntry, error) {
+
+	tr, err := getTransport(ci)
+	if err != nil {
+		return nil, err
+	}
+	return getDomains(ctx, tr, ci.InstanceId, ci.Parallelism)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as GetDomainsWithContext, using the background context                */
+/*----------------------------------------------------------------------------*/
+func GetDomains(ci CommonInputs) ([]common.DomainEntry, error) {
+	return GetDomainsWithContext(context.Background(), ci)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Prepares a signing bundle holding unsigned administrative commands for the */
+/* crypto units of a service instance.  See                                   */
+/* ep11cmds.PrepareSigningBundleWithContext.                                  */
+/*----------------------------------------------------------------------------*/
+func PrepareSigningBundleWithContext(ctx context.Context, ci CommonInputs,
+	commands []ep11cmds.PlannedCommand) (*ep11cmds.SigningBundle, error) {
+
+	tr, err := getTransport(ci)
+	if err != nil {
+		return nil, err
+	}
+	return ep11cmds.PrepareSigningBundleWithContext(ctx, tr, commands)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as PrepareSigningBundleWithContext, using the background context      */
+/*----------------------------------------------------------------------------*/
+func PrepareSigningBundle(ci CommonInputs,
+	commands []ep11cmds.PlannedCommand) (*ep11cmds.SigningBundle, error) {
+
+	return PrepareSigningBundleWithContext(context.Background(), ci, commands)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Plans the administrative commands Update would send to change the          */
+/* administrators and signature thresholds of the crypto units in a service   */
+/* instance, for use in a signing bundle.                                     */
+/*                                                                            */
+/* The plan is made from the administrator certificates in                    */
+/* AdminInfo.Certificate, so no signature keys are accessed, and only queries */
+/* are sent to the crypto units.  The same checks are made as by Update,      */
+/* except that the signature keys are not tried.  Each PlannedCommand needs   */
+/* the number of signatures Update would collect for it, from administrators  */
+/* installed when it is submitted.  Master key registers are not set, since   */
+/* the input to those commands depends on the output of earlier ones; run     */
+/* Update once the signing bundle has been submitted.                         */
+/*                                                                            */
+/* Update zeroizes crypto units in imprint mode before it adds                */
+/* administrators.  A zeroize gives the domain a new identifier, which the    */
+/* commands after it must be signed for, so they cannot be planned at the     */
+/* same time.  If a crypto unit in imprint mode holds administrators or       */
+/* master keys, the plan holds only the commands that zeroize those crypto    */
+/* units.  Submit the signing bundle and call PlanUpdate again.               */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units                                              */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.                                                            */
+/* HsmConfig -- the desired final configuration, as for Update.  Only the     */
+/*      Name and Certificate fields of the administrators are used.           */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* []ep11cmds.PlannedCommand -- the commands, in the order to submit them     */
+/* []string -- set of messages identifying either an invalid input or a       */
+/*      reason the transition from initial state to desired final state is    */
+/*      not possible                                                          */
+/* error -- identifies any error encountered when running the function        */
+/*----------------------------------------------------------------------------*/
+func PlanUpdateWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig) ([]ep11cmds.PlannedCommand, []string, error) {
+
+	// Check inputs in the resource block
+	problems := checkConfig(hc)
+	finalSKIs, adminNameMap, keyTypes, certMap, certProblems :=
+		getAdminCertificates(hc)
+	problems = append(problems, certProblems...)
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	// Read the initial configuration and check for invalid transitions
+	st, problems, err := checkUpdateTransition(ctx, ci, hc, finalSKIs,
+		adminNameMap)
+	if err != nil || len(problems) > 0 {
+		return nil, problems, err
+	}
+	problems, err = checkDilithiumSupport(ctx, st.tr, st.domains, st.addSKIs,
+		keyTypes, adminNameMap)
+	if err != nil || len(problems) > 0 {
+		return nil, problems, err
+	}
+
+	// Zeroize crypto units in imprint mode that hold anything a zeroize
+	// would remove.  Nothing else can be planned for them until then.
+	zeroizes := make([]ep11cmds.PlannedCommand, 0)
+	for i, hsm := range st.hsminfo {
+		if hsm.SignatureThreshold == 0 && (len(hsm.Admins) > 0 ||
+			hsm.CurrentMKStatus != "Empty" || hsm.NewMKStatus != "Empty") {
+
+			zeroizes = append(zeroizes, ep11cmds.PlannedCommand{
+				Domain:           st.domains[i],
+				CmdID:            ep11cmds.XCP_ADM_DOM_ZEROIZE,
+				Description:      "Zeroize crypto unit " + hsm.HsmLocation,
+				SignaturesNeeded: 0,
+			})
+		}
+	}
+	if len(zeroizes) > 0 {
+		return zeroizes, make([]string, 0), nil
+	}
+
+	st.certMap = certMap
+	planner := &planningAdminUpdater{ctx: ctx, tr: st.tr,
+		adminNameMap: st.adminNameMap,
+		mPolicy:      keyPartsRequired(hc),
+		commands:     make([]ep11cmds.PlannedCommand, 0)}
+	err = updateAdministrators(hc, st, planner)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+	return planner.commands, make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as PlanUpdateWithContext, using the background context                */
+/*----------------------------------------------------------------------------*/
+func PlanUpdate(ci CommonInputs,
+	hc HsmConfig) ([]ep11cmds.PlannedCommand, []string, error) {
+
+	return PlanUpdateWithContext(context.Background(), ci, hc)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the administrator certificates in HsmConfig, for PlanUpdate.         */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* map[string]bool -- set of the Subject Key Identifiers of the               */
+/*      administrators                                                        */
+/* map[string]string -- maps SKI --> administrator name                       */
+/* map[string]string -- maps SKI --> key type of the signature key            */
+/* map[string][]byte -- maps SKI --> administrator certificate                */
+/* []string -- messages identifying missing or invalid certificates           */
+/*----------------------------------------------------------------------------*/
+func getAdminCertificates(hc HsmConfig) (map[string]bool, map[string]string,
+	map[string]string, map[string][]byte, []string) {
+
+	finalSKIs := make(map[string]bool)
+	adminNameMap := make(map[string]string)
+	keyTypes := make(map[string]string)
+	certMap := make(map[string][]byte)
+	problems := make([]string, 0)
+
+	for _, admin := range hc.Admins {
+		if len(admin.Certificate) == 0 {
+			problems = append(problems, "No administrator certificate is "+
+				"specified for "+admin.Name+".")
+			continue
+		}
+		skibytes, certName, keyType, err :=
+			ep11cmds.ParseAdminCert(admin.Certificate)
+		if err != nil {
+			problems = append(problems, "The administrator certificate for "+
+				admin.Name+" cannot be used.  "+err.Error())
+			continue
+		}
+		if certName != strings.TrimRight(admin.Name, " ") {
+			problems = append(problems, "The administrator certificate for "+
+				admin.Name+" was created for administrator "+certName+".")
+			continue
+		}
+		ski := hex.EncodeToString(skibytes)
+		if finalSKIs[ski] {
+			problems = append(problems, "Signature keys are not unique.  "+
+				"The same signature key is specified for more than one "+
+				"administrator.")
+			continue
+		}
+		finalSKIs[ski] = true
+		adminNameMap[ski] = admin.Name
+		keyTypes[ski] = keyType
+		certMap[ski] = admin.Certificate
+	}
+	return finalSKIs, adminNameMap, keyTypes, certMap, problems
+}
+
+/** Lists the commands that change the administrators, for PlanUpdate */
+type planningAdminUpdater struct {
+	ctx          context.Context
+	tr           common.Transport
+	adminNameMap map[string]string
+	mPolicy      int // M policy for importing master keys
+	commands     []ep11cmds.PlannedCommand
+}
+
+func (p *planningAdminUpdater) removeAdmin(domain common.DomainEntry,
+	ski string, signers signerSet) error {
+
+	skibytes, err := hex.DecodeString(ski)
+	if err != nil {
+		return err
+	}
+	p.commands = append(p.commands, ep11cmds.PlannedCommand{
+		Domain:           domain,
+		CmdID:            ep11cmds.XCP_ADM_DOM_ADMIN_LOGOUT,
+		CmdInput:         skibytes,
+		Description:      "Remove administrator " + ski,
+		SignaturesNeeded: signers.needed,
+	})
+	return nil
+}
+
+func (p *planningAdminUpdater) addAdmin(domain common.DomainEntry,
+	ski string, cert []byte, signers signerSet) error {
+
+	p.commands = append(p.commands, ep11cmds.PlannedCommand{
+		Domain:           domain,
+		CmdID:            ep11cmds.XCP_ADM_DOM_ADMIN_LOGIN,
+		CmdInput:         cert,
+		Description:      "Add administrator " + p.adminNameMap[ski],
+		SignaturesNeeded: signers.needed,
+	})
+	return nil
+}
+
+func (p *planningAdminUpdater) setThresholds(domain common.DomainEntry,
+	newSigThr int, newRevThr int, signers signerSet) error {
+
+	domainAttributes, err := updatedDomainAttributes(p.ctx, p.tr, domain,
+		newSigThr, newRevThr, p.mPolicy)
+	if err != nil {
+		return err
+	}
+	p.commands = append(p.commands, ep11cmds.PlannedCommand{
+		Domain:   domain,
+		CmdID:    ep11cmds.XCP_ADM_DOM_SET_ATTR,
+		CmdInput: ep11cmds.SetDomainAttributesCmdInput(domainAttributes),
+		Description: "Set signature threshold to " + strconv.Itoa(newSigThr) +
+			" and revocation signature threshold to " +
+			strconv.Itoa(newRevThr),
+		SignaturesNeeded: signers.needed,
+	})
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signs the commands in a signing bundle file using a signature key, and     */
+/* saves the signatures in the file.  Used on the offline workstation holding */
+/* the signature key.                                                         */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* path -- the signing bundle file                                            */
+/* sigkey -- identifies the signature key, as in AdminInfo.Key                */
+/* sigkeyToken -- authentication token for the signature key                  */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- reports any error reading the file or signing the commands        */
+/*----------------------------------------------------------------------------*/
+func SignSigningBundleFile(path string, sigkey string,
+	sigkeyToken string) error {
+
+	bundle, err := ep11cmds.LoadSigningBundle(path)
+	if err != nil {
+		return err
+	}
+	signer, err := common.NewSigner(sigkey, sigkeyToken)
+	if err != nil {
+		return err
+	}
+	defer common.CloseSigners([]common.Signer{signer})
+	err = bundle.Sign(signer)
+	if err != nil {
+		return err
+	}
+	return bundle.Save(path)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Submits the signed commands in a signing bundle to the crypto units of a   */
+/* service instance, and returns the command output of each command.  See     */
+/* ep11cmds.SubmitSigningBundleWithContext.                                   */
+/*----------------------------------------------------------------------------*/
+func SubmitSigningBundleWithContext(ctx context.Context, ci CommonInputs,
+	bundle *ep11cmds.SigningBundle) ([][]byte, error) {
+
+	tr, err := getTransport(ci)
+	if err != nil {
+		return nil, err
+	}
+	return ep11cmds.SubmitSigningBundleWithContext(ctx, tr, bundle)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SubmitSigningBundleWithContext, using the background context       */
+/*----------------------------------------------------------------------------*/
+func SubmitSigningBundle(ci CommonInputs,
+	bundle *ep11cmds.SigningBundle) ([][]byte, error) {
+
+	return SubmitSigningBundleWithContext(context.Background(), ci, bundle)
+}
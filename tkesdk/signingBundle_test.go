@@ -0,0 +1,475 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Plan from administrator certificates only
+// 10/18/2026    CLH             Test a lost response to a bundle command
+
+package tkesdk_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/** Returns a temporary signing bundle file and a function to remove it */
+func tempBundleFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "bundle.json"), func() { os.RemoveAll(dir) }
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns a copy of an HsmConfig whose administrators have only a name and   */
+/* an administrator certificate, as used by PlanUpdate                        */
+/*----------------------------------------------------------------------------*/
+func certificateConfig(t *testing.T, hc tkesdk.HsmConfig) tkesdk.HsmConfig {
+	admins := make([]tkesdk.AdminInfo, len(hc.Admins))
+	for i, admin := range hc.Admins {
+		cert, err := ep11cmds.CreateAdminCert(admin.Signer, admin.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		admins[i] = tkesdk.AdminInfo{Name: admin.Name, Certificate: cert}
+	}
+	hc.Admins = admins
+	return hc
+}
+
+/** Transport that fails the test if a command other than a query is sent */
+type queryOnlyTransport struct {
+	common.Transport
+	t *testing.T
+}
+
+func (q *queryOnlyTransport) SubmitHTPRequest(ctx context.Context,
+	cryptoInstance string, hsmId string, htpRequest string) (string, error) {
+
+	// Query command identifiers begin 0x0001
+	cmdID := adminCommandID(htpRequest)
+	if len(cmdID) == 4 && cmdID[1] != 0x01 {
+		q.t.Errorf("Command %X was sent", cmdID)
+	}
+	return q.Transport.SubmitHTPRequest(ctx, cryptoInstance, hsmId,
+		htpRequest)
+}
+
+/** Returns CommonInputs that only allow queries to be sent */
+func queryOnlyInputs(t *testing.T, ci tkesdk.CommonInputs) tkesdk.CommonInputs {
+	ci.Transport = &queryOnlyTransport{Transport: ci.Transport, t: t}
+	return ci
+}
+
+/*----------------------------------------------------------------------------*/
+/* The administrator changes Update would make can be planned from            */
+/* administrator certificates, signed offline, and submitted, leaving the     */
+/* crypto units as Update does.                                               */
+/*----------------------------------------------------------------------------*/
+func TestPlanUpdateSigningBundle(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	planConfig := certificateConfig(t, hc)
+
+	// Only queries are sent while planning
+	plan, problems, err := tkesdk.PlanUpdate(queryOnlyInputs(t, ci),
+		planConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("PlanUpdate reported problems: %v", problems)
+	}
+	// Each crypto unit is in imprint mode, so three administrators are added
+	// without signatures and two of them sign the new thresholds
+	if len(plan) != 4*len(defaultTestUnits) {
+		t.Fatalf("PlanUpdate returned %d commands", len(plan))
+	}
+	for i, command := range plan {
+		expected := 0
+		if i%4 == 3 {
+			expected = 2
+		}
+		if command.SignaturesNeeded != expected {
+			t.Errorf("Command %d (%s) needs %d signatures, expected %d",
+				i+1, command.Description, command.SignaturesNeeded, expected)
+		}
+	}
+
+	bundle, err := tkesdk.PrepareSigningBundle(ci, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, cleanup := tempBundleFile(t)
+	defer cleanup()
+	err = bundle.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign on the "offline workstations"
+	for _, admin := range hc.Admins[0:2] {
+		bundle, err := ep11cmds.LoadSigningBundle(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = bundle.Sign(admin.Signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = bundle.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundle, err = ep11cmds.LoadSigningBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := tkesdk.SubmitSigningBundle(ci, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != len(plan) {
+		t.Errorf("SubmitSigningBundle returned %d outputs for %d commands",
+			len(outputs), len(plan))
+	}
+	for i, command := range bundle.Commands {
+		if !command.Submitted {
+			t.Errorf("Command %d was not marked as submitted", i+1)
+		}
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if len(hsm.Admins) != 3 || hsm.SignatureThreshold != 2 ||
+			hsm.RevocationThreshold != 2 {
+			t.Errorf("Crypto unit %s has %d administrators and thresholds "+
+				"%d and %d", hsm.HsmLocation, len(hsm.Admins),
+				hsm.SignatureThreshold, hsm.RevocationThreshold)
+		}
+	}
+
+	// No administrators remain to be added, so only the thresholds are set
+	// again, and Update sets the master key registers
+	plan, _, err = tkesdk.PlanUpdate(queryOnlyInputs(t, ci), planConfig)
+	if err != nil || len(plan) != len(defaultTestUnits) {
+		t.Errorf("PlanUpdate returned %d commands and %v after the bundle "+
+			"was submitted", len(plan), err)
+	}
+	for _, command := range plan {
+		if !common.ByteSlicesAreEqual(command.CmdID,
+			ep11cmds.XCP_ADM_DOM_SET_ATTR) {
+			t.Errorf("Unexpected command: %s", command.Description)
+		}
+	}
+	mustUpdate(t, ci, hc)
+	hsminfo := mustQuery(t, ci)
+	for _, hsm := range hsminfo {
+		if hsm.CurrentMKStatus != "Valid" ||
+			!sameVP(hsm.CurrentMKVP, hsminfo[0].CurrentMKVP) {
+			t.Errorf("Crypto unit %s has master key %s %s", hsm.HsmLocation,
+				hsm.CurrentMKStatus, hsm.CurrentMKVP)
+		}
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Crypto units in imprint mode that hold administrators are zeroized by a    */
+/* signing bundle of their own before their administrators are planned        */
+/*----------------------------------------------------------------------------*/
+func TestPlanUpdateZeroize(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	planConfig := certificateConfig(t, hc)
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Imprint mode administrators need no signatures to be added
+	for _, domain := range domains {
+		err = ep11cmds.AddDomainAdminWithTransport(em, domain,
+			planConfig.Admins[0].Certificate, []common.Signer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, problems, err := tkesdk.PlanUpdate(queryOnlyInputs(t, ci),
+		planConfig)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("PlanUpdate returned %v %v", problems, err)
+	}
+	if len(plan) != len(domains) {
+		t.Fatalf("PlanUpdate returned %d commands", len(plan))
+	}
+	for _, command := range plan {
+		if !common.ByteSlicesAreEqual(command.CmdID,
+			ep11cmds.XCP_ADM_DOM_ZEROIZE) || command.SignaturesNeeded != 0 {
+			t.Errorf("Unexpected command: %s", command.Description)
+		}
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if len(hsm.Admins) != 1 {
+			t.Fatalf("PlanUpdate changed crypto unit %s", hsm.HsmLocation)
+		}
+	}
+
+	// The zeroize needs no signatures
+	bundle, err := tkesdk.PrepareSigningBundle(ci, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tkesdk.SubmitSigningBundle(ci, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hsm := range mustQuery(t, ci) {
+		if len(hsm.Admins) != 0 {
+			t.Errorf("Crypto unit %s was not zeroized", hsm.HsmLocation)
+		}
+	}
+
+	// The administrators can then be planned
+	plan, problems, err = tkesdk.PlanUpdate(queryOnlyInputs(t, ci),
+		planConfig)
+	if err != nil || len(problems) > 0 ||
+		len(plan) != 4*len(defaultTestUnits) {
+		t.Errorf("PlanUpdate returned %d commands, %v %v after the zeroize",
+			len(plan), problems, err)
+	}
+}
+
+/** Administrators without a usable certificate are reported by PlanUpdate */
+func TestPlanUpdateCertificateProblems(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[0:1])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	planConfig := certificateConfig(t, hc)
+
+	missing := certificateConfig(t, hc)
+	missing.Admins[1].Certificate = nil
+	invalid := certificateConfig(t, hc)
+	invalid.Admins[1].Certificate = []byte{0x30, 0x00}
+	renamed := certificateConfig(t, hc)
+	renamed.Admins[1].Name = "someone else"
+	duplicate := certificateConfig(t, hc)
+	duplicate.Admins[1].Certificate = planConfig.Admins[0].Certificate
+	duplicate.Admins[1].Name = planConfig.Admins[0].Name
+	duplicate.SignerPreference = nil
+
+	for _, config := range []tkesdk.HsmConfig{missing, invalid, renamed,
+		duplicate} {
+		plan, problems, err := tkesdk.PlanUpdate(queryOnlyInputs(t, ci),
+			config)
+		if err != nil || len(problems) != 1 || plan != nil {
+			t.Errorf("PlanUpdate returned %d commands, %v %v", len(plan),
+				problems, err)
+		}
+	}
+}
+
+/** The command output returned by each command is saved and returned */
+func TestSubmitSigningBundleOutputs(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[0:1])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := tkesdk.PrepareSigningBundle(ci, []ep11cmds.PlannedCommand{
+		{Domain: domains[0], CmdID: ep11cmds.XCP_ADM_GEN_IMPORTER,
+			CmdInput: common.Uint32To4ByteSlice(
+				ep11cmds.XCP_IMPRKEY_EC_P521),
+			Description: "Generate importer key", SignaturesNeeded: 1},
+		{Domain: domains[0], CmdID: ep11cmds.XCP_ADM_CLEAR_NEXT_WK,
+			Description:      "Clear new master key register",
+			SignaturesNeeded: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bundle.Sign(hc.Admins[2].Signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := tkesdk.SubmitSigningBundle(ci, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 2 || len(outputs[0]) == 0 || outputs[0][0] != 0x30 {
+		t.Fatalf("Unexpected outputs %X", outputs)
+	}
+	if outputs[1] != nil {
+		t.Errorf("Clearing a register returned output %X", outputs[1])
+	}
+	if bundle.Commands[0].Output != hex.EncodeToString(outputs[0]) {
+		t.Error("The output was not saved in the signing bundle")
+	}
+
+	// Submitting again sends nothing and returns the saved outputs
+	again, err := tkesdk.SubmitSigningBundle(ci, bundle)
+	if err != nil || len(again) != 2 ||
+		hex.EncodeToString(again[0]) != bundle.Commands[0].Output {
+		t.Errorf("Submitting again returned %X, %v", again, err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Commands without enough signatures are not sent, and commands whose        */
+/* transaction counter is stale are reported                                  */
+/*----------------------------------------------------------------------------*/
+func TestSubmitSigningBundleErrors(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[0:1])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, _, err := ep11cmds.QueryDomainAttributesWithTransport(em, domains[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.SignatureThreshold = 3
+	bundle, err := tkesdk.PrepareSigningBundle(ci, []ep11cmds.PlannedCommand{
+		{Domain: domains[0], CmdID: ep11cmds.XCP_ADM_DOM_SET_ATTR,
+			CmdInput:    ep11cmds.SetDomainAttributesCmdInput(attrs),
+			Description: "Set signature threshold to 3", SignaturesNeeded: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One signature is not enough
+	err = bundle.Sign(hc.Admins[0].Signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tkesdk.SubmitSigningBundle(ci, bundle)
+	if err == nil || bundle.Commands[0].Submitted {
+		t.Fatal("A command was submitted with one of two signatures")
+	}
+
+	// SignerInfo from another tool can be added once
+	adminBlock, _ := hex.DecodeString(bundle.Commands[0].AdminBlock)
+	signerInfo, err := ep11cmds.CreateSignerInfoWithSigners(adminBlock,
+		[]common.Signer{hc.Admins[1].Signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bundle.AddSignerInfo(0, signerInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bundle.AddSignerInfo(0, signerInfo)
+	if err == nil {
+		t.Error("AddSignerInfo accepted a second signature from one key")
+	}
+
+	// Another command sent to the domain makes the bundle stale
+	err = ep11cmds.ClearPendingWKWithTransport(em, domains[0],
+		[]common.Signer{hc.Admins[2].Signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tkesdk.SubmitSigningBundle(ci, bundle)
+	var stale *ep11cmds.StaleTransactionCounterError
+	if !errors.As(err, &stale) || stale.Index != 0 {
+		t.Fatalf("SubmitSigningBundle returned %v for a stale bundle", err)
+	}
+	if mustQuery(t, ci)[0].SignatureThreshold != 2 {
+		t.Error("The stale command changed the signature threshold")
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* A command whose response is lost is marked as such rather than submitted,  */
+/* and the bundle is not submitted, signed, or given signatures again         */
+/*----------------------------------------------------------------------------*/
+func TestSubmitSigningBundleResponseLost(t *testing.T) {
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[0:1])
+	defer em.Close()
+	hc := newTestHsmConfig(t)
+	mustUpdate(t, ci, hc)
+	domains, err := tkesdk.GetDomains(ci)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, _, err := ep11cmds.QueryDomainAttributesWithTransport(em, domains[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.SignatureThreshold = 3
+	raised := ep11cmds.SetDomainAttributesCmdInput(attrs)
+	attrs.SignatureThreshold = 2
+	lowered := ep11cmds.SetDomainAttributesCmdInput(attrs)
+	bundle, err := tkesdk.PrepareSigningBundle(ci, []ep11cmds.PlannedCommand{
+		{Domain: domains[0], CmdID: ep11cmds.XCP_ADM_DOM_SET_ATTR,
+			CmdInput:    raised,
+			Description: "Set signature threshold to 3", SignaturesNeeded: 2},
+		{Domain: domains[0], CmdID: ep11cmds.XCP_ADM_DOM_SET_ATTR,
+			CmdInput:    lowered,
+			Description: "Set signature threshold to 2", SignaturesNeeded: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = bundle.Sign(hc.Admins[i].Signer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	losingCI, losing := losingInputs(ci, ep11cmds.XCP_ADM_DOM_SET_ATTR)
+	_, err = tkesdk.SubmitSigningBundle(losingCI, bundle)
+	if err != ep11cmds.ErrResponseLost || !losing.dropped {
+		t.Fatalf("SubmitSigningBundle returned %v for a lost response", err)
+	}
+	if !bundle.Commands[0].ResponseLost || bundle.Commands[0].Submitted {
+		t.Error("The command whose response was lost is marked as submitted")
+	}
+	if bundle.Commands[1].Submitted || bundle.Commands[1].ResponseLost {
+		t.Error("The command after the lost response was sent")
+	}
+
+	// The outcome of the first command is unknown, so nothing more is sent
+	_, err = tkesdk.SubmitSigningBundle(ci, bundle)
+	if err == nil || bundle.Commands[1].Submitted {
+		t.Error("A bundle with a lost response was submitted again")
+	}
+	adminBlock, _ := hex.DecodeString(bundle.Commands[0].AdminBlock)
+	signerInfo, err := ep11cmds.CreateSignerInfoWithSigners(adminBlock,
+		[]common.Signer{hc.Admins[2].Signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.AddSignerInfo(0, signerInfo) == nil {
+		t.Error("AddSignerInfo accepted a command whose response was lost")
+	}
+	if mustQuery(t, ci)[0].SignatureThreshold != 3 {
+		t.Error("The command whose response was lost did not take effect")
+	}
+}
@@ -7,21 +7,29 @@
 //
 // Date          Initials        Description
 // 04/30/2021    CLH             Initial version
+// 10/18/2026    CLH             Get signing service URL from common
+// 10/18/2026    CLH             Use common.Signer
+// 10/18/2026    CLH             Support signature keys in PKCS #11 tokens
+// 10/18/2026    CLH             Support signing service protocol version 2
+// 10/18/2026    CLH             Use GetSignatureKeyFileInfo
+// 10/18/2026    CLH             Resolve key URIs using registered schemes
+// 10/18/2026    CLH             Create signers with a given HTTP client
+// 10/18/2026    CLH             Reject signers from SignerQuorum.Signers
+// 10/18/2026    CLH             Keep GetSignatureKeysFromResourceBlock and add
+//                               GetSignersFromResourceBlock
+// 10/18/2026    CLH             Create signers once for each operation and
+//                               close them afterwards
 
 package tkesdk
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"math/big"
-	"os"
-	"strconv"
-	"strings"
+	"net/http"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
 )
 
 /** Used to work with an ASN.1 sequence representing an EC public key */
@@ -30,12 +38,49 @@ type ECPublicKey struct {
 	Y *big.Int
 }
 
+/*----------------------------------------------------------------------------*/
+/* Assembles information on the signature keys identified in the Terraform    */
+/* resource block.                                                            */
+/*                                                                            */
+/* Handles signature key files on the local workstation, a user-provided      */
+/* signing service, and signers supplied in AdminInfo.Signer.                 */
+/*                                                                            */
+/* Signers created from the Key and Token fields for keys in PKCS #11 tokens  */
+/* keep a session open with the token.  Close them using common.CloseSigners  */
+/* when they are no longer needed.  Signers supplied in AdminInfo.Signer are  */
+/* returned as they are.                                                      */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* HsmConfig -- A structure containing information from the hsm_config        */
+/*     section of the resource block for the HPCS service instance.  This     */
+/*     provides access to the signature keys for signing commands.            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* map[string]bool -- set of the Subject Key Identifiers for the signature    */
+/*     keys identified in the resource block.  maps SKI --> true.             */
+/* map[string]common.Signer -- maps SKI --> signer for the signature key      */
+/* map[string]string -- maps SKI --> administrator name                       */
+/* error -- reports any error during processing                               */
+/*----------------------------------------------------------------------------*/
+func GetSignersFromResourceBlock(hc HsmConfig) (map[string]bool,
+	map[string]common.Signer, map[string]string, error) {
+
+	admins := newAdminSigners(nil, hc.Admins)
+	suppliedSKIs, signerMap, adminNameMap, err := getSignatureKeys(admins)
+	if err != nil {
+		admins.close()
+	}
+	return suppliedSKIs, signerMap, adminNameMap, err
+}
+
 /*----------------------------------------------------------------------------*/
 /* Assembles information on the signature keys identified in the Terraform    */
 /* resource block.                                                            */
 /*                                                                            */
 /* Handles both signature key files on the local workstation and a            */
-/* user-provided signing service.                                             */
+/* user-provided signing service.  Administrators with a signer in            */
+/* AdminInfo.Signer have no signature key or token, and are mapped to empty   */
+/* strings; use GetSignersFromResourceBlock for them.                         */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* HsmConfig -- A structure containing information from the hsm_config        */
@@ -64,7 +109,13 @@ func GetSignatureKeysFromResourceBlock(hc HsmConfig) (map[string]bool,
 	adminNameMap := make(map[string]string)
 
 	for i := 0; i < len(hc.Admins); i++ {
-		ski, err := GetSigKeySKI(hc.Admins[i].Key, hc.Admins[i].Token)
+		var ski string
+		var err error
+		if hc.Admins[i].Signer != nil {
+			ski = hex.EncodeToString(hc.Admins[i].Signer.SKI())
+		} else {
+			ski, err = GetSigKeySKI(hc.Admins[i].Key, hc.Admins[i].Token)
+		}
 		if err != nil {
 			return suppliedSKIs, sigKeyMap, sigKeyTokenMap, adminNameMap, err
 		}
@@ -81,10 +132,106 @@ func GetSignatureKeysFromResourceBlock(hc HsmConfig) (map[string]bool,
 }
 
 /*----------------------------------------------------------------------------*/
-/* Returns the Subject Key Identifier (SKI) for a signature key.  Checks an   */
-/* environment variable to determine whether a signing service should be used */
-/* or whether the signature key is in a signature key file on the local       */
-/* workstation.                                                               */
+/* The signers for the administrators in the resource block.  A tkesdk        */
+/* function creates them once, passes them to the functions that need them,   */
+/* and closes them before it returns.  Signers supplied in AdminInfo.Signer   */
+/* belong to the caller and are not closed.                                   */
+/*----------------------------------------------------------------------------*/
+type adminSigners struct {
+	admins  []AdminInfo
+	signers []common.Signer // signer for each administrator, nil on error
+	errs    []error         // error creating the signer for each administrator
+	created []common.Signer // signers created from the Key and Token fields
+}
+
+/*----------------------------------------------------------------------------*/
+/* Creates the signers for the administrators in the resource block.  Signers */
+/* for signing services and Vault send their requests using httpClient, or    */
+/* the default HTTP client if httpClient is nil.  Errors are kept for each    */
+/* administrator, so that checkInputs can report every key that cannot be     */
+/* accessed.                                                                  */
+/*----------------------------------------------------------------------------*/
+func newAdminSigners(httpClient *http.Client,
+	admins []AdminInfo) *adminSigners {
+
+	a := &adminSigners{admins: admins,
+		signers: make([]common.Signer, len(admins)),
+		errs:    make([]error, len(admins))}
+	for i, admin := range admins {
+		signer, err := getSigner(httpClient, admin)
+		if err != nil {
+			a.errs[i] = err
+			continue
+		}
+		a.signers[i] = signer
+		if admin.Signer == nil {
+			a.created = append(a.created, signer)
+		}
+	}
+	return a
+}
+
+/** Closes the signers created from the Key and Token fields */
+func (a *adminSigners) close() {
+	common.CloseSigners(a.created)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as GetSignersFromResourceBlock, using signers that were already       */
+/* created                                                                    */
+/*----------------------------------------------------------------------------*/
+func getSignatureKeys(admins *adminSigners) (map[string]bool,
+	map[string]common.Signer, map[string]string, error) {
+
+	// Set of Subject Key Identifiers
+	suppliedSKIs := make(map[string]bool)
+		// Use a map to check if a signature key is specified more than once
+	// Maps SKIs to signers
+	signerMap := make(map[string]common.Signer)
+	// Maps SKIs to administrator name
+	adminNameMap := make(map[string]string)
+
+	for i, signer := range admins.signers {
+		if admins.errs[i] != nil {
+			return suppliedSKIs, signerMap, adminNameMap, admins.errs[i]
+		}
+		ski := hex.EncodeToString(signer.SKI())
+		if suppliedSKIs[ski] {
+			return suppliedSKIs, signerMap, adminNameMap,
+				errors.New("A signature key has been specified more than once in the resource block")
+		}
+		suppliedSKIs[ski] = true
+		signerMap[ski] = signer
+		adminNameMap[ski] = admins.admins[i].Name
+	}
+	return suppliedSKIs, signerMap, adminNameMap, nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the signer for an administrator.  Uses AdminInfo.Signer if it is   */
+/* set, otherwise creates a signer from the Key and Token fields.  Signers    */
+/* returned by ep11cmds.SignerQuorum.Signers have no signature key of their   */
+/* own, and are rejected.                                                     */
+/*----------------------------------------------------------------------------*/
+func getSigner(httpClient *http.Client, ai AdminInfo) (common.Signer,
+	error) {
+
+	if ai.Signer != nil {
+		if ep11cmds.IsQuorumSigner(ai.Signer) {
+			return nil, errors.New("The signer for administrator " + ai.Name +
+				" was returned by SignerQuorum.Signers and cannot be used " +
+				"as an administrator signature key.")
+		}
+		return ai.Signer, nil
+	}
+	return common.NewSignerWithClient(httpClient, ai.Key, ai.Token)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the Subject Key Identifier (SKI) for a signature key.  Key URIs    */
+/* are resolved using their scheme.  Otherwise checks an environment variable */
+/* to determine whether a signing service should be used or whether the       */
+/* signature key is in a signature key file on the local workstation.         */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* sigkey string -- a string identifying which signature key to access        */
@@ -97,41 +244,63 @@ func GetSignatureKeysFromResourceBlock(hc HsmConfig) (map[string]bool,
 /* error -- reports any error during processing                               */
 /*----------------------------------------------------------------------------*/
 func GetSigKeySKI(sigkey string, sigkeyToken string) (string, error) {
+	return getSigKeySKI(nil, sigkey, sigkeyToken)
+}
 
-	// Check if the environment variable is set indicating a signing service
-	// should be used
-	ssURL := os.Getenv("TKE_SIGNSERV_URL")
+/*----------------------------------------------------------------------------*/
+/* Same as GetSigKeySKI, but requests to a signing service or Vault are sent  */
+/* using httpClient, or the default HTTP client if httpClient is nil          */
+/*----------------------------------------------------------------------------*/
+func getSigKeySKI(httpClient *http.Client, sigkey string,
+	sigkeyToken string) (string, error) {
+
+	// Key files identified by file URIs are read without the password
+	scheme := common.KeyURIScheme(sigkey)
+	if scheme == "file" {
+		path, err := common.ParseFileKeyURI(sigkey)
+		if err != nil {
+			return "", err
+		}
+		info, err := GetSignatureKeyFileInfo(path)
+		if err != nil {
+			return "", err
+		}
+		return info.SKI, nil
+	}
+
+	// Other key URIs, such as PKCS #11 URIs, are resolved using the scheme
+	if scheme != "" {
+		signer, err := common.NewSignerWithClient(httpClient, sigkey,
+			sigkeyToken)
+		if err != nil {
+			return "", err
+		}
+		defer common.CloseSigners([]common.Signer{signer})
+		return hex.EncodeToString(signer.SKI()), nil
+	}
+
+	// Check if a signing service should be used
+	ssURL := common.GetSigningServiceURL()
 	if ssURL != "" {
 
 		// Use the signing service to get the public key
-		pubkey, err := common.GetPublicKeyFromSigningService(
+		signer, err := common.NewSigningServiceSignerWithClient(httpClient,
 			ssURL, sigkey, sigkeyToken)
 		if err != nil {
 			return "", err
 		}
-		hash := sha256.Sum256(pubkey[:])
-		return strings.ToLower(hex.EncodeToString(hash[:])), nil
+		return hex.EncodeToString(signer.SKI()), nil
 
 	} else {
 
 		// When a signing service is not used, assume signature keys are in
 		// files on the local workstation
 
-		// Read the signature key file
-		data, err := ioutil.ReadFile(sigkey)
+		// Read the SKI saved in the signature key file
+		info, err := GetSignatureKeyFileInfo(sigkey)
 		if err != nil {
 			return "", err
 		}
-		var skfields map[string]string
-		err = json.Unmarshal(data, &skfields)
-		if err != nil {
-			return "", err
-		}
-		ski := skfields["ski"]
-		if len(ski) != 64 {
-			return "", errors.New("Invalid Subject Key Identifier, length = " + strconv.Itoa(len(ski)))
-		}
-
-		return strings.ToLower(ski), nil
+		return info.SKI, nil
 	}
 }
@@ -0,0 +1,235 @@
+//
+// Copyright contributors to the ibm-hpcs-tke-sdk project
+// SPDX-License-Identifier: Apache2.0
+//
+
+// CHANGE HISTORY
+//
+// Date          Initials        Description
+// 10/18/2026    CLH             Initial version
+// 10/18/2026    CLH             Check that created signers are closed
+
+package tkesdk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
+	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
+	"github.com/IBM/ibm-hpcs-tke-sdk/tkesdk"
+)
+
+/*----------------------------------------------------------------------------*/
+/* One HsmConfig can use a key file, a key from a registered key URI scheme,  */
+/* and a signer supplied by the application.                                  */
+/*----------------------------------------------------------------------------*/
+func TestMixedSignatureKeyBackends(t *testing.T) {
+	dir, cleanup := tempKeyDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "admin1.sigkey")
+	fileSKI, err := tkesdk.CreateSignatureKeyFile(path, common.KEY_TYPE_P521EC,
+		"password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	appSKI := hex.EncodeToString(common.CalculateECKeyHash(appKey.PublicKey))
+	err = common.RegisterSignerScheme("tkeapp", func(keyURI string,
+		sigkeyToken string) (common.Signer, error) {
+
+		return common.NewPrivateKeySigner(appKey)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.RegisterSignerScheme("tkeapp", nil)
+
+	// The SKI of a file URI key is read without the password
+	ski, err := tkesdk.GetSigKeySKI("file://"+path, "")
+	if err != nil || ski != fileSKI {
+		t.Errorf("GetSigKeySKI for the file URI returned %s, %v", ski, err)
+	}
+	ski, err = tkesdk.GetSigKeySKI("tkeapp:admin2", "token2")
+	if err != nil || ski != appSKI {
+		t.Errorf("GetSigKeySKI for the registered scheme returned %s, %v",
+			ski, err)
+	}
+
+	hc := tkesdk.HsmConfig{SignatureThreshold: 2, RevocationThreshold: 2,
+		MasterKeyParts: 1, MasterKeyPartsRequired: 1,
+		Admins: append([]tkesdk.AdminInfo{
+			{Name: "admin1", Key: "file://" + path, Token: "password1"},
+			{Name: "admin2", Key: "tkeapp:admin2", Token: "token2"},
+		}, newTestAdmins(t, "admin3")...)}
+	skis, _, names, err := tkesdk.GetSignersFromResourceBlock(hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skis[fileSKI] || !skis[appSKI] || len(skis) != 3 ||
+		names[fileSKI] != "admin1" || names[appSKI] != "admin2" {
+		t.Errorf("Unexpected signature keys %v and names %v", skis, names)
+	}
+
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits[0:1])
+	defer em.Close()
+	mustUpdate(t, ci, hc)
+	hsm := mustQuery(t, ci)[0]
+	if len(hsm.Admins) != 3 || hsm.SignatureThreshold != 2 {
+		t.Errorf("Crypto unit has %d administrators and signature threshold "+
+			"%d", len(hsm.Admins), hsm.SignatureThreshold)
+	}
+
+	// The same key named twice is rejected, however it is named
+	hc.Admins[1] = tkesdk.AdminInfo{Name: "admin2", Key: path,
+		Token: "password1"}
+	_, _, _, err = tkesdk.GetSignersFromResourceBlock(hc)
+	if err == nil {
+		t.Error("A key file named by a path and a file URI was accepted twice")
+	}
+}
+
+/** Signers standing for a quorum signature are not administrator keys */
+func TestQuorumSignerAsAdministrator(t *testing.T) {
+	hc := newTestHsmConfig(t)
+	quorum := ep11cmds.NewSignerQuorum([]common.Signer{hc.Admins[0].Signer,
+		hc.Admins[1].Signer})
+	hc.Admins[2].Signer = quorum.Signers(1)[0]
+	_, _, _, err := tkesdk.GetSignersFromResourceBlock(hc)
+	if err == nil || !strings.Contains(err.Error(), "admin3") {
+		t.Errorf("GetSignersFromResourceBlock returned %v", err)
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+/* Signer that records when it is closed, standing in for a signer that keeps */
+/* a session open with a PKCS #11 token                                       */
+/*----------------------------------------------------------------------------*/
+type closingSigner struct {
+	common.Signer
+	counts *signerCounts
+	closed bool
+}
+
+/** Counts the closing signers created and closed */
+type signerCounts struct {
+	mutex   sync.Mutex
+	created int
+	closed  int
+}
+
+func (s *closingSigner) Sign(data []byte) ([]byte, error) {
+	s.counts.mutex.Lock()
+	closed := s.closed
+	s.counts.mutex.Unlock()
+	if closed {
+		return nil, errors.New("The signer is closed")
+	}
+	return s.Signer.Sign(data)
+}
+
+func (s *closingSigner) Close() error {
+	s.counts.mutex.Lock()
+	defer s.counts.mutex.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.counts.closed++
+	}
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Each tkesdk function creates the signer for a signature key once, and      */
+/* closes it before returning.  Signers supplied in AdminInfo.Signer are not  */
+/* closed.                                                                    */
+/*----------------------------------------------------------------------------*/
+func TestSignersClosed(t *testing.T) {
+	keys := make(map[string]*ecdsa.PrivateKey)
+	counts := &signerCounts{}
+	err := common.RegisterSignerScheme("tkeclose", func(keyURI string,
+		sigkeyToken string) (common.Signer, error) {
+
+		signer, err := common.NewPrivateKeySigner(
+			keys[strings.TrimPrefix(keyURI, "tkeclose:")])
+		if err != nil {
+			return nil, err
+		}
+		counts.mutex.Lock()
+		defer counts.mutex.Unlock()
+		counts.created++
+		return &closingSigner{Signer: signer, counts: counts}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.RegisterSignerScheme("tkeclose", nil)
+
+	hc := newTestHsmConfig(t)
+	for _, name := range []string{"admin1", "admin2"} {
+		keys[name], _ = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	}
+	hc.Admins[0] = tkesdk.AdminInfo{Name: "admin1", Key: "tkeclose:admin1"}
+	hc.Admins[1] = tkesdk.AdminInfo{Name: "admin2", Key: "tkeclose:admin2"}
+	em, ci := newTestInstance(t, "instance1", defaultTestUnits)
+	defer em.Close()
+
+	operations := []struct {
+		name string
+		run  func() ([]string, error)
+	}{
+		{"CheckTransition", func() ([]string, error) {
+			return tkesdk.CheckTransition(ci, hc)
+		}},
+		{"Update", func() ([]string, error) {
+			return tkesdk.Update(ci, hc)
+		}},
+		{"RotateMasterKey", func() ([]string, error) {
+			return tkesdk.RotateMasterKey(ci, hc)
+		}},
+		{"FinalizeMasterKeyRotation", func() ([]string, error) {
+			return tkesdk.FinalizeMasterKeyRotation(ci, hc)
+		}},
+		{"Zeroize", func() ([]string, error) {
+			return nil, tkesdk.Zeroize(ci, hc)
+		}},
+	}
+	for _, op := range operations {
+		counts.created, counts.closed = 0, 0
+		problems, err := op.run()
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("%s returned %v %v", op.name, problems, err)
+		}
+		if counts.created != 2 || counts.closed != 2 {
+			t.Errorf("%s created %d signers and closed %d, expected 2",
+				op.name, counts.created, counts.closed)
+		}
+	}
+
+	// Signers returned by GetSignersFromResourceBlock are left open for
+	// the caller
+	counts.created, counts.closed = 0, 0
+	_, signerMap, _, err := tkesdk.GetSignersFromResourceBlock(hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.closed != 0 {
+		t.Error("GetSignersFromResourceBlock closed the signers it returned")
+	}
+	signers := make([]common.Signer, 0)
+	for _, signer := range signerMap {
+		signers = append(signers, signer)
+	}
+	common.CloseSigners(signers)
+	if counts.created != 2 || counts.closed != 2 {
+		t.Errorf("CloseSigners closed %d of %d signers", counts.closed,
+			counts.created)
+	}
+}
@@ -7,12 +7,32 @@
 //
 // Date          Initials        Description
 // 06/21/2021    CLH             Initial version
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Select signature keys in a repeatable order
+// 10/18/2026    CLH             Get signing service URL from common
+// 10/18/2026    CLH             Use common.Signer
+// 10/18/2026    CLH             Check crypto unit support for Dilithium keys
+// 10/18/2026    CLH             Collect signatures concurrently from a quorum
+// 10/18/2026    CLH             Copy the master key in several key parts
+// 10/18/2026    CLH             Add option to leave master key registers empty
+// 10/18/2026    CLH             Report errors from the initial query
+// 10/18/2026    CLH             Create signers with CommonInputs.HTTPClient
+// 10/18/2026    CLH             Share the administrator changes with PlanUpdate
+// 10/18/2026    CLH             Recover the output of commands whose response was lost
+// 10/18/2026    CLH             Choose signature keys when commands are sent
+// 10/18/2026    CLH             Keep the authToken and urlStart variant of
+//                               SetDomainAttributes
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Create signers once and close them afterwards
+// 10/18/2026    CLH             Prohibit single key part imports for an M
+//                               policy of 2 or more
 
 package tkesdk
 
 import (
+	"context"
 	"errors"
-	"os"
+	"sort"
 
 	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
@@ -23,6 +43,10 @@ import (
 /* final configuration.                                                       */
 /*                                                                            */
 /* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
 /* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
 /*      functions.  This includes: the API endpoint and region, the HPCS      */
 /*      service instance id, and an IBM Cloud authentication token.           */
@@ -37,47 +61,243 @@ import (
 /*      not possible                                                          */
 /* error -- identifies any error encountered when running the function        */
 /*----------------------------------------------------------------------------*/
-func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
+func UpdateWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig) ([]string, error) {
 
-	// Check inputs in the resource block
-	problems, err := checkInputs(hc)
+	// Create the signers for the signature keys in the resource block
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+
+	st, problems, err := prepareUpdate(ctx, ci, hc, admins)
 	if err != nil {
 		return make([]string, 0), err
 	}
 	if len(problems) > 0 {
 		return problems, nil
 	}
+	hsminfo, tr, domains := st.hsminfo, st.tr, st.domains
+	suppliedSKIs, signerMap := st.suppliedSKIs, st.signerMap
+	signerOrder := st.signerOrder
 
-	// Read the initial configuration
-	hsminfo, urlStart, domains, err := internalQuery(ci)
+	//--------------------------------------------------------------------------
+	// Update administrators and signature thresholds
+	//--------------------------------------------------------------------------
 
-	// Check for invalid transitions
-	problems, err, keepSKIs, addSKIs, rmvSKIs := internalCheckTransition(ci, hc, hsminfo)
+	err = updateAdministrators(hc, st,
+		&sendingAdminUpdater{ctx: ctx, tr: tr, signerOrder: signerOrder,
+			signerMap: signerMap, mPolicy: keyPartsRequired(hc)})
 	if err != nil {
 		return make([]string, 0), err
 	}
+
+	//--------------------------------------------------------------------------
+	// Update the current master key registers
+	//--------------------------------------------------------------------------
+
+	// Two cases are handled:
+	// 1. All current master key registers are initially empty.
+	// 2. The current master key register in at least one recovery crypto unit
+	//    is set, and all other crypto units either have the same master key
+	//    value or they are empty.
+	//
+	// We do not attempt to handle any other initial condition.  The call to
+	// internalCheckTransition only allows these two cases, and the code below
+	// relies on that check being done.
+
+	// Don't have something we need in quite the right form
+	availableSKIs := make([]string, 0)
+	for ski := range suppliedSKIs {
+		availableSKIs = append(availableSKIs, ski)
+	}
+	sort.Strings(availableSKIs)
+
+	// Only need one signature for some commands
+	singleSigner :=
+		collectSigKeys(availableSKIs, signerOrder, signerMap, 1)
+
+	// Other commands require the signature threshold number of signatures
+	signers :=
+		collectSigKeys(availableSKIs, signerOrder, signerMap, hc.SignatureThreshold)
+
+	// Check if all master key registers are initially empty
+	allEmpty := true
+	for i := 0; i < len(hsminfo); i++ {
+		if hsminfo[i].CurrentMKStatus != "Empty" {
+			allEmpty = false
+			break
+		}
+	}
+
+	var recoveryHSM common.DomainEntry
+	var recoveryHSMindex int
+
+	if allEmpty && hc.NoRandomMasterKey {
+		// The master key will be loaded from customer key parts
+		return make([]string, 0), nil
+	}
+
+	if allEmpty {
+		// Look for a recovery crypto unit
+		foundIt := false
+		for i, domain := range domains {
+			if domain.Type == "recovery" {
+				recoveryHSM = domain
+				recoveryHSMindex = i
+				foundIt = true
+				break
+			}
+		}
+		if !foundIt {
+			return make([]string, 0), errors.New("No recovery crypto unit found when setting master key registers")
+		}
+
+		// Create a random WK in the recovery crypto unit
+		_, err := createRandomMasterKey(ctx, tr, recoveryHSM, singleSigner)
+		if err != nil {
+			return make([]string, 0), err
+		}
+
+	} else {
+		// Look for a recovery crypto unit whose current master key register
+		// is set
+		foundIt := false
+		for i, domain := range domains {
+			if domain.Type == "recovery" &&
+				hsminfo[i].CurrentMKStatus != "Empty" {
+
+				recoveryHSM = domain
+				recoveryHSMindex = i
+				foundIt = true
+				break
+			}
+		}
+		if !foundIt {
+			return make([]string, 0), errors.New("No recovery crypto unit found whose current master key register is set")
+		}
+	}
+
+	// Transfer the master key value to the other crypto units
+	for i, domain := range domains {
+		if i != recoveryHSMindex {
+			if hsminfo[i].CurrentMKStatus == "Empty" {
+
+				// Copy the master key value from the recovery crypto unit
+				err = copyMasterKey(ctx, tr, recoveryHSM, domain, false, hc,
+					signers, singleSigner)
+				if err != nil {
+					return make([]string, 0), err
+				}
+
+				// Commit the imported master key
+				err = commitPendingMasterKey(ctx, tr, domain, signers)
+				if err != nil {
+					return make([]string, 0), err
+				}
+
+				// Finalize the imported master key
+				err = finalizeMasterKey(ctx, tr, domain, singleSigner)
+				if err != nil {
+					return make([]string, 0), err
+				}
+			}
+		}
+	}
+
+	return make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as UpdateWithContext, using the background context                    */
+/*----------------------------------------------------------------------------*/
+func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
+	return UpdateWithContext(context.Background(), ci, hc)
+}
+
+/*----------------------------------------------------------------------------*/
+/* What Update has learned about the crypto units and the signature keys      */
+/* before it begins to change the administrators.                             */
+/*----------------------------------------------------------------------------*/
+type updateState struct {
+	hsminfo      []HsmInfo
+	tr           common.Transport
+	domains      []common.DomainEntry
+	keepSKIs     [][]string // administrators to keep, for each crypto unit
+	addSKIs      [][]string // administrators to add, for each crypto unit
+	rmvSKIs      [][]string // administrators to remove, for each crypto unit
+	suppliedSKIs map[string]bool
+	signerMap    map[string]common.Signer
+	adminNameMap map[string]string
+	signerOrder  []string
+	certMap      map[string][]byte // SKI --> administrator certificate
+}
+
+/*----------------------------------------------------------------------------*/
+/* Checks the inputs and the initial configuration for Update, and does the   */
+/* pre-emptive zeroize of crypto units in imprint mode.                       */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
+/*      functions.                                                            */
+/* HsmConfig -- the desired final configuration                               */
+/* *adminSigners -- the signers for the administrators in the resource block. */
+/*      The caller closes them after the update.                              */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *updateState -- the initial configuration and the signature keys           */
+/* []string -- problems with the inputs or the transition.  The updateState   */
+/*      is nil if any are returned.                                           */
+/* error -- identifies any error encountered                                  */
+/*----------------------------------------------------------------------------*/
+func prepareUpdate(ctx context.Context, ci CommonInputs, hc HsmConfig,
+	admins *adminSigners) (*updateState, []string, error) {
+
+	// Check inputs in the resource block
+	problems := checkInputs(hc, admins)
 	if len(problems) > 0 {
-		return problems, nil
+		return nil, problems, nil
+	}
+
+	// Identify what signature keys are in the resource block
+	suppliedSKIs, signerMap, adminNameMap, err := getSignatureKeys(admins)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+
+	// Read the initial configuration and check for invalid transitions
+	st, problems, err := checkUpdateTransition(ctx, ci, hc, suppliedSKIs,
+		adminNameMap)
+	if err != nil || len(problems) > 0 {
+		return nil, problems, err
+	}
+
+	// Check that administrators with Dilithium signature keys can be added.
+	// Administrators removed by the pre-emptive zeroize below are already
+	// installed, so they need not be checked again.
+	problems, err = checkDilithiumSupport(ctx, st.tr, st.domains, st.addSKIs,
+		signerKeyTypes(signerMap), adminNameMap)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
 	}
 
 	// Do a pre-emptive zeroize to work around an undesired consequence of
 	// an EP11 firmware update.
 	anyAdminsRemoved := false
-	for i := 0; i < len(hsminfo); i++ {
+	for i := 0; i < len(st.hsminfo); i++ {
 		// Only zeroize crypto units in imprint mode
-		if hsminfo[i].SignatureThreshold == 0 {
+		if st.hsminfo[i].SignatureThreshold == 0 {
 			// Will the pre-emptive zeroize remove administrators?
-			if len(hsminfo[i].Admins) > 0 {
+			if len(st.hsminfo[i].Admins) > 0 {
 				anyAdminsRemoved = true
 			}
 			// Do a pre-emptive zeroize
-			sigkeys := make([]string, 0)
-			sigkeySkis := make([]string, 0)
-			sigkeyTokens := make([]string, 0)
-			err := ep11cmds.ZeroizeDomain(ci.AuthToken, urlStart, domains[i],
-				sigkeys, sigkeySkis, sigkeyTokens)
+			signers := make([]common.Signer, 0)
+			err := zeroizeDomain(ctx, st.tr, st.domains[i], signers)
 			if err != nil {
-				return problems, err
+				return nil, problems, err
 			}
 		}
 	}
@@ -85,45 +305,184 @@ func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
 	// refetch the initial configuration and redetermine what administrators
 	// to keep, add, and remove.
 	if anyAdminsRemoved {
-		hsminfo, urlStart, domains, err = internalQuery(ci)
-		if err != nil {
-			return problems, err
-		}
-		problems, err, keepSKIs, addSKIs, rmvSKIs = internalCheckTransition(ci, hc, hsminfo)
-		if err != nil {
-			return make([]string, 0), err
+		st, problems, err = checkUpdateTransition(ctx, ci, hc, suppliedSKIs,
+			adminNameMap)
+		if err != nil || len(problems) > 0 {
+			return nil, problems, err
 		}
-		if len(problems) > 0 {
-			return problems, nil
+	}
+
+	// Create certificates for the signature keys of administrators to be
+	// added.  Other signature keys are only needed to sign commands, where
+	// a spare administrator can stand in for one that cannot sign.
+	certMap := make(map[string][]byte, 0)
+	// Maps SKI --> administrator certificate
+	for i := range st.addSKIs {
+		for _, ski := range st.addSKIs[i] {
+			if _, ok := certMap[ski]; ok {
+				continue
+			}
+			cert, err := ep11cmds.CreateAdminCert(signerMap[ski],
+				adminNameMap[ski])
+			if err != nil {
+				return nil, make([]string, 0), err
+			}
+			certMap[ski] = cert
 		}
 	}
 
-	// Identify what signature keys are in the resource block
-	suppliedSKIs, sigKeyMap, sigKeyTokenMap, adminNameMap, err :=
-		GetSignatureKeysFromResourceBlock(hc)
+	st.signerMap = signerMap
+	// Order of preference for signature keys
+	st.signerOrder = signerPreferenceOrder(hc, adminNameMap)
+	st.certMap = certMap
+	return st, make([]string, 0), nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Reads the initial configuration of the crypto units and checks that the    */
+/* transition to the desired final configuration is possible.  Used by Update */
+/* and PlanUpdate.                                                            */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/* CommonInputs -- identifies the service instance                            */
+/* HsmConfig -- the desired final configuration                               */
+/* map[string]bool -- set of the Subject Key Identifiers of the               */
+/*      administrators in the desired final configuration                     */
+/* map[string]string -- maps SKI --> administrator name                       */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* *updateState -- the initial configuration and the administrators to keep,  */
+/*      add, and remove.  The signature keys and certificates are not set.    */
+/* []string -- problems with the transition.  The updateState is nil if any   */
+/*      are returned.                                                         */
+/* error -- identifies any error encountered                                  */
+/*----------------------------------------------------------------------------*/
+func checkUpdateTransition(ctx context.Context, ci CommonInputs, hc HsmConfig,
+	finalSKIs map[string]bool,
+	adminNameMap map[string]string) (*updateState, []string, error) {
+
+	// Read the initial configuration
+	hsminfo, tr, domains, err := internalQuery(ctx, ci)
 	if err != nil {
-		return make([]string, 0), err
+		return nil, make([]string, 0), err
 	}
 
-	// Create certificates for each signature key
-	certMap := make(map[string][]byte, 0)
-	// Maps SKI --> administrator certificate
-	for ski := range suppliedSKIs {
-		cert, err := createAdminCert(ski, sigKeyMap[ski],
-			sigKeyTokenMap[ski], adminNameMap[ski])
-		if err != nil {
-			return make([]string, 0), err
-		}
-		certMap[ski] = cert
+	// Check for invalid transitions
+	problems, err, keepSKIs, addSKIs, rmvSKIs :=
+		internalCheckTransition(hc, hsminfo, finalSKIs, adminNameMap)
+	if err != nil {
+		return nil, make([]string, 0), err
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
 	}
 
-	//--------------------------------------------------------------------------
-	// Update administrators and signature thresholds
-	//--------------------------------------------------------------------------
+	return &updateState{
+		hsminfo:      hsminfo,
+		tr:           tr,
+		domains:      domains,
+		keepSKIs:     keepSKIs,
+		addSKIs:      addSKIs,
+		rmvSKIs:      rmvSKIs,
+		suppliedSKIs: finalSKIs,
+		adminNameMap: adminNameMap,
+	}, make([]string, 0), nil
+}
 
-	for i, domain := range domains {
+/*----------------------------------------------------------------------------*/
+/* Receives the administrative commands that change the administrators and    */
+/* signature thresholds of a crypto unit.  Update sends each command as it is */
+/* made; PlanUpdate lists them for a signing bundle.                          */
+/*----------------------------------------------------------------------------*/
+type adminUpdater interface {
+	removeAdmin(domain common.DomainEntry, ski string,
+		signers signerSet) error
+	addAdmin(domain common.DomainEntry, ski string, cert []byte,
+		signers signerSet) error
+	setThresholds(domain common.DomainEntry, newSigThr int, newRevThr int,
+		signers signerSet) error
+}
+
+/*----------------------------------------------------------------------------*/
+/* The administrators allowed to sign a command and the number of signatures  */
+/* it needs.  Update chooses the signature keys when the command is sent;     */
+/* PlanUpdate only needs the number of signatures.                            */
+/*----------------------------------------------------------------------------*/
+type signerSet struct {
+	allowedSKIs []string
+	needed      int
+}
+
+/** Returns a signerSet that does not change when allowedSKIs is appended to */
+func newSignerSet(allowedSKIs []string, needed int) signerSet {
+	return signerSet{
+		allowedSKIs: append([]string(nil), allowedSKIs...),
+		needed:      needed,
+	}
+}
+
+/** Sends the commands that change the administrators to the crypto units */
+type sendingAdminUpdater struct {
+	ctx         context.Context
+	tr          common.Transport
+	signerOrder []string
+	signerMap   map[string]common.Signer
+	mPolicy     int // M policy for importing master keys
+}
+
+/** Returns the signature keys to use to sign a command */
+func (u *sendingAdminUpdater) signers(s signerSet) []common.Signer {
+	return collectSigKeys(s.allowedSKIs, u.signerOrder, u.signerMap, s.needed)
+}
 
-		if hsminfo[i].SignatureThreshold >= hsminfo[i].RevocationThreshold {
+func (u *sendingAdminUpdater) removeAdmin(domain common.DomainEntry,
+	ski string, signers signerSet) error {
+
+	return removeDomainAdmin(u.ctx, u.tr, domain, ski, u.signers(signers))
+}
+
+func (u *sendingAdminUpdater) addAdmin(domain common.DomainEntry,
+	ski string, cert []byte, signers signerSet) error {
+
+	return addDomainAdmin(u.ctx, u.tr, domain, ski, cert,
+		u.signers(signers))
+}
+
+func (u *sendingAdminUpdater) setThresholds(domain common.DomainEntry,
+	newSigThr int, newRevThr int, signers signerSet) error {
+
+	domainAttributes, err := updatedDomainAttributes(u.ctx, u.tr, domain,
+		newSigThr, newRevThr, u.mPolicy)
+	if err != nil {
+		return err
+	}
+	return setDomainAttributes(u.ctx, u.tr, domain, domainAttributes,
+		u.signers(signers))
+}
+
+/*----------------------------------------------------------------------------*/
+/* Makes the commands that change the administrators and signature thresholds */
+/* of each crypto unit to those in the desired final configuration, in an     */
+/* order that keeps enough administrators installed to sign each command.     */
+/*                                                                            */
+/* Inputs:                                                                    */
+/* HsmConfig -- the desired final configuration                               */
+/* *updateState -- the initial configuration from prepareUpdate.  Added       */
+/*      administrators are moved to keepSKIs as they become available to      */
+/*      sign later commands.                                                  */
+/* adminUpdater -- receives the commands, in order                            */
+/*                                                                            */
+/* Outputs:                                                                   */
+/* error -- identifies any error returned by the adminUpdater                 */
+/*----------------------------------------------------------------------------*/
+func updateAdministrators(hc HsmConfig, st *updateState,
+	u adminUpdater) error {
+
+	var err error
+	for i, domain := range st.domains {
+
+		if st.hsminfo[i].SignatureThreshold >= st.hsminfo[i].RevocationThreshold {
 
 			//------------------------------------------------------------------
 			// This case can be handled by removing administrators first,
@@ -132,59 +491,54 @@ func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
 
 			// Assemble the set of signature keys to use to sign commands to
 			// remove administrators
-			sigkeys, sigkeySkis, sigkeyTokens :=
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].RevocationThreshold)
+			signers :=
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].RevocationThreshold)
 
 			// Remove administrators
-			for _, ski := range rmvSKIs[i] {
-				err = ep11cmds.RemoveDomainAdministrator(ci.AuthToken,
-					urlStart, domain, ski, sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.rmvSKIs[i] {
+				err = u.removeAdmin(domain, ski, signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 			}
 
 			// Assemble the set of signature keys to use to sign commands to
 			// add administrators
-			sigkeys, sigkeySkis, sigkeyTokens =
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].SignatureThreshold)
+			signers =
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].SignatureThreshold)
 
 			// Add administrators
-			for _, ski := range addSKIs[i] {
-				err = ep11cmds.AddDomainAdmin(ci.AuthToken, urlStart, domain,
-					certMap[ski], sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.addSKIs[i] {
+				err = u.addAdmin(domain, ski, st.certMap[ski], signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 				// Make this administrator available to sign subsequent commands
-				keepSKIs[i] = append(keepSKIs[i], ski)
+				st.keepSKIs[i] = append(st.keepSKIs[i], ski)
 			}
 
 			// Assemble the set of signature keys to use to sign the command
 			// to change the signature thresholds
-			if hsminfo[i].SignatureThreshold == 0 {
+			if st.hsminfo[i].SignatureThreshold == 0 {
 				// Leaving imprint mode is a special case.
 				// The number of required signatures is the new signature
 				// threshold value.
-				sigkeys, sigkeySkis, sigkeyTokens =
-					collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-						hc.SignatureThreshold)
+				signers =
+					newSignerSet(st.keepSKIs[i], hc.SignatureThreshold)
 			} else {
 				// Not leaving imprint mode
 				// Can use the set of signature keys already assembled
 			}
 
 			// Change the signature thresholds and other domain attributes
-			err = SetDomainAttributes(ci.AuthToken, urlStart, domain,
+			err = u.setThresholds(domain,
 				hc.SignatureThreshold, hc.RevocationThreshold,
-				sigkeys, sigkeySkis, sigkeyTokens)
+				signers)
 			if err != nil {
-				return make([]string, 0), err
+				return err
 			}
 
-		} else if hc.RevocationThreshold <= len(keepSKIs[i]) {
+		} else if hc.RevocationThreshold <= len(st.keepSKIs[i]) {
 
 			//------------------------------------------------------------------
 			// This case can be handled by changing the revocation threshold
@@ -194,59 +548,54 @@ func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
 
 			// Assemble the set of signature keys to use to sign the command to
 			// change the revocation threshold
-			sigkeys, sigkeySkis, sigkeyTokens :=
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].SignatureThreshold)
+			signers :=
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].SignatureThreshold)
 
 			// Keep current signature threshold but change the revocation
 			// threshold
-			err = SetDomainAttributes(ci.AuthToken, urlStart, domain,
-				hsminfo[i].SignatureThreshold, hc.RevocationThreshold,
-				sigkeys, sigkeySkis, sigkeyTokens)
+			err = u.setThresholds(domain,
+				st.hsminfo[i].SignatureThreshold, hc.RevocationThreshold,
+				signers)
 			if err != nil {
-				return make([]string, 0), err
+				return err
 			}
 
 			// Assemble the set of signature keys to use to sign commands to
 			// remove administrators
-			sigkeys, sigkeySkis, sigkeyTokens =
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hc.RevocationThreshold)
+			signers =
+				newSignerSet(st.keepSKIs[i], hc.RevocationThreshold)
 
 			// Remove administrators
-			for _, ski := range rmvSKIs[i] {
-				err = ep11cmds.RemoveDomainAdministrator(ci.AuthToken,
-					urlStart, domain, ski, sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.rmvSKIs[i] {
+				err = u.removeAdmin(domain, ski, signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 			}
 
 			// Assemble the set of signature keys to use to sign commands to
 			// add administrators
-			sigkeys, sigkeySkis, sigkeyTokens =
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].SignatureThreshold)
+			signers =
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].SignatureThreshold)
 
 			// Add administrators
-			for _, ski := range addSKIs[i] {
-				err = ep11cmds.AddDomainAdmin(ci.AuthToken, urlStart, domain,
-					certMap[ski], sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.addSKIs[i] {
+				err = u.addAdmin(domain, ski, st.certMap[ski], signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 			}
 
 			// Change the signature threshold
 			// Can use the same signature keys as the previous operation
-			err = SetDomainAttributes(ci.AuthToken, urlStart, domain,
+			err = u.setThresholds(domain,
 				hc.SignatureThreshold, hc.RevocationThreshold,
-				sigkeys, sigkeySkis, sigkeyTokens)
+				signers)
 			if err != nil {
-				return make([]string, 0), err
+				return err
 			}
 
-		} else if len(keepSKIs[i])+len(addSKIs[i])+len(rmvSKIs[i]) <= 8 {
+		} else if len(st.keepSKIs[i])+len(st.addSKIs[i])+len(st.rmvSKIs[i]) <= 8 {
 
 			//------------------------------------------------------------------
 			// This case can be handled by adding administrators first, then
@@ -255,259 +604,168 @@ func Update(ci CommonInputs, hc HsmConfig) ([]string, error) {
 
 			// Assemble the set of signature keys to use to sign commands to
 			// add administrators
-			sigkeys, sigkeySkis, sigkeyTokens :=
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].SignatureThreshold)
+			signers :=
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].SignatureThreshold)
 
 			// Add administrators
-			for _, ski := range addSKIs[i] {
-				err = ep11cmds.AddDomainAdmin(ci.AuthToken, urlStart, domain,
-					certMap[ski], sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.addSKIs[i] {
+				err = u.addAdmin(domain, ski, st.certMap[ski], signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 				// Make this administrator available to sign subsequent commands
-				keepSKIs[i] = append(keepSKIs[i], ski)
+				st.keepSKIs[i] = append(st.keepSKIs[i], ski)
 			}
 
 			// Assemble the set of signature keys to use to sign commands to
 			// remove administrators
-			sigkeys, sigkeySkis, sigkeyTokens =
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].RevocationThreshold)
+			signers =
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].RevocationThreshold)
 
 			// Remove administrators
-			for _, ski := range rmvSKIs[i] {
-				err = ep11cmds.RemoveDomainAdministrator(ci.AuthToken,
-					urlStart, domain, ski, sigkeys, sigkeySkis, sigkeyTokens)
+			for _, ski := range st.rmvSKIs[i] {
+				err = u.removeAdmin(domain, ski, signers)
 				if err != nil {
-					return make([]string, 0), err
+					return err
 				}
 			}
 
 			// Assemble the set of signature keys to use to sign the command
 			// to change the signature thresholds
-			sigkeys, sigkeySkis, sigkeyTokens =
-				collectSigKeys(keepSKIs[i], sigKeyMap, sigKeyTokenMap,
-					hsminfo[i].SignatureThreshold)
+			signers =
+				newSignerSet(st.keepSKIs[i], st.hsminfo[i].SignatureThreshold)
 
 			// Change the signature thresholds
-			err = SetDomainAttributes(ci.AuthToken, urlStart, domain,
+			err = u.setThresholds(domain,
 				hc.SignatureThreshold, hc.RevocationThreshold,
-				sigkeys, sigkeySkis, sigkeyTokens)
+				signers)
 			if err != nil {
-				return make([]string, 0), err
+				return err
 			}
 
 		} else {
 			// Previous checks should prevent us from ever getting here
-			return make([]string, 0), errors.New("Unsupported state transition")
+			return errors.New("Unsupported state transition")
 		}
 	}
 
-	//--------------------------------------------------------------------------
-	// Update the current master key registers
-	//--------------------------------------------------------------------------
-
-	// Two cases are handled:
-	// 1. All current master key registers are initially empty.
-	// 2. The current master key register in at least one recovery crypto unit
-	//    is set, and all other crypto units either have the same master key
-	//    value or they are empty.
-	//
-	// We do not attempt to handle any other initial condition.  The call to
-	// internalCheckTransition only allows these two cases, and the code below
-	// relies on that check being done.
-
-	// Don't have something we need in quite the right form
-	availableSKIs := make([]string, 0)
-	for ski := range suppliedSKIs {
-		availableSKIs = append(availableSKIs, ski)
-	}
-
-	// Only need one signature for some commands
-	singleSigkey, singleSigkeySki, singleSigkeyToken :=
-		collectSigKeys(availableSKIs, sigKeyMap, sigKeyTokenMap, 1)
-
-	// Other commands require the signature threshold number of signatures
-	sigkeys, sigkeySkis, sigkeyTokens :=
-		collectSigKeys(availableSKIs, sigKeyMap, sigKeyTokenMap, hc.SignatureThreshold)
-
-	// Check if all master key registers are initially empty
-	allEmpty := true
-	for i := 0; i < len(hsminfo); i++ {
-		if hsminfo[i].CurrentMKStatus != "Empty" {
-			allEmpty = false
-			break
-		}
-	}
-
-	var recoveryHSM common.DomainEntry
-	var recoveryHSMindex int
-
-	if allEmpty {
-		// Look for a recovery crypto unit
-		foundIt := false
-		for i, domain := range domains {
-			if domain.Type == "recovery" {
-				recoveryHSM = domain
-				recoveryHSMindex = i
-				foundIt = true
-				break
-			}
-		}
-		if !foundIt {
-			return make([]string, 0), errors.New("No recovery crypto unit found when setting master key registers")
-		}
-
-		// Create a random WK in the recovery crypto unit
-		err, _ := ep11cmds.CreateRandomWK(ci.AuthToken, urlStart, recoveryHSM,
-			singleSigkey, singleSigkeySki, singleSigkeyToken)
-		if err != nil {
-			return make([]string, 0), err
-		}
-
-	} else {
-		// Look for a recovery crypto unit whose current master key register
-		// is set
-		foundIt := false
-		for i, domain := range domains {
-			if domain.Type == "recovery" &&
-				hsminfo[i].CurrentMKStatus != "Empty" {
-
-				recoveryHSM = domain
-				recoveryHSMindex = i
-				foundIt = true
-				break
-			}
-		}
-		if !foundIt {
-			return make([]string, 0), errors.New("No recovery crypto unit found whose current master key register is set")
-		}
-	}
-
-	// Transfer the master key value to the other crypto units
-	for i, domain := range domains {
-		if i != recoveryHSMindex {
-			if hsminfo[i].CurrentMKStatus == "Empty" {
-
-				// Generate an importer key in the target domain
-				pubKey, _, err := ep11cmds.GenerateP521ECImporterKey(
-					ci.AuthToken, urlStart, domain, singleSigkey,
-					singleSigkeySki, singleSigkeyToken)
-				if err != nil {
-					return make([]string, 0), err
-				}
-
-				// Export the master key value from the recovery crypto unit using
-				// the importer key
-				kphcert := ep11cmds.KPHCert(pubKey)
-				pfile := ep11cmds.ExportWKParameterFile(kphcert)
-				pdata, err := ep11cmds.ExportWK(ci.AuthToken, urlStart,
-					recoveryHSM, pfile, sigkeys, sigkeySkis, sigkeyTokens)
-				if err != nil {
-					return make([]string, 0), err
-				}
-
-				var pMap common.ParameterMap
-				pMap, err = pMap.Load(pdata)
-				if err != nil {
-					return make([]string, 0), err
-				}
-
-				// Get the recipient info for the single key part
-				recipientInfo := make([][]byte, 0)
-				recipientInfo = append(recipientInfo, pMap.GetDataUsingIndex(common.PMTAG_ENCR_KEY_PART, 0))
-
-				// Import the master key to the target domain
-				err = ep11cmds.ImportWK(ci.AuthToken, urlStart, domain, recipientInfo,
-					singleSigkey, singleSigkeySki, singleSigkeyToken)
-				if err != nil {
-					return make([]string, 0), err
-				}
-
-				// Commit the imported master key
-				err = ep11cmds.CommitPendingWK(ci.AuthToken, urlStart, domain,
-					sigkeys, sigkeySkis, sigkeyTokens)
-				if err != nil {
-					return make([]string, 0), err
-				}
-
-				// Finalize the imported master key
-				err = ep11cmds.FinalizeWK(ci.AuthToken, urlStart, domain,
-					singleSigkey, singleSigkeySki, singleSigkeyToken)
-				if err != nil {
-					return make([]string, 0), err
-				}
-			}
-		}
-	}
-
-	return make([]string, 0), nil
+	return nil
 }
 
 /*----------------------------------------------------------------------------*/
 /* Assembles a set of signature keys that can be used to sign a command.      */
+/* All of the allowed signature keys are asked to sign, in order of           */
+/* preference, and the first valid signatures returned are used.  See         */
+/* ep11cmds.SignerQuorum.                                                     */
 /*                                                                            */
 /* Inputs:                                                                    */
 /* []string -- set of the Subject Key Identifiers for signature keys that     */
 /*     can be used to sign the command.  These SKIs must be for signature     */
 /*     keys that are specified in the resource block and that are already     */
 /*     installed as administrators on the target crypto unit.                 */
-/* map[string]string -- maps SKI --> signature key                            */
-/* map[string]string -- maps SKI --> signature key token                      */
+/* []string -- the SKIs of all signature keys in the resource block, in order */
+/*     of preference                                                          */
+/* map[string]common.Signer -- maps SKI --> signer for the signature key      */
 /* int -- number of signatures needed                                         */
 /*                                                                            */
 /* Outputs:                                                                   */
-/* []string -- identifies the signature keys to use to sign the command       */
-/* []string -- the Subject Key Identifiers for the signature keys             */
-/* []string -- authentication tokens for the signature keys                   */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*----------------------------------------------------------------------------*/
-func collectSigKeys(allowedSKIs []string, sigKeyMap map[string]string,
-	sigKeyTokenMap map[string]string, needed int) ([]string, []string,
-	[]string) {
+func collectSigKeys(allowedSKIs []string, signerOrder []string,
+	signerMap map[string]common.Signer, needed int) []common.Signer {
 
-	sigkeys := make([]string, 0)
-	sigkeySkis := make([]string, 0)
-	sigkeyTokens := make([]string, 0)
+	allowed := make(map[string]bool)
 	for _, ski := range allowedSKIs {
-		if len(sigkeys) < needed {
-			sigkeys = append(sigkeys, sigKeyMap[ski])
-			sigkeySkis = append(sigkeySkis, ski)
-			sigkeyTokens = append(sigkeyTokens, sigKeyTokenMap[ski])
-		} else {
-			break
+		allowed[ski] = true
+	}
+	candidates := make([]common.Signer, 0)
+	for _, ski := range signerOrder {
+		if allowed[ski] {
+			candidates = append(candidates, signerMap[ski])
 		}
 	}
-	if len(sigkeys) < needed {
+	if len(candidates) < needed {
 		panic("Internal error: not enough administrators to meet threshold value")
 	}
-	return sigkeys, sigkeySkis, sigkeyTokens
+	return ep11cmds.NewSignerQuorum(candidates).Signers(needed)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the SKIs of the signature keys in the resource block in order of   */
+/* preference: administrators named in HsmConfig.SignerPreference first, then */
+/* the others in the order of HsmConfig.Admins.                               */
+/*----------------------------------------------------------------------------*/
+func signerPreferenceOrder(hc HsmConfig,
+	adminNameMap map[string]string) []string {
+
+	names := append([]string(nil), hc.SignerPreference...)
+	for _, admin := range hc.Admins {
+		names = append(names, admin.Name)
+	}
+	order := make([]string, 0)
+	added := make(map[string]bool)
+	for _, name := range names {
+		// Administrators should have different names, but keep the order
+		// repeatable if they do not
+		skis := make([]string, 0)
+		for ski, adminName := range adminNameMap {
+			if adminName == name && !added[ski] {
+				skis = append(skis, ski)
+				added[ski] = true
+			}
+		}
+		sort.Strings(skis)
+		order = append(order, skis...)
+	}
+	return order
 }
 
 /*----------------------------------------------------------------------------*/
-/* Creates an administrator certificate                                       */
+/* Sets the domain attributes.  Different attributes are set for recovery     */
+/* HSMs and operational HSMs.                                                 */
 /*                                                                            */
 /* Inputs:                                                                    */
-/* string -- Subject Key Identifier for the administrator to be created,      */
-/*     represented as a hexadecimal string                                    */
-/* string -- Key parameter for the signature key to be used                   */
-/* string -- Token parameter for the signature key to be used                 */
-/* string -- the name to be placed in the certificate                         */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
+/* common.Transport -- the transport used to send requests to the crypto      */
+/*    unit                                                                    */
+/* DomainEntry -- identifies the domain whose attributes are to be set        */
+/* int -- new signature threshold value to set                                */
+/* int -- new revocation signature threshold value to set                     */
+/* []common.Signer -- the signature keys to use to sign the command           */
 /*                                                                            */
-/* Outputs:                                                                   */
-/* []byte -- the administrator certificate                                    */
-/* error -- identifies any error encountered during processing                */
+/* Output:                                                                    */
+/* error -- reports any errors accessing the domain                           */
 /*----------------------------------------------------------------------------*/
-func createAdminCert(ski string, sigkey string, sigkeyToken string,
-	adminName string) ([]byte, error) {
+func SetDomainAttributesWithContext(ctx context.Context,
+	tr common.Transport, domain common.DomainEntry, newSigThr int,
+	newRevThr int, signers []common.Signer) error {
 
-	ssURL := os.Getenv("TKE_SIGNSERV_URL")
-	if ssURL != "" {
-		return ep11cmds.CreateAdminCertUsingSigningService(ssURL, sigkey, sigkeyToken, adminName)
-	} else {
-		return CreateAdminCertFromFile(sigkey, ski, sigkeyToken, adminName)
+	domainAttributes, err := updatedDomainAttributes(ctx, tr, domain,
+		newSigThr, newRevThr, 1)
+	if err != nil {
+		return err
+	}
+
+	err = setDomainAttributes(ctx, tr, domain, domainAttributes, signers)
+	if err != nil {
+		return err
 	}
+
+	return nil
+}
+
+/*----------------------------------------------------------------------------*/
+/* Same as SetDomainAttributesWithContext, using the background context       */
+/*----------------------------------------------------------------------------*/
+func SetDomainAttributesWithTransport(tr common.Transport,
+	domain common.DomainEntry, newSigThr int, newRevThr int,
+	signers []common.Signer) error {
+
+	return SetDomainAttributesWithContext(context.Background(), tr, domain,
+		newSigThr, newRevThr, signers)
 }
 
 /*----------------------------------------------------------------------------*/
@@ -531,12 +789,32 @@ func SetDomainAttributes(authToken string, urlStart string,
 	domain common.DomainEntry, newSigThr int, newRevThr int,
 	sigkeys []string, sigkeySkis []string, sigkeyTokens []string) error {
 
-	// Get the current domain attributes
-	domainAttributes, _, err := ep11cmds.QueryDomainAttributes(
-		authToken, urlStart, domain)
+	signers, err := common.NewSigners(sigkeys, sigkeySkis, sigkeyTokens)
 	if err != nil {
 		return err
 	}
+	defer common.CloseSigners(signers)
+	return SetDomainAttributesWithTransport(
+		common.NewHTTPTransport(authToken, urlStart), domain, newSigThr,
+		newRevThr, signers)
+}
+
+/*----------------------------------------------------------------------------*/
+/* Returns the domain attributes SetDomainAttributes sets: the current        */
+/* attributes of the domain, with the new signature thresholds and the        */
+/* permissions wanted for recovery HSMs or operational HSMs.  Importing a     */
+/* master key in a single key part is allowed only for an M policy of 1.      */
+/*----------------------------------------------------------------------------*/
+func updatedDomainAttributes(ctx context.Context, tr common.Transport,
+	domain common.DomainEntry, newSigThr int, newRevThr int,
+	mPolicy int) (ep11cmds.DomainAttributes, error) {
+
+	// Get the current domain attributes
+	domainAttributes, _, err := ep11cmds.QueryDomainAttributesWithContext(ctx,
+		tr, domain)
+	if err != nil {
+		return domainAttributes, err
+	}
 
 	// Allow domains to be zeroized with a single signature
 	domainAttributes.Permissions |= 0x00000040
@@ -547,8 +825,13 @@ func SetDomainAttributes(authToken string, urlStart string,
 	// Allow master key import
 	domainAttributes.Permissions |= 0x00000001
 
-	// Allow importing using a single key part
-	domainAttributes.Permissions |= 0x00000004
+	// Allow importing using a single key part only when the master key
+	// is not required to be transported in several key parts
+	if mPolicy >= 2 {
+		domainAttributes.Permissions &^= 0x00000004
+	} else {
+		domainAttributes.Permissions |= 0x00000004
+	}
 
 	// Set some attributes differently for recovery HSMs and operational HSMs
 	if domain.Type == "recovery" {
@@ -599,12 +882,5 @@ func SetDomainAttributes(authToken string, urlStart string,
 	domainAttributes.SignatureThreshold = uint32(newSigThr)
 	domainAttributes.RevocationSignatureThreshold = uint32(newRevThr)
 
-	err = ep11cmds.SetDomainAttributes(
-		authToken, urlStart, domain, domainAttributes, sigkeys, sigkeySkis,
-		sigkeyTokens)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return domainAttributes, nil
 }
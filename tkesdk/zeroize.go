@@ -7,14 +7,21 @@
 //
 // Date          Initials        Description
 // 04/09/2021    CLH             Initial version
+// 10/18/2026    CLH             Add context variants
+// 10/18/2026    CLH             Use common.Signer
+// 10/18/2026    CLH             Create signers with CommonInputs.HTTPClient
+// 10/18/2026    CLH             Confirm commands whose response was lost
+// 10/18/2026    CLH             Create signers once and close them afterwards
 
 package tkesdk
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"strings"
 
+	"github.com/IBM/ibm-hpcs-tke-sdk/common"
 	"github.com/IBM/ibm-hpcs-tke-sdk/ep11cmds"
 )
 
@@ -26,6 +33,10 @@ const XCP_ADMP_ZERO_1SIGN = 0x00000040
 /* error if that is not possible.                                             */
 /*                                                                            */
 /* Inputs:                                                                    */
+/* context.Context -- controls cancellation and deadlines for the requests    */
+/*      sent to the crypto units.  Cancellation takes effect between          */
+/*      administrative commands.  A signed command that has been sent is      */
+/*      never abandoned; its response is always collected.                    */
 /* CommonInputs -- A structure containing inputs needed for all TKE SDK       */
 /*      functions.  This includes: the API endpoint and region, the HPCS      */
 /*      service instance id, and an IBM Cloud authentication token.           */
@@ -34,10 +45,11 @@ const XCP_ADMP_ZERO_1SIGN = 0x00000040
 /*      provides access to signature keys for signing commands to crypto      */
 /*      units.                                                                */
 /*----------------------------------------------------------------------------*/
-func Zeroize(ci CommonInputs, hc HsmConfig) error {
+func ZeroizeWithContext(ctx context.Context, ci CommonInputs,
+	hc HsmConfig) error {
 
 	// Query the initial configuration of the crypto units
-	hsminfo, urlStart, domains, err := internalQuery(ci)
+	hsminfo, tr, domains, err := internalQuery(ctx, ci)
 	if err != nil {
 		return err
 	}
@@ -53,12 +65,9 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 
 	// Handle special case of all crypto units in imprint mode
 	if imprintModeOnly {
-		sigkeys := make([]string, 0)
-		sigkeySkis := make([]string, 0)
-		sigkeyTokens := make([]string, 0)
+		signers := make([]common.Signer, 0)
 		for i := 0; i < len(hsminfo); i++ {
-			err := ep11cmds.ZeroizeDomain(ci.AuthToken, urlStart, domains[i],
-				sigkeys, sigkeySkis, sigkeyTokens)
+			err := zeroizeDomain(ctx, tr, domains[i], signers)
 			if err != nil {
 				return err
 			}
@@ -68,15 +77,16 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 
 	// Check that all signature keys specified in the resource block can be
 	// accessed
-	for _, adminInfo := range hc.Admins {
-		if !validKey(adminInfo) {
+	admins := newAdminSigners(ci.HTTPClient, hc.Admins)
+	defer admins.close()
+	for _, signer := range admins.signers {
+		if !validKey(signer) {
 			return errors.New("One or more signature keys cannot be accessed.")
 		}
 	}
 
 	// Determine what signature keys are available
-	suppliedSkis, sigKeyMap, sigKeyTokenMap, _, err :=
-		GetSignatureKeysFromResourceBlock(hc)
+	suppliedSkis, signerMap, _, err := getSignatureKeys(admins)
 	if err != nil {
 		return err
 	}
@@ -84,7 +94,8 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 	// Determine the zeroize with one signature attribute for all crypto units
 	zeroizeWithOne := make([]bool, 0)
 	for i := 0; i < len(domains); i++ {
-		attr, _, err := ep11cmds.QueryDomainAttributes(ci.AuthToken, urlStart, domains[i])
+		attr, _, err := ep11cmds.QueryDomainAttributesWithContext(ctx, tr,
+			domains[i])
 		if err != nil {
 			return err
 		}
@@ -94,7 +105,8 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 	// Read the installed administrators for all crypto units
 	installedAdminSkis := make([][]string, 0)
 	for i := 0; i < len(domains); i++ {
-		skiBytes, err := ep11cmds.QueryDomainAdmins(ci.AuthToken, urlStart, domains[i])
+		skiBytes, err := ep11cmds.QueryDomainAdminsWithContext(ctx, tr,
+			domains[i])
 		if err != nil {
 			return err
 		}
@@ -138,9 +150,7 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 	// Zeroize all crypto units
 	for i := 0; i < len(domains); i++ {
 
-		sigkeys := make([]string, 0)
-		sigkeySkis := make([]string, 0)
-		sigkeyTokens := make([]string, 0)
+		signers := make([]common.Signer, 0)
 
 		var signaturesNeeded int
 		if hsminfo[i].SignatureThreshold == 0 {
@@ -153,23 +163,20 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 
 		// Select the signature keys to be used for this crypto unit
 		for j := 0; j < len(installedAdminSkis[i]); j++ {
-			if len(sigkeys) == signaturesNeeded {
+			if len(signers) == signaturesNeeded {
 				break
 			}
 			if suppliedSkis[installedAdminSkis[i][j]] {
-				sigkeys = append(sigkeys, sigKeyMap[installedAdminSkis[i][j]])
-				sigkeySkis = append(sigkeySkis, installedAdminSkis[i][j])
-				sigkeyTokens = append(sigkeyTokens, sigKeyTokenMap[installedAdminSkis[i][j]])
+				signers = append(signers, signerMap[installedAdminSkis[i][j]])
 			}
 		}
-		if len(sigkeys) != signaturesNeeded {
+		if len(signers) != signaturesNeeded {
 			return errors.New("Error selecting signature keys to sign zeroize command")
 			// Previous checks should prevent this from ever being reported
 		}
 
 		// Zeroize the crypto unit
-		err := ep11cmds.ZeroizeDomain(ci.AuthToken, urlStart, domains[i],
-			sigkeys, sigkeySkis, sigkeyTokens)
+		err := zeroizeDomain(ctx, tr, domains[i], signers)
 		if err != nil {
 			return err
 		}
@@ -177,3 +184,10 @@ func Zeroize(ci CommonInputs, hc HsmConfig) error {
 
 	return nil
 }
+
+/*----------------------------------------------------------------------------*/
+/* Same as ZeroizeWithContext, using the background context                   */
+/*----------------------------------------------------------------------------*/
+func Zeroize(ci CommonInputs, hc HsmConfig) error {
+	return ZeroizeWithContext(context.Background(), ci, hc)
+}